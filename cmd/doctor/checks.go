@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+
+	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/llm"
+	"github.com/jadenj13/droid/internals/queue"
+)
+
+// checkAnthropic sends a one-word prompt with every configured API key.
+func checkAnthropic(ctx context.Context) []result {
+	keys := append([]string{os.Getenv("ANTHROPIC_API_KEY")}, splitEnv("ANTHROPIC_API_KEYS")...)
+	if keys[0] == "" {
+		return []result{{"anthropic", statusFail, "ANTHROPIC_API_KEY is not set"}}
+	}
+	opts, err := llm.TransportConfig{
+		BaseURL:  os.Getenv("ANTHROPIC_BASE_URL"),
+		Proxy:    os.Getenv("ANTHROPIC_PROXY"),
+		Headers:  os.Getenv("ANTHROPIC_HEADERS"),
+		CAFile:   os.Getenv("ANTHROPIC_CA_FILE"),
+		CertFile: os.Getenv("ANTHROPIC_CLIENT_CERT"),
+		KeyFile:  os.Getenv("ANTHROPIC_CLIENT_KEY"),
+	}.Options()
+	if err != nil {
+		return []result{{"anthropic", statusFail, "invalid transport settings: " + err.Error()}}
+	}
+
+	var out []result
+	for _, key := range keys {
+		client := llm.NewClient(key, append(opts, llm.WithMaxTokens(16))...)
+		name := "anthropic key " + client.KeyHealth()[0].Key
+		start := time.Now()
+		_, err := client.CompleteWithTools(ctx, "Reply with OK.", []llm.Message{{Role: "user", Content: "ping"}}, nil)
+		if err != nil {
+			out = append(out, result{name, statusFail, err.Error()})
+			continue
+		}
+		out = append(out, result{name, statusOK, fmt.Sprintf("%s answered in %s", llm.DefaultModel, time.Since(start).Round(time.Millisecond))})
+	}
+	return out
+}
+
+// checkSlack authenticates the bot token and opens a Socket Mode session
+// with the app token, as the planner does at startup.
+func checkSlack(ctx context.Context) []result {
+	botToken, appToken := os.Getenv("SLACK_BOT_TOKEN"), os.Getenv("SLACK_APP_TOKEN")
+	if botToken == "" {
+		return []result{{"slack", statusSkip, "SLACK_BOT_TOKEN is not set; the planner and Slack notifications need it"}}
+	}
+	api := slack.New(botToken, slack.OptionAppLevelToken(appToken))
+	auth, err := api.AuthTestContext(ctx)
+	if err != nil {
+		return []result{{"slack bot token", statusFail, err.Error()}}
+	}
+	out := []result{{"slack bot token", statusOK, fmt.Sprintf("authenticated as %s in %s", auth.User, auth.Team)}}
+	if appToken == "" {
+		return append(out, result{"slack app token", statusSkip, "SLACK_APP_TOKEN is not set; the planner needs it"})
+	}
+	if _, _, err := api.StartSocketModeContext(ctx); err != nil {
+		return append(out, result{"slack app token", statusFail, err.Error()})
+	}
+	return append(out, result{"slack app token", statusOK, "Socket Mode connection opened"})
+}
+
+// checkGitHosts checks that a token is configured and, given a repository,
+// what access it has there: write to run the agents, admin (maintainer on
+// GitLab) to manage webhooks.
+func checkGitHosts(ctx context.Context, repoURL string) []result {
+	githubToken, gitlabToken := os.Getenv("GITHUB_TOKEN"), os.Getenv("GITLAB_TOKEN")
+	if githubToken == "" && gitlabToken == "" {
+		return []result{{"git host", statusFail, "neither GITHUB_TOKEN nor GITLAB_TOKEN is set"}}
+	}
+	if repoURL == "" {
+		return []result{{"git host", statusSkip, "pass -repo to check token access to a repository"}}
+	}
+
+	network, err := git.NewNetwork(os.Getenv("GIT_HOST_CA_FILE"), os.Getenv("GIT_HOST_PROXY"))
+	if err != nil {
+		return []result{{"git host", statusFail, "invalid network settings: " + err.Error()}}
+	}
+	provider, info, err := git.NewFactory(githubToken, gitlabToken, git.WithNetwork(network)).ProviderFor(ctx, repoURL)
+	if err != nil {
+		return []result{{"git host", statusFail, err.Error()}}
+	}
+	name := info.Platform.String() + " token"
+	have, err := provider.TokenPermission(ctx)
+	if err != nil {
+		return []result{{name, statusFail, err.Error()}}
+	}
+	hooks := git.PermissionAdmin
+	if info.Platform == git.PlatformGitLab {
+		hooks = git.PermissionMaintain
+	}
+	switch {
+	case have < git.PermissionWrite:
+		return []result{{name, statusFail, fmt.Sprintf("%s access to %s; the agents need write", have, info.RawURL)}}
+	case have < hooks:
+		return []result{{name, statusWarn, fmt.Sprintf("%s access to %s; enough to run, but the onboard command needs %s to manage webhooks", have, info.RawURL, hooks)}}
+	}
+	return []result{{name, statusOK, fmt.Sprintf("%s access to %s", have, info.RawURL)}}
+}
+
+// checkWebhooks delivers a signed ping event to each service for every
+// platform with a webhook secret, the way the git host would. The services
+// ignore pings, so a 2xx answer means the URL is reachable and the secret
+// matches.
+func checkWebhooks(ctx context.Context, executorURL, reviewerURL string) []result {
+	services := []struct{ name, baseURL string }{{"executor", executorURL}, {"reviewer", reviewerURL}}
+	secrets := []struct {
+		platform git.Platform
+		secret   string
+	}{
+		{git.PlatformGitHub, os.Getenv("GITHUB_WEBHOOK_SECRET")},
+		{git.PlatformGitLab, os.Getenv("GITLAB_WEBHOOK_SECRET")},
+	}
+
+	var out []result
+	for _, svc := range services {
+		if svc.baseURL == "" {
+			out = append(out, result{svc.name + " webhook", statusSkip, "no public URL; pass -" + svc.name + "-url or set " + strings.ToUpper(svc.name) + "_PUBLIC_URL"})
+			continue
+		}
+		configured := false
+		for _, s := range secrets {
+			if s.secret == "" {
+				continue
+			}
+			configured = true
+			url := strings.TrimRight(svc.baseURL, "/") + "/webhook/" + s.platform.String()
+			out = append(out, pingWebhook(ctx, svc.name+" webhook", url, s.platform, s.secret))
+		}
+		if !configured {
+			out = append(out, result{svc.name + " webhook", statusWarn, "no webhook secret set; the services accept unsigned webhooks"})
+		}
+	}
+	return out
+}
+
+func pingWebhook(ctx context.Context, name, url string, platform git.Platform, secret string) result {
+	body := []byte(`{"zen":"droid doctor","object_kind":"ping"}`)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return result{name, statusFail, err.Error()}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if platform == git.PlatformGitLab {
+		req.Header.Set("X-Gitlab-Event", "Ping Hook")
+		req.Header.Set("X-Gitlab-Token", secret)
+	} else {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		req.Header.Set("X-GitHub-Event", "ping")
+		req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return result{name, statusFail, fmt.Sprintf("%s unreachable: %v", url, err)}
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return result{name, statusFail, fmt.Sprintf("%s rejected the %s secret", url, platform)}
+	case resp.StatusCode >= 300:
+		return result{name, statusFail, fmt.Sprintf("%s answered %s", url, resp.Status)}
+	}
+	return result{name, statusOK, url + " accepted a signed " + platform.String() + " delivery"}
+}
+
+// checkStorage checks that every data path the services use is writable and
+// that the executor's queue can be read.
+func checkStorage() []result {
+	out := []result{checkQueue(envOr("EXECUTOR_QUEUE_DIR", "data/executor-queue"))}
+
+	dirs := []struct{ name, env, def string }{
+		{"executor attempts", "EXECUTOR_ATTEMPTS_DIR", "data/executor-attempts"},
+		{"executor pauses", "EXECUTOR_PAUSES_DIR", "data/executor-pauses"},
+		{"executor dry runs", "EXECUTOR_DRY_RUNS_DIR", "data/executor-dry-runs"},
+		{"executor artifacts", "EXECUTOR_ARTIFACTS_DIR", "data/executor-artifacts"},
+		{"executor deliveries", "EXECUTOR_DELIVERIES_FILE", "data/executor-deliveries.json"},
+		{"executor analytics", "EXECUTOR_ANALYTICS_FILE", "data/executor-analytics.json"},
+		{"reviewer calibration", "REVIEWER_CALIBRATION_FILE", "data/reviewer-calibration.json"},
+		{"reviewer history", "REVIEWER_HISTORY_FILE", "data/reviewer-history.json"},
+	}
+	for _, d := range dirs {
+		p := envOr(d.env, d.def)
+		if p == "off" {
+			out = append(out, result{d.name, statusSkip, d.env + " is off"})
+			continue
+		}
+		dir := p
+		if strings.HasSuffix(p, ".json") {
+			dir = filepath.Dir(p)
+		}
+		if err := writable(dir); err != nil {
+			out = append(out, result{d.name, statusFail, err.Error()})
+			continue
+		}
+		out = append(out, result{d.name, statusOK, p})
+	}
+	return out
+}
+
+func checkQueue(dir string) result {
+	store, err := queue.NewFileStore(dir)
+	if err != nil {
+		return result{"executor queue", statusFail, err.Error()}
+	}
+	jobs, err := store.List()
+	if err != nil {
+		return result{"executor queue", statusFail, err.Error()}
+	}
+	if err := writable(dir); err != nil {
+		return result{"executor queue", statusFail, err.Error()}
+	}
+	return result{"executor queue", statusOK, fmt.Sprintf("%s, %d pending job(s)", dir, len(jobs))}
+}
+
+// writable creates dir if needed and writes and removes a file in it.
+func writable(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		return fmt.Errorf("%s is not writable: %w", dir, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// status is the outcome of one check.
+type status string
+
+const (
+	statusOK   status = "ok"
+	statusWarn status = "warn" // works, but something needs attention
+	statusFail status = "FAIL"
+	statusSkip status = "skip" // not configured
+)
+
+// result is one line of the readiness report.
+type result struct {
+	check  string
+	status status
+	detail string
+}
+
+// doctor checks a droid deployment end to end — the Anthropic API, Slack,
+// the git host tokens, webhook delivery to the executor and reviewer, and
+// the data directories — with the same environment the services run with,
+// and prints a readiness report. It exits non-zero if any check fails, so
+// it can gate an install or upgrade.
+func main() {
+	repoURL := flag.String("repo", "", "repository to check token access and webhook events on, e.g. https://github.com/org/repo")
+	executorURL := flag.String("executor-url", os.Getenv("EXECUTOR_PUBLIC_URL"), "public base URL of the executor")
+	reviewerURL := flag.String("reviewer-url", os.Getenv("REVIEWER_PUBLIC_URL"), "public base URL of the reviewer")
+	timeout := flag.Duration("timeout", 30*time.Second, "time limit for each check")
+	flag.Parse()
+
+	checks := []func(context.Context) []result{
+		checkAnthropic,
+		checkSlack,
+		func(ctx context.Context) []result { return checkGitHosts(ctx, *repoURL) },
+		func(ctx context.Context) []result { return checkWebhooks(ctx, *executorURL, *reviewerURL) },
+		func(context.Context) []result { return checkStorage() },
+	}
+
+	var results []result
+	for _, check := range checks {
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		results = append(results, check(ctx)...)
+		cancel()
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	failed := 0
+	for _, r := range results {
+		fmt.Fprintf(tw, "[%s]\t%s\t%s\n", r.status, r.check, r.detail)
+		if r.status == statusFail {
+			failed++
+		}
+	}
+	tw.Flush()
+
+	if failed > 0 {
+		fmt.Printf("\n%d check(s) failed.\n", failed)
+		os.Exit(1)
+	}
+	fmt.Println("\nReady.")
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// splitEnv returns the non-empty, comma-separated values of key.
+func splitEnv(key string) []string {
+	var out []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jadenj13/droid/internals/app"
+)
+
+const usage = `usage: droid serve
+
+Runs the planner, executor and reviewer in one process, configured by the
+same environment as the separate services. They share one LLM client and
+one HTTP listener on DROID_ADDR (default :8080), with each service's routes
+under its name: /executor/webhook/github, /reviewer/webhook/gitlab, and so on.
+`
+
+// droid is the single-binary deployment for small teams that do not want to
+// run the three services separately.
+func main() {
+	if len(os.Args) != 2 || os.Args[1] != "serve" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	shared := app.NewShared()
+	shared.Serve(app.EnvOr("DROID_ADDR", ":8080"),
+		app.Planner(shared),
+		app.Executor(shared),
+		app.Reviewer(shared),
+	)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/repoconfig"
+)
+
+// configBranch is the branch the starter .droid.yml is proposed on.
+const configBranch = "agent/onboard-droid-config"
+
+// stack is a build toolchain recognised by its marker file.
+type stack struct {
+	marker string
+	setup  string
+	build  string
+	test   string
+	lint   string
+}
+
+// stacks are tried in order; the first whose marker exists wins.
+var stacks = []stack{
+	{marker: "go.mod", setup: "go mod download", build: "go build ./...", test: "go test ./...", lint: "go vet ./..."},
+	{marker: "package.json", setup: "npm ci", build: "npm run build --if-present", test: "npm test", lint: "npm run lint --if-present"},
+	{marker: "Cargo.toml", setup: "cargo fetch", build: "cargo build", test: "cargo test", lint: "cargo clippy"},
+	{marker: "pyproject.toml", setup: "pip install -e .", test: "pytest"},
+	{marker: "requirements.txt", setup: "pip install -r requirements.txt", test: "pytest"},
+}
+
+// openConfigPR opens a PR adding a starter .droid.yml to the default branch
+// and returns its URL, or "" when the repository already has a config or an
+// open onboarding PR.
+func openConfigPR(ctx context.Context, provider git.GitProvider, info git.RepoInfo, token string, network *git.Network) (string, error) {
+	if n, err := provider.FindOpenPR(ctx, configBranch); err != nil {
+		return "", err
+	} else if n != 0 {
+		return "", nil
+	}
+
+	repo, err := git.Clone(ctx, provider.RepoURL(), token, git.WithCloneNetwork(network))
+	if err != nil {
+		return "", err
+	}
+	defer repo.Cleanup()
+
+	if _, err := os.Stat(filepath.Join(repo.Dir(), repoconfig.FileName)); err == nil {
+		return "", nil
+	}
+	base, err := repo.CurrentBranch(ctx)
+	if err != nil {
+		return "", fmt.Errorf("resolve default branch: %w", err)
+	}
+
+	if err := repo.CreateBranch(ctx, configBranch); err != nil {
+		return "", fmt.Errorf("create branch: %w", err)
+	}
+	if err := repo.WriteFile(repoconfig.FileName, starterConfig(detectStack(repo.Dir()))); err != nil {
+		return "", err
+	}
+	if err := repo.Add(ctx); err != nil {
+		return "", err
+	}
+	if _, err := repo.Commit(ctx, "Add starter "+repoconfig.FileName); err != nil {
+		return "", fmt.Errorf("commit: %w", err)
+	}
+	if err := repo.Push(ctx); err != nil {
+		return "", fmt.Errorf("push: %w", err)
+	}
+
+	return provider.OpenPR(ctx, git.PRInput{
+		Title:  "Add droid configuration",
+		Branch: configBranch,
+		Base:   base,
+		Body: "This adds a starter `" + repoconfig.FileName + "` so the droid agents know how to set up, build, test and lint this repository.\n\n" +
+			"The commands were guessed from the files in the repository. Check them, uncomment anything else you want to tune, and merge.\n",
+	})
+}
+
+// detectStack returns the toolchain of the checkout at dir, or a zero stack.
+func detectStack(dir string) stack {
+	for _, s := range stacks {
+		if _, err := os.Stat(filepath.Join(dir, s.marker)); err == nil {
+			return s
+		}
+	}
+	return stack{}
+}
+
+// starterConfig renders a .droid.yml with the detected commands filled in
+// and the other keys commented out.
+func starterConfig(s stack) string {
+	line := func(key, cmd string) string {
+		if cmd == "" {
+			return fmt.Sprintf("  # %s: \n", key)
+		}
+		return fmt.Sprintf("  %s: %s\n", key, cmd)
+	}
+	out := "# droid agent configuration; every key is optional.\n\n" +
+		"commands:                     # run by the executor to verify its work\n" +
+		line("build", s.build) + line("test", s.test) + line("lint", s.lint)
+	if s.setup != "" {
+		out += "setup:                        # run before the executor starts, e.g. to install dependencies\n  - " + s.setup + "\n"
+	} else {
+		out += "# setup:                      # run before the executor starts, e.g. to install dependencies\n#   - make deps\n"
+	}
+	return out + `
+# base_branch: main           # branch the executor starts from and targets with its PR
+# max_iterations: 30          # executor tool-loop limit
+# budget:                     # per-job budget
+#   usd: 5
+#   duration: 45m
+# protected_paths:            # the executor refuses to modify these
+#   - .github/**
+# review_rubric: |
+#   - Every new endpoint needs an integration test
+`
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/jadenj13/droid/internals/git"
+)
+
+// labels are the agent labels the services set and react to.
+var labels = []struct {
+	name, color, description string
+}{
+	{"agent:ready", "0e8a16", "Ready for the executor to implement"},
+	{"agent:review", "1d76db", "PR opened by the executor, awaiting review"},
+	{"agent:revision", "fbca04", "Reviewer requested changes; the executor reworks the PR"},
+	{"agent:approved", "5319e7", "Approved by the reviewer"},
+	{"agent:needs-human", "b60205", "The reviewer escalated this PR for a human decision"},
+	{"agent:failed", "d93f0b", "The executor could not complete the issue"},
+	{"agent:blocked", "cfd3d7", "Waits for the issues it depends on to close before the executor starts it"},
+	{"agent:tracking", "c5def5", "Tracking issue for a multi-issue plan"},
+	{"agent:budget-exceeded", "e99695", "The executor stopped at its budget; a draft PR holds the work so far"},
+	{"agent:dry-run", "bfd4f2", "Run the executor without pushing; the patch is posted for approval"},
+	{"agent:apply", "0e8a16", "Push the approved dry-run patch and open its PR"},
+	{"agent:needs-input", "d876e3", "The executor paused on a question; answer with /droid answer"},
+}
+
+// onboard prepares a repository to be driven by droid: it checks the token's
+// access, creates the agent labels, registers the executor and reviewer
+// webhooks, and opens a PR adding a starter .droid.yml. Re-running it is
+// safe: labels and hooks are updated in place, and the config PR is only
+// opened while the repository has no .droid.yml.
+func main() {
+	log := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+	}))
+
+	repoURL := flag.String("repo", "", "repository URL, e.g. https://github.com/org/repo")
+	executorURL := flag.String("executor-url", os.Getenv("EXECUTOR_PUBLIC_URL"), "public base URL of the executor, e.g. https://droid.example.com:8080")
+	reviewerURL := flag.String("reviewer-url", os.Getenv("REVIEWER_PUBLIC_URL"), "public base URL of the reviewer, e.g. https://droid.example.com:8081")
+	skipConfig := flag.Bool("skip-config", false, "do not open a PR adding a starter .droid.yml")
+	flag.Parse()
+
+	if *repoURL == "" || *executorURL == "" || *reviewerURL == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	gitNetwork, err := git.NewNetwork(os.Getenv("GIT_HOST_CA_FILE"), os.Getenv("GIT_HOST_PROXY"))
+	if err != nil {
+		log.Error("invalid git host network settings", "err", err)
+		os.Exit(1)
+	}
+	factory := git.NewFactory(os.Getenv("GITHUB_TOKEN"), os.Getenv("GITLAB_TOKEN"), git.WithNetwork(gitNetwork))
+
+	ctx := context.Background()
+	provider, info, err := factory.ProviderFor(ctx, *repoURL)
+	if err != nil {
+		log.Error("build provider", "err", err)
+		os.Exit(1)
+	}
+
+	token, secret := os.Getenv("GITHUB_TOKEN"), os.Getenv("GITHUB_WEBHOOK_SECRET")
+	if info.Platform == git.PlatformGitLab {
+		token, secret = os.Getenv("GITLAB_TOKEN"), os.Getenv("GITLAB_WEBHOOK_SECRET")
+	}
+	if secret == "" {
+		log.Error("no webhook secret set; without one the services accept unsigned webhooks", "platform", info.Platform.String())
+		os.Exit(1)
+	}
+
+	if err := checkPermission(ctx, provider, info.Platform); err != nil {
+		log.Error("token permission check failed", "repo", info.RawURL, "err", err)
+		os.Exit(1)
+	}
+	log.Info("token permissions verified", "repo", info.RawURL)
+
+	// The review labels can be renamed; create them under the names the
+	// services will use.
+	renamed := map[string]string{
+		"agent:review":      os.Getenv("LABEL_REVIEW"),
+		"agent:revision":    os.Getenv("LABEL_REVISION"),
+		"agent:approved":    os.Getenv("LABEL_APPROVED"),
+		"agent:needs-human": os.Getenv("LABEL_NEEDS_HUMAN"),
+	}
+	for _, l := range labels {
+		name := cmp.Or(renamed[l.name], l.name)
+		if err := provider.EnsureLabel(ctx, name, l.color, l.description); err != nil {
+			log.Error("ensure label", "label", name, "err", err)
+			os.Exit(1)
+		}
+	}
+	log.Info("labels created", "count", len(labels), "repo", info.RawURL)
+
+	hooks := []struct {
+		service string
+		baseURL string
+		events  []git.WebhookEvent
+	}{
+		{"executor", *executorURL, []git.WebhookEvent{git.WebhookEventIssues, git.WebhookEventPullRequests, git.WebhookEventComments}},
+		{"reviewer", *reviewerURL, []git.WebhookEvent{git.WebhookEventPullRequests, git.WebhookEventComments}},
+	}
+
+	for _, h := range hooks {
+		url := strings.TrimRight(h.baseURL, "/") + "/webhook/" + info.Platform.String()
+		if err := provider.EnsureWebhook(ctx, url, secret, h.events); err != nil {
+			log.Error("ensure webhook", "service", h.service, "url", url, "err", err)
+			os.Exit(1)
+		}
+		log.Info("webhook registered", "service", h.service, "url", url, "repo", info.RawURL)
+	}
+
+	if *skipConfig {
+		return
+	}
+	prURL, err := openConfigPR(ctx, provider, info, token, gitNetwork)
+	if err != nil {
+		log.Error("open starter .droid.yml PR", "err", err)
+		os.Exit(1)
+	}
+	if prURL == "" {
+		log.Info("repository already has a .droid.yml or an open onboarding PR", "repo", info.RawURL)
+		return
+	}
+	log.Info("starter .droid.yml PR opened", "url", prURL)
+}
+
+// checkPermission fails unless the token can do everything droid needs:
+// write to push branches, label issues and open PRs, and the access the
+// platform requires to manage webhooks — admin on GitHub, maintainer on
+// GitLab.
+func checkPermission(ctx context.Context, provider git.GitProvider, platform git.Platform) error {
+	have, err := provider.TokenPermission(ctx)
+	if err != nil {
+		return err
+	}
+	need := git.PermissionAdmin
+	if platform == git.PlatformGitLab {
+		need = git.PermissionMaintain
+	}
+	if have < need {
+		return fmt.Errorf("the token has %s access, but registering webhooks needs %s (the services themselves need write)", have, need)
+	}
+	return nil
+}
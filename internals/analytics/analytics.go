@@ -0,0 +1,288 @@
+package analytics
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jadenj13/droid/internals/retention"
+)
+
+// Run is the lifecycle of one agent-handled issue, from the moment it was
+// labeled for the executor to the merge (and possible revert) of its PR.
+type Run struct {
+	RepoURL    string    `json:"repo_url"`
+	Issue      int       `json:"issue"`
+	QueuedAt   time.Time `json:"queued_at"`
+	PRNumber   int       `json:"pr_number,omitempty"`
+	PRTitle    string    `json:"pr_title,omitempty"`
+	OpenedAt   time.Time `json:"opened_at,omitzero"`
+	MergedAt   time.Time `json:"merged_at,omitzero"`
+	RevertedAt time.Time `json:"reverted_at,omitzero"`
+	Failures   int       `json:"failures,omitempty"` // runs that failed for good
+	Tokens     int64     `json:"tokens,omitempty"`   // LLM tokens across every run
+	CostUSD    float64   `json:"cost_usd,omitempty"` // list-price LLM cost across every run
+}
+
+// Store persists runs in a single JSON file.
+type Store struct {
+	path string
+
+	mu   sync.Mutex
+	runs map[string]*Run // key: repo URL + "#" + issue
+}
+
+func NewStore(path string) (*Store, error) {
+	s := &Store{path: path, runs: make(map[string]*Run)}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read analytics file: %w", err)
+	}
+	if err := json.Unmarshal(b, &s.runs); err != nil {
+		return nil, fmt.Errorf("decode analytics file: %w", err)
+	}
+	return s, nil
+}
+
+func runKey(repoURL string, issue int) string {
+	return fmt.Sprintf("%s#%d", strings.TrimSuffix(repoURL, "/"), issue)
+}
+
+// update applies f to the run for repoURL and issue, creating it if needed,
+// and saves the store.
+func (s *Store) update(repoURL string, issue int, f func(r *Run)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := runKey(repoURL, issue)
+	r, ok := s.runs[key]
+	if !ok {
+		r = &Run{RepoURL: strings.TrimSuffix(repoURL, "/"), Issue: issue}
+		s.runs[key] = r
+	}
+	f(r)
+	return s.save()
+}
+
+// IssueQueued records when an issue was handed to the executor. Requeues keep
+// the original time, so lead time covers every attempt.
+func (s *Store) IssueQueued(repoURL string, issue int, at time.Time) error {
+	return s.update(repoURL, issue, func(r *Run) {
+		if r.QueuedAt.IsZero() {
+			r.QueuedAt = at
+		}
+	})
+}
+
+func (s *Store) PROpened(repoURL string, issue, prNumber int, title string, at time.Time) error {
+	return s.update(repoURL, issue, func(r *Run) {
+		r.PRNumber, r.PRTitle = prNumber, title
+		if r.OpenedAt.IsZero() {
+			r.OpenedAt = at
+		}
+	})
+}
+
+func (s *Store) RunFailed(repoURL string, issue int) error {
+	return s.update(repoURL, issue, func(r *Run) { r.Failures++ })
+}
+
+// AddUsage adds the LLM tokens and cost of one executor run.
+func (s *Store) AddUsage(repoURL string, issue int, tokens int64, costUSD float64) error {
+	return s.update(repoURL, issue, func(r *Run) {
+		r.Tokens += tokens
+		r.CostUSD += costUSD
+	})
+}
+
+// Get returns the run for repoURL and issue.
+func (s *Store) Get(repoURL string, issue int) (Run, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.runs[runKey(repoURL, issue)]
+	if !ok {
+		return Run{}, false
+	}
+	return *r, true
+}
+
+func (s *Store) PRMerged(repoURL string, issue int, at time.Time) error {
+	return s.update(repoURL, issue, func(r *Run) { r.MergedAt = at })
+}
+
+// PRReverted marks the merged agent PR whose title is revertedTitle as
+// reverted. It reports whether a matching PR was found.
+func (s *Store) PRReverted(repoURL, revertedTitle string, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repoURL = strings.TrimSuffix(repoURL, "/")
+	for _, r := range s.runs {
+		if r.RepoURL == repoURL && r.PRTitle == revertedTitle && !r.MergedAt.IsZero() && r.RevertedAt.IsZero() {
+			r.RevertedAt = at
+			return true, s.save()
+		}
+	}
+	return false, nil
+}
+
+// RevertedTitle extracts the original title from a revert PR title as
+// written by GitHub and GitLab: Revert "Original title".
+func RevertedTitle(title string) (string, bool) {
+	rest, ok := strings.CutPrefix(title, `Revert "`)
+	if !ok || !strings.HasSuffix(rest, `"`) {
+		return "", false
+	}
+	return strings.TrimSuffix(rest, `"`), true
+}
+
+func (s *Store) save() error {
+	b, err := json.MarshalIndent(s.runs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal analytics: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("create analytics dir: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return fmt.Errorf("write analytics: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// RepoMetrics are DORA-style metrics for one repository over a window.
+type RepoMetrics struct {
+	RepoURL string `json:"repo_url"`
+	// Throughput is the number of agent PRs merged in the window.
+	Throughput    int     `json:"throughput"`
+	MergedPerWeek float64 `json:"merged_per_week"`
+	// Lead time runs from the issue being labeled for the executor to the
+	// merge of its PR.
+	LeadTimeMedianHours float64 `json:"lead_time_median_hours"`
+	LeadTimeP90Hours    float64 `json:"lead_time_p90_hours"`
+	// ChangeFailureRate is the share of merged agent PRs later reverted.
+	ChangeFailureRate float64 `json:"change_failure_rate"`
+	Reverted          int     `json:"reverted"`
+	FailedRuns        int     `json:"failed_runs"`
+	Open              int     `json:"open"` // queued or in review, not yet merged
+}
+
+type Report struct {
+	Since time.Time     `json:"since"`
+	Until time.Time     `json:"until"`
+	Repos []RepoMetrics `json:"repos"`
+	Total RepoMetrics   `json:"total"`
+}
+
+// Report computes metrics for runs merged or still open within [since, now].
+func (s *Store) Report(since, now time.Time) Report {
+	s.mu.Lock()
+	byRepo := make(map[string][]Run)
+	var all []Run
+	for _, r := range s.runs {
+		byRepo[r.RepoURL] = append(byRepo[r.RepoURL], *r)
+		all = append(all, *r)
+	}
+	s.mu.Unlock()
+
+	rep := Report{Since: since, Until: now}
+	for repo, runs := range byRepo {
+		m := metrics(runs, since, now)
+		m.RepoURL = repo
+		rep.Repos = append(rep.Repos, m)
+	}
+	sort.Slice(rep.Repos, func(i, j int) bool { return rep.Repos[i].RepoURL < rep.Repos[j].RepoURL })
+	rep.Total = metrics(all, since, now)
+	return rep
+}
+
+func metrics(runs []Run, since, now time.Time) RepoMetrics {
+	var m RepoMetrics
+	var leadTimes []float64
+	for _, r := range runs {
+		if r.MergedAt.IsZero() {
+			if !r.QueuedAt.IsZero() && r.QueuedAt.After(since) {
+				m.Open++
+			}
+			m.FailedRuns += r.Failures
+			continue
+		}
+		if r.MergedAt.Before(since) || r.MergedAt.After(now) {
+			continue
+		}
+		m.Throughput++
+		m.FailedRuns += r.Failures
+		if !r.RevertedAt.IsZero() {
+			m.Reverted++
+		}
+		if !r.QueuedAt.IsZero() {
+			leadTimes = append(leadTimes, r.MergedAt.Sub(r.QueuedAt).Hours())
+		}
+	}
+
+	if weeks := now.Sub(since).Hours() / (24 * 7); weeks > 0 {
+		m.MergedPerWeek = float64(m.Throughput) / weeks
+	}
+	if m.Throughput > 0 {
+		m.ChangeFailureRate = float64(m.Reverted) / float64(m.Throughput)
+	}
+	m.LeadTimeMedianHours = percentile(leadTimes, 0.5)
+	m.LeadTimeP90Hours = percentile(leadTimes, 0.9)
+	return m
+}
+
+// percentile returns the nearest-rank percentile of values, or 0 if empty.
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	i := int(p*float64(len(sorted))+0.5) - 1
+	return sorted[min(max(i, 0), len(sorted)-1)]
+}
+
+// Name implements retention.Source.
+func (s *Store) Name() string { return "executor_analytics" }
+
+// ExportData returns the runs f selects, matched on when the issue was
+// queued. Runs hold no Slack user data, so a user filter matches none.
+func (s *Store) ExportData(f retention.Filter) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []Run{}
+	for _, r := range s.runs {
+		if f.Match(r.RepoURL, nil, r.QueuedAt) {
+			out = append(out, *r)
+		}
+	}
+	return out, nil
+}
+
+// DeleteData removes the runs f selects.
+func (s *Store) DeleteData(f retention.Filter) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for key, r := range s.runs {
+		if f.Match(r.RepoURL, nil, r.QueuedAt) {
+			delete(s.runs, key)
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	return n, s.save()
+}
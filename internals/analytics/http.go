@@ -0,0 +1,49 @@
+package analytics
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultWindowDays is the reporting window when ?days= is not given.
+const defaultWindowDays = 30
+
+// Handler serves the report as JSON. ?days=N sets the window (default 30).
+// Every request needs "Authorization: Bearer <token>".
+func Handler(s *Store, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, token) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		days := defaultWindowDays
+		if v := r.URL.Query().Get("days"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				http.Error(w, "days must be a positive integer", http.StatusBadRequest)
+				return
+			}
+			days = n
+		}
+
+		now := time.Now()
+		report := s.Report(now.AddDate(0, 0, -days), now)
+
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+	})
+}
+
+func authorized(r *http.Request, token string) bool {
+	if token == "" {
+		return false
+	}
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
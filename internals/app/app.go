@@ -0,0 +1,122 @@
+// Package app wires the planner, executor and reviewer from the environment.
+// The per-service binaries run one service each; cmd/droid runs all three in
+// one process on top of a single Shared. The builders run at startup only and
+// exit the process on invalid settings.
+package app
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/jadenj13/droid/internals/chaos"
+	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/llm"
+	"github.com/jadenj13/droid/internals/redact"
+	"github.com/jadenj13/droid/internals/standards"
+)
+
+// Shared is what the services of one process have in common: logging and
+// secret redaction, one LLM client — so its connection pool, API key pool and
+// rate-limit backoff are shared — the git host network, chaos injection and
+// the coding standards library.
+type Shared struct {
+	Log      *slog.Logger
+	Redactor *redact.Redactor
+	LLM      *llm.Client
+	Network  *git.Network
+	Chaos    *chaos.Injector
+
+	StandardsStore *standards.Store   // nil without STANDARDS_DIR
+	Standards      *standards.Library // nil without STANDARDS_DIR
+}
+
+// NewShared builds the shared dependencies from the environment.
+func NewShared() *Shared {
+	// Mask tokens and keys from the environment, plus anything shaped like a
+	// secret, in logs, command output shown to the model, and PR bodies.
+	redactor := redact.New(redact.EnvSecrets()...)
+	log := slog.New(redact.NewHandler(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+	}), redactor))
+
+	s := &Shared{Log: log, Redactor: redactor, Chaos: openChaos(log)}
+
+	llmOpts, err := llm.TransportConfig{
+		BaseURL:  os.Getenv("ANTHROPIC_BASE_URL"),
+		Proxy:    os.Getenv("ANTHROPIC_PROXY"),
+		Headers:  os.Getenv("ANTHROPIC_HEADERS"),
+		CAFile:   os.Getenv("ANTHROPIC_CA_FILE"),
+		CertFile: os.Getenv("ANTHROPIC_CLIENT_CERT"),
+		KeyFile:  os.Getenv("ANTHROPIC_CLIENT_KEY"),
+	}.Options()
+	if err != nil {
+		fail(log, "invalid Anthropic transport settings", "err", err)
+	}
+	if s.Chaos != nil {
+		llmOpts = append(llmOpts, llm.WithTransport(s.Chaos.LLMTransport))
+	}
+	s.LLM = llm.NewClient(mustEnv("ANTHROPIC_API_KEY"), append(llmOpts,
+		llm.WithMaxTokens(16000),
+		llm.WithAPIKeys(strings.Split(os.Getenv("ANTHROPIC_API_KEYS"), ",")...),
+	)...)
+
+	s.Network, err = git.NewNetwork(os.Getenv("GIT_HOST_CA_FILE"), os.Getenv("GIT_HOST_PROXY"))
+	if err != nil {
+		fail(log, "invalid git host network settings", "err", err)
+	}
+	s.StandardsStore, s.Standards = openStandards(log)
+	return s
+}
+
+// Factory returns a provider factory on the shared network, with chaos
+// injection when it is on.
+func (s *Shared) Factory(githubToken, gitlabToken string, opts ...git.FactoryOption) *git.Factory {
+	opts = append([]git.FactoryOption{git.WithNetwork(s.Network)}, opts...)
+	if s.Chaos != nil {
+		opts = append(opts, git.WithTransport(s.Chaos.ProviderTransport))
+	}
+	return git.NewFactory(githubToken, gitlabToken, opts...)
+}
+
+// openStandards opens the shared coding standards store named by
+// STANDARDS_DIR, or returns nils when it is not set.
+func openStandards(log *slog.Logger) (*standards.Store, *standards.Library) {
+	dir := os.Getenv("STANDARDS_DIR")
+	if dir == "" {
+		return nil, nil
+	}
+	store, err := standards.NewStore(dir)
+	if err != nil {
+		fail(log, "open standards store", "err", err)
+	}
+	var retriever standards.Retriever
+	if url := os.Getenv("STANDARDS_EMBEDDINGS_URL"); url != "" {
+		retriever = standards.NewEmbeddingRetriever(standards.HTTPEmbedder{
+			URL:    url,
+			Model:  os.Getenv("STANDARDS_EMBEDDINGS_MODEL"),
+			APIKey: os.Getenv("STANDARDS_EMBEDDINGS_KEY"),
+		})
+	}
+	return store, standards.NewLibrary(store, retriever, log)
+}
+
+// openChaos returns a fault injector when CHAOS_MODE=on, for staging, or nil.
+func openChaos(log *slog.Logger) *chaos.Injector {
+	if os.Getenv("CHAOS_MODE") != "on" {
+		return nil
+	}
+	cfg, err := chaos.Settings{
+		LLMFailRate:      os.Getenv("CHAOS_LLM_FAIL_RATE"),
+		ProviderFailRate: os.Getenv("CHAOS_PROVIDER_FAIL_RATE"),
+		ToolDelayRate:    os.Getenv("CHAOS_TOOL_DELAY_RATE"),
+		ToolDelay:        os.Getenv("CHAOS_TOOL_DELAY"),
+	}.Config()
+	if err != nil {
+		fail(log, "invalid chaos settings", "err", err)
+	}
+	log.Warn("CHAOS MODE ON: injecting LLM and provider failures — never use in production",
+		"llm_fail_rate", cfg.LLMFailRate, "provider_fail_rate", cfg.ProviderFailRate,
+		"tool_delay_rate", cfg.ToolDelayRate, "tool_delay", cfg.ToolDelay)
+	return chaos.New(cfg, log)
+}
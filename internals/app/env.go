@@ -0,0 +1,122 @@
+package app
+
+import (
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jadenj13/droid/internals/reviewer"
+	"github.com/jadenj13/droid/internals/tracker"
+)
+
+// fail logs a startup error and exits.
+func fail(log *slog.Logger, msg string, args ...any) {
+	log.Error(msg, args...)
+	os.Exit(1)
+}
+
+func mustEnv(key string) string {
+	v := os.Getenv(key)
+	if v == "" {
+		fail(slog.Default(), "missing required env var", "key", key)
+	}
+	return v
+}
+
+// EnvOr returns the value of key, or def when it is unset.
+func EnvOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func envInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		fail(slog.Default(), "invalid integer env var", "key", key, "value", v, "err", err)
+	}
+	return n
+}
+
+func envFloat(key string, def float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 {
+		fail(slog.Default(), "invalid number env var", "key", key, "value", v)
+	}
+	return f
+}
+
+func envDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		fail(slog.Default(), "invalid duration", "key", key, "value", v, "err", err)
+	}
+	return d
+}
+
+// splitList parses a comma-separated env value, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// reviewLabels returns the review handoff labels, renamed by LABEL_REVIEW,
+// LABEL_REVISION, LABEL_APPROVED and LABEL_NEEDS_HUMAN. Every service reads
+// the same variables, so the labels one sets are the ones another watches.
+func reviewLabels() reviewer.Labels {
+	d := reviewer.DefaultLabels()
+	return reviewer.Labels{
+		Review:     EnvOr("LABEL_REVIEW", d.Review),
+		Revision:   EnvOr("LABEL_REVISION", d.Revision),
+		Approved:   EnvOr("LABEL_APPROVED", d.Approved),
+		NeedsHuman: EnvOr("LABEL_NEEDS_HUMAN", d.NeedsHuman),
+	}
+}
+
+// trackers returns the trackers the planner files tickets in and the executor
+// links PRs from: Jira when JIRA_URL is set, Linear when LINEAR_API_KEY is.
+// Both services read the same variables.
+func trackers(log *slog.Logger) tracker.Set {
+	var set tracker.Set
+	if baseURL := os.Getenv("JIRA_URL"); baseURL != "" {
+		j, err := tracker.NewJira(tracker.JiraConfig{
+			BaseURL:   baseURL,
+			Email:     os.Getenv("JIRA_EMAIL"),
+			Token:     os.Getenv("JIRA_API_TOKEN"),
+			Project:   os.Getenv("JIRA_PROJECT"),
+			IssueType: os.Getenv("JIRA_ISSUE_TYPE"),
+		})
+		if err != nil {
+			fail(log, "invalid Jira settings", "err", err)
+		}
+		set = append(set, j)
+	}
+	if key := os.Getenv("LINEAR_API_KEY"); key != "" {
+		l, err := tracker.NewLinear(tracker.LinearConfig{APIKey: key, Team: os.Getenv("LINEAR_TEAM")})
+		if err != nil {
+			fail(log, "invalid Linear settings", "err", err)
+		}
+		set = append(set, l)
+	}
+	return set
+}
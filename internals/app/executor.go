@@ -0,0 +1,261 @@
+package app
+
+import (
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jadenj13/droid/internals/analytics"
+	"github.com/jadenj13/droid/internals/artifacts"
+	"github.com/jadenj13/droid/internals/executor"
+	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/queue"
+	"github.com/jadenj13/droid/internals/repoconfig"
+	"github.com/jadenj13/droid/internals/retention"
+	"github.com/jadenj13/droid/internals/sandbox"
+	"github.com/jadenj13/droid/internals/standards"
+)
+
+// Executor builds the implementation webhook server and its job queue,
+// listening on EXECUTOR_ADDR when run on its own.
+func Executor(s *Shared) *Service {
+	log := s.Log
+	githubToken := os.Getenv("GITHUB_TOKEN") // optional
+	gitlabToken := os.Getenv("GITLAB_TOKEN") // optional
+	githubSecret := os.Getenv("GITHUB_WEBHOOK_SECRET")
+	gitlabSecret := os.Getenv("GITLAB_WEBHOOK_SECRET")
+
+	pushStrategy, err := git.ParsePushStrategy(os.Getenv("EXECUTOR_PUSH_STRATEGY"))
+	if err != nil {
+		fail(log, "invalid EXECUTOR_PUSH_STRATEGY", "err", err)
+	}
+
+	cloneToken := githubToken
+	if cloneToken == "" {
+		cloneToken = gitlabToken
+	}
+
+	gitSSH, err := git.NewSSHAuth(git.SSHConfig{
+		Hosts:          strings.Split(os.Getenv("GIT_SSH_HOSTS"), ","),
+		KeyFile:        os.Getenv("GIT_SSH_KEY_FILE"),
+		Key:            os.Getenv("GIT_SSH_KEY"),
+		KnownHostsFile: EnvOr("GIT_SSH_KNOWN_HOSTS", "data/known_hosts"),
+		AcceptNewHosts: os.Getenv("GIT_SSH_ACCEPT_NEW_HOSTS") == "true",
+	}, EnvOr("GIT_SSH_DIR", "data/ssh"))
+	if err != nil {
+		fail(log, "invalid git SSH settings", "err", err)
+	}
+
+	agentOpts := []executor.AgentOption{
+		executor.WithPushStrategy(pushStrategy),
+		executor.WithGitNetwork(s.Network),
+		executor.WithGitSSH(gitSSH),
+		executor.WithRedactor(s.Redactor),
+		executor.WithCommandTimeouts(executor.CommandTimeouts{
+			Default: envDuration("EXECUTOR_COMMAND_TIMEOUT", executor.DefaultCommandTimeouts.Default),
+			Max:     envDuration("EXECUTOR_COMMAND_TIMEOUT_MAX", executor.DefaultCommandTimeouts.Max),
+		}),
+		executor.WithBudget(repoconfig.Budget{
+			USD:      envFloat("EXECUTOR_BUDGET_USD", 0),
+			Tokens:   int64(envInt("EXECUTOR_BUDGET_TOKENS", 0)),
+			Duration: envDuration("EXECUTOR_BUDGET_DURATION", 0),
+		}),
+		executor.WithCI(executor.CIConfig{
+			Timeout:   envDuration("EXECUTOR_CI_WAIT", 0),
+			FixRounds: envInt("EXECUTOR_CI_FIX_ROUNDS", 2),
+		}),
+		executor.WithDryRun(os.Getenv("EXECUTOR_DRY_RUN") == "true"),
+	}
+	if domains := os.Getenv("EXECUTOR_WEB_FETCH_DOMAINS"); domains != "" {
+		fetcher := executor.NewWebFetcher(strings.Split(domains, ","))
+		fetcher.MaxBytes = int64(envInt("EXECUTOR_WEB_FETCH_MAX_BYTES", int(fetcher.MaxBytes)))
+		agentOpts = append(agentOpts, executor.WithWebFetch(fetcher))
+	}
+	if os.Getenv("EXECUTOR_SANDBOX") == "docker" {
+		repoImages, err := sandbox.ParseRepoImages(os.Getenv("EXECUTOR_SANDBOX_REPO_IMAGES"))
+		if err != nil {
+			fail(log, "invalid EXECUTOR_SANDBOX_REPO_IMAGES", "err", err)
+		}
+		agentOpts = append(agentOpts, executor.WithSandbox(sandbox.Config{
+			DefaultImage: EnvOr("EXECUTOR_SANDBOX_IMAGE", "alpine:3.21"),
+			RepoImages:   repoImages,
+			Network:      os.Getenv("EXECUTOR_SANDBOX_NETWORK") == "true",
+		}))
+	}
+
+	factory := s.Factory(githubToken, gitlabToken)
+	if s.Standards != nil {
+		agentOpts = append(agentOpts, executor.WithStandards(s.Standards))
+	}
+	if s.Chaos != nil {
+		agentOpts = append(agentOpts, executor.WithChaos(s.Chaos))
+	}
+	artifactStore, artifactDir := openArtifacts(log)
+	if artifactStore != nil {
+		agentOpts = append(agentOpts, executor.WithArtifacts(artifactStore))
+	}
+	agent := executor.NewAgent(s.LLM, log, agentOpts...)
+	attempts, err := executor.NewAttemptStore(EnvOr("EXECUTOR_ATTEMPTS_DIR", "data/executor-attempts"))
+	if err != nil {
+		fail(log, "open attempt store", "err", err)
+	}
+	workerOpts := []executor.WorkerOption{
+		executor.WithAttemptStore(attempts),
+		executor.WithJobDeadline(envDuration("EXECUTOR_JOB_DEADLINE", time.Hour)),
+	}
+	var pauses *executor.PauseStore
+	if dir := EnvOr("EXECUTOR_PAUSES_DIR", "data/executor-pauses"); dir != "off" {
+		pauses, err = executor.NewPauseStore(dir)
+		if err != nil {
+			fail(log, "open pause store", "err", err)
+		}
+		workerOpts = append(workerOpts, executor.WithPauseStore(pauses))
+	}
+	var dryRuns *executor.DryRunStore
+	if dir := EnvOr("EXECUTOR_DRY_RUNS_DIR", "data/executor-dry-runs"); dir != "off" {
+		dryRuns, err = executor.NewDryRunStore(dir)
+		if err != nil {
+			fail(log, "open dry run store", "err", err)
+		}
+		workerOpts = append(workerOpts, executor.WithDryRunStore(dryRuns))
+	}
+	var slackNotifier *executor.SlackNotifier
+	if slackToken, slackChannel := os.Getenv("SLACK_BOT_TOKEN"), os.Getenv("SLACK_NOTIFY_CHANNEL"); slackToken != "" && slackChannel != "" {
+		slackNotifier = executor.NewSlackNotifier(slackToken, slackChannel)
+		workerOpts = append(workerOpts, executor.WithNotifier(slackNotifier))
+	}
+	var progressSinks []executor.ProgressSink
+	for _, target := range strings.Split(os.Getenv("EXECUTOR_PROGRESS"), ",") {
+		switch strings.TrimSpace(target) {
+		case "":
+		case "issue":
+			progressSinks = append(progressSinks, executor.IssueCommentSink{})
+		case "slack":
+			if slackNotifier == nil {
+				fail(log, "EXECUTOR_PROGRESS=slack needs SLACK_BOT_TOKEN and SLACK_NOTIFY_CHANNEL")
+			}
+			progressSinks = append(progressSinks, slackNotifier)
+		default:
+			fail(log, "invalid EXECUTOR_PROGRESS target — expected issue or slack", "target", target)
+		}
+	}
+	if len(progressSinks) > 0 {
+		workerOpts = append(workerOpts, executor.WithProgress(envDuration("EXECUTOR_PROGRESS_INTERVAL", 2*time.Minute), progressSinks...))
+	}
+	var metrics *analytics.Store
+	if path := EnvOr("EXECUTOR_ANALYTICS_FILE", "data/executor-analytics.json"); path != "off" {
+		metrics, err = analytics.NewStore(path)
+		if err != nil {
+			fail(log, "open analytics store", "err", err)
+		}
+		workerOpts = append(workerOpts, executor.WithAnalytics(metrics))
+	}
+	commandPermission, err := git.ParsePermission(EnvOr("EXECUTOR_COMMAND_PERMISSION", "write"))
+	if err != nil {
+		fail(log, "invalid EXECUTOR_COMMAND_PERMISSION", "err", err)
+	}
+	workerOpts = append(workerOpts, executor.WithCommandPolicy(commandPermission, splitList(os.Getenv("EXECUTOR_COMMAND_USERS"))...))
+	labels := reviewLabels()
+	workerOpts = append(workerOpts, executor.WithReviewLabels(labels.Review, labels.Revision, labels.Approved))
+	for _, t := range trackers(log) {
+		workerOpts = append(workerOpts, executor.WithTracker(t))
+	}
+	worker := executor.NewWorker(agent, *factory, cloneToken, log, workerOpts...)
+
+	store, err := queue.NewFileStore(EnvOr("EXECUTOR_QUEUE_DIR", "data/executor-queue"))
+	if err != nil {
+		fail(log, "open job store", "err", err)
+	}
+	jobs := queue.New(store, log,
+		queue.WithWorkers(envInt("EXECUTOR_CONCURRENCY", 2)),
+		queue.WithMaxAttempts(envInt("EXECUTOR_JOB_ATTEMPTS", 3)),
+		queue.WithGroupLimit(envInt("EXECUTOR_REPO_CONCURRENCY", 1), executor.JobRepo),
+		queue.WithDedup(executor.JobIssue),
+	)
+	worker.RegisterJobs(jobs)
+	webhookOpts := []executor.WebhookOption{executor.WithRevisionLabel(labels.Revision)}
+	if path := EnvOr("EXECUTOR_DELIVERIES_FILE", "data/executor-deliveries.json"); path != "off" {
+		deliveries, err := executor.NewDeliveryLog(path, envDuration("EXECUTOR_DELIVERY_TTL", 72*time.Hour))
+		if err != nil {
+			fail(log, "open delivery log", "err", err)
+		}
+		webhookOpts = append(webhookOpts, executor.WithDeliveryLog(deliveries))
+	}
+	if v := os.Getenv("LINEAR_REPOS"); v != "" {
+		repos, err := executor.ParseLinearRepos(v)
+		if err != nil {
+			fail(log, "invalid LINEAR_REPOS", "err", err)
+		}
+		webhookOpts = append(webhookOpts, executor.WithLinear(os.Getenv("LINEAR_WEBHOOK_SECRET"), repos))
+	}
+	webhook := executor.NewWebhookServer(jobs, githubSecret, gitlabSecret, log, webhookOpts...)
+
+	mux := http.NewServeMux()
+	mux.Handle("/webhook/", webhook.Handler())
+	if token := os.Getenv("EXECUTOR_STATS_TOKEN"); token != "" {
+		mux.Handle("GET /status", jobs.StatusHandler(token))
+		if metrics != nil {
+			mux.Handle("GET /analytics", analytics.Handler(metrics, token))
+		}
+	}
+	if s.StandardsStore != nil {
+		mux.Handle("/standards/", standards.Handler(s.StandardsStore, os.Getenv("STANDARDS_ADMIN_TOKEN")))
+	}
+	dataSources := []retention.Source{attempts}
+	if pauses != nil {
+		dataSources = append(dataSources, pauses)
+	}
+	if dryRuns != nil {
+		dataSources = append(dataSources, dryRuns)
+	}
+	if metrics != nil {
+		dataSources = append(dataSources, metrics)
+	}
+	if artifactDir != nil {
+		if token := os.Getenv("EXECUTOR_ARTIFACTS_TOKEN"); token != "" {
+			mux.Handle("GET /artifacts/", http.StripPrefix("/artifacts/", artifactDir.Handler(token)))
+		}
+		dataSources = append(dataSources, artifactDir)
+	}
+
+	return &Service{
+		Name:    "executor",
+		Addr:    EnvOr("EXECUTOR_ADDR", ":8080"),
+		Handler: mux,
+		Data:    dataSources,
+		Run:     jobs.Run,
+	}
+}
+
+// openArtifacts returns the store for run artifacts: an S3 bucket when
+// EXECUTOR_ARTIFACTS_S3_BUCKET is set, otherwise a local directory, which is
+// also returned so it can be served and expired. Both are nil when disabled.
+func openArtifacts(log *slog.Logger) (artifacts.Store, *artifacts.DirStore) {
+	if bucket := os.Getenv("EXECUTOR_ARTIFACTS_S3_BUCKET"); bucket != "" {
+		store, err := artifacts.NewS3Store(artifacts.S3Config{
+			Bucket:       bucket,
+			Region:       os.Getenv("AWS_REGION"),
+			Prefix:       os.Getenv("EXECUTOR_ARTIFACTS_S3_PREFIX"),
+			Endpoint:     os.Getenv("EXECUTOR_ARTIFACTS_S3_ENDPOINT"),
+			AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+			PublicURL:    os.Getenv("EXECUTOR_ARTIFACTS_URL"),
+		})
+		if err != nil {
+			fail(log, "invalid S3 artifact settings", "err", err)
+		}
+		return store, nil
+	}
+	dir := EnvOr("EXECUTOR_ARTIFACTS_DIR", "data/executor-artifacts")
+	if dir == "off" {
+		return nil, nil
+	}
+	store, err := artifacts.NewDirStore(dir, os.Getenv("EXECUTOR_ARTIFACTS_URL"))
+	if err != nil {
+		fail(log, "open artifact store", "err", err)
+	}
+	return store, store
+}
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jadenj13/droid/internals/planner"
+	"github.com/jadenj13/droid/internals/retention"
+	slackhandler "github.com/jadenj13/droid/internals/slack"
+)
+
+// Planner builds the Slack planning bot and its reminder scheduler. It serves
+// HTTP only for the /data API, at PLANNER_ADDR when DATA_ADMIN_TOKEN is set.
+func Planner(s *Shared) *Service {
+	log := s.Log
+	botToken := mustEnv("SLACK_BOT_TOKEN")
+	appToken := mustEnv("SLACK_APP_TOKEN")
+	// Either token is enough; set_repo accepts repositories on the hosts the
+	// planner has a token for.
+	githubToken := os.Getenv("GITHUB_TOKEN")
+	gitlabToken := os.Getenv("GITLAB_TOKEN")
+	if githubToken == "" && gitlabToken == "" {
+		fail(log, "set GITHUB_TOKEN, GITLAB_TOKEN or both")
+	}
+
+	factory := s.Factory(githubToken, gitlabToken)
+	var storeOpts []planner.SessionStoreOption
+	switch store := EnvOr("PLANNER_SESSION_STORE", "file"); store {
+	case "file":
+		backend, err := planner.NewFileSessionBackend(EnvOr("PLANNER_SESSIONS_DIR", "data/planner-sessions"))
+		if err != nil {
+			fail(log, "open session store", "err", err)
+		}
+		storeOpts = append(storeOpts, planner.WithSessionBackend(backend, factory))
+	case "redis":
+		backend, err := planner.NewRedisSessionBackend(mustEnv("PLANNER_REDIS_URL"), EnvOr("PLANNER_REDIS_PREFIX", "droid:planner:session:"))
+		if err != nil {
+			fail(log, "open session store", "err", err)
+		}
+		storeOpts = append(storeOpts, planner.WithSessionBackend(backend, factory))
+	case "memory":
+	default:
+		fail(log, "invalid PLANNER_SESSION_STORE — expected file, redis or memory", "value", store)
+	}
+	sessions := planner.NewSessionStore(log, storeOpts...)
+
+	gitUsers, err := planner.ParseGitUsers(os.Getenv("SLACK_GIT_USERS"))
+	if err != nil {
+		fail(log, "invalid SLACK_GIT_USERS", "err", err)
+	}
+	labels := reviewLabels()
+	agentOpts := []planner.AgentOption{
+		planner.WithGitUsers(gitUsers),
+		planner.WithReviewLabels(labels.Review, labels.Revision),
+		planner.WithReviewerURL(EnvOr("PLANNER_REVIEWER_URL", os.Getenv("REVIEWER_PUBLIC_URL"))),
+		planner.WithHistoryLimit(envInt("PLANNER_HISTORY_MESSAGES", 40)),
+	}
+	for _, t := range trackers(log) {
+		agentOpts = append(agentOpts, planner.WithTracker(t))
+	}
+	agent := planner.NewAgent(sessions, s.LLM, factory, log, agentOpts...)
+
+	replyMode, err := slackhandler.ParseReplyMode(os.Getenv("PLANNER_REPLY_MODE"))
+	if err != nil {
+		fail(log, "invalid PLANNER_REPLY_MODE", "err", err)
+	}
+
+	handlerOpts := []slackhandler.HandlerOption{
+		slackhandler.WithReplyMode(replyMode),
+		slackhandler.WithEphemeralNotices(os.Getenv("PLANNER_EPHEMERAL_NOTICES") == "true"),
+		slackhandler.WithSuppressPresence(os.Getenv("PLANNER_SUPPRESS_PRESENCE") != "false"),
+		slackhandler.WithUserRateLimit(envInt("PLANNER_USER_MESSAGES_PER_HOUR", 30), time.Hour),
+		slackhandler.WithChannelRateLimit(envInt("PLANNER_CHANNEL_MESSAGES_PER_HOUR", 120), time.Hour),
+	}
+	if v := os.Getenv("PLANNER_ALLOWED_CHANNELS"); v != "" {
+		handlerOpts = append(handlerOpts, slackhandler.WithAllowedChannels(strings.Split(v, ",")...))
+	}
+
+	handler, err := slackhandler.NewHandler(botToken, appToken, agent, log, handlerOpts...)
+	if err != nil {
+		fail(log, "failed to create slack handler", "err", err)
+	}
+
+	scheduler := planner.NewScheduler(sessions, handler, log)
+	scheduler.Interval = envDuration("PLANNER_REMINDER_INTERVAL", scheduler.Interval)
+	scheduler.StaleReady = envDuration("PLANNER_STALE_READY_AFTER", scheduler.StaleReady)
+	scheduler.StaleReview = envDuration("PLANNER_STALE_REVIEW_AFTER", scheduler.StaleReview)
+	scheduler.SessionTTL = envDuration("PLANNER_SESSION_TTL", scheduler.SessionTTL)
+	scheduler.ReviewLabel, scheduler.ApprovedLabel = labels.Review, labels.Approved
+
+	svc := &Service{
+		Name: "planner",
+		Data: []retention.Source{sessions},
+		Run: func(ctx context.Context) error {
+			go scheduler.Run(ctx)
+			return handler.Run(ctx)
+		},
+	}
+	if os.Getenv("DATA_ADMIN_TOKEN") != "" {
+		svc.Addr = os.Getenv("PLANNER_ADDR")
+	}
+	return svc
+}
@@ -0,0 +1,162 @@
+package app
+
+import (
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/repoconfig"
+	"github.com/jadenj13/droid/internals/retention"
+	"github.com/jadenj13/droid/internals/reviewer"
+	"github.com/jadenj13/droid/internals/sandbox"
+)
+
+// Reviewer builds the PR review webhook server, listening on REVIEWER_ADDR
+// when run on its own.
+func Reviewer(s *Shared) *Service {
+	log := s.Log
+	githubToken := os.Getenv("GITHUB_TOKEN")
+	gitlabToken := os.Getenv("GITLAB_TOKEN")
+	githubSecret := os.Getenv("GITHUB_WEBHOOK_SECRET")
+	gitlabSecret := os.Getenv("GITLAB_WEBHOOK_SECRET")
+	slackToken := mustEnv("SLACK_BOT_TOKEN")
+	slackChannel := mustEnv("SLACK_NOTIFY_CHANNEL") // e.g. "C01234ABCDE" (channel ID)
+
+	diffOpts := git.DefaultDiffOptions()
+	if v := os.Getenv("REVIEWER_DIFF_EXCLUDE"); v != "" {
+		diffOpts.Exclude = splitList(v)
+	}
+	if v := os.Getenv("REVIEWER_DIFF_MAX_FILE_BYTES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			fail(log, "invalid REVIEWER_DIFF_MAX_FILE_BYTES", "value", v, "err", err)
+		}
+		diffOpts.MaxFileBytes = n
+	}
+
+	cloneToken := githubToken
+	if cloneToken == "" {
+		cloneToken = gitlabToken
+	}
+
+	factory := s.Factory(githubToken, gitlabToken, git.WithDiffOptions(diffOpts))
+	notifier := reviewer.NewSlackNotifier(slackToken, slackChannel)
+	agent := reviewer.NewAgent(s.LLM, log)
+	workerOpts := []reviewer.WorkerOption{
+		reviewer.WithStickySummary(os.Getenv("REVIEWER_STICKY_SUMMARY") == "true"),
+		reviewer.WithSlackRouting(os.Getenv("REVIEWER_SLACK_ROUTING") == "true"),
+		reviewer.WithLabels(reviewLabels()),
+		reviewer.WithThreadResolution(os.Getenv("REVIEWER_RESOLVE_THREADS") != "false"),
+		reviewer.WithCriteriaCheckoff(os.Getenv("REVIEWER_CHECK_CRITERIA") == "true"),
+		reviewer.WithStackedReviews(os.Getenv("REVIEWER_STACKED_REVIEWS") != "false"),
+	}
+	triggers, err := reviewer.ParseTriggers(os.Getenv("REVIEWER_TRIGGERS"))
+	if err != nil {
+		fail(log, "invalid REVIEWER_TRIGGERS", "err", err)
+	}
+	if os.Getenv("REVIEWER_REVIEW_ON_PUSH") == "false" {
+		triggers = slices.DeleteFunc(triggers, func(t reviewer.Trigger) bool { return t == reviewer.TriggerPush })
+	}
+	workerOpts = append(workerOpts, reviewer.WithTriggers(triggers...))
+	if os.Getenv("REVIEWER_SECURITY_REVIEW") == "true" {
+		workerOpts = append(workerOpts, reviewer.WithSecurityPass(repoconfig.Security{
+			Paths:     splitList(EnvOr("REVIEWER_SECURITY_PATHS", "*auth*,*crypt*,*secret*,*token*,*session*,*password*,*permission*")),
+			Languages: splitList(os.Getenv("REVIEWER_SECURITY_LANGUAGES")),
+		}))
+	}
+	if os.Getenv("REVIEWER_REPO_ACCESS") != "false" {
+		workerOpts = append(workerOpts, reviewer.WithRepoAccess(cloneToken, s.Network))
+	}
+	docsMode, err := reviewer.ParseDocsMode(os.Getenv("REVIEWER_DOCS_SYNC"))
+	if err != nil {
+		fail(log, "invalid REVIEWER_DOCS_SYNC", "err", err)
+	}
+	if docsMode != reviewer.DocsOff {
+		workerOpts = append(workerOpts, reviewer.WithDocsSync(docsMode))
+	}
+	missingIssue, err := reviewer.ParseMissingIssuePolicy(os.Getenv("REVIEWER_MISSING_ISSUE"))
+	if err != nil {
+		fail(log, "invalid REVIEWER_MISSING_ISSUE", "err", err)
+	}
+	workerOpts = append(workerOpts, reviewer.WithMissingIssuePolicy(missingIssue))
+	var dataSources []retention.Source
+	if path := EnvOr("REVIEWER_CALIBRATION_FILE", "data/reviewer-calibration.json"); path != "off" {
+		calibration, err := reviewer.NewCalibrationStore(path)
+		if err != nil {
+			fail(log, "open calibration store", "err", err)
+		}
+		workerOpts = append(workerOpts, reviewer.WithCalibration(calibration))
+		dataSources = append(dataSources, calibration)
+	}
+	if path := EnvOr("REVIEWER_HISTORY_FILE", "data/reviewer-history.json"); path != "off" {
+		history, err := reviewer.NewReviewHistory(path)
+		if err != nil {
+			fail(log, "open review history", "err", err)
+		}
+		workerOpts = append(workerOpts, reviewer.WithHistory(history))
+		dataSources = append(dataSources, history)
+	}
+	if os.Getenv("REVIEWER_CONTRACT_TESTS") == "true" {
+		repoImages, err := sandbox.ParseRepoImages(os.Getenv("REVIEWER_SANDBOX_REPO_IMAGES"))
+		if err != nil {
+			fail(log, "invalid REVIEWER_SANDBOX_REPO_IMAGES", "err", err)
+		}
+		timeout, err := time.ParseDuration(EnvOr("REVIEWER_CONTRACT_TIMEOUT", "10m"))
+		if err != nil {
+			fail(log, "invalid REVIEWER_CONTRACT_TIMEOUT", "err", err)
+		}
+		workerOpts = append(workerOpts, reviewer.WithContractTests(reviewer.NewContractRunner(sandbox.Config{
+			DefaultImage: EnvOr("REVIEWER_SANDBOX_IMAGE", "alpine:3.21"),
+			RepoImages:   repoImages,
+		}, cloneToken, s.Network, timeout, log)))
+	}
+	if os.Getenv("REVIEWER_COVERAGE") == "true" {
+		repoImages, err := sandbox.ParseRepoImages(os.Getenv("REVIEWER_SANDBOX_REPO_IMAGES"))
+		if err != nil {
+			fail(log, "invalid REVIEWER_SANDBOX_REPO_IMAGES", "err", err)
+		}
+		timeout, err := time.ParseDuration(EnvOr("REVIEWER_COVERAGE_TIMEOUT", "20m"))
+		if err != nil {
+			fail(log, "invalid REVIEWER_COVERAGE_TIMEOUT", "err", err)
+		}
+		workerOpts = append(workerOpts, reviewer.WithCoverage(reviewer.NewCoverageRunner(sandbox.Config{
+			DefaultImage: EnvOr("REVIEWER_SANDBOX_IMAGE", "alpine:3.21"),
+			RepoImages:   repoImages,
+			Network:      os.Getenv("REVIEWER_COVERAGE_NETWORK") == "true",
+		}, cloneToken, s.Network, timeout, log)))
+	}
+	if os.Getenv("REVIEWER_LINT") == "true" {
+		repoImages, err := sandbox.ParseRepoImages(os.Getenv("REVIEWER_SANDBOX_REPO_IMAGES"))
+		if err != nil {
+			fail(log, "invalid REVIEWER_SANDBOX_REPO_IMAGES", "err", err)
+		}
+		timeout, err := time.ParseDuration(EnvOr("REVIEWER_LINT_TIMEOUT", "10m"))
+		if err != nil {
+			fail(log, "invalid REVIEWER_LINT_TIMEOUT", "err", err)
+		}
+		workerOpts = append(workerOpts, reviewer.WithLint(reviewer.NewLintRunner(sandbox.Config{
+			DefaultImage: EnvOr("REVIEWER_SANDBOX_IMAGE", "alpine:3.21"),
+			RepoImages:   repoImages,
+			Network:      os.Getenv("REVIEWER_LINT_NETWORK") == "true",
+		}, cloneToken, s.Network, timeout, log)))
+	}
+	commandPermission, err := git.ParsePermission(EnvOr("REVIEWER_COMMAND_PERMISSION", "write"))
+	if err != nil {
+		fail(log, "invalid REVIEWER_COMMAND_PERMISSION", "err", err)
+	}
+	workerOpts = append(workerOpts, reviewer.WithCommandPolicy(commandPermission, splitList(os.Getenv("REVIEWER_COMMAND_USERS"))...))
+	if s.Standards != nil {
+		workerOpts = append(workerOpts, reviewer.WithStandards(s.Standards))
+	}
+	worker := reviewer.NewWorker(agent, factory, notifier, log, workerOpts...)
+	webhook := reviewer.NewWebhookServer(worker, githubSecret, gitlabSecret, log, reviewer.WithStatsToken(os.Getenv("REVIEWER_STATS_TOKEN")))
+
+	return &Service{
+		Name:    "reviewer",
+		Addr:    EnvOr("REVIEWER_ADDR", ":8081"),
+		Handler: webhook.Handler(),
+		Data:    dataSources,
+	}
+}
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/jadenj13/droid/internals/retention"
+)
+
+// Service is one configured service, ready to run.
+type Service struct {
+	Name    string
+	Addr    string       // where the service listens when run on its own; "" for no HTTP
+	Handler http.Handler // its HTTP endpoints, or nil
+	Data    []retention.Source
+
+	// Run does the service's background work until ctx is done; nil for a
+	// service that only answers HTTP requests.
+	Run func(ctx context.Context) error
+}
+
+// Serve runs services until SIGINT or SIGTERM: their background work, the
+// DATA_RETENTION enforcer over all of their data and, when addr is set, one
+// HTTP listener. A single service is mounted at the root; with several, each
+// is mounted under /<name>/, so the executor's GitHub webhook, for example,
+// is /executor/webhook/github. With DATA_ADMIN_TOKEN set, /data exports and
+// deletes the data of every service.
+func (s *Shared) Serve(addr string, services ...*Service) {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	mux := http.NewServeMux()
+	var dataSources []retention.Source
+	for _, svc := range services {
+		dataSources = append(dataSources, svc.Data...)
+		switch {
+		case svc.Handler == nil:
+		case len(services) == 1:
+			mux.Handle("/", svc.Handler)
+		default:
+			mux.Handle("/"+svc.Name+"/", http.StripPrefix("/"+svc.Name, svc.Handler))
+		}
+	}
+	if token := os.Getenv("DATA_ADMIN_TOKEN"); token != "" {
+		mux.Handle("/data", retention.Handler(token, dataSources...))
+	}
+
+	if maxAge := envDuration("DATA_RETENTION", 0); maxAge > 0 {
+		go retention.NewEnforcer(maxAge, s.Log, dataSources...).Run(ctx)
+	}
+
+	var running sync.WaitGroup
+	for _, svc := range services {
+		if svc.Run == nil {
+			continue
+		}
+		running.Add(1)
+		go func() {
+			defer running.Done()
+			s.Log.Info(svc.Name + " starting")
+			if err := svc.Run(ctx); err != nil {
+				fail(s.Log, svc.Name+" exited with error", "err", err)
+			}
+		}()
+	}
+
+	var srv *http.Server
+	if addr != "" {
+		srv = &http.Server{
+			Addr:         addr,
+			Handler:      mux,
+			ReadTimeout:  10 * time.Second,
+			WriteTimeout: 10 * time.Second,
+		}
+		go func() {
+			s.Log.Info("listening", "addr", addr)
+			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fail(s.Log, "server error", "err", err)
+			}
+		}()
+	}
+
+	<-ctx.Done()
+	s.Log.Info("shutting down")
+
+	shutCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if srv != nil {
+		srv.Shutdown(shutCtx)
+	}
+	done := make(chan struct{})
+	go func() {
+		running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-shutCtx.Done():
+		s.Log.Warn("services did not stop in time")
+	}
+}
@@ -0,0 +1,220 @@
+// Package artifacts stores an audit bundle for each executor run — command
+// and test logs, the final diff and the full tool transcript — in a local
+// directory or an S3 bucket, so humans can see exactly what the agent did.
+package artifacts
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/retention"
+)
+
+// ManifestFile describes a bundle; every bundle has one.
+const ManifestFile = "manifest.json"
+
+// Manifest identifies the run a bundle belongs to.
+type Manifest struct {
+	RepoURL   string    `json:"repo_url"`
+	Issue     int       `json:"issue"`
+	RunID     string    `json:"run_id"`
+	Branch    string    `json:"branch,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// File is one file of a bundle.
+type File struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// Store keeps bundles. Save writes the files under key, with the manifest
+// and an index page linking them, and returns where humans can open the
+// bundle: a URL, or a path on the executor host when no URL is configured.
+type Store interface {
+	Save(ctx context.Context, key string, m Manifest, files []File) (string, error)
+}
+
+// NewRunID returns an ID that sorts by start time, e.g.
+// "20261016T101500Z-1a2b3c".
+func NewRunID(now time.Time) string {
+	b := make([]byte, 3)
+	_, _ = rand.Read(b)
+	return now.UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(b)
+}
+
+// Key is where a run's bundle is stored: host/owner/repo/issue-N/runID.
+func Key(repoURL string, issue int, runID string) string {
+	repo := strings.TrimSuffix(repoURL, "/")
+	if info, err := git.ParseRepoURL(repoURL); err == nil {
+		repo = info.Host + "/" + info.Owner + "/" + info.Repo
+	} else if _, rest, ok := strings.Cut(repo, "://"); ok {
+		repo = rest
+	}
+	return fmt.Sprintf("%s/issue-%d/%s", repo, issue, runID)
+}
+
+// bundleFiles adds the manifest and index page to files.
+func bundleFiles(m Manifest, files []File) ([]File, error) {
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	out := append(files[:len(files):len(files)], File{Name: ManifestFile, ContentType: "application/json", Data: manifest})
+	return append(out, File{Name: "index.html", ContentType: "text/html; charset=utf-8", Data: indexPage(m, out)}), nil
+}
+
+func indexPage(m Manifest, files []File) []byte {
+	var sb strings.Builder
+	title := html.EscapeString(fmt.Sprintf("Executor run %s — issue #%d", m.RunID, m.Issue))
+	sb.WriteString("<!doctype html>\n<meta charset=\"utf-8\">\n<title>" + title + "</title>\n<h1>" + title + "</h1>\n")
+	sb.WriteString(fmt.Sprintf("<p>%s<br>Branch %s, started %s</p>\n<ul>\n",
+		html.EscapeString(m.RepoURL), html.EscapeString(m.Branch), m.CreatedAt.UTC().Format(time.RFC3339)))
+	for _, f := range files {
+		name := html.EscapeString(f.Name)
+		sb.WriteString(fmt.Sprintf("<li><a href=\"%s\">%s</a> (%d bytes)</li>\n", name, name, len(f.Data)))
+	}
+	sb.WriteString("</ul>\n")
+	return []byte(sb.String())
+}
+
+// DirStore keeps bundles under a local directory, served by Handler.
+type DirStore struct {
+	dir     string
+	baseURL string // where Handler is reachable, e.g. https://droid.example.com/artifacts; "" links to paths
+}
+
+func NewDirStore(dir, baseURL string) (*DirStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create artifacts dir: %w", err)
+	}
+	return &DirStore{dir: dir, baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+// Save implements Store.
+func (s *DirStore) Save(_ context.Context, key string, m Manifest, files []File) (string, error) {
+	files, err := bundleFiles(m, files)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("create bundle dir: %w", err)
+	}
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(dir, path.Base(f.Name)), f.Data, 0o644); err != nil {
+			return "", fmt.Errorf("write %s: %w", f.Name, err)
+		}
+	}
+	if s.baseURL == "" {
+		return dir, nil
+	}
+	return s.baseURL + "/" + key + "/index.html", nil
+}
+
+// Handler serves the files of the stored bundles read-only, at their key
+// and name. Every request needs "Authorization: Bearer <token>", and
+// directories are not listed: they, like missing files, are 404s.
+func (s *DirStore) Handler(token string) http.Handler {
+	root := http.Dir(s.dir)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, token) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		f, err := root.Open(path.Clean("/" + r.URL.Path))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil || info.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+	})
+}
+
+func authorized(r *http.Request, token string) bool {
+	if token == "" {
+		return false
+	}
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// Name implements retention.Source.
+func (s *DirStore) Name() string { return "executor_artifacts" }
+
+// ExportData lists the bundles f selects, matched on when the run started.
+// Bundles hold no Slack user data, so a user filter matches none.
+func (s *DirStore) ExportData(f retention.Filter) (any, error) {
+	out := []Manifest{}
+	err := s.each(f, func(_ string, m Manifest) error {
+		out = append(out, m)
+		return nil
+	})
+	return out, err
+}
+
+// DeleteData removes the bundles f selects.
+func (s *DirStore) DeleteData(f retention.Filter) (int, error) {
+	n := 0
+	err := s.each(f, func(dir string, _ Manifest) error {
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+		n++
+		return nil
+	})
+	return n, err
+}
+
+// each calls fn with the directory and manifest of every bundle f selects.
+func (s *DirStore) each(f retention.Filter, fn func(dir string, m Manifest) error) error {
+	var dirs []string
+	var manifests []Manifest
+	err := filepath.WalkDir(s.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || d.Name() != ManifestFile {
+			return err
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		var m Manifest
+		if err := json.Unmarshal(b, &m); err != nil {
+			return fmt.Errorf("decode %s: %w", p, err)
+		}
+		if f.Match(m.RepoURL, nil, m.CreatedAt) {
+			dirs = append(dirs, filepath.Dir(p))
+			manifests = append(manifests, m)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for i := range dirs {
+		if err := fn(dirs[i], manifests[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Config locates the bucket bundles are uploaded to. Requests are signed
+// with AWS Signature Version 4, so S3-compatible stores work too.
+type S3Config struct {
+	Bucket       string
+	Region       string
+	Prefix       string // key prefix inside the bucket, e.g. "droid/"
+	Endpoint     string // e.g. https://minio.internal:9000; defaults to AWS S3 in Region
+	AccessKey    string
+	SecretKey    string
+	SessionToken string // for temporary credentials
+	PublicURL    string // base URL humans open bundles at; defaults to the object URL
+}
+
+// S3Store uploads bundles to an S3 bucket, addressed path-style.
+type S3Store struct {
+	cfg    S3Config
+	client *http.Client
+}
+
+func NewS3Store(cfg S3Config) (*S3Store, error) {
+	if cfg.Bucket == "" || cfg.Region == "" {
+		return nil, errors.New("S3 artifacts need a bucket and a region")
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, errors.New("S3 artifacts need AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", cfg.Region)
+	}
+	cfg.Endpoint = strings.TrimSuffix(cfg.Endpoint, "/")
+	cfg.PublicURL = strings.TrimSuffix(cfg.PublicURL, "/")
+	if cfg.Prefix != "" && !strings.HasSuffix(cfg.Prefix, "/") {
+		cfg.Prefix += "/"
+	}
+	return &S3Store{cfg: cfg, client: &http.Client{Timeout: time.Minute}}, nil
+}
+
+// Save implements Store.
+func (s *S3Store) Save(ctx context.Context, key string, m Manifest, files []File) (string, error) {
+	files, err := bundleFiles(m, files)
+	if err != nil {
+		return "", err
+	}
+	for _, f := range files {
+		if err := s.put(ctx, s.cfg.Prefix+key+"/"+path.Base(f.Name), f); err != nil {
+			return "", fmt.Errorf("upload %s: %w", f.Name, err)
+		}
+	}
+	if s.cfg.PublicURL != "" {
+		return s.cfg.PublicURL + "/" + key + "/index.html", nil
+	}
+	return s.objectURL(s.cfg.Prefix + key + "/index.html"), nil
+}
+
+func (s *S3Store) objectURL(object string) string {
+	return s.cfg.Endpoint + "/" + s.cfg.Bucket + "/" + escapePath(object)
+}
+
+func (s *S3Store) put(ctx context.Context, object string, f File) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(object), bytes.NewReader(f.Data))
+	if err != nil {
+		return err
+	}
+	contentType := f.ContentType
+	if contentType == "" {
+		contentType = "text/plain; charset=utf-8"
+	}
+	req.Header.Set("Content-Type", contentType)
+	s.sign(req, f.Data, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// sign adds the AWS Signature Version 4 headers to req.
+func (s *S3Store) sign(req *http.Request, payload []byte, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.cfg.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.cfg.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(req.Header.Get(name))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + s.cfg.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), date)
+	for _, part := range []string{s.cfg.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKey, scope, signedHeaders, signature))
+}
+
+// escapePath escapes each segment of an object key as S3 expects.
+func escapePath(p string) string {
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		parts[i] = strings.ReplaceAll(url.PathEscape(part), "+", "%2B")
+	}
+	return strings.Join(parts, "/")
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,169 @@
+// Package chaos injects faults into LLM and git provider calls and delays
+// executor tools, so retries, job checkpoints and failure alerts can be
+// exercised in staging before production depends on them. Never enable it in
+// production.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config sets how often faults are injected. Rates are probabilities per
+// call, from 0 to 1.
+type Config struct {
+	LLMFailRate      float64
+	ProviderFailRate float64
+	ToolDelayRate    float64
+	ToolDelay        time.Duration // longest injected tool delay
+}
+
+// DefaultConfig is used for any setting left empty.
+var DefaultConfig = Config{
+	LLMFailRate:      0.1,
+	ProviderFailRate: 0.1,
+	ToolDelayRate:    0.2,
+	ToolDelay:        10 * time.Second,
+}
+
+// Settings holds the raw values of the CHAOS_* environment variables. Every
+// field is optional.
+type Settings struct {
+	LLMFailRate      string
+	ProviderFailRate string
+	ToolDelayRate    string
+	ToolDelay        string
+}
+
+// Config parses the settings over DefaultConfig.
+func (s Settings) Config() (Config, error) {
+	cfg := DefaultConfig
+	for _, r := range []struct {
+		name  string
+		value string
+		dst   *float64
+	}{
+		{"LLM fail rate", s.LLMFailRate, &cfg.LLMFailRate},
+		{"provider fail rate", s.ProviderFailRate, &cfg.ProviderFailRate},
+		{"tool delay rate", s.ToolDelayRate, &cfg.ToolDelayRate},
+	} {
+		if r.value == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(r.value), 64)
+		if err != nil || f < 0 || f > 1 {
+			return Config{}, fmt.Errorf("invalid %s %q — expected a number from 0 to 1", r.name, r.value)
+		}
+		*r.dst = f
+	}
+	if s.ToolDelay != "" {
+		d, err := time.ParseDuration(s.ToolDelay)
+		if err != nil || d < 0 {
+			return Config{}, fmt.Errorf("invalid tool delay %q", s.ToolDelay)
+		}
+		cfg.ToolDelay = d
+	}
+	return cfg, nil
+}
+
+// Injector injects the faults described by its Config. A nil Injector
+// injects nothing.
+type Injector struct {
+	cfg Config
+	log *slog.Logger
+}
+
+func New(cfg Config, log *slog.Logger) *Injector {
+	return &Injector{cfg: cfg, log: log}
+}
+
+// LLMTransport wraps the transport used for LLM API calls.
+func (i *Injector) LLMTransport(next http.RoundTripper) http.RoundTripper {
+	return &transport{
+		next:     next,
+		rate:     i.cfg.LLMFailRate,
+		target:   "llm",
+		statuses: []int{http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusServiceUnavailable, 529},
+		log:      i.log,
+	}
+}
+
+// ProviderTransport wraps the transport used for GitHub and GitLab API
+// calls.
+func (i *Injector) ProviderTransport(next http.RoundTripper) http.RoundTripper {
+	return &transport{
+		next:     next,
+		rate:     i.cfg.ProviderFailRate,
+		target:   "provider",
+		statuses: []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable},
+		log:      i.log,
+	}
+}
+
+// DelayTool sleeps for a random time up to ToolDelay on a ToolDelayRate
+// share of calls, or until ctx is done.
+func (i *Injector) DelayTool(ctx context.Context, tool string) {
+	if i == nil || i.cfg.ToolDelay <= 0 || rand.Float64() >= i.cfg.ToolDelayRate {
+		return
+	}
+	d := rand.N(i.cfg.ToolDelay)
+	i.log.Info("chaos: delaying tool", "tool", tool, "delay", d)
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
+
+// errInjected is returned for injected connection failures.
+var errInjected = errors.New("chaos: injected connection failure")
+
+type transport struct {
+	next     http.RoundTripper
+	rate     float64
+	target   string
+	statuses []int
+	log      *slog.Logger
+}
+
+// RoundTrip fails a share of requests, half with a connection error and half
+// with a retryable error status, before they reach the server.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if rand.Float64() >= t.rate {
+		return t.next.RoundTrip(req)
+	}
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	if rand.IntN(2) == 0 {
+		t.log.Info("chaos: injected connection failure", "target", t.target, "method", req.Method, "url", req.URL.Redacted())
+		return nil, errInjected
+	}
+	status := t.statuses[rand.IntN(len(t.statuses))]
+	t.log.Info("chaos: injected error response", "target", t.target, "method", req.Method, "url", req.URL.Redacted(), "status", status)
+	body := `{"type":"error","error":{"type":"api_error","message":"chaos: injected failure"},"message":"chaos: injected failure"}`
+	resp := &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+	if status == http.StatusTooManyRequests {
+		resp.Header.Set("Retry-After", "1")
+	}
+	return resp, nil
+}
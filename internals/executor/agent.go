@@ -3,14 +3,23 @@ package executor
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"strings"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 
+	"github.com/jadenj13/droid/internals/artifacts"
+	"github.com/jadenj13/droid/internals/chaos"
 	"github.com/jadenj13/droid/internals/git"
 	"github.com/jadenj13/droid/internals/llm"
+	"github.com/jadenj13/droid/internals/redact"
+	"github.com/jadenj13/droid/internals/repoconfig"
+	"github.com/jadenj13/droid/internals/sandbox"
+	"github.com/jadenj13/droid/internals/standards"
 )
 
 const (
@@ -24,36 +33,299 @@ type LLM interface {
 }
 
 type PRResult struct {
-	Branch   string
-	Title    string
-	Summary  string
-	IssueURL string
+	Branch      string
+	BaseBranch  string
+	Title       string
+	Summary     string
+	IssueURL    string
+	Draft       bool       // open as a draft: the agent was not confident in the work
+	Notes       string     // the agent's reasons for low confidence
+	TestProof   *TestProof // before/after runs of the reproducing test, on bug issues
+	Screenshots []Screenshot
+	Linked      []LinkedPR // branches pushed to the issue's other repositories
+	// BudgetExceeded says why the run stopped early and pushed its work in
+	// progress, or is "" for a run that finished.
+	BudgetExceeded string
+	// Artifacts is where the run's logs, diff and transcript were saved, or
+	// "" if they were not.
+	Artifacts string
+	// Pipeline is the CI run of the pushed branch; its URL is empty when CI
+	// was not checked.
+	Pipeline git.Pipeline
+	// Paused is set when the run stopped to ask a question; its work so far
+	// is pushed to Branch and there is no PR to open yet.
+	Paused *Pause
+	// Patch is the change a dry run made, which nothing was pushed for; it
+	// is empty for a normal run.
+	Patch string
 }
 
 type Agent struct {
-	llm LLM
-	log *slog.Logger
+	llm          LLM
+	log          *slog.Logger
+	pushStrategy git.PushStrategy
+	sandbox      *sandbox.Config // nil runs commands on the host
+	timeouts     CommandTimeouts
+	network      *git.Network // nil uses git's own TLS and proxy settings
+	ssh          *git.SSHAuth // nil uses HTTPS token auth for every host
+	redactor     *redact.Redactor
+	standards    *standards.Library // nil adds no coding standards to the prompt
+	webFetch     *WebFetcher        // nil leaves out the web_fetch tool
+	chaos        *chaos.Injector    // nil injects no tool delays
+	budget       repoconfig.Budget  // default per-job budget; .droid.yml overrides it
+	artifacts    artifacts.Store    // nil saves no run artifacts
+	ci           CIConfig
+	dryRun       bool // push nothing; see WithDryRun
 }
 
-func NewAgent(llm LLM, log *slog.Logger) *Agent {
-	return &Agent{llm: llm, log: log}
+type AgentOption func(*Agent)
+
+// WithPushStrategy sets how the agent pushes its branch when the remote branch
+// already exists. Defaults to git.PushFastForwardOnly.
+func WithPushStrategy(s git.PushStrategy) AgentOption {
+	return func(a *Agent) { a.pushStrategy = s }
+}
+
+// WithSandbox runs every run_command call inside a container chosen by cfg.
+func WithSandbox(cfg sandbox.Config) AgentOption {
+	return func(a *Agent) { a.sandbox = &cfg }
+}
+
+// WithGitNetwork clones and pushes through n's CA bundle and proxy.
+func WithGitNetwork(n *git.Network) AgentOption {
+	return func(a *Agent) { a.network = n }
+}
+
+// WithGitSSH clones and pushes over SSH for the hosts a covers.
+func WithGitSSH(a *git.SSHAuth) AgentOption {
+	return func(ag *Agent) { ag.ssh = a }
+}
+
+// WithRedactor masks secrets in command output before the model sees it.
+// Secret-shaped strings are masked even without one.
+func WithRedactor(r *redact.Redactor) AgentOption {
+	return func(a *Agent) { a.redactor = r }
+}
+
+// WithStandards adds the org coding standards relevant to each issue to the
+// system prompt.
+func WithStandards(l *standards.Library) AgentOption {
+	return func(a *Agent) { a.standards = l }
+}
+
+// WithWebFetch gives the agent the web_fetch tool for the domains f allows.
+func WithWebFetch(f *WebFetcher) AgentOption {
+	return func(a *Agent) { a.webFetch = f }
+}
+
+// WithChaos delays tool executions at random, for fault-injection testing.
+func WithChaos(i *chaos.Injector) AgentOption {
+	return func(a *Agent) { a.chaos = i }
+}
+
+// WithBudget stops each run that exceeds b, pushing its work so far for a
+// draft PR. A repository's .droid.yml budget overrides it field by field.
+func WithBudget(b repoconfig.Budget) AgentOption {
+	return func(a *Agent) { a.budget = b }
+}
+
+// WithArtifacts saves each run's command and test logs, final diff and full
+// tool transcript to s, and links them from the PR.
+func WithArtifacts(s artifacts.Store) AgentOption {
+	return func(a *Agent) { a.artifacts = s }
+}
+
+// WithCommandTimeouts sets the default and maximum run_command timeouts.
+// Defaults to DefaultCommandTimeouts.
+func WithCommandTimeouts(t CommandTimeouts) AgentOption {
+	return func(a *Agent) { a.timeouts = t }
 }
 
-func (a *Agent) Run(ctx context.Context, issue git.Issue, provider git.GitProvider, token string) (PRResult, error) {
-	repo, err := git.Clone(ctx, provider.RepoURL(), token)
+func NewAgent(llm LLM, log *slog.Logger, opts ...AgentOption) *Agent {
+	a := &Agent{llm: llm, log: log, timeouts: DefaultCommandTimeouts}
+	for _, o := range opts {
+		o(a)
+	}
+	return a
+}
+
+// Run works on issue from a fresh branch and pushes it. Secondary
+// repositories are cloned onto a branch of the same name and pushed if the
+// run commits to them. If prior is non-nil, the failed attempt it describes
+// is included in the initial prompt. If resume is non-nil, the run continues
+// the paused one on its branch instead, with the answer to its question.
+func (a *Agent) Run(ctx context.Context, issue git.Issue, provider git.GitProvider, secondary []git.GitProvider, token string, prior *Attempt, resume *Pause) (PRResult, error) {
+	dryRun := a.isDryRun(issue, resume)
+	if dryRun {
+		if len(secondary) > 0 {
+			return PRResult{}, fmt.Errorf("dry runs cannot span several repositories")
+		}
+		ctx = contextWithQuestions(ctx, false) // pausing pushes the work so far
+	}
+	repo, cfg, err := a.clone(ctx, provider, token)
 	if err != nil {
-		return PRResult{}, fmt.Errorf("clone: %w", err)
+		return PRResult{}, err
 	}
 	defer repo.Cleanup()
 
+	base := cfg.BaseBranch
 	branch := git.BranchName(issue.Number, issue.Title)
-	if err := repo.CreateBranch(ctx, branch); err != nil {
-		return PRResult{}, fmt.Errorf("create branch: %w", err)
+	if resume != nil {
+		if err := repo.CheckoutRemoteBranch(ctx, resume.Branch); err != nil {
+			return PRResult{}, fmt.Errorf("checkout paused branch %s: %w", resume.Branch, err)
+		}
+		if cfg, err = loadRepoConfig(repo); err != nil {
+			return PRResult{}, err
+		}
+		base, branch = resume.BaseBranch, resume.Branch
+		a.log.Info("resuming paused run", "issue", issue.Number, "branch", branch, "answered", resume.Answer != "")
+	} else if requested := requestedBase(issue); requested != "" {
+		if err := repo.CheckoutRemoteBranch(ctx, requested); err != nil {
+			return PRResult{}, fmt.Errorf("checkout base %s requested by the issue: %w", requested, err)
+		}
+		// The target branch's own .droid.yml applies, as its build and test
+		// commands may differ from the default branch's.
+		if cfg, err = loadRepoConfig(repo); err != nil {
+			return PRResult{}, err
+		}
+		base = requested
+		a.log.Info("using base branch requested by the issue", "issue", issue.Number, "base", base)
+	} else if base != "" {
+		if err := repo.CheckoutRemoteBranch(ctx, base); err != nil {
+			return PRResult{}, fmt.Errorf("checkout base %s: %w", base, err)
+		}
+	} else if base, err = repo.CurrentBranch(ctx); err != nil {
+		return PRResult{}, fmt.Errorf("resolve default branch: %w", err)
+	}
+	ctx = llm.ContextWithModel(ctx, cfg.Model)
+
+	if resume == nil {
+		if err := repo.CreateBranch(ctx, branch); err != nil {
+			return PRResult{}, fmt.Errorf("create branch: %w", err)
+		}
+	}
+	start, err := repo.Head(ctx)
+	if err != nil {
+		return PRResult{}, fmt.Errorf("resolve HEAD: %w", err)
+	}
+	var record *transcript
+	if a.artifacts != nil {
+		record = &transcript{}
+		ctx = contextWithTranscript(ctx, record)
+	}
+
+	ws, err := a.openWorkspace(ctx, provider, secondary, token, branch)
+	if err != nil {
+		return PRResult{}, fmt.Errorf("clone secondary repo %w", err)
+	}
+	defer ws.cleanup()
+
+	a.log.Info("executor started", "issue", issue.Number, "branch", branch, "type", issueType(issue), "repos", 1+len(secondary))
+
+	setup, err := a.runSetup(ctx, repo, cfg)
+	if err != nil {
+		return PRResult{}, err
+	}
+	prompt := initialPrompt(issue) + ws.prompt(branch) + setup
+	if name, dir, ok := cfg.Area(issue.Labels); ok {
+		ctx = contextWithArea(ctx, dir)
+		prompt += areaPrompt(name, dir)
+		a.log.Info("run scoped to area", "issue", issue.Number, "area", name, "dir", dir)
+	}
+	if ws != nil {
+		for _, co := range ws.others {
+			s, err := a.runSetup(ctx, co.repo, co.cfg)
+			if err != nil {
+				return PRResult{}, err
+			}
+			if s != "" {
+				prompt += "\n\nIn " + co.name + ":" + s
+			}
+		}
+	}
+	if prior != nil {
+		prompt += priorAttemptSection(prior)
+	}
+	if resume != nil {
+		prompt += resumeSection(resume)
+	}
+
+	var proof *TestProof
+	if issueType(issue) == IssueBug {
+		proof = &TestProof{}
+	}
+	systemExtra := joinSections(a.probeEnvironment(ctx, repo), a.standardsFor(ctx, provider, repo, issue.Title+"\n"+issue.Body))
+	result, err := a.runLoop(ctx, repo, issue, cfg, systemExtra, prompt, proof, ws)
+	if err != nil {
+		return PRResult{}, err
+	}
+
+	if dryRun {
+		return a.dryRunResult(ctx, provider, issue, repo, branch, base, start, record, result, proof)
+	}
+	if err := repo.Push(ctx); err != nil {
+		return PRResult{}, fmt.Errorf("push: %w", err)
+	}
+	if result.Paused != nil {
+		result.Paused.Branch, result.Paused.BaseBranch = branch, base
+		return PRResult{Branch: branch, BaseBranch: base, IssueURL: issue.URL, Paused: result.Paused}, nil
+	}
+	pipeline, err := a.awaitCI(ctx, provider, repo, issue, cfg, branch, systemExtra, &result)
+	if err != nil {
+		return PRResult{}, err
+	}
+	linked, err := ws.push(ctx, branch)
+	if err != nil {
+		return PRResult{}, err
+	}
+	shots := a.captureScreenshots(ctx, repo, cfg, result.PreviewPaths)
+	saved := a.saveArtifacts(ctx, provider.RepoURL(), issue, repo, branch, start, record)
+
+	return PRResult{
+		Branch:      branch,
+		BaseBranch:  base,
+		Title:       result.PRTitle,
+		Summary:     result.PRSummary,
+		IssueURL:    issue.URL,
+		Draft:       result.PRDraft,
+		Notes:       result.PRNotes,
+		TestProof:   proof,
+		Screenshots: shots,
+		Linked:      linked,
+
+		BudgetExceeded: result.Budget,
+		Artifacts:      saved,
+		Pipeline:       pipeline,
+	}, nil
+}
+
+// Revise checks out the branch of an existing PR, addresses the review
+// comments left on it, and pushes the fixes to the same branch.
+func (a *Agent) Revise(ctx context.Context, issue git.Issue, pr git.PR, comments []git.PRComment, provider git.GitProvider, token string) (PRResult, error) {
+	repo, cfg, err := a.clone(ctx, provider, token)
+	if err != nil {
+		return PRResult{}, err
+	}
+	defer repo.Cleanup()
+	ctx = llm.ContextWithModel(ctx, cfg.Model)
+
+	if err := repo.CheckoutRemoteBranch(ctx, pr.Branch); err != nil {
+		return PRResult{}, fmt.Errorf("checkout PR branch %s: %w", pr.Branch, err)
 	}
 
-	a.log.Info("executor started", "issue", issue.Number, "branch", branch)
+	a.log.Info("executor revising", "issue", issue.Number, "pr", pr.Number, "comments", len(comments))
 
-	result, err := a.runLoop(ctx, repo, issue)
+	setup, err := a.runSetup(ctx, repo, cfg)
+	if err != nil {
+		return PRResult{}, err
+	}
+	prompt := revisionPrompt(issue, pr, comments) + setup
+	if name, dir, ok := cfg.Area(issue.Labels); ok {
+		ctx = contextWithArea(ctx, dir)
+		prompt += areaPrompt(name, dir)
+	}
+	systemExtra := joinSections(a.probeEnvironment(ctx, repo), a.standardsFor(ctx, provider, repo, issue.Title+"\n"+issue.Body+"\n"+pr.Diff))
+	result, err := a.runLoop(ctx, repo, issue, cfg, systemExtra, prompt, nil, nil)
 	if err != nil {
 		return PRResult{}, err
 	}
@@ -61,44 +333,277 @@ func (a *Agent) Run(ctx context.Context, issue git.Issue, provider git.GitProvid
 	if err := repo.Push(ctx); err != nil {
 		return PRResult{}, fmt.Errorf("push: %w", err)
 	}
+	pipeline, err := a.awaitCI(ctx, provider, repo, issue, cfg, pr.Branch, systemExtra, &result)
+	if err != nil {
+		return PRResult{}, err
+	}
 
 	return PRResult{
-		Branch:   branch,
-		Title:    result.PRTitle,
-		Summary:  result.PRSummary,
-		IssueURL: issue.URL,
+		Branch:     pr.Branch,
+		BaseBranch: pr.BaseBranch,
+		Title:      result.PRTitle,
+		Summary:    result.PRSummary,
+		IssueURL:   issue.URL,
+		Draft:      result.PRDraft,
+		Notes:      result.PRNotes,
+
+		BudgetExceeded: result.Budget,
+		Pipeline:       pipeline,
 	}, nil
 }
 
-func (a *Agent) runLoop(ctx context.Context, repo *git.Repo, issue git.Issue) (ToolResult, error) {
-	msgs := []llm.Message{{Role: "user", Content: initialPrompt(issue)}}
-	system := systemPrompt()
+// clone checks out the repository, running commands in the sandbox if one is
+// configured, and loads its .droid.yml.
+func (a *Agent) clone(ctx context.Context, provider git.GitProvider, token string) (*git.Repo, repoconfig.Config, error) {
+	cloneOpts := []git.CloneOption{git.WithPushStrategy(a.pushStrategy), git.WithCloneNetwork(a.network), git.WithSSH(a.ssh)}
+	if a.sandbox != nil {
+		info, err := git.ParseRepoURL(provider.RepoURL())
+		if err != nil {
+			return nil, repoconfig.Config{}, fmt.Errorf("parse repo url: %w", err)
+		}
+		runner := a.sandbox.RunnerFor(info.Owner + "/" + info.Repo)
+		a.log.Info("sandbox enabled", "image", runner.Image())
+		cloneOpts = append(cloneOpts, git.WithRunner(runner))
+	}
+
+	repo, err := git.Clone(ctx, provider.RepoURL(), token, cloneOpts...)
+	if err != nil {
+		return nil, repoconfig.Config{}, fmt.Errorf("clone: %w", err)
+	}
+
+	cfg, err := loadRepoConfig(repo)
+	if err != nil {
+		repo.Cleanup()
+		return nil, repoconfig.Config{}, err
+	}
+	return repo, cfg, nil
+}
+
+// loadRepoConfig reads .droid.yml from the clone. A missing file yields the
+// zero config; a malformed one is an error so protected paths are never
+// silently dropped.
+func loadRepoConfig(repo *git.Repo) (repoconfig.Config, error) {
+	content, err := repo.ReadFile(repoconfig.FileName)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return repoconfig.Config{}, nil
+		}
+		return repoconfig.Config{}, err
+	}
+	return repoconfig.Parse([]byte(content))
+}
+
+// standardsFor returns the coding standards section for the system prompt,
+// scoped to the repository and the languages in its tree.
+func (a *Agent) standardsFor(ctx context.Context, provider git.GitProvider, repo *git.Repo, text string) string {
+	if a.standards == nil {
+		return ""
+	}
+	var name string
+	if info, err := git.ParseRepoURL(provider.RepoURL()); err == nil {
+		name = info.Owner + "/" + info.Repo
+	}
+	files, _ := repo.Files(ctx, "")
+	return a.standards.PromptSection(ctx, standards.Query{
+		Repo:      name,
+		Languages: standards.Languages(files),
+		Text:      text,
+	})
+}
+
+// runLoop drives the model until it calls submit_work. systemExtra — the
+// environment report and coding standards — is appended to the system
+// prompt. A non-nil proof requires a
+// failing-then-passing test before submit_work is accepted, and is filled in
+// as the agent records runs. A non-nil ws makes the repository tools take a
+// repo selector; the test gate applies to the primary repository and
+// verification to all of them.
+func (a *Agent) runLoop(ctx context.Context, repo *git.Repo, issue git.Issue, cfg repoconfig.Config, systemExtra, prompt string, proof *TestProof, ws *workspace) (ToolResult, error) {
+	msgs := []llm.Message{{Role: "user", Content: prompt}}
+	system := systemPrompt(cfg)
+	if systemExtra != "" {
+		system += "\n\n" + systemExtra
+	}
+	tools := AllTools
+	if proof != nil {
+		tools = append(tools[:len(tools):len(tools)], toolRecordTestRun)
+	}
+	if a.webFetch != nil {
+		tools = append(tools[:len(tools):len(tools)], toolWebFetch)
+	}
+	// A paused run resumes on one branch, so multi-repo runs cannot pause.
+	asking := questionsAllowed(ctx) && ws == nil
+	if asking {
+		tools = append(tools[:len(tools):len(tools)], toolAskHuman)
+		system += "\n\nWhen a requirement is genuinely ambiguous and a wrong guess would waste the work, ask with ask_human instead of guessing. Use it sparingly: every question stops the run until someone answers."
+	}
+	tools = ws.tools(tools)
+
+	limit := maxIterations
+	if cfg.MaxIterations > 0 {
+		limit = cfg.MaxIterations
+	}
+	watch := budgetWatch{budget: cfg.Budget.Or(a.budget), limit: limit, start: time.Now(), meter: llm.MeterFrom(ctx)}
+	start, err := repo.Head(ctx)
+	if err != nil {
+		return ToolResult{}, fmt.Errorf("resolve HEAD: %w", err)
+	}
 
-	for i := range maxIterations {
-		resp, err := a.llm.CompleteWithTools(ctx, system, msgs, AllTools)
+	var steps []Step
+	report := progressFrom(ctx)
+	record := transcriptFrom(ctx)
+	tests := TestsUnknown
+	// With a configured test command, submit_work needs a passing full
+	// run_tests after the last file change.
+	requireTests := cfg.Commands["test"] != ""
+	testsOK := false
+	verifyFailures := 0
+	var checklist plan
+	loops := newLoopDetector()
+	fail := func(err error) (ToolResult, error) {
+		return ToolResult{}, &RunError{Err: err, Steps: steps, Plan: checklist.steps}
+	}
+
+	for i := 0; ; i++ {
+		if reason := watch.exceeded(i); reason != "" {
+			result, err := a.stopAtBudget(ctx, repo, ws, issue, start, reason, checklist.steps, steps)
+			if err != nil {
+				return fail(err)
+			}
+			return result, nil
+		}
+		turnSystem := system
+		if section := checklist.section(); section != "" {
+			turnSystem += "\n\n" + section
+		}
+		resp, err := a.llm.CompleteWithTools(ctx, turnSystem, msgs, tools)
 		if err != nil {
-			return ToolResult{}, fmt.Errorf("llm iter %d: %w", i, err)
+			return fail(fmt.Errorf("llm iter %d: %w", i, err))
 		}
 
 		toolCalls := extractToolCalls(resp)
+		record.text(i, extractText(resp))
 
 		if len(toolCalls) == 0 {
 			text := extractText(resp)
-			return ToolResult{}, fmt.Errorf("executor stopped without submit_work: %s", text)
+			return fail(fmt.Errorf("executor stopped without submit_work: %s", text))
 		}
 
 		toolResults := make([]anthropic.ToolResultBlockParam, 0, len(toolCalls))
 		var finalResult ToolResult
 
 		for _, tc := range toolCalls {
-			result, err := ExecuteTool(ctx, tc.Name, tc.Input, repo)
+			a.chaos.DelayTool(ctx, tc.Name)
+			var result ToolResult
+			target, pickErr := ws.pick(tc.Input)
+			var err error
+			switch {
+			case pickErr != nil:
+				result = ToolResult{Content: "error: " + pickErr.Error()}
+			case tc.Name == toolWritePlan.Name || tc.Name == toolUpdatePlan.Name:
+				result = checklist.execute(tc.Name, tc.Input)
+			case tc.Name == toolWebFetch.Name && a.webFetch != nil:
+				result, err = a.webFetch.execute(ctx, tc.Input)
+			case tc.Name == toolAskHuman.Name && asking:
+				result, err = a.askHuman(ctx, repo, issue, tc.Input, checklist.steps, steps)
+			case target != nil:
+				// The area belongs to the primary repository.
+				result, err = ExecuteTool(contextWithArea(ctx, ""), tc.Name, tc.Input, target.repo, target.cfg, a.timeouts)
+			default:
+				result, err = ExecuteTool(ctx, tc.Name, tc.Input, repo, cfg, a.timeouts)
+			}
 			if err != nil {
-				return ToolResult{}, fmt.Errorf("tool %q: %w", tc.Name, err)
+				return fail(fmt.Errorf("tool %q: %w", tc.Name, err))
+			}
+			if tc.Name == "run_command" || tc.Name == "record_test_run" || tc.Name == "run_tests" {
+				result.Content = a.redactor.String(result.Content)
+				if result.TestRun != nil {
+					result.TestRun.Output = result.Content
+				}
 			}
+			switch {
+			case result.TestRun != nil && proof != nil:
+				result.Content += "\n\n" + proof.record(result.TestPhase, result.TestRun)
+			case result.Done && proof != nil && !proof.complete() && !result.PRDraft:
+				result = ToolResult{Content: proofMissing}
+			case result.Done && requireTests && !testsOK && !result.PRDraft:
+				result = ToolResult{Content: testsNotPassing}
+			case target != nil:
+				// Secondary repositories are checked by verification only.
+			case result.Tests != nil:
+				if result.Tests.Full {
+					testsOK = result.Tests.Passed
+				}
+			case tc.Name == "write_file" || tc.Name == "edit_file":
+				if !strings.HasPrefix(result.Content, "error") {
+					testsOK = false
+				}
+			}
+			if result.Done {
+				// Check the build and tests ourselves rather than trusting the
+				// model's account of them.
+				failures, err := a.verifyAll(ctx, repo, cfg, ws)
+				if err != nil {
+					return fail(err)
+				}
+				if failures != "" {
+					record.tool(i, "verify", nil, failures)
+				}
+				switch {
+				case failures == "":
+				case result.PRDraft:
+					// A draft is already flagged as unfinished; show reviewers
+					// what is failing instead of blocking it.
+					result.PRNotes += "\n\nVerification after submit failed:\n\n" + failures
+				default:
+					verifyFailures++
+					if verifyFailures >= maxVerifyFailures {
+						return fail(fmt.Errorf("verification failed %d times after submit_work: %s", verifyFailures, preview(failures, 1000)))
+					}
+					testsOK = false
+					result = ToolResult{Content: "error: submit_work rejected — verification of the build and tests failed. Fix these failures, then submit again:\n\n" + failures}
+				}
+			}
+			if !result.Done {
+				touched := repo
+				if target != nil {
+					touched = target.repo
+				}
+				guidance, err := loops.observe(touched, tc.Name, tc.Input, result)
+				if err != nil {
+					a.log.Warn("run aborted by loop detection", "issue", issue.Number, "iter", i, "err", err)
+					return fail(err)
+				}
+				result.Content += guidance
+			}
+			record.tool(i, tc.Name, tc.Input, result.Content)
+			steps = append(steps, Step{
+				Tool:   tc.Name,
+				Input:  preview(string(tc.Input), 200),
+				Result: preview(result.Content, 200),
+			})
 
 			a.log.Info("tool executed", "tool", tc.Name, "iter", i,
 				"preview", preview(result.Content, 120))
 
+			if result.Tests != nil {
+				tests = TestsFailing
+				if result.Tests.Passed {
+					tests = TestsPassing
+				}
+			} else if tc.Name == "run_command" || tc.Name == "record_test_run" {
+				if status, ok := testStatusOf(tc.Input, result.Content, cfg); ok {
+					tests = status
+				}
+			}
+			report(Progress{
+				Iteration:     i + 1,
+				MaxIterations: limit,
+				LastTool:      tc.Name,
+				LastInput:     preview(string(tc.Input), 120),
+				Tests:         tests,
+			})
+
 			toolResults = append(toolResults, anthropic.ToolResultBlockParam{
 				ToolUseID: tc.ID,
 				Content: []anthropic.ToolResultBlockParamContentUnion{
@@ -106,9 +611,12 @@ func (a *Agent) runLoop(ctx context.Context, repo *git.Repo, issue git.Issue) (T
 				},
 			})
 
-			if result.Done {
+			if result.Done || result.Paused != nil {
 				finalResult = result
 			}
+			if result.Paused != nil {
+				break
+			}
 		}
 
 		msgs = append(msgs,
@@ -116,13 +624,15 @@ func (a *Agent) runLoop(ctx context.Context, repo *git.Repo, issue git.Issue) (T
 			llm.Message{Role: "tool_result", RawBlocks: toolResults},
 		)
 
+		if finalResult.Paused != nil {
+			a.log.Info("executor paused on a question", "issue", issue.Number, "iters", i+1)
+			return finalResult, nil
+		}
 		if finalResult.Done {
 			a.log.Info("executor completed", "issue", issue.Number, "iters", i+1)
 			return finalResult, nil
 		}
 	}
-
-	return ToolResult{}, fmt.Errorf("executor exceeded %d iterations without completing", maxIterations)
 }
 
 func initialPrompt(issue git.Issue) string {
@@ -137,20 +647,58 @@ Issue body:
 ---
 
 Start by listing the repository structure so you understand the codebase, then plan your approach before making any changes.
+
+%s
+
 When you are done and all tests pass, call submit_work.`,
-		issue.Number, issue.Title, issue.URL, issue.Body)
+		issue.Number, issue.Title, issue.URL, issue.Body, strategyPrompt(issueType(issue)))
+}
+
+func revisionPrompt(issue git.Issue, pr git.PR, comments []git.PRComment) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(`A reviewer has requested changes on your pull request for this issue.
+
+Issue #%d: %s
+URL: %s
+
+Issue body:
+---
+%s
+---
+
+Pull request #%d: %s
+Branch: %s (already checked out)
+
+Review comments:
+`, issue.Number, issue.Title, issue.URL, issue.Body, pr.Number, pr.Title, pr.Branch))
+
+	if len(comments) == 0 {
+		sb.WriteString("(no comments were found — read the diff against the base branch and fix anything that does not meet the acceptance criteria)\n")
+	}
+	for _, c := range comments {
+		if c.Path != "" {
+			sb.WriteString(fmt.Sprintf("- %s:%d — %s\n", c.Path, c.Line, c.Body))
+		} else {
+			sb.WriteString(fmt.Sprintf("- %s\n", c.Body))
+		}
+	}
+
+	sb.WriteString(`
+Address every comment with the smallest change that resolves it. Do not rewrite work that was not commented on.
+Commit your fixes on this branch. When you are done and all tests pass, call submit_work with a summary of what you changed in response to the review.`)
+	return sb.String()
 }
 
-func systemPrompt() string {
-	return `You are an expert software engineer working autonomously on a code repository.
+func systemPrompt(cfg repoconfig.Config) string {
+	prompt := `You are an expert software engineer working autonomously on a code repository.
 You have been assigned a GitHub issue to complete.
 
 Your workflow:
 1. Use list_files to understand the project structure
-2. Use read_file to read relevant existing code
-3. Plan your changes before writing anything
-4. Use write_file to implement changes
-5. Use run_command to run tests, linters, and build checks
+2. Use search_code to find relevant symbols and read_file to read the code around them
+3. Plan your changes before writing anything, and record the plan with write_plan; mark steps off with update_plan as you go
+4. Use edit_file to change existing files and write_file to create new ones
+5. Use run_tests to run the test suite and run_command for linters and build checks
 6. Fix any issues found by tests or linters
 7. Use commit_changes to commit logical groups of changes
 8. Once all tests pass and the work is complete, call submit_work
@@ -160,8 +708,14 @@ Rules:
 - Make the smallest change that satisfies the acceptance criteria
 - Follow existing code style and conventions — read existing files first
 - If you encounter something ambiguous in the requirements, make a reasonable decision and note it in the PR summary
+- If tests could not be run or you are unsure the work is right, submit with confidence "low" and explain what to double-check; the PR is opened as a draft
 - Do not modify files unrelated to the issue
 - Always run tests before submitting`
+
+	if section := cfg.PromptSection(); section != "" {
+		prompt += "\n\n" + section
+	}
+	return prompt
 }
 
 type toolCall struct {
@@ -0,0 +1,94 @@
+package executor
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jadenj13/droid/internals/analytics"
+	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/llm"
+)
+
+// prMarker identifies PRs opened by the executor; see buildPRBody.
+const prMarker = "*Opened by the Executor Agent*"
+
+// Analytics failures are logged and never fail the job.
+
+func (w *Worker) recordQueued(repoURL string, issue int, labeledAt time.Time) {
+	if w.metrics == nil {
+		return
+	}
+	if labeledAt.IsZero() {
+		labeledAt = time.Now()
+	}
+	if err := w.metrics.IssueQueued(repoURL, issue, labeledAt); err != nil {
+		w.log.Warn("failed to record queued issue", "issue", issue, "err", err)
+	}
+}
+
+func (w *Worker) recordOpened(repoURL string, issue int, prURL, title string) {
+	if w.metrics == nil {
+		return
+	}
+	if err := w.metrics.PROpened(repoURL, issue, issueNumberFromURL(prURL), title, time.Now()); err != nil {
+		w.log.Warn("failed to record opened PR", "issue", issue, "err", err)
+	}
+}
+
+func (w *Worker) recordFailed(repoURL string, issue int) {
+	if w.metrics == nil {
+		return
+	}
+	if err := w.metrics.RunFailed(repoURL, issue); err != nil {
+		w.log.Warn("failed to record failed run", "issue", issue, "err", err)
+	}
+}
+
+// recordUsage adds the tokens metered during a run to the issue's totals.
+func (w *Worker) recordUsage(repoURL string, issue int, meter *llm.Meter) {
+	if w.metrics == nil {
+		return
+	}
+	usage, cost, known := meter.Total()
+	if usage.Total() == 0 {
+		return
+	}
+	if !known {
+		w.log.Warn("no price for a model used; cost undercounted", "issue", issue)
+	}
+	if err := w.metrics.AddUsage(repoURL, issue, usage.Total(), cost); err != nil {
+		w.log.Warn("failed to record LLM usage", "issue", issue, "err", err)
+	}
+}
+
+// recordMerged records the merge of an agent PR, or the revert of one when a
+// revert PR is merged.
+func (w *Worker) recordMerged(repoURL, title, body string, mergedAt time.Time) {
+	if w.metrics == nil {
+		return
+	}
+	if mergedAt.IsZero() {
+		mergedAt = time.Now()
+	}
+
+	if reverted, ok := analytics.RevertedTitle(title); ok {
+		found, err := w.metrics.PRReverted(repoURL, reverted, mergedAt)
+		if err != nil {
+			w.log.Warn("failed to record revert", "title", reverted, "err", err)
+		} else if found {
+			w.log.Info("agent PR reverted", "title", reverted)
+		}
+		return
+	}
+
+	if !strings.Contains(body, prMarker) {
+		return
+	}
+	issue := issueNumberFromURL(git.ExtractIssueURL(body))
+	if issue == 0 {
+		return
+	}
+	if err := w.metrics.PRMerged(repoURL, issue, mergedAt); err != nil {
+		w.log.Warn("failed to record merged PR", "issue", issue, "err", err)
+	}
+}
@@ -0,0 +1,40 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// areaKey carries the monorepo subdirectory a run is scoped to, selected by
+// an "area:<name>" issue label. Tools default to it.
+type areaKey struct{}
+
+func contextWithArea(ctx context.Context, dir string) context.Context {
+	return context.WithValue(ctx, areaKey{}, dir)
+}
+
+// areaFrom returns the run's area directory, or "" for the whole repository.
+func areaFrom(ctx context.Context) string {
+	dir, _ := ctx.Value(areaKey{}).(string)
+	return dir
+}
+
+// areaPrompt tells the model which part of the repository the issue is about.
+func areaPrompt(name, dir string) string {
+	return fmt.Sprintf("\n\nThis issue is scoped to the %s area of a monorepo, in %s/. Work there: list_files and search_code default to that directory and run_command runs from it. "+
+		"Look outside it only for shared code the change depends on — pass subdir '.' or globs to list_files and search_code, and dir '.' to run_command.", name, dir)
+}
+
+// inDir prefixes command to run from dir, relative to the repository root.
+func inDir(dir, command string) (string, error) {
+	clean := path.Clean(dir)
+	if clean == "." {
+		return command, nil
+	}
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("dir %q is outside the repository", dir)
+	}
+	return "cd '" + strings.ReplaceAll(clean, "'", `'\''`) + "' && " + command, nil
+}
@@ -0,0 +1,114 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jadenj13/droid/internals/artifacts"
+	"github.com/jadenj13/droid/internals/git"
+)
+
+// TranscriptEntry is one event of a run: the model's text, or a tool call
+// with its full input and result.
+type TranscriptEntry struct {
+	Iteration int             `json:"iteration"`
+	Time      time.Time       `json:"time"`
+	Text      string          `json:"text,omitempty"`
+	Tool      string          `json:"tool,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	Result    string          `json:"result,omitempty"`
+}
+
+// transcript records a run for its artifact bundle. A nil transcript
+// records nothing.
+type transcript struct {
+	entries []TranscriptEntry
+	logs    strings.Builder // build, test and command output
+}
+
+type transcriptKey struct{}
+
+func contextWithTranscript(ctx context.Context, t *transcript) context.Context {
+	return context.WithValue(ctx, transcriptKey{}, t)
+}
+
+func transcriptFrom(ctx context.Context) *transcript {
+	t, _ := ctx.Value(transcriptKey{}).(*transcript)
+	return t
+}
+
+func (t *transcript) text(iter int, text string) {
+	if t == nil || strings.TrimSpace(text) == "" {
+		return
+	}
+	t.entries = append(t.entries, TranscriptEntry{Iteration: iter, Time: time.Now(), Text: text})
+}
+
+// tool records a tool call; the output of commands and tests also goes to
+// the log.
+func (t *transcript) tool(iter int, name string, input json.RawMessage, result string) {
+	if t == nil {
+		return
+	}
+	t.entries = append(t.entries, TranscriptEntry{Iteration: iter, Time: time.Now(), Tool: name, Input: input, Result: result})
+	switch name {
+	case "run_command", "run_tests", "record_test_run", "verify":
+		t.logs.WriteString(fmt.Sprintf("==> [iteration %d] %s %s\n%s\n\n", iter, name, string(input), result))
+	}
+}
+
+// saveArtifacts stores the bundle of a finished run and returns where it can
+// be opened, or "" if it could not be saved. Everything is redacted first.
+func (a *Agent) saveArtifacts(ctx context.Context, repoURL string, issue git.Issue, repo *git.Repo, branch, start string, t *transcript) string {
+	if a.artifacts == nil || t == nil {
+		return ""
+	}
+	entries := make([]TranscriptEntry, len(t.entries))
+	for i, e := range t.entries {
+		e.Text = a.redactor.String(e.Text)
+		e.Result = a.redactor.String(e.Result)
+		e.Input = json.RawMessage(a.redactor.String(string(e.Input)))
+		if !json.Valid(e.Input) {
+			e.Input = nil
+		}
+		entries[i] = e
+	}
+	transcriptJSON, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		a.log.Warn("failed to encode run transcript", "issue", issue.Number, "err", err)
+		return ""
+	}
+	diff, err := repo.DiffSince(ctx, start)
+	if err != nil {
+		a.log.Warn("failed to diff run for artifacts", "issue", issue.Number, "err", err)
+	}
+
+	runID := artifacts.NewRunID(time.Now())
+	manifest := artifacts.Manifest{RepoURL: repoURL, Issue: issue.Number, RunID: runID, Branch: branch, CreatedAt: time.Now()}
+	if len(entries) > 0 {
+		manifest.CreatedAt = entries[0].Time
+	}
+	location, err := a.artifacts.Save(ctx, artifacts.Key(repoURL, issue.Number, runID), manifest, []artifacts.File{
+		{Name: "transcript.json", ContentType: "application/json", Data: transcriptJSON},
+		{Name: "commands.log", Data: []byte(a.redactor.String(t.logs.String()))},
+		{Name: "final.diff", Data: []byte(a.redactor.String(diff))},
+	})
+	if err != nil {
+		a.log.Warn("failed to save run artifacts", "issue", issue.Number, "err", err)
+		return ""
+	}
+	a.log.Info("run artifacts saved", "issue", issue.Number, "location", location)
+	return location
+}
+
+// renderArtifacts is the PR body line pointing at a run's bundle.
+func renderArtifacts(location string) string {
+	what := "build and test logs, the final diff and the full tool transcript"
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		return fmt.Sprintf("[Run artifacts](%s): %s.", location, what)
+	}
+	return fmt.Sprintf("Run artifacts (%s) are in `%s` on the executor host.", what, location)
+}
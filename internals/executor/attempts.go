@@ -0,0 +1,248 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jadenj13/droid/internals/llm"
+	"github.com/jadenj13/droid/internals/retention"
+)
+
+// Step is one tool call made during a run, with previews of its input and
+// result.
+type Step struct {
+	Tool   string `json:"tool"`
+	Input  string `json:"input"`
+	Result string `json:"result"`
+}
+
+// Attempt records a failed run so that a retry of the same issue can see what
+// was already tried.
+type Attempt struct {
+	RepoURL    string     `json:"repo_url,omitempty"`
+	Issue      int        `json:"issue"`
+	Number     int        `json:"number"` // 1 for the first failed attempt
+	Error      string     `json:"error"`
+	Steps      []Step     `json:"steps"`
+	Summary    string     `json:"summary,omitempty"` // distilled account of what was tried and why it failed
+	Earlier    []string   `json:"earlier,omitempty"` // summaries of the attempts before this one
+	Plan       []PlanStep `json:"plan,omitempty"`    // the checklist the run kept
+	FinishedAt time.Time  `json:"finished_at"`
+}
+
+// RunError is returned by Agent.Run and Agent.Revise when the agentic loop
+// fails. It carries the steps taken before the failure.
+type RunError struct {
+	Err   error
+	Steps []Step
+	Plan  []PlanStep
+}
+
+func (e *RunError) Error() string { return e.Err.Error() }
+func (e *RunError) Unwrap() error { return e.Err }
+
+// stepsOf returns the steps recorded in err, if it is a RunError.
+func stepsOf(err error) []Step {
+	var runErr *RunError
+	if errors.As(err, &runErr) {
+		return runErr.Steps
+	}
+	return nil
+}
+
+// planOf returns the plan recorded in err, if it is a RunError.
+func planOf(err error) []PlanStep {
+	var runErr *RunError
+	if errors.As(err, &runErr) {
+		return runErr.Plan
+	}
+	return nil
+}
+
+// AttemptStore keeps the most recent failed attempt per issue as a JSON file.
+type AttemptStore struct {
+	dir string
+}
+
+func NewAttemptStore(dir string) (*AttemptStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create attempts dir: %w", err)
+	}
+	return &AttemptStore{dir: dir}, nil
+}
+
+func (s *AttemptStore) Save(repoURL string, a Attempt) error {
+	a.RepoURL = repoURL
+	b, err := json.Marshal(a)
+	if err != nil {
+		return fmt.Errorf("marshal attempt: %w", err)
+	}
+	if err := os.WriteFile(s.path(repoURL, a.Issue), b, 0o644); err != nil {
+		return fmt.Errorf("write attempt: %w", err)
+	}
+	return nil
+}
+
+// Load returns the last failed attempt for issue, or nil if there is none.
+func (s *AttemptStore) Load(repoURL string, issue int) (*Attempt, error) {
+	b, err := os.ReadFile(s.path(repoURL, issue))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read attempt: %w", err)
+	}
+	var a Attempt
+	if err := json.Unmarshal(b, &a); err != nil {
+		return nil, fmt.Errorf("decode attempt: %w", err)
+	}
+	return &a, nil
+}
+
+func (s *AttemptStore) Delete(repoURL string, issue int) error {
+	if err := os.Remove(s.path(repoURL, issue)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("delete attempt: %w", err)
+	}
+	return nil
+}
+
+func (s *AttemptStore) path(repoURL string, issue int) string {
+	key := strings.NewReplacer("https://", "", "http://", "", "/", "_", ":", "_").Replace(strings.TrimSuffix(repoURL, ".git"))
+	return filepath.Join(s.dir, fmt.Sprintf("%s_%d.json", key, issue))
+}
+
+// maxPriorSteps caps how many raw tool calls from a previous attempt are
+// replayed into a retry. Fewer are needed when a distilled summary exists.
+const (
+	maxPriorSteps        = 30
+	maxPriorStepsSummary = 10
+)
+
+// priorAttemptSection renders earlier failed attempts for the retry's initial
+// prompt: the distilled summary of each, then the final error and last tool
+// calls of the most recent one.
+func priorAttemptSection(a *Attempt) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("\n\nThis issue has failed %d time(s) before. You are starting from a fresh checkout — none of those changes were kept.\n", max(a.Number, 1)))
+
+	for i, summary := range a.Earlier {
+		sb.WriteString(fmt.Sprintf("\nAttempt %d:\n%s\n", i+1, summary))
+	}
+
+	sb.WriteString(fmt.Sprintf("\nMost recent attempt (%s) failed with:\n%s\n", a.FinishedAt.Format(time.RFC3339), a.Error))
+	if a.Summary != "" {
+		sb.WriteString("\nWhat it tried and why it failed:\n" + a.Summary + "\n")
+	}
+
+	if len(a.Plan) > 0 {
+		sb.WriteString("\nIts plan, as far as it got:\n" + renderPlan(a.Plan))
+	}
+
+	limit := maxPriorSteps
+	if a.Summary != "" {
+		limit = maxPriorStepsSummary
+	}
+	steps := a.Steps
+	if len(steps) > limit {
+		sb.WriteString(fmt.Sprintf("\nIt made %d tool calls; the last %d were:\n", len(steps), limit))
+		steps = steps[len(steps)-limit:]
+	} else if len(steps) > 0 {
+		sb.WriteString("\nTool calls it made:\n")
+	}
+	for i, st := range steps {
+		sb.WriteString(fmt.Sprintf("%d. %s(%s) → %s\n", i+1, st.Tool, st.Input, st.Result))
+	}
+
+	sb.WriteString("\nDo not repeat approaches that already failed. If the same obstacle blocks you again, explain it in the PR summary instead of looping.")
+	return sb.String()
+}
+
+const distillPrompt = `An autonomous coding agent failed to complete an issue. Below are the error it ended with and the tool calls it made.
+
+Write a short post-mortem for the next attempt, as 3-6 bullet points:
+- what approach it took
+- where exactly it got stuck or what failed (quote key error messages)
+- dead ends the next attempt should avoid
+- a suggested different approach, if one is apparent
+
+Be concrete and brief.`
+
+// distillAttempt asks the model for a short post-mortem of a failed attempt.
+func (a *Agent) distillAttempt(ctx context.Context, at Attempt) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("Error:\n" + at.Error + "\n\nTool calls:\n")
+	for i, st := range at.Steps {
+		sb.WriteString(fmt.Sprintf("%d. %s(%s) → %s\n", i+1, st.Tool, st.Input, st.Result))
+	}
+
+	resp, err := a.llm.CompleteWithTools(ctx, distillPrompt, []llm.Message{{Role: "user", Content: sb.String()}}, nil)
+	if err != nil {
+		return "", fmt.Errorf("distill attempt: %w", err)
+	}
+	return extractText(resp), nil
+}
+
+// Name implements retention.Source.
+func (s *AttemptStore) Name() string { return "executor_attempts" }
+
+// ExportData returns the recorded attempts f selects, matched on when they
+// finished. Attempts hold no Slack user data, so a user filter matches none.
+func (s *AttemptStore) ExportData(f retention.Filter) (any, error) {
+	out := []Attempt{}
+	err := s.each(func(_ string, a Attempt) error {
+		if f.Match(a.RepoURL, nil, a.FinishedAt) {
+			out = append(out, a)
+		}
+		return nil
+	})
+	return out, err
+}
+
+// DeleteData removes the recorded attempts f selects.
+func (s *AttemptStore) DeleteData(f retention.Filter) (int, error) {
+	n := 0
+	err := s.each(func(path string, a Attempt) error {
+		if !f.Match(a.RepoURL, nil, a.FinishedAt) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("delete attempt: %w", err)
+		}
+		n++
+		return nil
+	})
+	return n, err
+}
+
+// each calls fn for every stored attempt. Unreadable files are skipped.
+func (s *AttemptStore) each(fn func(path string, a Attempt) error) error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("read attempts dir: %w", err)
+	}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(s.dir, e.Name())
+		b, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var a Attempt
+		if json.Unmarshal(b, &a) != nil {
+			continue
+		}
+		if err := fn(path, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/llm"
+)
+
+const (
+	// maxBackportConflicts is the most conflicted files a backport tries to
+	// resolve; more than that is not a trivial conflict.
+	maxBackportConflicts = 5
+	// maxConflictBytes bounds a conflicted file sent for resolution.
+	maxConflictBytes = 60000
+)
+
+// backportMarker identifies the executor's reply to a backport command.
+const backportMarker = "<!-- droid:backport -->"
+
+// backportPRMarker identifies backport PRs. It differs from prMarker so a
+// merged backport is not counted as an issue run.
+const backportPRMarker = "*Backport opened by the Executor Agent*"
+
+const resolvePrompt = `You resolve merge conflicts left by cherry-picking a commit onto an older release branch.
+You are given one file containing git conflict markers: "ours" is the release branch, "theirs" is the change being backported.
+Resolve the conflict only if it is trivial — e.g. neighbouring edits, renamed context, import lists or formatting — so that the file keeps the release branch's code and gains the backported change.
+Reply with the complete resolved file in a single fenced code block and nothing else.
+If resolving needs judgement about behaviour, or the change depends on code the release branch does not have, reply with exactly CANNOT_RESOLVE.`
+
+// BackportResult is a backport branch pushed for a merged PR.
+type BackportResult struct {
+	Branch   string
+	SHA      string   // the cherry-picked commit
+	Resolved []string // files whose conflicts the LLM resolved
+	Failures string   // verification failures; the PR is opened as a draft
+}
+
+// backportFence matches the fenced block of a conflict resolution.
+var backportFence = regexp.MustCompile("(?s)```[^\\n]*\\n(.*?)\\n?```")
+
+// Backport cherry-picks pr's merge commit onto target in a new branch,
+// resolving trivial conflicts with the LLM, verifies the result with the
+// target branch's build and test commands, and pushes it.
+func (a *Agent) Backport(ctx context.Context, provider git.GitProvider, token string, pr git.PR, target string) (BackportResult, error) {
+	repo, _, err := a.clone(ctx, provider, token)
+	if err != nil {
+		return BackportResult{}, err
+	}
+	defer repo.Cleanup()
+
+	if err := repo.CheckoutRemoteBranch(ctx, target); err != nil {
+		return BackportResult{}, fmt.Errorf("checkout %s: %w", target, err)
+	}
+	// Build and test the way the release branch does.
+	cfg, err := loadRepoConfig(repo)
+	if err != nil {
+		return BackportResult{}, fmt.Errorf("load %s config: %w", target, err)
+	}
+	ctx = llm.ContextWithModel(ctx, cfg.Model)
+
+	result := BackportResult{Branch: backportBranch(pr.Number, target), SHA: pr.MergeSHA}
+	if err := repo.CreateBranch(ctx, result.Branch); err != nil {
+		return BackportResult{}, fmt.Errorf("create branch: %w", err)
+	}
+
+	a.log.Info("executor backporting", "pr", pr.Number, "sha", pr.MergeSHA, "target", target)
+	conflicts, err := repo.CherryPick(ctx, pr.MergeSHA)
+	if err != nil {
+		return BackportResult{}, err
+	}
+	if len(conflicts) > 0 {
+		if err := a.resolveConflicts(ctx, repo, conflicts); err != nil {
+			_ = repo.AbortCherryPick(ctx)
+			return BackportResult{}, err
+		}
+		if err := repo.ContinueCherryPick(ctx); err != nil {
+			return BackportResult{}, fmt.Errorf("commit resolved cherry-pick: %w", err)
+		}
+		result.Resolved = conflicts
+	}
+
+	if _, err := a.runSetup(ctx, repo, cfg); err != nil {
+		return BackportResult{}, err
+	}
+	if result.Failures, err = a.verify(ctx, repo, cfg); err != nil {
+		return BackportResult{}, err
+	}
+
+	if err := repo.Push(ctx); err != nil {
+		return BackportResult{}, fmt.Errorf("push: %w", err)
+	}
+	return result, nil
+}
+
+// resolveConflicts asks the LLM to resolve each conflicted file and writes
+// the resolutions. It fails if any file is not a trivial conflict.
+func (a *Agent) resolveConflicts(ctx context.Context, repo *git.Repo, files []string) error {
+	if len(files) > maxBackportConflicts {
+		return fmt.Errorf("%d files conflict (%s); only %d can be resolved automatically", len(files), strings.Join(files, ", "), maxBackportConflicts)
+	}
+	for _, f := range files {
+		content, err := repo.ReadFile(f)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", f, err)
+		}
+		if len(content) > maxConflictBytes {
+			return fmt.Errorf("%s conflicts and is too large to resolve automatically", f)
+		}
+		resp, err := a.llm.CompleteWithTools(ctx, resolvePrompt, []llm.Message{{Role: "user", Content: fmt.Sprintf("File: %s\n\n```\n%s\n```", f, content)}}, nil)
+		if err != nil {
+			return fmt.Errorf("resolve %s: %w", f, err)
+		}
+		text := extractText(resp)
+		m := backportFence.FindStringSubmatch(text)
+		if strings.Contains(text, "CANNOT_RESOLVE") || m == nil {
+			return fmt.Errorf("%s has a conflict that needs a human to resolve", f)
+		}
+		resolved := m[1]
+		if strings.Contains(resolved, "<<<<<<<") || strings.Contains(resolved, ">>>>>>>") {
+			return fmt.Errorf("%s still has conflict markers after resolution", f)
+		}
+		if strings.HasSuffix(content, "\n") && !strings.HasSuffix(resolved, "\n") {
+			resolved += "\n"
+		}
+		if err := repo.WriteFile(f, resolved); err != nil {
+			return fmt.Errorf("write %s: %w", f, err)
+		}
+		a.log.Info("backport conflict resolved", "file", f)
+	}
+	return nil
+}
+
+func backportBranch(prNumber int, target string) string {
+	slug := strings.NewReplacer("/", "-", "\\", "-", ":", "", " ", "-").Replace(target)
+	return fmt.Sprintf("agent/backport-%d-%s", prNumber, slug)
+}
+
+// backportArgs reads "<PR number> <branch>" from a backport command. On a PR
+// the number may be left out to backport that PR.
+func backportArgs(job commandJob) (int, string, error) {
+	args := job.Args
+	if len(args) == 1 && job.OnPR {
+		args = []string{strconv.Itoa(job.Issue.Number), args[0]}
+	}
+	if len(args) != 2 {
+		return 0, "", fmt.Errorf("usage: `/droid backport <PR number> <branch>`")
+	}
+	n, err := strconv.Atoi(strings.TrimLeft(args[0], "#!"))
+	if err != nil || n <= 0 {
+		return 0, "", fmt.Errorf("%q is not a PR number; usage: `/droid backport <PR number> <branch>`", args[0])
+	}
+	if !validBranch(args[1]) {
+		return 0, "", fmt.Errorf("%q is not a valid branch name", args[1])
+	}
+	return n, args[1], nil
+}
+
+// handleBackport runs a backport command and replies with the outcome. A
+// backport that cannot be done is reported in the reply, not retried.
+func (w *Worker) handleBackport(ctx context.Context, provider git.GitProvider, job commandJob) error {
+	reply := func(msg string) {
+		w.replyToCommand(ctx, provider, job, backportMarker, fmt.Sprintf("@%s, %s\n\n%s", job.Author, msg, backportMarker))
+	}
+	number, target, err := backportArgs(job)
+	if err != nil {
+		reply(err.Error())
+		return nil
+	}
+	pr, err := provider.GetPR(ctx, number)
+	if err != nil {
+		return fmt.Errorf("get PR %d: %w", number, err)
+	}
+	switch {
+	case !pr.Merged:
+		reply(fmt.Sprintf("#%d is not merged yet; only merged PRs can be backported.", number))
+		return nil
+	case pr.MergeSHA == "":
+		reply(fmt.Sprintf("#%d has no merge commit to cherry-pick.", number))
+		return nil
+	case pr.BaseBranch == target:
+		reply(fmt.Sprintf("#%d was merged into `%s` already.", number, target))
+		return nil
+	}
+
+	// The reply still goes out when the run hits the deadline.
+	runCtx, cancel := w.withDeadline(ctx)
+	defer cancel()
+
+	result, err := w.agent.Backport(runCtx, provider, w.token, pr, target)
+	if err != nil {
+		err = deadlineCause(runCtx, err)
+		w.log.Warn("backport failed", "pr", number, "target", target, "err", err)
+		reply(fmt.Sprintf("backporting #%d to `%s` failed: %s", number, target, w.agent.redactor.String(err.Error())))
+		return nil
+	}
+
+	url, err := provider.OpenPR(ctx, git.PRInput{
+		Title:  w.agent.redactor.String(fmt.Sprintf("[Backport %s] %s", target, pr.Title)),
+		Body:   w.agent.redactor.String(backportBody(pr, target, result)),
+		Branch: result.Branch,
+		Base:   target,
+		Draft:  result.Failures != "",
+	})
+	if err != nil {
+		return fmt.Errorf("open backport PR: %w", err)
+	}
+	w.log.Info("backport PR opened", "url", url, "pr", number, "target", target)
+
+	msg := fmt.Sprintf("opened %s backporting #%d to `%s`.", url, number, target)
+	if result.Failures != "" {
+		msg += " Verification failed, so it is a draft."
+	}
+	if len(result.Resolved) > 0 {
+		msg += " Conflicts were resolved automatically; review them closely."
+	}
+	reply(msg)
+	return nil
+}
+
+func backportBody(pr git.PR, target string, result BackportResult) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Backport of %s to `%s`.\n\nCherry-picked %s.\n", pr.URL, target, result.SHA))
+	if len(result.Resolved) > 0 {
+		sb.WriteString("\n### Conflicts resolved automatically\n\nThe cherry-pick conflicted in these files and the conflicts were resolved by the LLM. Review them closely:\n\n")
+		for _, f := range result.Resolved {
+			sb.WriteString(fmt.Sprintf("- `%s`\n", f))
+		}
+	}
+	if result.Failures != "" {
+		sb.WriteString("\n### :warning: Verification failed — opened as a draft\n\n" + result.Failures + "\n")
+	}
+	sb.WriteString("\n" + backportPRMarker)
+	return sb.String()
+}
@@ -0,0 +1,53 @@
+package executor
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/jadenj13/droid/internals/git"
+)
+
+// baseKeys introduce the line of an issue body naming the branch to work
+// from, e.g. "Base: release/1.4".
+var baseKeys = []string{"base", "base branch", "base-branch", "target branch"}
+
+// baseLabelPrefix marks a label naming the branch to work from, e.g.
+// "base:release/1.4".
+const baseLabelPrefix = "base:"
+
+var branchNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]*$`)
+
+// requestedBase returns the base branch an issue asks for, from a "base:"
+// label or a "Base:" line in its body, or "" to use the configured default.
+// A label wins over the body.
+func requestedBase(issue git.Issue) string {
+	for _, l := range issue.Labels {
+		if name, ok := strings.CutPrefix(l, baseLabelPrefix); ok && validBranch(strings.TrimSpace(name)) {
+			return strings.TrimSpace(name)
+		}
+	}
+	for _, line := range strings.Split(issue.Body, "\n") {
+		key, value, ok := strings.Cut(strings.Trim(line, " \t*_"), ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.Trim(key, " *_"))
+		for _, k := range baseKeys {
+			if key != k {
+				continue
+			}
+			value = strings.Trim(strings.TrimSpace(value), "*_`")
+			if validBranch(value) {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// validBranch rejects values that are not plausible branch names, such as
+// prose or option-like strings.
+func validBranch(name string) bool {
+	return branchNamePattern.MatchString(name) && !strings.Contains(name, "..") &&
+		!strings.HasSuffix(name, "/") && !strings.HasSuffix(name, ".lock")
+}
@@ -0,0 +1,108 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jadenj13/droid/internals/git"
+)
+
+// blockedLabel marks an issue the planner created with open dependencies. The
+// executor leaves it until every issue under its "Depends On" heading is
+// closed.
+const blockedLabel = "agent:blocked"
+
+// blockedMarker identifies the note saying what a blocked issue waits on.
+const blockedMarker = "<!-- droid:blocked -->"
+
+// openDependencies returns the issues issue depends on that are still open.
+func openDependencies(ctx context.Context, provider git.GitProvider, issue git.Issue) ([]int, error) {
+	var open []int
+	for _, n := range git.DependsOn(issue.Body) {
+		dep, err := provider.GetIssue(ctx, n)
+		if err != nil {
+			return nil, fmt.Errorf("fetch dependency #%d: %w", n, err)
+		}
+		if dep.State != "closed" {
+			open = append(open, n)
+		}
+	}
+	return open, nil
+}
+
+// waitForDependencies reports whether issue must wait for open dependencies,
+// in which case it notes on the issue what it waits on. An issue no longer
+// waiting loses its blocked label.
+func (w *Worker) waitForDependencies(ctx context.Context, provider git.GitProvider, issue git.Issue) (bool, error) {
+	if !issue.HasLabel(blockedLabel) {
+		return false, nil
+	}
+	open, err := openDependencies(ctx, provider, issue)
+	if err != nil {
+		return false, err
+	}
+	if len(open) > 0 {
+		refs := make([]string, len(open))
+		for i, n := range open {
+			refs[i] = fmt.Sprintf("#%d", n)
+		}
+		note := fmt.Sprintf(":hourglass: **Waiting on %s.** This issue starts automatically once they are closed. Remove the `%s` label to start it now.\n\n%s",
+			strings.Join(refs, ", "), blockedLabel, blockedMarker)
+		if err := provider.UpsertMarkedIssueComment(ctx, issue.Number, blockedMarker, note); err != nil {
+			w.log.Warn("failed to post blocked note", "issue", issue.Number, "err", err)
+		}
+		w.log.Info("issue blocked on open dependencies, skipping", "issue", issue.Number, "open", open)
+		return true, nil
+	}
+	if err := provider.RemoveLabel(ctx, issue.Number, blockedLabel); err != nil {
+		w.log.Warn("failed to remove blocked label", "issue", issue.Number, "err", err)
+	}
+	return false, nil
+}
+
+// HandleIssueClosed starts the blocked issues that were waiting only on the
+// closed issue: it removes their blocked label and re-adds agent:ready, so
+// the webhook sees a fresh "labeled" event.
+func (w *Worker) HandleIssueClosed(ctx context.Context, repoURL string, number int) error {
+	provider, _, err := w.factory.ProviderFor(ctx, repoURL)
+	if err != nil {
+		return fmt.Errorf("build provider: %w", err)
+	}
+	blocked, err := provider.ListIssuesByLabel(ctx, blockedLabel)
+	if err != nil {
+		return fmt.Errorf("list blocked issues: %w", err)
+	}
+	for _, issue := range blocked {
+		if !slices.Contains(git.DependsOn(issue.Body), number) {
+			continue
+		}
+		open, err := openDependencies(ctx, provider, issue)
+		if err != nil {
+			w.log.Warn("could not check dependencies", "issue", issue.Number, "err", err)
+			continue
+		}
+		if len(open) > 0 {
+			continue
+		}
+		if err := provider.RemoveLabel(ctx, issue.Number, blockedLabel); err != nil {
+			return fmt.Errorf("remove blocked label from #%d: %w", issue.Number, err)
+		}
+		w.log.Info("issue unblocked", "issue", issue.Number, "closed", number)
+		note := fmt.Sprintf(":arrow_forward: **Unblocked.** #%d was closed and this issue no longer waits on anything.\n\n%s", number, blockedMarker)
+		if err := provider.UpsertMarkedIssueComment(ctx, issue.Number, blockedMarker, note); err != nil {
+			w.log.Warn("failed to update blocked note", "issue", issue.Number, "err", err)
+		}
+		if !issue.HasLabel("agent:ready") {
+			continue
+		}
+		if err := provider.RemoveLabel(ctx, issue.Number, "agent:ready"); err != nil {
+			w.log.Debug("remove label before unblocking", "err", err)
+		}
+		if err := provider.AddLabel(ctx, issue.Number, "agent:ready"); err != nil {
+			return fmt.Errorf("add agent:ready label to #%d: %w", issue.Number, err)
+		}
+	}
+	return nil
+}
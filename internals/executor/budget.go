@@ -0,0 +1,118 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/llm"
+	"github.com/jadenj13/droid/internals/repoconfig"
+)
+
+// budgetLabel marks issues whose run stopped at its budget and left a draft
+// PR with the work so far.
+const budgetLabel = "agent:budget-exceeded"
+
+// budgetMarker identifies the executor's comment on a PR whose revision
+// stopped at its budget.
+const budgetMarker = "<!-- droid:budget -->"
+
+// errBudgetExceeded marks a run that hit its budget with nothing to hand over.
+var errBudgetExceeded = errors.New("budget exceeded")
+
+// budgetWatch tracks one run against its budget and iteration limit.
+type budgetWatch struct {
+	budget repoconfig.Budget
+	limit  int
+	start  time.Time
+	meter  *llm.Meter // nil when the caller does not meter the run
+}
+
+// exceeded returns why the run must stop before iteration i, or "".
+func (b budgetWatch) exceeded(i int) string {
+	if i >= b.limit {
+		return fmt.Sprintf("reached the %d-iteration limit", b.limit)
+	}
+	if b.budget.Duration > 0 {
+		if elapsed := time.Since(b.start); elapsed >= b.budget.Duration {
+			return fmt.Sprintf("ran for %s, over the %s time budget", elapsed.Round(time.Second), b.budget.Duration)
+		}
+	}
+	if b.meter == nil {
+		return ""
+	}
+	usage, cost, _ := b.meter.Total()
+	if b.budget.USD > 0 && cost >= b.budget.USD {
+		return fmt.Sprintf("spent $%.2f, over the $%.2f cost budget", cost, b.budget.USD)
+	}
+	if b.budget.Tokens > 0 && usage.Total() >= b.budget.Tokens {
+		return fmt.Sprintf("used %d tokens, over the %d-token budget", usage.Total(), b.budget.Tokens)
+	}
+	return ""
+}
+
+// stopAtBudget commits the work in progress so it can go out as a draft PR
+// describing what is left. A run that changed nothing has nothing to hand
+// over and fails instead.
+func (a *Agent) stopAtBudget(ctx context.Context, repo *git.Repo, ws *workspace, issue git.Issue, start, reason string, checklist []PlanStep, steps []Step) (ToolResult, error) {
+	a.log.Warn("executor stopped at budget", "issue", issue.Number, "reason", reason)
+	msg := "WIP: stopped at the executor budget\n\nThe run " + reason + " before finishing."
+	if _, err := repo.Commit(ctx, msg); err != nil {
+		return ToolResult{}, fmt.Errorf("commit work in progress: %w", err)
+	}
+	if ws != nil {
+		for _, co := range ws.others {
+			if _, err := co.repo.Commit(ctx, msg); err != nil {
+				return ToolResult{}, fmt.Errorf("%s: commit work in progress: %w", co.name, err)
+			}
+		}
+	}
+	head, err := repo.Head(ctx)
+	if err != nil {
+		return ToolResult{}, fmt.Errorf("resolve HEAD: %w", err)
+	}
+	if head == start {
+		return ToolResult{}, fmt.Errorf("%w: the run %s without making any changes", errBudgetExceeded, reason)
+	}
+
+	return ToolResult{
+		Done:      true,
+		PRTitle:   "WIP: " + issue.Title,
+		PRSummary: fmt.Sprintf("Work in progress on #%d. The executor %s and stopped early; this draft holds what it finished.", issue.Number, reason),
+		PRDraft:   true,
+		PRNotes:   remainingWork(checklist, steps),
+		Budget:    reason,
+	}, nil
+}
+
+// remainingWork describes what a stopped run left undone: the unfinished
+// steps of its plan, or its last tool calls when it kept no plan.
+func remainingWork(checklist []PlanStep, steps []Step) string {
+	var sb strings.Builder
+	if len(checklist) > 0 {
+		var left []PlanStep
+		for _, s := range checklist {
+			if s.Status != planDone && s.Status != planSkipped {
+				left = append(left, s)
+			}
+		}
+		if len(left) == 0 {
+			sb.WriteString("Every step of the agent's plan was finished, but the work was not verified or submitted.\n\n")
+		} else {
+			sb.WriteString("Steps of the agent's plan still open:\n\n" + renderPlan(left) + "\n")
+		}
+		sb.WriteString("Full plan:\n\n" + renderPlan(checklist))
+		return sb.String()
+	}
+	sb.WriteString("The agent kept no plan. Its last tool calls were:\n\n")
+	if len(steps) > maxFailureSteps {
+		steps = steps[len(steps)-maxFailureSteps:]
+	}
+	for _, st := range steps {
+		sb.WriteString(fmt.Sprintf("- `%s` %s → %s\n", st.Tool, inlineCode(st.Input), inlineCode(st.Result)))
+	}
+	return sb.String()
+}
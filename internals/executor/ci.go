@@ -0,0 +1,152 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/repoconfig"
+)
+
+const (
+	// ciPollInterval is how often a running pipeline is checked.
+	ciPollInterval = 20 * time.Second
+	// ciStartGrace is how long CI gets to pick up a push before the
+	// repository is taken to have none.
+	ciStartGrace = 2 * time.Minute
+)
+
+// ciMarker identifies the executor's comment on a PR whose revision left CI
+// failing.
+const ciMarker = "<!-- droid:ci -->"
+
+// CIConfig makes the executor wait for the repository's CI pipeline after
+// each push and fix what fails.
+type CIConfig struct {
+	Timeout   time.Duration // how long to wait for one pipeline; 0 disables CI checks
+	FixRounds int           // automatic fix rounds after a failed pipeline
+}
+
+// WithCI waits for CI after each push, hands failing job logs back to the
+// agent for up to c.FixRounds fix rounds, and links the pipeline from the PR.
+func WithCI(c CIConfig) AgentOption {
+	return func(a *Agent) { a.ci = c }
+}
+
+// awaitCI waits for the pipeline of the pushed branch. While it fails, the
+// agent gets the failing jobs' logs in a fresh loop, and its fixes are
+// pushed. A pipeline still failing once the rounds are used up turns the
+// result into a draft that says so. Errors talking to CI are logged and
+// leave the result as it is; only a failed push is returned.
+func (a *Agent) awaitCI(ctx context.Context, provider git.GitProvider, repo *git.Repo, issue git.Issue, cfg repoconfig.Config, branch, systemExtra string, result *ToolResult) (git.Pipeline, error) {
+	if a.ci.Timeout <= 0 || result.Budget != "" {
+		return git.Pipeline{}, nil
+	}
+	for round := 0; ; round++ {
+		sha, err := repo.Head(ctx)
+		if err != nil {
+			return git.Pipeline{}, fmt.Errorf("resolve HEAD: %w", err)
+		}
+		p, err := a.waitPipeline(ctx, provider, branch, sha)
+		if err != nil {
+			a.log.Warn("failed to check CI", "issue", issue.Number, "sha", sha, "err", err)
+			return p, nil
+		}
+		a.log.Info("CI finished", "issue", issue.Number, "sha", sha, "status", p.Status, "url", p.URL)
+		if p.Status != git.PipelineFailed && p.Status != git.PipelineCanceled {
+			// Passed, still running at the timeout, or no CI at all.
+			return p, nil
+		}
+
+		jobs, err := provider.FailedJobs(ctx, p)
+		if err != nil {
+			a.log.Warn("failed to fetch failed CI jobs", "issue", issue.Number, "err", err)
+		}
+		if round >= a.ci.FixRounds {
+			result.PRDraft = true
+			result.PRNotes += "\n\n" + renderCIFailure(p, jobs, round)
+			return p, nil
+		}
+
+		a.log.Info("executor fixing CI", "issue", issue.Number, "round", round+1, "failed_jobs", len(jobs))
+		fix, err := a.runLoop(contextWithQuestions(ctx, false), repo, issue, cfg, systemExtra, a.ciFixPrompt(issue, branch, p, jobs), nil, nil)
+		if err != nil {
+			a.log.Warn("CI fix round failed", "issue", issue.Number, "round", round+1, "err", err)
+			result.PRDraft = true
+			result.PRNotes += "\n\n" + renderCIFailure(p, jobs, round+1)
+			return p, nil
+		}
+		if fix.PRDraft {
+			result.PRDraft = true
+			result.PRNotes += "\n\n" + fix.PRNotes
+		}
+		if err := repo.Push(ctx); err != nil {
+			return p, fmt.Errorf("push CI fix: %w", err)
+		}
+	}
+}
+
+// waitPipeline waits for the pipeline of sha to finish, or for a.ci.Timeout.
+func (a *Agent) waitPipeline(ctx context.Context, provider git.GitProvider, branch, sha string) (git.Pipeline, error) {
+	start := time.Now()
+	p, err := provider.StartPipeline(ctx, branch, sha)
+	for err == nil && !p.Status.Done() {
+		if p.Status == git.PipelineNone && time.Since(start) >= ciStartGrace {
+			return p, nil
+		}
+		if time.Since(start) >= a.ci.Timeout {
+			return p, nil
+		}
+		select {
+		case <-ctx.Done():
+			return p, ctx.Err()
+		case <-time.After(ciPollInterval):
+		}
+		if p.Status == git.PipelineNone {
+			p, err = provider.StartPipeline(ctx, branch, sha)
+		} else {
+			p, err = provider.GetPipeline(ctx, p)
+		}
+	}
+	return p, err
+}
+
+func (a *Agent) ciFixPrompt(issue git.Issue, branch string, p git.Pipeline, jobs []git.JobLog) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("You are continuing work on issue #%d: %s\n\n%s\n\n", issue.Number, issue.Title, issue.Body))
+	sb.WriteString(fmt.Sprintf("Your changes are committed and pushed to branch %s, but its CI pipeline failed (%s).\n\n", branch, p.URL))
+	if len(jobs) == 0 {
+		sb.WriteString("The failing jobs' logs could not be fetched. Run the repository's build, lint and tests to find the failure.\n\n")
+	}
+	for _, j := range jobs {
+		sb.WriteString(fmt.Sprintf("Failed job %s — end of its log:\n```\n%s\n```\n\n", j.Name, a.redactor.String(j.Log)))
+	}
+	sb.WriteString("Reproduce the failures locally where you can, fix them, commit, and call submit_work. " +
+		"If a failure is unrelated to your change — a flaky test or broken infrastructure — give low confidence in submit_work and say so in its notes.")
+	return sb.String()
+}
+
+// renderCIFailure is the PR note for a pipeline left failing after rounds
+// fix rounds.
+func renderCIFailure(p git.Pipeline, jobs []git.JobLog, rounds int) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("The [CI pipeline](%s) %s", p.URL, p.Status))
+	if rounds > 0 {
+		sb.WriteString(fmt.Sprintf(" after %d automatic fix round(s)", rounds))
+	}
+	sb.WriteString(".")
+	if len(jobs) > 0 {
+		sb.WriteString(" Failing jobs:\n")
+		for _, j := range jobs {
+			sb.WriteString(fmt.Sprintf("- [%s](%s)\n", j.Name, j.URL))
+		}
+	}
+	return sb.String()
+}
+
+// renderPipeline is the PR body line linking the pipeline.
+func renderPipeline(p git.Pipeline) string {
+	return fmt.Sprintf("CI: [pipeline](%s) — %s.", p.URL, p.Status)
+}
@@ -0,0 +1,154 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jadenj13/droid/internals/git"
+)
+
+// commandLabels maps the verbs accepted in issue comment commands onto the
+// label that starts the corresponding job.
+var commandLabels = map[string]string{
+	"implement": "agent:ready",
+	"fix":       "agent:ready",
+	"start":     "agent:ready",
+	"retry":     "agent:ready",
+	"revise":    "agent:revision", // or the worker's renamed revision label
+	"apply":     applyLabel,
+}
+
+// commandBackport cherry-picks a merged PR onto another branch. It takes
+// arguments and is also accepted on PRs:
+//
+//	/droid backport <PR number> <branch>
+const commandBackport = "backport"
+
+// commandPattern matches "/droid <verb> [args]" or "@droid <verb> [args]" at
+// the start of a comment line.
+var commandPattern = regexp.MustCompile(`(?im)^\s*[/@]droid\s+([a-z]+)\b([^\n]*)`)
+
+// commandText returns the part of body after the first verb command, lines
+// after it included.
+func commandText(body, verb string) string {
+	for _, m := range commandPattern.FindAllStringSubmatchIndex(body, -1) {
+		if strings.EqualFold(body[m[2]:m[3]], verb) {
+			return strings.TrimSpace(body[m[4]:])
+		}
+	}
+	return ""
+}
+
+// parseCommand returns the verb and arguments of the first command in a
+// comment.
+func parseCommand(body string) (string, []string, bool) {
+	for _, m := range commandPattern.FindAllStringSubmatch(body, -1) {
+		verb := strings.ToLower(m[1])
+		if _, ok := commandLabels[verb]; ok || verb == commandBackport || verb == commandAnswer {
+			return verb, strings.Fields(m[2]), true
+		}
+	}
+	return "", nil, false
+}
+
+type commandJob struct {
+	RepoURL     string    `json:"repo_url"`
+	Issue       git.Issue `json:"issue"`
+	Author      string    `json:"author"`
+	Verb        string    `json:"verb"`
+	Args        []string  `json:"args,omitempty"`
+	Text        string    `json:"text,omitempty"`  // the rest of the comment after the verb, for answer
+	OnPR        bool      `json:"on_pr,omitempty"` // Issue is the PR the comment was left on
+	CommentedAt time.Time `json:"commented_at,omitzero"`
+}
+
+// commandMarker identifies the executor's reply to a refused command.
+const commandMarker = "<!-- droid:command -->"
+
+// HandleCommand starts the job a comment command asks for by applying its
+// label, so the run goes through the same path as a labeled issue. The
+// comment's author needs the configured permission on the repository, or to
+// be on the allowlist.
+func (w *Worker) HandleCommand(ctx context.Context, job commandJob) error {
+	label, ok := w.commandLabel(job.Verb)
+	if !ok && job.Verb != commandBackport && job.Verb != commandAnswer || job.OnPR && job.Verb != commandBackport {
+		return nil
+	}
+	provider, _, err := w.factory.ProviderFor(ctx, job.RepoURL)
+	if err != nil {
+		return fmt.Errorf("build provider: %w", err)
+	}
+
+	allowed, err := w.commandAllowed(ctx, provider, job.Author)
+	if err != nil {
+		return fmt.Errorf("check permission of %s: %w", job.Author, err)
+	}
+	if !allowed {
+		w.log.Info("command refused", "issue", job.Issue.Number, "author", job.Author, "verb", job.Verb)
+		hint := fmt.Sprintf("Ask a maintainer to run it or to add the `%s` label.", label)
+		if !ok {
+			hint = "Ask a maintainer to run it."
+		}
+		body := fmt.Sprintf("@%s, starting the executor from a comment needs %s access to this repository, so `%s` was ignored. %s\n\n%s",
+			job.Author, w.commandPermission, job.Verb, hint, commandMarker)
+		w.replyToCommand(ctx, provider, job, commandMarker, body)
+		return nil
+	}
+	if job.Verb == commandBackport {
+		return w.handleBackport(ctx, provider, job)
+	}
+	if job.Verb == commandAnswer {
+		return w.handleAnswer(ctx, provider, job)
+	}
+
+	// Remove and re-add so the webhook sees a fresh "labeled" event.
+	if err := provider.RemoveLabel(ctx, job.Issue.Number, label); err != nil {
+		w.log.Debug("remove label before command", "label", label, "err", err)
+	}
+	if err := provider.AddLabel(ctx, job.Issue.Number, label); err != nil {
+		return fmt.Errorf("add %s label: %w", label, err)
+	}
+	w.log.Info("command accepted", "issue", job.Issue.Number, "author", job.Author, "verb", job.Verb, "label", label)
+	return nil
+}
+
+// commandLabel returns the label that starts the job verb asks for.
+func (w *Worker) commandLabel(verb string) (string, bool) {
+	if verb == "revise" {
+		return w.revisionLabel, true
+	}
+	label, ok := commandLabels[verb]
+	return label, ok
+}
+
+func (w *Worker) commandAllowed(ctx context.Context, provider git.GitProvider, author string) (bool, error) {
+	if author == "" {
+		return false, nil
+	}
+	if slices.ContainsFunc(w.commandUsers, func(u string) bool { return strings.EqualFold(u, author) }) {
+		return true, nil
+	}
+	p, err := provider.UserPermission(ctx, author)
+	if err != nil {
+		return false, err
+	}
+	return p >= w.commandPermission, nil
+}
+
+// replyToCommand upserts the executor's marked reply on the issue or PR the
+// command was left on.
+func (w *Worker) replyToCommand(ctx context.Context, provider git.GitProvider, job commandJob, marker, body string) {
+	var err error
+	if job.OnPR {
+		err = provider.UpsertMarkedComment(ctx, job.Issue.Number, marker, body)
+	} else {
+		err = provider.UpsertMarkedIssueComment(ctx, job.Issue.Number, marker, body)
+	}
+	if err != nil {
+		w.log.Warn("failed to reply to command", "issue", job.Issue.Number, "verb", job.Verb, "err", err)
+	}
+}
@@ -0,0 +1,82 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jadenj13/droid/internals/git"
+)
+
+// completionMarker identifies the completion note, so a redelivered merge
+// event edits it instead of posting another.
+const completionMarker = "<!-- droid:completed -->"
+
+// completeIssue closes the issue behind a merged agent PR, which GitLab does
+// not always do itself, removes its workflow labels and posts a
+// completion note with the cycle time and LLM cost.
+func (w *Worker) completeIssue(ctx context.Context, provider git.GitProvider, repoURL string, number int, prURL string) error {
+	issue, err := provider.GetIssue(ctx, number)
+	if err != nil {
+		return fmt.Errorf("fetch issue: %w", err)
+	}
+
+	if issue.State != "closed" {
+		if err := provider.CloseIssue(ctx, number); err != nil {
+			return fmt.Errorf("close issue #%d: %w", number, err)
+		}
+		w.log.Info("issue closed after merge", "issue", number)
+	}
+	for _, l := range issue.Labels {
+		workflow := strings.HasPrefix(l, "agent:") || l == w.reviewLabel || l == w.revisionLabel || l == w.approvedLabel
+		if !workflow || l == trackingLabel {
+			continue
+		}
+		if err := provider.RemoveLabel(ctx, number, l); err != nil {
+			w.log.Warn("failed to remove workflow label", "issue", number, "label", l, "err", err)
+		}
+	}
+
+	if err := provider.UpsertMarkedIssueComment(ctx, number, completionMarker, w.completionNote(repoURL, number, prURL)); err != nil {
+		w.log.Warn("failed to post completion note", "issue", number, "err", err)
+	}
+	return nil
+}
+
+func (w *Worker) completionNote(repoURL string, number int, prURL string) string {
+	var sb strings.Builder
+	sb.WriteString(":white_check_mark: **Done.** ")
+	if prURL != "" {
+		sb.WriteString(fmt.Sprintf("%s was merged.", prURL))
+	} else {
+		sb.WriteString("The executor's PR was merged.")
+	}
+	if w.metrics != nil {
+		if run, ok := w.metrics.Get(repoURL, number); ok {
+			sb.WriteString("\n\n")
+			if !run.QueuedAt.IsZero() && !run.MergedAt.IsZero() {
+				sb.WriteString(fmt.Sprintf("- Cycle time: %s from `agent:ready` to merge\n", cycleTime(run.MergedAt.Sub(run.QueuedAt))))
+			}
+			if run.Tokens > 0 {
+				sb.WriteString(fmt.Sprintf("- Executor LLM cost: $%.2f (%d tokens, list price)\n", run.CostUSD, run.Tokens))
+			}
+			if run.Failures > 0 {
+				sb.WriteString(fmt.Sprintf("- Failed runs along the way: %d\n", run.Failures))
+			}
+		}
+	}
+	sb.WriteString("\n" + completionMarker)
+	return sb.String()
+}
+
+// cycleTime renders d in days and hours, or hours and minutes when shorter.
+func cycleTime(d time.Duration) string {
+	if d >= 24*time.Hour {
+		return fmt.Sprintf("%dd %dh", d/(24*time.Hour), d%(24*time.Hour)/time.Hour)
+	}
+	if d >= time.Hour {
+		return fmt.Sprintf("%dh %dm", d/time.Hour, d%time.Hour/time.Minute)
+	}
+	return d.Round(time.Minute).String()
+}
@@ -0,0 +1,90 @@
+package executor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DeliveryLog remembers the webhook deliveries the executor has accepted, by
+// the ID the git host gives each one, so a redelivery is acknowledged without
+// being processed again. IDs are forgotten after the log's TTL, which should
+// outlast the window in which the host redelivers.
+type DeliveryLog struct {
+	path string
+	ttl  time.Duration
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// NewDeliveryLog opens the log kept in the JSON file at path.
+func NewDeliveryLog(path string, ttl time.Duration) (*DeliveryLog, error) {
+	l := &DeliveryLog{path: path, ttl: ttl, seen: make(map[string]time.Time)}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read deliveries file: %w", err)
+	}
+	if err := json.Unmarshal(b, &l.seen); err != nil {
+		return nil, fmt.Errorf("decode deliveries file: %w", err)
+	}
+	return l, nil
+}
+
+// Claim records delivery id, reporting false if it was already recorded. An
+// empty id, from a host or proxy that sends none, is always claimed.
+func (l *DeliveryLog) Claim(id string) bool {
+	if id == "" {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if at, ok := l.seen[id]; ok && now.Sub(at) < l.ttl {
+		return false
+	}
+	l.seen[id] = now
+	for k, at := range l.seen {
+		if now.Sub(at) >= l.ttl {
+			delete(l.seen, k)
+		}
+	}
+	return true
+}
+
+// Release forgets delivery id, so that a delivery the executor failed to
+// accept is processed when the host retries it.
+func (l *DeliveryLog) Release(id string) {
+	if id == "" {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.seen, id)
+}
+
+// Save writes the log to its file.
+func (l *DeliveryLog) Save() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, err := json.Marshal(l.seen)
+	if err != nil {
+		return fmt.Errorf("marshal deliveries: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return fmt.Errorf("create deliveries dir: %w", err)
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return fmt.Errorf("write deliveries: %w", err)
+	}
+	return os.Rename(tmp, l.path)
+}
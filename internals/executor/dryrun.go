@@ -0,0 +1,334 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/retention"
+)
+
+// dryRunLabel makes the run of an issue a dry run, whatever WithDryRun says.
+const dryRunLabel = "agent:dry-run"
+
+// applyLabel turns the patch of an issue's dry run into a branch and PR.
+const applyLabel = "agent:apply"
+
+// dryRunMarker identifies the executor's dry-run patch comment on an issue.
+const dryRunMarker = "<!-- droid:dry-run -->"
+
+// maxCommentPatch caps the patch shown in the dry-run comment, under the
+// git hosts' comment size limits.
+const maxCommentPatch = 50000
+
+// WithDryRun makes every run a dry run: the agent does the work but pushes
+// nothing, and the patch is posted on the issue for approval instead of
+// opening a PR. Issues labeled agent:dry-run get a dry run regardless.
+func WithDryRun(on bool) AgentOption {
+	return func(a *Agent) { a.dryRun = on }
+}
+
+// isDryRun reports whether a fresh run of issue must push nothing. A resumed
+// run has already pushed its work, so it carries on as a normal run.
+func (a *Agent) isDryRun(issue git.Issue, resume *Pause) bool {
+	return resume == nil && (a.dryRun || issue.HasLabel(dryRunLabel))
+}
+
+// dryRunResult ends a dry run with its patch in place of a push.
+func (a *Agent) dryRunResult(ctx context.Context, provider git.GitProvider, issue git.Issue, repo *git.Repo, branch, base, start string, record *transcript, result ToolResult, proof *TestProof) (PRResult, error) {
+	patch, err := repo.Patch(ctx, start)
+	if err != nil {
+		return PRResult{}, fmt.Errorf("diff dry run: %w", err)
+	}
+	if strings.TrimSpace(patch) == "" {
+		return PRResult{}, fmt.Errorf("dry run made no changes")
+	}
+	return PRResult{
+		Branch:     branch,
+		BaseBranch: base,
+		Title:      result.PRTitle,
+		Summary:    result.PRSummary,
+		IssueURL:   issue.URL,
+		Draft:      result.PRDraft,
+		Notes:      result.PRNotes,
+		TestProof:  proof,
+
+		BudgetExceeded: result.Budget,
+		Artifacts:      a.saveArtifacts(ctx, provider.RepoURL(), issue, repo, branch, start, record),
+		Patch:          patch,
+	}, nil
+}
+
+// DryRun is the outcome of a dry run, kept until its patch is applied.
+type DryRun struct {
+	RepoURL    string     `json:"repo_url,omitempty"`
+	Issue      int        `json:"issue"`
+	Branch     string     `json:"branch"`
+	BaseBranch string     `json:"base_branch"`
+	Title      string     `json:"title"`
+	Summary    string     `json:"summary"`
+	Draft      bool       `json:"draft,omitempty"`
+	Notes      string     `json:"notes,omitempty"`
+	TestProof  *TestProof `json:"test_proof,omitempty"`
+	Artifacts  string     `json:"artifacts,omitempty"`
+	Patch      string     `json:"patch"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// result is the PRResult the applied patch opens its PR with.
+func (d *DryRun) result() PRResult {
+	return PRResult{
+		Branch:     d.Branch,
+		BaseBranch: d.BaseBranch,
+		Title:      d.Title,
+		Summary:    d.Summary,
+		Draft:      d.Draft,
+		Notes:      d.Notes,
+		TestProof:  d.TestProof,
+		Artifacts:  d.Artifacts,
+	}
+}
+
+// renderDryRun is the issue comment proposing a dry run's patch.
+func renderDryRun(d *DryRun, kept bool) string {
+	var sb strings.Builder
+	sb.WriteString("The executor finished a dry run and pushed nothing. Review the change below")
+	if kept {
+		sb.WriteString(fmt.Sprintf("; add the `%s` label or comment `/droid apply` to push it to branch `%s` and open the PR.\n\n", applyLabel, d.Branch))
+	} else {
+		sb.WriteString(". Dry runs are not kept on this deployment, so apply the patch by hand.\n\n")
+	}
+	sb.WriteString(fmt.Sprintf("### %s\n\n%s\n\n", d.Title, d.Summary))
+	if d.Draft && d.Notes != "" {
+		sb.WriteString("### :warning: Low confidence\n\n" + d.Notes + "\n\n")
+	}
+	patch := d.Patch
+	if len(patch) > maxCommentPatch {
+		patch = patch[:maxCommentPatch] + "\n… truncated; the full patch is applied\n"
+	}
+	sb.WriteString("<details><summary>Patch</summary>\n\n```diff\n" + patch + "\n```\n</details>\n\n")
+	if d.Artifacts != "" {
+		sb.WriteString(renderArtifacts(d.Artifacts) + "\n\n")
+	}
+	sb.WriteString(dryRunMarker)
+	return sb.String()
+}
+
+// proposePatch keeps a dry run's patch and posts it on the issue for approval.
+func (w *Worker) proposePatch(ctx context.Context, provider git.GitProvider, repoURL string, issue git.Issue, result PRResult) error {
+	d := &DryRun{
+		RepoURL:    repoURL,
+		Issue:      issue.Number,
+		Branch:     result.Branch,
+		BaseBranch: result.BaseBranch,
+		Title:      result.Title,
+		Summary:    result.Summary,
+		Draft:      result.Draft || result.BudgetExceeded != "",
+		Notes:      result.Notes,
+		TestProof:  result.TestProof,
+		Artifacts:  result.Artifacts,
+		Patch:      result.Patch,
+		CreatedAt:  time.Now(),
+	}
+	if w.dryRuns != nil {
+		if err := w.dryRuns.Save(*d); err != nil {
+			return fmt.Errorf("save dry run: %w", err)
+		}
+	}
+	if err := provider.UpsertMarkedIssueComment(ctx, issue.Number, dryRunMarker, w.agent.redactor.String(renderDryRun(d, w.dryRuns != nil))); err != nil {
+		return fmt.Errorf("post dry-run patch: %w", err)
+	}
+	w.log.Info("dry run finished", "issue", issue.Number, "patch_bytes", len(d.Patch))
+	return nil
+}
+
+// HandleApply pushes the patch of an issue's dry run to its branch and opens
+// the PR, as if the run had not been dry.
+func (w *Worker) HandleApply(ctx context.Context, repoURL string, issue git.Issue) error {
+	w.log.Info("applying dry run", "issue", issue.Number)
+
+	provider, _, err := w.factory.ProviderFor(ctx, repoURL)
+	if err != nil {
+		return fmt.Errorf("build provider: %w", err)
+	}
+	full, err := provider.GetIssue(ctx, issue.Number)
+	if err != nil {
+		return fmt.Errorf("fetch issue: %w", err)
+	}
+	issue = full
+
+	var d *DryRun
+	if w.dryRuns != nil {
+		if d, err = w.dryRuns.Load(repoURL, issue.Number); err != nil {
+			return err
+		}
+	}
+	if d == nil {
+		w.refuseApply(ctx, provider, issue, fmt.Sprintf("There is no dry run to apply on this issue. Add `agent:ready` together with `%s` to make one.", dryRunLabel))
+		return nil
+	}
+
+	repo, _, err := w.agent.clone(ctx, provider, w.token)
+	if err != nil {
+		return err
+	}
+	defer repo.Cleanup()
+	if err := repo.CheckoutRemoteBranch(ctx, d.BaseBranch); err != nil {
+		return fmt.Errorf("checkout base %s: %w", d.BaseBranch, err)
+	}
+	if err := repo.CreateBranch(ctx, d.Branch); err != nil {
+		return fmt.Errorf("create branch: %w", err)
+	}
+	if err := repo.ApplyPatch(ctx, d.Patch); err != nil {
+		w.log.Warn("dry-run patch no longer applies", "issue", issue.Number, "err", err)
+		w.refuseApply(ctx, provider, issue, fmt.Sprintf("The dry-run patch no longer applies to `%s`, which has moved on since. Add `agent:ready` to run the issue again.", d.BaseBranch))
+		return nil
+	}
+	if _, err := repo.Commit(ctx, d.Title); err != nil {
+		return fmt.Errorf("commit patch: %w", err)
+	}
+	if err := repo.Push(ctx); err != nil {
+		return fmt.Errorf("push: %w", err)
+	}
+
+	result := d.result()
+	result.IssueURL = issue.URL
+	if _, err := w.openPR(ctx, provider, repoURL, issue, result); err != nil {
+		return err
+	}
+	if err := w.dryRuns.Delete(repoURL, issue.Number); err != nil {
+		w.log.Warn("failed to clear dry run", "issue", issue.Number, "err", err)
+	}
+	if err := provider.RemoveLabel(ctx, issue.Number, applyLabel); err != nil {
+		w.log.Warn("failed to remove agent:apply label", "err", err)
+	}
+	return nil
+}
+
+// refuseApply explains on the issue why its patch was not applied.
+func (w *Worker) refuseApply(ctx context.Context, provider git.GitProvider, issue git.Issue, msg string) {
+	if err := provider.UpsertMarkedIssueComment(ctx, issue.Number, dryRunMarker, msg+"\n\n"+dryRunMarker); err != nil {
+		w.log.Warn("failed to comment on apply", "issue", issue.Number, "err", err)
+	}
+	if err := provider.RemoveLabel(ctx, issue.Number, applyLabel); err != nil {
+		w.log.Warn("failed to remove agent:apply label", "err", err)
+	}
+}
+
+// DryRunStore keeps the latest dry run of each issue as a JSON file, until
+// its patch is applied.
+type DryRunStore struct {
+	dir string
+}
+
+func NewDryRunStore(dir string) (*DryRunStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create dry runs dir: %w", err)
+	}
+	return &DryRunStore{dir: dir}, nil
+}
+
+func (s *DryRunStore) Save(d DryRun) error {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("marshal dry run: %w", err)
+	}
+	if err := os.WriteFile(s.path(d.RepoURL, d.Issue), b, 0o644); err != nil {
+		return fmt.Errorf("write dry run: %w", err)
+	}
+	return nil
+}
+
+// Load returns the dry run of issue, or nil if there is none.
+func (s *DryRunStore) Load(repoURL string, issue int) (*DryRun, error) {
+	b, err := os.ReadFile(s.path(repoURL, issue))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read dry run: %w", err)
+	}
+	var d DryRun
+	if err := json.Unmarshal(b, &d); err != nil {
+		return nil, fmt.Errorf("decode dry run: %w", err)
+	}
+	return &d, nil
+}
+
+func (s *DryRunStore) Delete(repoURL string, issue int) error {
+	if err := os.Remove(s.path(repoURL, issue)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("delete dry run: %w", err)
+	}
+	return nil
+}
+
+func (s *DryRunStore) path(repoURL string, issue int) string {
+	key := strings.NewReplacer("https://", "", "http://", "", "/", "_", ":", "_").Replace(strings.TrimSuffix(repoURL, ".git"))
+	return filepath.Join(s.dir, fmt.Sprintf("%s_%d.json", key, issue))
+}
+
+// Name implements retention.Source.
+func (s *DryRunStore) Name() string { return "executor_dry_runs" }
+
+// ExportData returns the dry runs f selects, matched on when they finished.
+// They hold no user identifiers, so a user filter matches none.
+func (s *DryRunStore) ExportData(f retention.Filter) (any, error) {
+	out := []DryRun{}
+	err := s.each(func(_ string, d DryRun) error {
+		if f.Match(d.RepoURL, nil, d.CreatedAt) {
+			out = append(out, d)
+		}
+		return nil
+	})
+	return out, err
+}
+
+// DeleteData removes the dry runs f selects; their patches can then no
+// longer be applied.
+func (s *DryRunStore) DeleteData(f retention.Filter) (int, error) {
+	n := 0
+	err := s.each(func(path string, d DryRun) error {
+		if !f.Match(d.RepoURL, nil, d.CreatedAt) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("delete dry run: %w", err)
+		}
+		n++
+		return nil
+	})
+	return n, err
+}
+
+// each calls fn for every stored dry run. Unreadable files are skipped.
+func (s *DryRunStore) each(fn func(path string, d DryRun) error) error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("read dry runs dir: %w", err)
+	}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(s.dir, e.Name())
+		b, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var d DryRun
+		if json.Unmarshal(b, &d) != nil {
+			continue
+		}
+		if err := fn(path, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
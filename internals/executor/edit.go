@@ -0,0 +1,200 @@
+package executor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// searchReplace is one anchored edit: OldString must occur exactly once in the
+// file unless ReplaceAll is set.
+type searchReplace struct {
+	OldString  string `json:"old_string"`
+	NewString  string `json:"new_string"`
+	ReplaceAll bool   `json:"replace_all"`
+}
+
+// applyEdits applies edits in order. Either every edit applies or the original
+// content is kept and the first failure is returned.
+func applyEdits(content string, edits []searchReplace) (string, error) {
+	for i, e := range edits {
+		if e.OldString == "" {
+			return "", fmt.Errorf("edit %d: old_string is empty — use write_file to create a file", i+1)
+		}
+		if e.OldString == e.NewString {
+			return "", fmt.Errorf("edit %d: old_string and new_string are identical", i+1)
+		}
+
+		n := strings.Count(content, e.OldString)
+		switch {
+		case n == 0:
+			return "", fmt.Errorf("edit %d: old_string not found%s", i+1, closestLineHint(content, e.OldString))
+		case n > 1 && !e.ReplaceAll:
+			return "", fmt.Errorf("edit %d: old_string matches %d times (lines %s) — include more surrounding context to make it unique, or set replace_all",
+				i+1, n, strings.Join(matchLines(content, e.OldString), ", "))
+		}
+
+		if e.ReplaceAll {
+			content = strings.ReplaceAll(content, e.OldString, e.NewString)
+		} else {
+			content = strings.Replace(content, e.OldString, e.NewString, 1)
+		}
+	}
+	return content, nil
+}
+
+// matchLines returns the 1-based line numbers where needle starts.
+func matchLines(content, needle string) []string {
+	var lines []string
+	offset := 0
+	for {
+		i := strings.Index(content[offset:], needle)
+		if i < 0 {
+			return lines
+		}
+		lines = append(lines, strconv.Itoa(strings.Count(content[:offset+i], "\n")+1))
+		offset += i + len(needle)
+	}
+}
+
+// closestLineHint points at the line that matches the first line of needle
+// once whitespace is ignored, which catches the common indentation mistake.
+func closestLineHint(content, needle string) string {
+	first := strings.Join(strings.Fields(strings.SplitN(needle, "\n", 2)[0]), " ")
+	if first == "" {
+		return " — read the file again and copy the text exactly"
+	}
+	for i, l := range strings.Split(content, "\n") {
+		if strings.Join(strings.Fields(l), " ") == first {
+			return fmt.Sprintf(" — line %d matches apart from whitespace: %q. Copy the text exactly, including indentation", i+1, l)
+		}
+	}
+	return " — read the file again and copy the text exactly"
+}
+
+type hunk struct {
+	header   string
+	oldStart int // 1-based, as written in the header
+	lines    []string
+}
+
+// applyPatch applies a unified diff for a single file. Hunks are located by
+// their context and removed lines, starting at the line the header names and
+// falling back to the nearest match elsewhere, so slightly stale line numbers
+// still apply.
+func applyPatch(content, patch string) (string, error) {
+	hunks, err := parseHunks(patch)
+	if err != nil {
+		return "", err
+	}
+
+	lines := strings.Split(content, "\n")
+	shift := 0 // line delta introduced by earlier hunks
+	for _, h := range hunks {
+		var old, repl []string
+		for _, l := range h.lines {
+			switch {
+			case l == "" || l[0] == ' ':
+				text := strings.TrimPrefix(l, " ")
+				old = append(old, text)
+				repl = append(repl, text)
+			case l[0] == '-':
+				old = append(old, l[1:])
+			case l[0] == '+':
+				repl = append(repl, l[1:])
+			case l[0] == '\\':
+				// "\ No newline at end of file"
+			default:
+				return "", fmt.Errorf("hunk %s: unexpected line %q — every line must start with ' ', '+' or '-'", h.header, l)
+			}
+		}
+
+		at := findBlock(lines, old, h.oldStart-1+shift)
+		if at < 0 {
+			return "", fmt.Errorf("hunk %s does not match the file — the expected lines starting with %q were not found. Read the file again and regenerate the patch", h.header, firstOr(old, ""))
+		}
+		lines = append(lines[:at], append(repl, lines[at+len(old):]...)...)
+		shift += len(repl) - len(old)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func parseHunks(patch string) ([]hunk, error) {
+	var hunks []hunk
+	for _, l := range strings.Split(strings.TrimRight(patch, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(l, "---"), strings.HasPrefix(l, "+++"), strings.HasPrefix(l, "diff "), strings.HasPrefix(l, "index "):
+			if len(hunks) == 0 {
+				continue // file headers
+			}
+			hunks[len(hunks)-1].lines = append(hunks[len(hunks)-1].lines, l)
+		case strings.HasPrefix(l, "@@"):
+			start, err := parseHunkStart(l)
+			if err != nil {
+				return nil, err
+			}
+			hunks = append(hunks, hunk{header: l, oldStart: start})
+		default:
+			if len(hunks) == 0 {
+				return nil, fmt.Errorf("patch has content before the first @@ hunk header")
+			}
+			hunks[len(hunks)-1].lines = append(hunks[len(hunks)-1].lines, l)
+		}
+	}
+	if len(hunks) == 0 {
+		return nil, fmt.Errorf("patch contains no @@ hunks")
+	}
+	return hunks, nil
+}
+
+// parseHunkStart reads the old-file start line from "@@ -12,7 +12,9 @@".
+func parseHunkStart(header string) (int, error) {
+	fields := strings.Fields(header)
+	if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") {
+		return 0, fmt.Errorf("malformed hunk header %q", header)
+	}
+	start, _, _ := strings.Cut(fields[1][1:], ",")
+	n, err := strconv.Atoi(start)
+	if err != nil {
+		return 0, fmt.Errorf("malformed hunk header %q", header)
+	}
+	return max(n, 1), nil
+}
+
+// findBlock returns the index where block occurs in lines, preferring the
+// occurrence closest to hint, or -1.
+func findBlock(lines, block []string, hint int) int {
+	if len(block) == 0 {
+		return min(max(hint, 0), len(lines))
+	}
+	best, bestDist := -1, 0
+	for i := 0; i+len(block) <= len(lines); i++ {
+		if !equalAt(lines, block, i) {
+			continue
+		}
+		d := i - hint
+		if d < 0 {
+			d = -d
+		}
+		if best < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
+
+func equalAt(lines, block []string, at int) bool {
+	for j, b := range block {
+		if lines[at+j] != b {
+			return false
+		}
+	}
+	return true
+}
+
+func firstOr(s []string, def string) string {
+	if len(s) == 0 {
+		return def
+	}
+	return s[0]
+}
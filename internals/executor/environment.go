@@ -0,0 +1,159 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/standards"
+)
+
+// probeTimeout bounds the toolchain probe, which only asks for versions.
+const probeTimeout = 30 * time.Second
+
+// packageManagers maps the manifest and lock files found in a repository to
+// the package manager they belong to and the command that runs it.
+var packageManagers = map[string]struct{ name, tool string }{
+	"go.mod":            {"Go modules", "go"},
+	"package-lock.json": {"npm", "npm"},
+	"yarn.lock":         {"Yarn", "yarn"},
+	"pnpm-lock.yaml":    {"pnpm", "pnpm"},
+	"bun.lockb":         {"Bun", "bun"},
+	"requirements.txt":  {"pip", "pip3"},
+	"poetry.lock":       {"Poetry", "poetry"},
+	"uv.lock":           {"uv", "uv"},
+	"Pipfile":           {"Pipenv", "pipenv"},
+	"Cargo.toml":        {"Cargo", "cargo"},
+	"Gemfile":           {"Bundler", "bundle"},
+	"pom.xml":           {"Maven", "mvn"},
+	"build.gradle":      {"Gradle", "gradle"},
+	"build.gradle.kts":  {"Gradle", "gradle"},
+	"composer.json":     {"Composer", "composer"},
+	"Package.swift":     {"Swift Package Manager", "swift"},
+}
+
+// languageTools are the commands whose versions matter for each language, as
+// named by standards.Languages.
+var languageTools = map[string][]string{
+	"go":         {"go"},
+	"python":     {"python3", "python"},
+	"typescript": {"node", "tsc"},
+	"javascript": {"node"},
+	"ruby":       {"ruby"},
+	"java":       {"java"},
+	"kotlin":     {"java", "kotlinc"},
+	"rust":       {"rustc", "cargo"},
+	"csharp":     {"dotnet"},
+	"php":        {"php"},
+	"swift":      {"swift"},
+	"scala":      {"java", "scala"},
+	"terraform":  {"terraform"},
+}
+
+// probeEnvironment detects the repository's languages and package managers
+// and which of their toolchains the command environment — the sandbox, if
+// one is configured — actually has, and returns the system prompt section
+// reporting them, or "" when there is nothing to report. It keeps the model
+// from reaching for npm in a Go repository or assuming a Python version that
+// is not installed.
+func (a *Agent) probeEnvironment(ctx context.Context, repo *git.Repo) string {
+	files, err := repo.Files(ctx, "")
+	if err != nil {
+		a.log.Warn("environment probe: list files", "err", err)
+		return ""
+	}
+	langs := standards.Languages(files)
+
+	var managers []string
+	var tools []string
+	seen := make(map[string]bool)
+	for _, f := range files {
+		pm, ok := packageManagers[path.Base(f)]
+		if !ok || seen[pm.name] {
+			continue
+		}
+		seen[pm.name] = true
+		managers = append(managers, fmt.Sprintf("%s (%s)", pm.name, f))
+		tools = append(tools, pm.tool)
+	}
+	for _, l := range langs {
+		tools = append(tools, languageTools[l]...)
+	}
+	slices.Sort(tools)
+	tools = slices.Compact(tools)
+	if len(langs) == 0 && len(tools) == 0 {
+		return ""
+	}
+
+	var versions string
+	if len(tools) > 0 {
+		probeCtx, cancel := context.WithTimeout(ctx, probeTimeout)
+		out, err := repo.RunInDir(probeCtx, probeScript(tools))
+		cancel()
+		if err != nil {
+			a.log.Warn("environment probe failed", "err", err)
+		} else {
+			versions = parseProbe(out, tools)
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## Environment\n\n")
+	if len(langs) > 0 {
+		sb.WriteString("Languages in the repository, most files first: " + strings.Join(langs, ", ") + "\n")
+	}
+	if len(managers) > 0 {
+		sb.WriteString("Package managers: " + strings.Join(managers, ", ") + "\n")
+	}
+	if versions != "" {
+		sb.WriteString("\nToolchains where run_command and run_tests run:\n" + versions)
+	}
+	sb.WriteString("\nUse the package managers and toolchains listed here. Do not assume a tool that is missing, or a version other than the one installed; install one only if the issue cannot be done without it.")
+	a.log.Info("environment probed", "languages", langs, "tools", len(tools))
+	return sb.String()
+}
+
+// joinSections joins the non-empty prompt sections.
+func joinSections(sections ...string) string {
+	var out []string
+	for _, s := range sections {
+		if s != "" {
+			out = append(out, s)
+		}
+	}
+	return strings.Join(out, "\n\n")
+}
+
+// probeScript prints "<tool>: <first line of its version>" or "<tool>:
+// missing" for each tool.
+func probeScript(tools []string) string {
+	var sb strings.Builder
+	for _, t := range tools {
+		flag := "--version"
+		if t == "go" {
+			flag = "version"
+		} else if t == "java" {
+			flag = "-version" // prints to stderr
+		}
+		sb.WriteString(fmt.Sprintf("if command -v %[1]s >/dev/null 2>&1; then echo \"%[1]s: $(%[1]s %[2]s 2>&1 | head -n 1)\"; else echo \"%[1]s: missing\"; fi\n", t, flag))
+	}
+	return sb.String()
+}
+
+// parseProbe turns the probe's output into a list, keeping only lines for
+// the tools asked about.
+func parseProbe(out string, tools []string) string {
+	var sb strings.Builder
+	for _, line := range strings.Split(out, "\n") {
+		tool, version, ok := strings.Cut(line, ": ")
+		if !ok || !slices.Contains(tools, tool) {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("- %s: %s\n", tool, strings.TrimSpace(version)))
+	}
+	return sb.String()
+}
@@ -0,0 +1,101 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jadenj13/droid/internals/git"
+)
+
+// failedLabel marks issues whose executor job failed for good.
+const failedLabel = "agent:failed"
+
+// failureMarker identifies the executor's failure comment on an issue, so a
+// later failure replaces it instead of piling up comments.
+const failureMarker = "<!-- droid:executor-failure -->"
+
+// maxFailureSteps is how many of the last tool calls the failure comment shows.
+const maxFailureSteps = 5
+
+// classifyRunError names the broad cause of a failed run from its error text.
+func classifyRunError(msg string) string {
+	switch {
+	case strings.Contains(msg, errJobDeadline.Error()):
+		return "job deadline exceeded"
+	case strings.Contains(msg, "iteration limit"):
+		return "iteration limit reached"
+	case strings.Contains(msg, errBudgetExceeded.Error()):
+		return "budget exceeded"
+	case strings.Contains(msg, errStuckRun.Error()):
+		return "agent stuck in a loop"
+	case strings.Contains(msg, "verification failed"):
+		return "build or tests failing"
+	case strings.Contains(msg, "stopped without submit_work"):
+		return "agent stopped without submitting"
+	case strings.Contains(msg, "push:"):
+		return "push rejected"
+	case strings.Contains(msg, "llm iter"), strings.Contains(msg, "anthropic api"):
+		return "LLM request failed"
+	case strings.Contains(msg, "clone:"), strings.Contains(msg, "checkout"), strings.Contains(msg, "create branch"):
+		return "repository setup failed"
+	case strings.Contains(msg, "open PR"):
+		return "could not open the pull request"
+	default:
+		return "unexpected error"
+	}
+}
+
+// failureComment renders the comment posted on an issue whose job failed for
+// good. steps may be empty when no attempt was recorded.
+func failureComment(runErr string, attempts int, steps []Step, retryLabel string) string {
+	var sb strings.Builder
+	sb.WriteString(failureMarker + "\n")
+	sb.WriteString(fmt.Sprintf("### :x: Executor run failed: %s\n\n", classifyRunError(runErr)))
+	sb.WriteString(fmt.Sprintf("Gave up after %d attempt(s). Last error:\n\n```\n%s\n```\n", attempts, preview(runErr, 1500)))
+
+	if len(steps) > 0 {
+		if len(steps) > maxFailureSteps {
+			steps = steps[len(steps)-maxFailureSteps:]
+		}
+		sb.WriteString("\n<details><summary>Last tool calls</summary>\n\n")
+		for _, st := range steps {
+			sb.WriteString(fmt.Sprintf("- `%s` %s → %s\n", st.Tool, inlineCode(st.Input), inlineCode(st.Result)))
+		}
+		sb.WriteString("\n</details>\n")
+	}
+
+	sb.WriteString(fmt.Sprintf("\nTo retry, remove `%s` and re-add the `%s` label. The next run starts with a summary of what this one tried.\n", failedLabel, retryLabel))
+	return sb.String()
+}
+
+// reportFailure comments on the issue and labels it agent:failed.
+func (w *Worker) reportFailure(ctx context.Context, provider git.GitProvider, repoURL string, issue git.Issue, attempts int, runErr, retryLabel string) {
+	var steps []Step
+	if w.attempts != nil {
+		if at, err := w.attempts.Load(repoURL, issue.Number); err == nil && at != nil {
+			steps = at.Steps
+		}
+	}
+	body := w.agent.redactor.String(failureComment(runErr, attempts, steps, retryLabel))
+	if err := provider.UpsertMarkedIssueComment(ctx, issue.Number, failureMarker, body); err != nil {
+		w.log.Warn("failed to post failure comment", "issue", issue.Number, "err", err)
+	}
+	// Drop the trigger label so re-adding it fires a new webhook.
+	if err := provider.RemoveLabel(ctx, issue.Number, retryLabel); err != nil {
+		w.log.Warn("failed to remove trigger label", "label", retryLabel, "err", err)
+	}
+	if err := provider.AddLabel(ctx, issue.Number, failedLabel); err != nil {
+		w.log.Warn("failed to add agent:failed label", "err", err)
+	}
+}
+
+// clearFailure removes the agent:failed label after a successful run.
+func (w *Worker) clearFailure(ctx context.Context, provider git.GitProvider, issue git.Issue) {
+	if !issue.HasLabel(failedLabel) {
+		return
+	}
+	if err := provider.RemoveLabel(ctx, issue.Number, failedLabel); err != nil {
+		w.log.Warn("failed to remove agent:failed label", "err", err)
+	}
+}
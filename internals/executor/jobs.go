@@ -0,0 +1,145 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/queue"
+)
+
+// Job kinds handled by the executor worker.
+const (
+	jobIssue    = "executor.issue"
+	jobRevision = "executor.revision"
+	jobPRMerged = "executor.pr_merged"
+	jobCommand  = "executor.command"
+	jobApply    = "executor.apply"
+	jobClosed   = "executor.issue_closed"
+	jobLinear   = "executor.linear_issue"
+)
+
+type issueJob struct {
+	RepoURL   string    `json:"repo_url"`
+	Issue     git.Issue `json:"issue"`
+	LabeledAt time.Time `json:"labeled_at,omitzero"`
+}
+
+type issueClosedJob struct {
+	RepoURL string `json:"repo_url"`
+	Issue   int    `json:"issue"`
+}
+
+type prMergedJob struct {
+	RepoURL  string    `json:"repo_url"`
+	PRTitle  string    `json:"pr_title"`
+	PRURL    string    `json:"pr_url,omitempty"`
+	PRBody   string    `json:"pr_body"`
+	MergedAt time.Time `json:"merged_at,omitzero"`
+}
+
+// JobRepo groups executor jobs by repository, so that a per-repo limit keeps
+// two runs from racing on the same branches and PRs.
+func JobRepo(kind string, payload json.RawMessage) string {
+	var job struct {
+		RepoURL string `json:"repo_url"`
+	}
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(job.RepoURL, "/"), ".git")
+}
+
+// JobIssue keys issue, revision and apply jobs by their repository and issue,
+// and Linear jobs by their Linear issue, so that with queue.WithDedup a
+// redelivered label event cannot start a second run, and a second PR, for an
+// issue that is already being worked on.
+func JobIssue(kind string, payload json.RawMessage) string {
+	if kind == jobLinear {
+		var job linearIssueJob
+		if err := json.Unmarshal(payload, &job); err != nil || job.Ticket.Key == "" {
+			return ""
+		}
+		return fmt.Sprintf("%s#%s", JobRepo(kind, payload), job.Ticket.Key)
+	}
+	if kind != jobIssue && kind != jobRevision && kind != jobApply {
+		return ""
+	}
+	var job issueJob
+	if err := json.Unmarshal(payload, &job); err != nil || job.Issue.Number == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s#%d", JobRepo(kind, payload), job.Issue.Number)
+}
+
+// RegisterJobs wires the worker's handlers into q.
+func (w *Worker) RegisterJobs(q *queue.Queue) {
+	q.Handle(jobIssue, func(ctx context.Context, payload json.RawMessage) error {
+		var job issueJob
+		if err := json.Unmarshal(payload, &job); err != nil {
+			return fmt.Errorf("decode issue job: %w", err)
+		}
+		w.recordQueued(job.RepoURL, job.Issue.Number, job.LabeledAt)
+		return w.HandleIssue(ctx, job.RepoURL, job.Issue)
+	})
+	q.Handle(jobRevision, func(ctx context.Context, payload json.RawMessage) error {
+		var job issueJob
+		if err := json.Unmarshal(payload, &job); err != nil {
+			return fmt.Errorf("decode revision job: %w", err)
+		}
+		return w.HandleRevision(ctx, job.RepoURL, job.Issue)
+	})
+	q.Handle(jobApply, func(ctx context.Context, payload json.RawMessage) error {
+		var job issueJob
+		if err := json.Unmarshal(payload, &job); err != nil {
+			return fmt.Errorf("decode apply job: %w", err)
+		}
+		return w.HandleApply(ctx, job.RepoURL, job.Issue)
+	})
+	retryLabels := map[string]string{jobIssue: "agent:ready", jobRevision: w.revisionLabel, jobApply: applyLabel}
+	for kind, label := range retryLabels {
+		q.OnFailure(kind, func(ctx context.Context, job queue.Job) {
+			var ij issueJob
+			if err := json.Unmarshal(job.Payload, &ij); err != nil {
+				w.log.Error("decode failed job", "job", job.ID, "err", err)
+				return
+			}
+			if job.Kind == jobIssue {
+				w.recordFailed(ij.RepoURL, ij.Issue.Number)
+			}
+			w.NotifyFailed(ctx, ij.RepoURL, ij.Issue, job.Attempts, job.LastError, label)
+		})
+	}
+	q.Handle(jobCommand, func(ctx context.Context, payload json.RawMessage) error {
+		var job commandJob
+		if err := json.Unmarshal(payload, &job); err != nil {
+			return fmt.Errorf("decode command job: %w", err)
+		}
+		return w.HandleCommand(ctx, job)
+	})
+	q.Handle(jobClosed, func(ctx context.Context, payload json.RawMessage) error {
+		var job issueClosedJob
+		if err := json.Unmarshal(payload, &job); err != nil {
+			return fmt.Errorf("decode issue closed job: %w", err)
+		}
+		return w.HandleIssueClosed(ctx, job.RepoURL, job.Issue)
+	})
+	q.Handle(jobLinear, func(ctx context.Context, payload json.RawMessage) error {
+		var job linearIssueJob
+		if err := json.Unmarshal(payload, &job); err != nil {
+			return fmt.Errorf("decode linear issue job: %w", err)
+		}
+		return w.HandleLinearIssue(ctx, job)
+	})
+	q.Handle(jobPRMerged, func(ctx context.Context, payload json.RawMessage) error {
+		var job prMergedJob
+		if err := json.Unmarshal(payload, &job); err != nil {
+			return fmt.Errorf("decode PR merged job: %w", err)
+		}
+		w.recordMerged(job.RepoURL, job.PRTitle, job.PRBody, job.MergedAt)
+		return w.HandlePRMerged(ctx, job.RepoURL, job.PRURL, job.PRBody)
+	})
+}
@@ -0,0 +1,175 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/tracker"
+)
+
+// Linear issues cannot be worked on directly: labeling one agent:ready
+// creates a copy in the repository mapped to its team, naming the Linear
+// issue on its ticket line. The copy carries agent:ready, so the usual
+// execute → review loop runs on it, and the PR is linked back from Linear.
+
+// linearReadyLabel is the Linear label that hands an issue to the executor.
+const linearReadyLabel = "agent:ready"
+
+// WithLinear accepts Linear webhooks, verified with secret, for the teams in
+// repos, which maps a team key (e.g. "ENG") to the repository its issues are
+// worked on in.
+func WithLinear(secret string, repos map[string]string) WebhookOption {
+	return func(s *WebhookServer) { s.linearSecret, s.linearRepos = secret, repos }
+}
+
+// ParseLinearRepos parses TEAM=repo-url pairs separated by commas.
+func ParseLinearRepos(s string) (map[string]string, error) {
+	out := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		team, repo, ok := strings.Cut(pair, "=")
+		if !ok || team == "" || repo == "" {
+			return nil, fmt.Errorf("invalid Linear team mapping %q — expected TEAM=repository-url", pair)
+		}
+		out[strings.ToUpper(strings.TrimSpace(team))] = strings.TrimSpace(repo)
+	}
+	return out, nil
+}
+
+type linearLabel struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type linearWebhookPayload struct {
+	Action string `json:"action"`
+	Type   string `json:"type"`
+	Data   struct {
+		Identifier  string `json:"identifier"`
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		URL         string `json:"url"`
+		Team        struct {
+			Key string `json:"key"`
+		} `json:"team"`
+		Labels []linearLabel `json:"labels"`
+	} `json:"data"`
+	UpdatedFrom struct {
+		LabelIDs *[]string `json:"labelIds"` // set only when the labels changed
+	} `json:"updatedFrom"`
+}
+
+// readyAdded reports whether the event added the ready label to the issue.
+func (p linearWebhookPayload) readyAdded() bool {
+	i := slices.IndexFunc(p.Data.Labels, func(l linearLabel) bool { return l.Name == linearReadyLabel })
+	if i < 0 {
+		return false
+	}
+	switch p.Action {
+	case "create":
+		return true
+	case "update":
+		return p.UpdatedFrom.LabelIDs != nil && !slices.Contains(*p.UpdatedFrom.LabelIDs, p.Data.Labels[i].ID)
+	}
+	return false
+}
+
+type linearIssueJob struct {
+	RepoURL     string         `json:"repo_url"`
+	Ticket      tracker.Ticket `json:"ticket"`
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+}
+
+func (s *WebhookServer) handleLinear(w http.ResponseWriter, r *http.Request) {
+	body, err := s.readAndVerify(r, s.linearSecret, "linear-signature")
+	if err != nil {
+		s.log.Warn("linear webhook verify failed", "err", err)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	s.once(w, r.Header.Get("linear-delivery"), func(w http.ResponseWriter) {
+		s.routeLinear(w, body)
+	})
+}
+
+func (s *WebhookServer) routeLinear(w http.ResponseWriter, body []byte) {
+	var payload linearWebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, "bad payload", http.StatusBadRequest)
+		return
+	}
+	if payload.Type != "Issue" || !payload.readyAdded() {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	team := payload.Data.Team.Key
+	if team == "" {
+		team, _, _ = strings.Cut(payload.Data.Identifier, "-")
+	}
+	repoURL, ok := s.linearRepos[strings.ToUpper(team)]
+	if !ok {
+		s.log.Info("linear issue from a team without a repository, ignoring", "issue", payload.Data.Identifier, "team", team)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	job := linearIssueJob{
+		RepoURL:     repoURL,
+		Ticket:      tracker.Ticket{Tracker: "Linear", Key: payload.Data.Identifier, URL: payload.Data.URL},
+		Title:       payload.Data.Title,
+		Description: payload.Data.Description,
+	}
+	if err := s.queue.Enqueue(jobLinear, job); err != nil {
+		s.log.Error("enqueue linear issue failed", "issue", job.Ticket.Key, "err", err)
+		http.Error(w, "enqueue failed", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// HandleLinearIssue creates the repository issue for a Linear issue labeled
+// agent:ready, unless an open one already names it.
+func (w *Worker) HandleLinearIssue(ctx context.Context, job linearIssueJob) error {
+	provider, _, err := w.factory.ProviderFor(ctx, job.RepoURL)
+	if err != nil {
+		return fmt.Errorf("build provider: %w", err)
+	}
+	ready, err := provider.ListIssuesByLabel(ctx, "agent:ready")
+	if err != nil {
+		return fmt.Errorf("list ready issues: %w", err)
+	}
+	for _, issue := range ready {
+		if t, ok := tracker.TicketFor(issue.Body); ok && t.Key == job.Ticket.Key {
+			w.log.Info("linear issue already has a repository issue", "ticket", job.Ticket.Key, "issue", issue.Number)
+			return nil
+		}
+	}
+
+	body := fmt.Sprintf("%s\n\n%s\n\n---\n*Created from Linear by the Executor Agent*", strings.TrimSpace(job.Description), job.Ticket.Line())
+	issue, err := provider.CreateIssue(ctx, git.IssueInput{
+		Title:  job.Title,
+		Body:   body,
+		Labels: []string{"agent:ready"},
+	})
+	if err != nil {
+		return fmt.Errorf("create issue for %s: %w", job.Ticket.Key, err)
+	}
+	w.log.Info("repository issue created for linear issue", "ticket", job.Ticket.Key, "issue", issue.Number, "url", issue.URL)
+
+	if tr := w.trackers.Get(job.Ticket.Tracker); tr != nil {
+		if err := tr.AddLink(ctx, job.Ticket.Key, issue.URL, fmt.Sprintf("Issue #%d: %s", issue.Number, issue.Title)); err != nil {
+			w.log.Warn("failed to link issue from ticket", "ticket", job.Ticket.Key, "err", err)
+		}
+	}
+	return nil
+}
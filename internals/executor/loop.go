@@ -0,0 +1,90 @@
+package executor
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jadenj13/droid/internals/git"
+)
+
+const (
+	// loopRepeatLimit is how many times the same tool call may return the
+	// same result before the run counts as looping.
+	loopRepeatLimit = 3
+	// maxLoopWarnings is how many loops a run is warned about before it is
+	// aborted.
+	maxLoopWarnings = 3
+)
+
+// errStuckRun marks a run aborted by loop detection.
+var errStuckRun = errors.New("executor stuck in a loop")
+
+// loopDetector watches a run's tool calls for signs that the model is stuck:
+// the same call returning the same result again and again, such as rerunning
+// failing tests without changing anything, or a file edited back to content
+// it already had.
+type loopDetector struct {
+	calls    map[string]int      // tool, input and result digest → times seen
+	files    map[string][]string // path → digests of its written contents
+	warnings int
+	findings []string // the diagnostic report, one line per loop
+}
+
+func newLoopDetector() *loopDetector {
+	return &loopDetector{calls: make(map[string]int), files: make(map[string][]string)}
+}
+
+// observe records a tool call and its result. It returns guidance to append
+// to the result when the call shows a loop, and an error once the run has
+// looped more than maxLoopWarnings times.
+func (d *loopDetector) observe(repo *git.Repo, name string, input json.RawMessage, result ToolResult) (string, error) {
+	var finding string
+	key := name + "\x00" + string(input) + "\x00" + digest(result.Content)
+	d.calls[key]++
+	if n := d.calls[key]; n >= loopRepeatLimit {
+		finding = fmt.Sprintf("%s(%s) returned the same result %d times", name, preview(string(input), 120), n)
+	}
+
+	if (name == "write_file" || name == "edit_file") && !strings.HasPrefix(result.Content, "error") {
+		var in struct {
+			Path string `json:"path"`
+			Repo string `json:"repo"`
+		}
+		_ = json.Unmarshal(input, &in)
+		if content, err := repo.ReadFile(in.Path); err == nil && in.Path != "" {
+			sum := digest(content)
+			file := in.Repo + ":" + in.Path
+			history := d.files[file]
+			// Rewriting the current content is caught as a repeated call;
+			// returning to an older version is an oscillation.
+			for i, h := range history {
+				if h == sum && i < len(history)-1 {
+					finding = fmt.Sprintf("%s was changed back to a version it had %d edit(s) earlier", in.Path, len(history)-i)
+					break
+				}
+			}
+			d.files[file] = append(history, sum)
+		}
+	}
+
+	if finding == "" {
+		return "", nil
+	}
+	d.findings = append(d.findings, finding)
+	d.warnings++
+	if d.warnings > maxLoopWarnings {
+		return "", fmt.Errorf("%w: %s", errStuckRun, strings.Join(d.findings, "; "))
+	}
+	return fmt.Sprintf("\n\n[loop detected] %s. Repeating the same steps will not change the outcome. "+
+		"Stop and reconsider: re-read the error, inspect the code it points at, and try a different approach. "+
+		"If you are blocked, call submit_work with confidence 'low' and explain what blocks you. "+
+		"(warning %d of %d; the run is aborted after that)", finding, d.warnings, maxLoopWarnings), nil
+}
+
+func digest(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return string(sum[:8])
+}
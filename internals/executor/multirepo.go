@@ -0,0 +1,229 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/repoconfig"
+)
+
+// alsoReposKey introduces the line of an issue body that names the other
+// repositories the issue spans:
+//
+//	Also-Repos: https://github.com/myorg/api-client, https://github.com/myorg/sdk
+const alsoReposKey = "also-repos"
+
+// SecondaryRepos returns the repository URLs declared on the issue body's
+// Also-Repos line, in order and without duplicates.
+func SecondaryRepos(body string) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(body, "\n") {
+		key, value, ok := strings.Cut(strings.Trim(line, " \t*_"), ":")
+		if !ok || !strings.EqualFold(strings.Trim(key, " *_"), alsoReposKey) {
+			continue
+		}
+		for _, u := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+			u = strings.TrimRight(strings.Trim(u, "*_<>`"), ".;")
+			if _, err := git.ParseRepoURL(u); err != nil || seen[u] {
+				continue
+			}
+			seen[u] = true
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// checkout is a secondary repository cloned for a multi-repo run.
+type checkout struct {
+	name     string // "owner/repo"
+	provider git.GitProvider
+	repo     *git.Repo
+	cfg      repoconfig.Config
+	base     string
+	start    string // commit the branch started from
+}
+
+// LinkedPR is a branch pushed to a secondary repository, for which the worker
+// opens a PR linked to the primary one.
+type LinkedPR struct {
+	Repo       string // "owner/repo"
+	Provider   git.GitProvider
+	Branch     string
+	BaseBranch string
+	URL        string // set once the PR is opened
+}
+
+// workspace holds the repositories of a multi-repo run. A nil workspace is a
+// single-repository run.
+type workspace struct {
+	primary string // "owner/repo" of the issue's repository
+	others  []*checkout
+}
+
+// repoScopedTools are the tools that act on one repository and so take a
+// repo selector in a multi-repo run.
+var repoScopedTools = map[string]bool{
+	"list_files": true, "search_code": true, "read_file": true, "write_file": true,
+	"edit_file": true, "run_command": true, "run_tests": true, "commit_changes": true,
+}
+
+// tools adds an optional "repo" argument to the repository-scoped tools.
+func (ws *workspace) tools(tools []anthropic.ToolParam) []anthropic.ToolParam {
+	if ws == nil {
+		return tools
+	}
+	names := []string{ws.primary}
+	for _, co := range ws.others {
+		names = append(names, co.name)
+	}
+	out := make([]anthropic.ToolParam, len(tools))
+	for i, t := range tools {
+		out[i] = t
+		if !repoScopedTools[t.Name] {
+			continue
+		}
+		props, _ := t.InputSchema.Properties.(map[string]interface{})
+		props = maps.Clone(props)
+		props["repo"] = map[string]interface{}{
+			"type":        "string",
+			"enum":        names,
+			"description": fmt.Sprintf("Repository to act on. Defaults to %s, the issue's repository.", ws.primary),
+		}
+		out[i].InputSchema.Properties = props
+	}
+	return out
+}
+
+// pick returns the secondary checkout a tool call selects, or nil for the
+// primary repository.
+func (ws *workspace) pick(raw json.RawMessage) (*checkout, error) {
+	if ws == nil {
+		return nil, nil
+	}
+	var in struct {
+		Repo string `json:"repo"`
+	}
+	_ = json.Unmarshal(raw, &in)
+	if in.Repo == "" || strings.EqualFold(in.Repo, ws.primary) {
+		return nil, nil
+	}
+	for _, co := range ws.others {
+		if strings.EqualFold(in.Repo, co.name) {
+			return co, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown repo %q", in.Repo)
+}
+
+// prompt explains the repositories to the model.
+func (ws *workspace) prompt(branch string) string {
+	if ws == nil {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("\n\nThis issue spans several repositories. Each is checked out on branch %s:\n", branch))
+	sb.WriteString(fmt.Sprintf("- %s (the issue's repository; the default)\n", ws.primary))
+	for _, co := range ws.others {
+		sb.WriteString(fmt.Sprintf("- %s\n", co.name))
+	}
+	sb.WriteString("File, search, command, test and commit tools take a `repo` argument choosing the repository. " +
+		"Keep the changes consistent across repositories (e.g. an API and its client), commit in each repository you change, " +
+		"and call submit_work once: a linked PR is opened in every repository with commits.")
+	for _, co := range ws.others {
+		if section := co.cfg.PromptSection(); section != "" {
+			sb.WriteString(fmt.Sprintf("\n\nConfiguration of %s:\n%s", co.name, section))
+		}
+	}
+	return sb.String()
+}
+
+// cleanup removes the secondary clones.
+func (ws *workspace) cleanup() {
+	if ws == nil {
+		return
+	}
+	for _, co := range ws.others {
+		co.repo.Cleanup()
+	}
+}
+
+// openWorkspace clones each secondary repository onto branch, from its
+// configured base branch.
+func (a *Agent) openWorkspace(ctx context.Context, primary git.GitProvider, secondary []git.GitProvider, token, branch string) (*workspace, error) {
+	if len(secondary) == 0 {
+		return nil, nil
+	}
+	ws := &workspace{primary: repoName(primary)}
+	for _, p := range secondary {
+		repo, cfg, err := a.clone(ctx, p, token)
+		if err != nil {
+			ws.cleanup()
+			return nil, fmt.Errorf("%s: %w", repoName(p), err)
+		}
+		co := &checkout{name: repoName(p), provider: p, repo: repo, cfg: cfg}
+		ws.others = append(ws.others, co)
+
+		if err := a.startBranch(ctx, co, branch); err != nil {
+			ws.cleanup()
+			return nil, fmt.Errorf("%s: %w", co.name, err)
+		}
+	}
+	return ws, nil
+}
+
+func (a *Agent) startBranch(ctx context.Context, co *checkout, branch string) error {
+	co.base = co.cfg.BaseBranch
+	var err error
+	if co.base != "" {
+		if err := co.repo.CheckoutRemoteBranch(ctx, co.base); err != nil {
+			return fmt.Errorf("checkout base %s: %w", co.base, err)
+		}
+	} else if co.base, err = co.repo.CurrentBranch(ctx); err != nil {
+		return fmt.Errorf("resolve default branch: %w", err)
+	}
+	if co.start, err = co.repo.Head(ctx); err != nil {
+		return fmt.Errorf("resolve HEAD: %w", err)
+	}
+	if err := co.repo.CreateBranch(ctx, branch); err != nil {
+		return fmt.Errorf("create branch: %w", err)
+	}
+	return nil
+}
+
+// push pushes every secondary repository the run committed to and returns
+// the branches to open linked PRs for.
+func (ws *workspace) push(ctx context.Context, branch string) ([]LinkedPR, error) {
+	if ws == nil {
+		return nil, nil
+	}
+	var linked []LinkedPR
+	for _, co := range ws.others {
+		head, err := co.repo.Head(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: resolve HEAD: %w", co.name, err)
+		}
+		if head == co.start {
+			continue // untouched
+		}
+		if err := co.repo.Push(ctx); err != nil {
+			return nil, fmt.Errorf("%s: push: %w", co.name, err)
+		}
+		linked = append(linked, LinkedPR{Repo: co.name, Provider: co.provider, Branch: branch, BaseBranch: co.base})
+	}
+	return linked, nil
+}
+
+func repoName(p git.GitProvider) string {
+	info, err := git.ParseRepoURL(p.RepoURL())
+	if err != nil {
+		return p.RepoURL()
+	}
+	return info.Owner + "/" + info.Repo
+}
@@ -0,0 +1,151 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+
+	slackhandler "github.com/jadenj13/droid/internals/slack"
+)
+
+type Notifier interface {
+	NotifyRunFailed(ctx context.Context, msg RunFailedMessage) error
+	NotifyQuestion(ctx context.Context, msg QuestionMessage) error
+}
+
+type RunFailedMessage struct {
+	IssueURL   string
+	IssueTitle string
+	RepoURL    string
+	Error      string
+	Attempts   int
+}
+
+// QuestionMessage is a question a paused run asked on its issue.
+type QuestionMessage struct {
+	IssueURL   string
+	IssueTitle string
+	Question   string
+}
+
+type SlackNotifier struct {
+	client    *slack.Client
+	channelID string // channel to post failure notifications to
+
+	mu      sync.Mutex
+	threads map[string]string // issue URL → progress thread ts
+}
+
+func NewSlackNotifier(botToken, channelID string) *SlackNotifier {
+	return &SlackNotifier{
+		client:    slack.New(botToken),
+		channelID: channelID,
+		threads:   make(map[string]string),
+	}
+}
+
+// NotifyRunFailed posts the failure with a Retry button. The planner handles
+// the button, since it owns the Slack Socket Mode connection.
+func (n *SlackNotifier) NotifyRunFailed(ctx context.Context, msg RunFailedMessage) error {
+	text := fmt.Sprintf(
+		":x: *Executor run failed* after %d attempt(s)\n"+
+			"Issue: <%s|%s>\n"+
+			"Repo: %s\n"+
+			"```%s```",
+		msg.Attempts,
+		msg.IssueURL, msg.IssueTitle,
+		msg.RepoURL,
+		preview(msg.Error, 500),
+	)
+
+	button := slack.NewButtonBlockElement(slackhandler.ActionRetryIssue, msg.IssueURL,
+		slack.NewTextBlockObject(slack.PlainTextType, "Retry", false, false),
+	)
+	_, _, err := n.client.PostMessageContext(ctx, n.channelID,
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionBlocks(
+			slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil),
+			slack.NewActionBlock("", button),
+		),
+	)
+	if err != nil {
+		return fmt.Errorf("slack notify: %w", err)
+	}
+	return nil
+}
+
+// NotifyQuestion points the channel at a question a paused run asked. It is
+// answered on the issue, where the answer's author is checked.
+func (n *SlackNotifier) NotifyQuestion(ctx context.Context, msg QuestionMessage) error {
+	text := fmt.Sprintf(
+		":question: *Executor needs input* on <%s|%s>\n"+
+			"%s\n"+
+			"Answer on the issue with `/droid answer <your answer>`.",
+		msg.IssueURL, msg.IssueTitle,
+		quote(preview(msg.Question, 1500)),
+	)
+	if _, _, err := n.client.PostMessageContext(ctx, n.channelID, slack.MsgOptionText(text, false)); err != nil {
+		return fmt.Errorf("slack notify: %w", err)
+	}
+	return nil
+}
+
+// PublishProgress starts a thread in the notify channel for each job and
+// replies to it with every update.
+func (n *SlackNotifier) PublishProgress(ctx context.Context, u ProgressUpdate) error {
+	n.mu.Lock()
+	ts, ok := n.threads[u.Issue.URL]
+	n.mu.Unlock()
+
+	if !ok {
+		verb := "Working on"
+		if u.Revision {
+			verb = "Revising"
+		}
+		_, newTS, err := n.client.PostMessageContext(ctx, n.channelID,
+			slack.MsgOptionText(fmt.Sprintf(":hammer_and_wrench: %s <%s|%s>", verb, u.Issue.URL, u.Issue.Title), false))
+		if err != nil {
+			return fmt.Errorf("slack progress: %w", err)
+		}
+		ts = newTS
+		n.mu.Lock()
+		n.threads[u.Issue.URL] = ts
+		n.mu.Unlock()
+	}
+
+	_, _, err := n.client.PostMessageContext(ctx, n.channelID,
+		slack.MsgOptionText(slackProgressText(u), false),
+		slack.MsgOptionTS(ts),
+	)
+	if u.Outcome != "" {
+		n.mu.Lock()
+		delete(n.threads, u.Issue.URL)
+		n.mu.Unlock()
+	}
+	if err != nil {
+		return fmt.Errorf("slack progress: %w", err)
+	}
+	return nil
+}
+
+func slackProgressText(u ProgressUpdate) string {
+	elapsed := u.Elapsed.Round(time.Second)
+	if u.Outcome != "" {
+		return fmt.Sprintf("*Finished* after %s: %s", elapsed, u.Outcome)
+	}
+	p := u.Progress
+	parts := []string{elapsed.String() + " elapsed"}
+	if p.Iteration > 0 {
+		parts = append(parts,
+			fmt.Sprintf("iteration %d/%d", p.Iteration, p.MaxIterations),
+			fmt.Sprintf("last tool `%s`", p.LastTool))
+	}
+	if p.Tests != TestsUnknown {
+		parts = append(parts, "tests "+string(p.Tests))
+	}
+	return strings.Join(parts, " · ")
+}
@@ -0,0 +1,315 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+
+	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/retention"
+)
+
+var toolAskHuman = anthropic.ToolParam{
+	Name:        "ask_human",
+	Description: anthropic.String("Ask the people on the issue a question and pause until they answer. Use it only when the requirements are genuinely ambiguous and a wrong guess would waste the work — not for anything you can find out from the code, the issue or the docs. Your work so far is committed and pushed, and you continue in a fresh session with the answer."),
+	InputSchema: anthropic.ToolInputSchemaParam{
+		Properties: map[string]interface{}{
+			"question": map[string]interface{}{
+				"type":        "string",
+				"description": "The question, self-contained: what is ambiguous, the options you see and what each would mean for the change.",
+			},
+		},
+		Required: []string{"question"},
+	},
+}
+
+// needsInputLabel marks issues whose run is paused on a question.
+const needsInputLabel = "agent:needs-input"
+
+// questionMarker identifies the executor's question comment on an issue.
+const questionMarker = "<!-- droid:question -->"
+
+// commandAnswer answers the question of a paused run and resumes it. The rest
+// of the comment is the answer:
+//
+//	/droid answer Use the v2 endpoint; v1 is deprecated.
+const commandAnswer = "answer"
+
+// Pause is a run paused on an ask_human question, kept until it resumes.
+type Pause struct {
+	RepoURL    string     `json:"repo_url,omitempty"`
+	Issue      int        `json:"issue"`
+	Branch     string     `json:"branch"` // holds the work so far
+	BaseBranch string     `json:"base_branch"`
+	Question   string     `json:"question"`
+	Plan       []PlanStep `json:"plan,omitempty"`
+	Steps      []Step     `json:"steps,omitempty"`
+	AskedAt    time.Time  `json:"asked_at"`
+	Answer     string     `json:"answer,omitempty"`
+	AnsweredBy string     `json:"answered_by,omitempty"`
+}
+
+// questionsKey marks a run that may pause with ask_human.
+type questionsKey struct{}
+
+func contextWithQuestions(ctx context.Context, allowed bool) context.Context {
+	return context.WithValue(ctx, questionsKey{}, allowed)
+}
+
+func questionsAllowed(ctx context.Context) bool {
+	allowed, _ := ctx.Value(questionsKey{}).(bool)
+	return allowed
+}
+
+// askHuman commits the work in progress and returns the result that ends the
+// loop on question.
+func (a *Agent) askHuman(ctx context.Context, repo *git.Repo, issue git.Issue, raw json.RawMessage, checklist []PlanStep, steps []Step) (ToolResult, error) {
+	var in struct {
+		Question string `json:"question"`
+	}
+	if err := json.Unmarshal(raw, &in); err != nil || strings.TrimSpace(in.Question) == "" {
+		return ToolResult{Content: "error: ask_human needs a question"}, nil
+	}
+	a.log.Info("executor asking a human", "issue", issue.Number, "question", preview(in.Question, 200))
+	if _, err := repo.Commit(ctx, "WIP: waiting for an answer\n\n"+in.Question); err != nil {
+		return ToolResult{}, fmt.Errorf("commit work in progress: %w", err)
+	}
+	return ToolResult{
+		Content: "Question posted; the run pauses here.",
+		Paused:  &Pause{Question: in.Question, Plan: checklist, Steps: steps},
+	}, nil
+}
+
+// resumeSection tells a resumed run what it asked and what it was told.
+func resumeSection(p *Pause) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("\n\nYou already started on this issue and paused to ask:\n\n%s\n\n", p.Question))
+	if p.Answer != "" {
+		sb.WriteString(fmt.Sprintf("%s answered:\n\n%s\n", p.AnsweredBy, p.Answer))
+	} else {
+		sb.WriteString("Nobody answered; the issue was sent back to you to carry on without an answer. Make a reasonable decision and explain it in the PR summary.\n")
+	}
+	if len(p.Plan) > 0 {
+		sb.WriteString("\nYour plan so far:\n" + renderPlan(p.Plan))
+	}
+	sb.WriteString(fmt.Sprintf("\nYour work so far is committed on branch %s, which is checked out. Continue from there; do not redo finished steps.", p.Branch))
+	return sb.String()
+}
+
+// renderQuestion is the issue comment asking a paused run's question.
+func renderQuestion(p *Pause) string {
+	return fmt.Sprintf("The executor paused on a question:\n\n%s\n\n"+
+		"Reply with `/droid answer` followed by your answer and it picks up where it left off on branch `%s`. "+
+		"Adding the `agent:ready` label again resumes it without one.\n\n%s",
+		quote(p.Question), p.Branch, questionMarker)
+}
+
+func quote(s string) string {
+	return "> " + strings.ReplaceAll(strings.TrimSpace(s), "\n", "\n> ")
+}
+
+// pause records the paused run, asks its question on the issue and in Slack,
+// and labels the issue as waiting.
+func (w *Worker) pause(ctx context.Context, provider git.GitProvider, repoURL string, issue git.Issue, p *Pause) error {
+	p.RepoURL = repoURL
+	p.Issue = issue.Number
+	p.AskedAt = time.Now()
+	if err := w.pauses.Save(*p); err != nil {
+		return fmt.Errorf("save pause: %w", err)
+	}
+	if err := provider.UpsertMarkedIssueComment(ctx, issue.Number, questionMarker, w.agent.redactor.String(renderQuestion(p))); err != nil {
+		return fmt.Errorf("post question: %w", err)
+	}
+	if err := provider.AddLabel(ctx, issue.Number, needsInputLabel); err != nil {
+		w.log.Warn("failed to add needs-input label", "issue", issue.Number, "err", err)
+	}
+	if w.notifier != nil {
+		err := w.notifier.NotifyQuestion(ctx, QuestionMessage{
+			IssueURL:   issue.URL,
+			IssueTitle: issue.Title,
+			Question:   w.agent.redactor.String(p.Question),
+		})
+		if err != nil {
+			w.log.Warn("failed to send question notification", "issue", issue.Number, "err", err)
+		}
+	}
+	return nil
+}
+
+// handleAnswer records the answer to a paused run's question and sends the
+// issue back to the executor.
+func (w *Worker) handleAnswer(ctx context.Context, provider git.GitProvider, job commandJob) error {
+	var p *Pause
+	if w.pauses != nil {
+		var err error
+		if p, err = w.pauses.Load(job.RepoURL, job.Issue.Number); err != nil {
+			return err
+		}
+	}
+	if p == nil {
+		w.replyToCommand(ctx, provider, job, commandMarker,
+			fmt.Sprintf("@%s, the executor is not waiting for an answer on this issue.\n\n%s", job.Author, commandMarker))
+		return nil
+	}
+	if strings.TrimSpace(job.Text) == "" {
+		w.replyToCommand(ctx, provider, job, commandMarker,
+			fmt.Sprintf("@%s, put your answer after `/droid answer`.\n\n%s", job.Author, commandMarker))
+		return nil
+	}
+
+	p.Answer, p.AnsweredBy = strings.TrimSpace(job.Text), job.Author
+	if err := w.pauses.Save(*p); err != nil {
+		return fmt.Errorf("save answer: %w", err)
+	}
+	// Remove and re-add so the webhook sees a fresh "labeled" event.
+	if err := provider.RemoveLabel(ctx, job.Issue.Number, "agent:ready"); err != nil {
+		w.log.Debug("remove label before resume", "err", err)
+	}
+	if err := provider.AddLabel(ctx, job.Issue.Number, "agent:ready"); err != nil {
+		return fmt.Errorf("add agent:ready label: %w", err)
+	}
+	w.log.Info("question answered", "issue", job.Issue.Number, "author", job.Author)
+	return nil
+}
+
+func (w *Worker) loadPause(repoURL string, issue int) *Pause {
+	if w.pauses == nil {
+		return nil
+	}
+	p, err := w.pauses.Load(repoURL, issue)
+	if err != nil {
+		w.log.Warn("failed to load pause", "issue", issue, "err", err)
+		return nil
+	}
+	return p
+}
+
+// resumed clears a paused run that has now finished.
+func (w *Worker) resumed(ctx context.Context, provider git.GitProvider, repoURL string, issue int) {
+	if err := w.pauses.Delete(repoURL, issue); err != nil {
+		w.log.Warn("failed to clear pause", "issue", issue, "err", err)
+	}
+	if err := provider.RemoveLabel(ctx, issue, needsInputLabel); err != nil {
+		w.log.Debug("remove needs-input label", "issue", issue, "err", err)
+	}
+}
+
+// PauseStore keeps the paused run of each issue as a JSON file, so a pause
+// outlives restarts.
+type PauseStore struct {
+	dir string
+}
+
+func NewPauseStore(dir string) (*PauseStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create pauses dir: %w", err)
+	}
+	return &PauseStore{dir: dir}, nil
+}
+
+func (s *PauseStore) Save(p Pause) error {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("marshal pause: %w", err)
+	}
+	if err := os.WriteFile(s.path(p.RepoURL, p.Issue), b, 0o644); err != nil {
+		return fmt.Errorf("write pause: %w", err)
+	}
+	return nil
+}
+
+// Load returns the paused run of issue, or nil if there is none.
+func (s *PauseStore) Load(repoURL string, issue int) (*Pause, error) {
+	b, err := os.ReadFile(s.path(repoURL, issue))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read pause: %w", err)
+	}
+	var p Pause
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("decode pause: %w", err)
+	}
+	return &p, nil
+}
+
+func (s *PauseStore) Delete(repoURL string, issue int) error {
+	if err := os.Remove(s.path(repoURL, issue)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("delete pause: %w", err)
+	}
+	return nil
+}
+
+func (s *PauseStore) path(repoURL string, issue int) string {
+	key := strings.NewReplacer("https://", "", "http://", "", "/", "_", ":", "_").Replace(strings.TrimSuffix(repoURL, ".git"))
+	return filepath.Join(s.dir, fmt.Sprintf("%s_%d.json", key, issue))
+}
+
+// Name implements retention.Source.
+func (s *PauseStore) Name() string { return "executor_pauses" }
+
+// ExportData returns the paused runs f selects, matched on when they asked.
+// The answering user is a git host account, not a Slack user, so a user
+// filter matches none.
+func (s *PauseStore) ExportData(f retention.Filter) (any, error) {
+	out := []Pause{}
+	err := s.each(func(_ string, p Pause) error {
+		if f.Match(p.RepoURL, nil, p.AskedAt) {
+			out = append(out, p)
+		}
+		return nil
+	})
+	return out, err
+}
+
+// DeleteData removes the paused runs f selects; their issues then start over
+// when labeled again.
+func (s *PauseStore) DeleteData(f retention.Filter) (int, error) {
+	n := 0
+	err := s.each(func(path string, p Pause) error {
+		if !f.Match(p.RepoURL, nil, p.AskedAt) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("delete pause: %w", err)
+		}
+		n++
+		return nil
+	})
+	return n, err
+}
+
+// each calls fn for every stored pause. Unreadable files are skipped.
+func (s *PauseStore) each(fn func(path string, p Pause) error) error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("read pauses dir: %w", err)
+	}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(s.dir, e.Name())
+		b, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var p Pause
+		if json.Unmarshal(b, &p) != nil {
+			continue
+		}
+		if err := fn(path, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
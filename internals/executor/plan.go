@@ -0,0 +1,169 @@
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+var toolWritePlan = anthropic.ToolParam{
+	Name:        "write_plan",
+	Description: anthropic.String("Write your plan for the issue as an ordered checklist of concrete steps, replacing any existing plan. The plan is kept outside the conversation and shown in your instructions on every turn, so it survives long runs. Write it once you understand the task, before making changes."),
+	InputSchema: anthropic.ToolInputSchemaParam{
+		Properties: map[string]interface{}{
+			"steps": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "The steps, in order. E.g. 'Add an expiry check to ValidateToken in internal/auth/token.go'",
+			},
+		},
+		Required: []string{"steps"},
+	},
+}
+
+var toolUpdatePlan = anthropic.ToolParam{
+	Name:        "update_plan",
+	Description: anthropic.String("Update one step of your plan: mark it in progress, done or skipped, and optionally note what you found. Add a step by giving new text without a step number."),
+	InputSchema: anthropic.ToolInputSchemaParam{
+		Properties: map[string]interface{}{
+			"step": map[string]interface{}{
+				"type":        "integer",
+				"description": "1-based number of the step to update. Omit to append a new step.",
+			},
+			"status": map[string]interface{}{
+				"type": "string",
+				"enum": []string{planPending, planInProgress, planDone, planSkipped},
+			},
+			"text": map[string]interface{}{
+				"type":        "string",
+				"description": "New wording of the step, or the text of a new step.",
+			},
+			"note": map[string]interface{}{
+				"type":        "string",
+				"description": "A short finding worth remembering, e.g. where a function lives or why a step was skipped.",
+			},
+		},
+	},
+}
+
+const (
+	planPending    = "pending"
+	planInProgress = "in_progress"
+	planDone       = "done"
+	planSkipped    = "skipped"
+)
+
+// PlanStep is one item of the agent's checklist.
+type PlanStep struct {
+	Text   string `json:"text"`
+	Status string `json:"status"`
+	Note   string `json:"note,omitempty"`
+}
+
+// plan is the checklist kept by write_plan and update_plan. It lives outside
+// the message history and is rendered into the system prompt each turn.
+type plan struct {
+	steps []PlanStep
+}
+
+func (p *plan) execute(name string, raw json.RawMessage) ToolResult {
+	switch name {
+	case toolWritePlan.Name:
+		var in struct {
+			Steps []string `json:"steps"`
+		}
+		if err := json.Unmarshal(raw, &in); err != nil {
+			return ToolResult{Content: fmt.Sprintf("error: %s", err)}
+		}
+		p.steps = p.steps[:0]
+		for _, s := range in.Steps {
+			if s = strings.TrimSpace(s); s != "" {
+				p.steps = append(p.steps, PlanStep{Text: s, Status: planPending})
+			}
+		}
+		if len(p.steps) == 0 {
+			return ToolResult{Content: "error: the plan needs at least one step"}
+		}
+		return ToolResult{Content: fmt.Sprintf("Plan saved with %d steps.", len(p.steps))}
+
+	case toolUpdatePlan.Name:
+		var in struct {
+			Step   int    `json:"step"`
+			Status string `json:"status"`
+			Text   string `json:"text"`
+			Note   string `json:"note"`
+		}
+		if err := json.Unmarshal(raw, &in); err != nil {
+			return ToolResult{Content: fmt.Sprintf("error: %s", err)}
+		}
+		switch in.Status {
+		case "", planPending, planInProgress, planDone, planSkipped:
+		default:
+			return ToolResult{Content: fmt.Sprintf("error: unknown status %q", in.Status)}
+		}
+		if in.Step == 0 {
+			if strings.TrimSpace(in.Text) == "" {
+				return ToolResult{Content: "error: give a step number, or text for a new step"}
+			}
+			p.steps = append(p.steps, PlanStep{Text: strings.TrimSpace(in.Text), Status: planPending})
+			in.Step = len(p.steps)
+		}
+		if in.Step < 1 || in.Step > len(p.steps) {
+			return ToolResult{Content: fmt.Sprintf("error: the plan has no step %d (it has %d)", in.Step, len(p.steps))}
+		}
+		s := &p.steps[in.Step-1]
+		if in.Status != "" {
+			s.Status = in.Status
+		}
+		if t := strings.TrimSpace(in.Text); t != "" {
+			s.Text = t
+		}
+		if n := strings.TrimSpace(in.Note); n != "" {
+			s.Note = n
+		}
+		return ToolResult{Content: fmt.Sprintf("Step %d is %s. %s", in.Step, strings.ReplaceAll(s.Status, "_", " "), p.progress())}
+	}
+	return ToolResult{Content: fmt.Sprintf("error: unknown plan tool %q", name)}
+}
+
+func (p *plan) progress() string {
+	done := 0
+	for _, s := range p.steps {
+		if s.Status == planDone || s.Status == planSkipped {
+			done++
+		}
+	}
+	return fmt.Sprintf("%d of %d steps finished.", done, len(p.steps))
+}
+
+// section renders the plan for the system prompt, or "" before one is
+// written.
+func (p *plan) section() string {
+	if len(p.steps) == 0 {
+		return ""
+	}
+	return "## Your plan\n\nThis is the plan you wrote; keep it current with update_plan.\n\n" + renderPlan(p.steps)
+}
+
+func renderPlan(steps []PlanStep) string {
+	var sb strings.Builder
+	for i, s := range steps {
+		box := "[ ]"
+		switch s.Status {
+		case planDone:
+			box = "[x]"
+		case planInProgress:
+			box = "[~]"
+		case planSkipped:
+			box = "[-]"
+		}
+		sb.WriteString(fmt.Sprintf("%d. %s %s", i+1, box, s.Text))
+		if s.Note != "" {
+			sb.WriteString(" — " + s.Note)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,116 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/repoconfig"
+)
+
+// maxScreenshots caps how many pages are screenshotted per PR.
+const maxScreenshots = 5
+
+// previewDir is where screenshots are written inside the clone. It is only
+// used after the branch has been pushed, so nothing in it is committed.
+const previewDir = ".droid-preview"
+
+// Screenshot is a captured page of the running dev server.
+type Screenshot struct {
+	Path string // URL path of the page, e.g. "/settings"
+	PNG  []byte
+	URL  string // where the image was uploaded; set by the worker
+}
+
+// captureScreenshots starts the repository's dev server and screenshots each
+// page with Playwright, in one command so it works in the sandbox, where each
+// command runs in a fresh container. Screenshots are best-effort: failures
+// are logged and yield fewer or no screenshots.
+func (a *Agent) captureScreenshots(ctx context.Context, repo *git.Repo, cfg repoconfig.Config, paths []string) []Screenshot {
+	if !cfg.Preview.Enabled() || len(paths) == 0 {
+		return nil
+	}
+	var pages []string
+	for _, p := range paths {
+		if u, err := url.Parse(p); err == nil && strings.HasPrefix(p, "/") && u.Host == "" && len(pages) < maxScreenshots {
+			pages = append(pages, p)
+		}
+	}
+	if len(pages) == 0 {
+		return nil
+	}
+
+	cmdCtx, cancel := context.WithTimeout(ctx, a.timeouts.Max)
+	defer cancel()
+	out, err := repo.RunInDir(cmdCtx, previewScript(cfg.Preview, pages))
+	if err != nil || strings.Contains(out, "\n(exit: ") || cmdCtx.Err() != nil {
+		a.log.Warn("screenshots failed", "err", err, "output", preview(a.redactor.String(out), 500))
+	}
+
+	var shots []Screenshot
+	for i, p := range pages {
+		png, err := repo.ReadFile(fmt.Sprintf("%s/%d.png", previewDir, i))
+		if err != nil {
+			continue
+		}
+		shots = append(shots, Screenshot{Path: p, PNG: []byte(png)})
+	}
+	a.log.Info("screenshots captured", "pages", len(pages), "captured", len(shots))
+	return shots
+}
+
+// previewScript starts the dev server in the background, waits up to two
+// minutes for it to answer, screenshots each page, and stops the server.
+func previewScript(p repoconfig.Preview, pages []string) string {
+	var sb strings.Builder
+	sb.WriteString("mkdir -p " + previewDir + "\n")
+	sb.WriteString(fmt.Sprintf("( %s ) > %s/server.log 2>&1 &\nserver=$!\n", p.Start, previewDir))
+	sb.WriteString(fmt.Sprintf(`i=0
+until node -e "fetch(process.argv[1]).then(() => process.exit(0), () => process.exit(1))" %s; do
+  i=$((i+1))
+  if [ $i -ge 60 ]; then echo "dev server did not start"; tail -n 50 %s/server.log; kill $server; exit 1; fi
+  sleep 2
+done
+status=0
+`, shQuote(p.URL), previewDir))
+	for i, page := range pages {
+		sb.WriteString(fmt.Sprintf("npx --yes playwright screenshot --full-page %s %s/%d.png || status=1\n",
+			shQuote(p.URL+page), previewDir, i))
+	}
+	sb.WriteString("kill $server\nexit $status\n")
+	return sb.String()
+}
+
+func shQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// uploadScreenshots stores each screenshot with the provider so the PR body
+// can embed it. Screenshots that fail to upload are dropped.
+func (w *Worker) uploadScreenshots(ctx context.Context, provider git.GitProvider, issue git.Issue, shots []Screenshot) []Screenshot {
+	var uploaded []Screenshot
+	for i, s := range shots {
+		name := fmt.Sprintf("issue-%d-%d.png", issue.Number, i+1)
+		u, err := provider.UploadImage(ctx, name, s.PNG)
+		if err != nil {
+			w.log.Warn("failed to upload screenshot", "page", s.Path, "err", err)
+			continue
+		}
+		s.URL = u
+		uploaded = append(uploaded, s)
+	}
+	return uploaded
+}
+
+// renderScreenshots renders the PR body section embedding uploaded
+// screenshots.
+func renderScreenshots(shots []Screenshot) string {
+	var sb strings.Builder
+	sb.WriteString("### Screenshots\n")
+	for _, s := range shots {
+		sb.WriteString(fmt.Sprintf("\n**%s**\n\n![%s](%s)\n", s.Path, s.Path, s.URL))
+	}
+	return sb.String()
+}
@@ -0,0 +1,192 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/repoconfig"
+)
+
+// TestStatus is the result of the most recent test command in a run.
+type TestStatus string
+
+const (
+	TestsUnknown TestStatus = ""
+	TestsPassing TestStatus = "passing"
+	TestsFailing TestStatus = "failing"
+)
+
+// Progress is a snapshot of a running agentic loop, taken after each tool call.
+type Progress struct {
+	Iteration     int
+	MaxIterations int
+	LastTool      string
+	LastInput     string // preview of the last tool call's input
+	Tests         TestStatus
+}
+
+// ProgressUpdate is what a ProgressSink publishes.
+type ProgressUpdate struct {
+	RepoURL  string
+	Issue    git.Issue
+	Provider git.GitProvider
+	Revision bool // addressing review feedback rather than a first run
+	Progress Progress
+	Elapsed  time.Duration
+	// Outcome is set on the final update of a job, e.g. "PR opened: <url>".
+	Outcome string
+}
+
+// ProgressSink publishes status updates for long runs.
+type ProgressSink interface {
+	PublishProgress(ctx context.Context, u ProgressUpdate) error
+}
+
+type progressKey struct{}
+
+// contextWithProgress attaches a callback that runLoop calls after every tool
+// call, like llm.ContextWithModel carries the per-run model.
+func contextWithProgress(ctx context.Context, f func(Progress)) context.Context {
+	return context.WithValue(ctx, progressKey{}, f)
+}
+
+func progressFrom(ctx context.Context) func(Progress) {
+	if f, ok := ctx.Value(progressKey{}).(func(Progress)); ok {
+		return f
+	}
+	return func(Progress) {}
+}
+
+// testStatusOf reports the test outcome of a run_command call, or false if the
+// command is not a test run. A command counts as a test run when it is the
+// repo's configured test command or mentions "test".
+func testStatusOf(input json.RawMessage, output string, cfg repoconfig.Config) (TestStatus, bool) {
+	var in runCommandInput
+	if err := json.Unmarshal(input, &in); err != nil {
+		return TestsUnknown, false
+	}
+	cmd := strings.TrimSpace(in.Command)
+	if cmd == "" || (cmd != cfg.Commands["test"] && !strings.Contains(cmd, "test")) {
+		return TestsUnknown, false
+	}
+	if strings.Contains(output, "\n(exit: ") || strings.Contains(output, "\n(stopped: ") {
+		return TestsFailing, true
+	}
+	return TestsPassing, true
+}
+
+// progressTracker throttles one job's progress updates to the worker's sinks.
+type progressTracker struct {
+	w       *Worker
+	base    ProgressUpdate
+	started time.Time
+
+	mu       sync.Mutex
+	last     Progress
+	lastSent time.Time
+}
+
+func (w *Worker) trackProgress(repoURL string, issue git.Issue, provider git.GitProvider, revision bool) *progressTracker {
+	return &progressTracker{
+		w:       w,
+		base:    ProgressUpdate{RepoURL: repoURL, Issue: issue, Provider: provider, Revision: revision},
+		started: time.Now(),
+	}
+}
+
+// step records p and publishes it if the interval has passed since the last
+// update or the test status changed.
+func (t *progressTracker) step(ctx context.Context, p Progress) {
+	if len(t.w.progress) == 0 {
+		return
+	}
+	t.mu.Lock()
+	due := time.Since(t.lastSent) >= t.w.progressEvery || p.Tests != t.last.Tests
+	t.last = p
+	if due {
+		t.lastSent = time.Now()
+	}
+	t.mu.Unlock()
+
+	if due {
+		t.publish(ctx, "")
+	}
+}
+
+// context returns ctx carrying the tracker's step callback for runLoop.
+func (t *progressTracker) context(ctx context.Context) context.Context {
+	return contextWithProgress(ctx, func(p Progress) { t.step(ctx, p) })
+}
+
+// finish publishes the final update for the job.
+func (t *progressTracker) finish(ctx context.Context, outcome string) {
+	if len(t.w.progress) == 0 {
+		return
+	}
+	t.publish(context.WithoutCancel(ctx), outcome)
+}
+
+func (t *progressTracker) publish(ctx context.Context, outcome string) {
+	t.mu.Lock()
+	u := t.base
+	u.Progress = t.last
+	t.mu.Unlock()
+	u.Elapsed = time.Since(t.started)
+	u.Outcome = outcome
+
+	for _, sink := range t.w.progress {
+		if err := sink.PublishProgress(ctx, u); err != nil {
+			t.w.log.Warn("failed to publish progress", "issue", u.Issue.Number, "err", err)
+		}
+	}
+}
+
+// renderProgress formats an update as Markdown.
+func renderProgress(u ProgressUpdate) string {
+	var sb strings.Builder
+	switch {
+	case u.Outcome != "":
+		sb.WriteString(fmt.Sprintf("**Finished** after %s: %s\n", u.Elapsed.Round(time.Second), u.Outcome))
+	case u.Revision:
+		sb.WriteString(fmt.Sprintf("**Addressing review feedback** — %s elapsed\n", u.Elapsed.Round(time.Second)))
+	default:
+		sb.WriteString(fmt.Sprintf("**Working on this issue** — %s elapsed\n", u.Elapsed.Round(time.Second)))
+	}
+
+	p := u.Progress
+	if p.Iteration > 0 {
+		sb.WriteString(fmt.Sprintf("- Iteration: %d of %d\n", p.Iteration, p.MaxIterations))
+		sb.WriteString(fmt.Sprintf("- Last tool: `%s` %s\n", p.LastTool, inlineCode(p.LastInput)))
+	}
+	if p.Tests != TestsUnknown {
+		sb.WriteString(fmt.Sprintf("- Tests: %s\n", p.Tests))
+	}
+	return sb.String()
+}
+
+func inlineCode(s string) string {
+	s = strings.ReplaceAll(strings.ReplaceAll(s, "\n", " "), "`", "'")
+	if s == "" {
+		return ""
+	}
+	return "`" + s + "`"
+}
+
+// progressMarker identifies the executor's progress comment on an issue.
+const progressMarker = "<!-- droid:executor-progress -->"
+
+// IssueCommentSink keeps a single progress comment on the issue up to date.
+type IssueCommentSink struct{}
+
+func (IssueCommentSink) PublishProgress(ctx context.Context, u ProgressUpdate) error {
+	body := progressMarker + "\n" + renderProgress(u)
+	if err := u.Provider.UpsertMarkedIssueComment(ctx, u.Issue.Number, progressMarker, body); err != nil {
+		return fmt.Errorf("upsert progress comment: %w", err)
+	}
+	return nil
+}
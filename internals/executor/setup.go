@@ -0,0 +1,48 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/repoconfig"
+)
+
+// runSetup runs the repository's setup commands before the first LLM call, so
+// the model does not spend iterations discovering and installing
+// dependencies. A failing command does not stop the run; the model is told
+// what failed and can work around it. It returns the prompt section
+// describing the results, or "" if there are no setup commands.
+func (a *Agent) runSetup(ctx context.Context, repo *git.Repo, cfg repoconfig.Config) (string, error) {
+	if len(cfg.Setup) == 0 {
+		return "", nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\n\nSetup commands from " + repoconfig.FileName + " have already been run:\n")
+	for _, command := range cfg.Setup {
+		cmdCtx, cancel := context.WithTimeout(ctx, a.timeouts.Max)
+		out, err := repo.RunInDir(cmdCtx, command)
+		timedOut := cmdCtx.Err() != nil
+		cancel()
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("setup %q interrupted: %w", command, context.Cause(ctx))
+		}
+
+		out = a.redactor.String(out)
+		switch {
+		case err != nil:
+			a.log.Warn("setup command failed", "command", command, "err", err)
+			sb.WriteString(fmt.Sprintf("- %s — failed: %s\n", command, a.redactor.String(err.Error())))
+		case timedOut || strings.Contains(out, "\n(exit: "):
+			a.log.Warn("setup command failed", "command", command, "output", preview(out, 300))
+			sb.WriteString(fmt.Sprintf("- %s — failed. Last output:\n```\n%s\n```\n", command, outputTail(out, 1500)))
+		default:
+			a.log.Info("setup command succeeded", "command", command)
+			sb.WriteString(fmt.Sprintf("- %s — succeeded\n", command))
+		}
+	}
+	sb.WriteString("Do not run these again unless you change the dependencies.")
+	return sb.String(), nil
+}
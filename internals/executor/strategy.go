@@ -0,0 +1,78 @@
+package executor
+
+import (
+	"strings"
+
+	"github.com/jadenj13/droid/internals/git"
+)
+
+// IssueType selects the strategy the executor follows for an issue.
+type IssueType string
+
+const (
+	IssueFeature  IssueType = "feature"
+	IssueBug      IssueType = "bug"
+	IssueRefactor IssueType = "refactor"
+)
+
+// typeLabels maps common label spellings to an issue type. Labels are matched
+// case-insensitively, with any "type:", "type/" or "kind/" prefix removed.
+var typeLabels = map[string]IssueType{
+	"bug":         IssueBug,
+	"bugfix":      IssueBug,
+	"fix":         IssueBug,
+	"defect":      IssueBug,
+	"regression":  IssueBug,
+	"feature":     IssueFeature,
+	"enhancement": IssueFeature,
+	"refactor":    IssueRefactor,
+	"refactoring": IssueRefactor,
+	"cleanup":     IssueRefactor,
+	"tech-debt":   IssueRefactor,
+}
+
+// issueType reads the issue's type from its labels. Issues without a type
+// label are treated as features. A bug label wins over the others, since a
+// wrongly skipped reproduction step costs more than an unneeded one.
+func issueType(issue git.Issue) IssueType {
+	found := IssueFeature
+	for _, l := range issue.Labels {
+		l = strings.ToLower(strings.TrimSpace(l))
+		for _, prefix := range []string{"type:", "type/", "kind/"} {
+			l = strings.TrimPrefix(l, prefix)
+		}
+		switch typeLabels[l] {
+		case IssueBug:
+			return IssueBug
+		case IssueRefactor:
+			found = IssueRefactor
+		}
+	}
+	return found
+}
+
+// strategyPrompt is the type-specific approach appended to the initial prompt.
+func strategyPrompt(t IssueType) string {
+	switch t {
+	case IssueBug:
+		return `This issue is a bug report. Follow this approach:
+1. Reproduce first: find the code path involved and write a test that fails because of the bug. Record it with record_test_run (phase "failing") before changing any non-test code, and confirm it fails for the reason described in the issue.
+2. If the bug cannot be reproduced in a test, explain why in the PR summary and submit with confidence "low" — submit_work is otherwise refused without a recorded failing and fixed run.
+3. Fix the root cause with the smallest change, not just the symptom in the test.
+4. Record the same test command with record_test_run (phase "fixed"), then run the full suite; both must pass. Both recorded runs go in the PR body as proof.
+5. In the PR summary, state the root cause, the reproducing test, and the fix.`
+	case IssueRefactor:
+		return `This issue is a refactor: behavior must not change. Follow this approach:
+1. Before changing anything, run the full test suite and note the result. Identify the behavior the refactored code must preserve and check it is covered by tests; add characterization tests for anything uncovered, and commit them separately before the refactor.
+2. Refactor in small steps, running the tests after each one.
+3. Do not change public interfaces, outputs or error messages unless the issue asks for it, and do not fix unrelated bugs you notice — mention them in the PR summary instead.
+4. Finish by running the same test suite as in step 1 and confirm the results match.
+5. In the PR summary, describe how behavior equivalence was checked (before/after test results and any tests added).`
+	default:
+		return `This issue is a feature. Follow this approach:
+1. Design before coding: read the code the feature touches and decide where it belongs, which existing patterns to follow, and which interfaces change.
+2. Write a short design note (components touched, new types or functions, how it is tested) and include it in the PR summary.
+3. Implement it in logical commits, adding tests for each acceptance criterion.
+4. Run the full suite before submitting.`
+	}
+}
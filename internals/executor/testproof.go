@@ -0,0 +1,137 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/jadenj13/droid/internals/git"
+)
+
+// toolRecordTestRun is offered only on bug issues, where the agent must show
+// a test that fails before the fix and passes after it.
+var toolRecordTestRun = anthropic.ToolParam{
+	Name:        "record_test_run",
+	Description: anthropic.String("Run a test command and record its result as proof for the PR. For bug fixes, first record the new test failing (phase 'failing') before changing any non-test code, then record the same command passing after the fix (phase 'fixed')."),
+	InputSchema: anthropic.ToolInputSchemaParam{
+		Properties: map[string]interface{}{
+			"command": map[string]interface{}{
+				"type":        "string",
+				"description": "Command that runs the reproducing test, narrowed to that test where possible. E.g. 'go test ./internal/auth -run TestExpiredToken'",
+			},
+			"phase": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"failing", "fixed"},
+				"description": "'failing' to record the test reproducing the bug, 'fixed' to record it passing after the fix.",
+			},
+			"timeout_seconds": map[string]interface{}{
+				"type":        "integer",
+				"description": "How long to let the command run before stopping it.",
+			},
+		},
+		Required: []string{"command", "phase"},
+	},
+}
+
+type recordTestRunInput struct {
+	Command        string `json:"command"`
+	Phase          string `json:"phase"`
+	TimeoutSeconds int    `json:"timeout_seconds"`
+}
+
+// TestRun is one recorded run of the reproducing test.
+type TestRun struct {
+	Command string
+	Output  string
+	Passed  bool
+}
+
+// TestProof is the before/after evidence that a bug fix works.
+type TestProof struct {
+	Failing *TestRun // the test reproducing the bug, before the fix
+	Fixed   *TestRun // the same test after the fix
+}
+
+func (p *TestProof) complete() bool {
+	return p != nil && p.Failing != nil && p.Fixed != nil
+}
+
+func execRecordTestRun(ctx context.Context, raw json.RawMessage, repo *git.Repo, timeouts CommandTimeouts) (ToolResult, error) {
+	var in recordTestRunInput
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return ToolResult{}, err
+	}
+	if in.Phase != "failing" && in.Phase != "fixed" {
+		return ToolResult{Content: fmt.Sprintf("error: phase must be 'failing' or 'fixed', got %q", in.Phase)}, nil
+	}
+	cmdInput, _ := json.Marshal(runCommandInput{Command: in.Command, TimeoutSeconds: in.TimeoutSeconds})
+	result, err := execRunCommand(ctx, cmdInput, repo, timeouts)
+	if err != nil || strings.HasPrefix(result.Content, "error: ") {
+		return result, err
+	}
+	result.TestRun = &TestRun{
+		Command: strings.TrimSpace(in.Command),
+		Output:  result.Content,
+		Passed:  !strings.Contains(result.Content, "\n(exit: ") && !strings.Contains(result.Content, "\n(stopped: "),
+	}
+	result.TestPhase = in.Phase
+	return result, nil
+}
+
+// record validates a run reported by record_test_run against the proof so
+// far and returns the note to append to the tool result.
+func (p *TestProof) record(phase string, run *TestRun) string {
+	switch phase {
+	case "failing":
+		if run.Passed {
+			return "error: the test passed, so it does not reproduce the bug. Write a test that fails because of the bug and record it again."
+		}
+		if p.Fixed != nil {
+			return "error: a fixed run is already recorded; the failing run must come before the fix."
+		}
+		p.Failing = run
+		return "recorded as the failing run. Now fix the bug, then record the same command with phase 'fixed'."
+	default:
+		if p.Failing == nil {
+			return "error: record the test failing (phase 'failing') before recording the fix."
+		}
+		if run.Command != p.Failing.Command {
+			return fmt.Sprintf("error: the fixed run must use the same command as the failing run: %s", p.Failing.Command)
+		}
+		if !run.Passed {
+			return "error: the test still fails. Keep working on the fix and record it again once it passes."
+		}
+		p.Fixed = run
+		return "recorded as the fixed run."
+	}
+}
+
+// proofMissing is returned in place of submit_work's result on a bug issue
+// that has no before/after proof.
+const proofMissing = `error: this is a bug issue, so submit_work needs proof that the fix works. Use record_test_run to record a new test failing (phase 'failing') and then the same command passing after the fix (phase 'fixed'). If the bug cannot be reproduced in a test, submit with confidence "low" and explain why in confidence_notes.`
+
+// renderTestProof renders both runs for the PR body.
+func renderTestProof(p *TestProof) string {
+	var sb strings.Builder
+	sb.WriteString("### Reproduction\n\n")
+	sb.WriteString(fmt.Sprintf("The test below failed before the fix and passes after it: %s\n", inlineCode(p.Failing.Command)))
+	for _, r := range []struct {
+		label string
+		run   *TestRun
+	}{{"Before the fix (failing)", p.Failing}, {"After the fix (passing)", p.Fixed}} {
+		sb.WriteString(fmt.Sprintf("\n<details><summary>%s</summary>\n\n```\n%s\n```\n\n</details>\n", r.label, outputTail(r.run.Output, 3000)))
+	}
+	return sb.String()
+}
+
+// outputTail keeps the last n bytes of test output, where failures and the
+// exit status are, and defuses code fences that would end the block early.
+func outputTail(out string, n int) string {
+	out = strings.TrimSpace(strings.ReplaceAll(out, "```", "'''"))
+	if len(out) > n {
+		out = "…" + out[len(out)-n:]
+	}
+	return out
+}
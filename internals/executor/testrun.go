@@ -0,0 +1,305 @@
+package executor
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/repoconfig"
+)
+
+var toolRunTests = anthropic.ToolParam{
+	Name:        "run_tests",
+	Description: anthropic.String("Run the repository's configured test command and get a summary: pass/fail counts and the failing tests with their output. Prefer this over run_command for tests. submit_work is refused until the most recent run_tests after your last file change passed."),
+	InputSchema: anthropic.ToolInputSchemaParam{
+		Properties: map[string]interface{}{
+			"args": map[string]interface{}{
+				"type":        "string",
+				"description": "Extra arguments appended to the test command to narrow the run, e.g. './internal/auth -run TestLogin' or 'tests/test_auth.py'. Omit to run the full suite.",
+			},
+			"timeout_seconds": map[string]interface{}{
+				"type":        "integer",
+				"description": "How long to let the tests run before stopping them.",
+			},
+		},
+	},
+}
+
+type runTestsInput struct {
+	Args           string `json:"args"`
+	TimeoutSeconds int    `json:"timeout_seconds"`
+}
+
+// TestReport is the parsed result of a run_tests call.
+type TestReport struct {
+	Passed  bool // the command exited successfully
+	Full    bool // the whole suite ran, not a narrowed subset
+	Format  string
+	Pass    int
+	Fail    int
+	Skip    int
+	Failing []FailingTest
+}
+
+// FailingTest is one failed test and the tail of its output.
+type FailingTest struct {
+	Name   string
+	Output string
+}
+
+// testOutputFile keeps the full test output inside .git, where it is never
+// committed, so parsing is not limited by the size cap on command output.
+const testOutputFile = ".git/droid-test-output"
+
+func execRunTests(ctx context.Context, raw json.RawMessage, repo *git.Repo, cfg repoconfig.Config, timeouts CommandTimeouts) (ToolResult, error) {
+	var in runTestsInput
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return ToolResult{}, err
+	}
+	command := strings.TrimSpace(cfg.Commands["test"])
+	if command == "" {
+		return ToolResult{Content: "error: no test command is configured in " + repoconfig.FileName + " (commands.test); use run_command to run tests instead"}, nil
+	}
+	format := testFormat(command)
+	if format == "go" && !strings.Contains(command, "-json") {
+		command = strings.Replace(command, "go test", "go test -json", 1)
+	}
+	if args := strings.TrimSpace(in.Args); args != "" {
+		command += " " + args
+	}
+
+	cmdInput, _ := json.Marshal(runCommandInput{
+		Command:        fmt.Sprintf("( %s ) > %s 2>&1", command, testOutputFile),
+		TimeoutSeconds: in.TimeoutSeconds,
+	})
+	result, err := execRunCommand(ctx, cmdInput, repo, timeouts)
+	if err != nil || strings.HasPrefix(result.Content, "error: ") {
+		return result, err
+	}
+	output, err := repo.ReadFile(testOutputFile)
+	if err != nil {
+		return ToolResult{Content: fmt.Sprintf("error: read test output: %s", err)}, nil
+	}
+
+	report := parseTestOutput(format, output)
+	report.Passed = !strings.Contains(result.Content, "\n(exit: ") && !strings.Contains(result.Content, "\n(stopped: ")
+	report.Full = strings.TrimSpace(in.Args) == ""
+
+	content := renderTestReport(report)
+	if report.Format == "" || (!report.Passed && len(report.Failing) == 0) {
+		// Nothing structured to show; fall back to the raw tail.
+		content += "\n\nOutput (last lines):\n" + outputTail(output, 4000)
+	}
+	if note := strings.TrimSpace(result.Content); note != "" {
+		content += "\n\n" + note
+	}
+	return ToolResult{Content: content, Tests: &report}, nil
+}
+
+// testFormat guesses the test runner from the command.
+func testFormat(command string) string {
+	switch {
+	case strings.Contains(command, "go test"):
+		return "go"
+	case strings.Contains(command, "jest"), strings.Contains(command, "vitest"):
+		return "jest"
+	case strings.Contains(command, "pytest"):
+		return "pytest"
+	}
+	return ""
+}
+
+// parseTestOutput extracts counts and failing tests. Output in an unknown
+// format, e.g. from "npm test" wrapping jest, is tried against each parser.
+func parseTestOutput(format, output string) TestReport {
+	switch format {
+	case "go":
+		return parseGoTest(output)
+	case "jest":
+		return parseJest(output)
+	case "pytest":
+		return parsePytest(output)
+	}
+	if r := parseJest(output); r.Format != "" {
+		return r
+	}
+	if r := parsePytest(output); r.Format != "" {
+		return r
+	}
+	return TestReport{}
+}
+
+type goTestEvent struct {
+	Action  string `json:"Action"`
+	Package string `json:"Package"`
+	Test    string `json:"Test"`
+	Output  string `json:"Output"`
+}
+
+// parseGoTest reads "go test -json" events. Build failures are not JSON, so
+// non-JSON lines are kept as the output of a pseudo-test for the package.
+func parseGoTest(output string) TestReport {
+	r := TestReport{Format: "go"}
+	outputs := make(map[string]*strings.Builder)
+	var other strings.Builder
+	sc := bufio.NewScanner(strings.NewReader(output))
+	sc.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for sc.Scan() {
+		var ev goTestEvent
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil {
+			other.WriteString(sc.Text() + "\n")
+			continue
+		}
+		key := ev.Package + "." + ev.Test
+		switch ev.Action {
+		case "output":
+			if outputs[key] == nil {
+				outputs[key] = &strings.Builder{}
+			}
+			outputs[key].WriteString(ev.Output)
+		case "pass":
+			if ev.Test != "" {
+				r.Pass++
+			}
+		case "skip":
+			if ev.Test != "" {
+				r.Skip++
+			}
+		case "fail":
+			if ev.Test == "" {
+				// A package that fails without failing tests did not build or
+				// panicked outside a test.
+				if !hasFailedTestIn(r.Failing, ev.Package) {
+					out := other.String() // build errors are printed as plain text
+					if b := outputs[key]; b != nil {
+						out = b.String()
+					}
+					r.Failing = append(r.Failing, FailingTest{Name: ev.Package + " (package)", Output: out})
+				}
+				continue
+			}
+			r.Fail++
+			out := ""
+			if b := outputs[key]; b != nil {
+				out = b.String()
+			}
+			r.Failing = append(r.Failing, FailingTest{Name: ev.Package + "." + ev.Test, Output: out})
+		}
+	}
+	if s := strings.TrimSpace(other.String()); s != "" && len(r.Failing) == 0 {
+		r.Failing = append(r.Failing, FailingTest{Name: "build", Output: s})
+	}
+	return r
+}
+
+func hasFailedTestIn(failing []FailingTest, pkg string) bool {
+	for _, f := range failing {
+		if strings.HasPrefix(f.Name, pkg+".") {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	jestSummary = regexp.MustCompile(`(?m)^Tests:\s+(.*)$`)
+	jestFailing = regexp.MustCompile(`(?m)^\s+● (.+)$`)
+	countRe     = regexp.MustCompile(`(\d+) (passed|failed|skipped|pending|todo|errors?)`)
+)
+
+// parseJest reads jest's (and vitest's) summary and "●" failure headings.
+func parseJest(output string) TestReport {
+	m := jestSummary.FindStringSubmatch(output)
+	if m == nil {
+		return TestReport{}
+	}
+	r := TestReport{Format: "jest"}
+	addCounts(&r, m[1])
+	sections := jestFailing.FindAllStringSubmatchIndex(output, -1)
+	for i, loc := range sections {
+		end := len(output)
+		if i+1 < len(sections) {
+			end = sections[i+1][0]
+		}
+		r.Failing = append(r.Failing, FailingTest{
+			Name:   output[loc[2]:loc[3]],
+			Output: output[loc[1]:end],
+		})
+	}
+	return r
+}
+
+var (
+	pytestSummary = regexp.MustCompile(`(?m)^=+ (.*\d+ (?:passed|failed|error).*) in [\d.]+s.* =+$`)
+	pytestFailed  = regexp.MustCompile(`(?m)^(?:FAILED|ERROR) (\S+)(?: - (.*))?$`)
+)
+
+// parsePytest reads pytest's final summary line and short test summary.
+func parsePytest(output string) TestReport {
+	m := pytestSummary.FindStringSubmatch(output)
+	if m == nil {
+		return TestReport{}
+	}
+	r := TestReport{Format: "pytest"}
+	addCounts(&r, m[1])
+	for _, f := range pytestFailed.FindAllStringSubmatch(output, -1) {
+		r.Failing = append(r.Failing, FailingTest{Name: f[1], Output: f[2]})
+	}
+	return r
+}
+
+func addCounts(r *TestReport, summary string) {
+	for _, c := range countRe.FindAllStringSubmatch(summary, -1) {
+		n, _ := strconv.Atoi(c[1])
+		switch c[2] {
+		case "passed":
+			r.Pass += n
+		case "failed", "error", "errors":
+			r.Fail += n
+		default:
+			r.Skip += n
+		}
+	}
+}
+
+// maxFailingShown bounds the failing tests listed back to the model.
+const maxFailingShown = 10
+
+// renderTestReport summarizes the run for the model.
+func renderTestReport(r TestReport) string {
+	var sb strings.Builder
+	status := "PASS"
+	if !r.Passed {
+		status = "FAIL"
+	}
+	scope := "full suite"
+	if !r.Full {
+		scope = "narrowed run"
+	}
+	if r.Format == "" {
+		sb.WriteString(fmt.Sprintf("tests: %s (%s; output format not recognised)", status, scope))
+		return sb.String()
+	}
+	sb.WriteString(fmt.Sprintf("tests: %s (%s) — %d passed, %d failed, %d skipped", status, scope, r.Pass, r.Fail, r.Skip))
+	for i, f := range r.Failing {
+		if i == maxFailingShown {
+			sb.WriteString(fmt.Sprintf("\n... and %d more failing", len(r.Failing)-maxFailingShown))
+			break
+		}
+		sb.WriteString("\n\nFAIL " + f.Name)
+		if out := outputTail(f.Output, 1200); out != "" {
+			sb.WriteString("\n" + out)
+		}
+	}
+	return sb.String()
+}
+
+// testsNotPassing is returned in place of submit_work's result when the
+// repository has a test command and the latest full run_tests did not pass.
+const testsNotPassing = `error: submit_work requires passing tests. Run run_tests (the full suite, without args) after your last change and fix any failures. If the tests cannot pass for reasons outside this issue, submit with confidence "low" and explain in confidence_notes.`
@@ -4,9 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/repoconfig"
 )
 
 var toolReadFile = anthropic.ToolParam{
@@ -41,15 +44,54 @@ var toolWriteFile = anthropic.ToolParam{
 	},
 }
 
+var toolEditFile = anthropic.ToolParam{
+	Name:        "edit_file",
+	Description: anthropic.String("Make targeted changes to an existing file without rewriting it. Provide either edits (exact search/replace pairs, applied in order) or patch (a unified diff for this file). Prefer this over write_file for anything but new or very small files. Nothing is written unless every edit applies."),
+	InputSchema: anthropic.ToolInputSchemaParam{
+		Properties: map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Path to the file relative to the repo root.",
+			},
+			"edits": map[string]interface{}{
+				"type":        "array",
+				"description": "Search/replace edits. old_string must match the file exactly, including indentation, and occur once unless replace_all is true.",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"old_string":  map[string]interface{}{"type": "string"},
+						"new_string":  map[string]interface{}{"type": "string"},
+						"replace_all": map[string]interface{}{"type": "boolean"},
+					},
+					"required": []string{"old_string", "new_string"},
+				},
+			},
+			"patch": map[string]interface{}{
+				"type":        "string",
+				"description": "Unified diff with @@ hunk headers. Context and removed lines must match the file.",
+			},
+		},
+		Required: []string{"path"},
+	},
+}
+
 var toolRunCommand = anthropic.ToolParam{
 	Name:        "run_command",
-	Description: anthropic.String("Run a shell command in the repository root. Use for building, testing, linting, and installing dependencies. Non-zero exit codes are returned as output, not errors."),
+	Description: anthropic.String("Run a shell command in the repository root, or in the issue's area of a monorepo. Use for building, testing, linting, and installing dependencies. Non-zero exit codes are returned as output, not errors."),
 	InputSchema: anthropic.ToolInputSchemaParam{
 		Properties: map[string]interface{}{
 			"command": map[string]interface{}{
 				"type":        "string",
 				"description": "Shell command to run. E.g. 'go test ./...' or 'npm run lint'",
 			},
+			"dir": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional. Directory to run in, relative to the repository root; '.' is the root. Defaults to the issue's area in a monorepo, otherwise the root.",
+			},
+			"timeout_seconds": map[string]interface{}{
+				"type":        "integer",
+				"description": "Optional. Kill the command after this many seconds and return its output so far. Raise it for slow installs or test suites; values above the executor's cap are clamped.",
+			},
 		},
 		Required: []string{"command"},
 	},
@@ -57,15 +99,45 @@ var toolRunCommand = anthropic.ToolParam{
 
 var toolListFiles = anthropic.ToolParam{
 	Name:        "list_files",
-	Description: anthropic.String("List files in the repository, optionally scoped to a subdirectory. Use this to understand the project structure before making changes."),
+	Description: anthropic.String("Show the repository's files as a directory tree with per-directory file counts, optionally scoped to a subdirectory. Files excluded by .gitignore are not listed. Use this to understand the project structure before making changes."),
 	InputSchema: anthropic.ToolInputSchemaParam{
 		Properties: map[string]interface{}{
 			"subdir": map[string]interface{}{
 				"type":        "string",
-				"description": "Subdirectory to list relative to repo root. Use '.' for the full repo.",
+				"description": "Subdirectory to list relative to repo root. Use '.' for the full repo. Omit to list the issue's area in a monorepo, otherwise the full repo.",
+			},
+			"depth": map[string]interface{}{
+				"type":        "integer",
+				"description": "How many directory levels to expand; deeper directories are shown with file counts only. Omit to expand as deep as fits.",
+			},
+		},
+	},
+}
+
+var toolSearchCode = anthropic.ToolParam{
+	Name:        "search_code",
+	Description: anthropic.String("Search file contents across the repository with a regular expression (git grep). Use this to find definitions, call sites, and config keys instead of reading files one by one. Results are 'path:line:text'; context lines use '-' instead of ':'."),
+	InputSchema: anthropic.ToolInputSchemaParam{
+		Properties: map[string]interface{}{
+			"pattern": map[string]interface{}{
+				"type":        "string",
+				"description": "Extended regular expression. E.g. 'func (New|Open)Client' or 'TODO\\(auth\\)'",
+			},
+			"globs": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Optional path globs to restrict the search. E.g. ['**/*.go', 'internal/**']. Without globs, a monorepo issue's area is searched; pass ['**'] to search everything.",
+			},
+			"context_lines": map[string]interface{}{
+				"type":        "integer",
+				"description": "Lines of context to show around each match (0-10). Defaults to 0.",
+			},
+			"ignore_case": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Match case-insensitively.",
 			},
 		},
-		Required: []string{"subdir"},
+		Required: []string{"pattern"},
 	},
 }
 
@@ -96,6 +168,20 @@ var toolSubmitWork = anthropic.ToolParam{
 				"type":        "string",
 				"description": "Description of what was done and any relevant notes for the reviewer.",
 			},
+			"confidence": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"high", "low"},
+				"description": "'low' if you are unsure the work is correct, e.g. tests could not be run or the requirements were ambiguous. The PR is then opened as a draft. Defaults to 'high'.",
+			},
+			"confidence_notes": map[string]interface{}{
+				"type":        "string",
+				"description": "Required when confidence is 'low': why, and what the reviewer should double-check.",
+			},
+			"preview_paths": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Only when UI preview is configured: up to 5 URL paths of pages your change affects, e.g. '/settings'. Each is screenshotted and attached to the PR.",
+			},
 		},
 		Required: []string{"title", "summary"},
 	},
@@ -103,10 +189,15 @@ var toolSubmitWork = anthropic.ToolParam{
 
 var AllTools = []anthropic.ToolParam{
 	toolListFiles,
+	toolSearchCode,
 	toolReadFile,
 	toolWriteFile,
+	toolEditFile,
 	toolRunCommand,
+	toolRunTests,
 	toolCommitChanges,
+	toolWritePlan,
+	toolUpdatePlan,
 	toolSubmitWork,
 }
 
@@ -119,12 +210,50 @@ type writeFileInput struct {
 	Content string `json:"content"`
 }
 
+type editFileInput struct {
+	Path  string          `json:"path"`
+	Edits []searchReplace `json:"edits"`
+	Patch string          `json:"patch"`
+}
+
 type runCommandInput struct {
-	Command string `json:"command"`
+	Command        string `json:"command"`
+	Dir            string `json:"dir"`
+	TimeoutSeconds int    `json:"timeout_seconds"`
+}
+
+// CommandTimeouts bounds each run_command call. The model may ask for any
+// timeout up to Max; Default applies when it does not ask.
+type CommandTimeouts struct {
+	Default time.Duration
+	Max     time.Duration
+}
+
+// DefaultCommandTimeouts are used when the agent is not configured otherwise.
+var DefaultCommandTimeouts = CommandTimeouts{Default: 5 * time.Minute, Max: 20 * time.Minute}
+
+// For returns the timeout for a call that requested seconds (0 for none).
+func (t CommandTimeouts) For(seconds int) time.Duration {
+	d := t.Default
+	if seconds > 0 {
+		d = time.Duration(seconds) * time.Second
+	}
+	if t.Max > 0 && d > t.Max {
+		d = t.Max
+	}
+	return d
 }
 
 type listFilesInput struct {
 	Subdir string `json:"subdir"`
+	Depth  int    `json:"depth"`
+}
+
+type searchCodeInput struct {
+	Pattern      string   `json:"pattern"`
+	Globs        []string `json:"globs"`
+	ContextLines int      `json:"context_lines"`
+	IgnoreCase   bool     `json:"ignore_case"`
 }
 
 type commitChangesInput struct {
@@ -132,29 +261,48 @@ type commitChangesInput struct {
 }
 
 type submitWorkInput struct {
-	Title   string `json:"title"`
-	Summary string `json:"summary"`
+	Title           string   `json:"title"`
+	Summary         string   `json:"summary"`
+	Confidence      string   `json:"confidence"`
+	ConfidenceNotes string   `json:"confidence_notes"`
+	PreviewPaths    []string `json:"preview_paths"`
 }
 
 type ToolResult struct {
-	Content   string
-	Done      bool   // true when submit_work is called — signals the loop to exit
-	PRTitle   string // populated on submit_work
-	PRSummary string
+	Content      string
+	Done         bool   // true when submit_work is called — signals the loop to exit
+	PRTitle      string // populated on submit_work
+	PRSummary    string
+	PRDraft      bool   // the agent reported low confidence
+	PRNotes      string // what the reviewer should double-check
+	PreviewPaths []string
+	TestRun      *TestRun // populated on record_test_run
+	TestPhase    string
+	Tests        *TestReport // populated on run_tests
+	Budget       string      // why the run stopped early, when it hit its budget
+	Paused       *Pause      // set when ask_human paused the run
 }
 
-func ExecuteTool(ctx context.Context, name string, raw json.RawMessage, repo *git.Repo) (ToolResult, error) {
+func ExecuteTool(ctx context.Context, name string, raw json.RawMessage, repo *git.Repo, cfg repoconfig.Config, timeouts CommandTimeouts) (ToolResult, error) {
 	switch name {
 	case "read_file":
 		return execReadFile(raw, repo)
 	case "write_file":
-		return execWriteFile(raw, repo)
+		return execWriteFile(raw, repo, cfg)
+	case "edit_file":
+		return execEditFile(raw, repo, cfg)
 	case "run_command":
-		return execRunCommand(ctx, raw, repo)
+		return execRunCommand(ctx, raw, repo, timeouts)
 	case "list_files":
 		return execListFiles(ctx, raw, repo)
+	case "search_code":
+		return execSearchCode(ctx, raw, repo)
 	case "commit_changes":
-		return execCommitChanges(ctx, raw, repo)
+		return execCommitChanges(ctx, raw, repo, cfg)
+	case "run_tests":
+		return execRunTests(ctx, raw, repo, cfg, timeouts)
+	case "record_test_run":
+		return execRecordTestRun(ctx, raw, repo, timeouts)
 	case "submit_work":
 		return execSubmitWork(raw)
 	default:
@@ -174,26 +322,83 @@ func execReadFile(raw json.RawMessage, repo *git.Repo) (ToolResult, error) {
 	return ToolResult{Content: content}, nil
 }
 
-func execWriteFile(raw json.RawMessage, repo *git.Repo) (ToolResult, error) {
+func execWriteFile(raw json.RawMessage, repo *git.Repo, cfg repoconfig.Config) (ToolResult, error) {
 	var in writeFileInput
 	if err := json.Unmarshal(raw, &in); err != nil {
 		return ToolResult{}, err
 	}
+	if cfg.IsProtected(in.Path) {
+		return ToolResult{Content: fmt.Sprintf("error: %s is a protected path and must not be modified", in.Path)}, nil
+	}
 	if err := repo.WriteFile(in.Path, in.Content); err != nil {
 		return ToolResult{Content: fmt.Sprintf("error: %s", err)}, nil
 	}
 	return ToolResult{Content: fmt.Sprintf("wrote %s", in.Path)}, nil
 }
 
-func execRunCommand(ctx context.Context, raw json.RawMessage, repo *git.Repo) (ToolResult, error) {
+func execEditFile(raw json.RawMessage, repo *git.Repo, cfg repoconfig.Config) (ToolResult, error) {
+	var in editFileInput
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return ToolResult{}, err
+	}
+	if cfg.IsProtected(in.Path) {
+		return ToolResult{Content: fmt.Sprintf("error: %s is a protected path and must not be modified", in.Path)}, nil
+	}
+	if (len(in.Edits) == 0) == (in.Patch == "") {
+		return ToolResult{Content: "error: provide exactly one of edits or patch"}, nil
+	}
+
+	content, err := repo.ReadFile(in.Path)
+	if err != nil {
+		return ToolResult{Content: fmt.Sprintf("error: %s — use write_file to create new files", err)}, nil
+	}
+
+	var updated string
+	if in.Patch != "" {
+		updated, err = applyPatch(content, in.Patch)
+	} else {
+		updated, err = applyEdits(content, in.Edits)
+	}
+	if err != nil {
+		return ToolResult{Content: fmt.Sprintf("error: %s. %s was not changed", err, in.Path)}, nil
+	}
+
+	if err := repo.WriteFile(in.Path, updated); err != nil {
+		return ToolResult{Content: fmt.Sprintf("error: %s", err)}, nil
+	}
+	return ToolResult{Content: fmt.Sprintf("edited %s", in.Path)}, nil
+}
+
+func execRunCommand(ctx context.Context, raw json.RawMessage, repo *git.Repo, timeouts CommandTimeouts) (ToolResult, error) {
 	var in runCommandInput
 	if err := json.Unmarshal(raw, &in); err != nil {
 		return ToolResult{}, err
 	}
-	out, err := repo.RunInDir(ctx, in.Command)
+
+	if in.Dir == "" {
+		in.Dir = areaFrom(ctx)
+	}
+	command, err := inDir(in.Dir, in.Command)
+	if err != nil {
+		return ToolResult{Content: "error: " + err.Error()}, nil
+	}
+
+	timeout := timeouts.For(in.TimeoutSeconds)
+	cmdCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	out, err := repo.RunInDir(cmdCtx, command)
+	if ctx.Err() != nil {
+		// The job itself was cancelled or ran out of time; stop the loop.
+		return ToolResult{}, fmt.Errorf("run_command interrupted: %w", context.Cause(ctx))
+	}
 	if err != nil {
 		return ToolResult{Content: fmt.Sprintf("error: %s", err)}, nil
 	}
+	if cmdCtx.Err() != nil {
+		out += fmt.Sprintf("\n\nerror: command timed out after %s; the output above is partial. If it needs longer, retry with a larger timeout_seconds (max %d), or run a narrower command.",
+			timeout, int(timeouts.Max.Seconds()))
+	}
 	return ToolResult{Content: out}, nil
 }
 
@@ -202,14 +407,39 @@ func execListFiles(ctx context.Context, raw json.RawMessage, repo *git.Repo) (To
 	if err := json.Unmarshal(raw, &in); err != nil {
 		return ToolResult{}, err
 	}
-	out, err := repo.ListFiles(ctx, in.Subdir)
+	if in.Subdir == "" {
+		in.Subdir = areaFrom(ctx)
+	}
+	out, err := repo.ListFiles(ctx, in.Subdir, in.Depth)
 	if err != nil {
 		return ToolResult{Content: fmt.Sprintf("error: %s", err)}, nil
 	}
 	return ToolResult{Content: out}, nil
 }
 
-func execCommitChanges(ctx context.Context, raw json.RawMessage, repo *git.Repo) (ToolResult, error) {
+func execSearchCode(ctx context.Context, raw json.RawMessage, repo *git.Repo) (ToolResult, error) {
+	var in searchCodeInput
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return ToolResult{}, err
+	}
+	if area := areaFrom(ctx); len(in.Globs) == 0 && area != "" {
+		in.Globs = []string{area + "/**"}
+	}
+	out, err := repo.Search(ctx, in.Pattern, git.SearchOptions{
+		Globs:        in.Globs,
+		ContextLines: in.ContextLines,
+		IgnoreCase:   in.IgnoreCase,
+	})
+	if err != nil {
+		return ToolResult{Content: fmt.Sprintf("error: %s", err)}, nil
+	}
+	if out == "" {
+		return ToolResult{Content: "no matches"}, nil
+	}
+	return ToolResult{Content: out}, nil
+}
+
+func execCommitChanges(ctx context.Context, raw json.RawMessage, repo *git.Repo, cfg repoconfig.Config) (ToolResult, error) {
 	var in commitChangesInput
 	if err := json.Unmarshal(raw, &in); err != nil {
 		return ToolResult{}, err
@@ -217,6 +447,24 @@ func execCommitChanges(ctx context.Context, raw json.RawMessage, repo *git.Repo)
 	if err := repo.Add(ctx); err != nil {
 		return ToolResult{Content: fmt.Sprintf("error staging: %s", err)}, nil
 	}
+	if len(cfg.ProtectedPaths) > 0 {
+		staged, err := repo.StagedFiles(ctx)
+		if err != nil {
+			return ToolResult{Content: fmt.Sprintf("error listing staged files: %s", err)}, nil
+		}
+		var blocked []string
+		for _, f := range staged {
+			if cfg.IsProtected(f) {
+				blocked = append(blocked, f)
+			}
+		}
+		if len(blocked) > 0 {
+			if err := repo.Unstage(ctx); err != nil {
+				return ToolResult{Content: fmt.Sprintf("error unstaging: %s", err)}, nil
+			}
+			return ToolResult{Content: fmt.Sprintf("error: changes to protected paths are not allowed: %s. Revert them (e.g. git checkout -- <path>) and commit again.", strings.Join(blocked, ", "))}, nil
+		}
+	}
 	committed, err := repo.Commit(ctx, in.Message)
 	if err != nil {
 		return ToolResult{Content: fmt.Sprintf("error committing: %s", err)}, nil
@@ -232,10 +480,22 @@ func execSubmitWork(raw json.RawMessage) (ToolResult, error) {
 	if err := json.Unmarshal(raw, &in); err != nil {
 		return ToolResult{}, err
 	}
+	switch in.Confidence {
+	case "", "high":
+	case "low":
+		if strings.TrimSpace(in.ConfidenceNotes) == "" {
+			return ToolResult{Content: "error: confidence_notes is required when confidence is low — explain what the reviewer should double-check"}, nil
+		}
+	default:
+		return ToolResult{Content: fmt.Sprintf("error: confidence must be 'high' or 'low', got %q", in.Confidence)}, nil
+	}
 	return ToolResult{
-		Content:   "work submitted",
-		Done:      true,
-		PRTitle:   in.Title,
-		PRSummary: in.Summary,
+		Content:      "work submitted",
+		Done:         true,
+		PRTitle:      in.Title,
+		PRSummary:    in.Summary,
+		PRDraft:      in.Confidence == "low",
+		PRNotes:      strings.TrimSpace(in.ConfidenceNotes),
+		PreviewPaths: in.PreviewPaths,
 	}, nil
 }
@@ -0,0 +1,71 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jadenj13/droid/internals/git"
+)
+
+// trackingLabel marks the parent tracking issues created by the planner.
+const trackingLabel = "agent:tracking"
+
+// HandlePRMerged completes the PR's originating issue if the executor opened
+// the PR, and checks off its task item in any open tracking issue that lists
+// it.
+func (w *Worker) HandlePRMerged(ctx context.Context, repoURL, prURL, prBody string) error {
+	issueNumber := issueNumberFromURL(git.ExtractIssueURL(prBody))
+	if issueNumber == 0 {
+		return nil // not an agent PR, or no linked issue
+	}
+
+	provider, _, err := w.factory.ProviderFor(ctx, repoURL)
+	if err != nil {
+		return fmt.Errorf("build provider: %w", err)
+	}
+
+	if strings.Contains(prBody, prMarker) {
+		if err := w.completeIssue(ctx, provider, repoURL, issueNumber, prURL); err != nil {
+			return err
+		}
+	}
+
+	trackers, err := provider.ListIssuesByLabel(ctx, trackingLabel)
+	if err != nil {
+		return fmt.Errorf("list tracking issues: %w", err)
+	}
+
+	for _, t := range trackers {
+		body, changed := checkTask(t.Body, issueNumber)
+		if !changed {
+			continue
+		}
+		if err := provider.UpdateIssueBody(ctx, t.Number, body); err != nil {
+			return fmt.Errorf("update tracking issue #%d: %w", t.Number, err)
+		}
+		w.log.Info("tracking issue updated", "tracking", t.Number, "issue", issueNumber)
+	}
+	return nil
+}
+
+// checkTask ticks the "- [ ] #N" task item for issueNumber. It reports whether
+// the body changed.
+func checkTask(body string, issueNumber int) (string, bool) {
+	re := regexp.MustCompile(`(?m)^(\s*[-*] )\[ \]( #` + strconv.Itoa(issueNumber) + `\b)`)
+	out := re.ReplaceAllString(body, "${1}[x]${2}")
+	return out, out != body
+}
+
+// issueNumberFromURL extracts the issue number from a URL like
+// https://github.com/org/repo/issues/42
+func issueNumberFromURL(url string) int {
+	parts := strings.Split(strings.TrimRight(url, "/"), "/")
+	n, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil {
+		return 0
+	}
+	return n
+}
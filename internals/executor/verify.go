@@ -0,0 +1,74 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/repoconfig"
+)
+
+// maxVerifyFailures is how many times verification may reject submit_work
+// before the run fails instead of opening a broken PR.
+const maxVerifyFailures = 3
+
+// verify runs the repository's build and test commands after submit_work,
+// independently of what the model reports. It returns a description of the
+// failures, or "" when everything passed or nothing is configured.
+func (a *Agent) verify(ctx context.Context, repo *git.Repo, cfg repoconfig.Config) (string, error) {
+	var failures []string
+
+	if build := strings.TrimSpace(cfg.Commands["build"]); build != "" {
+		cmdCtx, cancel := context.WithTimeout(ctx, a.timeouts.Max)
+		out, err := repo.RunInDir(cmdCtx, build)
+		timedOut := cmdCtx.Err() != nil
+		cancel()
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("verify build interrupted: %w", context.Cause(ctx))
+		}
+		switch {
+		case err != nil:
+			failures = append(failures, fmt.Sprintf("build (%s) could not run: %s", build, err))
+		case timedOut || strings.Contains(out, "\n(exit: "):
+			failures = append(failures, fmt.Sprintf("build (%s) failed:\n```\n%s\n```", build, outputTail(a.redactor.String(out), 3000)))
+		}
+	}
+
+	if cfg.Commands["test"] != "" {
+		res, err := execRunTests(ctx, []byte(`{}`), repo, cfg, CommandTimeouts{Default: a.timeouts.Max, Max: a.timeouts.Max})
+		if err != nil {
+			return "", fmt.Errorf("verify tests: %w", err)
+		}
+		if res.Tests == nil || !res.Tests.Passed {
+			failures = append(failures, "tests failed:\n"+a.redactor.String(res.Content))
+		}
+	}
+
+	if len(failures) > 0 {
+		a.log.Warn("verification failed", "checks", len(failures))
+	}
+	return strings.Join(failures, "\n\n"), nil
+}
+
+// verifyAll verifies the primary repository and every secondary one in ws.
+func (a *Agent) verifyAll(ctx context.Context, repo *git.Repo, cfg repoconfig.Config, ws *workspace) (string, error) {
+	failures, err := a.verify(ctx, repo, cfg)
+	if err != nil || ws == nil {
+		return failures, err
+	}
+	all := []string{}
+	if failures != "" {
+		all = append(all, "In "+ws.primary+":\n"+failures)
+	}
+	for _, co := range ws.others {
+		f, err := a.verify(ctx, co.repo, co.cfg)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", co.name, err)
+		}
+		if f != "" {
+			all = append(all, "In "+co.name+":\n"+f)
+		}
+	}
+	return strings.Join(all, "\n\n"), nil
+}
@@ -0,0 +1,182 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+var toolWebFetch = anthropic.ToolParam{
+	Name:        "web_fetch",
+	Description: anthropic.String("Fetch a documentation page, changelog or API reference and return it as plain text. Only allowlisted domains can be fetched. Use this when the issue involves a library or API you are unsure about; prefer the repository's own code and vendored sources when they answer the question."),
+	InputSchema: anthropic.ToolInputSchemaParam{
+		Properties: map[string]interface{}{
+			"url": map[string]interface{}{
+				"type":        "string",
+				"description": "The http(s) URL to fetch.",
+			},
+			"offset": map[string]interface{}{
+				"type":        "integer",
+				"description": "Character offset to start from, to read past the end of a long page.",
+			},
+		},
+		Required: []string{"url"},
+	},
+}
+
+type webFetchInput struct {
+	URL    string `json:"url"`
+	Offset int    `json:"offset"`
+}
+
+// WebFetcher backs the web_fetch tool. Only hosts in Domains, or their
+// subdomains, can be fetched, including after redirects.
+type WebFetcher struct {
+	Domains  []string
+	MaxBytes int64 // cap on the downloaded body
+	MaxChars int   // cap on the text returned per call
+	client   *http.Client
+}
+
+func NewWebFetcher(domains []string) *WebFetcher {
+	f := &WebFetcher{Domains: domains, MaxBytes: 2 << 20, MaxChars: 20000}
+	f.client = &http.Client{
+		Timeout: 30 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return errors.New("too many redirects")
+			}
+			if !f.allowed(req.URL) {
+				return fmt.Errorf("redirect to %s is not on the allowlist", req.URL.Host)
+			}
+			return nil
+		},
+	}
+	return f
+}
+
+func (f *WebFetcher) allowed(u *url.URL) bool {
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, d := range f.Domains {
+		d = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(d), "*."))
+		if d != "" && (host == d || strings.HasSuffix(host, "."+d)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *WebFetcher) execute(ctx context.Context, raw json.RawMessage) (ToolResult, error) {
+	var in webFetchInput
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return ToolResult{}, err
+	}
+	u, err := url.Parse(strings.TrimSpace(in.URL))
+	if err != nil || u.Host == "" {
+		return ToolResult{Content: fmt.Sprintf("error: invalid URL %q", in.URL)}, nil
+	}
+	if !f.allowed(u) {
+		return ToolResult{Content: fmt.Sprintf("error: %s is not on the allowlist; allowed domains: %s", u.Host, strings.Join(f.Domains, ", "))}, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return ToolResult{Content: fmt.Sprintf("error: %s", err)}, nil
+	}
+	req.Header.Set("User-Agent", "droid-executor")
+	req.Header.Set("Accept", "text/html,text/plain,text/markdown,application/json;q=0.9,*/*;q=0.1")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return ToolResult{Content: fmt.Sprintf("error: fetch %s: %s", u, err)}, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return ToolResult{Content: fmt.Sprintf("error: fetch %s: %s", u, resp.Status)}, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.MaxBytes))
+	if err != nil {
+		return ToolResult{Content: fmt.Sprintf("error: read %s: %s", u, err)}, nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	var text string
+	switch {
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		text = htmlToText(string(body))
+	case strings.HasPrefix(mediaType, "text/"), mediaType == "application/json", mediaType == "":
+		text = string(body)
+	default:
+		return ToolResult{Content: fmt.Sprintf("error: %s is %s, not a text document", u, mediaType)}, nil
+	}
+
+	total := len(text)
+	start := min(max(in.Offset, 0), total)
+	end := min(start+f.MaxChars, total)
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s (characters %d-%d of %d)\n\n", resp.Request.URL, start, end, total))
+	sb.WriteString(strings.ToValidUTF8(text[start:end], ""))
+	if end < total {
+		sb.WriteString(fmt.Sprintf("\n\n... truncated; call web_fetch with offset %d to continue", end))
+	}
+	return ToolResult{Content: sb.String()}, nil
+}
+
+// htmlDropped matches elements whose content is never worth reading. Go's
+// regexp has no backreferences, so there is one pattern per element.
+var htmlDropped = func() []*regexp.Regexp {
+	var out []*regexp.Regexp
+	for _, tag := range []string{"script", "style", "noscript", "svg", "head", "nav", "footer"} {
+		out = append(out, regexp.MustCompile(`(?is)<`+tag+`\b.*?</`+tag+`\s*>`))
+	}
+	return out
+}()
+
+var (
+	htmlComment  = regexp.MustCompile(`(?s)<!--.*?-->`)
+	htmlHeading  = regexp.MustCompile(`(?i)<h([1-6])\b[^>]*>`)
+	htmlListItem = regexp.MustCompile(`(?i)<li\b[^>]*>`)
+	htmlBreak    = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|pre|tr|h[1-6]|section|article|table|ul|ol|dd|dt|blockquote)>`)
+	htmlCell     = regexp.MustCompile(`(?i)</t[dh]>`)
+	htmlTag      = regexp.MustCompile(`(?s)<[^>]*>`)
+	blankLines   = regexp.MustCompile(`\n{3,}`)
+	spaceRun     = regexp.MustCompile(`[ \t]+`)
+)
+
+// htmlToText reduces a page to readable text: scripts, styles and page
+// chrome are dropped, block elements become line breaks and headings keep a
+// markdown marker. Whitespace inside <pre> is not preserved exactly.
+func htmlToText(s string) string {
+	s = htmlComment.ReplaceAllString(s, "")
+	for _, re := range htmlDropped {
+		s = re.ReplaceAllString(s, "")
+	}
+	s = htmlHeading.ReplaceAllStringFunc(s, func(m string) string {
+		n := htmlHeading.FindStringSubmatch(m)[1][0] - '0'
+		return "\n\n" + strings.Repeat("#", int(n)) + " "
+	})
+	s = htmlListItem.ReplaceAllString(s, "\n- ")
+	s = htmlBreak.ReplaceAllString(s, "\n")
+	s = htmlCell.ReplaceAllString(s, " | ")
+	s = htmlTag.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(spaceRun.ReplaceAllString(line, " "))
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
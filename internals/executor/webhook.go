@@ -1,43 +1,88 @@
 package executor
 
 import (
-	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/queue"
 )
 
 type WebhookServer struct {
-	worker       *Worker
+	queue        Enqueuer
 	githubSecret string
 	gitlabSecret string
+	linearSecret string
+	linearRepos  map[string]string // Linear team key → repository URL; empty ignores Linear
+	deliveries   *DeliveryLog
+	labelJobs    map[string]string // issue label → job kind
 	log          *slog.Logger
 }
 
-func NewWebhookServer(worker *Worker, githubSecret, gitlabSecret string, log *slog.Logger) *WebhookServer {
-	return &WebhookServer{
-		worker:       worker,
+type WebhookOption func(*WebhookServer)
+
+// WithDeliveryLog skips webhook deliveries already in log, so that a delivery
+// the git host sends twice is processed once.
+func WithDeliveryLog(log *DeliveryLog) WebhookOption {
+	return func(s *WebhookServer) { s.deliveries = log }
+}
+
+// WithRevisionLabel watches label, in place of agent:revision, for the
+// reviewer's revision requests.
+func WithRevisionLabel(label string) WebhookOption {
+	return func(s *WebhookServer) {
+		delete(s.labelJobs, "agent:revision")
+		s.labelJobs[label] = jobRevision
+	}
+}
+
+// Enqueuer accepts jobs for durable, asynchronous processing.
+type Enqueuer interface {
+	Enqueue(kind string, payload any) error
+}
+
+func NewWebhookServer(queue Enqueuer, githubSecret, gitlabSecret string, log *slog.Logger, opts ...WebhookOption) *WebhookServer {
+	s := &WebhookServer{
+		queue:        queue,
 		githubSecret: githubSecret,
 		gitlabSecret: gitlabSecret,
+		labelJobs:    maps.Clone(labelJobs),
 		log:          log,
 	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
 }
 
 func (s *WebhookServer) Handler() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/webhook/github", s.handleGitHub)
 	mux.HandleFunc("/webhook/gitlab", s.handleGitLab)
+	if len(s.linearRepos) > 0 {
+		mux.HandleFunc("/webhook/linear", s.handleLinear)
+	}
 	return mux
 }
 
+// labelJobs maps the issue labels the executor reacts to onto job kinds, by
+// default.
+var labelJobs = map[string]string{
+	"agent:ready":    jobIssue,
+	"agent:revision": jobRevision,
+	applyLabel:       jobApply,
+}
+
 type githubWebhookPayload struct {
 	Action string `json:"action"`
 	Label  struct {
@@ -61,8 +106,20 @@ func (s *WebhookServer) handleGitHub(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
+	s.once(w, r.Header.Get("x-github-delivery"), func(w http.ResponseWriter) {
+		s.routeGitHub(w, r.Header.Get("x-github-event"), body)
+	})
+}
 
-	event := r.Header.Get("x-github-event")
+func (s *WebhookServer) routeGitHub(w http.ResponseWriter, event string, body []byte) {
+	if event == "pull_request" {
+		s.handleGitHubPR(w, body)
+		return
+	}
+	if event == "issue_comment" {
+		s.handleGitHubComment(w, body)
+		return
+	}
 	if event != "issues" {
 		w.WriteHeader(http.StatusNoContent)
 		return
@@ -74,7 +131,13 @@ func (s *WebhookServer) handleGitHub(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if payload.Action != "labeled" || payload.Label.Name != "agent:ready" {
+	if payload.Action == "closed" {
+		s.enqueueClosed(w, issueClosedJob{RepoURL: payload.Repository.HTMLURL, Issue: payload.Issue.Number})
+		return
+	}
+
+	kind, ok := s.labelJobs[payload.Label.Name]
+	if payload.Action != "labeled" || !ok {
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
@@ -85,13 +148,174 @@ func (s *WebhookServer) handleGitHub(w http.ResponseWriter, r *http.Request) {
 		URL:    payload.Issue.URL,
 	}
 
-	go func() {
-		ctx := context.Background()
-		if err := s.worker.HandleIssue(ctx, payload.Repository.HTMLURL, issue); err != nil {
-			s.log.Error("handle issue failed", "issue", issue.Number, "err", err)
-		}
-	}()
+	s.enqueueIssue(w, kind, issueJob{RepoURL: payload.Repository.HTMLURL, Issue: issue, LabeledAt: time.Now()})
+}
 
+// enqueueIssue queues an issue or revision job unless one for the same issue
+// is already queued or running.
+func (s *WebhookServer) enqueueIssue(w http.ResponseWriter, kind string, job issueJob) {
+	err := s.queue.Enqueue(kind, job)
+	if errors.Is(err, queue.ErrDuplicate) {
+		s.log.Info("issue already queued, ignoring label event", "issue", job.Issue.Number, "kind", kind)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		s.log.Error("enqueue issue failed", "issue", job.Issue.Number, "err", err)
+		http.Error(w, "enqueue failed", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// enqueueClosed queues the check for issues blocked on a closed issue.
+func (s *WebhookServer) enqueueClosed(w http.ResponseWriter, job issueClosedJob) {
+	if err := s.queue.Enqueue(jobClosed, job); err != nil {
+		s.log.Error("enqueue issue closed failed", "issue", job.Issue, "err", err)
+		http.Error(w, "enqueue failed", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// once runs handle for a delivery unless the delivery log has already seen
+// its ID. A delivery that handle does not accept is forgotten again, so the
+// host's retry goes through.
+func (s *WebhookServer) once(w http.ResponseWriter, id string, handle func(http.ResponseWriter)) {
+	if s.deliveries == nil {
+		handle(w)
+		return
+	}
+	if !s.deliveries.Claim(id) {
+		s.log.Info("ignoring redelivered webhook", "delivery", id)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	handle(rec)
+	if rec.status >= 300 {
+		s.deliveries.Release(id)
+		return
+	}
+	if err := s.deliveries.Save(); err != nil {
+		s.log.Warn("failed to save delivery log", "err", err)
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+type githubPRPayload struct {
+	Action      string `json:"action"`
+	PullRequest struct {
+		Number   int       `json:"number"`
+		Title    string    `json:"title"`
+		Merged   bool      `json:"merged"`
+		MergedAt time.Time `json:"merged_at"`
+		Body     string    `json:"body"`
+		URL      string    `json:"html_url"`
+	} `json:"pull_request"`
+	Repository struct {
+		HTMLURL string `json:"html_url"`
+	} `json:"repository"`
+}
+
+func (s *WebhookServer) handleGitHubPR(w http.ResponseWriter, body []byte) {
+	var payload githubPRPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, "bad payload", http.StatusBadRequest)
+		return
+	}
+
+	if payload.Action != "closed" || !payload.PullRequest.Merged {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	job := prMergedJob{
+		RepoURL:  payload.Repository.HTMLURL,
+		PRTitle:  payload.PullRequest.Title,
+		PRURL:    payload.PullRequest.URL,
+		PRBody:   payload.PullRequest.Body,
+		MergedAt: payload.PullRequest.MergedAt,
+	}
+	if err := s.queue.Enqueue(jobPRMerged, job); err != nil {
+		s.log.Error("enqueue PR merged failed", "pr", payload.PullRequest.Number, "err", err)
+		http.Error(w, "enqueue failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+type githubCommentPayload struct {
+	Action  string `json:"action"`
+	Comment struct {
+		Body string `json:"body"`
+		User struct {
+			Login string `json:"login"`
+			Type  string `json:"type"`
+		} `json:"user"`
+	} `json:"comment"`
+	Issue struct {
+		Number      int       `json:"number"`
+		Title       string    `json:"title"`
+		URL         string    `json:"html_url"`
+		PullRequest *struct{} `json:"pull_request"`
+	} `json:"issue"`
+	Repository struct {
+		HTMLURL string `json:"html_url"`
+	} `json:"repository"`
+}
+
+func (s *WebhookServer) handleGitHubComment(w http.ResponseWriter, body []byte) {
+	var payload githubCommentPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, "bad payload", http.StatusBadRequest)
+		return
+	}
+	if payload.Action != "created" || payload.Comment.User.Type == "Bot" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	s.enqueueCommand(w, payload.Comment.Body, commandJob{
+		RepoURL: payload.Repository.HTMLURL,
+		Issue: git.Issue{
+			Number: payload.Issue.Number,
+			Title:  payload.Issue.Title,
+			URL:    payload.Issue.URL,
+		},
+		Author: payload.Comment.User.Login,
+		OnPR:   payload.Issue.PullRequest != nil, // comments on PRs arrive here too
+	})
+}
+
+// enqueueCommand queues the command in a comment body, if there is one.
+func (s *WebhookServer) enqueueCommand(w http.ResponseWriter, body string, job commandJob) {
+	verb, args, ok := parseCommand(body)
+	// Only backport works on PRs; the other commands label issues.
+	if !ok || job.OnPR && verb != commandBackport {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	job.Verb = verb
+	job.Args = args
+	if verb == commandAnswer {
+		job.Text = commandText(body, verb)
+	}
+	job.CommentedAt = time.Now()
+	if err := s.queue.Enqueue(jobCommand, job); err != nil {
+		s.log.Error("enqueue command failed", "issue", job.Issue.Number, "err", err)
+		http.Error(w, "enqueue failed", http.StatusInternalServerError)
+		return
+	}
 	w.WriteHeader(http.StatusAccepted)
 }
 
@@ -108,13 +332,28 @@ type gitlabWebhookPayload struct {
 		} `json:"labels"`
 	} `json:"changes"`
 	ObjectAttributes struct {
-		IID   int    `json:"iid"`
-		Title string `json:"title"`
-		URL   string `json:"url"`
+		IID          int    `json:"iid"`
+		Title        string `json:"title"`
+		URL          string `json:"url"`
+		Action       string `json:"action"`
+		Description  string `json:"description"`
+		Note         string `json:"note"`
+		NoteableType string `json:"noteable_type"`
 	} `json:"object_attributes"`
 	Project struct {
 		WebURL string `json:"web_url"`
 	} `json:"project"`
+	User struct {
+		Username string `json:"username"`
+	} `json:"user"`
+	Issue struct {
+		IID   int    `json:"iid"`
+		Title string `json:"title"`
+	} `json:"issue"`
+	MergeRequest struct {
+		IID   int    `json:"iid"`
+		Title string `json:"title"`
+	} `json:"merge_request"`
 }
 
 func (s *WebhookServer) handleGitLab(w http.ResponseWriter, r *http.Request) {
@@ -129,18 +368,71 @@ func (s *WebhookServer) handleGitLab(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.once(w, r.Header.Get("x-gitlab-event-uuid"), func(w http.ResponseWriter) {
+		s.routeGitLab(w, body)
+	})
+}
+
+func (s *WebhookServer) routeGitLab(w http.ResponseWriter, body []byte) {
 	var payload gitlabWebhookPayload
 	if err := json.Unmarshal(body, &payload); err != nil {
 		http.Error(w, "bad payload", http.StatusBadRequest)
 		return
 	}
 
+	if payload.ObjectKind == "merge_request" && payload.ObjectAttributes.Action == "merge" {
+		job := prMergedJob{
+			RepoURL:  payload.Project.WebURL,
+			PRTitle:  payload.ObjectAttributes.Title,
+			PRURL:    payload.ObjectAttributes.URL,
+			PRBody:   payload.ObjectAttributes.Description,
+			MergedAt: time.Now(),
+		}
+		if err := s.queue.Enqueue(jobPRMerged, job); err != nil {
+			s.log.Error("enqueue MR merged failed", "mr", payload.ObjectAttributes.IID, "err", err)
+			http.Error(w, "enqueue failed", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	if payload.ObjectKind == "note" && (payload.ObjectAttributes.NoteableType == "Issue" || payload.ObjectAttributes.NoteableType == "MergeRequest") {
+		issueURL, _, _ := strings.Cut(payload.ObjectAttributes.URL, "#") // the note's URL is the issue's plus #note_N
+		job := commandJob{
+			RepoURL: payload.Project.WebURL,
+			Issue: git.Issue{
+				Number: payload.Issue.IID,
+				Title:  payload.Issue.Title,
+				URL:    issueURL,
+			},
+			Author: payload.User.Username,
+		}
+		if payload.ObjectAttributes.NoteableType == "MergeRequest" {
+			job.Issue.Number, job.Issue.Title = payload.MergeRequest.IID, payload.MergeRequest.Title
+			job.OnPR = true
+		}
+		s.enqueueCommand(w, payload.ObjectAttributes.Note, job)
+		return
+	}
+
 	if payload.ObjectKind != "issue" {
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
+	if payload.ObjectAttributes.Action == "close" {
+		s.enqueueClosed(w, issueClosedJob{RepoURL: payload.Project.WebURL, Issue: payload.ObjectAttributes.IID})
+		return
+	}
 
-	if !labelAdded(payload.Changes.Labels.Current, payload.Changes.Labels.Previous, "agent:ready") {
+	kind := ""
+	for label, k := range s.labelJobs {
+		if labelAdded(payload.Changes.Labels.Current, payload.Changes.Labels.Previous, label) {
+			kind = k
+			break
+		}
+	}
+	if kind == "" {
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
@@ -151,14 +443,7 @@ func (s *WebhookServer) handleGitLab(w http.ResponseWriter, r *http.Request) {
 		URL:    payload.ObjectAttributes.URL,
 	}
 
-	go func() {
-		ctx := context.Background()
-		if err := s.worker.HandleIssue(ctx, payload.Project.WebURL, issue); err != nil {
-			s.log.Error("handle issue failed", "issue", issue.Number, "err", err)
-		}
-	}()
-
-	w.WriteHeader(http.StatusAccepted)
+	s.enqueueIssue(w, kind, issueJob{RepoURL: payload.Project.WebURL, Issue: issue, LabeledAt: time.Now()})
 }
 
 func (s *WebhookServer) readAndVerify(r *http.Request, secret, sigHeader string) ([]byte, error) {
@@ -2,40 +2,134 @@ package executor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
+	"time"
 
+	"github.com/jadenj13/droid/internals/analytics"
 	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/llm"
+	"github.com/jadenj13/droid/internals/tracker"
 )
 
-type PROpener interface {
-	OpenPR(ctx context.Context, input PRInput) (PRURL string, err error)
+type Worker struct {
+	agent    *Agent
+	factory  git.Factory
+	token    string // git clone token (same as the issue tracker token)
+	log      *slog.Logger
+	attempts *AttemptStore    // nil disables prior-attempt context
+	pauses   *PauseStore      // nil leaves out the ask_human tool
+	dryRuns  *DryRunStore     // nil keeps no dry runs to apply
+	notifier Notifier         // nil disables failure notifications
+	metrics  *analytics.Store // nil disables delivery analytics
+	trackers tracker.Set      // empty links no tracker tickets to PRs
+	deadline time.Duration    // 0 lets a job run until the loop ends
+
+	progress      []ProgressSink // empty disables progress updates
+	progressEvery time.Duration
+
+	commandPermission git.Permission // needed to start work from a comment
+	commandUsers      []string       // may start work from a comment regardless
+
+	reviewLabel   string // added to the issue once its PR is open, for the reviewer
+	revisionLabel string // the reviewer's request for a revision
+	approvedLabel string // the reviewer's approval, removed on merge
 }
 
-type PRInput struct {
-	Title       string
-	Body        string
-	Branch      string // head branch
-	Base        string // target branch, usually "main"
-	IssueNumber int
-	Draft       bool
+type WorkerOption func(*Worker)
+
+// WithAttemptStore records failed runs so a retry of the same issue starts
+// with a summary of what the previous run tried.
+func WithAttemptStore(s *AttemptStore) WorkerOption {
+	return func(w *Worker) { w.attempts = s }
 }
 
-type Worker struct {
-	agent   *Agent
-	factory git.Factory
-	token   string // git clone token (same as the issue tracker token)
-	log     *slog.Logger
+// WithPauseStore gives runs the ask_human tool: a run can pause on a
+// question, kept in s until a "/droid answer" comment resumes it.
+func WithPauseStore(s *PauseStore) WorkerOption {
+	return func(w *Worker) { w.pauses = s }
+}
+
+// WithDryRunStore keeps the patch of each dry run in s, so that agent:apply
+// can open it as a PR.
+func WithDryRunStore(s *DryRunStore) WorkerOption {
+	return func(w *Worker) { w.dryRuns = s }
+}
+
+// WithNotifier reports runs that failed for good, with a way to retry them.
+func WithNotifier(n Notifier) WorkerOption {
+	return func(w *Worker) { w.notifier = n }
+}
+
+// WithAnalytics records each issue's path from label to merged PR for the
+// delivery metrics endpoint.
+func WithAnalytics(s *analytics.Store) WorkerOption {
+	return func(w *Worker) { w.metrics = s }
+}
+
+// WithJobDeadline bounds how long one issue or revision job may run, so a
+// stuck run cannot hold a worker slot indefinitely.
+func WithJobDeadline(d time.Duration) WorkerOption {
+	return func(w *Worker) { w.deadline = d }
+}
+
+// WithCommandPolicy sets who may start work with a "/droid implement" style
+// issue comment: users with at least min access to the repository, and the
+// listed users regardless of their access.
+func WithCommandPolicy(min git.Permission, users ...string) WorkerOption {
+	return func(w *Worker) {
+		w.commandPermission = min
+		w.commandUsers = users
+	}
 }
 
-func NewWorker(agent *Agent, factory git.Factory, token string, log *slog.Logger) *Worker {
-	return &Worker{agent: agent, factory: factory, token: token, log: log}
+// WithReviewLabels renames the labels the executor shares with the reviewer:
+// the one it hands PRs over with, the reviewer's revision request and its
+// approval. They must match the reviewer's.
+func WithReviewLabels(review, revision, approved string) WorkerOption {
+	return func(w *Worker) { w.reviewLabel, w.revisionLabel, w.approvedLabel = review, revision, approved }
+}
+
+// WithTracker links each PR from the ticket in t that its issue was filed
+// for, named on the issue's ticket line, e.g. "Jira: …".
+func WithTracker(t tracker.Tracker) WorkerOption {
+	return func(w *Worker) { w.trackers = append(w.trackers, t) }
+}
+
+// WithProgress publishes the status of each running job to sinks, at most
+// once per interval unless the test status changes.
+func WithProgress(interval time.Duration, sinks ...ProgressSink) WorkerOption {
+	return func(w *Worker) {
+		w.progress = append(w.progress, sinks...)
+		w.progressEvery = interval
+	}
+}
+
+func NewWorker(agent *Agent, factory git.Factory, token string, log *slog.Logger, opts ...WorkerOption) *Worker {
+	w := &Worker{
+		agent: agent, factory: factory, token: token, log: log,
+		commandPermission: git.PermissionWrite,
+		reviewLabel:       "agent:review",
+		revisionLabel:     "agent:revision",
+		approvedLabel:     "agent:approved",
+	}
+	for _, o := range opts {
+		o(w)
+	}
+	return w
 }
 
 func (w *Worker) HandleIssue(ctx context.Context, repoURL string, issue git.Issue) error {
 	w.log.Info("handling issue", "issue", issue.Number, "title", issue.Title)
 
+	ctx, cancel := w.withDeadline(ctx)
+	defer cancel()
+	meter := llm.NewMeter()
+	ctx = llm.ContextWithMeter(ctx, meter)
+	defer w.recordUsage(repoURL, issue.Number, meter)
+
 	provider, _, err := w.factory.ProviderFor(ctx, repoURL)
 	if err != nil {
 		return fmt.Errorf("build provider: %w", err)
@@ -46,44 +140,388 @@ func (w *Worker) HandleIssue(ctx context.Context, repoURL string, issue git.Issu
 		return fmt.Errorf("fetch issue: %w", err)
 	}
 	issue = full
+	if wait, err := w.waitForDependencies(ctx, provider, issue); err != nil {
+		return fmt.Errorf("check dependencies: %w", err)
+	} else if wait {
+		return nil
+	}
+
+	if err := provider.AddReaction(ctx, issue.Number, git.ReactionEyes); err != nil {
+		w.log.Warn("failed to add pickup reaction", "issue", issue.Number, "err", err)
+	}
+
+	var secondary []git.GitProvider
+	for _, u := range SecondaryRepos(issue.Body) {
+		p, _, err := w.factory.ProviderFor(ctx, u)
+		if err != nil {
+			return fmt.Errorf("build provider for %s: %w", u, err)
+		}
+		secondary = append(secondary, p)
+	}
 
-	result, err := w.agent.Run(ctx, issue, provider, w.token)
+	progress := w.trackProgress(repoURL, issue, provider, false)
+	prior := w.loadAttempt(repoURL, issue.Number)
+	resume := w.loadPause(repoURL, issue.Number)
+	runCtx := contextWithQuestions(progress.context(ctx), w.pauses != nil)
+	result, err := w.agent.Run(runCtx, issue, provider, secondary, w.token, prior, resume)
 	if err != nil {
+		err = deadlineCause(ctx, err)
+		progress.finish(ctx, "failed: "+preview(err.Error(), 300))
+		w.saveAttempt(ctx, repoURL, issue.Number, err, prior)
 		return fmt.Errorf("agent run: %w", err)
 	}
+	w.clearAttempt(repoURL, issue.Number)
+	if result.Paused != nil {
+		if err := w.pause(ctx, provider, repoURL, issue, result.Paused); err != nil {
+			return err
+		}
+		w.log.Info("run paused on a question", "issue", issue.Number, "branch", result.Branch)
+		progress.finish(ctx, "paused: waiting for an answer on the issue")
+		return nil
+	}
+	if resume != nil {
+		w.resumed(ctx, provider, repoURL, issue.Number)
+	}
+	if result.Patch != "" {
+		if err := w.proposePatch(ctx, provider, repoURL, issue, result); err != nil {
+			return err
+		}
+		progress.finish(ctx, "dry run: patch posted on the issue for approval")
+		return nil
+	}
 
-	opener, ok := provider.(PROpener)
-	if !ok {
-		return fmt.Errorf("provider does not support opening PRs")
+	if len(result.Screenshots) > 0 {
+		result.Screenshots = w.uploadScreenshots(ctx, provider, issue, result.Screenshots)
 	}
+	for i := range result.Linked {
+		w.openLinkedPR(ctx, &result.Linked[i], result, issue, repoName(provider))
+	}
+	prURL, err := w.openPR(ctx, provider, repoURL, issue, result)
+	if err != nil {
+		return err
+	}
+	progress.finish(ctx, "PR opened: "+prURL)
+	return nil
+}
 
-	prURL, err := opener.OpenPR(ctx, PRInput{
-		Title:       result.Title,
-		Body:        buildPRBody(result, issue),
+// openPR opens the PR for a finished run and hands the issue to the
+// reviewer, or to a human when the run stopped at its budget.
+func (w *Worker) openPR(ctx context.Context, provider git.GitProvider, repoURL string, issue git.Issue, result PRResult) (string, error) {
+	prURL, err := provider.OpenPR(ctx, git.PRInput{
+		Title:       w.agent.redactor.String(result.Title),
+		Body:        w.agent.redactor.String(buildPRBody(result, issue)),
 		Branch:      result.Branch,
-		Base:        "main",
+		Base:        result.BaseBranch,
 		IssueNumber: issue.Number,
-		Draft:       false,
+		Draft:       result.Draft,
 	})
 	if err != nil {
-		return fmt.Errorf("open PR: %w", err)
+		return "", fmt.Errorf("open PR: %w", err)
 	}
 
-	w.log.Info("PR opened", "url", prURL, "issue", issue.Number)
+	w.log.Info("PR opened", "url", prURL, "issue", issue.Number, "draft", result.Draft, "budget_exceeded", result.BudgetExceeded != "")
+	w.clearFailure(ctx, provider, issue)
+	w.recordOpened(repoURL, issue.Number, prURL, result.Title)
+	w.linkTicket(ctx, issue, prURL, result.Title)
 
-	if err := provider.AddLabel(ctx, issue.Number, "agent:review"); err != nil {
-		w.log.Warn("failed to add agent:review label", "err", err)
+	if err := provider.AddReaction(ctx, issue.Number, git.ReactionRocket); err != nil {
+		w.log.Warn("failed to add PR-opened reaction", "issue", issue.Number, "err", err)
+	}
+
+	if result.BudgetExceeded != "" {
+		// The draft needs a human to finish it before review.
+		if err := provider.AddLabel(ctx, issue.Number, budgetLabel); err != nil {
+			w.log.Warn("failed to add budget label", "err", err)
+		}
+		return prURL, nil
+	}
+	if err := provider.AddLabel(ctx, issue.Number, w.reviewLabel); err != nil {
+		w.log.Warn("failed to add review label", "label", w.reviewLabel, "err", err)
 		// Non-fatal — the PR is open regardless.
 	}
 
+	return prURL, nil
+}
+
+// openLinkedPR opens the PR for a secondary repository's branch and records
+// its URL on l. Failures are logged: the branch stays pushed and the primary
+// PR lists it, so it can be opened by hand.
+func (w *Worker) openLinkedPR(ctx context.Context, l *LinkedPR, result PRResult, issue git.Issue, primary string) {
+	body := fmt.Sprintf("%s\n\n---\nPart of %s, together with the PR from branch `%s` in %s. Merge them together.\n",
+		result.Summary, issue.URL, result.Branch, primary)
+	url, err := l.Provider.OpenPR(ctx, git.PRInput{
+		Title:  w.agent.redactor.String(result.Title),
+		Body:   w.agent.redactor.String(body),
+		Branch: l.Branch,
+		Base:   l.BaseBranch,
+		Draft:  result.Draft,
+	})
+	if err != nil {
+		w.log.Warn("failed to open linked PR", "repo", l.Repo, "branch", l.Branch, "err", err)
+		return
+	}
+	l.URL = url
+	w.log.Info("linked PR opened", "url", url, "issue", issue.Number)
+}
+
+// linkTicket links the PR from the tracker ticket the issue was filed for, if
+// any, so the ticket shows the work done for it.
+func (w *Worker) linkTicket(ctx context.Context, issue git.Issue, prURL, title string) {
+	t, ok := tracker.TicketFor(issue.Body)
+	if !ok {
+		return
+	}
+	tr := w.trackers.Get(t.Tracker)
+	if tr == nil {
+		w.log.Debug("issue names a ticket in an unconfigured tracker", "issue", issue.Number, "tracker", t.Tracker)
+		return
+	}
+	if err := tr.AddLink(ctx, t.Key, prURL, "PR: "+title); err != nil {
+		w.log.Warn("failed to link PR from ticket", "ticket", t.Key, "url", prURL, "err", err)
+		return
+	}
+	w.log.Info("PR linked from ticket", "ticket", t.Key, "url", prURL)
+}
+
+// HandleRevision addresses review feedback on the open PR for issue, pushes the
+// fixes to the PR branch, and hands the issue back to the reviewer.
+func (w *Worker) HandleRevision(ctx context.Context, repoURL string, issue git.Issue) error {
+	w.log.Info("handling revision", "issue", issue.Number)
+
+	ctx, cancel := w.withDeadline(ctx)
+	defer cancel()
+	meter := llm.NewMeter()
+	ctx = llm.ContextWithMeter(ctx, meter)
+	defer w.recordUsage(repoURL, issue.Number, meter)
+
+	provider, _, err := w.factory.ProviderFor(ctx, repoURL)
+	if err != nil {
+		return fmt.Errorf("build provider: %w", err)
+	}
+
+	full, err := provider.GetIssue(ctx, issue.Number)
+	if err != nil {
+		return fmt.Errorf("fetch issue: %w", err)
+	}
+	issue = full
+
+	branch := git.BranchName(issue.Number, issue.Title)
+	prNumber, err := provider.FindOpenPR(ctx, branch)
+	if err != nil {
+		return fmt.Errorf("find PR: %w", err)
+	}
+	if prNumber == 0 {
+		return fmt.Errorf("no open PR for branch %s", branch)
+	}
+
+	pr, err := provider.GetPR(ctx, prNumber)
+	if err != nil {
+		return fmt.Errorf("get PR: %w", err)
+	}
+	comments, err := provider.GetPRComments(ctx, prNumber)
+	if err != nil {
+		return fmt.Errorf("get PR comments: %w", err)
+	}
+
+	if err := provider.AddReaction(ctx, issue.Number, git.ReactionEyes); err != nil {
+		w.log.Warn("failed to add pickup reaction", "issue", issue.Number, "err", err)
+	}
+
+	progress := w.trackProgress(repoURL, issue, provider, true)
+	result, err := w.agent.Revise(progress.context(ctx), issue, pr, comments, provider, w.token)
+	if err != nil {
+		err = deadlineCause(ctx, err)
+		progress.finish(ctx, "failed: "+preview(err.Error(), 300))
+		return fmt.Errorf("agent revise: %w", err)
+	}
+
+	w.log.Info("revision pushed", "pr", prNumber, "issue", issue.Number)
+	w.clearFailure(ctx, provider, issue)
+	progress.finish(ctx, fmt.Sprintf("revision pushed to %s", pr.URL))
+
+	if err := provider.RemoveLabel(ctx, issue.Number, w.revisionLabel); err != nil {
+		w.log.Warn("failed to remove revision label", "label", w.revisionLabel, "err", err)
+	}
+	if result.BudgetExceeded != "" {
+		w.log.Warn("revision stopped at budget", "pr", prNumber, "reason", result.BudgetExceeded)
+		body := fmt.Sprintf("The executor %s while addressing the review and pushed its work so far. It still needs to be finished by hand.\n\n%s\n\n%s",
+			result.BudgetExceeded, result.Notes, budgetMarker)
+		if err := provider.UpsertMarkedComment(ctx, prNumber, budgetMarker, w.agent.redactor.String(body)); err != nil {
+			w.log.Warn("failed to comment on budget stop", "pr", prNumber, "err", err)
+		}
+		if err := provider.AddLabel(ctx, issue.Number, budgetLabel); err != nil {
+			w.log.Warn("failed to add budget label", "err", err)
+		}
+		return nil
+	}
+	if p := result.Pipeline; p.Status == git.PipelineFailed || p.Status == git.PipelineCanceled {
+		body := fmt.Sprintf("The revision is pushed, but CI is still failing.\n\n%s\n\n%s", result.Notes, ciMarker)
+		if err := provider.UpsertMarkedComment(ctx, prNumber, ciMarker, w.agent.redactor.String(body)); err != nil {
+			w.log.Warn("failed to comment on CI failure", "pr", prNumber, "err", err)
+		}
+	}
+	// Remove and re-add so the reviewer sees a fresh "labeled" event.
+	if err := provider.RemoveLabel(ctx, issue.Number, w.reviewLabel); err != nil {
+		w.log.Warn("failed to remove review label", "label", w.reviewLabel, "err", err)
+	}
+	if err := provider.AddLabel(ctx, issue.Number, w.reviewLabel); err != nil {
+		return fmt.Errorf("add %s label: %w", w.reviewLabel, err)
+	}
+
 	return nil
 }
 
+// errJobDeadline is the cancellation cause when a job runs past its deadline.
+var errJobDeadline = errors.New("job deadline exceeded")
+
+func (w *Worker) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if w.deadline <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeoutCause(ctx, w.deadline, fmt.Errorf("%w (%s)", errJobDeadline, w.deadline))
+}
+
+// deadlineCause makes a run error that was caused by the job deadline say so,
+// rather than surfacing only the bare "context deadline exceeded" of whatever
+// call was in flight.
+func deadlineCause(ctx context.Context, err error) error {
+	if cause := context.Cause(ctx); errors.Is(cause, errJobDeadline) && !errors.Is(err, errJobDeadline) {
+		return fmt.Errorf("%w: %w", cause, err)
+	}
+	return err
+}
+
+// NotifyFailed reports an issue whose job has exhausted its retries, on the
+// issue itself and to the notifier. retryLabel is the label that triggered the
+// job, which a human re-adds to retry.
+func (w *Worker) NotifyFailed(ctx context.Context, repoURL string, issue git.Issue, attempts int, runErr, retryLabel string) {
+	if provider, _, err := w.factory.ProviderFor(ctx, repoURL); err != nil {
+		w.log.Warn("failed to build provider for failure report", "issue", issue.Number, "err", err)
+	} else {
+		if full, err := provider.GetIssue(ctx, issue.Number); err == nil {
+			issue = full
+		}
+		w.reportFailure(ctx, provider, repoURL, issue, attempts, runErr, retryLabel)
+	}
+
+	if w.notifier == nil {
+		return
+	}
+	err := w.notifier.NotifyRunFailed(ctx, RunFailedMessage{
+		IssueURL:   issue.URL,
+		IssueTitle: issue.Title,
+		RepoURL:    repoURL,
+		Error:      runErr,
+		Attempts:   attempts,
+	})
+	if err != nil {
+		w.log.Warn("failed to send failure notification", "issue", issue.Number, "err", err)
+	}
+}
+
+func (w *Worker) loadAttempt(repoURL string, issue int) *Attempt {
+	if w.attempts == nil {
+		return nil
+	}
+	a, err := w.attempts.Load(repoURL, issue)
+	if err != nil {
+		w.log.Warn("failed to load prior attempt", "issue", issue, "err", err)
+		return nil
+	}
+	if a != nil {
+		w.log.Info("retrying with prior attempt context", "issue", issue, "steps", len(a.Steps))
+	}
+	return a
+}
+
+// saveAttempt records a failed run with a distilled summary, carrying forward
+// the summaries of earlier attempts.
+func (w *Worker) saveAttempt(ctx context.Context, repoURL string, issue int, runErr error, prior *Attempt) {
+	if w.attempts == nil {
+		return
+	}
+	at := Attempt{
+		Issue:      issue,
+		Number:     1,
+		Error:      runErr.Error(),
+		Steps:      stepsOf(runErr),
+		Plan:       planOf(runErr),
+		FinishedAt: time.Now(),
+	}
+	if prior != nil {
+		at.Number = prior.Number + 1
+		at.Earlier = append(at.Earlier, prior.Earlier...)
+		if prior.Summary != "" {
+			at.Earlier = append(at.Earlier, prior.Summary)
+		} else {
+			at.Earlier = append(at.Earlier, "Failed with: "+prior.Error)
+		}
+	}
+
+	// Runs that failed before any tool call (clone, config) have nothing to
+	// distill. The run may have failed because ctx was cancelled; still record it.
+	if len(at.Steps) > 0 {
+		summary, err := w.agent.distillAttempt(context.WithoutCancel(ctx), at)
+		if err != nil {
+			w.log.Warn("failed to distill attempt", "issue", issue, "err", err)
+		}
+		at.Summary = summary
+	}
+
+	if err := w.attempts.Save(repoURL, at); err != nil {
+		w.log.Warn("failed to save attempt", "issue", issue, "err", err)
+	}
+}
+
+func (w *Worker) clearAttempt(repoURL string, issue int) {
+	if w.attempts == nil {
+		return
+	}
+	if err := w.attempts.Delete(repoURL, issue); err != nil {
+		w.log.Warn("failed to clear attempt", "issue", issue, "err", err)
+	}
+}
+
 func buildPRBody(result PRResult, issue git.Issue) string {
 	var sb strings.Builder
 	sb.WriteString(result.Summary)
+	if result.TestProof.complete() {
+		sb.WriteString("\n\n" + renderTestProof(result.TestProof))
+	}
+	if len(result.Screenshots) > 0 {
+		sb.WriteString("\n\n" + renderScreenshots(result.Screenshots))
+	}
+	if len(result.Linked) > 0 {
+		sb.WriteString("\n\n### Linked pull requests\n\nThis change spans several repositories; merge these together with this PR:\n")
+		for _, l := range result.Linked {
+			if l.URL != "" {
+				sb.WriteString(fmt.Sprintf("- %s: %s\n", l.Repo, l.URL))
+			} else {
+				sb.WriteString(fmt.Sprintf("- %s: branch `%s` (opening the PR failed)\n", l.Repo, l.Branch))
+			}
+		}
+	}
+	if result.BudgetExceeded != "" {
+		sb.WriteString("\n\n### :hourglass: Budget exceeded — opened as a draft\n\n")
+		sb.WriteString(fmt.Sprintf("The executor %s before finishing, so this PR is unverified work in progress. ", result.BudgetExceeded))
+		sb.WriteString(result.Notes)
+		sb.WriteString("\n\nFinish the remaining work on this branch, then mark the PR ready for review.")
+	} else if result.Draft {
+		sb.WriteString("\n\n### :warning: Low confidence — opened as a draft\n\n")
+		sb.WriteString(result.Notes)
+		sb.WriteString("\n\nPlease double-check the points above, then mark the PR ready for review.")
+	}
+	if p := result.Pipeline; p.URL != "" && p.Status != git.PipelineNone {
+		sb.WriteString("\n\n" + renderPipeline(p))
+	}
+	if result.Artifacts != "" {
+		sb.WriteString("\n\n" + renderArtifacts(result.Artifacts))
+	}
 	sb.WriteString("\n\n---\n")
 	sb.WriteString(fmt.Sprintf("Closes %s\n", issue.URL))
-	sb.WriteString("\n*Opened by the Executor Agent*")
+	if t, ok := tracker.TicketFor(issue.Body); ok {
+		sb.WriteString(t.Line() + "\n")
+	}
+	sb.WriteString("\n" + prMarker)
 	return sb.String()
 }
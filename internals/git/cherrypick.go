@@ -0,0 +1,70 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// CherryPick applies commit onto the current branch, recording its origin in
+// the message (-x). A merge commit is picked against its first parent. When
+// the commit does not apply cleanly the pick is left in progress and the
+// conflicted paths are returned; resolve them and call ContinueCherryPick, or
+// AbortCherryPick.
+func (r *Repo) CherryPick(ctx context.Context, sha string) ([]string, error) {
+	// The clone is shallow: fetch the commit with its parents so the pick has
+	// a base to diff against.
+	if _, err := run(ctx, r.dir, "git", "fetch", "--depth=2", "origin", sha); err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", sha, err)
+	}
+	parents, err := run(ctx, r.dir, "git", "rev-list", "--parents", "-n", "1", sha)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"cherry-pick", "-x"}
+	if len(strings.Fields(parents)) > 2 {
+		args = append(args, "-m", "1")
+	}
+	_, pickErr := run(ctx, r.dir, "git", append(args, sha)...)
+	if pickErr == nil {
+		return nil, nil
+	}
+	conflicts, err := r.ConflictedFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(conflicts) == 0 {
+		return nil, fmt.Errorf("cherry-pick %s: %w", sha, pickErr)
+	}
+	return conflicts, nil
+}
+
+// ConflictedFiles lists the paths with unresolved merge conflicts.
+func (r *Repo) ConflictedFiles(ctx context.Context) ([]string, error) {
+	out, err := run(ctx, r.dir, "git", "diff", "--name-only", "--diff-filter=U")
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, l := range strings.Split(out, "\n") {
+		if l != "" {
+			files = append(files, l)
+		}
+	}
+	return files, nil
+}
+
+// ContinueCherryPick stages the working tree and commits the in-progress
+// cherry-pick with its prepared message.
+func (r *Repo) ContinueCherryPick(ctx context.Context) error {
+	if err := r.Add(ctx); err != nil {
+		return err
+	}
+	_, err := run(ctx, r.dir, "git", "-c", "core.editor=true", "cherry-pick", "--continue")
+	return err
+}
+
+func (r *Repo) AbortCherryPick(ctx context.Context) error {
+	_, err := run(ctx, r.dir, "git", "cherry-pick", "--abort")
+	return err
+}
@@ -8,43 +8,121 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
+// agentEmail is the commit author identity used for every agent commit. It is
+// also how Push recognises commits the agent owns.
+const agentEmail = "agent@localhost"
+
 type Repo struct {
-	dir string // absolute path to the working tree
+	dir          string // absolute path to the working tree
+	pushStrategy PushStrategy
+	runner       CommandRunner // nil runs commands directly on the host
+	network      *Network      // nil uses git's own TLS and proxy settings
+	ssh          *SSHAuth      // nil authenticates every host with the HTTPS token
+}
+
+// CommandRunner executes an agent-supplied shell command against the working
+// tree, e.g. inside a container sandbox. Implementations return the combined
+// output; a failing command is reported in the output, not as an error.
+type CommandRunner interface {
+	Run(ctx context.Context, dir, command string) (string, error)
+}
+
+type CloneOption func(*Repo)
+
+// WithRunner routes RunInDir through r instead of the host shell.
+func WithRunner(r CommandRunner) CloneOption {
+	return func(repo *Repo) { repo.runner = r }
+}
+
+func WithPushStrategy(s PushStrategy) CloneOption {
+	return func(r *Repo) { r.pushStrategy = s }
+}
+
+// WithSSH clones and pushes over SSH for the hosts a is configured for.
+func WithSSH(a *SSHAuth) CloneOption {
+	return func(r *Repo) { r.ssh = a }
 }
 
-func Clone(ctx context.Context, repoURL, token string) (*Repo, error) {
+// WithCloneNetwork clones, fetches, and pushes through n's CA bundle and proxy.
+func WithCloneNetwork(n *Network) CloneOption {
+	return func(r *Repo) { r.network = n }
+}
+
+func Clone(ctx context.Context, repoURL, token string, opts ...CloneOption) (*Repo, error) {
 	dir, err := os.MkdirTemp("", "agent-executor-*")
 	if err != nil {
 		return nil, fmt.Errorf("create temp dir: %w", err)
 	}
 
-	authedURL, err := injectToken(repoURL, token)
-	if err != nil {
-		os.RemoveAll(dir)
-		return nil, err
+	r := &Repo{dir: dir}
+	for _, o := range opts {
+		o(r)
+	}
+
+	args := append([]string{"clone", "--depth=1"}, r.network.cloneConfig()...)
+	remote, sshConfig, useSSH := r.ssh.remote(repoURL)
+	if useSSH {
+		args = append(args, sshConfig...)
+	} else {
+		remote, err = injectToken(repoURL, token)
+		if err != nil {
+			os.RemoveAll(dir)
+			return nil, err
+		}
 	}
 
-	if _, err := run(ctx, "", "git", "clone", "--depth=1", authedURL, dir); err != nil {
+	if _, err := run(ctx, "", "git", append(args, remote, dir)...); err != nil {
 		os.RemoveAll(dir)
 		return nil, fmt.Errorf("git clone: %w", err)
 	}
 
-	if _, err := run(ctx, dir, "git", "config", "user.email", "agent@localhost"); err != nil {
+	if _, err := run(ctx, dir, "git", "config", "user.email", agentEmail); err != nil {
 		return nil, err
 	}
 	if _, err := run(ctx, dir, "git", "config", "user.name", "Executor Agent"); err != nil {
 		return nil, err
 	}
-
-	return &Repo{dir: dir}, nil
+	return r, nil
 }
 
 func (r *Repo) Dir() string { return r.dir }
 
 func (r *Repo) Cleanup() { os.RemoveAll(r.dir) }
 
+// CheckoutRemoteBranch fetches branch from origin and checks it out, so work
+// can start from a branch other than the default one the clone landed on.
+func (r *Repo) CheckoutRemoteBranch(ctx context.Context, branch string) error {
+	if _, err := run(ctx, r.dir, "git", "fetch", "--depth=1", "origin", branch); err != nil {
+		return err
+	}
+	_, err := run(ctx, r.dir, "git", "checkout", "-B", branch, "FETCH_HEAD")
+	return err
+}
+
+// StagedFiles lists the paths staged for the next commit.
+func (r *Repo) StagedFiles(ctx context.Context) ([]string, error) {
+	out, err := run(ctx, r.dir, "git", "diff", "--cached", "--name-only")
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, l := range strings.Split(out, "\n") {
+		if l != "" {
+			files = append(files, l)
+		}
+	}
+	return files, nil
+}
+
+// Unstage resets the index to HEAD, keeping working tree changes.
+func (r *Repo) Unstage(ctx context.Context) error {
+	_, err := run(ctx, r.dir, "git", "reset", "--quiet")
+	return err
+}
+
 func (r *Repo) CreateBranch(ctx context.Context, name string) error {
 	_, err := run(ctx, r.dir, "git", "checkout", "-b", name)
 	return err
@@ -55,6 +133,12 @@ func (r *Repo) CurrentBranch(ctx context.Context) (string, error) {
 	return strings.TrimSpace(out), err
 }
 
+// Head returns the commit HEAD points at.
+func (r *Repo) Head(ctx context.Context) (string, error) {
+	out, err := run(ctx, r.dir, "git", "rev-parse", "HEAD")
+	return strings.TrimSpace(out), err
+}
+
 func (r *Repo) Add(ctx context.Context) error {
 	_, err := run(ctx, r.dir, "git", "add", "-A")
 	return err
@@ -72,19 +156,15 @@ func (r *Repo) Commit(ctx context.Context, message string) (bool, error) {
 	return err == nil, err
 }
 
-func (r *Repo) Push(ctx context.Context) error {
-	branch, err := r.CurrentBranch(ctx)
-	if err != nil {
-		return err
-	}
-	_, err = run(ctx, r.dir, "git", "push", "origin", branch)
-	return err
-}
-
 func (r *Repo) Diff(ctx context.Context) (string, error) {
 	return run(ctx, r.dir, "git", "diff", "HEAD")
 }
 
+// DiffSince returns the unified diff from commit to HEAD.
+func (r *Repo) DiffSince(ctx context.Context, commit string) (string, error) {
+	return run(ctx, r.dir, "git", "diff", commit, "HEAD")
+}
+
 func BranchName(issueNumber int, title string) string {
 	slug := strings.ToLower(title)
 	replacer := strings.NewReplacer(" ", "-", "/", "-", "\\", "-", ":", "", ".", "")
@@ -123,24 +203,60 @@ func run(ctx context.Context, dir string, name string, args ...string) (string,
 	return stdout.String(), nil
 }
 
+// StopGrace is how long a cancelled command's output pipes are kept open
+// after it is killed, so that output from lingering child processes is still
+// collected without letting them block the caller.
+const StopGrace = 5 * time.Second
+
+// RunInDir runs an agent-supplied shell command in the working tree. When ctx
+// ends first the command is killed and the output so far is returned, followed
+// by a note saying why it stopped.
 func (r *Repo) RunInDir(ctx context.Context, command string) (string, error) {
-	cmd := exec.CommandContext(ctx, "sh", "-c", command)
-	cmd.Dir = r.dir
+	var out string
+	if r.runner != nil {
+		var err error
+		out, err = r.runner.Run(ctx, r.dir, command)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		cmd := exec.CommandContext(ctx, "sh", "-c", command)
+		cmd.Dir = r.dir
+		cmd.WaitDelay = StopGrace
 
-	var buf bytes.Buffer
-	cmd.Stdout = &buf
-	cmd.Stderr = &buf
+		var buf bytes.Buffer
+		cmd.Stdout = &buf
+		cmd.Stderr = &buf
 
-	_ = cmd.Run()
-	out := buf.String()
+		err := cmd.Run()
+		out = buf.String()
+		if ctx.Err() != nil {
+			out += fmt.Sprintf("\n(stopped: %s)", ctx.Err())
+		} else if _, ok := err.(*exec.ExitError); ok {
+			out += fmt.Sprintf("\n(exit: %s)", err)
+		}
+	}
 
 	const maxBytes = 8000
 	if len(out) > maxBytes {
-		out = out[:maxBytes] + fmt.Sprintf("\n... (truncated, %d bytes total)", len(out))
+		// Keep the exit status line, which callers read to tell pass from fail.
+		body, status := splitStatus(out)
+		out = body[:min(len(body), maxBytes)] + fmt.Sprintf("\n... (truncated, %d bytes total)", len(out)) + status
 	}
 	return out, nil
 }
 
+// splitStatus separates a trailing "(exit: …)" or "(stopped: …)" line from
+// command output.
+func splitStatus(out string) (body, status string) {
+	for _, marker := range []string{"\n(exit: ", "\n(stopped: "} {
+		if i := strings.LastIndex(out, marker); i >= 0 && !strings.Contains(out[i+1:], "\n") {
+			return out[:i], out[i:]
+		}
+	}
+	return out, ""
+}
+
 func (r *Repo) ReadFile(relPath string) (string, error) {
 	abs := filepath.Join(r.dir, relPath)
 	b, err := os.ReadFile(abs)
@@ -157,26 +273,3 @@ func (r *Repo) WriteFile(relPath, content string) error {
 	}
 	return os.WriteFile(abs, []byte(content), 0644)
 }
-
-func (r *Repo) ListFiles(ctx context.Context, subdir string) (string, error) {
-	target := filepath.Join(r.dir, subdir)
-	out, err := run(ctx, r.dir, "find", target,
-		"-not", "-path", "*/.git/*",
-		"-not", "-path", "*/node_modules/*",
-		"-not", "-path", "*/__pycache__/*",
-	)
-	if err != nil {
-		return "", err
-	}
-
-	lines := strings.Split(strings.TrimSpace(out), "\n")
-	for i, l := range lines {
-		lines[i] = strings.TrimPrefix(l, r.dir+"/")
-	}
-	const maxLines = 200
-	if len(lines) > maxLines {
-		lines = lines[:maxLines]
-		lines = append(lines, fmt.Sprintf("... (%d more files)", len(lines)-maxLines))
-	}
-	return strings.Join(lines, "\n"), nil
-}
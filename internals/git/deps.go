@@ -0,0 +1,38 @@
+package git
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	dependsOnHeading = regexp.MustCompile(`(?i)^#{1,6}\s+depends on\s*$`)
+	dependsOnRef     = regexp.MustCompile(`^[-*]\s+(?:\[[ xX]\]\s+)?#(\d+)\b`)
+	markdownHeading  = regexp.MustCompile(`^#{1,6}\s`)
+)
+
+// DependsOn returns the issue numbers listed under the "Depends On" heading
+// the planner writes into issue bodies.
+func DependsOn(body string) []int {
+	var out []int
+	in := false
+	for _, l := range strings.Split(body, "\n") {
+		l = strings.TrimSpace(l)
+		switch {
+		case dependsOnHeading.MatchString(l):
+			in = true
+		case markdownHeading.MatchString(l):
+			in = false
+		case in:
+			if m := dependsOnRef.FindStringSubmatch(l); m != nil {
+				n, _ := strconv.Atoi(m[1])
+				if !slices.Contains(out, n) {
+					out = append(out, n)
+				}
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,126 @@
+package git
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// DiffOptions controls which files GetPR includes in PR.Diff and how much of
+// each patch is kept, so the reviewer sees the meaningful changes rather than
+// lockfiles and generated code.
+type DiffOptions struct {
+	// Exclude holds glob patterns matched against each file path. A pattern
+	// without a slash matches the base name ("*.lock"); a trailing "/**"
+	// matches everything under a directory ("vendor/**"); a leading "**/"
+	// matches at any depth.
+	Exclude []string
+	// MaxFileBytes caps the patch kept for a single file. 0 means no cap.
+	MaxFileBytes int
+}
+
+// DefaultDiffOptions returns the filters used when none are configured.
+func DefaultDiffOptions() DiffOptions {
+	return DiffOptions{
+		Exclude: []string{
+			"*.lock",
+			"package-lock.json",
+			"pnpm-lock.yaml",
+			"go.sum",
+			"vendor/**",
+			"node_modules/**",
+			"*.min.js",
+			"*.min.css",
+		},
+		MaxFileBytes: 10000,
+	}
+}
+
+// Excludes reports whether p matches one of the exclude patterns.
+func (o DiffOptions) Excludes(p string) bool {
+	for _, pattern := range o.Exclude {
+		if matchGlob(pattern, p) {
+			return true
+		}
+	}
+	return false
+}
+
+type diffFile struct {
+	OldPath string
+	NewPath string
+	Patch   string
+	Binary  bool
+}
+
+func renderDiff(files []diffFile, opts DiffOptions) string {
+	var sb strings.Builder
+	var omitted []string
+
+	for _, f := range files {
+		if opts.Excludes(f.NewPath) {
+			omitted = append(omitted, f.NewPath)
+			continue
+		}
+
+		sb.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", f.OldPath, f.NewPath))
+		switch {
+		case f.Binary:
+			sb.WriteString("(binary file — contents omitted)")
+		case opts.MaxFileBytes > 0 && len(f.Patch) > opts.MaxFileBytes:
+			// Cut at the last line end within the cap, so no hunk line or
+			// rune is split.
+			if cut := strings.LastIndexByte(f.Patch[:opts.MaxFileBytes+1], '\n'); cut > 0 {
+				sb.WriteString(f.Patch[:cut] + "\n")
+			}
+			sb.WriteString(fmt.Sprintf("... (patch truncated, %d bytes total)", len(f.Patch)))
+		default:
+			sb.WriteString(f.Patch)
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(omitted) > 0 {
+		sb.WriteString(fmt.Sprintf("\n(%d files omitted by diff filters: %s)\n", len(omitted), strings.Join(omitted, ", ")))
+	}
+	return sb.String()
+}
+
+// isBinaryPatch detects the placeholder git emits instead of a textual patch.
+func isBinaryPatch(patch string) bool {
+	return strings.HasPrefix(patch, "Binary files ") || strings.Contains(patch, "\nBinary files ") ||
+		strings.HasPrefix(patch, "GIT binary patch")
+}
+
+func matchGlob(pattern, p string) bool {
+	switch {
+	case strings.HasSuffix(pattern, "/**"):
+		dir := strings.TrimSuffix(pattern, "/**")
+		if strings.HasPrefix(dir, "**/") {
+			dir = strings.TrimPrefix(dir, "**/")
+			return p == dir || strings.HasPrefix(p, dir+"/") || strings.Contains(p, "/"+dir+"/")
+		}
+		return strings.HasPrefix(p, dir+"/")
+
+	case strings.HasPrefix(pattern, "**/"):
+		rest := strings.TrimPrefix(pattern, "**/")
+		for {
+			if ok, _ := path.Match(rest, p); ok {
+				return true
+			}
+			i := strings.Index(p, "/")
+			if i < 0 {
+				return false
+			}
+			p = p[i+1:]
+		}
+
+	case !strings.Contains(pattern, "/"):
+		ok, _ := path.Match(pattern, path.Base(p))
+		return ok
+
+	default:
+		ok, _ := path.Match(pattern, p)
+		return ok
+	}
+}
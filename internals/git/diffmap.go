@@ -0,0 +1,187 @@
+package git
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxCommentShift is how far PostReview moves a comment to reach a line the
+// diff shows before it goes into the summary instead.
+const maxCommentShift = 5
+
+// diffHunk reads the old and new start lines and lengths from a hunk header;
+// a missing length means 1.
+var diffHunk = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// DiffLine is one line a diff shows, by its number in the old and the new
+// file. An added line has no Old and a removed one no New; a context line
+// has both.
+type DiffLine struct {
+	Old, New int
+}
+
+// FileDiff is the lines the diff of one file shows, in diff order.
+type FileDiff struct {
+	OldPath string
+	NewPath string
+	Lines   []DiffLine
+}
+
+// Find returns the diff line at line of the given side: "LEFT" for the old
+// file, anything else for the new one.
+func (f *FileDiff) Find(line int, side string) (DiffLine, bool) {
+	for _, l := range f.Lines {
+		if l.on(side) == line {
+			return l, true
+		}
+	}
+	return DiffLine{}, false
+}
+
+// Nearest returns the diff line on side closest to line, at most maxDist
+// lines away.
+func (f *FileDiff) Nearest(line int, side string, maxDist int) (DiffLine, bool) {
+	var nearest DiffLine
+	best := maxDist + 1
+	for _, l := range f.Lines {
+		n := l.on(side)
+		if n == 0 {
+			continue
+		}
+		if d := max(n-line, line-n); d < best {
+			nearest, best = l, d
+		}
+	}
+	return nearest, best <= maxDist
+}
+
+func (l DiffLine) on(side string) int {
+	if side == "LEFT" {
+		return l.Old
+	}
+	return l.New
+}
+
+// DiffMap maps each file of a diff, by its new path, to the lines the diff
+// shows: the only lines GitHub and GitLab accept inline comments on.
+type DiffMap map[string]*FileDiff
+
+// ParseDiff reads a unified diff with "--- old" and "+++ new" headers per
+// file, as GetPR and CompareCommits render it. Hunk lengths bound each hunk,
+// so a truncation note after a cut-off patch is not taken for a line.
+func ParseDiff(diff string) DiffMap {
+	m := make(DiffMap)
+	var file *FileDiff
+	oldPath := ""
+	oldN, newN, oldLeft, newLeft := 0, 0, 0, 0
+	for _, l := range strings.Split(diff, "\n") {
+		if oldLeft > 0 || newLeft > 0 {
+			switch {
+			case strings.HasPrefix(l, "+"):
+				file.Lines = append(file.Lines, DiffLine{New: newN})
+				newN++
+				newLeft--
+				continue
+			case strings.HasPrefix(l, "-"):
+				file.Lines = append(file.Lines, DiffLine{Old: oldN})
+				oldN++
+				oldLeft--
+				continue
+			case strings.HasPrefix(l, " "):
+				file.Lines = append(file.Lines, DiffLine{Old: oldN, New: newN})
+				oldN++
+				newN++
+				oldLeft--
+				newLeft--
+				continue
+			case strings.HasPrefix(l, `\`):
+				continue
+			}
+			oldLeft, newLeft = 0, 0 // the patch was cut short
+		}
+		switch {
+		case strings.HasPrefix(l, "--- "):
+			oldPath = diffPath(strings.TrimPrefix(l, "--- "))
+		case strings.HasPrefix(l, "+++ "):
+			newPath := diffPath(strings.TrimPrefix(l, "+++ "))
+			file = &FileDiff{OldPath: cmp.Or(oldPath, newPath), NewPath: cmp.Or(newPath, oldPath)}
+			m[file.NewPath] = file
+		case file != nil && strings.HasPrefix(l, "@@"):
+			if h := diffHunk.FindStringSubmatch(l); h != nil {
+				oldN, _ = strconv.Atoi(h[1])
+				newN, _ = strconv.Atoi(h[3])
+				oldLeft, newLeft = hunkLength(h[2]), hunkLength(h[4])
+			}
+		}
+	}
+	return m
+}
+
+func diffPath(p string) string {
+	p = strings.TrimSpace(p)
+	if p == "/dev/null" {
+		return ""
+	}
+	return p
+}
+
+func hunkLength(s string) int {
+	if s == "" {
+		return 1
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// File returns the diff of path, which may also be a renamed file's old
+// path, or nil when the diff does not touch it.
+func (m DiffMap) File(path string) *FileDiff {
+	if f, ok := m[path]; ok {
+		return f
+	}
+	for _, f := range m {
+		if f.OldPath == path {
+			return f
+		}
+	}
+	return nil
+}
+
+// Normalize moves c onto a line the diff shows. A comment on a line outside
+// the diff moves to the nearest one on the same side, at most maxDist lines
+// away, saying which line it meant; a context line is always addressed on
+// the new side, where both providers show it. It returns the diff line the
+// comment landed on, and false when the comment cannot be placed inline.
+func (m DiffMap) Normalize(c PRComment, maxDist int) (PRComment, DiffLine, bool) {
+	f := m.File(c.Path)
+	if f == nil {
+		return c, DiffLine{}, false
+	}
+	side := cmp.Or(c.Side, "RIGHT")
+	l, ok := f.Find(c.Line, side)
+	if !ok {
+		if l, ok = f.Nearest(c.Line, side, maxDist); !ok {
+			return c, DiffLine{}, false
+		}
+		c.Body = fmt.Sprintf("*(About line %d.)* %s", c.Line, c.Body)
+	}
+	c.Path = f.NewPath
+	if l.New > 0 {
+		c.Side, c.Line = "RIGHT", l.New
+	} else {
+		c.Side, c.Line = "LEFT", l.Old
+	}
+	return c, l, true
+}
+
+// unplacedComment is the summary line for a comment that could not be posted
+// inline, with why.
+func unplacedComment(c PRComment, reason string) string {
+	return fmt.Sprintf("- `%s:%d` (%s): %s", c.Path, c.Line, reason, strings.ReplaceAll(strings.TrimSpace(c.Text()), "\n", "\n  "))
+}
+
+// unplacedHeading leads the summary section listing those comments.
+const unplacedHeading = "#### Comments that could not be posted inline"
@@ -1,18 +1,189 @@
 package git
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
 
 type GitProvider interface {
 	CreateIssue(ctx context.Context, input IssueInput) (Issue, error)
 	GetIssue(ctx context.Context, number int) (Issue, error)
+	// ListIssuesByLabel returns the open issues carrying label.
+	ListIssuesByLabel(ctx context.Context, label string) ([]Issue, error)
+	UpdateIssueBody(ctx context.Context, number int, body string) error
+	UpdateIssueTitle(ctx context.Context, number int, title string) error
+	CloseIssue(ctx context.Context, number int) error
 	AddLabel(ctx context.Context, number int, label string) error
+	RemoveLabel(ctx context.Context, number int, label string) error
+	// AddPRLabel labels a PR, which GitLab keeps apart from issues.
+	AddPRLabel(ctx context.Context, prNumber int, label string) error
+	AddReaction(ctx context.Context, number int, emoji string) error
+	// AssignIssue adds username to the issue's assignees.
+	AssignIssue(ctx context.Context, number int, username string) error
 	OpenPR(ctx context.Context, input PRInput) (string, error)
 	GetPR(ctx context.Context, prNumber int) (PR, error)
+	// CompareCommits returns the diff from commit base to commit head,
+	// filtered and capped like PR.Diff. It returns ErrNotAncestor when head
+	// does not contain base, e.g. after a force push.
+	CompareCommits(ctx context.Context, base, head string) (string, error)
+	// FindOpenPR returns the number of the open PR whose head is branch, or 0
+	// if there is none.
+	FindOpenPR(ctx context.Context, branch string) (int, error)
 	PostReview(ctx context.Context, prNumber int, review Review) error
+	// DismissReviews withdraws the change requests this client posted on the
+	// PR, leaving message as the reason.
+	DismissReviews(ctx context.Context, prNumber int, message string) error
 	GetPRComments(ctx context.Context, prNumber int) ([]PRComment, error)
+	// GetReviewThread returns the inline comment thread id belongs to: a
+	// review comment ID on GitHub, a discussion ID on GitLab.
+	GetReviewThread(ctx context.Context, prNumber int, id string) (ReviewThread, error)
+	// ReplyToThread adds body to the end of thread.
+	ReplyToThread(ctx context.Context, prNumber int, thread ReviewThread, body string) error
+	// RetractThread withdraws the thread's first comment, keeping its text
+	// folded away, and replies with reason. On GitLab it also resolves the
+	// discussion.
+	RetractThread(ctx context.Context, prNumber int, thread ReviewThread, reason string) error
+	// ListReviewThreads returns the PR's inline comment threads.
+	ListReviewThreads(ctx context.Context, prNumber int) ([]ReviewThread, error)
+	// ResolveReviewThread marks thread resolved; resolving a resolved thread
+	// does nothing.
+	ResolveReviewThread(ctx context.Context, prNumber int, thread ReviewThread) error
+	// GetMarkedComment returns the body of the first top-level PR comment
+	// containing marker, or "" if there is none.
+	GetMarkedComment(ctx context.Context, prNumber int, marker string) (string, error)
+	// UpsertMarkedComment replaces the first top-level PR comment containing
+	// marker with body, or creates a new comment if none exists.
+	UpsertMarkedComment(ctx context.Context, prNumber int, marker, body string) error
+	// UpsertMarkedIssueComment does the same for a comment on an issue.
+	UpsertMarkedIssueComment(ctx context.Context, number int, marker, body string) error
+	// GetFileAtRef returns the contents of path at ref (a branch, tag, or
+	// commit SHA) without cloning the repository.
+	GetFileAtRef(ctx context.Context, path, ref string) (string, error)
+	// CommitFile writes content to path on branch in one commit through the
+	// host's file API, without cloning, creating branch from the default
+	// branch if it does not exist. It returns the default branch, which a PR
+	// for the change targets.
+	CommitFile(ctx context.Context, branch, path, content, message string) (string, error)
+	// UploadImage stores an image, e.g. a screenshot, where PR descriptions
+	// can embed it, and returns its URL.
+	UploadImage(ctx context.Context, name string, data []byte) (string, error)
+	// EnsureWebhook registers a webhook for url with the given events, or
+	// updates the existing one if a hook for url is already registered.
+	EnsureWebhook(ctx context.Context, url, secret string, events []WebhookEvent) error
+	// UserPermission returns username's access to the repository.
+	UserPermission(ctx context.Context, username string) (Permission, error)
+	// TokenPermission returns the access the client's own token has to the
+	// repository.
+	TokenPermission(ctx context.Context) (Permission, error)
+	// EnsureLabel creates the label, or updates its color and description if
+	// it already exists. color is a hex RGB value without the leading '#'.
+	EnsureLabel(ctx context.Context, name, color, description string) error
+	// StartPipeline returns the CI pipeline for commit sha on branch,
+	// starting one if the push did not. Its status is PipelineNone when the
+	// repository has no CI for the commit.
+	StartPipeline(ctx context.Context, branch, sha string) (Pipeline, error)
+	// GetPipeline refreshes p's status.
+	GetPipeline(ctx context.Context, p Pipeline) (Pipeline, error)
+	// FailedJobs returns the failed jobs of p with the tail of each log.
+	FailedJobs(ctx context.Context, p Pipeline) ([]JobLog, error)
 	RepoURL() string
 }
 
+// Permission is a user's access level on a repository, in increasing order.
+// GitLab roles map onto it as guest → read, reporter → triage,
+// developer → write, maintainer → maintain and owner → admin.
+type Permission int
+
+const (
+	PermissionNone Permission = iota
+	PermissionRead
+	PermissionTriage
+	PermissionWrite
+	PermissionMaintain
+	PermissionAdmin
+)
+
+var permissionNames = []string{"none", "read", "triage", "write", "maintain", "admin"}
+
+func (p Permission) String() string {
+	if p < 0 || int(p) >= len(permissionNames) {
+		return "unknown"
+	}
+	return permissionNames[p]
+}
+
+// ParsePermission parses a permission name such as "write".
+func ParsePermission(s string) (Permission, error) {
+	for i, name := range permissionNames {
+		if strings.EqualFold(strings.TrimSpace(s), name) {
+			return Permission(i), nil
+		}
+	}
+	return PermissionNone, fmt.Errorf("unknown permission %q — expected one of %s", s, strings.Join(permissionNames, ", "))
+}
+
+// WebhookEvent is a platform-neutral webhook event category. Providers map
+// each one to their own event names.
+type WebhookEvent string
+
+const (
+	WebhookEventIssues       WebhookEvent = "issues"
+	WebhookEventPullRequests WebhookEvent = "pull_requests"
+	WebhookEventComments     WebhookEvent = "comments"
+	WebhookEventPush         WebhookEvent = "push"
+)
+
+// Reaction names accepted by AddReaction. Both GitHub and GitLab use these
+// names for the corresponding emoji.
+const (
+	ReactionEyes   = "eyes"   // 👀 — work picked up
+	ReactionRocket = "rocket" // 🚀 — PR opened
+)
+
+// PipelineStatus is the platform-neutral state of a CI pipeline.
+type PipelineStatus string
+
+const (
+	PipelineNone     PipelineStatus = "none" // no CI ran for the commit
+	PipelinePending  PipelineStatus = "pending"
+	PipelineSuccess  PipelineStatus = "success"
+	PipelineFailed   PipelineStatus = "failed"
+	PipelineCanceled PipelineStatus = "canceled"
+)
+
+// Done reports whether the pipeline has finished.
+func (s PipelineStatus) Done() bool {
+	return s == PipelineSuccess || s == PipelineFailed || s == PipelineCanceled
+}
+
+// Pipeline is the CI run for one commit. On GitHub it stands for every
+// workflow run of the commit.
+type Pipeline struct {
+	ID     int64 // GitLab pipeline ID; 0 on GitHub
+	SHA    string
+	URL    string
+	Status PipelineStatus
+}
+
+// JobLog is a failed CI job and the end of its log.
+type JobLog struct {
+	Name string
+	URL  string
+	Log  string
+}
+
+// maxJobLogBytes bounds the log tail kept for each failed job.
+const maxJobLogBytes = 8000
+
+func logTail(log string) string {
+	if len(log) <= maxJobLogBytes {
+		return log
+	}
+	return "…" + log[len(log)-maxJobLogBytes:]
+}
+
 type PRInput struct {
 	Title       string
 	Body        string
@@ -29,10 +200,23 @@ type IssueInput struct {
 }
 
 type Issue struct {
-	Number int
-	Title  string
-	Body   string
-	URL    string
+	Number    int
+	Title     string
+	Body      string
+	URL       string
+	State     string // "open" or "closed"
+	Labels    []string
+	UpdatedAt time.Time
+}
+
+// HasLabel reports whether the issue carries label.
+func (i Issue) HasLabel(label string) bool {
+	for _, l := range i.Labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
 }
 
 type PR struct {
@@ -42,15 +226,63 @@ type PR struct {
 	URL         string
 	Branch      string
 	BaseBranch  string
+	HeadSHA     string // commit the PR head points at
 	Diff        string // unified diff of all changes
 	IssueURL    string // the originating issue URL parsed from the PR body
+	Merged      bool
+	// MergeSHA is the commit that brought the PR into its base branch: the
+	// merge or squash commit. Empty until merged, or when a fast-forward
+	// merge left no single commit.
+	MergeSHA string
 }
 
 type Review struct {
-	// Verdict is one of "approve", "request_changes", or "comment".
+	// Verdict is one of "approve", "request_changes", "comment" or
+	// "escalate".
 	Verdict  string
 	Summary  string // overall review comment
 	Comments []PRComment
+	// Reasons says why an "escalate" review needs a human. Providers do not
+	// post it.
+	Reasons []string
+	// Resolved holds the IDs of comments from earlier rounds the review
+	// finds addressed. Providers do not post it.
+	Resolved []int
+	// Criteria assesses the issue's acceptance criteria one by one.
+	// Providers do not post it.
+	Criteria []CriterionCheck
+}
+
+// CriterionCheck is a review's assessment of one acceptance criterion.
+type CriterionCheck struct {
+	Index    int    `json:"index"`  // 1-based, in the issue's order
+	Status   string `json:"status"` // "met", "unmet" or "unclear"
+	Evidence string `json:"evidence"`
+}
+
+// ReviewThread is an inline review comment and the replies to it.
+type ReviewThread struct {
+	ID       string // the first comment's ID on GitHub; the discussion ID on GitLab
+	Path     string
+	Line     int
+	DiffHunk string // the diff around the comment; GitHub only
+	Resolved bool   // set by ListReviewThreads
+	Comments []ThreadComment
+
+	nodeID string // GitHub's GraphQL ID of the thread, when known
+}
+
+// ThreadComment is one comment in a ReviewThread.
+type ThreadComment struct {
+	ID     string
+	Author string
+	Body   string
+	Mine   bool // posted with this client's token
+}
+
+// retractedBody is what a retracted comment is edited to.
+func retractedBody(original, reason string) string {
+	return "**Retracted:** " + reason + "\n\n<details><summary>Original comment</summary>\n\n" + original + "\n\n</details>"
 }
 
 type PRComment struct {
@@ -59,6 +291,24 @@ type PRComment struct {
 	Body string // comment text
 	// Side is "RIGHT" (new file) or "LEFT" (old file). Defaults to RIGHT.
 	Side string
+	// Severity is "blocker", "major", "minor" or "nit", and Category "bug",
+	// "security", "tests" or "style". Both are empty on comments read back
+	// from the provider.
+	Severity string
+	Category string
+}
+
+// Text is the comment as posted: the body, led by its severity and category
+// when it has them.
+func (c PRComment) Text() string {
+	if c.Severity == "" {
+		return c.Body
+	}
+	label := "**" + c.Severity + "**"
+	if c.Category != "" {
+		label += " · " + c.Category
+	}
+	return label + "\n\n" + c.Body
 }
 
 type Platform int
@@ -2,8 +2,13 @@ package git
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/go-github/v60/github"
 	"golang.org/x/oauth2"
@@ -12,6 +17,7 @@ import (
 type GitHubProvider struct {
 	gh   *github.Client
 	info RepoInfo
+	diff DiffOptions
 }
 
 func NewGitHubProvider(ctx context.Context, token string, info RepoInfo) (*GitHubProvider, error) {
@@ -19,6 +25,7 @@ func NewGitHubProvider(ctx context.Context, token string, info RepoInfo) (*GitHu
 	return &GitHubProvider{
 		gh:   github.NewClient(oauth2.NewClient(ctx, ts)),
 		info: info,
+		diff: DefaultDiffOptions(),
 	}, nil
 }
 
@@ -46,12 +53,78 @@ func (t *GitHubProvider) GetIssue(ctx context.Context, number int) (Issue, error
 	if err != nil {
 		return Issue{}, fmt.Errorf("github get issue: %w", err)
 	}
+	return githubIssue(issue), nil
+}
+
+func githubIssue(issue *github.Issue) Issue {
+	labels := make([]string, 0, len(issue.Labels))
+	for _, l := range issue.Labels {
+		labels = append(labels, l.GetName())
+	}
 	return Issue{
-		Number: issue.GetNumber(),
-		Title:  issue.GetTitle(),
-		Body:   issue.GetBody(),
-		URL:    issue.GetHTMLURL(),
-	}, nil
+		Number:    issue.GetNumber(),
+		Title:     issue.GetTitle(),
+		Body:      issue.GetBody(),
+		URL:       issue.GetHTMLURL(),
+		State:     issue.GetState(),
+		Labels:    labels,
+		UpdatedAt: issue.GetUpdatedAt().Time,
+	}
+}
+
+func (t *GitHubProvider) ListIssuesByLabel(ctx context.Context, label string) ([]Issue, error) {
+	opts := &github.IssueListByRepoOptions{
+		State:       "open",
+		Labels:      []string{label},
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	var out []Issue
+	for {
+		issues, resp, err := t.gh.Issues.ListByRepo(ctx, t.info.Owner, t.info.Repo, opts)
+		if err != nil {
+			return nil, fmt.Errorf("github list issues: %w", err)
+		}
+		for _, issue := range issues {
+			if issue.IsPullRequest() {
+				continue // the issues API also returns PRs
+			}
+			out = append(out, githubIssue(issue))
+		}
+		if resp.NextPage == 0 {
+			return out, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+func (t *GitHubProvider) UpdateIssueBody(ctx context.Context, number int, body string) error {
+	_, _, err := t.gh.Issues.Edit(ctx, t.info.Owner, t.info.Repo, number, &github.IssueRequest{
+		Body: github.String(body),
+	})
+	if err != nil {
+		return fmt.Errorf("github update issue: %w", err)
+	}
+	return nil
+}
+
+func (t *GitHubProvider) UpdateIssueTitle(ctx context.Context, number int, title string) error {
+	_, _, err := t.gh.Issues.Edit(ctx, t.info.Owner, t.info.Repo, number, &github.IssueRequest{
+		Title: github.String(title),
+	})
+	if err != nil {
+		return fmt.Errorf("github update issue title: %w", err)
+	}
+	return nil
+}
+
+func (t *GitHubProvider) CloseIssue(ctx context.Context, number int) error {
+	_, _, err := t.gh.Issues.Edit(ctx, t.info.Owner, t.info.Repo, number, &github.IssueRequest{
+		State: github.String("closed"),
+	})
+	if err != nil {
+		return fmt.Errorf("github close issue: %w", err)
+	}
+	return nil
 }
 
 func (t *GitHubProvider) AddLabel(ctx context.Context, number int, label string) error {
@@ -62,6 +135,98 @@ func (t *GitHubProvider) AddLabel(ctx context.Context, number int, label string)
 	return nil
 }
 
+// AddPRLabel is AddLabel: GitHub PRs are issues.
+func (t *GitHubProvider) AddPRLabel(ctx context.Context, prNumber int, label string) error {
+	return t.AddLabel(ctx, prNumber, label)
+}
+
+// AddReaction reacts to an issue or PR. GitHub treats PRs as issues, so either
+// number works here.
+func (t *GitHubProvider) AddReaction(ctx context.Context, number int, emoji string) error {
+	_, _, err := t.gh.Reactions.CreateIssueReaction(ctx, t.info.Owner, t.info.Repo, number, emoji)
+	if err != nil {
+		return fmt.Errorf("github add reaction: %w", err)
+	}
+	return nil
+}
+
+func (t *GitHubProvider) AssignIssue(ctx context.Context, number int, username string) error {
+	_, _, err := t.gh.Issues.AddAssignees(ctx, t.info.Owner, t.info.Repo, number, []string{username})
+	if err != nil {
+		return fmt.Errorf("github assign issue: %w", err)
+	}
+	return nil
+}
+
+func (t *GitHubProvider) UserPermission(ctx context.Context, username string) (Permission, error) {
+	level, _, err := t.gh.Repositories.GetPermissionLevel(ctx, t.info.Owner, t.info.Repo, username)
+	if err != nil {
+		return PermissionNone, fmt.Errorf("github get permission: %w", err)
+	}
+	// The legacy permission folds triage into read and maintain into write;
+	// the role name keeps them apart.
+	switch level.GetUser().GetRoleName() {
+	case "triage":
+		return PermissionTriage, nil
+	case "maintain":
+		return PermissionMaintain, nil
+	}
+	p, err := ParsePermission(level.GetPermission())
+	if err != nil {
+		return PermissionNone, nil
+	}
+	return p, nil
+}
+
+func (t *GitHubProvider) TokenPermission(ctx context.Context) (Permission, error) {
+	repo, _, err := t.gh.Repositories.Get(ctx, t.info.Owner, t.info.Repo)
+	if err != nil {
+		return PermissionNone, fmt.Errorf("github get repo: %w", err)
+	}
+	perms := repo.GetPermissions()
+	for p := PermissionAdmin; p > PermissionNone; p-- {
+		if perms[githubPermissionKeys[p]] {
+			return p, nil
+		}
+	}
+	return PermissionNone, nil
+}
+
+// githubPermissionKeys are the keys of a repository's permissions map.
+var githubPermissionKeys = map[Permission]string{
+	PermissionRead:     "pull",
+	PermissionTriage:   "triage",
+	PermissionWrite:    "push",
+	PermissionMaintain: "maintain",
+	PermissionAdmin:    "admin",
+}
+
+func (t *GitHubProvider) EnsureLabel(ctx context.Context, name, color, description string) error {
+	label := &github.Label{Name: github.String(name), Color: github.String(color), Description: github.String(description)}
+	_, resp, err := t.gh.Issues.GetLabel(ctx, t.info.Owner, t.info.Repo, name)
+	if err != nil {
+		if resp == nil || resp.StatusCode != http.StatusNotFound {
+			return fmt.Errorf("github get label: %w", err)
+		}
+		if _, _, err := t.gh.Issues.CreateLabel(ctx, t.info.Owner, t.info.Repo, label); err != nil {
+			return fmt.Errorf("github create label: %w", err)
+		}
+		return nil
+	}
+	if _, _, err := t.gh.Issues.EditLabel(ctx, t.info.Owner, t.info.Repo, name, label); err != nil {
+		return fmt.Errorf("github edit label: %w", err)
+	}
+	return nil
+}
+
+func (t *GitHubProvider) RemoveLabel(ctx context.Context, number int, label string) error {
+	_, err := t.gh.Issues.RemoveLabelForIssue(ctx, t.info.Owner, t.info.Repo, number, label)
+	if err != nil {
+		return fmt.Errorf("github remove label: %w", err)
+	}
+	return nil
+}
+
 func (t *GitHubProvider) OpenPR(ctx context.Context, input PRInput) (string, error) {
 	pr, _, err := t.gh.PullRequests.Create(ctx, t.info.Owner, t.info.Repo, &github.NewPullRequest{
 		Title: github.String(input.Title),
@@ -76,6 +241,20 @@ func (t *GitHubProvider) OpenPR(ctx context.Context, input PRInput) (string, err
 	return pr.GetHTMLURL(), nil
 }
 
+func (t *GitHubProvider) FindOpenPR(ctx context.Context, branch string) (int, error) {
+	prs, _, err := t.gh.PullRequests.List(ctx, t.info.Owner, t.info.Repo, &github.PullRequestListOptions{
+		State: "open",
+		Head:  t.info.Owner + ":" + branch,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("github find PR: %w", err)
+	}
+	if len(prs) == 0 {
+		return 0, nil
+	}
+	return prs[0].GetNumber(), nil
+}
+
 func (t *GitHubProvider) GetPRComments(ctx context.Context, prNumber int) ([]PRComment, error) {
 	comments, _, err := t.gh.PullRequests.ListComments(ctx, t.info.Owner, t.info.Repo, prNumber, nil)
 	if err != nil {
@@ -92,26 +271,97 @@ func (t *GitHubProvider) GetPRComments(ctx context.Context, prNumber int) ([]PRC
 	return out, nil
 }
 
+func (t *GitHubProvider) GetMarkedComment(ctx context.Context, prNumber int, marker string) (string, error) {
+	c, err := t.findMarkedComment(ctx, prNumber, marker)
+	if err != nil || c == nil {
+		return "", err
+	}
+	return c.GetBody(), nil
+}
+
+func (t *GitHubProvider) UpsertMarkedComment(ctx context.Context, prNumber int, marker, body string) error {
+	c, err := t.findMarkedComment(ctx, prNumber, marker)
+	if err != nil {
+		return err
+	}
+	if c == nil {
+		_, _, err = t.gh.Issues.CreateComment(ctx, t.info.Owner, t.info.Repo, prNumber, &github.IssueComment{
+			Body: github.String(body),
+		})
+		if err != nil {
+			return fmt.Errorf("github create comment: %w", err)
+		}
+		return nil
+	}
+	_, _, err = t.gh.Issues.EditComment(ctx, t.info.Owner, t.info.Repo, c.GetID(), &github.IssueComment{
+		Body: github.String(body),
+	})
+	if err != nil {
+		return fmt.Errorf("github edit comment: %w", err)
+	}
+	return nil
+}
+
+// UpsertMarkedIssueComment shares the implementation with PRs, since GitHub
+// serves PR conversation comments through the issues API.
+func (t *GitHubProvider) UpsertMarkedIssueComment(ctx context.Context, number int, marker, body string) error {
+	return t.UpsertMarkedComment(ctx, number, marker, body)
+}
+
+func (t *GitHubProvider) findMarkedComment(ctx context.Context, prNumber int, marker string) (*github.IssueComment, error) {
+	opts := &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		comments, resp, err := t.gh.Issues.ListComments(ctx, t.info.Owner, t.info.Repo, prNumber, opts)
+		if err != nil {
+			return nil, fmt.Errorf("github list comments: %w", err)
+		}
+		for _, c := range comments {
+			if strings.Contains(c.GetBody(), marker) {
+				return c, nil
+			}
+		}
+		if resp.NextPage == 0 {
+			return nil, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// PostReview posts the review with its inline comments. GitHub rejects the
+// whole review when one comment is on a line outside the diff, so each is
+// first moved onto a line of the PR's diff; one that cannot be placed is
+// appended to the summary instead.
 func (t *GitHubProvider) PostReview(ctx context.Context, prNumber int, review Review) error {
 	event := verdictToGitHubEvent(review.Verdict)
 
+	files, err := t.prDiffFiles(ctx, prNumber)
+	if err != nil {
+		return err
+	}
+	diff := ParseDiff(renderDiff(files, DiffOptions{}))
 	comments := make([]*github.DraftReviewComment, 0, len(review.Comments))
+	var failed []string
 	for _, c := range review.Comments {
-		side := c.Side
-		if side == "" {
-			side = "RIGHT"
+		placed, _, ok := diff.Normalize(c, maxCommentShift)
+		if !ok {
+			failed = append(failed, unplacedComment(c, "outside the diff"))
+			continue
 		}
 		comments = append(comments, &github.DraftReviewComment{
-			Path: github.String(c.Path),
-			Line: github.Int(c.Line),
-			Body: github.String(c.Body),
-			Side: github.String(side),
+			Path: github.String(placed.Path),
+			Line: github.Int(placed.Line),
+			Body: github.String(placed.Text()),
+			Side: github.String(placed.Side),
 		})
 	}
 
-	_, _, err := t.gh.PullRequests.CreateReview(ctx, t.info.Owner, t.info.Repo, prNumber, &github.PullRequestReviewRequest{
+	summary := review.Summary
+	if len(failed) > 0 {
+		summary += "\n\n" + unplacedHeading + "\n\n" + strings.Join(failed, "\n")
+	}
+	_, _, err = t.gh.PullRequests.CreateReview(ctx, t.info.Owner, t.info.Repo, prNumber, &github.PullRequestReviewRequest{
 		Event:    github.String(event),
-		Body:     github.String(review.Summary),
+		Body:     github.String(summary),
 		Comments: comments,
 	})
 	if err != nil {
@@ -120,20 +370,92 @@ func (t *GitHubProvider) PostReview(ctx context.Context, prNumber int, review Re
 	return nil
 }
 
+// DismissReviews dismisses every CHANGES_REQUESTED review by the
+// authenticated user.
+func (t *GitHubProvider) DismissReviews(ctx context.Context, prNumber int, message string) error {
+	me, _, err := t.gh.Users.Get(ctx, "")
+	if err != nil {
+		return fmt.Errorf("github get user: %w", err)
+	}
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		reviews, resp, err := t.gh.PullRequests.ListReviews(ctx, t.info.Owner, t.info.Repo, prNumber, opts)
+		if err != nil {
+			return fmt.Errorf("github list reviews: %w", err)
+		}
+		for _, r := range reviews {
+			if r.GetState() != "CHANGES_REQUESTED" || r.GetUser().GetLogin() != me.GetLogin() {
+				continue
+			}
+			_, _, err := t.gh.PullRequests.DismissReview(ctx, t.info.Owner, t.info.Repo, prNumber, r.GetID(), &github.PullRequestReviewDismissalRequest{
+				Message: github.String(message),
+			})
+			if err != nil {
+				return fmt.Errorf("github dismiss review: %w", err)
+			}
+		}
+		if resp.NextPage == 0 {
+			return nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
 func (t *GitHubProvider) getPRDiff(ctx context.Context, prNumber int) (string, error) {
-	opts := &github.ListOptions{}
-	files, _, err := t.gh.PullRequests.ListFiles(ctx, t.info.Owner, t.info.Repo, prNumber, opts)
+	files, err := t.prDiffFiles(ctx, prNumber)
 	if err != nil {
-		return "", fmt.Errorf("github list PR files: %w", err)
+		return "", err
+	}
+	return renderDiff(files, t.diff), nil
+}
+
+func (t *GitHubProvider) prDiffFiles(ctx context.Context, prNumber int) ([]diffFile, error) {
+	opts := &github.ListOptions{PerPage: 100}
+	var files []diffFile
+	for {
+		page, resp, err := t.gh.PullRequests.ListFiles(ctx, t.info.Owner, t.info.Repo, prNumber, opts)
+		if err != nil {
+			return nil, fmt.Errorf("github list PR files: %w", err)
+		}
+		files = append(files, githubDiffFiles(page)...)
+		if resp.NextPage == 0 {
+			return files, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+func githubDiffFiles(page []*github.CommitFile) []diffFile {
+	files := make([]diffFile, 0, len(page))
+	for _, f := range page {
+		oldPath := f.GetPreviousFilename()
+		if oldPath == "" {
+			oldPath = f.GetFilename()
+		}
+		// GitHub omits the patch for binary files; a textual change always
+		// has one unless it is a pure rename.
+		binary := f.GetPatch() == "" && f.GetChanges() == 0 && f.GetStatus() != "renamed"
+		files = append(files, diffFile{
+			OldPath: oldPath,
+			NewPath: f.GetFilename(),
+			Patch:   f.GetPatch(),
+			Binary:  binary || isBinaryPatch(f.GetPatch()),
+		})
 	}
+	return files
+}
 
-	var sb strings.Builder
-	for _, f := range files {
-		sb.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", f.GetFilename(), f.GetFilename()))
-		sb.WriteString(f.GetPatch())
-		sb.WriteString("\n")
+func (t *GitHubProvider) CompareCommits(ctx context.Context, base, head string) (string, error) {
+	cmp, _, err := t.gh.Repositories.CompareCommits(ctx, t.info.Owner, t.info.Repo, base, head, nil)
+	if err != nil {
+		return "", fmt.Errorf("github compare %s...%s: %w", base, head, err)
 	}
-	return sb.String(), nil
+	// "diverged" or "behind": the diff would be against a merge base, not
+	// base itself.
+	if s := cmp.GetStatus(); s != "ahead" && s != "identical" {
+		return "", ErrNotAncestor
+	}
+	return renderDiff(githubDiffFiles(cmp.Files), t.diff), nil
 }
 
 func (t *GitHubProvider) GetPR(ctx context.Context, prNumber int) (PR, error) {
@@ -142,7 +464,7 @@ func (t *GitHubProvider) GetPR(ctx context.Context, prNumber int) (PR, error) {
 		return PR{}, fmt.Errorf("github get PR: %w", err)
 	}
 
-	// Fetch the unified diff by requesting the PR with the diff media type.
+	// Build the unified diff from the PR's files, filtered by t.diff.
 	diff, err := t.getPRDiff(ctx, prNumber)
 	if err != nil {
 		return PR{}, err
@@ -155,11 +477,289 @@ func (t *GitHubProvider) GetPR(ctx context.Context, prNumber int) (PR, error) {
 		URL:         pr.GetHTMLURL(),
 		Branch:      pr.GetHead().GetRef(),
 		BaseBranch:  pr.GetBase().GetRef(),
+		HeadSHA:     pr.GetHead().GetSHA(),
 		Diff:        diff,
-		IssueURL:    extractIssueURL(pr.GetBody()),
+		IssueURL:    ExtractIssueURL(pr.GetBody()),
+		Merged:      pr.GetMerged(),
+		MergeSHA:    pr.GetMergeCommitSHA(),
 	}, nil
 }
 
+func (t *GitHubProvider) GetFileAtRef(ctx context.Context, path, ref string) (string, error) {
+	file, _, _, err := t.gh.Repositories.GetContents(ctx, t.info.Owner, t.info.Repo, path, &github.RepositoryContentGetOptions{
+		Ref: ref,
+	})
+	if err != nil {
+		return "", fmt.Errorf("github get file %s@%s: %w", path, ref, err)
+	}
+	if file == nil {
+		return "", fmt.Errorf("github get file %s@%s: path is a directory", path, ref)
+	}
+	content, err := file.GetContent()
+	if err != nil {
+		return "", fmt.Errorf("github decode file %s@%s: %w", path, ref, err)
+	}
+	return content, nil
+}
+
+func (t *GitHubProvider) CommitFile(ctx context.Context, branch, path, content, message string) (string, error) {
+	repo, _, err := t.gh.Repositories.Get(ctx, t.info.Owner, t.info.Repo)
+	if err != nil {
+		return "", fmt.Errorf("github get repo: %w", err)
+	}
+	base := repo.GetDefaultBranch()
+	if _, resp, err := t.gh.Git.GetRef(ctx, t.info.Owner, t.info.Repo, "heads/"+branch); err != nil {
+		if resp == nil || resp.StatusCode != http.StatusNotFound {
+			return "", fmt.Errorf("github get branch %s: %w", branch, err)
+		}
+		head, _, err := t.gh.Git.GetRef(ctx, t.info.Owner, t.info.Repo, "heads/"+base)
+		if err != nil {
+			return "", fmt.Errorf("github get default branch: %w", err)
+		}
+		_, _, err = t.gh.Git.CreateRef(ctx, t.info.Owner, t.info.Repo, &github.Reference{
+			Ref:    github.String("refs/heads/" + branch),
+			Object: &github.GitObject{SHA: head.Object.SHA},
+		})
+		if err != nil {
+			return "", fmt.Errorf("github create branch %s: %w", branch, err)
+		}
+	}
+
+	opts := &github.RepositoryContentFileOptions{
+		Message: github.String(message),
+		Content: []byte(content),
+		Branch:  github.String(branch),
+	}
+	existing, _, resp, err := t.gh.Repositories.GetContents(ctx, t.info.Owner, t.info.Repo, path, &github.RepositoryContentGetOptions{Ref: branch})
+	switch {
+	case err == nil && existing != nil:
+		opts.SHA = existing.SHA // updating a file needs the blob it replaces
+		_, _, err = t.gh.Repositories.UpdateFile(ctx, t.info.Owner, t.info.Repo, path, opts)
+	case resp != nil && resp.StatusCode == http.StatusNotFound:
+		_, _, err = t.gh.Repositories.CreateFile(ctx, t.info.Owner, t.info.Repo, path, opts)
+	case err == nil:
+		return "", fmt.Errorf("github commit %s: path is a directory", path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("github commit %s: %w", path, err)
+	}
+	return base, nil
+}
+
+// assetsBranch holds files uploaded by UploadImage, since GitHub has no API
+// for attaching images to PRs.
+const assetsBranch = "droid-assets"
+
+func (t *GitHubProvider) UploadImage(ctx context.Context, name string, data []byte) (string, error) {
+	if err := t.ensureAssetsBranch(ctx); err != nil {
+		return "", err
+	}
+	path := fmt.Sprintf("screenshots/%d-%s", time.Now().UnixNano(), name)
+	res, _, err := t.gh.Repositories.CreateFile(ctx, t.info.Owner, t.info.Repo, path, &github.RepositoryContentFileOptions{
+		Message: github.String("Add " + name),
+		Content: data,
+		Branch:  github.String(assetsBranch),
+	})
+	if err != nil {
+		return "", fmt.Errorf("github upload %s: %w", name, err)
+	}
+	return res.Content.GetHTMLURL() + "?raw=true", nil
+}
+
+// ensureAssetsBranch creates the assets branch from the default branch if it
+// does not exist yet.
+func (t *GitHubProvider) ensureAssetsBranch(ctx context.Context) error {
+	_, resp, err := t.gh.Git.GetRef(ctx, t.info.Owner, t.info.Repo, "heads/"+assetsBranch)
+	if err == nil {
+		return nil
+	}
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("github get %s branch: %w", assetsBranch, err)
+	}
+	repo, _, err := t.gh.Repositories.Get(ctx, t.info.Owner, t.info.Repo)
+	if err != nil {
+		return fmt.Errorf("github get repo: %w", err)
+	}
+	base, _, err := t.gh.Git.GetRef(ctx, t.info.Owner, t.info.Repo, "heads/"+repo.GetDefaultBranch())
+	if err != nil {
+		return fmt.Errorf("github get default branch: %w", err)
+	}
+	_, _, err = t.gh.Git.CreateRef(ctx, t.info.Owner, t.info.Repo, &github.Reference{
+		Ref:    github.String("refs/heads/" + assetsBranch),
+		Object: &github.GitObject{SHA: base.Object.SHA},
+	})
+	if err != nil {
+		return fmt.Errorf("github create %s branch: %w", assetsBranch, err)
+	}
+	return nil
+}
+
+func (t *GitHubProvider) EnsureWebhook(ctx context.Context, url, secret string, events []WebhookEvent) error {
+	hook := &github.Hook{
+		Config: &github.HookConfig{
+			URL:         github.String(url),
+			ContentType: github.String("json"),
+			Secret:      github.String(secret),
+		},
+		Events: githubHookEvents(events),
+		Active: github.Bool(true),
+	}
+
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		hooks, resp, err := t.gh.Repositories.ListHooks(ctx, t.info.Owner, t.info.Repo, opts)
+		if err != nil {
+			return fmt.Errorf("github list hooks: %w", err)
+		}
+		for _, h := range hooks {
+			if h.GetConfig().GetURL() == url {
+				if _, _, err := t.gh.Repositories.EditHook(ctx, t.info.Owner, t.info.Repo, h.GetID(), hook); err != nil {
+					return fmt.Errorf("github edit hook: %w", err)
+				}
+				return nil
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	if _, _, err := t.gh.Repositories.CreateHook(ctx, t.info.Owner, t.info.Repo, hook); err != nil {
+		return fmt.Errorf("github create hook: %w", err)
+	}
+	return nil
+}
+
+// StartPipeline implements GitProvider. Pushes start GitHub Actions
+// workflows by themselves, so this only looks the runs up.
+func (t *GitHubProvider) StartPipeline(ctx context.Context, branch, sha string) (Pipeline, error) {
+	return t.GetPipeline(ctx, Pipeline{SHA: sha})
+}
+
+func (t *GitHubProvider) GetPipeline(ctx context.Context, p Pipeline) (Pipeline, error) {
+	runs, err := t.workflowRuns(ctx, p.SHA)
+	if err != nil {
+		return p, err
+	}
+	p.URL = strings.TrimSuffix(t.info.RawURL, "/") + "/commit/" + p.SHA + "/checks"
+	if len(runs) == 1 {
+		p.URL = runs[0].GetHTMLURL()
+	}
+	p.Status = PipelineNone
+	if len(runs) > 0 {
+		p.Status = PipelineSuccess
+	}
+	for _, run := range runs {
+		switch s := githubRunStatus(run); {
+		case s == PipelinePending:
+			p.Status = PipelinePending
+		case s == PipelineFailed && p.Status != PipelinePending:
+			p.Status = PipelineFailed
+		case s == PipelineCanceled && p.Status == PipelineSuccess:
+			p.Status = PipelineCanceled
+		}
+	}
+	return p, nil
+}
+
+func (t *GitHubProvider) workflowRuns(ctx context.Context, sha string) ([]*github.WorkflowRun, error) {
+	runs, _, err := t.gh.Actions.ListRepositoryWorkflowRuns(ctx, t.info.Owner, t.info.Repo, &github.ListWorkflowRunsOptions{
+		HeadSHA:     sha,
+		ListOptions: github.ListOptions{PerPage: 100},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("github list workflow runs: %w", err)
+	}
+	return runs.WorkflowRuns, nil
+}
+
+func githubRunStatus(run *github.WorkflowRun) PipelineStatus {
+	if run.GetStatus() != "completed" {
+		return PipelinePending
+	}
+	switch run.GetConclusion() {
+	case "success", "neutral", "skipped":
+		return PipelineSuccess
+	case "cancelled":
+		return PipelineCanceled
+	default:
+		return PipelineFailed
+	}
+}
+
+func (t *GitHubProvider) FailedJobs(ctx context.Context, p Pipeline) ([]JobLog, error) {
+	runs, err := t.workflowRuns(ctx, p.SHA)
+	if err != nil {
+		return nil, err
+	}
+	var out []JobLog
+	for _, run := range runs {
+		if githubRunStatus(run) != PipelineFailed {
+			continue
+		}
+		jobs, _, err := t.gh.Actions.ListWorkflowJobs(ctx, t.info.Owner, t.info.Repo, run.GetID(), &github.ListWorkflowJobsOptions{Filter: "latest"})
+		if err != nil {
+			return nil, fmt.Errorf("github list workflow jobs: %w", err)
+		}
+		for _, job := range jobs.Jobs {
+			if c := job.GetConclusion(); c != "failure" && c != "timed_out" {
+				continue
+			}
+			log, err := t.jobLog(ctx, job.GetID())
+			if err != nil {
+				log = "(log unavailable: " + err.Error() + ")"
+			}
+			out = append(out, JobLog{Name: run.GetName() + " / " + job.GetName(), URL: job.GetHTMLURL(), Log: logTail(log)})
+		}
+	}
+	return out, nil
+}
+
+// jobLog downloads a job's log. GitHub redirects to a signed URL, which is
+// fetched without the API token.
+func (t *GitHubProvider) jobLog(ctx context.Context, jobID int64) (string, error) {
+	u, _, err := t.gh.Actions.GetWorkflowJobLogs(ctx, t.info.Owner, t.info.Repo, jobID, 1)
+	if err != nil {
+		return "", fmt.Errorf("github get job logs: %w", err)
+	}
+	client := &http.Client{Timeout: time.Minute}
+	if tr, ok := t.gh.Client().Transport.(*oauth2.Transport); ok {
+		client.Transport = tr.Base
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("download job log: %s", resp.Status)
+	}
+	b, err := io.ReadAll(resp.Body)
+	return string(b), err
+}
+
+func githubHookEvents(events []WebhookEvent) []string {
+	out := make([]string, 0, len(events))
+	for _, e := range events {
+		switch e {
+		case WebhookEventIssues:
+			out = append(out, "issues")
+		case WebhookEventPullRequests:
+			out = append(out, "pull_request")
+		case WebhookEventComments:
+			out = append(out, "issue_comment", "pull_request_review_comment")
+		case WebhookEventPush:
+			out = append(out, "push")
+		}
+	}
+	return out
+}
+
 func verdictToGitHubEvent(verdict string) string {
 	switch verdict {
 	case "approve":
@@ -171,7 +771,9 @@ func verdictToGitHubEvent(verdict string) string {
 	}
 }
 
-func extractIssueURL(body string) string {
+// ExtractIssueURL returns the issue URL from the "Closes <url>" line the
+// executor writes into every PR body, or "" if there is none.
+func ExtractIssueURL(body string) string {
 	for _, line := range strings.Split(body, "\n") {
 		line = strings.TrimSpace(line)
 		if strings.HasPrefix(line, "Closes ") {
@@ -180,3 +782,209 @@ func extractIssueURL(body string) string {
 	}
 	return ""
 }
+
+func (t *GitHubProvider) GetReviewThread(ctx context.Context, prNumber int, id string) (ReviewThread, error) {
+	commentID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return ReviewThread{}, fmt.Errorf("github review comment ID %q: %w", id, err)
+	}
+	c, _, err := t.gh.PullRequests.GetComment(ctx, t.info.Owner, t.info.Repo, commentID)
+	if err != nil {
+		return ReviewThread{}, fmt.Errorf("github get review comment: %w", err)
+	}
+	root := c.GetID()
+	if c.GetInReplyTo() != 0 {
+		root = c.GetInReplyTo()
+	}
+	me, _, err := t.gh.Users.Get(ctx, "")
+	if err != nil {
+		return ReviewThread{}, fmt.Errorf("github get user: %w", err)
+	}
+
+	thread := ReviewThread{ID: strconv.FormatInt(root, 10)}
+	opts := &github.PullRequestListCommentsOptions{Sort: "created", Direction: "asc", ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		page, resp, err := t.gh.PullRequests.ListComments(ctx, t.info.Owner, t.info.Repo, prNumber, opts)
+		if err != nil {
+			return ReviewThread{}, fmt.Errorf("github list review comments: %w", err)
+		}
+		for _, pc := range page {
+			if pc.GetID() != root && pc.GetInReplyTo() != root {
+				continue
+			}
+			if pc.GetID() == root {
+				thread.Path, thread.Line, thread.DiffHunk = pc.GetPath(), pc.GetLine(), pc.GetDiffHunk()
+			}
+			thread.Comments = append(thread.Comments, ThreadComment{
+				ID:     strconv.FormatInt(pc.GetID(), 10),
+				Author: pc.GetUser().GetLogin(),
+				Body:   pc.GetBody(),
+				Mine:   pc.GetUser().GetLogin() == me.GetLogin(),
+			})
+		}
+		if resp.NextPage == 0 {
+			return thread, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+func (t *GitHubProvider) ReplyToThread(ctx context.Context, prNumber int, thread ReviewThread, body string) error {
+	root, err := strconv.ParseInt(thread.ID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("github review comment ID %q: %w", thread.ID, err)
+	}
+	if _, _, err := t.gh.PullRequests.CreateCommentInReplyTo(ctx, t.info.Owner, t.info.Repo, prNumber, body, root); err != nil {
+		return fmt.Errorf("github reply to review comment: %w", err)
+	}
+	return nil
+}
+
+func (t *GitHubProvider) RetractThread(ctx context.Context, prNumber int, thread ReviewThread, reason string) error {
+	root, err := strconv.ParseInt(thread.ID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("github review comment ID %q: %w", thread.ID, err)
+	}
+	if len(thread.Comments) > 0 {
+		_, _, err := t.gh.PullRequests.EditComment(ctx, t.info.Owner, t.info.Repo, root, &github.PullRequestComment{
+			Body: github.String(retractedBody(thread.Comments[0].Body, reason)),
+		})
+		if err != nil {
+			return fmt.Errorf("github edit review comment: %w", err)
+		}
+	}
+	return t.ReplyToThread(ctx, prNumber, thread, reason)
+}
+
+// githubThreadsQuery pages through a PR's review threads. Resolving threads
+// is only in GitHub's GraphQL API.
+const githubThreadsQuery = `query($owner: String!, $repo: String!, $pr: Int!, $cursor: String) {
+  repository(owner: $owner, name: $repo) {
+    pullRequest(number: $pr) {
+      reviewThreads(first: 50, after: $cursor) {
+        pageInfo { hasNextPage endCursor }
+        nodes {
+          id isResolved path line
+          comments(first: 50) { nodes { databaseId body viewerDidAuthor author { login } } }
+        }
+      }
+    }
+  }
+}`
+
+func (t *GitHubProvider) ListReviewThreads(ctx context.Context, prNumber int) ([]ReviewThread, error) {
+	var threads []ReviewThread
+	var cursor *string
+	for {
+		var out struct {
+			Repository struct {
+				PullRequest struct {
+					ReviewThreads struct {
+						PageInfo struct {
+							HasNextPage bool   `json:"hasNextPage"`
+							EndCursor   string `json:"endCursor"`
+						} `json:"pageInfo"`
+						Nodes []struct {
+							ID         string `json:"id"`
+							IsResolved bool   `json:"isResolved"`
+							Path       string `json:"path"`
+							Line       int    `json:"line"`
+							Comments   struct {
+								Nodes []struct {
+									DatabaseID      int64  `json:"databaseId"`
+									Body            string `json:"body"`
+									ViewerDidAuthor bool   `json:"viewerDidAuthor"`
+									Author          struct {
+										Login string `json:"login"`
+									} `json:"author"`
+								} `json:"nodes"`
+							} `json:"comments"`
+						} `json:"nodes"`
+					} `json:"reviewThreads"`
+				} `json:"pullRequest"`
+			} `json:"repository"`
+		}
+		vars := map[string]any{"owner": t.info.Owner, "repo": t.info.Repo, "pr": prNumber, "cursor": cursor}
+		if err := t.graphql(ctx, githubThreadsQuery, vars, &out); err != nil {
+			return nil, fmt.Errorf("github list review threads: %w", err)
+		}
+		page := out.Repository.PullRequest.ReviewThreads
+		for _, n := range page.Nodes {
+			if len(n.Comments.Nodes) == 0 {
+				continue
+			}
+			thread := ReviewThread{
+				ID:       strconv.FormatInt(n.Comments.Nodes[0].DatabaseID, 10),
+				Path:     n.Path,
+				Line:     n.Line,
+				Resolved: n.IsResolved,
+				nodeID:   n.ID,
+			}
+			for _, c := range n.Comments.Nodes {
+				thread.Comments = append(thread.Comments, ThreadComment{
+					ID:     strconv.FormatInt(c.DatabaseID, 10),
+					Author: c.Author.Login,
+					Body:   c.Body,
+					Mine:   c.ViewerDidAuthor,
+				})
+			}
+			threads = append(threads, thread)
+		}
+		if !page.PageInfo.HasNextPage {
+			return threads, nil
+		}
+		cursor = &page.PageInfo.EndCursor
+	}
+}
+
+func (t *GitHubProvider) ResolveReviewThread(ctx context.Context, prNumber int, thread ReviewThread) error {
+	if thread.nodeID == "" {
+		threads, err := t.ListReviewThreads(ctx, prNumber)
+		if err != nil {
+			return err
+		}
+		for _, th := range threads {
+			if th.ID == thread.ID {
+				thread = th
+			}
+		}
+		if thread.nodeID == "" {
+			return fmt.Errorf("github review thread %s not found", thread.ID)
+		}
+	}
+	if thread.Resolved {
+		return nil
+	}
+	const mutation = `mutation($id: ID!) { resolveReviewThread(input: {threadId: $id}) { thread { id } } }`
+	if err := t.graphql(ctx, mutation, map[string]any{"id": thread.nodeID}, nil); err != nil {
+		return fmt.Errorf("github resolve review thread: %w", err)
+	}
+	return nil
+}
+
+// graphql runs a GraphQL query against the API the client points at and
+// decodes its data into out, if not nil.
+func (t *GitHubProvider) graphql(ctx context.Context, query string, vars map[string]any, out any) error {
+	// Relative to the REST base: api.github.com/graphql, or /api/graphql on
+	// GitHub Enterprise.
+	req, err := t.gh.NewRequest(http.MethodPost, "../graphql", map[string]any{"query": query, "variables": vars})
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if _, err := t.gh.Do(ctx, req, &resp); err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		return fmt.Errorf("graphql: %s", resp.Errors[0].Message)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Data, out)
+}
@@ -1,8 +1,11 @@
 package git
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"net/http"
+	"strconv"
 	"strings"
 
 	gitlab "gitlab.com/gitlab-org/api/client-go"
@@ -12,14 +15,15 @@ type GitLabProvider struct {
 	gl      *gitlab.Client
 	info    RepoInfo
 	baseURL string
+	diff    DiffOptions
 }
 
-func NewGitLabProvider(token, baseURL string, info RepoInfo) (*GitLabProvider, error) {
-	gl, err := gitlab.NewClient(token, gitlab.WithBaseURL(baseURL+"/api/v4"))
+func NewGitLabProvider(token, baseURL string, info RepoInfo, opts ...gitlab.ClientOptionFunc) (*GitLabProvider, error) {
+	gl, err := gitlab.NewClient(token, append([]gitlab.ClientOptionFunc{gitlab.WithBaseURL(baseURL + "/api/v4")}, opts...)...)
 	if err != nil {
 		return nil, fmt.Errorf("gitlab client: %w", err)
 	}
-	return &GitLabProvider{gl: gl, info: info, baseURL: baseURL}, nil
+	return &GitLabProvider{gl: gl, info: info, baseURL: baseURL, diff: DefaultDiffOptions()}, nil
 }
 
 func (t *GitLabProvider) RepoURL() string { return t.info.RawURL }
@@ -50,12 +54,81 @@ func (t *GitLabProvider) GetIssue(ctx context.Context, number int) (Issue, error
 	if err != nil {
 		return Issue{}, fmt.Errorf("gitlab get issue: %w", err)
 	}
-	return Issue{
+	return gitlabIssue(issue), nil
+}
+
+func gitlabIssue(issue *gitlab.Issue) Issue {
+	state := issue.State
+	if state == "opened" {
+		state = "open" // normalise to GitHub's vocabulary
+	}
+	out := Issue{
 		Number: int(issue.IID),
 		Title:  issue.Title,
 		Body:   issue.Description,
 		URL:    issue.WebURL,
-	}, nil
+		State:  state,
+		Labels: []string(issue.Labels),
+	}
+	if issue.UpdatedAt != nil {
+		out.UpdatedAt = *issue.UpdatedAt
+	}
+	return out
+}
+
+func (t *GitLabProvider) ListIssuesByLabel(ctx context.Context, label string) ([]Issue, error) {
+	opts := &gitlab.ListProjectIssuesOptions{
+		State:       gitlab.Ptr("opened"),
+		Labels:      (*gitlab.LabelOptions)(&[]string{label}),
+		ListOptions: gitlab.ListOptions{PerPage: 100},
+	}
+	var out []Issue
+	for {
+		issues, resp, err := t.gl.Issues.ListProjectIssues(t.pid(), opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("gitlab list issues: %w", err)
+		}
+		for _, issue := range issues {
+			out = append(out, gitlabIssue(issue))
+		}
+		if resp.NextPage == 0 {
+			return out, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+func (t *GitLabProvider) UpdateIssueBody(ctx context.Context, number int, body string) error {
+	opts := &gitlab.UpdateIssueOptions{
+		Description: gitlab.Ptr(body),
+	}
+	_, _, err := t.gl.Issues.UpdateIssue(t.pid(), int64(number), opts, gitlab.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("gitlab update issue: %w", err)
+	}
+	return nil
+}
+
+func (t *GitLabProvider) UpdateIssueTitle(ctx context.Context, number int, title string) error {
+	opts := &gitlab.UpdateIssueOptions{
+		Title: gitlab.Ptr(title),
+	}
+	_, _, err := t.gl.Issues.UpdateIssue(t.pid(), int64(number), opts, gitlab.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("gitlab update issue title: %w", err)
+	}
+	return nil
+}
+
+func (t *GitLabProvider) CloseIssue(ctx context.Context, number int) error {
+	opts := &gitlab.UpdateIssueOptions{
+		StateEvent: gitlab.Ptr("close"),
+	}
+	_, _, err := t.gl.Issues.UpdateIssue(t.pid(), int64(number), opts, gitlab.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("gitlab close issue: %w", err)
+	}
+	return nil
 }
 
 func (t *GitLabProvider) AddLabel(ctx context.Context, number int, label string) error {
@@ -69,9 +142,149 @@ func (t *GitLabProvider) AddLabel(ctx context.Context, number int, label string)
 	return nil
 }
 
+func (t *GitLabProvider) AddPRLabel(ctx context.Context, prNumber int, label string) error {
+	_, _, err := t.gl.MergeRequests.UpdateMergeRequest(t.pid(), int64(prNumber), &gitlab.UpdateMergeRequestOptions{
+		AddLabels: (*gitlab.LabelOptions)(&[]string{label}),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("gitlab add MR label: %w", err)
+	}
+	return nil
+}
+
+func (t *GitLabProvider) AddReaction(ctx context.Context, number int, emoji string) error {
+	_, _, err := t.gl.AwardEmoji.CreateIssueAwardEmoji(t.pid(), int64(number), &gitlab.CreateAwardEmojiOptions{
+		Name: emoji,
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("gitlab add reaction: %w", err)
+	}
+	return nil
+}
+
+func (t *GitLabProvider) AssignIssue(ctx context.Context, number int, username string) error {
+	users, _, err := t.gl.Users.ListUsers(&gitlab.ListUsersOptions{Username: gitlab.Ptr(username)}, gitlab.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("gitlab find user: %w", err)
+	}
+	if len(users) == 0 {
+		return fmt.Errorf("gitlab find user: no user %q", username)
+	}
+	issue, _, err := t.gl.Issues.GetIssue(t.pid(), int64(number), gitlab.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("gitlab get issue: %w", err)
+	}
+	ids := []int64{users[0].ID}
+	for _, a := range issue.Assignees {
+		if a.ID != users[0].ID {
+			ids = append(ids, a.ID)
+		}
+	}
+	_, _, err = t.gl.Issues.UpdateIssue(t.pid(), int64(number), &gitlab.UpdateIssueOptions{
+		AssigneeIDs: &ids,
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("gitlab assign issue: %w", err)
+	}
+	return nil
+}
+
+func (t *GitLabProvider) UserPermission(ctx context.Context, username string) (Permission, error) {
+	users, _, err := t.gl.Users.ListUsers(&gitlab.ListUsersOptions{Username: gitlab.Ptr(username)}, gitlab.WithContext(ctx))
+	if err != nil {
+		return PermissionNone, fmt.Errorf("gitlab find user: %w", err)
+	}
+	if len(users) == 0 {
+		return PermissionNone, nil
+	}
+	member, resp, err := t.gl.ProjectMembers.GetInheritedProjectMember(t.pid(), users[0].ID, gitlab.WithContext(ctx))
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return PermissionNone, nil
+		}
+		return PermissionNone, fmt.Errorf("gitlab get member: %w", err)
+	}
+	return gitlabPermission(member.AccessLevel), nil
+}
+
+func gitlabPermission(level gitlab.AccessLevelValue) Permission {
+	switch {
+	case level >= gitlab.OwnerPermissions:
+		return PermissionAdmin
+	case level >= gitlab.MaintainerPermissions:
+		return PermissionMaintain
+	case level >= gitlab.DeveloperPermissions:
+		return PermissionWrite
+	case level >= gitlab.ReporterPermissions:
+		return PermissionTriage
+	case level >= gitlab.GuestPermissions:
+		return PermissionRead
+	}
+	return PermissionNone
+}
+
+func (t *GitLabProvider) TokenPermission(ctx context.Context) (Permission, error) {
+	project, _, err := t.gl.Projects.GetProject(t.pid(), nil, gitlab.WithContext(ctx))
+	if err != nil {
+		return PermissionNone, fmt.Errorf("gitlab get project: %w", err)
+	}
+	// Access can come from the project or its group; the higher one applies.
+	var level gitlab.AccessLevelValue
+	if perms := project.Permissions; perms != nil {
+		if perms.ProjectAccess != nil {
+			level = perms.ProjectAccess.AccessLevel
+		}
+		if perms.GroupAccess != nil && perms.GroupAccess.AccessLevel > level {
+			level = perms.GroupAccess.AccessLevel
+		}
+	}
+	return gitlabPermission(level), nil
+}
+
+func (t *GitLabProvider) EnsureLabel(ctx context.Context, name, color, description string) error {
+	_, resp, err := t.gl.Labels.GetLabel(t.pid(), name, gitlab.WithContext(ctx))
+	if err != nil {
+		if resp == nil || resp.StatusCode != http.StatusNotFound {
+			return fmt.Errorf("gitlab get label: %w", err)
+		}
+		_, _, err := t.gl.Labels.CreateLabel(t.pid(), &gitlab.CreateLabelOptions{
+			Name:        gitlab.Ptr(name),
+			Color:       gitlab.Ptr("#" + color),
+			Description: gitlab.Ptr(description),
+		}, gitlab.WithContext(ctx))
+		if err != nil {
+			return fmt.Errorf("gitlab create label: %w", err)
+		}
+		return nil
+	}
+	_, _, err = t.gl.Labels.UpdateLabel(t.pid(), name, &gitlab.UpdateLabelOptions{
+		Color:       gitlab.Ptr("#" + color),
+		Description: gitlab.Ptr(description),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("gitlab update label: %w", err)
+	}
+	return nil
+}
+
+func (t *GitLabProvider) RemoveLabel(ctx context.Context, number int, label string) error {
+	opts := &gitlab.UpdateIssueOptions{
+		RemoveLabels: (*gitlab.LabelOptions)(&[]string{label}),
+	}
+	_, _, err := t.gl.Issues.UpdateIssue(t.pid(), int64(number), opts, gitlab.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("gitlab remove label: %w", err)
+	}
+	return nil
+}
+
 func (t *GitLabProvider) OpenPR(ctx context.Context, input PRInput) (string, error) {
+	title := input.Title
+	if input.Draft {
+		title = "Draft: " + title // GitLab marks MRs as drafts by title prefix
+	}
 	mr, _, err := t.gl.MergeRequests.CreateMergeRequest(t.pid(), &gitlab.CreateMergeRequestOptions{
-		Title:        gitlab.Ptr(input.Title),
+		Title:        gitlab.Ptr(title),
 		Description:  gitlab.Ptr(input.Body),
 		SourceBranch: gitlab.Ptr(input.Branch),
 		TargetBranch: gitlab.Ptr(input.Base),
@@ -93,6 +306,10 @@ func (t *GitLabProvider) GetPR(ctx context.Context, prNumber int) (PR, error) {
 		return PR{}, err
 	}
 
+	mergeSHA := mr.MergeCommitSHA
+	if mergeSHA == "" {
+		mergeSHA = mr.SquashCommitSHA
+	}
 	return PR{
 		Number:      int(mr.IID),
 		Title:       mr.Title,
@@ -100,57 +317,395 @@ func (t *GitLabProvider) GetPR(ctx context.Context, prNumber int) (PR, error) {
 		URL:         mr.WebURL,
 		Branch:      mr.SourceBranch,
 		BaseBranch:  mr.TargetBranch,
+		HeadSHA:     mr.SHA,
 		Diff:        diff,
-		IssueURL:    extractIssueURL(mr.Description),
+		IssueURL:    ExtractIssueURL(mr.Description),
+		Merged:      mr.State == "merged",
+		MergeSHA:    mergeSHA,
 	}, nil
 }
 
+func (t *GitLabProvider) CompareCommits(ctx context.Context, base, head string) (string, error) {
+	mb, _, err := t.gl.Repositories.MergeBase(t.pid(), &gitlab.MergeBaseOptions{
+		Ref: &[]string{base, head},
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("gitlab merge base %s %s: %w", base, head, err)
+	}
+	if mb.ID != base {
+		return "", ErrNotAncestor
+	}
+	cmp, _, err := t.gl.Repositories.Compare(t.pid(), &gitlab.CompareOptions{
+		From: gitlab.Ptr(base),
+		To:   gitlab.Ptr(head),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("gitlab compare %s...%s: %w", base, head, err)
+	}
+	files := make([]diffFile, 0, len(cmp.Diffs))
+	for _, d := range cmp.Diffs {
+		files = append(files, diffFile{
+			OldPath: d.OldPath,
+			NewPath: d.NewPath,
+			Patch:   d.Diff,
+			Binary:  isBinaryPatch(d.Diff),
+		})
+	}
+	return renderDiff(files, t.diff), nil
+}
+
+func (t *GitLabProvider) FindOpenPR(ctx context.Context, branch string) (int, error) {
+	mrs, _, err := t.gl.MergeRequests.ListProjectMergeRequests(t.pid(), &gitlab.ListProjectMergeRequestsOptions{
+		State:        gitlab.Ptr("opened"),
+		SourceBranch: gitlab.Ptr(branch),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("gitlab find MR: %w", err)
+	}
+	if len(mrs) == 0 {
+		return 0, nil
+	}
+	return int(mrs[0].IID), nil
+}
+
+func (t *GitLabProvider) GetFileAtRef(ctx context.Context, path, ref string) (string, error) {
+	b, _, err := t.gl.RepositoryFiles.GetRawFile(t.pid(), path, &gitlab.GetRawFileOptions{
+		Ref: gitlab.Ptr(ref),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("gitlab get file %s@%s: %w", path, ref, err)
+	}
+	return string(b), nil
+}
+
+func (t *GitLabProvider) CommitFile(ctx context.Context, branch, path, content, message string) (string, error) {
+	project, _, err := t.gl.Projects.GetProject(t.pid(), nil, gitlab.WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("gitlab get project: %w", err)
+	}
+	base := project.DefaultBranch
+	if _, resp, err := t.gl.Branches.GetBranch(t.pid(), branch, gitlab.WithContext(ctx)); err != nil {
+		if resp == nil || resp.StatusCode != http.StatusNotFound {
+			return "", fmt.Errorf("gitlab get branch %s: %w", branch, err)
+		}
+		_, _, err = t.gl.Branches.CreateBranch(t.pid(), &gitlab.CreateBranchOptions{
+			Branch: gitlab.Ptr(branch),
+			Ref:    gitlab.Ptr(base),
+		}, gitlab.WithContext(ctx))
+		if err != nil {
+			return "", fmt.Errorf("gitlab create branch %s: %w", branch, err)
+		}
+	}
+
+	_, resp, err := t.gl.RepositoryFiles.GetFileMetaData(t.pid(), path, &gitlab.GetFileMetaDataOptions{Ref: gitlab.Ptr(branch)}, gitlab.WithContext(ctx))
+	switch {
+	case err == nil:
+		_, _, err = t.gl.RepositoryFiles.UpdateFile(t.pid(), path, &gitlab.UpdateFileOptions{
+			Branch:        gitlab.Ptr(branch),
+			Content:       gitlab.Ptr(content),
+			CommitMessage: gitlab.Ptr(message),
+		}, gitlab.WithContext(ctx))
+	case resp != nil && resp.StatusCode == http.StatusNotFound:
+		_, _, err = t.gl.RepositoryFiles.CreateFile(t.pid(), path, &gitlab.CreateFileOptions{
+			Branch:        gitlab.Ptr(branch),
+			Content:       gitlab.Ptr(content),
+			CommitMessage: gitlab.Ptr(message),
+		}, gitlab.WithContext(ctx))
+	}
+	if err != nil {
+		return "", fmt.Errorf("gitlab commit %s: %w", path, err)
+	}
+	return base, nil
+}
+
+func (t *GitLabProvider) UploadImage(ctx context.Context, name string, data []byte) (string, error) {
+	f, _, err := t.gl.ProjectMarkdownUploads.UploadProjectMarkdown(t.pid(), bytes.NewReader(data), name, gitlab.WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("gitlab upload %s: %w", name, err)
+	}
+	// The URL is relative to the project, which is where MR descriptions
+	// resolve it.
+	return f.URL, nil
+}
+
+func (t *GitLabProvider) StartPipeline(ctx context.Context, branch, sha string) (Pipeline, error) {
+	existing, _, err := t.gl.Pipelines.ListProjectPipelines(t.pid(), &gitlab.ListProjectPipelinesOptions{
+		SHA: gitlab.Ptr(sha),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return Pipeline{}, fmt.Errorf("gitlab list pipelines: %w", err)
+	}
+	if len(existing) > 0 {
+		// Newest first: the pipeline the push started.
+		p := existing[0]
+		return Pipeline{ID: p.ID, SHA: sha, URL: p.WebURL, Status: gitlabPipelineStatus(p.Status)}, nil
+	}
+	p, resp, err := t.gl.Pipelines.CreatePipeline(t.pid(), &gitlab.CreatePipelineOptions{
+		Ref: gitlab.Ptr(branch),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		// GitLab refuses to create a pipeline for a project without CI
+		// configuration.
+		if resp != nil && resp.StatusCode == http.StatusBadRequest {
+			return Pipeline{SHA: sha, Status: PipelineNone}, nil
+		}
+		return Pipeline{}, fmt.Errorf("gitlab create pipeline: %w", err)
+	}
+	return Pipeline{ID: p.ID, SHA: sha, URL: p.WebURL, Status: gitlabPipelineStatus(p.Status)}, nil
+}
+
+func (t *GitLabProvider) GetPipeline(ctx context.Context, p Pipeline) (Pipeline, error) {
+	if p.ID == 0 {
+		return p, nil
+	}
+	got, _, err := t.gl.Pipelines.GetPipeline(t.pid(), p.ID, gitlab.WithContext(ctx))
+	if err != nil {
+		return p, fmt.Errorf("gitlab get pipeline: %w", err)
+	}
+	p.URL = got.WebURL
+	p.Status = gitlabPipelineStatus(got.Status)
+	return p, nil
+}
+
+func gitlabPipelineStatus(s string) PipelineStatus {
+	switch s {
+	case "success":
+		return PipelineSuccess
+	case "failed":
+		return PipelineFailed
+	case "canceled", "canceling":
+		return PipelineCanceled
+	case "skipped":
+		return PipelineNone
+	default: // created, waiting_for_resource, preparing, pending, running, manual, scheduled
+		return PipelinePending
+	}
+}
+
+func (t *GitLabProvider) FailedJobs(ctx context.Context, p Pipeline) ([]JobLog, error) {
+	jobs, _, err := t.gl.Jobs.ListPipelineJobs(t.pid(), p.ID, &gitlab.ListJobsOptions{
+		Scope: &[]gitlab.BuildStateValue{gitlab.Failed},
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("gitlab list pipeline jobs: %w", err)
+	}
+	out := make([]JobLog, 0, len(jobs))
+	for _, job := range jobs {
+		log := "(log unavailable)"
+		if trace, _, err := t.gl.Jobs.GetTraceFile(t.pid(), job.ID, gitlab.WithContext(ctx)); err == nil {
+			var b bytes.Buffer
+			_, _ = b.ReadFrom(trace)
+			log = b.String()
+		}
+		out = append(out, JobLog{Name: job.Stage + " / " + job.Name, URL: job.WebURL, Log: logTail(log)})
+	}
+	return out, nil
+}
+
+func (t *GitLabProvider) EnsureWebhook(ctx context.Context, url, secret string, events []WebhookEvent) error {
+	enabled := make(map[WebhookEvent]bool, len(events))
+	for _, e := range events {
+		enabled[e] = true
+	}
+
+	opts := &gitlab.ListProjectHooksOptions{ListOptions: gitlab.ListOptions{PerPage: 100}}
+	for {
+		hooks, resp, err := t.gl.Projects.ListProjectHooks(t.pid(), opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return fmt.Errorf("gitlab list hooks: %w", err)
+		}
+		for _, h := range hooks {
+			if h.URL != url {
+				continue
+			}
+			_, _, err := t.gl.Projects.EditProjectHook(t.pid(), h.ID, &gitlab.EditProjectHookOptions{
+				URL:                 gitlab.Ptr(url),
+				Token:               gitlab.Ptr(secret),
+				IssuesEvents:        gitlab.Ptr(enabled[WebhookEventIssues]),
+				MergeRequestsEvents: gitlab.Ptr(enabled[WebhookEventPullRequests]),
+				NoteEvents:          gitlab.Ptr(enabled[WebhookEventComments]),
+				PushEvents:          gitlab.Ptr(enabled[WebhookEventPush]),
+			}, gitlab.WithContext(ctx))
+			if err != nil {
+				return fmt.Errorf("gitlab edit hook: %w", err)
+			}
+			return nil
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	_, _, err := t.gl.Projects.AddProjectHook(t.pid(), &gitlab.AddProjectHookOptions{
+		URL:                 gitlab.Ptr(url),
+		Token:               gitlab.Ptr(secret),
+		IssuesEvents:        gitlab.Ptr(enabled[WebhookEventIssues]),
+		MergeRequestsEvents: gitlab.Ptr(enabled[WebhookEventPullRequests]),
+		NoteEvents:          gitlab.Ptr(enabled[WebhookEventComments]),
+		PushEvents:          gitlab.Ptr(enabled[WebhookEventPush]),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("gitlab add hook: %w", err)
+	}
+	return nil
+}
+
 func (t *GitLabProvider) getMRDiff(ctx context.Context, mrNumber int) (string, error) {
+	files, err := t.mrDiffFiles(ctx, mrNumber)
+	if err != nil {
+		return "", err
+	}
+	return renderDiff(files, t.diff), nil
+}
+
+func (t *GitLabProvider) mrDiffFiles(ctx context.Context, mrNumber int) ([]diffFile, error) {
 	diffs, _, err := t.gl.MergeRequests.ListMergeRequestDiffs(t.pid(), int64(mrNumber), nil, gitlab.WithContext(ctx))
 	if err != nil {
-		return "", fmt.Errorf("gitlab get MR diff: %w", err)
+		return nil, fmt.Errorf("gitlab get MR diff: %w", err)
 	}
 
-	var sb strings.Builder
+	files := make([]diffFile, 0, len(diffs))
 	for _, d := range diffs {
-		sb.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", d.OldPath, d.NewPath))
-		sb.WriteString(d.Diff)
-		sb.WriteString("\n")
+		files = append(files, diffFile{
+			OldPath: d.OldPath,
+			NewPath: d.NewPath,
+			Patch:   d.Diff,
+			Binary:  isBinaryPatch(d.Diff),
+		})
 	}
-	return sb.String(), nil
+	return files, nil
 }
 
-func (t *GitLabProvider) PostReview(ctx context.Context, prNumber int, review Review) error {
-	_, _, err := t.gl.Notes.CreateMergeRequestNote(t.pid(), int64(prNumber), &gitlab.CreateMergeRequestNoteOptions{
-		Body: gitlab.Ptr(review.Summary),
+func (t *GitLabProvider) GetMarkedComment(ctx context.Context, prNumber int, marker string) (string, error) {
+	n, err := t.findMarkedNote(ctx, prNumber, marker)
+	if err != nil || n == nil {
+		return "", err
+	}
+	return n.Body, nil
+}
+
+func (t *GitLabProvider) UpsertMarkedComment(ctx context.Context, prNumber int, marker, body string) error {
+	n, err := t.findMarkedNote(ctx, prNumber, marker)
+	if err != nil {
+		return err
+	}
+	if n == nil {
+		_, _, err = t.gl.Notes.CreateMergeRequestNote(t.pid(), int64(prNumber), &gitlab.CreateMergeRequestNoteOptions{
+			Body: gitlab.Ptr(body),
+		}, gitlab.WithContext(ctx))
+		if err != nil {
+			return fmt.Errorf("gitlab create MR note: %w", err)
+		}
+		return nil
+	}
+	_, _, err = t.gl.Notes.UpdateMergeRequestNote(t.pid(), int64(prNumber), n.ID, &gitlab.UpdateMergeRequestNoteOptions{
+		Body: gitlab.Ptr(body),
 	}, gitlab.WithContext(ctx))
 	if err != nil {
-		return fmt.Errorf("gitlab post review note: %w", err)
+		return fmt.Errorf("gitlab update MR note: %w", err)
 	}
+	return nil
+}
 
-	for _, c := range review.Comments {
-		side := "new" // GitLab uses "new"/"old" instead of "RIGHT"/"LEFT"
-		if c.Side == "LEFT" {
-			side = "old"
-		}
-		_, _, err := t.gl.Discussions.CreateMergeRequestDiscussion(t.pid(), int64(prNumber), &gitlab.CreateMergeRequestDiscussionOptions{
-			Body: gitlab.Ptr(c.Body),
-			Position: &gitlab.PositionOptions{
-				PositionType: gitlab.Ptr("text"),
-				NewPath:      gitlab.Ptr(c.Path),
-				NewLine:      gitlab.Ptr(int64(c.Line)),
-				LineRange: &gitlab.LineRangeOptions{
-					Start: &gitlab.LinePositionOptions{
-						Type: gitlab.Ptr(side),
-					},
-				},
-			},
+func (t *GitLabProvider) UpsertMarkedIssueComment(ctx context.Context, number int, marker, body string) error {
+	n, err := t.findMarkedIssueNote(ctx, number, marker)
+	if err != nil {
+		return err
+	}
+	if n == nil {
+		_, _, err = t.gl.Notes.CreateIssueNote(t.pid(), int64(number), &gitlab.CreateIssueNoteOptions{
+			Body: gitlab.Ptr(body),
 		}, gitlab.WithContext(ctx))
 		if err != nil {
-			// Non-fatal — line number mapping can fail if the diff shifts.
-			// Log and continue rather than aborting the whole review.
-			_ = err
+			return fmt.Errorf("gitlab create issue note: %w", err)
+		}
+		return nil
+	}
+	_, _, err = t.gl.Notes.UpdateIssueNote(t.pid(), int64(number), n.ID, &gitlab.UpdateIssueNoteOptions{
+		Body: gitlab.Ptr(body),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("gitlab update issue note: %w", err)
+	}
+	return nil
+}
+
+func (t *GitLabProvider) findMarkedIssueNote(ctx context.Context, number int, marker string) (*gitlab.Note, error) {
+	opts := &gitlab.ListIssueNotesOptions{ListOptions: gitlab.ListOptions{PerPage: 100}}
+	for {
+		notes, resp, err := t.gl.Notes.ListIssueNotes(t.pid(), int64(number), opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("gitlab list issue notes: %w", err)
+		}
+		for _, n := range notes {
+			if !n.System && strings.Contains(n.Body, marker) {
+				return n, nil
+			}
+		}
+		if resp.NextPage == 0 {
+			return nil, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+func (t *GitLabProvider) findMarkedNote(ctx context.Context, prNumber int, marker string) (*gitlab.Note, error) {
+	opts := &gitlab.ListMergeRequestNotesOptions{ListOptions: gitlab.ListOptions{PerPage: 100}}
+	for {
+		notes, resp, err := t.gl.Notes.ListMergeRequestNotes(t.pid(), int64(prNumber), opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("gitlab list MR notes: %w", err)
+		}
+		for _, n := range notes {
+			if !n.System && strings.Contains(n.Body, marker) {
+				return n, nil
+			}
 		}
+		if resp.NextPage == 0 {
+			return nil, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// PostReview posts the inline comments as diff discussions, then the summary
+// as a note. Each comment is first moved onto a line of the MR's diff, with
+// the old path and old line GitLab also wants for renamed files and context
+// lines; one that cannot be placed, or that GitLab still rejects, is appended
+// to the summary with the reason, so no review content is lost.
+func (t *GitLabProvider) PostReview(ctx context.Context, prNumber int, review Review) error {
+	mr, _, err := t.gl.MergeRequests.GetMergeRequest(t.pid(), int64(prNumber), nil, gitlab.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("gitlab get MR: %w", err)
+	}
+	files, err := t.mrDiffFiles(ctx, prNumber)
+	if err != nil {
+		return err
+	}
+	diff := ParseDiff(renderDiff(files, DiffOptions{}))
+	var failed []string
+	for _, c := range review.Comments {
+		placed, line, ok := diff.Normalize(c, maxCommentShift)
+		if !ok {
+			failed = append(failed, unplacedComment(c, "outside the diff"))
+			continue
+		}
+		pos := gitlabPosition(mr.DiffRefs, diff[placed.Path].OldPath, placed.Path, line.New, line.Old)
+		if err := t.createDiscussion(ctx, prNumber, placed.Text(), pos); err != nil {
+			failed = append(failed, unplacedComment(placed, err.Error()))
+		}
+	}
+
+	summary := review.Summary
+	if len(failed) > 0 {
+		summary += "\n\n" + unplacedHeading + "\n\n" + strings.Join(failed, "\n")
+	}
+	_, _, err = t.gl.Notes.CreateMergeRequestNote(t.pid(), int64(prNumber), &gitlab.CreateMergeRequestNoteOptions{
+		Body: gitlab.Ptr(summary),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("gitlab post review note: %w", err)
 	}
 
 	if review.Verdict == "approve" {
@@ -163,6 +718,47 @@ func (t *GitLabProvider) PostReview(ctx context.Context, prNumber int, review Re
 	return nil
 }
 
+func (t *GitLabProvider) createDiscussion(ctx context.Context, prNumber int, body string, pos *gitlab.PositionOptions) error {
+	_, _, err := t.gl.Discussions.CreateMergeRequestDiscussion(t.pid(), int64(prNumber), &gitlab.CreateMergeRequestDiscussionOptions{
+		Body:     gitlab.Ptr(body),
+		Position: pos,
+	}, gitlab.WithContext(ctx))
+	return err
+}
+
+// gitlabPosition anchors a diff discussion. GitLab wants the new line for an
+// added line, the old line for a removed one, and both for a context line;
+// a zero line is left out.
+func gitlabPosition(refs gitlab.MergeRequestDiffRefs, oldPath, newPath string, newLine, oldLine int) *gitlab.PositionOptions {
+	pos := &gitlab.PositionOptions{
+		PositionType: gitlab.Ptr("text"),
+		BaseSHA:      gitlab.Ptr(refs.BaseSha),
+		StartSHA:     gitlab.Ptr(refs.StartSha),
+		HeadSHA:      gitlab.Ptr(refs.HeadSha),
+		OldPath:      gitlab.Ptr(oldPath),
+		NewPath:      gitlab.Ptr(newPath),
+	}
+	if newLine > 0 {
+		pos.NewLine = gitlab.Ptr(int64(newLine))
+	}
+	if oldLine > 0 {
+		pos.OldLine = gitlab.Ptr(int64(oldLine))
+	}
+	return pos
+}
+
+// DismissReviews posts message on the MR. GitLab reviews from this client
+// are plain notes and never block merging, so there is nothing to withdraw.
+func (t *GitLabProvider) DismissReviews(ctx context.Context, prNumber int, message string) error {
+	_, _, err := t.gl.Notes.CreateMergeRequestNote(t.pid(), int64(prNumber), &gitlab.CreateMergeRequestNoteOptions{
+		Body: gitlab.Ptr(message),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("gitlab post dismissal note: %w", err)
+	}
+	return nil
+}
+
 func (t *GitLabProvider) GetPRComments(ctx context.Context, prNumber int) ([]PRComment, error) {
 	notes, _, err := t.gl.Notes.ListMergeRequestNotes(t.pid(), int64(prNumber), nil, gitlab.WithContext(ctx))
 	if err != nil {
@@ -179,3 +775,108 @@ func (t *GitLabProvider) GetPRComments(ctx context.Context, prNumber int) ([]PRC
 	}
 	return out, nil
 }
+
+func (t *GitLabProvider) GetReviewThread(ctx context.Context, prNumber int, id string) (ReviewThread, error) {
+	d, _, err := t.gl.Discussions.GetMergeRequestDiscussion(t.pid(), int64(prNumber), id, gitlab.WithContext(ctx))
+	if err != nil {
+		return ReviewThread{}, fmt.Errorf("gitlab get discussion: %w", err)
+	}
+	me, _, err := t.gl.Users.CurrentUser(gitlab.WithContext(ctx))
+	if err != nil {
+		return ReviewThread{}, fmt.Errorf("gitlab get user: %w", err)
+	}
+	thread := ReviewThread{ID: d.ID}
+	for i, n := range d.Notes {
+		if i == 0 && n.Position != nil {
+			thread.Path, thread.Line = n.Position.NewPath, int(n.Position.NewLine)
+		}
+		if n.System {
+			continue
+		}
+		thread.Comments = append(thread.Comments, ThreadComment{
+			ID:     strconv.FormatInt(n.ID, 10),
+			Author: n.Author.Username,
+			Body:   n.Body,
+			Mine:   n.Author.Username == me.Username,
+		})
+	}
+	return thread, nil
+}
+
+func (t *GitLabProvider) ReplyToThread(ctx context.Context, prNumber int, thread ReviewThread, body string) error {
+	_, _, err := t.gl.Discussions.AddMergeRequestDiscussionNote(t.pid(), int64(prNumber), thread.ID, &gitlab.AddMergeRequestDiscussionNoteOptions{
+		Body: gitlab.Ptr(body),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("gitlab reply to discussion: %w", err)
+	}
+	return nil
+}
+
+func (t *GitLabProvider) RetractThread(ctx context.Context, prNumber int, thread ReviewThread, reason string) error {
+	if len(thread.Comments) > 0 {
+		first := thread.Comments[0]
+		noteID, err := strconv.ParseInt(first.ID, 10, 64)
+		if err != nil {
+			return fmt.Errorf("gitlab note ID %q: %w", first.ID, err)
+		}
+		_, _, err = t.gl.Discussions.UpdateMergeRequestDiscussionNote(t.pid(), int64(prNumber), thread.ID, noteID, &gitlab.UpdateMergeRequestDiscussionNoteOptions{
+			Body: gitlab.Ptr(retractedBody(first.Body, reason)),
+		}, gitlab.WithContext(ctx))
+		if err != nil {
+			return fmt.Errorf("gitlab edit discussion note: %w", err)
+		}
+	}
+	if err := t.ReplyToThread(ctx, prNumber, thread, reason); err != nil {
+		return err
+	}
+	return t.ResolveReviewThread(ctx, prNumber, thread)
+}
+
+func (t *GitLabProvider) ListReviewThreads(ctx context.Context, prNumber int) ([]ReviewThread, error) {
+	me, _, err := t.gl.Users.CurrentUser(gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("gitlab get user: %w", err)
+	}
+	var threads []ReviewThread
+	opts := &gitlab.ListMergeRequestDiscussionsOptions{ListOptions: gitlab.ListOptions{PerPage: 100}}
+	for {
+		page, resp, err := t.gl.Discussions.ListMergeRequestDiscussions(t.pid(), int64(prNumber), opts, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("gitlab list discussions: %w", err)
+		}
+		for _, d := range page {
+			if len(d.Notes) == 0 || d.Notes[0].Position == nil {
+				continue // not an inline thread
+			}
+			first := d.Notes[0]
+			thread := ReviewThread{ID: d.ID, Path: first.Position.NewPath, Line: int(first.Position.NewLine), Resolved: first.Resolved}
+			for _, n := range d.Notes {
+				if n.System {
+					continue
+				}
+				thread.Comments = append(thread.Comments, ThreadComment{
+					ID:     strconv.FormatInt(n.ID, 10),
+					Author: n.Author.Username,
+					Body:   n.Body,
+					Mine:   n.Author.Username == me.Username,
+				})
+			}
+			threads = append(threads, thread)
+		}
+		if resp.NextPage == 0 {
+			return threads, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+func (t *GitLabProvider) ResolveReviewThread(ctx context.Context, prNumber int, thread ReviewThread) error {
+	_, _, err := t.gl.Discussions.ResolveMergeRequestDiscussion(t.pid(), int64(prNumber), thread.ID, &gitlab.ResolveMergeRequestDiscussionOptions{
+		Resolved: gitlab.Ptr(true),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("gitlab resolve discussion: %w", err)
+	}
+	return nil
+}
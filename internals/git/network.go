@@ -0,0 +1,82 @@
+package git
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// Network carries the TLS and proxy settings needed to reach a Git host from
+// behind a corporate proxy or TLS interception.
+type Network struct {
+	caFile string
+	proxy  *url.URL
+	client *http.Client
+}
+
+// NewNetwork builds network settings from a PEM CA bundle and a proxy URL.
+// Both are optional; with neither set it returns nil, which callers treat as
+// "use the defaults". The CA bundle is trusted in addition to the system roots
+// by the API clients, but the git CLI uses it in place of its own bundle, so
+// it must contain every CA git needs.
+func NewNetwork(caFile, proxy string) (*Network, error) {
+	if caFile == "" && proxy == "" {
+		return nil, nil
+	}
+	n := &Network{caFile: caFile}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA file %s contains no PEM certificates", caFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	if proxy != "" {
+		u, err := url.Parse(proxy)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", proxy)
+		}
+		n.proxy = u
+		transport.Proxy = http.ProxyURL(u)
+	}
+
+	n.client = &http.Client{Transport: transport}
+	return n, nil
+}
+
+// HTTPClient returns the client for provider API calls, or nil for the
+// default.
+func (n *Network) HTTPClient() *http.Client {
+	if n == nil {
+		return nil
+	}
+	return n.client
+}
+
+// cloneConfig returns "git clone -c" arguments. git clone writes them into the
+// new repository's config, so later fetches and pushes use them too.
+func (n *Network) cloneConfig() []string {
+	if n == nil {
+		return nil
+	}
+	var args []string
+	if n.caFile != "" {
+		args = append(args, "-c", "http.sslCAInfo="+n.caFile)
+	}
+	if n.proxy != nil {
+		args = append(args, "-c", "http.proxy="+n.proxy.String())
+	}
+	return args
+}
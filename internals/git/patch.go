@@ -0,0 +1,34 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// Patch returns the changes from commit to HEAD as a patch ApplyPatch can
+// replay, binary files included.
+func (r *Repo) Patch(ctx context.Context, commit string) (string, error) {
+	return run(ctx, r.dir, "git", "diff", "--binary", commit, "HEAD")
+}
+
+// ApplyPatch applies patch to the working tree and stages it. Nothing is
+// changed if any part of it does not apply.
+func (r *Repo) ApplyPatch(ctx context.Context, patch string) error {
+	f, err := os.CreateTemp("", "droid-patch-*.diff")
+	if err != nil {
+		return fmt.Errorf("create patch file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(patch); err != nil {
+		f.Close()
+		return fmt.Errorf("write patch file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write patch file: %w", err)
+	}
+	if _, err := run(ctx, r.dir, "git", "apply", "--index", "--binary", f.Name()); err != nil {
+		return fmt.Errorf("apply patch: %w", err)
+	}
+	return nil
+}
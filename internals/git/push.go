@@ -0,0 +1,142 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// PushStrategy controls how Push behaves when the remote branch already exists
+// and has diverged from the local branch.
+type PushStrategy int
+
+const (
+	// PushFastForwardOnly refuses any push that is not a fast-forward of the
+	// remote branch. This is the default and never rewrites remote history.
+	PushFastForwardOnly PushStrategy = iota
+	// PushForceWithLeaseIfOwned force-pushes with --force-with-lease, but only
+	// when every commit that would be dropped from the remote branch was
+	// authored by the agent. Human commits on the branch are never discarded.
+	PushForceWithLeaseIfOwned
+)
+
+func (s PushStrategy) String() string {
+	switch s {
+	case PushFastForwardOnly:
+		return "ff-only"
+	case PushForceWithLeaseIfOwned:
+		return "force-with-lease"
+	default:
+		return "unknown"
+	}
+}
+
+// ParsePushStrategy maps a config value ("ff-only" or "force-with-lease") to a
+// PushStrategy. An empty string selects the default, PushFastForwardOnly.
+func ParsePushStrategy(s string) (PushStrategy, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "ff-only":
+		return PushFastForwardOnly, nil
+	case "force-with-lease":
+		return PushForceWithLeaseIfOwned, nil
+	default:
+		return 0, fmt.Errorf("unknown push strategy %q — expected ff-only or force-with-lease", s)
+	}
+}
+
+var (
+	// ErrNonFastForward is returned when the remote branch has commits that
+	// are not in the local branch and the strategy forbids overwriting them.
+	ErrNonFastForward = errors.New("remote branch has diverged: non-fast-forward push rejected")
+	// ErrForeignCommits is returned when a force push would discard commits
+	// that were not authored by the agent.
+	ErrForeignCommits = errors.New("remote branch contains commits not authored by the agent")
+	// ErrNotAncestor is returned by CompareCommits when the base commit is
+	// not in the head commit's history.
+	ErrNotAncestor = errors.New("base commit is not an ancestor of head")
+)
+
+// Push pushes the current branch to origin according to the repo's push
+// strategy. A branch that does not yet exist on the remote is always pushed
+// normally.
+func (r *Repo) Push(ctx context.Context) error {
+	branch, err := r.CurrentBranch(ctx)
+	if err != nil {
+		return err
+	}
+
+	remoteSHA, err := r.remoteBranchSHA(ctx, branch)
+	if err != nil {
+		return err
+	}
+	if remoteSHA == "" {
+		_, err = run(ctx, r.dir, "git", "push", "origin", branch)
+		return err
+	}
+
+	switch r.pushStrategy {
+	case PushForceWithLeaseIfOwned:
+		return r.forcePushIfOwned(ctx, branch, remoteSHA)
+	default:
+		return r.pushFastForward(ctx, branch)
+	}
+}
+
+func (r *Repo) pushFastForward(ctx context.Context, branch string) error {
+	_, err := run(ctx, r.dir, "git", "push", "origin", branch)
+	if err != nil && isNonFastForward(err) {
+		return fmt.Errorf("push %s: %w", branch, ErrNonFastForward)
+	}
+	return err
+}
+
+func (r *Repo) forcePushIfOwned(ctx context.Context, branch, remoteSHA string) error {
+	// Fetch the branch rather than remoteSHA: many servers refuse to serve a
+	// commit by SHA that no ref advertises.
+	if _, err := run(ctx, r.dir, "git", "fetch", "origin", "refs/heads/"+branch); err != nil {
+		return fmt.Errorf("fetch remote %s: %w", branch, err)
+	}
+	fetched, err := run(ctx, r.dir, "git", "rev-parse", "FETCH_HEAD")
+	if err != nil {
+		return fmt.Errorf("resolve fetched %s: %w", branch, err)
+	}
+	if fetched = strings.TrimSpace(fetched); fetched != remoteSHA {
+		return fmt.Errorf("force push %s: remote branch moved from %s to %s: %w", branch, remoteSHA, fetched, ErrNonFastForward)
+	}
+
+	// Commits reachable from the remote tip but not from HEAD are the ones a
+	// force push would discard.
+	out, err := run(ctx, r.dir, "git", "log", "--format=%ae", "HEAD..FETCH_HEAD")
+	if err != nil {
+		return fmt.Errorf("list remote-only commits: %w", err)
+	}
+	for _, email := range strings.Fields(out) {
+		if email != agentEmail {
+			return fmt.Errorf("force push %s: %w (author %s)", branch, ErrForeignCommits, email)
+		}
+	}
+
+	lease := fmt.Sprintf("--force-with-lease=%s:%s", branch, remoteSHA)
+	_, err = run(ctx, r.dir, "git", "push", lease, "origin", branch)
+	return err
+}
+
+// remoteBranchSHA returns the commit the remote branch points at, or "" if the
+// branch does not exist on origin.
+func (r *Repo) remoteBranchSHA(ctx context.Context, branch string) (string, error) {
+	out, err := run(ctx, r.dir, "git", "ls-remote", "--heads", "origin", branch)
+	if err != nil {
+		return "", fmt.Errorf("ls-remote %s: %w", branch, err)
+	}
+	fields := strings.Fields(out)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return fields[0], nil
+}
+
+func isNonFastForward(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "non-fast-forward") || strings.Contains(msg, "fetch first")
+}
@@ -3,8 +3,12 @@ package git
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"net/url"
 	"strings"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+	"golang.org/x/oauth2"
 )
 
 type RepoInfo struct {
@@ -93,6 +97,9 @@ type Factory struct {
 	githubToken   string
 	gitlabToken   string
 	gitlabBaseURL string
+	diffOptions   DiffOptions
+	network       *Network
+	wrapTransport func(http.RoundTripper) http.RoundTripper
 }
 
 type FactoryOption func(*Factory)
@@ -101,11 +108,28 @@ func WithGitLabBaseURL(baseURL string) FactoryOption {
 	return func(f *Factory) { f.gitlabBaseURL = baseURL }
 }
 
+// WithNetwork routes provider API calls through n's CA bundle and proxy.
+func WithNetwork(n *Network) FactoryOption {
+	return func(f *Factory) { f.network = n }
+}
+
+// WithTransport wraps the HTTP transport used for provider API calls, e.g.
+// to inject faults in a staging environment.
+func WithTransport(wrap func(http.RoundTripper) http.RoundTripper) FactoryOption {
+	return func(f *Factory) { f.wrapTransport = wrap }
+}
+
+// WithDiffOptions sets the diff filters applied to every repo.
+func WithDiffOptions(opts DiffOptions) FactoryOption {
+	return func(f *Factory) { f.diffOptions = opts }
+}
+
 func NewFactory(githubToken, gitlabToken string, opts ...FactoryOption) *Factory {
 	f := &Factory{
 		githubToken:   githubToken,
 		gitlabToken:   gitlabToken,
 		gitlabBaseURL: "https://gitlab.com",
+		diffOptions:   DefaultDiffOptions(),
 	}
 	for _, o := range opts {
 		o(f)
@@ -124,8 +148,15 @@ func (f *Factory) ProviderFor(ctx context.Context, repoURL string) (GitProvider,
 		if f.githubToken == "" {
 			return nil, info, fmt.Errorf("no GitHub token configured")
 		}
+		if client := f.httpClient(); client != nil {
+			ctx = context.WithValue(ctx, oauth2.HTTPClient, client)
+		}
 		t, err := NewGitHubProvider(ctx, f.githubToken, info)
-		return t, info, err
+		if err != nil {
+			return nil, info, err
+		}
+		t.diff = f.diffOptions
+		return t, info, nil
 
 	case PlatformGitLab:
 		if f.gitlabToken == "" {
@@ -137,9 +168,31 @@ func (f *Factory) ProviderFor(ctx context.Context, repoURL string) (GitProvider,
 			parsed, _ := url.Parse(info.RawURL)
 			baseURL = parsed.Scheme + "://" + parsed.Host
 		}
-		t, err := NewGitLabProvider(f.gitlabToken, baseURL, info)
-		return t, info, err
+		var glOpts []gitlab.ClientOptionFunc
+		if client := f.httpClient(); client != nil {
+			glOpts = append(glOpts, gitlab.WithHTTPClient(client))
+		}
+		t, err := NewGitLabProvider(f.gitlabToken, baseURL, info, glOpts...)
+		if err != nil {
+			return nil, info, err
+		}
+		t.diff = f.diffOptions
+		return t, info, nil
 	}
 
 	return nil, info, fmt.Errorf("unsupported platform: %s", info.Platform)
 }
+
+// httpClient returns the client for provider API calls, or nil for the
+// default.
+func (f *Factory) httpClient() *http.Client {
+	client := f.network.HTTPClient()
+	if f.wrapTransport == nil {
+		return client
+	}
+	next := http.DefaultTransport
+	if client != nil {
+		next = client.Transport
+	}
+	return &http.Client{Transport: f.wrapTransport(next)}
+}
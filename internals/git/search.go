@@ -0,0 +1,65 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// SearchOptions narrows a code search.
+type SearchOptions struct {
+	Globs        []string // pathspec globs, e.g. "**/*.go" or "internal/**"
+	ContextLines int      // lines of context around each match
+	IgnoreCase   bool
+}
+
+// maxSearchBytes caps search output so a broad pattern can't flood the
+// agent's context.
+const maxSearchBytes = 12000
+
+// Search runs git grep with an extended regular expression over the working
+// tree, including files the agent has created but not yet committed. Output
+// lines are "path:line:text" for matches and "path-line-text" for context. It
+// returns "" when nothing matches.
+func (r *Repo) Search(ctx context.Context, pattern string, opts SearchOptions) (string, error) {
+	args := []string{"grep", "-n", "-E", "-I", "--untracked", "--no-color", "--full-name"}
+	if opts.IgnoreCase {
+		args = append(args, "-i")
+	}
+	if opts.ContextLines > 0 {
+		args = append(args, "-C", strconv.Itoa(min(opts.ContextLines, 10)))
+	}
+	args = append(args, "-e", pattern, "--")
+	for _, g := range opts.Globs {
+		args = append(args, ":(glob)"+g)
+	}
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = r.dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		// git grep exits 1 when nothing matches.
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && stderr.Len() == 0 {
+			return "", nil
+		}
+		return "", fmt.Errorf("git grep: %w\nstderr: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	out := stdout.String()
+	if len(out) > maxSearchBytes {
+		cut := strings.LastIndexByte(out[:maxSearchBytes], '\n')
+		if cut < 0 {
+			cut = maxSearchBytes
+		}
+		out = out[:cut] + fmt.Sprintf("\n... (truncated, %d matching lines total — narrow the pattern or add globs)", strings.Count(out, "\n"))
+	}
+	return out, nil
+}
@@ -0,0 +1,118 @@
+package git
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SSHConfig selects SSH instead of HTTPS token auth for clones and pushes to
+// some hosts, e.g. self-hosted instances that disable token auth over HTTPS.
+type SSHConfig struct {
+	Hosts []string // hosts to reach over SSH; others keep using HTTPS tokens
+
+	// The private key is read from KeyFile, or given inline in Key (e.g. from a
+	// secret store injected as an environment variable).
+	KeyFile string
+	Key     string
+
+	// KnownHostsFile pins host keys. With AcceptNewHosts set, keys of hosts not
+	// yet in the file are added on first contact; otherwise unknown hosts are
+	// rejected.
+	KnownHostsFile string
+	AcceptNewHosts bool
+}
+
+// SSHAuth is a validated SSHConfig ready for use by Clone.
+type SSHAuth struct {
+	hosts      map[string]bool
+	keyFile    string
+	knownHosts string
+	acceptNew  bool
+}
+
+// NewSSHAuth validates cfg. An inline key is written to a private file under
+// dir. It returns nil when no SSH hosts are configured.
+func NewSSHAuth(cfg SSHConfig, dir string) (*SSHAuth, error) {
+	hosts := make(map[string]bool)
+	for _, h := range cfg.Hosts {
+		if h = strings.ToLower(strings.TrimSpace(h)); h != "" {
+			hosts[h] = true
+		}
+	}
+	if len(hosts) == 0 {
+		return nil, nil
+	}
+	if (cfg.KeyFile == "") == (cfg.Key == "") {
+		return nil, fmt.Errorf("set exactly one of the SSH key file or inline key")
+	}
+	if cfg.KnownHostsFile == "" {
+		return nil, fmt.Errorf("an SSH known_hosts file is required")
+	}
+
+	a := &SSHAuth{hosts: hosts, keyFile: cfg.KeyFile, acceptNew: cfg.AcceptNewHosts}
+	if cfg.Key != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, fmt.Errorf("create ssh dir: %w", err)
+		}
+		a.keyFile = filepath.Join(dir, "id_droid")
+		key := strings.TrimSpace(cfg.Key) + "\n" // ssh rejects keys without a trailing newline
+		if err := os.WriteFile(a.keyFile, []byte(key), 0o600); err != nil {
+			return nil, fmt.Errorf("write ssh key: %w", err)
+		}
+	}
+	if _, err := os.Stat(a.keyFile); err != nil {
+		return nil, fmt.Errorf("ssh key: %w", err)
+	}
+
+	kh, err := filepath.Abs(cfg.KnownHostsFile)
+	if err != nil {
+		return nil, fmt.Errorf("known_hosts path: %w", err)
+	}
+	if _, err := os.Stat(kh); err != nil {
+		if !os.IsNotExist(err) || !cfg.AcceptNewHosts {
+			return nil, fmt.Errorf("known_hosts: %w", err)
+		}
+		// First start with accept-new: create an empty file for ssh to fill.
+		if err := os.MkdirAll(filepath.Dir(kh), 0o700); err != nil {
+			return nil, fmt.Errorf("create known_hosts dir: %w", err)
+		}
+		if err := os.WriteFile(kh, nil, 0o600); err != nil {
+			return nil, fmt.Errorf("create known_hosts: %w", err)
+		}
+	}
+	a.knownHosts = kh
+
+	if a.keyFile, err = filepath.Abs(a.keyFile); err != nil {
+		return nil, fmt.Errorf("ssh key path: %w", err)
+	}
+	return a, nil
+}
+
+// remote returns the SSH URL and git config for repoURL, or ok=false when the
+// host should keep using HTTPS.
+func (a *SSHAuth) remote(repoURL string) (sshURL string, config []string, ok bool) {
+	if a == nil {
+		return "", nil, false
+	}
+	u, err := url.Parse(repoURL)
+	if err != nil || !a.hosts[strings.ToLower(u.Hostname())] {
+		return "", nil, false
+	}
+	path := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git") + ".git"
+
+	strict := "yes"
+	if a.acceptNew {
+		strict = "accept-new"
+	}
+	sshCommand := fmt.Sprintf("ssh -i %s -o IdentitiesOnly=yes -o UserKnownHostsFile=%s -o StrictHostKeyChecking=%s -o BatchMode=yes",
+		shellQuote(a.keyFile), shellQuote(a.knownHosts), strict)
+	// git clone -c persists core.sshCommand, so fetches and pushes use it too.
+	return fmt.Sprintf("ssh://git@%s/%s", u.Hostname(), path), []string{"-c", "core.sshCommand=" + sshCommand}, true
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
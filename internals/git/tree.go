@@ -0,0 +1,213 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// maxTreeLines bounds the rendered tree. When a listing would exceed
+	// it, deeper directories are collapsed to their file counts.
+	maxTreeLines = 300
+	// maxDirFiles bounds the files listed directly inside one directory.
+	maxDirFiles = 40
+)
+
+// Files returns the paths of the files under subdir, relative to the repo
+// root: tracked files plus untracked ones that .gitignore does not exclude.
+func (r *Repo) Files(ctx context.Context, subdir string) ([]string, error) {
+	subdir, err := r.relDir(subdir)
+	if err != nil {
+		return nil, err
+	}
+	out, err := run(ctx, r.dir, "git", "ls-files", "-z", "--cached", "--others", "--exclude-standard", "--", subdir)
+	if err != nil {
+		return nil, err
+	}
+	// --cached still lists tracked files deleted from the working tree.
+	deleted, err := run(ctx, r.dir, "git", "ls-files", "-z", "--deleted", "--", subdir)
+	if err != nil {
+		return nil, err
+	}
+	skip := make(map[string]bool)
+	for _, f := range strings.Split(deleted, "\x00") {
+		skip[f] = true
+	}
+	var files []string
+	for _, f := range strings.Split(out, "\x00") {
+		if f == "" || skip[f] {
+			continue
+		}
+		skip[f] = true // a conflicted file is listed once per stage
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// relDir cleans subdir and rejects paths outside the repository.
+func (r *Repo) relDir(subdir string) (string, error) {
+	subdir = filepath.ToSlash(filepath.Clean(strings.TrimPrefix(strings.TrimSpace(subdir), "/")))
+	if subdir == ".." || strings.HasPrefix(subdir, "../") {
+		return "", fmt.Errorf("%s is outside the repository", subdir)
+	}
+	return subdir, nil
+}
+
+// ListFiles renders the files under subdir as an indented tree, with a file
+// count on every directory. Directories deeper than depth levels below subdir
+// are collapsed to their counts; depth 0 picks the deepest level that fits.
+func (r *Repo) ListFiles(ctx context.Context, subdir string, depth int) (string, error) {
+	files, err := r.Files(ctx, subdir)
+	if err != nil {
+		return "", err
+	}
+	base, _ := r.relDir(subdir)
+	if base == "." {
+		base = ""
+	}
+	if len(files) == 0 {
+		return fmt.Sprintf("no files under %s (ignored files are not listed)", displayDir(base)), nil
+	}
+
+	root := newTreeDir()
+	for _, f := range files {
+		rel := strings.TrimPrefix(strings.TrimPrefix(f, base), "/")
+		root.add(strings.Split(rel, "/"))
+	}
+
+	if depth <= 0 {
+		depth = root.height()
+	}
+	var lines []string
+	for ; depth >= 1; depth-- {
+		lines = lines[:0]
+		root.render(&lines, "", depth)
+		if len(lines) <= maxTreeLines {
+			break
+		}
+	}
+	if depth < 1 {
+		depth = 1
+	}
+	truncated := len(lines) > maxTreeLines
+	if truncated {
+		lines = lines[:maxTreeLines]
+	}
+
+	header := fmt.Sprintf("%s under %s", plural(root.count, "file"), displayDir(base))
+	if depth < root.height() {
+		header += fmt.Sprintf("; directories below depth %d are collapsed to file counts — list a subdirectory to expand them", depth)
+	}
+	out := header + "\n" + strings.Join(lines, "\n")
+	if truncated {
+		out += "\n... (listing truncated; list a subdirectory to see the rest)"
+	}
+	return out, nil
+}
+
+func displayDir(d string) string {
+	if d == "" {
+		return "the repository root"
+	}
+	return d + "/"
+}
+
+type treeDir struct {
+	dirs  map[string]*treeDir
+	files []string
+	count int // files at any depth below
+}
+
+func newTreeDir() *treeDir {
+	return &treeDir{dirs: make(map[string]*treeDir)}
+}
+
+func (d *treeDir) add(parts []string) {
+	d.count++
+	if len(parts) == 1 {
+		d.files = append(d.files, parts[0])
+		return
+	}
+	sub, ok := d.dirs[parts[0]]
+	if !ok {
+		sub = newTreeDir()
+		d.dirs[parts[0]] = sub
+	}
+	sub.add(parts[1:])
+}
+
+// height is the number of levels needed to show every file.
+func (d *treeDir) height() int {
+	h := 1
+	for _, sub := range d.dirs {
+		h = max(h, sub.height()+1)
+	}
+	return h
+}
+
+// render appends d's entries, directories first, expanding depth levels.
+func (d *treeDir) render(lines *[]string, indent string, depth int) {
+	names := make([]string, 0, len(d.dirs))
+	for name := range d.dirs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sub := d.dirs[name]
+		*lines = append(*lines, fmt.Sprintf("%s%s/ (%s)", indent, name, plural(sub.count, "file")))
+		if depth > 1 {
+			sub.render(lines, indent+"  ", depth-1)
+		}
+	}
+	sort.Slice(d.files, func(i, j int) bool { return d.files[i] < d.files[j] })
+	for i, f := range d.files {
+		if i == maxDirFiles {
+			*lines = append(*lines, fmt.Sprintf("%s... and %s (%s)", indent, plural(len(d.files)-maxDirFiles, "more file"), extSummary(d.files[maxDirFiles:])))
+			break
+		}
+		*lines = append(*lines, indent+f)
+	}
+}
+
+// extSummary describes the extensions of files, most common first.
+func extSummary(files []string) string {
+	counts := make(map[string]int)
+	for _, f := range files {
+		ext := path.Ext(f)
+		if ext == "" {
+			ext = "no extension"
+		}
+		counts[ext]++
+	}
+	exts := make([]string, 0, len(counts))
+	for e := range counts {
+		exts = append(exts, e)
+	}
+	sort.Slice(exts, func(i, j int) bool {
+		if counts[exts[i]] != counts[exts[j]] {
+			return counts[exts[i]] > counts[exts[j]]
+		}
+		return exts[i] < exts[j]
+	})
+	parts := make([]string, 0, 3)
+	for i, e := range exts {
+		if i == 3 {
+			parts = append(parts, "…")
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%d %s", counts[e], e))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
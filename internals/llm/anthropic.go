@@ -2,10 +2,13 @@ package llm
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"math/rand/v2"
+	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
@@ -24,9 +27,17 @@ const (
 )
 
 type Client struct {
-	client    anthropic.Client
+	keys      *keyPool
+	extraKeys []string
 	model     anthropic.Model
 	maxTokens int64
+
+	baseURL   string
+	proxy     *url.URL
+	headers   [][2]string
+	tlsConfig *tls.Config
+
+	wrapTransport func(http.RoundTripper) http.RoundTripper
 }
 
 type Option func(*Client)
@@ -39,18 +50,40 @@ func WithMaxTokens(n int64) Option {
 	return func(c *Client) { c.maxTokens = n }
 }
 
+// WithAPIKeys adds keys to the pool alongside the key passed to NewClient.
+// Requests go to the least-loaded key; a key is skipped while rate limited and
+// dropped for good once the API rejects it.
+func WithAPIKeys(keys ...string) Option {
+	return func(c *Client) { c.extraKeys = append(c.extraKeys, keys...) }
+}
+
 func NewClient(apiKey string, opts ...Option) *Client {
 	c := &Client{
-		client:    anthropic.NewClient(option.WithAPIKey(apiKey)),
 		model:     DefaultModel,
 		maxTokens: DefaultMaxTokens,
 	}
 	for _, o := range opts {
 		o(c)
 	}
+	shared := c.requestOptions()
+	c.keys = newKeyPool(append([]string{apiKey}, c.extraKeys...), func(key string) anthropic.Client {
+		return anthropic.NewClient(append([]option.RequestOption{option.WithAPIKey(key)}, shared...)...)
+	})
 	return c
 }
 
+type modelKey struct{}
+
+// ContextWithModel returns a context that makes CompleteWithTools use model
+// instead of the client's configured model, e.g. for a per-repo override. An
+// empty model leaves ctx unchanged.
+func ContextWithModel(ctx context.Context, model string) context.Context {
+	if model == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, modelKey{}, anthropic.Model(model))
+}
+
 func (c *Client) CompleteWithTools(ctx context.Context, system string, messages []Message, tools []anthropic.ToolParam) (*anthropic.Message, error) {
 	apiMessages, err := toAPIMessages(messages)
 	if err != nil {
@@ -70,17 +103,36 @@ func (c *Client) CompleteWithTools(ctx context.Context, system string, messages
 		Messages:  apiMessages,
 		Tools:     toolUnions,
 	}
+	if m, ok := ctx.Value(modelKey{}).(anthropic.Model); ok {
+		params.Model = m
+	}
 
 	var resp *anthropic.Message
-	for attempt := range maxRetries {
-		resp, err = c.client.Messages.New(ctx, params)
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		key, kerr := c.keys.acquire()
+		if kerr != nil {
+			return nil, kerr
+		}
+		resp, err = key.client.Messages.New(ctx, params)
+		c.keys.release(key, err)
 		if err == nil {
+			MeterFrom(ctx).record(params.Model, resp.Usage)
 			return resp, nil
 		}
 
+		// A rejected key is dropped from the pool; try the next one without
+		// spending the retry budget.
+		if isAuthError(err) && c.keys.live() {
+			attempt--
+			continue
+		}
 		if !isRetryable(err) || attempt == maxRetries-1 {
 			return nil, fmt.Errorf("anthropic api: %w", err)
 		}
+		// The key is cooling down, but another may have quota left right now.
+		if isRateLimit(err) && c.keys.ready() {
+			continue
+		}
 
 		delay := retryDelay(attempt)
 		select {
@@ -107,6 +159,16 @@ func isRetryable(err error) bool {
 	return false
 }
 
+func isAuthError(err error) bool {
+	var apiErr *anthropic.Error
+	return errors.As(err, &apiErr) && (apiErr.StatusCode == 401 || apiErr.StatusCode == 403)
+}
+
+func isRateLimit(err error) bool {
+	var apiErr *anthropic.Error
+	return errors.As(err, &apiErr) && (apiErr.StatusCode == 429 || apiErr.StatusCode == 529)
+}
+
 // retryDelay returns an exponential backoff duration with full jitter.
 func retryDelay(attempt int) time.Duration {
 	exp := baseDelay * (1 << attempt) // 1s, 2s, 4s, 8s, ...
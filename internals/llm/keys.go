@@ -0,0 +1,193 @@
+package llm
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// defaultCooldown is how long a rate-limited key is skipped when the response
+// carries no usable retry-after header.
+const defaultCooldown = 30 * time.Second
+
+// ErrNoUsableKeys is returned when every API key in the pool has been disabled
+// after authentication failures.
+var ErrNoUsableKeys = errors.New("no usable Anthropic API keys: every key was rejected")
+
+// apiKey is one key in the pool with its own SDK client and health.
+type apiKey struct {
+	key    string
+	client anthropic.Client
+
+	inFlight      int
+	cooldownUntil time.Time
+	disabled      bool
+	requests      int
+	rateLimited   int
+	lastError     string
+}
+
+// keyPool spreads requests across API keys: least in-flight requests first,
+// round-robin among equals, skipping keys that are cooling down after a rate
+// limit and keys that failed authentication.
+type keyPool struct {
+	mu   sync.Mutex
+	keys []*apiKey
+	next int // round-robin start for tie-breaking
+}
+
+func newKeyPool(keys []string, newClient func(key string) anthropic.Client) *keyPool {
+	p := &keyPool{}
+	seen := make(map[string]bool)
+	for _, k := range keys {
+		k = strings.TrimSpace(k)
+		if k == "" || seen[k] {
+			continue
+		}
+		seen[k] = true
+		p.keys = append(p.keys, &apiKey{key: k, client: newClient(k)})
+	}
+	return p
+}
+
+// acquire picks a key for one request. When every live key is cooling down it
+// returns the one that recovers soonest rather than failing.
+func (p *keyPool) acquire() (*apiKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var best, soonest *apiKey
+	n := len(p.keys)
+	for i := range n {
+		k := p.keys[(p.next+i)%n]
+		if k.disabled {
+			continue
+		}
+		if soonest == nil || k.cooldownUntil.Before(soonest.cooldownUntil) {
+			soonest = k
+		}
+		if k.cooldownUntil.After(now) {
+			continue
+		}
+		if best == nil || k.inFlight < best.inFlight {
+			best = k
+		}
+	}
+	if best == nil {
+		best = soonest
+	}
+	if best == nil {
+		return nil, ErrNoUsableKeys
+	}
+	p.next = (p.next + 1) % n
+	best.inFlight++
+	best.requests++
+	return best, nil
+}
+
+// release returns k to the pool and records the outcome of its request.
+func (p *keyPool) release(k *apiKey, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	k.inFlight--
+	if err == nil {
+		return
+	}
+	k.lastError = err.Error()
+
+	var apiErr *anthropic.Error
+	if !errors.As(err, &apiErr) {
+		return
+	}
+	switch apiErr.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		k.disabled = true
+	case http.StatusTooManyRequests, 529:
+		k.rateLimited++
+		k.cooldownUntil = time.Now().Add(retryAfter(apiErr.Response))
+	}
+}
+
+// ready reports whether some live key can take a request right now.
+func (p *keyPool) ready() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	for _, k := range p.keys {
+		if !k.disabled && !k.cooldownUntil.After(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// live reports whether any key is still enabled.
+func (p *keyPool) live() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, k := range p.keys {
+		if !k.disabled {
+			return true
+		}
+	}
+	return false
+}
+
+func retryAfter(resp *http.Response) time.Duration {
+	if resp == nil {
+		return defaultCooldown
+	}
+	secs, err := strconv.Atoi(resp.Header.Get("retry-after"))
+	if err != nil || secs <= 0 {
+		return defaultCooldown
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// KeyHealth describes one pooled API key. The key itself is masked.
+type KeyHealth struct {
+	Key           string
+	Disabled      bool // rejected by the API; never used again
+	CoolingDown   bool
+	InFlight      int
+	Requests      int
+	RateLimited   int
+	LastError     string
+	CooldownUntil time.Time
+}
+
+// KeyHealth reports the state of every key in the pool.
+func (c *Client) KeyHealth() []KeyHealth {
+	c.keys.mu.Lock()
+	defer c.keys.mu.Unlock()
+
+	now := time.Now()
+	out := make([]KeyHealth, 0, len(c.keys.keys))
+	for _, k := range c.keys.keys {
+		out = append(out, KeyHealth{
+			Key:           maskKey(k.key),
+			Disabled:      k.disabled,
+			CoolingDown:   k.cooldownUntil.After(now),
+			InFlight:      k.inFlight,
+			Requests:      k.requests,
+			RateLimited:   k.rateLimited,
+			LastError:     k.lastError,
+			CooldownUntil: k.cooldownUntil,
+		})
+	}
+	return out
+}
+
+func maskKey(k string) string {
+	if len(k) <= 8 {
+		return "****"
+	}
+	return "…" + k[len(k)-4:]
+}
@@ -0,0 +1,118 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// Usage counts the tokens billed for LLM calls.
+type Usage struct {
+	InputTokens      int64 `json:"input_tokens"`
+	OutputTokens     int64 `json:"output_tokens"`
+	CacheReadTokens  int64 `json:"cache_read_tokens,omitempty"`
+	CacheWriteTokens int64 `json:"cache_write_tokens,omitempty"`
+}
+
+// Total is the number of tokens of every kind.
+func (u Usage) Total() int64 {
+	return u.InputTokens + u.OutputTokens + u.CacheReadTokens + u.CacheWriteTokens
+}
+
+// Price is a model's list price in USD per million tokens.
+type Price struct {
+	Input  float64
+	Output float64
+}
+
+// Prices maps model name prefixes to list prices. The longest matching prefix
+// wins. Cache reads bill at a tenth of the input price and cache writes at
+// 1.25 times it.
+var Prices = map[string]Price{
+	"claude-opus-4":     {Input: 15, Output: 75},
+	"claude-opus-4-5":   {Input: 5, Output: 25},
+	"claude-sonnet-4":   {Input: 3, Output: 15},
+	"claude-3-7-sonnet": {Input: 3, Output: 15},
+	"claude-3-5-sonnet": {Input: 3, Output: 15},
+	"claude-haiku-4":    {Input: 1, Output: 5},
+	"claude-3-5-haiku":  {Input: 0.8, Output: 4},
+}
+
+// Cost returns the list-price cost of u on model in USD, or false if the
+// model's price is unknown.
+func (u Usage) Cost(model string) (float64, bool) {
+	var price Price
+	best := -1
+	for prefix, p := range Prices {
+		if strings.HasPrefix(model, prefix) && len(prefix) > best {
+			price, best = p, len(prefix)
+		}
+	}
+	if best < 0 {
+		return 0, false
+	}
+	return (float64(u.InputTokens)*price.Input +
+		float64(u.OutputTokens)*price.Output +
+		float64(u.CacheReadTokens)*price.Input*0.1 +
+		float64(u.CacheWriteTokens)*price.Input*1.25) / 1e6, true
+}
+
+// Meter sums the usage of the calls made with a context from
+// ContextWithMeter, per model. It is safe for concurrent use.
+type Meter struct {
+	mu      sync.Mutex
+	byModel map[string]Usage
+}
+
+func NewMeter() *Meter {
+	return &Meter{byModel: make(map[string]Usage)}
+}
+
+type meterKey struct{}
+
+// ContextWithMeter returns a context that makes CompleteWithTools record its
+// usage in m.
+func ContextWithMeter(ctx context.Context, m *Meter) context.Context {
+	return context.WithValue(ctx, meterKey{}, m)
+}
+
+// MeterFrom returns the meter set by ContextWithMeter, or nil.
+func MeterFrom(ctx context.Context) *Meter {
+	m, _ := ctx.Value(meterKey{}).(*Meter)
+	return m
+}
+
+func (m *Meter) record(model anthropic.Model, u anthropic.Usage) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sum := m.byModel[string(model)]
+	sum.InputTokens += u.InputTokens
+	sum.OutputTokens += u.OutputTokens
+	sum.CacheReadTokens += u.CacheReadInputTokens
+	sum.CacheWriteTokens += u.CacheCreationInputTokens
+	m.byModel[string(model)] = sum
+}
+
+// Total returns the usage summed over every model, and its cost in USD. The
+// cost leaves out models without a known price; known is false if there
+// were any.
+func (m *Meter) Total() (usage Usage, cost float64, known bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	known = true
+	for model, u := range m.byModel {
+		usage.InputTokens += u.InputTokens
+		usage.OutputTokens += u.OutputTokens
+		usage.CacheReadTokens += u.CacheReadTokens
+		usage.CacheWriteTokens += u.CacheWriteTokens
+		c, ok := u.Cost(model)
+		cost += c
+		known = known && ok
+	}
+	return usage, cost, known
+}
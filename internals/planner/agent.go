@@ -5,12 +5,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/anthropics/anthropic-sdk-go"
 
+	"github.com/jadenj13/droid/internals/git"
 	"github.com/jadenj13/droid/internals/llm"
 	slackhandler "github.com/jadenj13/droid/internals/slack"
+	"github.com/jadenj13/droid/internals/tracker"
 )
 
 type LLM interface {
@@ -22,57 +27,192 @@ type Agent struct {
 	llm      LLM
 	factory  ProviderFactory
 	log      *slog.Logger
+	gitUsers map[string]string // Slack user ID → GitHub/GitLab username
+
+	reviewLabel   string
+	revisionLabel string
+	reviewerURL   string      // base URL of the reviewer, for review stats
+	historyLimit  int         // messages kept before older ones are summarised; 0 keeps all
+	trackers      tracker.Set // the trackers set_tracker can choose
+
+	breakdownMu sync.Mutex // serialises button presses on breakdowns, so Create runs once
+}
+
+type AgentOption func(*Agent)
+
+// WithGitUsers maps Slack user IDs to GitHub or GitLab usernames, so "I'll fix
+// it myself" can assign the issue to whoever clicked it.
+func WithGitUsers(users map[string]string) AgentOption {
+	return func(a *Agent) { a.gitUsers = users }
+}
+
+// WithReviewLabels renames the reviewer's labels the planner sets and clears
+// when a human routes a change request. They must match the reviewer's.
+func WithReviewLabels(review, revision string) AgentOption {
+	return func(a *Agent) { a.reviewLabel, a.revisionLabel = review, revision }
+}
+
+// WithReviewerURL lets "/droid stats" fetch review statistics from the
+// reviewer at baseURL.
+func WithReviewerURL(baseURL string) AgentOption {
+	return func(a *Agent) { a.reviewerURL = baseURL }
 }
 
-func NewAgent(sessions *SessionStore, llm LLM, factory ProviderFactory, log *slog.Logger) *Agent {
-	return &Agent{sessions: sessions, llm: llm, factory: factory, log: log}
+// WithTracker lets sessions file their issues in t as well, chosen per
+// session with set_tracker.
+func WithTracker(t tracker.Tracker) AgentOption {
+	return func(a *Agent) { a.trackers = append(a.trackers, t) }
 }
 
-func (a *Agent) Handle(ctx context.Context, msg slackhandler.IncomingMessage) (string, error) {
+// WithHistoryLimit summarises the oldest messages of a thread once it holds
+// more than n, keeping the most recent n/2. 0 keeps every message.
+func WithHistoryLimit(n int) AgentOption {
+	return func(a *Agent) { a.historyLimit = n }
+}
+
+func NewAgent(sessions *SessionStore, llm LLM, factory ProviderFactory, log *slog.Logger, opts ...AgentOption) *Agent {
+	a := &Agent{sessions: sessions, llm: llm, factory: factory, log: log, reviewLabel: "agent:review", revisionLabel: "agent:revision", historyLimit: defaultHistoryLimit}
+	for _, o := range opts {
+		o(a)
+	}
+	return a
+}
+
+func (a *Agent) Handle(ctx context.Context, msg slackhandler.IncomingMessage) (slackhandler.Reply, error) {
 	sess := a.sessions.GetOrCreate(msg.ThreadTS, msg.ChannelID)
+	if msg.UserID != "" && !slices.Contains(sess.Users, msg.UserID) {
+		sess.Users = append(sess.Users, msg.UserID)
+	}
 
 	if err := a.sessions.AppendMessage(sess, "user", msg.Text); err != nil {
-		return "", fmt.Errorf("append user message: %w", err)
+		return slackhandler.Reply{}, fmt.Errorf("append user message: %w", err)
 	}
+	a.compactHistory(ctx, sess)
 
 	reply, err := a.runLoop(ctx, sess)
 	if err != nil {
-		return "", err
+		return slackhandler.Reply{}, err
 	}
 
-	if err := a.sessions.AppendMessage(sess, "assistant", reply); err != nil {
-		return "", fmt.Errorf("append assistant message: %w", err)
+	if err := a.sessions.AppendMessage(sess, "assistant", reply.Text); err != nil {
+		return slackhandler.Reply{}, fmt.Errorf("append assistant message: %w", err)
 	}
 
 	return reply, nil
 }
 
-func (a *Agent) runLoop(ctx context.Context, sess *Session) (string, error) {
+// Requeue re-applies agent:ready to an issue from this thread's session so the
+// executor picks it up again.
+func (a *Agent) Requeue(ctx context.Context, threadTS string, issueNumber int) (string, error) {
+	sess, ok := a.sessions.Get(threadTS)
+	if !ok || sess.GitProvider == nil {
+		return "", fmt.Errorf("no planning session with a repository for thread %s", threadTS)
+	}
+
+	if err := a.requeue(ctx, sess.GitProvider, issueNumber); err != nil {
+		return "", err
+	}
+	a.log.Info("issue requeued", "issue", issueNumber, "thread", threadTS)
+	return fmt.Sprintf(":repeat: Requeued #%d for the executor.", issueNumber), nil
+}
+
+// Retry requeues a failed issue given its URL, or its number in the session's
+// repository. The executor keeps the failed run's transcript and includes it
+// in the retry.
+func (a *Agent) Retry(ctx context.Context, threadTS, ref string) (string, error) {
+	ref = strings.Trim(strings.TrimSpace(ref), "<>")
+	if n, err := strconv.Atoi(ref); err == nil {
+		sess, ok := a.sessions.Get(threadTS)
+		if !ok || sess.GitProvider == nil {
+			return "", fmt.Errorf("this thread has no repository — pass the full issue URL")
+		}
+		if err := a.requeue(ctx, sess.GitProvider, n); err != nil {
+			return "", err
+		}
+		a.log.Info("issue retried", "issue", n, "thread", threadTS)
+		return fmt.Sprintf(":repeat: Retrying #%d.", n), nil
+	}
+
+	repoURL, n, ok := splitIssueURL(ref)
+	if !ok {
+		return "", fmt.Errorf("%q is not an issue number or issue URL", ref)
+	}
+	provider, _, err := a.factory.ProviderFor(ctx, repoURL)
+	if err != nil {
+		return "", err
+	}
+	if err := a.requeue(ctx, provider, n); err != nil {
+		return "", err
+	}
+	a.log.Info("issue retried", "url", ref)
+	return fmt.Sprintf(":repeat: Retrying <%s|#%d>.", ref, n), nil
+}
+
+// requeue removes and re-adds agent:ready, because the executor only reacts
+// to the label being added.
+func (a *Agent) requeue(ctx context.Context, provider git.GitProvider, issueNumber int) error {
+	if err := provider.RemoveLabel(ctx, issueNumber, "agent:ready"); err != nil {
+		a.log.Warn("requeue: remove label failed", "issue", issueNumber, "err", err)
+	}
+	if err := provider.AddLabel(ctx, issueNumber, "agent:ready"); err != nil {
+		return fmt.Errorf("requeue issue #%d: %w", issueNumber, err)
+	}
+	return nil
+}
+
+// splitIssueURL splits https://host/org/repo/issues/42 (or GitLab's
+// .../-/issues/42) into the repository URL and issue number.
+func splitIssueURL(u string) (string, int, bool) {
+	repo, num, ok := strings.Cut(u, "/issues/")
+	if !ok {
+		return "", 0, false
+	}
+	n, err := strconv.Atoi(strings.Trim(num, "/"))
+	if err != nil {
+		return "", 0, false
+	}
+	return strings.TrimSuffix(repo, "/-"), n, true
+}
+
+func (a *Agent) runLoop(ctx context.Context, sess *Session) (slackhandler.Reply, error) {
 	msgs := make([]llm.Message, len(sess.Messages))
 	copy(msgs, sess.Messages)
 
+	// Tool-provided reply text is appended after the model's final answer.
+	var extra []string
+	var showBreakdown bool
+
 	const maxIter = 10 // safety limit
 	for i := range maxIter {
 		resp, err := a.llm.CompleteWithTools(ctx, systemPrompt(sess), msgs, AllTools)
 		if err != nil {
-			return "", fmt.Errorf("llm (iter %d): %w", i, err)
+			return slackhandler.Reply{}, fmt.Errorf("llm (iter %d): %w", i, err)
 		}
 
 		toolCalls := extractToolCalls(resp)
 
 		if len(toolCalls) == 0 {
-			return extractText(resp), nil
+			reply := slackhandler.Reply{Text: strings.Join(append([]string{extractText(resp)}, extra...), "\n\n")}
+			if showBreakdown && sess.Proposal != nil {
+				b := sess.Proposal.view()
+				reply.Breakdown = &b
+			}
+			return reply, nil
 		}
 
 		a.log.Info("executing tools", "count", len(toolCalls), "iter", i)
 
 		toolResults := make([]anthropic.ToolResultBlockParam, 0, len(toolCalls))
 		for _, tc := range toolCalls {
-			result, err := ExecuteTool(ctx, tc.Name, tc.Input, sess, a.factory)
+			result, err := ExecuteTool(ctx, tc.Name, tc.Input, sess, a.factory, a.trackers, a.log)
 			if err != nil {
-				return "", fmt.Errorf("execute tool %q: %w", tc.Name, err)
+				return slackhandler.Reply{}, fmt.Errorf("execute tool %q: %w", tc.Name, err)
 			}
 			a.log.Info("tool executed", "tool", tc.Name, "result", result.Content)
+			if result.Reply != "" {
+				extra = append(extra, result.Reply)
+			}
+			showBreakdown = showBreakdown || result.ShowBreakdown
 			toolResults = append(toolResults, anthropic.ToolResultBlockParam{
 				ToolUseID: tc.ID,
 				Content: []anthropic.ToolResultBlockParamContentUnion{
@@ -81,13 +221,19 @@ func (a *Agent) runLoop(ctx context.Context, sess *Session) (string, error) {
 			})
 		}
 
+		// Keep what the tools produced — a PRD, criteria, issues, the stage —
+		// even if a later step of this turn fails.
+		if err := a.sessions.Save(sess); err != nil {
+			a.log.Warn("could not save session after tools", "thread", sess.ThreadTS, "err", err)
+		}
+
 		msgs = append(msgs,
 			llm.Message{Role: "assistant", Content: marshalBlocks(resp.Content)},
 			llm.Message{Role: "tool_result", RawBlocks: toolResults},
 		)
 	}
 
-	return "", fmt.Errorf("tool loop exceeded %d iterations", maxIter)
+	return slackhandler.Reply{}, fmt.Errorf("tool loop exceeded %d iterations", maxIter)
 }
 
 type toolCall struct {
@@ -146,9 +292,10 @@ Guidelines:
 - Ask clarifying questions before writing any documents.
 - Be concise in Slack — use bullet points, avoid walls of text.
 - When writing PRDs or acceptance criteria, be specific and testable.
-- Only move to the next stage when the user confirms they're happy.
+- Only move to the next stage when the user confirms they're happy, and then call set_stage.
+  When the user asks to go to a stage ("move to PRD", "back to brainstorming"), call set_stage too.
 - When creating issues, make each one small enough for a single engineer to complete in a day or two.
-- Always include the 'agent:ready' label when creating issues.
+- Always include the 'agent:ready' label when creating issues, plus one type label — 'bug', 'feature' or 'refactor' — which decides how the executor approaches the work.
 `, repoLine)
 	switch sess.Stage {
 	case StageBrainstorm:
@@ -159,7 +306,7 @@ Help the user articulate what they're building and why. Ask about:
 - Who the users are
 - What success looks like
 - Any known constraints or dependencies
-When you have enough context, suggest moving to writing the PRD.`
+When you have enough context, suggest moving to writing the PRD; once the user agrees, call set_stage with prd.`
 
 	case StagePRD:
 		base += `
@@ -171,40 +318,104 @@ Write a structured PRD with these sections:
 - User Stories
 - Technical Approach (high level)
 - Open Questions
-Present it in full, then ask the user for feedback.`
+Save it with update_prd (one markdown heading per section), present it in full, then ask for feedback.
+For targeted edits like "change the Goals section to …" use revise_prd_section rather than
+rewriting the whole document. The user sees a diff of every revision automatically, so
+don't repeat the full PRD after a revision. If the user wants an earlier draft back, use revert_prd.
+Once the user is happy with the PRD, offer to publish it to the repository with publish_prd, so it
+lives next to the code and the issues link to it, then call set_stage with criteria.`
 
 	case StageCriteria:
 		base += `
 Current stage: ACCEPTANCE CRITERIA
 Based on the PRD, write clear, testable acceptance criteria.
 Format each as: "Given [context], when [action], then [outcome]".
-Group them by feature area if there are many.`
+Group them by feature area if there are many.
+Save them with save_criteria whenever you write or revise them, then present them and ask for feedback.
+Once the user is happy, call set_stage with issues.`
 
 	case StageIssues:
 		base += `
 Current stage: ISSUE BREAKDOWN
-Break the work into GitHub issues. For each issue:
-- Present the full list to the user first and ask for approval.
-- Only call create_issue AFTER the user says they're happy with the breakdown.
-- Call create_issue once per issue, not in bulk.
-- Call finish_planning after all issues are created.`
+Break the work into issues in the session's repository. For each issue:
+- Call propose_issues with the whole breakdown instead of listing it in your reply. It is posted
+  with Approve, Edit and Remove buttons, and the approved issues are created when the user presses
+  Create; do not ask for approval in text or create them yourself.
+- If the user asks for changes in the conversation, call propose_issues again with the full revised list.
+- Use create_issue only for an extra issue once the breakdown is done, one issue per call.
+- To change an issue already created, use update_issue; to drop one, use close_issue.
+- List issues in dependency order, every issue after the ones it builds on, and set depends_on to
+  their positions in the list (or, for create_issue, their numbers). Issues with open dependencies
+  are labeled agent:blocked for you, and the executor starts them once their dependencies close; do
+  not add that label yourself.
+- Call finish_planning after all issues are created. Ask the user whether they want a tracking issue
+  that aggregates the PRD summary, a task list of the issues, and a dependency graph. Pass the key
+  decisions made during planning and the reasoning behind them as key_decisions; they are recorded
+  on the tracking issue together with a transcript of this conversation.`
 
 	case StageDone:
 		base += `
 Current stage: DONE
-All issues have been created. Help the user review or answer questions.`
+All issues have been created. Help the user review or answer questions. If the plan changes, use
+update_issue to retitle or re-scope an issue and close_issue to retract one the plan no longer
+needs, after confirming with the user, rather than leaving stale issues.`
 	}
 
 	if sess.PRDDraft != "" {
 		base += "\n\nCurrent PRD draft:\n" + sess.PRDDraft
 	}
+	if p := sess.PublishedPRD; p != nil {
+		base += fmt.Sprintf("\n\nPRD v%d is published as %s (%s).", p.Version, p.Path, p.PRURL)
+		if p.Version < len(sess.PRDVersions) {
+			base += " The draft has changed since; call publish_prd again to update it."
+		}
+	}
+
+	if t := sess.Tracker; t != nil {
+		project := t.Project
+		if project == "" {
+			project = "the default project"
+		}
+		base += fmt.Sprintf("\n\nIssues are also filed as %s tickets in %s; create_issue does this for you.", t.Name, project)
+	}
+
+	if len(sess.Criteria) > 0 {
+		base += "\n\nSaved acceptance criteria:"
+		for _, c := range sess.Criteria {
+			base += "\n- " + c
+		}
+	}
+
+	if len(sess.PRDVersions) > 0 {
+		base += "\n\nPRD history:"
+		for _, v := range sess.PRDVersions {
+			base += fmt.Sprintf("\n- v%d: %s", v.Number, v.Note)
+		}
+	}
+
+	if p := sess.Proposal; p != nil && !p.Done {
+		base += "\n\nProposed breakdown awaiting approval in Slack:"
+		for _, it := range p.Items {
+			base += fmt.Sprintf("\n- %d. %s (%s)", it.Key, it.Input.Title, it.Status)
+		}
+	}
 
 	if len(sess.Issues) > 0 {
 		base += "\n\nIssues created so far:"
 		for _, iss := range sess.Issues {
 			base += fmt.Sprintf("\n- #%d %s (%s)", iss.Number, iss.Title, iss.URL)
+			if iss.Ticket != "" {
+				base += ", ticket " + iss.Ticket
+			}
+			if iss.Closed {
+				base += " — closed"
+			}
 		}
 	}
 
+	if sess.Summary != "" {
+		base += "\n\nSummary of the earlier conversation (its messages are no longer shown):\n" + sess.Summary
+	}
+
 	return base
 }
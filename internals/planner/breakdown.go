@@ -0,0 +1,288 @@
+package planner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+
+	slackhandler "github.com/jadenj13/droid/internals/slack"
+)
+
+// maxProposedIssues keeps a breakdown within Slack's 50 blocks per message.
+const maxProposedIssues = 15
+
+// Proposal is an issue breakdown posted for approval. Its issues are created
+// only when someone presses Create, and only those approved.
+type Proposal struct {
+	Round int // 1 for the thread's first breakdown; a new one retires the old buttons
+	Items []ProposedIssue
+	Done  bool // nothing is left to approve or create
+}
+
+// ProposedIssue is one issue of a breakdown. Input.DependsOn holds keys of
+// other items until the issue is created.
+type ProposedIssue struct {
+	Key    int
+	Input  createIssueInput
+	Status slackhandler.ItemStatus
+	Number int    // set once created
+	URL    string // set once created
+	Error  string // why creating it failed
+}
+
+var toolProposeIssues = anthropic.ToolParam{
+	Name:        "propose_issues",
+	Description: anthropic.String("Posts the full issue breakdown in Slack with Approve, Edit and Remove buttons on each issue. Nothing is created yet: when the user presses Create approved issues, exactly the approved ones are created, in order. Call it again with the whole revised list if the user asks for changes in the conversation."),
+	InputSchema: anthropic.ToolInputSchemaParam{
+		Properties: map[string]interface{}{
+			"issues": map[string]interface{}{
+				"type":        "array",
+				"description": fmt.Sprintf("The issues, at most %d, in dependency order: every issue after the ones it builds on.", maxProposedIssues),
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"title":               map[string]interface{}{"type": "string", "description": "Short, action-oriented issue title."},
+						"description":         map[string]interface{}{"type": "string", "description": "Detailed description of what needs to be done, including technical context."},
+						"acceptance_criteria": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "Testable acceptance criteria for this issue."},
+						"labels":              map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "Labels to apply. Always include 'agent:ready' and exactly one type label: 'bug', 'feature' or 'refactor'."},
+						"depends_on":          map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "integer"}, "description": "1-based positions in this list of the issues this one builds on; they must come before it."},
+						"also_repos":          map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "URLs of other repositories this issue must change in the same piece of work. Omit for single-repository issues."},
+						"base_branch":         map[string]interface{}{"type": "string", "description": "Branch the work must start from and the PR must target. Omit to use the default branch."},
+					},
+					"required": []string{"title", "description", "acceptance_criteria", "labels"},
+				},
+			},
+		},
+		Required: []string{"issues"},
+	},
+}
+
+type proposeIssuesInput struct {
+	Issues []createIssueInput `json:"issues"`
+}
+
+func execProposeIssues(raw json.RawMessage, sess *Session) (ToolResult, error) {
+	var input proposeIssuesInput
+	if err := json.Unmarshal(raw, &input); err != nil {
+		return ToolResult{}, fmt.Errorf("unmarshal propose_issues: %w", err)
+	}
+	if sess.GitProvider == nil {
+		return ToolResult{Content: "error: no repository configured — ask the user for a repo URL first"}, nil
+	}
+	if len(input.Issues) == 0 || len(input.Issues) > maxProposedIssues {
+		return ToolResult{Content: fmt.Sprintf("error: propose between 1 and %d issues, got %d. Split a larger plan into rounds", maxProposedIssues, len(input.Issues))}, nil
+	}
+
+	round := 1
+	if sess.Proposal != nil {
+		round = sess.Proposal.Round + 1
+	}
+	p := &Proposal{Round: round}
+	for i, in := range input.Issues {
+		key := i + 1
+		if strings.TrimSpace(in.Title) == "" {
+			return ToolResult{Content: fmt.Sprintf("error: issue %d has no title", key)}, nil
+		}
+		for _, d := range in.DependsOn {
+			if d < 1 || d >= key {
+				return ToolResult{Content: fmt.Sprintf("error: issue %d depends on %d, which is not an earlier issue in the list. List issues in dependency order and refer to them by position", key, d)}, nil
+			}
+		}
+		p.Items = append(p.Items, ProposedIssue{Key: key, Input: in, Status: slackhandler.ItemPending})
+	}
+	sess.Proposal = p
+	sess.advanceTo(StageIssues)
+
+	return ToolResult{
+		Content:       fmt.Sprintf("Posted the breakdown of %d issues with approval buttons. Briefly tell the user to approve, edit or remove each issue and then press Create approved issues; do not list the issues again or call create_issue for them.", len(p.Items)),
+		ShowBreakdown: true,
+	}, nil
+}
+
+// view renders the proposal for Slack.
+func (p *Proposal) view() slackhandler.Breakdown {
+	b := slackhandler.Breakdown{Round: p.Round, Done: p.Done}
+	for _, it := range p.Items {
+		b.Items = append(b.Items, slackhandler.BreakdownItem{
+			Key:                it.Key,
+			Title:              it.Input.Title,
+			Description:        it.Input.Description,
+			AcceptanceCriteria: it.Input.AcceptanceCriteria,
+			DependsOn:          it.Input.DependsOn,
+			Status:             it.Status,
+			IssueURL:           it.URL,
+		})
+	}
+	return b
+}
+
+// item returns the proposal's item with key.
+func (p *Proposal) item(key int) (*ProposedIssue, bool) {
+	if key < 1 || key > len(p.Items) {
+		return nil, false
+	}
+	return &p.Items[key-1], true
+}
+
+// proposal returns the session and breakdown ref's button belongs to, or an
+// error when the breakdown was replaced since.
+func (a *Agent) proposal(ref slackhandler.BreakdownRef) (*Session, *Proposal, error) {
+	sess, ok := a.sessions.Get(ref.ThreadTS)
+	if !ok || sess.Proposal == nil {
+		return nil, nil, fmt.Errorf("no issue breakdown in this thread")
+	}
+	if sess.Proposal.Round != ref.Round {
+		return nil, nil, fmt.Errorf("this breakdown was replaced by a newer one further down the thread")
+	}
+	return sess, sess.Proposal, nil
+}
+
+// ReviewBreakdown approves or removes a proposed issue, or creates the
+// approved ones.
+func (a *Agent) ReviewBreakdown(ctx context.Context, ref slackhandler.BreakdownRef, choice slackhandler.BreakdownChoice, userID string) (slackhandler.Breakdown, string, error) {
+	a.breakdownMu.Lock()
+	defer a.breakdownMu.Unlock()
+	sess, p, err := a.proposal(ref)
+	if err != nil {
+		return slackhandler.Breakdown{}, "", err
+	}
+	if choice == slackhandler.BreakdownCreate {
+		reply, err := a.createApproved(ctx, sess, p)
+		if err != nil {
+			return slackhandler.Breakdown{}, "", err
+		}
+		a.log.Info("breakdown issues created", "thread", sess.ThreadTS, "user", userID)
+		return p.view(), reply, nil
+	}
+
+	it, ok := p.item(ref.Key)
+	if !ok {
+		return slackhandler.Breakdown{}, "", fmt.Errorf("no issue %d in the breakdown", ref.Key)
+	}
+	if it.Status == slackhandler.ItemCreated {
+		return slackhandler.Breakdown{}, "", fmt.Errorf("issue %d was already created", ref.Key)
+	}
+	switch choice {
+	case slackhandler.BreakdownApprove:
+		it.Status = slackhandler.ItemApproved
+	case slackhandler.BreakdownRemove:
+		it.Status = slackhandler.ItemRemoved
+	default:
+		return slackhandler.Breakdown{}, "", fmt.Errorf("unknown breakdown action %q", choice)
+	}
+	if err := a.sessions.Save(sess); err != nil {
+		return slackhandler.Breakdown{}, "", fmt.Errorf("save session: %w", err)
+	}
+	a.log.Info("breakdown item reviewed", "thread", sess.ThreadTS, "key", ref.Key, "status", it.Status, "user", userID)
+	return p.view(), "", nil
+}
+
+// BreakdownItem returns a proposed issue for the edit dialog.
+func (a *Agent) BreakdownItem(ctx context.Context, ref slackhandler.BreakdownRef) (slackhandler.BreakdownItem, error) {
+	_, p, err := a.proposal(ref)
+	if err != nil {
+		return slackhandler.BreakdownItem{}, err
+	}
+	if _, ok := p.item(ref.Key); !ok {
+		return slackhandler.BreakdownItem{}, fmt.Errorf("no issue %d in the breakdown", ref.Key)
+	}
+	return p.view().Items[ref.Key-1], nil
+}
+
+// EditBreakdown replaces a proposed issue's title, description and acceptance
+// criteria with what the user wrote in the edit dialog.
+func (a *Agent) EditBreakdown(ctx context.Context, ref slackhandler.BreakdownRef, edit slackhandler.ItemEdit, userID string) (slackhandler.Breakdown, error) {
+	a.breakdownMu.Lock()
+	defer a.breakdownMu.Unlock()
+	sess, p, err := a.proposal(ref)
+	if err != nil {
+		return slackhandler.Breakdown{}, err
+	}
+	it, ok := p.item(ref.Key)
+	if !ok {
+		return slackhandler.Breakdown{}, fmt.Errorf("no issue %d in the breakdown", ref.Key)
+	}
+	if it.Status == slackhandler.ItemCreated {
+		return slackhandler.Breakdown{}, fmt.Errorf("issue %d was already created; ask the planner to update it instead", ref.Key)
+	}
+	if edit.Title == "" {
+		return slackhandler.Breakdown{}, fmt.Errorf("the title cannot be empty")
+	}
+	it.Input.Title, it.Input.Description, it.Input.AcceptanceCriteria = edit.Title, edit.Description, edit.AcceptanceCriteria
+	if err := a.sessions.Save(sess); err != nil {
+		return slackhandler.Breakdown{}, fmt.Errorf("save session: %w", err)
+	}
+	a.log.Info("breakdown item edited", "thread", sess.ThreadTS, "key", ref.Key, "user", userID)
+	return p.view(), nil
+}
+
+// createApproved creates the approved issues in order, pointing their
+// dependencies at the issues created for earlier items. An item whose
+// dependency was not created is not created either. It returns a summary
+// for the thread.
+func (a *Agent) createApproved(ctx context.Context, sess *Session, p *Proposal) (string, error) {
+	if sess.GitProvider == nil {
+		return "", fmt.Errorf("this thread has no repository")
+	}
+	var created, failed []string
+	pending := 0
+	for i := range p.Items {
+		it := &p.Items[i]
+		switch it.Status {
+		case slackhandler.ItemPending:
+			pending++
+			continue
+		case slackhandler.ItemApproved, slackhandler.ItemFailed:
+		default:
+			continue
+		}
+
+		input := it.Input
+		input.DependsOn = nil
+		it.Error = ""
+		for _, k := range it.Input.DependsOn {
+			dep, _ := p.item(k)
+			switch dep.Status {
+			case slackhandler.ItemCreated:
+				input.DependsOn = append(input.DependsOn, dep.Number)
+			case slackhandler.ItemRemoved:
+				// The dependency was dropped from the plan.
+			default:
+				it.Error = fmt.Sprintf("it depends on %d, which was not created", k)
+			}
+		}
+		if it.Error == "" {
+			linked, _, errMsg := createIssue(ctx, sess, a.trackers, input, a.log)
+			it.Number, it.URL, it.Error = linked.Number, linked.URL, errMsg
+		}
+		if it.Error != "" {
+			it.Status = slackhandler.ItemFailed
+			failed = append(failed, fmt.Sprintf("%d (%s)", it.Key, it.Error))
+			continue
+		}
+		it.Status = slackhandler.ItemCreated
+		created = append(created, fmt.Sprintf("<%s|#%d>", it.URL, it.Number))
+	}
+	if len(created) == 0 && len(failed) == 0 {
+		return "", fmt.Errorf("approve at least one issue first")
+	}
+	p.Done = pending == 0 && len(failed) == 0
+	if err := a.sessions.Save(sess); err != nil {
+		a.log.Warn("could not save session after creating issues", "thread", sess.ThreadTS, "err", err)
+	}
+
+	var sb strings.Builder
+	if len(created) > 0 {
+		sb.WriteString(":white_check_mark: Created " + strings.Join(created, ", ") + ".")
+	}
+	if len(failed) > 0 {
+		sb.WriteString("\n:warning: Not created: " + strings.Join(failed, "; ") + ". Press Create again to retry.")
+	}
+	if pending > 0 {
+		sb.WriteString(fmt.Sprintf("\n%d issues are still awaiting approval.", pending))
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
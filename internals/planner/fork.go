@@ -0,0 +1,92 @@
+package planner
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jadenj13/droid/internals/llm"
+)
+
+// Fork copies the session in parentTS into a new session keyed by forkTS, so
+// the user can explore an alternative in a separate thread without touching
+// the original draft.
+func (a *Agent) Fork(ctx context.Context, parentTS, forkTS, channelID string) (string, error) {
+	parent, ok := a.sessions.Get(parentTS)
+	if !ok {
+		return "", fmt.Errorf("no planning session for thread %s", parentTS)
+	}
+
+	fork := parent.clone(forkTS, channelID)
+	if err := a.sessions.Save(fork); err != nil {
+		return "", fmt.Errorf("save fork: %w", err)
+	}
+
+	a.log.Info("session forked", "parent", parentTS, "fork", forkTS)
+	return fmt.Sprintf(":twisted_rightwards_arrows: This thread is a fork of an existing plan (stage: %s). "+
+		"Explore the alternative here — the original thread is unchanged. "+
+		"Say `/droid merge` when you're done to bring the conclusions back.", fork.Stage), nil
+}
+
+// Merge summarises what was concluded in the fork at forkTS and records it in
+// the parent session. A PRD draft or acceptance criteria revised in the fork
+// replace the parent's. It returns the parent thread and the message to post
+// there.
+func (a *Agent) Merge(ctx context.Context, forkTS string) (string, string, error) {
+	fork, ok := a.sessions.Get(forkTS)
+	if !ok || fork.ForkedFrom == "" {
+		return "", "", fmt.Errorf("thread %s is not a forked planning session", forkTS)
+	}
+	parent, ok := a.sessions.Get(fork.ForkedFrom)
+	if !ok {
+		return "", "", fmt.Errorf("parent session %s no longer exists", fork.ForkedFrom)
+	}
+
+	summary, err := a.summariseFork(ctx, fork)
+	if err != nil {
+		return "", "", err
+	}
+
+	var adopted []string
+	if fork.PRDDraft != "" && fork.PRDDraft != parent.PRDDraft {
+		parent.PRDDraft = fork.PRDDraft
+		adopted = append(adopted, "PRD draft")
+	}
+	if len(fork.Criteria) > 0 && !slices.Equal(fork.Criteria, parent.Criteria) {
+		parent.Criteria = append([]string(nil), fork.Criteria...)
+		adopted = append(adopted, "acceptance criteria")
+	}
+
+	note := "Conclusions merged back from a forked thread:\n" + summary
+	if len(adopted) > 0 {
+		note += "\n\nThe fork's " + strings.Join(adopted, " and ") + " replaced the current version."
+	}
+	if err := a.sessions.AppendMessage(parent, "user", note); err != nil {
+		return "", "", fmt.Errorf("append merge note: %w", err)
+	}
+	if err := a.sessions.AppendMessage(parent, "assistant", "Got it — I've taken the fork's conclusions into account."); err != nil {
+		return "", "", fmt.Errorf("append merge ack: %w", err)
+	}
+
+	a.log.Info("fork merged", "parent", parent.ThreadTS, "fork", forkTS, "adopted", adopted)
+	return parent.ThreadTS, ":leftwards_arrow_with_hook: " + note, nil
+}
+
+func (a *Agent) summariseFork(ctx context.Context, fork *Session) (string, error) {
+	// Only the messages added after the fork point carry new conclusions.
+	msgs := append([]llm.Message(nil), fork.Messages[fork.forkPoint:]...)
+	if len(msgs) == 0 {
+		return "No further discussion took place in the fork.", nil
+	}
+	msgs = append(msgs, llm.Message{
+		Role:    "user",
+		Content: "Summarise the decisions and conclusions reached in this conversation as a few concise bullet points. Include rejected alternatives and why they were rejected.",
+	})
+
+	resp, err := a.llm.CompleteWithTools(ctx, "You summarise planning discussions for a Slack thread.", msgs, nil)
+	if err != nil {
+		return "", fmt.Errorf("summarise fork: %w", err)
+	}
+	return extractText(resp), nil
+}
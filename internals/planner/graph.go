@@ -0,0 +1,48 @@
+package planner
+
+import (
+	"fmt"
+	"strings"
+)
+
+// dependencyGraph renders the session's issues as a Mermaid flowchart. Edges
+// point from a dependency to the issue that depends on it, so the graph reads
+// in execution order. Issues closed by close_issue are left out.
+func dependencyGraph(issues []LinkedIssue) string {
+	issues = openIssues(issues)
+	known := make(map[int]bool, len(issues))
+	for _, iss := range issues {
+		known[iss.Number] = true
+	}
+
+	var sb strings.Builder
+	sb.WriteString("```mermaid\ngraph TD\n")
+	for _, iss := range issues {
+		sb.WriteString(fmt.Sprintf("  I%d[\"#%d %s\"]\n", iss.Number, iss.Number, mermaidEscape(iss.Title)))
+	}
+	for _, iss := range issues {
+		for _, dep := range iss.DependsOn {
+			if !known[dep] {
+				continue // dependency outside this plan — nothing to draw
+			}
+			sb.WriteString(fmt.Sprintf("  I%d --> I%d\n", dep, iss.Number))
+		}
+	}
+	sb.WriteString("```")
+	return sb.String()
+}
+
+// openIssues returns the issues not closed by close_issue.
+func openIssues(issues []LinkedIssue) []LinkedIssue {
+	var out []LinkedIssue
+	for _, iss := range issues {
+		if !iss.Closed {
+			out = append(out, iss)
+		}
+	}
+	return out
+}
+
+func mermaidEscape(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
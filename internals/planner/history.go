@@ -0,0 +1,59 @@
+package planner
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jadenj13/droid/internals/llm"
+)
+
+// defaultHistoryLimit is how many messages a thread keeps before the oldest
+// are summarised.
+const defaultHistoryLimit = 40
+
+// compactHistory keeps a session's conversation within the history window.
+// When it holds more than historyLimit messages, all but the most recent
+// half are summarised into Summary — together with the summary of earlier
+// compactions — and dropped, so neither the prompt nor the stored session
+// grows without bound. The window always starts with a user message.
+func (a *Agent) compactHistory(ctx context.Context, sess *Session) {
+	if a.historyLimit <= 0 || len(sess.Messages) <= a.historyLimit {
+		return
+	}
+	cut := len(sess.Messages) - a.historyLimit/2
+	for cut < len(sess.Messages) && sess.Messages[cut].Role != "user" {
+		cut++
+	}
+	if cut == len(sess.Messages) {
+		return
+	}
+
+	summary, err := a.summariseHistory(ctx, sess.Summary, sess.Messages[:cut])
+	if err != nil {
+		// The full history still works; it is compacted on a later message.
+		a.log.Warn("could not summarise planning history", "thread", sess.ThreadTS, "err", err)
+		return
+	}
+	sess.Summary = summary
+	sess.Messages = append([]llm.Message(nil), sess.Messages[cut:]...)
+	sess.forkPoint = max(0, sess.forkPoint-cut)
+	if err := a.sessions.Save(sess); err != nil {
+		a.log.Warn("could not save compacted planning session", "thread", sess.ThreadTS, "err", err)
+	}
+	a.log.Info("planning history compacted", "thread", sess.ThreadTS, "dropped", cut, "kept", len(sess.Messages))
+}
+
+func (a *Agent) summariseHistory(ctx context.Context, earlier string, msgs []llm.Message) (string, error) {
+	content := "Summarise this planning conversation so it can continue without it. Keep every decision, requirement, constraint, open question and anything the user asked to remember; say which stage the plan reached. Be concise: bullet points, no preamble.\n\n"
+	if earlier != "" {
+		content += "## Summary of the conversation before this part\n\n" + earlier + "\n\n"
+	}
+	content += "## Conversation\n\n" + buildTranscript(msgs)
+
+	resp, err := a.llm.CompleteWithTools(ctx, "You summarise planning discussions for a Slack thread.",
+		[]llm.Message{{Role: "user", Content: content}}, nil)
+	if err != nil {
+		return "", fmt.Errorf("summarise history: %w", err)
+	}
+	return extractText(resp), nil
+}
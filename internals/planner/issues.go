@@ -0,0 +1,264 @@
+package planner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+
+	"github.com/jadenj13/droid/internals/tracker"
+)
+
+// retractMarker identifies the planner's comment on an issue it closed.
+const retractMarker = "<!-- droid:planner-retracted -->"
+
+var toolUpdateIssue = anthropic.ToolParam{
+	Name:        "update_issue",
+	Description: anthropic.String("Changes an issue created earlier in this thread when the plan changes: retitle it, re-scope its description or acceptance criteria, or change what it depends on. Only the fields given are changed. Confirm the change with the user first."),
+	InputSchema: anthropic.ToolInputSchemaParam{
+		Properties: map[string]interface{}{
+			"number": map[string]interface{}{
+				"type":        "integer",
+				"description": "Number of the issue to change.",
+			},
+			"title": map[string]interface{}{
+				"type":        "string",
+				"description": "New title.",
+			},
+			"description": map[string]interface{}{
+				"type":        "string",
+				"description": "New description, replacing the whole Description section.",
+			},
+			"acceptance_criteria": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "New acceptance criteria, replacing all of the current ones.",
+			},
+			"depends_on": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "integer"},
+				"description": "New list of issues this one depends on, replacing the current one. Pass an empty list to remove all dependencies.",
+			},
+		},
+		Required: []string{"number"},
+	},
+}
+
+var toolCloseIssue = anthropic.ToolParam{
+	Name:        "close_issue",
+	Description: anthropic.String("Closes an issue created earlier in this thread that the plan no longer needs, with the reason as a comment on it. Confirm with the user first."),
+	InputSchema: anthropic.ToolInputSchemaParam{
+		Properties: map[string]interface{}{
+			"number": map[string]interface{}{
+				"type":        "integer",
+				"description": "Number of the issue to close.",
+			},
+			"reason": map[string]interface{}{
+				"type":        "string",
+				"description": "Why the issue is no longer needed, e.g. 'Folded into #14' or 'Dropped from scope: …'.",
+			},
+		},
+		Required: []string{"number", "reason"},
+	},
+}
+
+type updateIssueInput struct {
+	Number             int      `json:"number"`
+	Title              string   `json:"title"`
+	Description        string   `json:"description"`
+	AcceptanceCriteria []string `json:"acceptance_criteria"`
+	DependsOn          *[]int   `json:"depends_on"`
+}
+
+type closeIssueInput struct {
+	Number int    `json:"number"`
+	Reason string `json:"reason"`
+}
+
+// sessionIssue returns the issue numbered n from the session's issues. The
+// tools only change issues the thread created, so a conversation cannot
+// rewrite unrelated work.
+func sessionIssue(sess *Session, n int) (*LinkedIssue, string) {
+	if sess.GitProvider == nil {
+		return nil, "no repository configured — ask the user for a repo URL first"
+	}
+	i := slices.IndexFunc(sess.Issues, func(l LinkedIssue) bool { return l.Number == n })
+	if i < 0 {
+		return nil, fmt.Sprintf("#%d was not created in this planning thread; only its own issues can be changed", n)
+	}
+	if sess.Issues[i].Closed {
+		return nil, fmt.Sprintf("#%d is already closed", n)
+	}
+	return &sess.Issues[i], ""
+}
+
+func execUpdateIssue(ctx context.Context, raw json.RawMessage, sess *Session) (ToolResult, error) {
+	var input updateIssueInput
+	if err := json.Unmarshal(raw, &input); err != nil {
+		return ToolResult{}, fmt.Errorf("unmarshal update_issue: %w", err)
+	}
+	linked, errMsg := sessionIssue(sess, input.Number)
+	if errMsg != "" {
+		return ToolResult{Content: "error: " + errMsg}, nil
+	}
+	title := strings.TrimSpace(input.Title)
+	if title == "" && input.Description == "" && input.AcceptanceCriteria == nil && input.DependsOn == nil {
+		return ToolResult{Content: "error: nothing to change — pass a title, description, acceptance_criteria or depends_on"}, nil
+	}
+
+	var blocked bool
+	if input.DependsOn != nil {
+		if slices.Contains(*input.DependsOn, input.Number) {
+			return ToolResult{Content: "error: an issue cannot depend on itself"}, nil
+		}
+		if blocked, errMsg = checkDependencies(ctx, sess, *input.DependsOn); errMsg != "" {
+			return ToolResult{Content: "error: " + errMsg}, nil
+		}
+	}
+
+	var changed []string
+	if title != "" && title != linked.Title {
+		if err := sess.GitProvider.UpdateIssueTitle(ctx, linked.Number, title); err != nil {
+			return ToolResult{Content: fmt.Sprintf("error updating issue: %s", err)}, nil
+		}
+		linked.Title = title
+		changed = append(changed, "title")
+	}
+
+	if input.Description != "" || input.AcceptanceCriteria != nil || input.DependsOn != nil {
+		issue, err := sess.GitProvider.GetIssue(ctx, linked.Number)
+		if err != nil {
+			return ToolResult{Content: fmt.Sprintf("error fetching issue: %s", err)}, nil
+		}
+		body := issue.Body
+		if input.Description != "" {
+			body = replaceIssueSection(body, "Description", input.Description+"\n")
+			changed = append(changed, "description")
+		}
+		if input.AcceptanceCriteria != nil {
+			var ac strings.Builder
+			for _, c := range input.AcceptanceCriteria {
+				ac.WriteString(fmt.Sprintf("- [ ] %s\n", c))
+			}
+			body = replaceIssueSection(body, "Acceptance Criteria", ac.String())
+			changed = append(changed, "acceptance criteria")
+		}
+		if input.DependsOn != nil {
+			body = replaceIssueSection(body, "Depends On", dependsOnSection(*input.DependsOn))
+			changed = append(changed, "dependencies")
+		}
+		if err := sess.GitProvider.UpdateIssueBody(ctx, linked.Number, body); err != nil {
+			return ToolResult{Content: fmt.Sprintf("error updating issue: %s", err)}, nil
+		}
+
+		if input.DependsOn != nil {
+			linked.DependsOn = *input.DependsOn
+			switch {
+			case blocked && !issue.HasLabel(blockedLabel):
+				err = sess.GitProvider.AddLabel(ctx, linked.Number, blockedLabel)
+			case !blocked && issue.HasLabel(blockedLabel):
+				err = sess.GitProvider.RemoveLabel(ctx, linked.Number, blockedLabel)
+			}
+			if err != nil {
+				return ToolResult{Content: fmt.Sprintf("Updated #%d's %s, but changing its %s label failed: %s", linked.Number, strings.Join(changed, ", "), blockedLabel, err)}, nil
+			}
+		}
+	}
+
+	return ToolResult{Content: fmt.Sprintf("Updated #%d's %s.\n%s", linked.Number, strings.Join(changed, ", "), linked.URL)}, nil
+}
+
+func execCloseIssue(ctx context.Context, raw json.RawMessage, sess *Session) (ToolResult, error) {
+	var input closeIssueInput
+	if err := json.Unmarshal(raw, &input); err != nil {
+		return ToolResult{}, fmt.Errorf("unmarshal close_issue: %w", err)
+	}
+	linked, errMsg := sessionIssue(sess, input.Number)
+	if errMsg != "" {
+		return ToolResult{Content: "error: " + errMsg}, nil
+	}
+
+	note := fmt.Sprintf(":wastebasket: **Closed by the planner.** %s\n\n%s", strings.TrimSpace(input.Reason), retractMarker)
+	if err := sess.GitProvider.UpsertMarkedIssueComment(ctx, linked.Number, retractMarker, note); err != nil {
+		return ToolResult{Content: fmt.Sprintf("error commenting on issue: %s", err)}, nil
+	}
+	if err := sess.GitProvider.CloseIssue(ctx, linked.Number); err != nil {
+		return ToolResult{Content: fmt.Sprintf("error closing issue: %s", err)}, nil
+	}
+	linked.Closed = true
+
+	content := fmt.Sprintf("Closed #%d.", linked.Number)
+	// Closing a dependency unblocks the issues waiting on it, as if it were
+	// done, so the model must re-point them.
+	var dependents []string
+	for _, iss := range sess.Issues {
+		if !iss.Closed && slices.Contains(iss.DependsOn, linked.Number) {
+			dependents = append(dependents, fmt.Sprintf("#%d", iss.Number))
+		}
+	}
+	if len(dependents) > 0 {
+		content += fmt.Sprintf(" %s depended on it and will now be started as if it were done: update their depends_on or scope, or close them too.", strings.Join(dependents, ", "))
+	}
+	return ToolResult{Content: content}, nil
+}
+
+// dependsOnSection is the body of an issue's "Depends On" section, or empty
+// for an issue without dependencies.
+func dependsOnSection(dependsOn []int) string {
+	if len(dependsOn) == 0 {
+		return ""
+	}
+	refs := make([]string, len(dependsOn))
+	for i, n := range dependsOn {
+		refs[i] = fmt.Sprintf("#%d", n)
+	}
+	closed := "they are"
+	if len(refs) == 1 {
+		closed = "it is"
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Blocked by %s. The executor starts this issue once %s closed.\n\n", strings.Join(refs, ", "), closed))
+	for _, n := range dependsOn {
+		sb.WriteString(fmt.Sprintf("- #%d\n", n))
+	}
+	return sb.String()
+}
+
+// replaceIssueSection replaces the content under the "## heading" section of an
+// issue body written by buildIssueBody, up to the next heading or the lines
+// that follow the sections. A missing section is added after the others;
+// empty content removes the section.
+func replaceIssueSection(body, heading, content string) string {
+	lines := strings.Split(body, "\n")
+	trailer := func(l string) bool {
+		return l == "---" || strings.HasPrefix(l, "Also-Repos:") || strings.HasPrefix(l, "Base:") || tracker.IsTicketLine(l)
+	}
+	next := func(from int, stop func(string) bool) int {
+		for i := from; i < len(lines); i++ {
+			if stop(strings.TrimSpace(lines[i])) {
+				return i
+			}
+		}
+		return len(lines)
+	}
+
+	start := slices.IndexFunc(lines, func(l string) bool { return strings.TrimSpace(l) == "## "+heading })
+	var end int
+	if start < 0 {
+		start = next(0, trailer)
+		end = start
+	} else {
+		end = next(start+1, func(l string) bool { return strings.HasPrefix(l, "## ") || trailer(l) })
+	}
+
+	var section []string
+	if content != "" {
+		section = append([]string{"## " + heading, ""}, strings.Split(strings.TrimRight(content, "\n"), "\n")...)
+		section = append(section, "")
+	}
+	out := slices.Concat(lines[:start], section, lines[end:])
+	return strings.Join(out, "\n")
+}
@@ -0,0 +1,183 @@
+package planner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/llm"
+)
+
+// SessionBackend persists encoded sessions by thread so planning threads
+// survive restarts. Implementations must make Save atomic: a crash mid-write
+// must leave either the old or the new session.
+type SessionBackend interface {
+	// Load returns the session saved for threadTS, or nil when there is none.
+	Load(threadTS string) ([]byte, error)
+	Save(threadTS string, data []byte) error
+	Delete(threadTS string) error
+	// List returns the threads that have a saved session.
+	List() ([]string, error)
+}
+
+// FileSessionBackend keeps one JSON file per thread in a directory. It needs
+// no external services, which suits a single planner with a persistent
+// volume.
+type FileSessionBackend struct {
+	dir string
+}
+
+func NewFileSessionBackend(dir string) (*FileSessionBackend, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create session dir: %w", err)
+	}
+	return &FileSessionBackend{dir: dir}, nil
+}
+
+func (b *FileSessionBackend) Load(threadTS string) ([]byte, error) {
+	data, err := os.ReadFile(b.path(threadTS))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read session %s: %w", threadTS, err)
+	}
+	return data, nil
+}
+
+func (b *FileSessionBackend) Save(threadTS string, data []byte) error {
+	// Write to a temp file and rename so a load never sees a partial session.
+	tmp := b.path(threadTS) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write session %s: %w", threadTS, err)
+	}
+	if err := os.Rename(tmp, b.path(threadTS)); err != nil {
+		return fmt.Errorf("commit session %s: %w", threadTS, err)
+	}
+	return nil
+}
+
+func (b *FileSessionBackend) Delete(threadTS string) error {
+	if err := os.Remove(b.path(threadTS)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("delete session %s: %w", threadTS, err)
+	}
+	return nil
+}
+
+func (b *FileSessionBackend) List() ([]string, error) {
+	entries, err := os.ReadDir(b.dir)
+	if err != nil {
+		return nil, fmt.Errorf("list session dir: %w", err)
+	}
+	var threads []string
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".json")
+		if e.IsDir() || !ok {
+			continue
+		}
+		if ts, err := url.PathUnescape(name); err == nil {
+			threads = append(threads, ts)
+		}
+	}
+	return threads, nil
+}
+
+func (b *FileSessionBackend) path(threadTS string) string {
+	return filepath.Join(b.dir, url.PathEscape(threadTS)+".json")
+}
+
+// sessionData is the stored form of a session. The git provider is not
+// stored; it is reconnected from Repo when the session is loaded.
+type sessionData struct {
+	ThreadTS      string         `json:"thread_ts"`
+	ChannelID     string         `json:"channel_id"`
+	Stage         Stage          `json:"stage"`
+	Messages      []llm.Message  `json:"messages"`
+	Users         []string       `json:"users,omitempty"`
+	Summary       string         `json:"summary,omitempty"`
+	Repo          *git.RepoInfo  `json:"repo,omitempty"`
+	PRDDraft      string         `json:"prd_draft,omitempty"`
+	PRDVersions   []PRDVersion   `json:"prd_versions,omitempty"`
+	PublishedPRD  *PublishedPRD  `json:"published_prd,omitempty"`
+	Proposal      *Proposal      `json:"proposal,omitempty"`
+	Tracker       *TrackerTarget `json:"tracker,omitempty"`
+	Criteria      []string       `json:"criteria,omitempty"`
+	Issues        []LinkedIssue  `json:"issues,omitempty"`
+	TrackingIssue *LinkedIssue   `json:"tracking_issue,omitempty"`
+	ForkedFrom    string         `json:"forked_from,omitempty"`
+	ForkPoint     int            `json:"fork_point,omitempty"`
+	Archived      bool           `json:"archived,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+func encodeSession(s *Session) ([]byte, error) {
+	b, err := json.Marshal(sessionData{
+		ThreadTS:      s.ThreadTS,
+		ChannelID:     s.ChannelID,
+		Stage:         s.Stage,
+		Messages:      s.Messages,
+		Users:         s.Users,
+		Summary:       s.Summary,
+		Repo:          s.Repo,
+		PRDDraft:      s.PRDDraft,
+		PRDVersions:   s.PRDVersions,
+		PublishedPRD:  s.PublishedPRD,
+		Proposal:      s.Proposal,
+		Tracker:       s.Tracker,
+		Criteria:      s.Criteria,
+		Issues:        s.Issues,
+		TrackingIssue: s.TrackingIssue,
+		ForkedFrom:    s.ForkedFrom,
+		ForkPoint:     s.forkPoint,
+		Archived:      s.Archived,
+		CreatedAt:     s.CreatedAt,
+		UpdatedAt:     s.UpdatedAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encode session %s: %w", s.ThreadTS, err)
+	}
+	return b, nil
+}
+
+func decodeSession(b []byte) (*Session, error) {
+	var d sessionData
+	if err := json.Unmarshal(b, &d); err != nil {
+		return nil, fmt.Errorf("decode session: %w", err)
+	}
+	if d.Messages == nil {
+		d.Messages = []llm.Message{}
+	}
+	if d.Tracker != nil && d.Tracker.Name == "" {
+		d.Tracker.Name = "Jira" // saved when Jira was the only tracker
+	}
+	return &Session{
+		ThreadTS:      d.ThreadTS,
+		ChannelID:     d.ChannelID,
+		Stage:         d.Stage,
+		Messages:      d.Messages,
+		Users:         d.Users,
+		Summary:       d.Summary,
+		Repo:          d.Repo,
+		PRDDraft:      d.PRDDraft,
+		PRDVersions:   d.PRDVersions,
+		PublishedPRD:  d.PublishedPRD,
+		Proposal:      d.Proposal,
+		Tracker:       d.Tracker,
+		Criteria:      d.Criteria,
+		Issues:        d.Issues,
+		TrackingIssue: d.TrackingIssue,
+		ForkedFrom:    d.ForkedFrom,
+		forkPoint:     d.ForkPoint,
+		Archived:      d.Archived,
+		CreatedAt:     d.CreatedAt,
+		UpdatedAt:     d.UpdatedAt,
+	}, nil
+}
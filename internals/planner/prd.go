@@ -0,0 +1,186 @@
+package planner
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PRDVersion is one saved revision of a session's PRD draft.
+type PRDVersion struct {
+	Number    int
+	Content   string
+	Note      string // what changed, in the model's words
+	CreatedAt time.Time
+}
+
+// savePRD records content as the next PRD version and makes it the current
+// draft. It returns the new version and a summary of what changed.
+func (s *Session) savePRD(content, note string) (PRDVersion, string) {
+	prev := s.PRDDraft
+	v := PRDVersion{
+		Number:    len(s.PRDVersions) + 1,
+		Content:   content,
+		Note:      note,
+		CreatedAt: time.Now(),
+	}
+	s.PRDVersions = append(s.PRDVersions, v)
+	s.PRDDraft = content
+	s.advanceTo(StagePRD)
+	return v, diffSummary(prev, content)
+}
+
+// prdVersion returns the saved version with the given number.
+func (s *Session) prdVersion(n int) (PRDVersion, bool) {
+	if n < 1 || n > len(s.PRDVersions) {
+		return PRDVersion{}, false
+	}
+	return s.PRDVersions[n-1], true
+}
+
+// replaceSection swaps the body of the markdown section whose heading matches
+// name (case-insensitively, ignoring leading '#') for body. The section runs
+// until the next heading of the same or a higher level.
+func replaceSection(prd, name, body string) (string, error) {
+	lines := strings.Split(prd, "\n")
+	start, level := -1, 0
+	var headings []string
+	for i, l := range lines {
+		lvl, title, ok := heading(l)
+		if !ok {
+			continue
+		}
+		headings = append(headings, title)
+		if start < 0 && sectionMatches(title, name) {
+			start, level = i, lvl
+			continue
+		}
+		if start >= 0 && lvl <= level {
+			out := append(append(append([]string{}, lines[:start+1]...), "", strings.TrimSpace(body), ""), lines[i:]...)
+			return strings.Join(out, "\n"), nil
+		}
+	}
+	if start < 0 {
+		return "", fmt.Errorf("no section matching %q — sections are: %s", name, strings.Join(headings, ", "))
+	}
+	out := append(append([]string{}, lines[:start+1]...), "", strings.TrimSpace(body))
+	return strings.Join(out, "\n") + "\n", nil
+}
+
+func heading(line string) (level int, title string, ok bool) {
+	trimmed := strings.TrimLeft(line, "#")
+	level = len(line) - len(trimmed)
+	if level == 0 || level > 6 || !strings.HasPrefix(trimmed, " ") {
+		return 0, "", false
+	}
+	return level, strings.TrimSpace(trimmed), true
+}
+
+// sectionMatches accepts an exact title or a leading word, so "Goals" finds
+// "Goals & Non-goals".
+func sectionMatches(title, name string) bool {
+	title, name = strings.ToLower(title), strings.ToLower(strings.TrimSpace(name))
+	return title == name || strings.HasPrefix(title, name+" ") || strings.HasPrefix(title, name+"&")
+}
+
+// maxDiffLines caps how many changed lines are shown in Slack.
+const maxDiffLines = 20
+
+// diffSummary describes the change from old to new: the sections touched with
+// line counts, followed by the changed lines as a diff block.
+func diffSummary(old, new string) string {
+	if old == "" {
+		return fmt.Sprintf("First draft (%d lines).", strings.Count(new, "\n")+1)
+	}
+	ops := diffLines(strings.Split(old, "\n"), strings.Split(new, "\n"))
+
+	type counts struct{ added, removed int }
+	perSection := map[string]*counts{}
+	var order []string
+	var changed []string
+	section := "(top)"
+	for _, op := range ops {
+		if _, title, ok := heading(op.text); ok {
+			section = title
+		}
+		if op.kind == ' ' {
+			continue
+		}
+		c, ok := perSection[section]
+		if !ok {
+			c = &counts{}
+			perSection[section] = c
+			order = append(order, section)
+		}
+		if op.kind == '+' {
+			c.added++
+		} else {
+			c.removed++
+		}
+		if strings.TrimSpace(op.text) != "" {
+			changed = append(changed, string(op.kind)+" "+op.text)
+		}
+	}
+	if len(order) == 0 {
+		return "No changes."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Changed sections:\n")
+	for _, name := range order {
+		c := perSection[name]
+		sb.WriteString(fmt.Sprintf("• %s (+%d −%d)\n", name, c.added, c.removed))
+	}
+	if len(changed) > maxDiffLines {
+		changed = append(changed[:maxDiffLines], fmt.Sprintf("… %d more changed lines", len(changed)-maxDiffLines))
+	}
+	sb.WriteString("```\n" + strings.Join(changed, "\n") + "\n```")
+	return sb.String()
+}
+
+type diffOp struct {
+	kind byte // ' ', '+' or '-'
+	text string
+}
+
+// diffLines computes a line diff via the longest common subsequence. PRDs are
+// at most a few hundred lines, so the quadratic table is fine.
+func diffLines(a, b []string) []diffOp {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
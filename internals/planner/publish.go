@@ -0,0 +1,153 @@
+package planner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/jadenj13/droid/internals/git"
+)
+
+// prdDir is where publish_prd commits PRDs in the repository.
+const prdDir = "docs/prds"
+
+// PublishedPRD records where a session's PRD was committed to the
+// repository.
+type PublishedPRD struct {
+	Path    string // e.g. docs/prds/bulk-export.md
+	Branch  string
+	PRURL   string
+	Version int // the PRD version last published
+}
+
+var toolPublishPRD = anthropic.ToolParam{
+	Name:        "publish_prd",
+	Description: anthropic.String("Commits the current PRD to the repository as docs/prds/<slug>.md through a small PR, so the planning document lives next to the code. Issues created afterwards link to it, and issues already created get the link added. Call it once the user is happy with the PRD, ideally before creating issues; calling it again publishes the latest version to the same PR."),
+	InputSchema: anthropic.ToolInputSchemaParam{
+		Properties: map[string]interface{}{
+			"title": map[string]interface{}{
+				"type":        "string",
+				"description": "Title of the feature, used for the file name and the PR title, e.g. 'Bulk export'.",
+			},
+		},
+		Required: []string{"title"},
+	},
+}
+
+type publishPRDInput struct {
+	Title string `json:"title"`
+}
+
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// prdSlug turns a title into a file name: lower case, words joined by
+// hyphens, at most 60 characters.
+func prdSlug(title string) string {
+	slug := strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if len(slug) > 60 {
+		slug = strings.TrimRight(slug[:60], "-")
+	}
+	return slug
+}
+
+func execPublishPRD(ctx context.Context, raw json.RawMessage, sess *Session) (ToolResult, error) {
+	var input publishPRDInput
+	if err := json.Unmarshal(raw, &input); err != nil {
+		return ToolResult{}, fmt.Errorf("unmarshal publish_prd: %w", err)
+	}
+	if sess.GitProvider == nil {
+		return ToolResult{Content: "error: no repository configured — ask the user for a repo URL first"}, nil
+	}
+	if sess.PRDDraft == "" {
+		return ToolResult{Content: "error: there is no PRD to publish — write it with update_prd first"}, nil
+	}
+
+	pub := sess.PublishedPRD
+	if pub == nil {
+		slug := prdSlug(input.Title)
+		if slug == "" {
+			return ToolResult{Content: "error: the title must contain letters or digits, for the file name"}, nil
+		}
+		pub = &PublishedPRD{Path: prdDir + "/" + slug + ".md", Branch: "droid/prd-" + slug}
+	}
+	version := len(sess.PRDVersions)
+	if pub.Version == version && pub.PRURL != "" {
+		return ToolResult{Content: fmt.Sprintf("PRD v%d is already published in %s (%s).", version, pub.Path, pub.PRURL)}, nil
+	}
+
+	verb := "Add"
+	if pub.Version > 0 {
+		verb = "Update"
+	}
+	message := fmt.Sprintf("%s PRD: %s (v%d)", verb, input.Title, version)
+	base, err := sess.GitProvider.CommitFile(ctx, pub.Branch, pub.Path, strings.TrimSpace(sess.PRDDraft)+"\n", message)
+	if err != nil {
+		return ToolResult{Content: fmt.Sprintf("error committing the PRD: %s", err)}, nil
+	}
+
+	n, err := sess.GitProvider.FindOpenPR(ctx, pub.Branch)
+	if err != nil {
+		return ToolResult{Content: fmt.Sprintf("error looking up the PRD's PR: %s", err)}, nil
+	}
+	if n == 0 {
+		url, err := sess.GitProvider.OpenPR(ctx, git.PRInput{
+			Title:  "docs: PRD for " + input.Title,
+			Body:   fmt.Sprintf("Adds the product requirements for **%s**, written in a planning session, as `%s`. The issues planned from it link here.\n\n---\n*Created by the Planner Agent*", input.Title, pub.Path),
+			Branch: pub.Branch,
+			Base:   base,
+		})
+		if err != nil {
+			return ToolResult{Content: fmt.Sprintf("Committed the PRD to branch %s, but opening its PR failed: %s", pub.Branch, err)}, nil
+		}
+		pub.PRURL = url
+	} else if pub.PRURL == "" {
+		pr, err := sess.GitProvider.GetPR(ctx, n)
+		if err != nil {
+			return ToolResult{Content: fmt.Sprintf("error fetching the PRD's PR: %s", err)}, nil
+		}
+		pub.PRURL = pr.URL
+	}
+	pub.Version = version
+	sess.PublishedPRD = pub
+
+	content := fmt.Sprintf("Published PRD v%d as %s: %s", version, pub.Path, pub.PRURL)
+	if failed := linkPRD(ctx, sess); len(failed) > 0 {
+		content += fmt.Sprintf("\nAdding the PRD link failed for %s.", strings.Join(failed, ", "))
+	}
+	return ToolResult{
+		Content: content,
+		Reply:   fmt.Sprintf(":page_facing_up: PRD v%d published as `%s`: <%s|PR>", version, pub.Path, pub.PRURL),
+	}, nil
+}
+
+// linkPRD adds or refreshes the PRD section of the session's open issues. It
+// returns the issues it could not update.
+func linkPRD(ctx context.Context, sess *Session) []string {
+	var failed []string
+	for _, linked := range openIssues(sess.Issues) {
+		issue, err := sess.GitProvider.GetIssue(ctx, linked.Number)
+		if err == nil {
+			body := replaceIssueSection(issue.Body, "PRD", prdSection(sess.PublishedPRD))
+			if body == issue.Body {
+				continue
+			}
+			err = sess.GitProvider.UpdateIssueBody(ctx, linked.Number, body)
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("#%d (%s)", linked.Number, err))
+		}
+	}
+	return failed
+}
+
+// prdSection is the body of an issue's "PRD" section, or empty before the
+// PRD is published.
+func prdSection(pub *PublishedPRD) string {
+	if pub == nil {
+		return ""
+	}
+	return fmt.Sprintf("`%s`, added in %s\n", pub.Path, pub.PRURL)
+}
@@ -0,0 +1,211 @@
+package planner
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// redisTimeout bounds each Redis command, dial included.
+const redisTimeout = 10 * time.Second
+
+// RedisSessionBackend keeps each session under "<prefix><thread>" in Redis,
+// for planners without a persistent volume or running as several replicas.
+// It speaks the Redis protocol directly and opens a connection per command,
+// which is plenty at one save per planning message.
+type RedisSessionBackend struct {
+	addr     string
+	useTLS   bool
+	username string
+	password string
+	db       int
+	prefix   string
+}
+
+// NewRedisSessionBackend connects to the server at rawURL, in the form
+// redis://[user:password@]host:port[/db] (rediss:// for TLS), and checks it
+// answers.
+func NewRedisSessionBackend(rawURL, prefix string) (*RedisSessionBackend, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse redis url: %w", err)
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("redis url %q: scheme must be redis or rediss", rawURL)
+	}
+	b := &RedisSessionBackend{addr: u.Host, useTLS: u.Scheme == "rediss", prefix: prefix}
+	if u.Port() == "" {
+		b.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		b.password, _ = u.User.Password()
+		if b.password == "" {
+			b.password = u.User.Username() // redis://:password@ or redis://password@
+		} else {
+			b.username = u.User.Username()
+		}
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if b.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("redis url %q: database must be a number", rawURL)
+		}
+	}
+	if _, err := b.do("PING"); err != nil {
+		return nil, fmt.Errorf("connect to redis: %w", err)
+	}
+	return b, nil
+}
+
+func (b *RedisSessionBackend) Load(threadTS string) ([]byte, error) {
+	reply, err := b.do("GET", b.prefix+threadTS)
+	if err != nil {
+		return nil, fmt.Errorf("redis get session %s: %w", threadTS, err)
+	}
+	data, _ := reply.([]byte) // nil when there is no session
+	return data, nil
+}
+
+func (b *RedisSessionBackend) Save(threadTS string, data []byte) error {
+	if _, err := b.do("SET", b.prefix+threadTS, string(data)); err != nil {
+		return fmt.Errorf("redis set session %s: %w", threadTS, err)
+	}
+	return nil
+}
+
+func (b *RedisSessionBackend) Delete(threadTS string) error {
+	if _, err := b.do("DEL", b.prefix+threadTS); err != nil {
+		return fmt.Errorf("redis delete session %s: %w", threadTS, err)
+	}
+	return nil
+}
+
+func (b *RedisSessionBackend) List() ([]string, error) {
+	var threads []string
+	cursor := "0"
+	for {
+		reply, err := b.do("SCAN", cursor, "MATCH", b.prefix+"*", "COUNT", "100")
+		if err != nil {
+			return nil, fmt.Errorf("redis scan sessions: %w", err)
+		}
+		page, ok := reply.([]any)
+		if !ok || len(page) != 2 {
+			return nil, fmt.Errorf("redis scan sessions: unexpected reply %v", reply)
+		}
+		next, _ := page[0].([]byte)
+		keys, _ := page[1].([]any)
+		for _, k := range keys {
+			if key, ok := k.([]byte); ok {
+				threads = append(threads, strings.TrimPrefix(string(key), b.prefix))
+			}
+		}
+		if cursor = string(next); cursor == "0" || cursor == "" {
+			return threads, nil
+		}
+	}
+}
+
+// do runs one command on a fresh connection, authenticating and selecting
+// the database first.
+func (b *RedisSessionBackend) do(args ...string) (any, error) {
+	dialer := &net.Dialer{Timeout: redisTimeout}
+	var conn net.Conn
+	var err error
+	if b.useTLS {
+		host, _, _ := net.SplitHostPort(b.addr)
+		conn, err = tls.DialWithDialer(dialer, "tcp", b.addr, &tls.Config{ServerName: host})
+	} else {
+		conn, err = dialer.Dial("tcp", b.addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(redisTimeout)); err != nil {
+		return nil, err
+	}
+
+	var cmds [][]string
+	if b.password != "" {
+		if b.username != "" {
+			cmds = append(cmds, []string{"AUTH", b.username, b.password})
+		} else {
+			cmds = append(cmds, []string{"AUTH", b.password})
+		}
+	}
+	if b.db != 0 {
+		cmds = append(cmds, []string{"SELECT", strconv.Itoa(b.db)})
+	}
+	cmds = append(cmds, args)
+
+	w := bufio.NewWriter(conn)
+	for _, cmd := range cmds {
+		fmt.Fprintf(w, "*%d\r\n", len(cmd))
+		for _, a := range cmd {
+			fmt.Fprintf(w, "$%d\r\n%s\r\n", len(a), a)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(conn)
+	var reply any
+	for range cmds {
+		if reply, err = readRedisReply(r); err != nil {
+			return nil, err
+		}
+	}
+	return reply, nil
+}
+
+// readRedisReply reads one RESP reply: a string or bulk string as []byte (nil
+// for a null bulk string), an integer as int64, an array as []any, and an
+// error reply as an error.
+func readRedisReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty redis reply")
+	}
+	switch line[0] {
+	case '+':
+		return []byte(line[1:]), nil
+	case '-':
+		return nil, errors.New(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		out := make([]any, n)
+		for i := range out {
+			if out[i], err = readRedisReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unexpected redis reply %q", line)
+	}
+}
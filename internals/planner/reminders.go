@@ -0,0 +1,141 @@
+package planner
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	slackhandler "github.com/jadenj13/droid/internals/slack"
+)
+
+type ReminderPoster interface {
+	PostReminder(ctx context.Context, r slackhandler.Reminder) error
+}
+
+// Scheduler periodically checks the issues created by each planning session
+// and posts a reminder in the originating Slack thread when one has stalled:
+// still agent:ready after StaleReady, or approved but awaiting a human merge
+// after StaleReview. Each pass first archives the sessions idle for longer
+// than SessionTTL.
+type Scheduler struct {
+	sessions *SessionStore
+	poster   ReminderPoster
+	log      *slog.Logger
+
+	Interval    time.Duration
+	StaleReady  time.Duration
+	StaleReview time.Duration
+	SessionTTL  time.Duration // 0 never expires sessions
+
+	// ReviewLabel and ApprovedLabel are the reviewer's labels for a PR under
+	// review and an approved one.
+	ReviewLabel   string
+	ApprovedLabel string
+
+	mu       sync.Mutex
+	reminded map[string]time.Time // key: thread + issue number
+}
+
+func NewScheduler(sessions *SessionStore, poster ReminderPoster, log *slog.Logger) *Scheduler {
+	return &Scheduler{
+		sessions:    sessions,
+		poster:      poster,
+		log:         log,
+		Interval:    time.Hour,
+		StaleReady:  3 * 24 * time.Hour,
+		StaleReview: 2 * 24 * time.Hour,
+		SessionTTL:  30 * 24 * time.Hour,
+		reminded:    make(map[string]time.Time),
+
+		ReviewLabel:   "agent:review",
+		ApprovedLabel: "agent:approved",
+	}
+}
+
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.check(ctx)
+		}
+	}
+}
+
+func (s *Scheduler) check(ctx context.Context) {
+	if s.SessionTTL > 0 {
+		if n, err := s.sessions.Expire(s.SessionTTL, time.Now()); err != nil {
+			s.log.Warn("session expiry failed", "err", err)
+		} else if n > 0 {
+			s.log.Info("idle planning sessions archived", "count", n, "ttl", s.SessionTTL)
+		}
+	}
+	for _, sess := range s.sessions.List() {
+		if sess.GitProvider == nil {
+			continue
+		}
+		for _, linked := range openIssues(sess.Issues) {
+			issue, err := sess.GitProvider.GetIssue(ctx, linked.Number)
+			if err != nil {
+				s.log.Warn("reminder: fetch issue failed", "issue", linked.Number, "err", err)
+				continue
+			}
+			if issue.State != "open" {
+				continue
+			}
+
+			idle := time.Since(issue.UpdatedAt)
+			var text string
+			switch {
+			case issue.HasLabel(s.ApprovedLabel) && idle > s.StaleReview:
+				text = fmt.Sprintf(":hourglass: <%s|#%d %s> was approved %s ago and is waiting for a human to review and merge.",
+					issue.URL, issue.Number, issue.Title, roundDays(idle))
+			case issue.HasLabel("agent:ready") && !issue.HasLabel(s.ReviewLabel) && !issue.HasLabel(blockedLabel) && idle > s.StaleReady:
+				text = fmt.Sprintf(":wave: <%s|#%d %s> has been ready for %s with no progress. Requeue it for the executor?",
+					issue.URL, issue.Number, issue.Title, roundDays(idle))
+			default:
+				continue
+			}
+
+			if !s.shouldRemind(sess.ThreadTS, issue.Number) {
+				continue
+			}
+			err = s.poster.PostReminder(ctx, slackhandler.Reminder{
+				ChannelID:   sess.ChannelID,
+				ThreadTS:    sess.ThreadTS,
+				IssueNumber: issue.Number,
+				Text:        text,
+			})
+			if err != nil {
+				s.log.Warn("reminder: post failed", "issue", issue.Number, "err", err)
+			}
+		}
+	}
+}
+
+// shouldRemind rate-limits reminders to one per issue per StaleReady period.
+func (s *Scheduler) shouldRemind(threadTS string, issueNumber int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := fmt.Sprintf("%s#%d", threadTS, issueNumber)
+	if last, ok := s.reminded[key]; ok && time.Since(last) < s.StaleReady {
+		return false
+	}
+	s.reminded[key] = time.Now()
+	return true
+}
+
+func roundDays(d time.Duration) string {
+	days := int(d.Hours() / 24)
+	if days == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", days)
+}
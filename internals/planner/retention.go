@@ -0,0 +1,90 @@
+package planner
+
+import (
+	"time"
+
+	"github.com/jadenj13/droid/internals/llm"
+	"github.com/jadenj13/droid/internals/retention"
+)
+
+// SessionRecord is the exported form of a session: its conversation
+// transcript and everything drafted in it.
+type SessionRecord struct {
+	ThreadTS    string        `json:"thread_ts"`
+	ChannelID   string        `json:"channel_id"`
+	Users       []string      `json:"users"`
+	Repo        string        `json:"repo,omitempty"`
+	Stage       string        `json:"stage"`
+	Messages    []llm.Message `json:"messages"`
+	PRDDraft    string        `json:"prd_draft,omitempty"`
+	PRDVersions []PRDVersion  `json:"prd_versions,omitempty"`
+	Criteria    []string      `json:"criteria,omitempty"`
+	Issues      []LinkedIssue `json:"issues,omitempty"`
+	ForkedFrom  string        `json:"forked_from,omitempty"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
+
+// Name implements retention.Source.
+func (s *SessionStore) Name() string { return "sessions" }
+
+// ExportData returns the sessions f selects, matched on their last activity.
+func (s *SessionStore) ExportData(f retention.Filter) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []SessionRecord{}
+	for _, sess := range s.all() {
+		if matchSession(f, sess) {
+			out = append(out, sess.record())
+		}
+	}
+	return out, nil
+}
+
+// DeleteData removes the sessions f selects.
+func (s *SessionStore) DeleteData(f retention.Filter) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, sess := range s.all() {
+		if matchSession(f, sess) {
+			if s.backend != nil {
+				if err := s.backend.Delete(sess.ThreadTS); err != nil {
+					return n, err
+				}
+			}
+			delete(s.sessions, sess.ThreadTS)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func matchSession(f retention.Filter, sess *Session) bool {
+	repo := ""
+	if sess.Repo != nil {
+		repo = sess.Repo.RawURL
+	}
+	return f.Match(repo, sess.Users, sess.UpdatedAt)
+}
+
+func (s *Session) record() SessionRecord {
+	r := SessionRecord{
+		ThreadTS:    s.ThreadTS,
+		ChannelID:   s.ChannelID,
+		Users:       s.Users,
+		Stage:       s.Stage.String(),
+		Messages:    s.Messages,
+		PRDDraft:    s.PRDDraft,
+		PRDVersions: s.PRDVersions,
+		Criteria:    s.Criteria,
+		Issues:      s.Issues,
+		ForkedFrom:  s.ForkedFrom,
+		CreatedAt:   s.CreatedAt,
+		UpdatedAt:   s.UpdatedAt,
+	}
+	if s.Repo != nil {
+		r.Repo = s.Repo.RawURL
+	}
+	return r
+}
@@ -0,0 +1,78 @@
+package planner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	slackhandler "github.com/jadenj13/droid/internals/slack"
+)
+
+// ParseGitUsers parses "U0123=octocat,U0456=jdoe" into a map from Slack user
+// ID to git username.
+func ParseGitUsers(s string) (map[string]string, error) {
+	out := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		id, username, ok := strings.Cut(pair, "=")
+		if !ok || id == "" || username == "" {
+			return nil, fmt.Errorf("invalid git user %q — expected SLACK_USER_ID=username", pair)
+		}
+		out[strings.TrimSpace(id)] = strings.TrimPrefix(strings.TrimSpace(username), "@")
+	}
+	return out, nil
+}
+
+// botLabels are the labels that hand an issue to one of the agents.
+func (a *Agent) botLabels() []string {
+	return []string{"agent:ready", a.reviewLabel, a.revisionLabel}
+}
+
+// RouteReview carries out a human's choice for a change request the reviewer
+// posted to Slack, in place of the reviewer labeling the issue itself.
+func (a *Agent) RouteReview(ctx context.Context, route slackhandler.ReviewRoute, ref slackhandler.ReviewRef, userID string) (string, error) {
+	repoURL, issueNumber, ok := splitIssueURL(ref.IssueURL)
+	if !ok {
+		return "", fmt.Errorf("%q is not an issue URL", ref.IssueURL)
+	}
+	provider, _, err := a.factory.ProviderFor(ctx, repoURL)
+	if err != nil {
+		return "", err
+	}
+	a.log.Info("routing review", "route", route, "issue", ref.IssueURL, "pr", ref.PRNumber, "user", userID)
+
+	switch route {
+	case slackhandler.RouteRevise:
+		if err := provider.AddLabel(ctx, issueNumber, a.revisionLabel); err != nil {
+			return "", fmt.Errorf("add %s label: %w", a.revisionLabel, err)
+		}
+		return fmt.Sprintf(":arrows_counterclockwise: <@%s> sent this back to the executor for revision.", userID), nil
+
+	case slackhandler.RouteSelf:
+		for _, l := range a.botLabels() {
+			if err := provider.RemoveLabel(ctx, issueNumber, l); err != nil {
+				a.log.Debug("remove label", "label", l, "err", err) // usually not present
+			}
+		}
+		reply := fmt.Sprintf(":raising_hand: <@%s> is fixing this by hand; the agents have stepped back.", userID)
+		username, ok := a.gitUsers[userID]
+		if !ok {
+			return reply + " (No git username is mapped to you, so the issue was not assigned.)", nil
+		}
+		if err := provider.AssignIssue(ctx, issueNumber, username); err != nil {
+			a.log.Warn("assign issue failed", "issue", issueNumber, "user", username, "err", err)
+			return reply + fmt.Sprintf(" (Assigning the issue to %s failed.)", username), nil
+		}
+		return reply + fmt.Sprintf(" Assigned to %s.", username), nil
+
+	case slackhandler.RouteDismiss:
+		if err := provider.DismissReviews(ctx, ref.PRNumber, "Review dismissed from Slack."); err != nil {
+			return "", fmt.Errorf("dismiss review: %w", err)
+		}
+		return fmt.Sprintf(":no_entry_sign: <@%s> dismissed the review.", userID), nil
+	}
+	return "", fmt.Errorf("unknown review route %q", route)
+}
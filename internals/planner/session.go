@@ -1,6 +1,10 @@
 package planner
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
 	"sync"
 	"time"
 
@@ -22,27 +26,57 @@ func (s Stage) String() string {
 	return [...]string{"brainstorm", "prd", "criteria", "issues", "done"}[s]
 }
 
+// ParseStage returns the stage named s, as String prints it.
+func ParseStage(s string) (Stage, bool) {
+	for st := StageBrainstorm; st <= StageDone; st++ {
+		if strings.EqualFold(strings.TrimSpace(s), st.String()) {
+			return st, true
+		}
+	}
+	return 0, false
+}
+
 type Session struct {
 	ThreadTS  string
 	ChannelID string
 	Stage     Stage
 	Messages  []llm.Message
+	Users     []string // Slack IDs of everyone who wrote in the thread
+	// Summary condenses the messages dropped from Messages when the history
+	// outgrew its window. Empty until the first compaction.
+	Summary string
 
 	Repo        *git.RepoInfo
 	GitProvider git.GitProvider
 
-	PRDDraft string
-	Criteria []string
-	Issues   []LinkedIssue
+	PRDDraft      string
+	PRDVersions   []PRDVersion
+	PublishedPRD  *PublishedPRD  // set once publish_prd committed the PRD
+	Proposal      *Proposal      // the breakdown last posted by propose_issues
+	Tracker       *TrackerTarget // set by set_tracker; nil creates issues in the repository only
+	Criteria      []string
+	Issues        []LinkedIssue
+	TrackingIssue *LinkedIssue
+
+	ForkedFrom string // thread of the session this one was forked from
+	forkPoint  int    // len(Messages) at the moment of the fork
+
+	// Archived is set on a session that expired. It stays saved, out of
+	// memory and off the reminders, until its thread is used again.
+	Archived bool
 
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
 
 type LinkedIssue struct {
-	Number int
-	Title  string
-	URL    string
+	Number    int
+	Title     string
+	URL       string
+	DependsOn []int  // numbers of issues that must be completed first
+	Closed    bool   // closed by close_issue because the plan no longer needs it
+	Ticket    string // key of the tracker ticket filed for it, e.g. PAY-12
+	TicketURL string
 }
 
 func newSession(threadTS, channelID string) *Session {
@@ -56,22 +90,84 @@ func newSession(threadTS, channelID string) *Session {
 	}
 }
 
+// advanceTo moves the session forward to stage when it produced that stage's
+// work — a PRD, criteria or issues — without the model calling set_stage. It
+// never moves a session back.
+func (s *Session) advanceTo(stage Stage) {
+	if s.Stage < stage {
+		s.Stage = stage
+	}
+}
+
+// clone returns a deep copy of s keyed by threadTS, recording s as its parent.
+func (s *Session) clone(threadTS, channelID string) *Session {
+	c := *s
+	c.ThreadTS = threadTS
+	c.ChannelID = channelID
+	c.Messages = append([]llm.Message(nil), s.Messages...)
+	c.Users = append([]string(nil), s.Users...)
+	c.PRDVersions = append([]PRDVersion(nil), s.PRDVersions...)
+	c.Criteria = append([]string(nil), s.Criteria...)
+	c.Issues = append([]LinkedIssue(nil), s.Issues...)
+	if s.TrackingIssue != nil {
+		t := *s.TrackingIssue
+		c.TrackingIssue = &t
+	}
+	if s.PublishedPRD != nil {
+		p := *s.PublishedPRD
+		c.PublishedPRD = &p
+	}
+	if s.Tracker != nil {
+		t := *s.Tracker
+		t.Labels = append([]string(nil), s.Tracker.Labels...)
+		c.Tracker = &t
+	}
+	if s.Proposal != nil {
+		p := *s.Proposal
+		p.Items = append([]ProposedIssue(nil), s.Proposal.Items...)
+		c.Proposal = &p
+	}
+	c.ForkedFrom = s.ThreadTS
+	c.forkPoint = len(s.Messages)
+	c.CreatedAt = time.Now()
+	return &c
+}
+
+// SessionStore holds the planning sessions by thread. With a backend, every
+// save is persisted and a thread's session is loaded the first time the
+// thread is used after a restart.
 type SessionStore struct {
 	mu       sync.RWMutex
-	sessions map[string]*Session // key: threadTS
+	sessions map[string]*Session // key: threadTS; the sessions loaded so far
+	backend  SessionBackend      // nil keeps sessions in memory only
+	factory  ProviderFactory     // reconnects a loaded session to its repository
+	log      *slog.Logger
+}
+
+type SessionStoreOption func(*SessionStore)
+
+// WithSessionBackend persists sessions in b. factory reconnects a loaded
+// session to the repository it was planning for.
+func WithSessionBackend(b SessionBackend, factory ProviderFactory) SessionStoreOption {
+	return func(s *SessionStore) { s.backend, s.factory = b, factory }
 }
 
-func NewSessionStore() *SessionStore {
-	return &SessionStore{
+func NewSessionStore(log *slog.Logger, opts ...SessionStoreOption) *SessionStore {
+	s := &SessionStore{
 		sessions: make(map[string]*Session),
+		log:      log,
 	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
 }
 
 func (s *SessionStore) GetOrCreate(threadTS, channelID string) *Session {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if sess, ok := s.sessions[threadTS]; ok {
+	if sess, ok := s.lookup(threadTS); ok {
 		return sess
 	}
 
@@ -81,10 +177,162 @@ func (s *SessionStore) GetOrCreate(threadTS, channelID string) *Session {
 }
 
 func (s *SessionStore) Get(threadTS string) (*Session, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lookup(threadTS)
+}
+
+// lookup returns the session for threadTS, loading it from the backend if it
+// is not in memory yet. An archived session is restored: its thread is in
+// use again. The caller holds s.mu.
+func (s *SessionStore) lookup(threadTS string) (*Session, bool) {
+	if sess, ok := s.sessions[threadTS]; ok {
+		return sess, true
+	}
+	sess := s.load(threadTS)
+	if sess == nil {
+		return nil, false
+	}
+	if sess.Archived {
+		sess.Archived = false
+		s.log.Info("archived planning session restored", "thread", threadTS)
+	}
+	s.connect(sess)
+	s.sessions[threadTS] = sess
+	s.log.Info("planning session loaded", "thread", threadTS, "stage", sess.Stage, "messages", len(sess.Messages))
+	return sess, true
+}
+
+// load reads the saved session for threadTS, or returns nil.
+func (s *SessionStore) load(threadTS string) *Session {
+	if s.backend == nil {
+		return nil
+	}
+	data, err := s.backend.Load(threadTS)
+	if err != nil {
+		s.log.Warn("could not load planning session", "thread", threadTS, "err", err)
+		return nil
+	}
+	if data == nil {
+		return nil
+	}
+	sess, err := decodeSession(data)
+	if err != nil {
+		s.log.Warn("could not decode planning session", "thread", threadTS, "err", err)
+		return nil
+	}
+	return sess
+}
+
+// connect reconnects a loaded session to the repository it was planning for.
+func (s *SessionStore) connect(sess *Session) {
+	if sess.Repo == nil || s.factory == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	provider, _, err := s.factory.ProviderFor(ctx, sess.Repo.RawURL)
+	if err != nil {
+		s.log.Warn("could not reconnect planning session to its repository", "thread", sess.ThreadTS, "repo", sess.Repo.RawURL, "err", err)
+		return
+	}
+	sess.GitProvider = provider
+}
+
+// all returns every session, archived ones included, loading the saved
+// sessions of threads not used since a restart. Those not archived stay in
+// memory. The caller holds s.mu.
+func (s *SessionStore) all() []*Session {
+	out := make([]*Session, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		out = append(out, sess)
+	}
+	if s.backend == nil {
+		return out
+	}
+	threads, err := s.backend.List()
+	if err != nil {
+		s.log.Warn("could not list saved planning sessions", "err", err)
+		return out
+	}
+	for _, ts := range threads {
+		if _, ok := s.sessions[ts]; ok {
+			continue
+		}
+		sess := s.load(ts)
+		if sess == nil {
+			continue
+		}
+		if !sess.Archived {
+			s.connect(sess)
+			s.sessions[ts] = sess
+		}
+		out = append(out, sess)
+	}
+	return out
+}
+
+// List returns a snapshot of all sessions not archived.
+func (s *SessionStore) List() []*Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []*Session
+	for _, sess := range s.all() {
+		if !sess.Archived {
+			out = append(out, sess)
+		}
+	}
+	return out
+}
+
+// Archive archives the session for threadTS: it is dropped from memory and,
+// with a backend, kept saved until the thread is used again. Without a
+// backend the session is gone.
+func (s *SessionStore) Archive(threadTS string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	sess, ok := s.sessions[threadTS]
-	return sess, ok
+	if !ok {
+		if sess = s.load(threadTS); sess == nil || sess.Archived {
+			return fmt.Errorf("no active planning session for thread %s", threadTS)
+		}
+	}
+	return s.archive(sess)
+}
+
+// Expire archives the sessions with no activity for ttl before now and
+// returns how many.
+func (s *SessionStore) Expire(ttl time.Duration, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, sess := range s.all() {
+		if sess.Archived || now.Sub(sess.UpdatedAt) <= ttl {
+			continue
+		}
+		if err := s.archive(sess); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// archive saves sess as archived, keeping its last activity, and drops it
+// from memory. The caller holds s.mu.
+func (s *SessionStore) archive(sess *Session) error {
+	if s.backend != nil {
+		sess.Archived = true
+		data, err := encodeSession(sess)
+		if err != nil {
+			return err
+		}
+		if err := s.backend.Save(sess.ThreadTS, data); err != nil {
+			return err
+		}
+	}
+	delete(s.sessions, sess.ThreadTS)
+	return nil
 }
 
 func (s *SessionStore) Save(sess *Session) error {
@@ -92,7 +340,14 @@ func (s *SessionStore) Save(sess *Session) error {
 	defer s.mu.Unlock()
 	sess.UpdatedAt = time.Now()
 	s.sessions[sess.ThreadTS] = sess
-	return nil
+	if s.backend == nil {
+		return nil
+	}
+	data, err := encodeSession(sess)
+	if err != nil {
+		return err
+	}
+	return s.backend.Save(sess.ThreadTS, data)
 }
 
 func (s *SessionStore) AppendMessage(sess *Session, role, content string) error {
@@ -0,0 +1,99 @@
+package planner
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxListedSessions caps the sessions "/droid sessions" lists.
+const maxListedSessions = 30
+
+// Sessions lists or cleans up the active planning sessions, for operators:
+// "/droid sessions" lists them, "/droid sessions archive [thread]" archives
+// one (this thread by default) and "/droid sessions expire <duration>"
+// archives those idle longer than duration.
+func (a *Agent) Sessions(ctx context.Context, threadTS, args string) (string, error) {
+	verb, rest, _ := strings.Cut(strings.TrimSpace(args), " ")
+	rest = strings.TrimSpace(rest)
+	switch strings.ToLower(verb) {
+	case "", "list":
+		return a.listSessions(time.Now()), nil
+
+	case "archive":
+		target := threadTS
+		if rest != "" {
+			target = rest
+		}
+		if err := a.sessions.Archive(target); err != nil {
+			return "", err
+		}
+		a.log.Info("planning session archived", "thread", target)
+		return fmt.Sprintf(":file_cabinet: Archived the planning session for thread `%s`. It is restored if someone writes in the thread again.", target), nil
+
+	case "expire":
+		idle, err := time.ParseDuration(rest)
+		if err != nil || idle <= 0 {
+			return "", fmt.Errorf("expected a duration such as 72h, got %q", rest)
+		}
+		n, err := a.sessions.Expire(idle, time.Now())
+		if err != nil {
+			return "", fmt.Errorf("expire sessions: %w", err)
+		}
+		a.log.Info("planning sessions expired", "idle", idle, "count", n)
+		return fmt.Sprintf(":file_cabinet: Archived %d planning sessions idle for more than %s.", n, idle), nil
+
+	default:
+		return "", fmt.Errorf("unknown sessions command %q — expected list, archive [thread] or expire <duration>", verb)
+	}
+}
+
+// listSessions describes the active sessions, most recently used first.
+func (a *Agent) listSessions(now time.Time) string {
+	sessions := a.sessions.List()
+	if len(sessions) == 0 {
+		return "No active planning sessions."
+	}
+	slices.SortFunc(sessions, func(x, y *Session) int { return y.UpdatedAt.Compare(x.UpdatedAt) })
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(":card_index: %d active planning sessions:\n", len(sessions)))
+	for i, sess := range sessions {
+		if i == maxListedSessions {
+			sb.WriteString(fmt.Sprintf("… and %d more\n", len(sessions)-i))
+			break
+		}
+		repo := "no repository"
+		if sess.Repo != nil {
+			repo = sess.Repo.RawURL
+		}
+		size := 0
+		if data, err := encodeSession(sess); err == nil {
+			size = len(data)
+		}
+		sb.WriteString(fmt.Sprintf("• `%s` in <#%s> — %s, %s, %d messages (%s), last active %s ago\n",
+			sess.ThreadTS, sess.ChannelID, sess.Stage, repo, len(sess.Messages), formatBytes(size), roundAge(now.Sub(sess.UpdatedAt))))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func formatBytes(n int) string {
+	if n < 1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+	return fmt.Sprintf("%.1f KB", float64(n)/1024)
+}
+
+// roundAge renders d in minutes, hours or days, whichever reads best.
+func roundAge(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return roundDays(d)
+	}
+}
@@ -0,0 +1,56 @@
+package planner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// statsClient fetches review stats from the reviewer.
+var statsClient = &http.Client{Timeout: 15 * time.Second}
+
+// ReviewStats fetches the reviewer's verdict and finding statistics for
+// "/droid stats [repo URL] [days]". Without a repository it uses the one of
+// the planning session in threadTS, or covers every repository.
+func (a *Agent) ReviewStats(ctx context.Context, threadTS, args string) (string, error) {
+	if a.reviewerURL == "" {
+		return "", fmt.Errorf("review stats are not configured — set PLANNER_REVIEWER_URL")
+	}
+	q := url.Values{"format": {"text"}}
+	for _, arg := range strings.Fields(args) {
+		arg = strings.Trim(arg, "<>")
+		if n, err := strconv.Atoi(arg); err == nil && n > 0 {
+			q.Set("days", arg)
+			continue
+		}
+		q.Set("repo", arg)
+	}
+	if q.Get("repo") == "" {
+		if sess, ok := a.sessions.Get(threadTS); ok && sess.GitProvider != nil {
+			q.Set("repo", sess.GitProvider.RepoURL())
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(a.reviewerURL, "/")+"/stats?"+q.Encode(), nil)
+	if err != nil {
+		return "", fmt.Errorf("build stats request: %w", err)
+	}
+	resp, err := statsClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetch review stats: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return "", fmt.Errorf("read review stats: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("reviewer returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return ":bar_chart: " + string(body), nil
+}
@@ -0,0 +1,124 @@
+package planner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+
+	"github.com/jadenj13/droid/internals/tracker"
+)
+
+// TrackerTarget is where a session files its issues in a tracker, in
+// addition to the repository issues the executor works from.
+type TrackerTarget struct {
+	Name      string   // the tracker's name, e.g. "Jira"
+	Project   string   // Jira project or Linear team key; empty uses the tracker's default
+	IssueType string   // Jira only; empty uses JIRA_ISSUE_TYPE
+	Labels    []string // added to every ticket
+}
+
+var toolSetTracker = anthropic.ToolParam{
+	Name:        "set_tracker",
+	Description: anthropic.String("Chooses where this session's issues are tracked. With 'jira' or 'linear', create_issue files each issue as a ticket there too: the ticket is linked from the repository issue the executor works on, and the PR opened for it is linked back from the ticket. With 'repository', issues are only created in the repository. Call it when the user says their team tracks work in Jira or Linear."),
+	InputSchema: anthropic.ToolInputSchemaParam{
+		Properties: map[string]interface{}{
+			"tracker": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"jira", "linear", "repository"},
+				"description": "Where issues are tracked.",
+			},
+			"project_key": map[string]interface{}{
+				"type":        "string",
+				"description": "Jira project key or Linear team key, e.g. 'PAY'. Omit to use the configured default.",
+			},
+			"issue_type": map[string]interface{}{
+				"type":        "string",
+				"description": "Jira issue type for the tickets, e.g. 'Story' or 'Task'. Omit to use the configured default; Linear has no issue types.",
+			},
+			"labels": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Labels added to every ticket, in addition to the issue's own. Linear only applies labels that already exist.",
+			},
+		},
+		Required: []string{"tracker"},
+	},
+}
+
+type setTrackerInput struct {
+	Tracker    string   `json:"tracker"`
+	ProjectKey string   `json:"project_key"`
+	IssueType  string   `json:"issue_type"`
+	Labels     []string `json:"labels"`
+}
+
+func execSetTracker(ctx context.Context, raw json.RawMessage, sess *Session, trackers tracker.Set) (ToolResult, error) {
+	var input setTrackerInput
+	if err := json.Unmarshal(raw, &input); err != nil {
+		return ToolResult{}, fmt.Errorf("unmarshal set_tracker: %w", err)
+	}
+	if input.Tracker == "repository" {
+		sess.Tracker = nil
+		return ToolResult{Content: "Issues will be created in the repository only."}, nil
+	}
+	tr := trackers.Get(input.Tracker)
+	if tr == nil {
+		configured := "none"
+		if len(trackers) > 0 {
+			configured = strings.Join(trackers.Names(), ", ")
+		}
+		return ToolResult{Content: fmt.Sprintf("error: %q is not configured for this planner (configured: %s); issues can only be created in the repository", input.Tracker, configured)}, nil
+	}
+	project := strings.ToUpper(strings.TrimSpace(input.ProjectKey))
+	if err := tr.CheckProject(ctx, project); err != nil {
+		return ToolResult{Content: fmt.Sprintf("error: %s", err)}, nil
+	}
+
+	sess.Tracker = &TrackerTarget{Name: tr.Name(), Project: project, IssueType: strings.TrimSpace(input.IssueType), Labels: input.Labels}
+	where := "the default " + tr.Name() + " project"
+	if project != "" {
+		where = tr.Name() + " " + project
+	}
+	return ToolResult{Content: fmt.Sprintf("Issues will also be filed in %s, linked to their repository issues and PRs.", where)}, nil
+}
+
+// fileTicket files input as a ticket in the session's tracker project. The
+// agent:* workflow labels stay on the repository issue: on a Linear ticket,
+// agent:ready would start a second run.
+func fileTicket(ctx context.Context, tr tracker.Tracker, target *TrackerTarget, input createIssueInput) (tracker.Ticket, error) {
+	labels := append([]string(nil), target.Labels...)
+	for _, l := range input.Labels {
+		if !strings.HasPrefix(l, "agent:") {
+			labels = append(labels, l)
+		}
+	}
+	return tr.CreateTicket(ctx, tracker.TicketInput{
+		Project:     target.Project,
+		IssueType:   target.IssueType,
+		Summary:     input.Title,
+		Description: ticketDescription(target.Name, input),
+		Labels:      labels,
+	})
+}
+
+// ticketDescription renders the issue in the tracker's markup: wiki markup
+// for Jira, Markdown for Linear.
+func ticketDescription(trackerName string, input createIssueInput) string {
+	heading, bullet, rule, note := "### ", "- ", "---", "*Filed by the Planner Agent. The work is done in the linked repository issue.*"
+	if trackerName == "Jira" {
+		heading, bullet, rule, note = "h3. ", "* ", "----", "_Filed by the Planner Agent. The work is done in the linked repository issue._"
+	}
+	var sb strings.Builder
+	sb.WriteString(input.Description)
+	if len(input.AcceptanceCriteria) > 0 {
+		sb.WriteString("\n\n" + heading + "Acceptance Criteria\n")
+		for _, c := range input.AcceptanceCriteria {
+			sb.WriteString(bullet + c + "\n")
+		}
+	}
+	sb.WriteString("\n" + rule + "\n" + note)
+	return sb.String()
+}
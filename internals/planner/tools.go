@@ -4,14 +4,22 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/tracker"
 )
 
+// blockedLabel marks issues that depend on open issues; the executor leaves
+// them until their dependencies are closed.
+const blockedLabel = "agent:blocked"
+
 var toolSetRepo = anthropic.ToolParam{
 	Name:        "set_repo",
-	Description: anthropic.String("Validates and stores the repository URL for this planning session. Call this as soon as the user provides a repo URL, before creating any issues."),
+	Description: anthropic.String("Validates and stores the repository URL for this planning session: any GitHub or GitLab repository, including self-hosted GitLab. Call this as soon as the user provides a repo URL, before creating any issues."),
 	InputSchema: anthropic.ToolInputSchemaParam{
 		Properties: map[string]interface{}{
 			"repo_url": map[string]interface{}{
@@ -44,7 +52,21 @@ var toolCreateIssue = anthropic.ToolParam{
 			"labels": map[string]interface{}{
 				"type":        "array",
 				"items":       map[string]interface{}{"type": "string"},
-				"description": "Labels to apply. Always include 'agent:ready'.",
+				"description": "Labels to apply. Always include 'agent:ready' and exactly one type label: 'bug', 'feature' or 'refactor'.",
+			},
+			"depends_on": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "integer"},
+				"description": "Numbers of issues that must be completed before this one: issues created earlier in this session, or existing issues in the repository. Create dependencies first. The issue is labeled agent:blocked and the executor waits until they are closed. Omit if there are none.",
+			},
+			"also_repos": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "URLs of other repositories this issue must change in the same piece of work, e.g. the client of an API change. The executor clones them too and opens a linked PR in each. Omit for single-repository issues.",
+			},
+			"base_branch": map[string]interface{}{
+				"type":        "string",
+				"description": "Branch the work must start from and the PR must target, e.g. 'release/1.4' for a backport. Omit to use the repository's default branch.",
 			},
 		},
 		Required: []string{"title", "description", "acceptance_criteria", "labels"},
@@ -53,50 +75,200 @@ var toolCreateIssue = anthropic.ToolParam{
 
 var toolFinishPlanning = anthropic.ToolParam{
 	Name:        "finish_planning",
-	Description: anthropic.String("Marks the planning session as complete after all issues have been created."),
+	Description: anthropic.String("Marks the planning session as complete after all issues have been created. Optionally creates a tracking issue that aggregates the PRD summary, a task list of the created issues, and a dependency graph."),
 	InputSchema: anthropic.ToolInputSchemaParam{
 		Properties: map[string]interface{}{
 			"summary": map[string]interface{}{
 				"type":        "string",
 				"description": "Brief summary of what was planned and how many issues were created.",
 			},
+			"create_tracking_issue": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Whether to create a parent tracking issue. Ask the user if they want one; defaults to true.",
+			},
+			"title": map[string]interface{}{
+				"type":        "string",
+				"description": "Title for the tracking issue. E.g. 'Tracking: user authentication'",
+			},
+			"key_decisions": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "The key decisions made during planning and the reasoning behind them, one per item. Recorded on the tracking issue with the conversation transcript.",
+			},
 		},
 		Required: []string{"summary"},
 	},
 }
 
-var AllTools = []anthropic.ToolParam{toolSetRepo, toolCreateIssue, toolFinishPlanning}
+var toolUpdatePRD = anthropic.ToolParam{
+	Name:        "update_prd",
+	Description: anthropic.String("Saves a complete PRD as a new version of the draft. Use this for the first draft and for rewrites that touch most of the document. A diff summary is shown to the user automatically."),
+	InputSchema: anthropic.ToolInputSchemaParam{
+		Properties: map[string]interface{}{
+			"content": map[string]interface{}{
+				"type":        "string",
+				"description": "The full PRD in markdown, with one heading per section.",
+			},
+			"note": map[string]interface{}{
+				"type":        "string",
+				"description": "One line describing what changed in this version.",
+			},
+		},
+		Required: []string{"content", "note"},
+	},
+}
+
+var toolRevisePRDSection = anthropic.ToolParam{
+	Name:        "revise_prd_section",
+	Description: anthropic.String("Replaces the body of one PRD section and saves the result as a new version. Use this for targeted edits such as 'change the Goals section to …'. A diff summary is shown to the user automatically."),
+	InputSchema: anthropic.ToolInputSchemaParam{
+		Properties: map[string]interface{}{
+			"section": map[string]interface{}{
+				"type":        "string",
+				"description": "Heading of the section to replace, e.g. 'Goals & Non-goals'. A leading word such as 'Goals' also matches.",
+			},
+			"content": map[string]interface{}{
+				"type":        "string",
+				"description": "New body of the section, without its heading.",
+			},
+			"note": map[string]interface{}{
+				"type":        "string",
+				"description": "One line describing the change.",
+			},
+		},
+		Required: []string{"section", "content", "note"},
+	},
+}
+
+var toolRevertPRD = anthropic.ToolParam{
+	Name:        "revert_prd",
+	Description: anthropic.String("Restores an earlier PRD version. The restored text is saved as a new version, so the revert itself can be undone."),
+	InputSchema: anthropic.ToolInputSchemaParam{
+		Properties: map[string]interface{}{
+			"version": map[string]interface{}{
+				"type":        "integer",
+				"description": "Version number to restore, as listed in the PRD history.",
+			},
+		},
+		Required: []string{"version"},
+	},
+}
+
+var toolSetStage = anthropic.ToolParam{
+	Name:        "set_stage",
+	Description: anthropic.String("Moves the planning session to another stage. Call it when the user agrees to move on, or asks to, e.g. 'move to PRD' or 'back to brainstorming'. Saving a PRD, criteria or an issue moves the session forward on its own. Use finish_planning, not this, to finish."),
+	InputSchema: anthropic.ToolInputSchemaParam{
+		Properties: map[string]interface{}{
+			"stage": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"brainstorm", "prd", "criteria", "issues"},
+				"description": "The stage to move to.",
+			},
+		},
+		Required: []string{"stage"},
+	},
+}
+
+var toolSaveCriteria = anthropic.ToolParam{
+	Name:        "save_criteria",
+	Description: anthropic.String("Saves the acceptance criteria for the plan, replacing any saved before. Call it whenever you write or revise them, then present them to the user."),
+	InputSchema: anthropic.ToolInputSchemaParam{
+		Properties: map[string]interface{}{
+			"criteria": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "The criteria, one per item, each as 'Given [context], when [action], then [outcome]'.",
+			},
+		},
+		Required: []string{"criteria"},
+	},
+}
+
+var AllTools = []anthropic.ToolParam{toolSetRepo, toolSetTracker, toolSetStage, toolUpdatePRD, toolRevisePRDSection, toolRevertPRD, toolPublishPRD, toolSaveCriteria, toolProposeIssues, toolCreateIssue, toolUpdateIssue, toolCloseIssue, toolFinishPlanning}
 
 type setRepoInput struct {
 	RepoURL string `json:"repo_url"`
 }
 
+type setStageInput struct {
+	Stage string `json:"stage"`
+}
+
+type saveCriteriaInput struct {
+	Criteria []string `json:"criteria"`
+}
+
 type createIssueInput struct {
 	Title              string   `json:"title"`
 	Description        string   `json:"description"`
 	AcceptanceCriteria []string `json:"acceptance_criteria"`
 	Labels             []string `json:"labels"`
+	DependsOn          []int    `json:"depends_on"`
+	AlsoRepos          []string `json:"also_repos"`
+	BaseBranch         string   `json:"base_branch"`
 }
 
 type finishPlanningInput struct {
-	Summary string `json:"summary"`
+	Summary             string   `json:"summary"`
+	CreateTrackingIssue *bool    `json:"create_tracking_issue"`
+	Title               string   `json:"title"`
+	KeyDecisions        []string `json:"key_decisions"`
+}
+
+type updatePRDInput struct {
+	Content string `json:"content"`
+	Note    string `json:"note"`
+}
+
+type revisePRDSectionInput struct {
+	Section string `json:"section"`
+	Content string `json:"content"`
+	Note    string `json:"note"`
+}
+
+type revertPRDInput struct {
+	Version int `json:"version"`
 }
 
 type ToolResult struct {
 	Content string
+	Reply   string // appended verbatim to the Slack reply, bypassing the model
+	// ShowBreakdown posts the session's proposal with its approval buttons
+	// after the reply.
+	ShowBreakdown bool
 }
 type ProviderFactory interface {
 	ProviderFor(ctx context.Context, repoURL string) (git.GitProvider, git.RepoInfo, error)
 }
 
-func ExecuteTool(ctx context.Context, name string, raw json.RawMessage, sess *Session, factory ProviderFactory) (ToolResult, error) {
+func ExecuteTool(ctx context.Context, name string, raw json.RawMessage, sess *Session, factory ProviderFactory, trackers tracker.Set, log *slog.Logger) (ToolResult, error) {
 	switch name {
 	case "set_repo":
 		return execSetRepo(ctx, raw, sess, factory)
+	case "set_tracker":
+		return execSetTracker(ctx, raw, sess, trackers)
+	case "set_stage":
+		return execSetStage(raw, sess)
+	case "update_prd":
+		return execUpdatePRD(raw, sess)
+	case "revise_prd_section":
+		return execRevisePRDSection(raw, sess)
+	case "revert_prd":
+		return execRevertPRD(raw, sess)
+	case "publish_prd":
+		return execPublishPRD(ctx, raw, sess)
+	case "save_criteria":
+		return execSaveCriteria(raw, sess)
+	case "propose_issues":
+		return execProposeIssues(raw, sess)
 	case "create_issue":
-		return execCreateIssue(ctx, raw, sess)
+		return execCreateIssue(ctx, raw, sess, trackers, log)
+	case "update_issue":
+		return execUpdateIssue(ctx, raw, sess)
+	case "close_issue":
+		return execCloseIssue(ctx, raw, sess)
 	case "finish_planning":
-		return execFinishPlanning(raw, sess)
+		return execFinishPlanning(ctx, raw, sess)
 	default:
 		return ToolResult{}, fmt.Errorf("unknown tool: %s", name)
 	}
@@ -123,50 +295,302 @@ func execSetRepo(ctx context.Context, raw json.RawMessage, sess *Session, factor
 	}, nil
 }
 
-func execCreateIssue(ctx context.Context, raw json.RawMessage, sess *Session) (ToolResult, error) {
+func execSetStage(raw json.RawMessage, sess *Session) (ToolResult, error) {
+	var input setStageInput
+	if err := json.Unmarshal(raw, &input); err != nil {
+		return ToolResult{}, fmt.Errorf("unmarshal set_stage: %w", err)
+	}
+	stage, ok := ParseStage(input.Stage)
+	if !ok || stage == StageDone {
+		return ToolResult{Content: fmt.Sprintf("error: unknown stage %q — expected brainstorm, prd, criteria or issues", input.Stage)}, nil
+	}
+	// Moving back is always allowed; moving on needs the earlier stage's work.
+	switch {
+	case stage >= StageCriteria && sess.PRDDraft == "":
+		return ToolResult{Content: "error: there is no PRD yet — write one with update_prd before moving on"}, nil
+	case stage >= StageIssues && len(sess.Criteria) == 0:
+		return ToolResult{Content: "error: there are no acceptance criteria yet — save them with save_criteria before moving on"}, nil
+	}
+	prev := sess.Stage
+	sess.Stage = stage
+	return ToolResult{Content: fmt.Sprintf("Stage changed from %s to %s.", prev, stage)}, nil
+}
+
+func execSaveCriteria(raw json.RawMessage, sess *Session) (ToolResult, error) {
+	var input saveCriteriaInput
+	if err := json.Unmarshal(raw, &input); err != nil {
+		return ToolResult{}, fmt.Errorf("unmarshal save_criteria: %w", err)
+	}
+	var criteria []string
+	for _, c := range input.Criteria {
+		if c = strings.TrimSpace(c); c != "" {
+			criteria = append(criteria, c)
+		}
+	}
+	if len(criteria) == 0 {
+		return ToolResult{Content: "error: no criteria given"}, nil
+	}
+	sess.Criteria = criteria
+	sess.advanceTo(StageCriteria)
+	return ToolResult{Content: fmt.Sprintf("Saved %d acceptance criteria. Present them to the user and ask for feedback.", len(criteria))}, nil
+}
+
+func execUpdatePRD(raw json.RawMessage, sess *Session) (ToolResult, error) {
+	var input updatePRDInput
+	if err := json.Unmarshal(raw, &input); err != nil {
+		return ToolResult{}, fmt.Errorf("unmarshal update_prd: %w", err)
+	}
+	v, diff := sess.savePRD(input.Content, input.Note)
+	return prdSavedResult(v, diff), nil
+}
+
+func execRevisePRDSection(raw json.RawMessage, sess *Session) (ToolResult, error) {
+	var input revisePRDSectionInput
+	if err := json.Unmarshal(raw, &input); err != nil {
+		return ToolResult{}, fmt.Errorf("unmarshal revise_prd_section: %w", err)
+	}
+	if sess.PRDDraft == "" {
+		return ToolResult{Content: "error: there is no PRD draft yet — write one with update_prd first"}, nil
+	}
+
+	revised, err := replaceSection(sess.PRDDraft, input.Section, input.Content)
+	if err != nil {
+		return ToolResult{Content: fmt.Sprintf("error: %s", err)}, nil
+	}
+	v, diff := sess.savePRD(revised, input.Note)
+	return prdSavedResult(v, diff), nil
+}
+
+func execRevertPRD(raw json.RawMessage, sess *Session) (ToolResult, error) {
+	var input revertPRDInput
+	if err := json.Unmarshal(raw, &input); err != nil {
+		return ToolResult{}, fmt.Errorf("unmarshal revert_prd: %w", err)
+	}
+	old, ok := sess.prdVersion(input.Version)
+	if !ok {
+		return ToolResult{Content: fmt.Sprintf("error: no PRD version %d — there are %d versions", input.Version, len(sess.PRDVersions))}, nil
+	}
+	v, diff := sess.savePRD(old.Content, fmt.Sprintf("Reverted to v%d", old.Number))
+	return prdSavedResult(v, diff), nil
+}
+
+// prdSavedResult tells the model the version was saved and shows the user the
+// diff directly, so the model doesn't need to repeat the whole document.
+func prdSavedResult(v PRDVersion, diff string) ToolResult {
+	if v.Number == 1 {
+		return ToolResult{
+			Content: "Saved PRD v1. Present it to the user in full and ask for feedback.",
+			Reply:   fmt.Sprintf(":memo: *PRD v1* saved — %s", diff),
+		}
+	}
+	return ToolResult{
+		Content: fmt.Sprintf("Saved PRD v%d. The user has been shown this diff summary — do not repeat the full PRD:\n%s", v.Number, diff),
+		Reply:   fmt.Sprintf(":memo: *PRD v%d* — %s\n%s", v.Number, v.Note, diff),
+	}
+}
+
+func execCreateIssue(ctx context.Context, raw json.RawMessage, sess *Session, trackers tracker.Set, log *slog.Logger) (ToolResult, error) {
 	if sess.GitProvider == nil {
 		return ToolResult{Content: "error: no repository configured — ask the user for a repo URL first"}, nil
 	}
 
+	if p := sess.Proposal; p != nil && !p.Done {
+		return ToolResult{Content: "error: the proposed breakdown is awaiting approval in Slack. Its issues are created when the user presses Create approved issues; do not create them with create_issue"}, nil
+	}
+
 	var input createIssueInput
 	if err := json.Unmarshal(raw, &input); err != nil {
 		return ToolResult{}, fmt.Errorf("unmarshal create_issue: %w", err)
 	}
 
+	linked, blocked, errMsg := createIssue(ctx, sess, trackers, input, log)
+	if errMsg != "" {
+		return ToolResult{Content: "error: " + errMsg}, nil
+	}
+	content := fmt.Sprintf("Created issue #%d: %s\n%s", linked.Number, linked.Title, linked.URL)
+	if linked.Ticket != "" {
+		content += fmt.Sprintf("\nFiled as %s ticket %s: %s", sess.Tracker.Name, linked.Ticket, linked.TicketURL)
+	}
+	if blocked {
+		content += fmt.Sprintf("\nLabeled %s: the executor starts it once its dependencies are closed.", blockedLabel)
+	}
+	return ToolResult{Content: content}, nil
+}
+
+// createIssue creates input's issue in the session's repository and records
+// it in the session. It reports whether the issue was labeled blocked; a
+// problem is returned as a message.
+func createIssue(ctx context.Context, sess *Session, trackers tracker.Set, input createIssueInput, log *slog.Logger) (LinkedIssue, bool, string) {
+	blocked, errMsg := checkDependencies(ctx, sess, input.DependsOn)
+	if errMsg != "" {
+		return LinkedIssue{}, false, errMsg
+	}
+	labels := slices.Clone(input.Labels)
+	if blocked && !slices.Contains(labels, blockedLabel) {
+		labels = append(labels, blockedLabel)
+	}
+
+	// The ticket is filed first so the repository issue can name it.
+	var ticket tracker.Ticket
+	var tr tracker.Tracker
+	if sess.Tracker != nil {
+		if tr = trackers.Get(sess.Tracker.Name); tr == nil {
+			return LinkedIssue{}, false, fmt.Sprintf("the session tracks issues in %s, but %s is no longer configured for this planner — call set_tracker with 'repository'", sess.Tracker.Name, sess.Tracker.Name)
+		}
+		var err error
+		if ticket, err = fileTicket(ctx, tr, sess.Tracker, input); err != nil {
+			return LinkedIssue{}, false, fmt.Sprintf("filing the %s ticket: %s", tr.Name(), err)
+		}
+	}
+
 	issue, err := sess.GitProvider.CreateIssue(ctx, git.IssueInput{
 		Title:  input.Title,
-		Body:   buildIssueBody(input.Description, input.AcceptanceCriteria),
-		Labels: input.Labels,
+		Body:   buildIssueBody(input.Description, input.AcceptanceCriteria, input.DependsOn, sess.PublishedPRD, input.AlsoRepos, input.BaseBranch, ticket),
+		Labels: labels,
 	})
 	if err != nil {
-		return ToolResult{Content: fmt.Sprintf("error creating issue: %s", err)}, nil
+		if ticket.Key != "" {
+			return LinkedIssue{}, false, fmt.Sprintf("creating issue: %s (%s ticket %s was filed without it)", err, tr.Name(), ticket.Key)
+		}
+		return LinkedIssue{}, false, fmt.Sprintf("creating issue: %s", err)
+	}
+	if ticket.Key != "" {
+		// Best effort: the issue names the ticket either way, and the
+		// executor links the PR from it.
+		if err := tr.AddLink(ctx, ticket.Key, issue.URL, fmt.Sprintf("Issue #%d: %s", issue.Number, issue.Title)); err != nil {
+			log.Warn("failed to link issue from ticket", "ticket", ticket.Key, "issue", issue.Number, "err", err)
+		}
 	}
 
-	sess.Issues = append(sess.Issues, LinkedIssue{
-		Number: issue.Number,
-		Title:  issue.Title,
-		URL:    issue.URL,
-	})
+	linked := LinkedIssue{
+		Number:    issue.Number,
+		Title:     issue.Title,
+		URL:       issue.URL,
+		DependsOn: input.DependsOn,
+		Ticket:    ticket.Key,
+		TicketURL: ticket.URL,
+	}
+	sess.Issues = append(sess.Issues, linked)
+	sess.advanceTo(StageIssues)
+	return linked, blocked, ""
+}
 
-	return ToolResult{
-		Content: fmt.Sprintf("Created issue #%d: %s\n%s", issue.Number, issue.Title, issue.URL),
-	}, nil
+// checkDependencies checks that every issue in dependsOn exists before the
+// issue depending on it is created, so issues are created in dependency
+// order, and reports whether any of them is still open. A problem is
+// returned as a message for the model.
+func checkDependencies(ctx context.Context, sess *Session, dependsOn []int) (blocked bool, errMsg string) {
+	for _, n := range dependsOn {
+		if slices.ContainsFunc(sess.Issues, func(l LinkedIssue) bool { return l.Number == n && l.Closed }) {
+			return false, fmt.Sprintf("depends_on #%d was closed with close_issue and is no longer part of the plan", n)
+		}
+		dep, err := sess.GitProvider.GetIssue(ctx, n)
+		if err != nil {
+			return false, fmt.Sprintf("depends_on #%d could not be fetched (%s). Create dependencies before the issues that depend on them", n, err)
+		}
+		if dep.State != "closed" {
+			blocked = true
+		}
+	}
+	return blocked, ""
 }
 
-func execFinishPlanning(raw json.RawMessage, sess *Session) (ToolResult, error) {
+func execFinishPlanning(ctx context.Context, raw json.RawMessage, sess *Session) (ToolResult, error) {
 	var input finishPlanningInput
 	if err := json.Unmarshal(raw, &input); err != nil {
 		return ToolResult{}, fmt.Errorf("unmarshal finish_planning: %w", err)
 	}
 	sess.Stage = StageDone
-	return ToolResult{Content: "Planning session marked as complete."}, nil
+
+	if sess.GitProvider == nil || len(sess.Issues) == 0 {
+		return ToolResult{Content: "Planning session marked as complete."}, nil
+	}
+
+	graph := dependencyGraph(sess.Issues)
+	if input.CreateTrackingIssue != nil && !*input.CreateTrackingIssue {
+		return ToolResult{
+			Content: "Planning session marked as complete. No tracking issue was created.",
+			Reply:   "Dependency graph:\n" + graph,
+		}, nil
+	}
+
+	title := input.Title
+	if title == "" {
+		title = "Tracking: planned work"
+	}
+
+	tracking, err := sess.GitProvider.CreateIssue(ctx, git.IssueInput{
+		Title:  title,
+		Body:   buildTrackingBody(input.Summary, sess.PRDDraft, graph, sess.Issues, input.KeyDecisions, sessionTranscript(sess)),
+		Labels: []string{"agent:tracking"},
+	})
+	if err != nil {
+		return ToolResult{
+			Content: fmt.Sprintf("Planning session marked as complete, but creating the tracking issue failed: %s", err),
+			Reply:   "Dependency graph:\n" + graph,
+		}, nil
+	}
+	sess.TrackingIssue = &LinkedIssue{Number: tracking.Number, Title: tracking.Title, URL: tracking.URL}
+
+	return ToolResult{
+		Content: fmt.Sprintf("Planning session marked as complete. Created tracking issue #%d: %s", tracking.Number, tracking.URL),
+		Reply:   fmt.Sprintf("Tracking issue: <%s|#%d>\nDependency graph:\n%s", tracking.URL, tracking.Number, graph),
+	}, nil
 }
 
-func buildIssueBody(description string, ac []string) string {
+func buildIssueBody(description string, ac []string, dependsOn []int, prd *PublishedPRD, alsoRepos []string, base string, ticket tracker.Ticket) string {
 	body := fmt.Sprintf("## Description\n\n%s\n\n## Acceptance Criteria\n", description)
 	for _, c := range ac {
 		body += fmt.Sprintf("- [ ] %s\n", c)
 	}
+	if len(dependsOn) > 0 {
+		body += "\n## Depends On\n\n" + dependsOnSection(dependsOn)
+	}
+	if prd != nil {
+		body += "\n## PRD\n\n" + prdSection(prd)
+	}
+	if len(alsoRepos) > 0 {
+		// The executor reads this line to clone the other repositories.
+		body += "\nAlso-Repos: " + strings.Join(alsoRepos, ", ") + "\n"
+	}
+	if base = strings.TrimSpace(base); base != "" {
+		// The executor reads this line to pick the branch to work from.
+		body += "\nBase: " + base + "\n"
+	}
+	if ticket.URL != "" {
+		// The executor reads this line to link the PR from the ticket.
+		body += "\n" + ticket.Line() + "\n"
+	}
 	body += "\n---\n*Created by the Planner Agent*"
 	return body
 }
+
+// trackingMarker identifies tracking issues created by the planner.
+const trackingMarker = "<!-- droid:tracking -->"
+
+// buildTrackingBody renders the tracking issue: the summary, the PRD folded
+// into a details block, a task list with one checkbox per child issue (GitHub
+// and GitLab both render "- [ ] #N" as a linked task), the dependency graph,
+// and the key decisions with the planning transcript that led to them.
+func buildTrackingBody(summary, prd, graph string, issues []LinkedIssue, decisions []string, transcript string) string {
+	body := trackingMarker + "\n" + fmt.Sprintf("## Summary\n\n%s\n", summary)
+	if prd != "" {
+		body += fmt.Sprintf("\n<details>\n<summary>PRD</summary>\n\n%s\n\n</details>\n", prd)
+	}
+	body += "\n## Tasks\n\n"
+	for _, iss := range openIssues(issues) {
+		body += fmt.Sprintf("- [ ] #%d %s\n", iss.Number, iss.Title)
+	}
+	body += fmt.Sprintf("\n## Dependency Graph\n\n%s\n", graph)
+	if len(decisions) > 0 {
+		body += "\n## Key Decisions\n\n"
+		for _, d := range decisions {
+			body += fmt.Sprintf("- %s\n", d)
+		}
+	}
+	if transcript != "" {
+		body += fmt.Sprintf("\n<details>\n<summary>Planning transcript</summary>\n\n%s\n\n</details>\n", transcript)
+	}
+	body += "\n---\n*Created by the Planner Agent — task boxes are checked as PRs merge*"
+	return body
+}
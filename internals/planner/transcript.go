@@ -0,0 +1,66 @@
+package planner
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jadenj13/droid/internals/llm"
+)
+
+const (
+	maxTranscriptMessageChars = 2000
+	// maxTranscriptChars keeps the tracking issue well under GitHub's 65536
+	// character body limit once the PRD and task list are added.
+	maxTranscriptChars = 30000
+)
+
+var (
+	slackMention = regexp.MustCompile(`<@[A-Z0-9]+>`)
+	slackLink    = regexp.MustCompile(`<(https?://[^|>]+)\|([^>]+)>`)
+	slackBare    = regexp.MustCompile(`<(https?://[^>]+)>`)
+)
+
+// buildTranscript renders the session's conversation as markdown for the
+// tracking issue, with Slack markup converted and long messages shortened.
+func buildTranscript(msgs []llm.Message) string {
+	var sb strings.Builder
+	for i, m := range msgs {
+		text := cleanSlackText(m.Content)
+		if text == "" {
+			continue
+		}
+		speaker := "**User**"
+		if m.Role == "assistant" {
+			speaker = "**Planner**"
+		}
+		if len(text) > maxTranscriptMessageChars {
+			text = text[:maxTranscriptMessageChars] + " …"
+		}
+		entry := fmt.Sprintf("%s: %s\n\n", speaker, text)
+		if sb.Len()+len(entry) > maxTranscriptChars {
+			sb.WriteString(fmt.Sprintf("*… %d later messages omitted*\n", len(msgs)-i))
+			break
+		}
+		sb.WriteString(entry)
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// sessionTranscript is the session's transcript, led by the summary of the
+// messages compaction dropped.
+func sessionTranscript(sess *Session) string {
+	transcript := buildTranscript(sess.Messages)
+	if sess.Summary == "" {
+		return transcript
+	}
+	return "*Earlier conversation, summarised:*\n\n" + sess.Summary + "\n\n" + transcript
+}
+
+func cleanSlackText(s string) string {
+	s = slackMention.ReplaceAllString(s, "")
+	s = slackLink.ReplaceAllString(s, "[$2]($1)")
+	s = slackBare.ReplaceAllString(s, "$1")
+	s = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&").Replace(s)
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,380 @@
+package queue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusPending Status = "pending"
+	StatusFailed  Status = "failed" // exhausted its attempts; kept for inspection
+)
+
+type Job struct {
+	ID        string          `json:"id"`
+	Kind      string          `json:"kind"`
+	Group     string          `json:"group,omitempty"` // jobs in one group share a concurrency limit
+	Key       string          `json:"key,omitempty"`   // at most one pending job per key
+	Payload   json.RawMessage `json:"payload"`
+	Status    Status          `json:"status"`
+	Attempts  int             `json:"attempts"`
+	LastError string          `json:"last_error,omitempty"`
+	NotBefore time.Time       `json:"not_before"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type HandlerFunc func(ctx context.Context, payload json.RawMessage) error
+
+// FailureFunc is called once a job has exhausted its attempts.
+type FailureFunc func(ctx context.Context, job Job)
+
+// GroupFunc assigns a job to a concurrency group, e.g. its repository.
+type GroupFunc func(kind string, payload json.RawMessage) string
+
+// KeyFunc assigns a job its deduplication key, e.g. the issue it works on.
+type KeyFunc func(kind string, payload json.RawMessage) string
+
+// ErrDuplicate is returned by Enqueue when a job with the same key is already
+// queued or running.
+var ErrDuplicate = errors.New("duplicate job")
+
+// Queue is a durable job queue with a bounded worker pool. Jobs are persisted
+// before Enqueue returns and deleted only once their handler succeeds, so work
+// accepted before a crash is picked up again on the next Run.
+//
+// Due jobs run in FIFO order, except that a job whose group is already at its
+// limit waits while later jobs from other groups go ahead.
+type Queue struct {
+	store       Store
+	log         *slog.Logger
+	workers     int
+	maxAttempts int
+	groupOf     GroupFunc
+	groupLimit  int // 0 means only the worker count applies
+	keyOf       KeyFunc
+
+	mu       sync.RWMutex
+	handlers map[string]HandlerFunc
+	failures map[string]FailureFunc
+
+	smu       sync.Mutex
+	due       []Job           // ready to run, oldest first
+	delayed   int             // waiting for their NotBefore time
+	scheduled map[string]bool // IDs of delayed and due jobs
+	running   map[string]int  // group → jobs in flight
+	keys      map[string]bool // keys of jobs not yet finished
+	active    int
+	wake      chan struct{}
+}
+
+type Option func(*Queue)
+
+// WithWorkers sets how many jobs run concurrently. Defaults to 2.
+func WithWorkers(n int) Option {
+	return func(q *Queue) { q.workers = n }
+}
+
+// WithMaxAttempts sets how many times a job is tried before it is marked
+// failed. Defaults to 3.
+func WithMaxAttempts(n int) Option {
+	return func(q *Queue) { q.maxAttempts = n }
+}
+
+// WithGroupLimit caps how many jobs of the same group run at once, where
+// group assigns each job its group. Jobs with an empty group are not capped.
+func WithGroupLimit(n int, group GroupFunc) Option {
+	return func(q *Queue) {
+		q.groupLimit = n
+		q.groupOf = group
+	}
+}
+
+// WithDedup makes Enqueue refuse a job while another with the same key, as
+// assigned by key, is queued, retrying or running. Jobs with an empty key are
+// never refused.
+func WithDedup(key KeyFunc) Option {
+	return func(q *Queue) { q.keyOf = key }
+}
+
+func New(store Store, log *slog.Logger, opts ...Option) *Queue {
+	q := &Queue{
+		store:       store,
+		log:         log,
+		workers:     2,
+		maxAttempts: 3,
+		handlers:    make(map[string]HandlerFunc),
+		failures:    make(map[string]FailureFunc),
+		running:     make(map[string]int),
+		scheduled:   make(map[string]bool),
+		keys:        make(map[string]bool),
+		wake:        make(chan struct{}, 1),
+	}
+	for _, o := range opts {
+		o(q)
+	}
+	return q
+}
+
+// Handle registers the handler for jobs of kind.
+func (q *Queue) Handle(kind string, h HandlerFunc) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[kind] = h
+}
+
+// OnFailure registers a callback for jobs of kind that fail permanently.
+func (q *Queue) OnFailure(kind string, f FailureFunc) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.failures[kind] = f
+}
+
+// Enqueue persists a new job and schedules it for immediate processing. With
+// WithDedup, it returns ErrDuplicate instead if the job's key is taken.
+func (q *Queue) Enqueue(kind string, payload any) error {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal %s payload: %w", kind, err)
+	}
+	now := time.Now()
+	job := Job{
+		ID:        newID(now),
+		Kind:      kind,
+		Group:     q.group(kind, b),
+		Key:       q.key(kind, b),
+		Payload:   b,
+		Status:    StatusPending,
+		NotBefore: now,
+		CreatedAt: now,
+	}
+	if !q.claim(job.Key) {
+		return fmt.Errorf("%w: %s", ErrDuplicate, job.Key)
+	}
+	if err := q.store.Put(job); err != nil {
+		q.release(job)
+		return err
+	}
+	q.schedule(job)
+	return nil
+}
+
+// Run reloads pending jobs from the store and processes jobs until ctx is
+// cancelled, then waits for in-flight jobs to return. Jobs interrupted by
+// shutdown stay pending and run again on the next start.
+func (q *Queue) Run(ctx context.Context) error {
+	jobs, err := q.store.List()
+	if err != nil {
+		return fmt.Errorf("load pending jobs: %w", err)
+	}
+	for _, job := range jobs {
+		if job.Status == StatusPending {
+			if job.Group == "" {
+				job.Group = q.group(job.Kind, job.Payload)
+			}
+			if job.Key == "" {
+				job.Key = q.key(job.Kind, job.Payload)
+			}
+			q.claim(job.Key)
+			q.schedule(job)
+		}
+	}
+	if len(jobs) > 0 {
+		q.log.Info("queue resumed", "jobs", len(jobs))
+	}
+
+	var wg sync.WaitGroup
+	for {
+		for ctx.Err() == nil {
+			job, ok := q.next()
+			if !ok {
+				break
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				q.process(ctx, job)
+				q.finish(job)
+			}()
+		}
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return nil
+		case <-q.wake:
+		}
+	}
+}
+
+// next takes the oldest due job that may start now, if any.
+func (q *Queue) next() (Job, bool) {
+	q.smu.Lock()
+	defer q.smu.Unlock()
+	if q.active >= q.workers {
+		return Job{}, false
+	}
+	for i, job := range q.due {
+		if q.groupLimit > 0 && job.Group != "" && q.running[job.Group] >= q.groupLimit {
+			continue
+		}
+		q.due = append(q.due[:i], q.due[i+1:]...)
+		delete(q.scheduled, job.ID)
+		q.active++
+		q.running[job.Group]++
+		return job, true
+	}
+	return Job{}, false
+}
+
+func (q *Queue) finish(job Job) {
+	q.smu.Lock()
+	q.active--
+	if q.running[job.Group]--; q.running[job.Group] <= 0 {
+		delete(q.running, job.Group)
+	}
+	q.smu.Unlock()
+	q.signal()
+}
+
+func (q *Queue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *Queue) group(kind string, payload json.RawMessage) string {
+	if q.groupOf == nil {
+		return ""
+	}
+	return q.groupOf(kind, payload)
+}
+
+func (q *Queue) key(kind string, payload json.RawMessage) string {
+	if q.keyOf == nil {
+		return ""
+	}
+	return q.keyOf(kind, payload)
+}
+
+// claim takes key for a new job, reporting false if another job holds it.
+func (q *Queue) claim(key string) bool {
+	if key == "" {
+		return true
+	}
+	q.smu.Lock()
+	defer q.smu.Unlock()
+	if q.keys[key] {
+		return false
+	}
+	q.keys[key] = true
+	return true
+}
+
+// release frees the key of a job that is done, so the same work can be
+// enqueued again.
+func (q *Queue) release(job Job) {
+	if job.Key == "" {
+		return
+	}
+	q.smu.Lock()
+	delete(q.keys, job.Key)
+	q.smu.Unlock()
+}
+
+func (q *Queue) process(ctx context.Context, job Job) {
+	q.mu.RLock()
+	h, ok := q.handlers[job.Kind]
+	q.mu.RUnlock()
+	if !ok {
+		job.Status = StatusFailed
+		job.LastError = "no handler registered for kind " + job.Kind
+		q.persist(job)
+		q.release(job)
+		q.log.Error("job has no handler", "job", job.ID, "kind", job.Kind)
+		return
+	}
+
+	err := h(ctx, job.Payload)
+	if err == nil {
+		if err := q.store.Delete(job.ID); err != nil {
+			q.log.Error("delete finished job", "job", job.ID, "err", err)
+		}
+		q.release(job)
+		return
+	}
+	if ctx.Err() != nil {
+		return // interrupted by shutdown — leave it pending for the next start
+	}
+
+	job.Attempts++
+	job.LastError = err.Error()
+	if job.Attempts >= q.maxAttempts {
+		job.Status = StatusFailed
+		q.persist(job)
+		q.release(job)
+		q.log.Error("job failed permanently", "job", job.ID, "kind", job.Kind, "attempts", job.Attempts, "err", err)
+		q.mu.RLock()
+		onFailure := q.failures[job.Kind]
+		q.mu.RUnlock()
+		if onFailure != nil {
+			onFailure(ctx, job)
+		}
+		return
+	}
+
+	job.NotBefore = time.Now().Add(retryDelay(job.Attempts))
+	q.persist(job)
+	q.log.Warn("job failed, will retry", "job", job.ID, "kind", job.Kind, "attempt", job.Attempts, "retry_at", job.NotBefore, "err", err)
+	q.schedule(job)
+}
+
+func (q *Queue) persist(job Job) {
+	if err := q.store.Put(job); err != nil {
+		q.log.Error("persist job", "job", job.ID, "err", err)
+	}
+}
+
+// schedule makes job due once its NotBefore time has passed. A job that is
+// already scheduled, e.g. enqueued just before Run reloads the store, is not
+// scheduled twice.
+func (q *Queue) schedule(job Job) {
+	q.smu.Lock()
+	if q.scheduled[job.ID] {
+		q.smu.Unlock()
+		return
+	}
+	q.scheduled[job.ID] = true
+	q.delayed++
+	q.smu.Unlock()
+	time.AfterFunc(max(time.Until(job.NotBefore), 0), func() {
+		q.smu.Lock()
+		q.delayed--
+		q.due = append(q.due, job)
+		q.smu.Unlock()
+		q.signal()
+	})
+}
+
+// retryDelay backs off exponentially from 30s, capped at 30 minutes.
+func retryDelay(attempt int) time.Duration {
+	d := 30 * time.Second << (attempt - 1)
+	if d > 30*time.Minute || d <= 0 {
+		d = 30 * time.Minute
+	}
+	return d
+}
+
+func newID(now time.Time) string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("%d-%s", now.UnixNano(), hex.EncodeToString(b))
+}
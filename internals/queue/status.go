@@ -0,0 +1,93 @@
+package queue
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// GroupStats is the load of one concurrency group.
+type GroupStats struct {
+	Group   string `json:"group"`
+	Running int    `json:"running"`
+	Queued  int    `json:"queued"`
+}
+
+// Stats is a snapshot of the queue's depth.
+type Stats struct {
+	Workers    int          `json:"workers"`
+	GroupLimit int          `json:"group_limit,omitempty"`
+	Running    int          `json:"running"`
+	Queued     int          `json:"queued"`  // due, waiting for a free worker or group slot
+	Delayed    int          `json:"delayed"` // waiting to retry after a failure
+	Failed     int          `json:"failed"`  // exhausted their attempts
+	Groups     []GroupStats `json:"groups,omitempty"`
+}
+
+func (q *Queue) Stats() Stats {
+	q.smu.Lock()
+	s := Stats{
+		Workers:    q.workers,
+		GroupLimit: q.groupLimit,
+		Running:    q.active,
+		Queued:     len(q.due),
+		Delayed:    q.delayed,
+	}
+	groups := make(map[string]*GroupStats)
+	get := func(name string) *GroupStats {
+		g, ok := groups[name]
+		if !ok {
+			g = &GroupStats{Group: name}
+			groups[name] = g
+		}
+		return g
+	}
+	for name, n := range q.running {
+		get(name).Running = n
+	}
+	for _, job := range q.due {
+		get(job.Group).Queued++
+	}
+	q.smu.Unlock()
+
+	for _, g := range groups {
+		if g.Group != "" {
+			s.Groups = append(s.Groups, *g)
+		}
+	}
+	sort.Slice(s.Groups, func(i, j int) bool { return s.Groups[i].Group < s.Groups[j].Group })
+
+	if jobs, err := q.store.List(); err == nil {
+		for _, job := range jobs {
+			if job.Status == StatusFailed {
+				s.Failed++
+			}
+		}
+	}
+	return s
+}
+
+// StatusHandler serves Stats as JSON. Every request needs
+// "Authorization: Bearer <token>".
+func (q *Queue) StatusHandler(token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, token) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(q.Stats())
+	})
+}
+
+func authorized(r *http.Request, token string) bool {
+	if token == "" {
+		return false
+	}
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
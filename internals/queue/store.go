@@ -0,0 +1,82 @@
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Store persists jobs so they survive restarts. Implementations must make Put
+// atomic: a crash mid-write must leave either the old or the new job.
+type Store interface {
+	Put(job Job) error
+	Delete(id string) error
+	List() ([]Job, error)
+}
+
+// FileStore keeps one JSON file per job in a directory. It needs no external
+// services, which suits a single executor instance with a persistent volume.
+type FileStore struct {
+	dir string
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create queue dir: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) Put(job Job) error {
+	b, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("marshal job %s: %w", job.ID, err)
+	}
+	// Write to a temp file and rename so readers never see a partial job.
+	tmp := filepath.Join(s.dir, job.ID+".json.tmp")
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return fmt.Errorf("write job %s: %w", job.ID, err)
+	}
+	if err := os.Rename(tmp, s.path(job.ID)); err != nil {
+		return fmt.Errorf("commit job %s: %w", job.ID, err)
+	}
+	return nil
+}
+
+func (s *FileStore) Delete(id string) error {
+	if err := os.Remove(s.path(id)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("delete job %s: %w", id, err)
+	}
+	return nil
+}
+
+func (s *FileStore) List() ([]Job, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("list queue dir: %w", err)
+	}
+	var jobs []Job
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(s.dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read job %s: %w", e.Name(), err)
+		}
+		var job Job
+		if err := json.Unmarshal(b, &job); err != nil {
+			return nil, fmt.Errorf("decode job %s: %w", e.Name(), err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+func (s *FileStore) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
@@ -0,0 +1,103 @@
+// Package redact masks secrets in text before it reaches the LLM, the logs,
+// or anything posted to a Git host.
+package redact
+
+import (
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Mask replaces each redacted secret.
+const Mask = "[REDACTED]"
+
+// minSecretLen keeps short values like "1" or "true" from being treated as
+// secrets and masked wherever they appear.
+const minSecretLen = 8
+
+// patterns match secrets by shape. Where a pattern has a capture group, only
+// the group is masked so the surrounding text (e.g. a variable name) stays
+// readable.
+var patterns = []*regexp.Regexp{
+	regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z0-9 ]*PRIVATE KEY-----`),
+	regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	regexp.MustCompile(`(?i)aws_secret_access_key["']?\s*[=:]\s*["']?([A-Za-z0-9/+=]{40})`),
+	regexp.MustCompile(`\b(?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36,}\b`),
+	regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{22,}\b`),
+	regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}`),
+	regexp.MustCompile(`\bsk-ant-[A-Za-z0-9_-]{20,}`),
+	regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`),
+	// Credentials in URLs, e.g. the token in an authenticated clone URL.
+	regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^/\s:@]*:([^/\s@]+)@`),
+	// Assignments to secret-looking names: FOO_TOKEN=..., "api_key": "...".
+	regexp.MustCompile(`(?i)\b[A-Z0-9_]*(?:TOKEN|SECRET|PASSWORD|PASSWD|API_KEY|ACCESS_KEY|PRIVATE_KEY)["']?\s*[=:]\s*["']?([^\s"',;]{8,})`),
+}
+
+// secretEnv matches the names of environment variables holding secrets.
+var secretEnv = regexp.MustCompile(`(?i)(TOKEN|SECRET|PASSWORD|_KEY|_KEYS)$`)
+
+// Redactor masks known secret values and secret-shaped strings. A nil
+// Redactor masks only by pattern.
+type Redactor struct {
+	literals []string // longest first, so a secret containing another is masked whole
+}
+
+// New returns a Redactor that also masks each of secrets wherever it appears.
+// Values shorter than 8 characters are ignored.
+func New(secrets ...string) *Redactor {
+	r := &Redactor{}
+	seen := make(map[string]bool)
+	for _, s := range secrets {
+		if s = strings.TrimSpace(s); len(s) >= minSecretLen && !seen[s] {
+			seen[s] = true
+			r.literals = append(r.literals, s)
+		}
+	}
+	sort.Slice(r.literals, func(i, j int) bool { return len(r.literals[i]) > len(r.literals[j]) })
+	return r
+}
+
+// EnvSecrets returns the values of environment variables whose names mark
+// them as secrets, e.g. GITHUB_TOKEN or ANTHROPIC_API_KEYS. Comma-separated
+// values are split so each key in a list is masked on its own.
+func EnvSecrets() []string {
+	var secrets []string
+	for _, kv := range os.Environ() {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !secretEnv.MatchString(name) {
+			continue
+		}
+		secrets = append(secrets, value)
+		if strings.Contains(value, ",") {
+			secrets = append(secrets, strings.Split(value, ",")...)
+		}
+	}
+	return secrets
+}
+
+// String returns s with every secret masked.
+func (r *Redactor) String(s string) string {
+	if s == "" {
+		return s
+	}
+	if r != nil {
+		for _, lit := range r.literals {
+			s = strings.ReplaceAll(s, lit, Mask)
+		}
+	}
+	for _, re := range patterns {
+		if re.NumSubexp() == 0 {
+			s = re.ReplaceAllString(s, Mask)
+			continue
+		}
+		s = re.ReplaceAllStringFunc(s, func(m string) string {
+			loc := re.FindStringSubmatchIndex(m)
+			if loc[2] < 0 || strings.HasPrefix(m[loc[2]:loc[3]], Mask) {
+				return m
+			}
+			return m[:loc[2]] + Mask + m[loc[3]:]
+		})
+	}
+	return s
+}
@@ -0,0 +1,513 @@
+package repoconfig
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileName is the per-repo agent configuration file, read from the repo root.
+const FileName = ".droid.yml"
+
+// GuidelinesFiles are where a repo may keep its review guidelines — naming
+// conventions, architectural rules, forbidden packages — in Markdown. The
+// first that exists is used.
+var GuidelinesFiles = []string{"REVIEW_GUIDELINES.md", ".github/REVIEW_GUIDELINES.md", "docs/REVIEW_GUIDELINES.md"}
+
+// Config is the per-repo agent configuration. Every field is optional; the
+// zero value means "use the service defaults".
+//
+//	base_branch: develop
+//	model: claude-sonnet-4-20250514
+//	max_iterations: 30
+//	budget:
+//	  usd: 5
+//	  tokens: 3000000
+//	  duration: 45m
+//	commands:
+//	  build: go build ./...
+//	  test: go test ./...
+//	  lint: go vet ./...
+//	setup:
+//	  - go mod download
+//	preview:
+//	  start: npm run dev
+//	  url: http://localhost:3000
+//	contract:
+//	  start: go run ./cmd/api
+//	  url: http://localhost:8080
+//	  fixtures: testdata/contracts.json
+//	  handlers: internal/http/**, cmd/api/**
+//	coverage:
+//	  command: go test -coverprofile=coverage.out ./...
+//	  profile: coverage.out
+//	docs:
+//	  paths: README.md, docs/**
+//	  api: pkg/**, api/openapi.yaml
+//	security:
+//	  paths: internal/auth/**, internal/crypto/**
+//	  languages: php
+//	areas:
+//	  frontend: web
+//	  backend: services/api
+//	protected_paths:
+//	  - .github/**
+//	  - migrations/**
+//	review_rubric: |
+//	  - Every new endpoint needs an integration test
+type Config struct {
+	BaseBranch     string
+	Model          string
+	MaxIterations  int
+	Budget         Budget            // overrides the executor's default budget field by field
+	Commands       map[string]string // e.g. "build", "test", "lint"
+	Setup          []string          // run by the executor before the agent starts, e.g. dependency installs
+	Preview        Preview           // dev server for PR screenshots; zero disables them
+	Contract       Contract          // recorded API contract tests run by the reviewer; zero disables them
+	Coverage       Coverage          // coverage command the reviewer runs on the PR and its base; zero disables it
+	Docs           Docs              // where the docs and public API live, for the reviewer's docs check
+	Security       Security          // what the reviewer's security pass covers; zero uses the service defaults
+	Areas          map[string]string // monorepo area name → subdirectory, selected by "area:<name>" issue labels
+	ProtectedPaths []string          // globs the agents must not modify
+	ReviewRubric   string
+	// ReviewGuidelines is the repo's guidelines file (see GuidelinesFiles),
+	// which the reviewer loads alongside the config.
+	ReviewGuidelines string
+}
+
+// Budget caps what one executor job may spend. Zero fields are unlimited.
+type Budget struct {
+	USD      float64       // list-price LLM cost
+	Tokens   int64         // LLM tokens of every kind
+	Duration time.Duration // wall-clock time of the agent loop
+}
+
+// Or fills b's unset fields from def.
+func (b Budget) Or(def Budget) Budget {
+	if b.USD == 0 {
+		b.USD = def.USD
+	}
+	if b.Tokens == 0 {
+		b.Tokens = def.Tokens
+	}
+	if b.Duration == 0 {
+		b.Duration = def.Duration
+	}
+	return b
+}
+
+// Preview describes how to start a frontend's dev server so the executor can
+// screenshot the pages a change affects.
+type Preview struct {
+	Start string // command that starts the dev server and keeps running
+	URL   string // base URL the server listens on, e.g. http://localhost:3000
+}
+
+// Enabled reports whether screenshots are configured.
+func (p Preview) Enabled() bool {
+	return p.Start != "" && p.URL != ""
+}
+
+// Contract describes how the reviewer replays recorded HTTP fixtures against
+// a PR's build of the service.
+type Contract struct {
+	Start    string   // command that starts the service and keeps running
+	URL      string   // base URL the service listens on
+	Fixtures string   // JSON file of recorded requests and responses, read from the base branch
+	Handlers []string // globs of files whose change triggers the tests; empty means any change
+}
+
+// Enabled reports whether contract tests are configured.
+func (c Contract) Enabled() bool {
+	return c.Start != "" && c.URL != "" && c.Fixtures != ""
+}
+
+// Triggered reports whether a change to any of files should run the tests.
+func (c Contract) Triggered(files []string) bool {
+	if len(c.Handlers) == 0 {
+		return len(files) > 0
+	}
+	for _, f := range files {
+		if matchAny(c.Handlers, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// Coverage describes how to measure a repo's test coverage, so the reviewer
+// can compare a PR's with its base branch's.
+type Coverage struct {
+	Command string // runs the tests with coverage, writing Profile
+	Profile string // coverage file the command writes: a Go cover profile or LCOV
+}
+
+// Enabled reports whether coverage is configured.
+func (c Coverage) Enabled() bool {
+	return c.Command != "" && c.Profile != ""
+}
+
+// DefaultDocPaths are the documentation globs used when .droid.yml names none.
+var DefaultDocPaths = []string{"README*", "*.md", "docs/**"}
+
+// Docs tells the reviewer's docs check which files document the project and
+// which define its public API.
+type Docs struct {
+	Paths []string // globs of documentation files; empty means DefaultDocPaths
+	API   []string // globs of files whose exported declarations are public API; empty means every source file
+}
+
+// IsDoc reports whether p is a documentation file.
+func (d Docs) IsDoc(p string) bool {
+	if len(d.Paths) == 0 {
+		return matchAny(DefaultDocPaths, p)
+	}
+	return matchAny(d.Paths, p)
+}
+
+// IsAPI reports whether p may define public API.
+func (d Docs) IsAPI(p string) bool {
+	return len(d.API) == 0 || matchAny(d.API, p)
+}
+
+// Security names the changes that get the reviewer's security pass: files
+// under the path globs, or in one of the languages (as named by
+// standards.Languages, e.g. "go", "python").
+type Security struct {
+	Paths     []string
+	Languages []string
+}
+
+// Empty reports whether s names nothing.
+func (s Security) Empty() bool {
+	return len(s.Paths) == 0 && len(s.Languages) == 0
+}
+
+// Sensitive reports whether p is under one of the path globs.
+func (s Security) Sensitive(p string) bool {
+	return matchAny(s.Paths, p)
+}
+
+// IsProtected reports whether p matches one of the protected path globs. A
+// trailing "/**" protects everything under a directory; a pattern without a
+// slash matches the base name.
+func (c Config) IsProtected(p string) bool {
+	return matchAny(c.ProtectedPaths, p)
+}
+
+// matchAny reports whether p matches one of patterns, using the glob rules
+// described on IsProtected.
+func matchAny(patterns []string, p string) bool {
+	p = strings.TrimPrefix(path.Clean(p), "./")
+	for _, pattern := range patterns {
+		switch {
+		case strings.HasSuffix(pattern, "/**"):
+			if strings.HasPrefix(p, strings.TrimSuffix(pattern, "**")) {
+				return true
+			}
+		case !strings.Contains(pattern, "/"):
+			if ok, _ := path.Match(pattern, path.Base(p)); ok {
+				return true
+			}
+		default:
+			if ok, _ := path.Match(pattern, p); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PromptSection renders the parts of the config the agents should know about,
+// or "" if there is nothing to say.
+func (c Config) PromptSection() string {
+	var sb strings.Builder
+	if len(c.Commands) > 0 {
+		sb.WriteString("Repository commands (from " + FileName + ") — use these to build and verify:\n")
+		for _, name := range []string{"install", "build", "test", "lint"} {
+			if cmd, ok := c.Commands[name]; ok {
+				sb.WriteString(fmt.Sprintf("- %s: %s\n", name, cmd))
+			}
+		}
+		for name, cmd := range c.Commands {
+			switch name {
+			case "install", "build", "test", "lint":
+			default:
+				sb.WriteString(fmt.Sprintf("- %s: %s\n", name, cmd))
+			}
+		}
+	}
+	if c.Preview.Enabled() {
+		sb.WriteString("UI preview is configured: after you submit, each page listed in submit_work's preview_paths is screenshotted from the dev server (" + c.Preview.URL + ") and attached to the PR. List the pages your change affects.\n")
+	}
+	if len(c.ProtectedPaths) > 0 {
+		sb.WriteString("Protected paths — these must not be modified:\n")
+		for _, p := range c.ProtectedPaths {
+			sb.WriteString("- " + p + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// Parse reads a .droid.yml document. It supports the subset of YAML the file
+// needs: top-level scalars, one level of nested string maps, string lists, and
+// "|" block scalars. Unknown keys are ignored so older services tolerate newer
+// files.
+func Parse(data []byte) (Config, error) {
+	var cfg Config
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+
+	for i := 0; i < len(lines); i++ {
+		line := stripComment(lines[i])
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if indentOf(line) > 0 {
+			return Config{}, fmt.Errorf("%s line %d: unexpected indentation", FileName, i+1)
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return Config{}, fmt.Errorf("%s line %d: expected \"key: value\"", FileName, i+1)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		// Gather the indented block that belongs to this key, if any.
+		var block []string
+		for i+1 < len(lines) {
+			next := lines[i+1]
+			if strings.TrimSpace(next) != "" && indentOf(next) == 0 {
+				break
+			}
+			block = append(block, next)
+			i++
+		}
+
+		switch key {
+		case "base_branch":
+			cfg.BaseBranch = unquote(value)
+		case "model":
+			cfg.Model = unquote(value)
+		case "max_iterations":
+			n, err := strconv.Atoi(unquote(value))
+			if err != nil {
+				return Config{}, fmt.Errorf("%s: max_iterations: %w", FileName, err)
+			}
+			cfg.MaxIterations = n
+		case "budget":
+			m, err := parseMap(block)
+			if err != nil {
+				return Config{}, fmt.Errorf("%s: budget: %w", FileName, err)
+			}
+			if cfg.Budget, err = parseBudget(m); err != nil {
+				return Config{}, fmt.Errorf("%s: budget: %w", FileName, err)
+			}
+		case "commands":
+			m, err := parseMap(block)
+			if err != nil {
+				return Config{}, fmt.Errorf("%s: commands: %w", FileName, err)
+			}
+			cfg.Commands = m
+		case "setup":
+			cfg.Setup = parseList(block)
+		case "preview":
+			m, err := parseMap(block)
+			if err != nil {
+				return Config{}, fmt.Errorf("%s: preview: %w", FileName, err)
+			}
+			cfg.Preview = Preview{Start: m["start"], URL: strings.TrimSuffix(m["url"], "/")}
+		case "contract":
+			m, err := parseMap(block)
+			if err != nil {
+				return Config{}, fmt.Errorf("%s: contract: %w", FileName, err)
+			}
+			cfg.Contract = Contract{
+				Start:    m["start"],
+				URL:      strings.TrimSuffix(m["url"], "/"),
+				Fixtures: m["fixtures"],
+			}
+			cfg.Contract.Handlers = splitList(m["handlers"])
+		case "coverage":
+			m, err := parseMap(block)
+			if err != nil {
+				return Config{}, fmt.Errorf("%s: coverage: %w", FileName, err)
+			}
+			cfg.Coverage = Coverage{Command: m["command"], Profile: m["profile"]}
+		case "docs":
+			m, err := parseMap(block)
+			if err != nil {
+				return Config{}, fmt.Errorf("%s: docs: %w", FileName, err)
+			}
+			cfg.Docs = Docs{Paths: splitList(m["paths"]), API: splitList(m["api"])}
+		case "security":
+			m, err := parseMap(block)
+			if err != nil {
+				return Config{}, fmt.Errorf("%s: security: %w", FileName, err)
+			}
+			cfg.Security = Security{Paths: splitList(m["paths"]), Languages: splitList(m["languages"])}
+		case "areas":
+			m, err := parseMap(block)
+			if err != nil {
+				return Config{}, fmt.Errorf("%s: areas: %w", FileName, err)
+			}
+			if cfg.Areas, err = parseAreas(m); err != nil {
+				return Config{}, fmt.Errorf("%s: areas: %w", FileName, err)
+			}
+		case "protected_paths":
+			cfg.ProtectedPaths = parseList(block)
+		case "review_rubric":
+			if value == "|" || value == "|-" {
+				cfg.ReviewRubric = parseBlockScalar(block)
+			} else {
+				cfg.ReviewRubric = unquote(value)
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// AreaLabelPrefix marks an issue label selecting a monorepo area, e.g.
+// "area:frontend".
+const AreaLabelPrefix = "area:"
+
+// Area returns the area an issue's labels select and its subdirectory, or
+// ok false when they select none or more than one.
+func (c Config) Area(labels []string) (name, dir string, ok bool) {
+	for _, l := range labels {
+		n, found := strings.CutPrefix(l, AreaLabelPrefix)
+		d, known := c.Areas[strings.TrimSpace(n)]
+		if !found || !known {
+			continue
+		}
+		if ok && d != dir {
+			return "", "", false
+		}
+		name, dir, ok = strings.TrimSpace(n), d, true
+	}
+	return name, dir, ok
+}
+
+// parseAreas cleans each area's directory, which must stay inside the
+// repository.
+func parseAreas(m map[string]string) (map[string]string, error) {
+	for name, dir := range m {
+		clean := strings.TrimPrefix(path.Clean(strings.Trim(dir, "/")), "./")
+		if dir == "" || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+			return nil, fmt.Errorf("%s: invalid directory %q", name, dir)
+		}
+		m[name] = clean
+	}
+	return m, nil
+}
+
+func parseBudget(m map[string]string) (Budget, error) {
+	var b Budget
+	var err error
+	if v := m["usd"]; v != "" {
+		if b.USD, err = strconv.ParseFloat(strings.TrimPrefix(v, "$"), 64); err != nil || b.USD < 0 {
+			return Budget{}, fmt.Errorf("usd: invalid amount %q", v)
+		}
+	}
+	if v := m["tokens"]; v != "" {
+		if b.Tokens, err = strconv.ParseInt(strings.ReplaceAll(v, "_", ""), 10, 64); err != nil || b.Tokens < 0 {
+			return Budget{}, fmt.Errorf("tokens: invalid count %q", v)
+		}
+	}
+	if v := m["duration"]; v != "" {
+		if b.Duration, err = time.ParseDuration(v); err != nil || b.Duration < 0 {
+			return Budget{}, fmt.Errorf("duration: invalid duration %q", v)
+		}
+	}
+	return b, nil
+}
+
+func parseMap(block []string) (map[string]string, error) {
+	out := make(map[string]string)
+	for _, l := range block {
+		l = strings.TrimSpace(stripComment(l))
+		if l == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(l, ":")
+		if !ok {
+			return nil, fmt.Errorf("expected \"name: value\", got %q", l)
+		}
+		out[strings.TrimSpace(k)] = unquote(strings.TrimSpace(v))
+	}
+	return out, nil
+}
+
+// splitList splits a comma-separated value, dropping empty items.
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func parseList(block []string) []string {
+	var out []string
+	for _, l := range block {
+		l = strings.TrimSpace(stripComment(l))
+		if item, ok := strings.CutPrefix(l, "- "); ok {
+			out = append(out, unquote(strings.TrimSpace(item)))
+		}
+	}
+	return out
+}
+
+// parseBlockScalar joins a "|" block, removing the common indentation.
+func parseBlockScalar(block []string) string {
+	indent := -1
+	for _, l := range block {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		if n := indentOf(l); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	out := make([]string, 0, len(block))
+	for _, l := range block {
+		if len(l) >= indent && indent > 0 {
+			l = l[indent:]
+		}
+		out = append(out, l)
+	}
+	return strings.TrimRight(strings.Join(out, "\n"), "\n")
+}
+
+// stripComment drops a trailing "# comment" that is not inside quotes.
+func stripComment(l string) string {
+	inSingle, inDouble := false, false
+	for i, r := range l {
+		switch r {
+		case '\'':
+			inSingle = !inSingle && !inDouble
+		case '"':
+			inDouble = !inDouble && !inSingle
+		case '#':
+			if !inSingle && !inDouble && (i == 0 || l[i-1] == ' ' || l[i-1] == '\t') {
+				return strings.TrimRight(l[:i], " \t")
+			}
+		}
+	}
+	return l
+}
+
+func indentOf(l string) int {
+	return len(l) - len(strings.TrimLeft(l, " \t"))
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' && s[len(s)-1] == '"' || s[0] == '\'' && s[len(s)-1] == '\'') {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
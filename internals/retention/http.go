@@ -0,0 +1,86 @@
+package retention
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Handler serves the sources' data:
+//
+//	GET    /data?user=U123&repo=owner/repo&since=2025-01-01&until=2025-02-01  export
+//	DELETE /data?...                                                          hard-delete
+//
+// Every request needs "Authorization: Bearer <adminToken>". A deletion must
+// set at least one filter, so a bare DELETE cannot wipe everything.
+func Handler(adminToken string, sources ...Source) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, adminToken) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		f, err := parseFilter(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		out := make(map[string]any, len(sources))
+		switch r.Method {
+		case http.MethodGet:
+			for _, s := range sources {
+				records, err := s.ExportData(f)
+				if err != nil {
+					http.Error(w, s.Name()+": "+err.Error(), http.StatusInternalServerError)
+					return
+				}
+				out[s.Name()] = records
+			}
+		case http.MethodDelete:
+			if f.Empty() {
+				http.Error(w, "refusing to delete without a user, repo, since or until filter", http.StatusBadRequest)
+				return
+			}
+			for _, s := range sources {
+				n, err := s.DeleteData(f)
+				if err != nil {
+					http.Error(w, s.Name()+": "+err.Error(), http.StatusInternalServerError)
+					return
+				}
+				out[s.Name()] = map[string]int{"deleted": n}
+			}
+		default:
+			w.Header().Set("Allow", "GET, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(out)
+	})
+}
+
+func parseFilter(r *http.Request) (Filter, error) {
+	q := r.URL.Query()
+	f := Filter{User: q.Get("user"), Repo: q.Get("repo")}
+	var err error
+	if v := q.Get("since"); v != "" {
+		if f.Since, err = ParseTime(v); err != nil {
+			return Filter{}, err
+		}
+	}
+	if v := q.Get("until"); v != "" {
+		if f.Until, err = ParseTime(v); err != nil {
+			return Filter{}, err
+		}
+	}
+	return f, nil
+}
+
+func authorized(r *http.Request, adminToken string) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return adminToken != "" && ok && subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1
+}
@@ -0,0 +1,139 @@
+// Package retention exports and hard-deletes stored data on request, for
+// data-subject and compliance requests, and deletes data past a configured
+// age.
+package retention
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Filter selects records by Slack user, repository and time. Empty fields
+// match everything.
+type Filter struct {
+	User  string    // Slack user ID
+	Repo  string    // "owner/repo" or a repository URL
+	Since time.Time // inclusive
+	Until time.Time // exclusive
+}
+
+// Empty reports whether f would match every record.
+func (f Filter) Empty() bool {
+	return f.User == "" && f.Repo == "" && f.Since.IsZero() && f.Until.IsZero()
+}
+
+// Match reports whether a record for repoURL, involving users, last active
+// at t, is selected. Records that hold no user data never match a user
+// filter.
+func (f Filter) Match(repoURL string, users []string, t time.Time) bool {
+	if f.User != "" && !contains(users, f.User) {
+		return false
+	}
+	if f.Repo != "" && !sameRepo(repoURL, f.Repo) {
+		return false
+	}
+	if !f.Since.IsZero() && t.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !t.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// sameRepo compares a stored repository URL with a filter given as a URL or
+// as its "owner/repo" path.
+func sameRepo(repoURL, want string) bool {
+	have, want := repoPath(repoURL), repoPath(want)
+	return have != "" && (have == want || strings.HasSuffix(have, "/"+want))
+}
+
+func repoPath(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if _, rest, ok := strings.Cut(s, "://"); ok {
+		s = rest
+	} else if _, rest, ok := strings.Cut(s, "@"); ok {
+		s = strings.Replace(rest, ":", "/", 1) // git@host:owner/repo
+	}
+	return strings.TrimSuffix(strings.Trim(s, "/"), ".git")
+}
+
+// Source is one kind of stored data.
+type Source interface {
+	// Name identifies the source in exports, e.g. "sessions".
+	Name() string
+	// ExportData returns the records f selects, in a JSON-encodable form.
+	ExportData(f Filter) (any, error)
+	// DeleteData removes the records f selects and returns how many there
+	// were.
+	DeleteData(f Filter) (int, error)
+}
+
+// Enforcer deletes records older than MaxAge from its sources every
+// Interval.
+type Enforcer struct {
+	sources []Source
+	log     *slog.Logger
+
+	MaxAge   time.Duration
+	Interval time.Duration
+}
+
+func NewEnforcer(maxAge time.Duration, log *slog.Logger, sources ...Source) *Enforcer {
+	return &Enforcer{sources: sources, log: log, MaxAge: maxAge, Interval: time.Hour}
+}
+
+// Run enforces the policy once at start and then every Interval until ctx is
+// done.
+func (e *Enforcer) Run(ctx context.Context) {
+	e.enforce(time.Now())
+	ticker := time.NewTicker(e.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			e.enforce(now)
+		}
+	}
+}
+
+func (e *Enforcer) enforce(now time.Time) {
+	f := Filter{Until: now.Add(-e.MaxAge)}
+	for _, s := range e.sources {
+		n, err := s.DeleteData(f)
+		if err != nil {
+			e.log.Warn("retention: delete failed", "source", s.Name(), "err", err)
+			continue
+		}
+		if n > 0 {
+			e.log.Info("retention: deleted expired records", "source", s.Name(), "count", n, "before", f.Until.Format(time.RFC3339))
+		}
+	}
+}
+
+// ParseTime accepts RFC 3339 timestamps and YYYY-MM-DD dates (UTC midnight).
+func ParseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: want RFC 3339 or YYYY-MM-DD", s)
+	}
+	return t, nil
+}
@@ -5,10 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"strings"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/jadenj13/droid/internals/git"
 	"github.com/jadenj13/droid/internals/llm"
+	"github.com/jadenj13/droid/internals/repoconfig"
 )
 
 type LLM interface {
@@ -24,24 +26,121 @@ func NewAgent(llm LLM, log *slog.Logger) *Agent {
 	return &Agent{llm: llm, log: log}
 }
 
-func (a *Agent) Review(ctx context.Context, pr git.PR, originalIssue git.Issue) (git.Review, error) {
+// ReviewRequest is everything the agent needs to review one PR.
+type ReviewRequest struct {
+	PR     git.PR
+	Issue  git.Issue // the originating issue; zero if it could not be resolved
+	Config repoconfig.Config
+	// IssueError says why the issue the PR links could not be fetched. Empty
+	// when it was, or when the PR links none.
+	IssueError string
+	// Calibration is guidance derived from how humans resolved this repo's
+	// earlier bot-reviewed PRs. Empty when there is nothing to adjust.
+	Calibration string
+	// ContractResults describes recorded API fixtures replayed against the
+	// PR's build. Empty when contract tests did not run.
+	ContractResults string
+	// Coverage compares the PR's test coverage with the base branch's.
+	// Empty when coverage was not measured.
+	Coverage string
+	// Lint lists the repo's linter findings on the lines the diff adds,
+	// which are posted as inline comments of their own. Empty when the lint
+	// command did not run or found nothing.
+	Lint string
+	// Parents describes the open PRs this one is stacked on. Empty when it
+	// has none.
+	Parents string
+	// Standards are the org coding standards relevant to the PR. Empty when
+	// none apply.
+	Standards string
+	// APIChanges lists public declarations the PR changes without touching
+	// the docs. Empty when the docs check is off or found nothing.
+	APIChanges string
+	// Repo is a clone of the PR's branch the agent can read, search and list
+	// before its verdict. Nil reviews the diff alone.
+	Repo *git.Repo
+	// Since is the head commit of the previous review when this one covers
+	// only the commits pushed after it; PR.Diff then holds just those
+	// changes and Files every file the PR changes. Since is empty for a full
+	// review.
+	Since string
+	Files []string
+	// Earlier are the comments from earlier rounds not yet found resolved.
+	Earlier []RaisedComment
+}
+
+// Review reviews the PR in req. A diff longer than maxDiffChars is reviewed
+// in parts and the part reviews combined, so no file goes unread.
+func (a *Agent) Review(ctx context.Context, req ReviewRequest) (git.Review, error) {
+	if len(req.PR.Diff) > maxDiffChars {
+		return a.reviewChunked(ctx, req)
+	}
+	return a.review(ctx, req, "")
+}
+
+// review makes one review of req, with note, if set, appended to the prompt.
+func (a *Agent) review(ctx context.Context, req ReviewRequest, note string) (git.Review, error) {
+	content := buildReviewPrompt(req.PR, issueSection(req.Issue, req.IssueError), req.Config)
+	if req.Parents != "" {
+		content += "\n\n## Stacked On\n\nThis PR builds on the open PRs below, which are not merged into its base branch yet. Code this PR uses that they add is not missing — review only what this PR changes.\n\n" + req.Parents
+	}
+	if req.ContractResults != "" {
+		content += "\n\n## Contract Test Results\n\nRecorded request/response fixtures from the base branch were replayed against this PR's build of the service.\n\n" + req.ContractResults
+	}
+	if req.Coverage != "" {
+		content += "\n\n## Test Coverage\n\nThe repository's coverage command was run on this PR's branch and on the base branch.\n\n" + req.Coverage
+	}
+	if req.APIChanges != "" {
+		content += "\n\n## Public API Changes Without Docs\n\n" + req.APIChanges
+	}
+	if req.Lint != "" {
+		content += "\n\n## Lint Findings\n\nThe repository's linters reported these on the changed lines. They are posted as inline comments already — do not repeat them; spend the review on logic, behavior and design the linters cannot check.\n\n" + req.Lint
+	}
+	content += reReviewSections(req)
+	if note != "" {
+		content += "\n\n" + note
+	}
 	msgs := []llm.Message{{
 		Role:    "user",
-		Content: buildReviewPrompt(pr, originalIssue),
+		Content: content,
 	}}
 
-	resp, err := a.llm.CompleteWithTools(ctx, systemPrompt(), msgs, []anthropic.ToolParam{toolSubmitReview})
-	if err != nil {
-		return git.Review{}, fmt.Errorf("llm review: %w", err)
+	system := systemPrompt(req.Config)
+	if req.Standards != "" {
+		system += "\n\n" + req.Standards + "\nRequest changes for violations of these standards in the changed code."
+	}
+	if req.Calibration != "" {
+		system += "\n\nCalibration from past reviews:\n" + req.Calibration
+	}
+	if req.Since != "" || len(req.Earlier) > 0 {
+		system += "\n\n" + reReviewGuidance
 	}
 
-	for _, block := range resp.Content {
-		if block.Type == "tool_use" && block.Name == "submit_review" {
-			return parseReviewResult(block.Input)
+	ctx = llm.ContextWithModel(ctx, req.Config.Model)
+	var text string
+	if req.Repo != nil {
+		system += "\n\nYou can read, search and list the files of the PR's branch. Before your verdict, check what the diff alone does not show: the code around each change, the call sites of changed functions, and the tests that cover them. Keep it focused — a few targeted lookups, not a tour of the repository."
+		input, answer, err := a.explore(ctx, system, msgs, req.Repo)
+		if err != nil {
+			return git.Review{}, err
+		}
+		if input != nil {
+			return parseReviewResult(input)
+		}
+		text = answer
+	} else {
+		resp, err := a.llm.CompleteWithTools(ctx, system, msgs, []anthropic.ToolParam{toolSubmitReview})
+		if err != nil {
+			return git.Review{}, fmt.Errorf("llm review: %w", err)
 		}
+		for _, block := range resp.Content {
+			if block.Type == "tool_use" && block.Name == "submit_review" {
+				return parseReviewResult(block.Input)
+			}
+		}
+		text = extractText(resp)
 	}
 
-	text := extractText(resp)
 	a.log.Warn("reviewer responded with text instead of tool call — using as comment")
 	return git.Review{
 		Verdict: "comment",
@@ -49,6 +148,37 @@ func (a *Agent) Review(ctx context.Context, pr git.PR, originalIssue git.Issue)
 	}, nil
 }
 
+// reReviewGuidance tells the model how to treat a later review round.
+const reReviewGuidance = "This is a later round of review on a PR you already reviewed. When the diff covers only the commits since your last review, review those changes — whether they fix what was asked and whether they introduce new problems — and weigh the acceptance criteria against the whole PR, whose other changes you already reviewed. " +
+	"For each earlier comment listed, decide whether the PR now addresses it and put the IDs of those it does in resolved_comments. Do not post an earlier comment again: the ones left unresolved are carried into the summary, and they still count toward your verdict."
+
+// reReviewSections returns the prompt sections for a later review round, or
+// "" for a first review.
+func reReviewSections(req ReviewRequest) string {
+	var sb strings.Builder
+	if req.Since != "" {
+		sb.WriteString(fmt.Sprintf("\n\n## Since Last Review\n\nThe diff above holds only the commits pushed since your review of %s. All files the PR changes:\n\n%s", shortSHA(req.Since), strings.Join(req.Files, "\n")))
+	}
+	if len(req.Earlier) > 0 {
+		sb.WriteString("\n\n## Earlier Comments\n\n")
+		for _, c := range req.Earlier {
+			sb.WriteString(fmt.Sprintf("- ID %d — %s:%d (round %d", c.ID, c.Path, c.Line, c.Round))
+			if c.Severity != "" {
+				sb.WriteString(", " + c.Severity)
+			}
+			sb.WriteString("): " + truncate(c.Body, 500) + "\n")
+		}
+	}
+	return sb.String()
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
 var toolSubmitReview = anthropic.ToolParam{
 	Name:        "submit_review",
 	Description: anthropic.String("Submit the completed code review. Always call this — never respond with plain text."),
@@ -56,8 +186,8 @@ var toolSubmitReview = anthropic.ToolParam{
 		Properties: map[string]interface{}{
 			"verdict": map[string]interface{}{
 				"type":        "string",
-				"enum":        []string{"approve", "request_changes", "comment"},
-				"description": "approve if all acceptance criteria are met and the code is correct. request_changes if there are issues that must be fixed. comment for minor observations that don't block merging.",
+				"enum":        []string{"approve", "request_changes", "comment", "escalate"},
+				"description": "approve if all acceptance criteria are met and the code is correct. request_changes only for blocker or major findings, or an unmet acceptance criterion. comment when the findings are all minor or nits. escalate when the change is too risky to approve without a human, however correct it looks — database schema migrations, authentication or authorization changes, large deletions, irreversible data or infrastructure changes.",
 			},
 			"summary": map[string]interface{}{
 				"type":        "string",
@@ -80,11 +210,44 @@ var toolSubmitReview = anthropic.ToolParam{
 							"type":        "string",
 							"description": "Comment text. Be specific and actionable.",
 						},
+						"severity": map[string]interface{}{
+							"type":        "string",
+							"enum":        severities,
+							"description": "blocker: must not merge — broken behavior, data loss, a security hole. major: a real defect or missing test that should be fixed before merging. minor: worth fixing but does not block. nit: optional polish.",
+						},
+						"category": map[string]interface{}{
+							"type":        "string",
+							"enum":        categories,
+							"description": "What kind of problem this is.",
+						},
 					},
-					"required": []string{"path", "line", "body"},
+					"required": []string{"path", "line", "body", "severity", "category"},
 				},
 				"description": "Inline comments on specific lines. Only include comments for genuine issues, not style nits.",
 			},
+			"escalation_reasons": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "With escalate: each reason a human must review this PR, e.g. 'drops the users.email column'.",
+			},
+			"criteria": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"index":    map[string]interface{}{"type": "integer", "description": "The criterion's number."},
+						"status":   map[string]interface{}{"type": "string", "enum": []string{"met", "unmet", "unclear"}},
+						"evidence": map[string]interface{}{"type": "string", "description": "Where the diff meets it — file and function, or test — or what is missing."},
+					},
+					"required": []string{"index", "status", "evidence"},
+				},
+				"description": "One entry per numbered acceptance criterion, if the issue lists any.",
+			},
+			"resolved_comments": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "integer"},
+				"description": "IDs of the earlier comments, if any are listed, that the PR now addresses.",
+			},
 		},
 		Required: []string{"verdict", "summary", "comments"},
 	},
@@ -94,10 +257,15 @@ type submitReviewInput struct {
 	Verdict  string `json:"verdict"`
 	Summary  string `json:"summary"`
 	Comments []struct {
-		Path string `json:"path"`
-		Line int    `json:"line"`
-		Body string `json:"body"`
+		Path     string `json:"path"`
+		Line     int    `json:"line"`
+		Body     string `json:"body"`
+		Severity string `json:"severity"`
+		Category string `json:"category"`
 	} `json:"comments"`
+	EscalationReasons []string             `json:"escalation_reasons"`
+	Criteria          []git.CriterionCheck `json:"criteria"`
+	ResolvedComments  []int                `json:"resolved_comments"`
 }
 
 func parseReviewResult(raw json.RawMessage) (git.Review, error) {
@@ -109,10 +277,12 @@ func parseReviewResult(raw json.RawMessage) (git.Review, error) {
 	comments := make([]git.PRComment, 0, len(input.Comments))
 	for _, c := range input.Comments {
 		comments = append(comments, git.PRComment{
-			Path: c.Path,
-			Line: c.Line,
-			Body: c.Body,
-			Side: "RIGHT",
+			Path:     c.Path,
+			Line:     c.Line,
+			Body:     c.Body,
+			Side:     "RIGHT",
+			Severity: normalizeSeverity(c.Severity),
+			Category: normalizeCategory(c.Category),
 		})
 	}
 
@@ -120,11 +290,14 @@ func parseReviewResult(raw json.RawMessage) (git.Review, error) {
 		Verdict:  input.Verdict,
 		Summary:  input.Summary,
 		Comments: comments,
+		Reasons:  input.EscalationReasons,
+		Resolved: input.ResolvedComments,
+		Criteria: input.Criteria,
 	}, nil
 }
 
-func systemPrompt() string {
-	return `You are an expert code reviewer. You will be given a pull request diff and the
+func systemPrompt(cfg repoconfig.Config) string {
+	prompt := `You are an expert code reviewer. You will be given a pull request diff and the
 original issue it addresses. Your job is to review the changes and decide whether they
 should be approved, require changes, or need a comment.
 
@@ -137,17 +310,59 @@ Review criteria — check all of these:
 - Are there any security concerns (injection, auth bypass, data exposure)?
 
 Be direct and specific. When requesting changes, tell the executor exactly what to fix.
+Escalate instead of approving when the change is too risky for an automated approval —
+schema migrations, auth changes, large deletions — and give the reasons.
+Grade every inline comment by severity and category. Only blocker and major findings
+justify request_changes; minor findings and nits go in a comment review.
 Do not request stylistic changes that don't affect correctness or maintainability.
 Always respond by calling submit_review — never with plain text.`
+
+	if cfg.ReviewRubric != "" {
+		prompt += "\n\nRepository review rubric — apply these in addition to the criteria above:\n" + cfg.ReviewRubric
+	}
+	if cfg.ReviewGuidelines != "" {
+		prompt += "\n\nThe team's review guidelines (from the repository) follow. Hold the changed code to them: a violation is a finding like any other, graded by how much it matters, and your verdict must reflect them.\n\n" + cfg.ReviewGuidelines
+	}
+	if len(cfg.ProtectedPaths) > 0 {
+		prompt += "\n\nChanges to protected paths must not be approved; request changes and ask for them to be reverted."
+	}
+	if cfg.Contract.Enabled() {
+		prompt += "\n\nWhen contract test results are included, treat each mismatch as a behavioral regression and request changes, unless the issue explicitly asks for that behavior to change — then say in the summary which fixtures need re-recording."
+	}
+	if cfg.Coverage.Enabled() {
+		prompt += "\n\nWhen test coverage results are included, new or changed lines that no test runs are findings in the tests category: comment on them, naming the behavior a test should pin down. Grade untested logic — branches, error handling, calculations — as major and trivial lines such as logging as nits. A drop in coverage alone is not a finding."
+	}
+	return prompt
 }
 
-func buildReviewPrompt(pr git.PR, issue git.Issue) string {
-	return fmt.Sprintf(`Please review the following pull request.
+// issueSection describes the issue a PR addresses, says it could not be
+// fetched, or says it has none — a person's PR reviewed on request.
+func issueSection(issue git.Issue, issueErr string) string {
+	if issueErr != "" {
+		return missingIssueSection(issue.URL, issueErr)
+	}
+	if issue.URL == "" && issue.Title == "" {
+		return `## Original Issue
+
+None — this pull request was not opened for an issue; someone asked for it to be reviewed.
+Judge it against its title and description instead of acceptance criteria, and review the
+code for correctness, tests, conventions and security as usual. Address the PR's author
+rather than the executor.`
+	}
+	section := fmt.Sprintf("## Original Issue\n\nTitle: %s\nURL: %s", issue.Title, issue.URL)
+	if body := strings.TrimSpace(issue.Body); body != "" {
+		section += "\n\n" + truncate(body, 4000)
+	}
+	if criteria := parseCriteria(issue.Body); len(criteria) > 0 {
+		section += "\n\n" + renderCriteriaPrompt(criteria)
+	}
+	return section
+}
 
-## Original Issue
+func buildReviewPrompt(pr git.PR, issue string, cfg repoconfig.Config) string {
+	prompt := fmt.Sprintf(`Please review the following pull request.
 
-Title: %s
-URL: %s
+%s
 
 ## Pull Request
 
@@ -159,13 +374,34 @@ Branch: %s → %s
 ## Diff
 
 %s`,
-		issue.Title,
-		issue.URL,
+		issue,
 		pr.Title,
 		pr.Branch, pr.BaseBranch,
 		truncate(pr.Description, 1000),
-		truncate(pr.Diff, 20000),
+		truncate(pr.Diff, maxDiffChars),
 	)
+
+	var touched []string
+	for _, f := range diffFiles(pr.Diff) {
+		if cfg.IsProtected(f) {
+			touched = append(touched, f)
+		}
+	}
+	if len(touched) > 0 {
+		prompt += "\n\n## Protected Paths Modified\n\n" + strings.Join(touched, "\n")
+	}
+	return prompt
+}
+
+// diffFiles returns the new-side paths from the "+++ path" headers in diff.
+func diffFiles(diff string) []string {
+	var out []string
+	for _, line := range strings.Split(diff, "\n") {
+		if p, ok := strings.CutPrefix(line, "+++ "); ok {
+			out = append(out, strings.TrimPrefix(p, "b/"))
+		}
+	}
+	return out
 }
 
 func truncate(s string, max int) string {
@@ -0,0 +1,268 @@
+package reviewer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jadenj13/droid/internals/retention"
+)
+
+// Outcome is what humans eventually did with a bot-reviewed PR.
+type Outcome string
+
+const (
+	OutcomePending  Outcome = ""         // still open
+	OutcomeMerged   Outcome = "merged"   // merged exactly as last reviewed
+	OutcomeModified Outcome = "modified" // merged after commits the bot never reviewed
+	OutcomeClosed   Outcome = "closed"   // closed without merging
+)
+
+// CalibrationRecord pairs the bot's last verdict on a PR with the human outcome.
+type CalibrationRecord struct {
+	RepoURL     string    `json:"repo_url"`
+	PRNumber    int       `json:"pr_number"`
+	PRURL       string    `json:"pr_url"`
+	Verdict     string    `json:"verdict"`
+	ReviewedSHA string    `json:"reviewed_sha"`
+	Rounds      int       `json:"rounds"`
+	ReviewedAt  time.Time `json:"reviewed_at"`
+	Outcome     Outcome   `json:"outcome"`
+	OutcomeAt   time.Time `json:"outcome_at,omitzero"`
+}
+
+// falseApprove reports whether the bot approved a PR that humans changed or
+// rejected.
+func (r CalibrationRecord) falseApprove() bool {
+	return r.Verdict == "approve" && (r.Outcome == OutcomeModified || r.Outcome == OutcomeClosed)
+}
+
+// falseBlock reports whether the bot requested changes on a PR that humans
+// merged exactly as it was.
+func (r CalibrationRecord) falseBlock() bool {
+	return r.Verdict == "request_changes" && r.Outcome == OutcomeMerged
+}
+
+// CalibrationStore persists calibration records in a single JSON file. The
+// volume is one record per PR, so rewriting the file on each update is fine.
+type CalibrationStore struct {
+	path string
+
+	mu      sync.Mutex
+	records map[string]CalibrationRecord // key: repo URL + "#" + PR number
+}
+
+func NewCalibrationStore(path string) (*CalibrationStore, error) {
+	s := &CalibrationStore{path: path, records: make(map[string]CalibrationRecord)}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read calibration file: %w", err)
+	}
+	if err := json.Unmarshal(b, &s.records); err != nil {
+		return nil, fmt.Errorf("decode calibration file: %w", err)
+	}
+	return s, nil
+}
+
+func calibrationKey(repoURL string, prNumber int) string {
+	return fmt.Sprintf("%s#%d", strings.TrimSuffix(repoURL, "/"), prNumber)
+}
+
+// RecordVerdict stores the bot's latest verdict on a PR and the head commit it
+// reviewed.
+func (s *CalibrationStore) RecordVerdict(repoURL string, prNumber int, prURL, verdict, headSHA string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := calibrationKey(repoURL, prNumber)
+	r := s.records[key]
+	r.RepoURL, r.PRNumber, r.PRURL = repoURL, prNumber, prURL
+	r.Verdict = verdict
+	r.ReviewedSHA = headSHA
+	r.Rounds++
+	r.ReviewedAt = time.Now()
+	s.records[key] = r
+	return s.save()
+}
+
+// RecordOutcome stores how a bot-reviewed PR was resolved. PRs the bot never
+// reviewed are ignored.
+func (s *CalibrationStore) RecordOutcome(repoURL string, prNumber int, merged bool, headSHA string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := calibrationKey(repoURL, prNumber)
+	r, ok := s.records[key]
+	if !ok {
+		return nil
+	}
+	switch {
+	case !merged:
+		r.Outcome = OutcomeClosed
+	case headSHA != "" && headSHA != r.ReviewedSHA:
+		r.Outcome = OutcomeModified
+	default:
+		r.Outcome = OutcomeMerged
+	}
+	r.OutcomeAt = time.Now()
+	s.records[key] = r
+	return s.save()
+}
+
+func (s *CalibrationStore) save() error {
+	b, err := json.MarshalIndent(s.records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal calibration: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("create calibration dir: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return fmt.Errorf("write calibration: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// CalibrationReport summarises resolved PRs, optionally for one repository.
+type CalibrationReport struct {
+	Resolved       int
+	Approved       int
+	Blocked        int // verdict request_changes
+	FalseApprovals []CalibrationRecord
+	FalseBlocks    []CalibrationRecord
+}
+
+func (r CalibrationReport) FalseApproveRate() float64 {
+	return rate(len(r.FalseApprovals), r.Approved)
+}
+
+func (r CalibrationReport) FalseBlockRate() float64 {
+	return rate(len(r.FalseBlocks), r.Blocked)
+}
+
+func rate(n, of int) float64 {
+	if of == 0 {
+		return 0
+	}
+	return float64(n) / float64(of)
+}
+
+// Report builds a calibration report. An empty repoURL covers every repo.
+func (s *CalibrationStore) Report(repoURL string) CalibrationReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rep CalibrationReport
+	for _, r := range s.records {
+		if r.Outcome == OutcomePending || (repoURL != "" && strings.TrimSuffix(r.RepoURL, "/") != strings.TrimSuffix(repoURL, "/")) {
+			continue
+		}
+		rep.Resolved++
+		switch r.Verdict {
+		case "approve":
+			rep.Approved++
+		case "request_changes":
+			rep.Blocked++
+		}
+		if r.falseApprove() {
+			rep.FalseApprovals = append(rep.FalseApprovals, r)
+		}
+		if r.falseBlock() {
+			rep.FalseBlocks = append(rep.FalseBlocks, r)
+		}
+	}
+	byRecent := func(list []CalibrationRecord) {
+		sort.Slice(list, func(i, j int) bool { return list[i].OutcomeAt.After(list[j].OutcomeAt) })
+	}
+	byRecent(rep.FalseApprovals)
+	byRecent(rep.FalseBlocks)
+	return rep
+}
+
+// String renders the report as plain text for the /calibration endpoint.
+func (r CalibrationReport) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Resolved PRs: %d\n", r.Resolved))
+	sb.WriteString(fmt.Sprintf("False-approve rate: %.0f%% (%d of %d approvals were modified or closed by humans)\n",
+		100*r.FalseApproveRate(), len(r.FalseApprovals), r.Approved))
+	sb.WriteString(fmt.Sprintf("False-block rate: %.0f%% (%d of %d change requests were merged unchanged)\n",
+		100*r.FalseBlockRate(), len(r.FalseBlocks), r.Blocked))
+
+	list := func(title string, recs []CalibrationRecord) {
+		if len(recs) == 0 {
+			return
+		}
+		sb.WriteString("\n" + title + ":\n")
+		for _, rec := range recs {
+			sb.WriteString(fmt.Sprintf("- %s (verdict %s, outcome %s, %d rounds)\n", rec.PRURL, rec.Verdict, rec.Outcome, rec.Rounds))
+		}
+	}
+	list("False approvals", r.FalseApprovals)
+	list("False blocks", r.FalseBlocks)
+	return sb.String()
+}
+
+// minCalibrationSample is how many resolved PRs a repo needs before its
+// calibration feeds back into the review prompt.
+const minCalibrationSample = 10
+
+// promptGuidance turns a report into a nudge for the reviewer, or "" when the
+// sample is too small or the bot is well calibrated.
+func (r CalibrationReport) promptGuidance() string {
+	if r.Resolved < minCalibrationSample {
+		return ""
+	}
+	var notes []string
+	if r.Approved > 0 && r.FalseApproveRate() >= 0.2 {
+		notes = append(notes, fmt.Sprintf("%.0f%% of your past approvals in this repository were later modified or closed by humans — be more skeptical before approving, and check edge cases and tests carefully.", 100*r.FalseApproveRate()))
+	}
+	if r.Blocked > 0 && r.FalseBlockRate() >= 0.2 {
+		notes = append(notes, fmt.Sprintf("%.0f%% of your past change requests in this repository were merged unchanged by humans — only request changes for real defects, not preferences.", 100*r.FalseBlockRate()))
+	}
+	return strings.Join(notes, "\n")
+}
+
+// Name implements retention.Source.
+func (s *CalibrationStore) Name() string { return "reviewer_calibration" }
+
+// ExportData returns the records f selects, matched on the last review.
+// Records hold no Slack user data, so a user filter matches none.
+func (s *CalibrationStore) ExportData(f retention.Filter) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []CalibrationRecord{}
+	for _, r := range s.records {
+		if f.Match(r.RepoURL, nil, r.ReviewedAt) {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+// DeleteData removes the records f selects.
+func (s *CalibrationStore) DeleteData(f retention.Filter) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for key, r := range s.records {
+		if f.Match(r.RepoURL, nil, r.ReviewedAt) {
+			delete(s.records, key)
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	return n, s.save()
+}
@@ -0,0 +1,265 @@
+package reviewer
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/llm"
+)
+
+// maxDiffChars is the most diff one review call sees. Larger PRs are reviewed
+// in parts of at most this size and the part reviews combined.
+const maxDiffChars = 20000
+
+// reviewChunked reviews a diff too large for one call part by part, then
+// combines the part reviews into one.
+func (a *Agent) reviewChunked(ctx context.Context, req ReviewRequest) (git.Review, error) {
+	chunks := splitDiff(req.PR.Diff, maxDiffChars)
+	files := req.Files
+	if req.Since == "" {
+		files = diffFiles(req.PR.Diff)
+	}
+	a.log.Info("reviewing large PR in parts", "pr", req.PR.Number, "diff_chars", len(req.PR.Diff), "parts", len(chunks))
+
+	parts := make([]git.Review, 0, len(chunks))
+	for i, chunk := range chunks {
+		part := req
+		part.PR.Diff = chunk
+		// Contract results, coverage, API changes and earlier comments
+		// concern the whole PR; the combined review weighs them.
+		part.ContractResults, part.Coverage, part.APIChanges = "", "", ""
+		part.Since, part.Files, part.Earlier = "", nil, nil
+		note := fmt.Sprintf("## Review Scope\n\nThis PR is too large to review at once, so you are reviewing part %d of %d of its diff. "+
+			"Review only the code in this part for bugs, unhandled edge cases, missing tests, error handling and security. "+
+			"Do not judge whether the whole issue is done — other parts may hold the rest — and use approve if this part has no problems. "+
+			"All files the PR changes:\n\n%s", i+1, len(chunks), strings.Join(files, "\n"))
+		r, err := a.review(ctx, part, note)
+		if err != nil {
+			return git.Review{}, fmt.Errorf("review part %d of %d: %w", i+1, len(chunks), err)
+		}
+		parts = append(parts, r)
+	}
+	return a.combine(ctx, req, files, parts), nil
+}
+
+// combine merges part reviews into one review of the PR: every inline
+// comment is kept, the verdict is the strictest of the parts and of an
+// overall call that judges the issue's acceptance criteria, and the summary
+// comes from that call. If the call fails, the part summaries are joined.
+func (a *Agent) combine(ctx context.Context, req ReviewRequest, files []string, parts []git.Review) git.Review {
+	var combined git.Review
+	var sb strings.Builder
+	for i, p := range parts {
+		combined.Verdict = stricter(combined.Verdict, p.Verdict)
+		combined.Comments = append(combined.Comments, p.Comments...)
+		combined.Reasons = append(combined.Reasons, p.Reasons...)
+		sb.WriteString(fmt.Sprintf("### Part %d — %s\n\n%s\n", i+1, p.Verdict, p.Summary))
+		for _, c := range p.Comments {
+			sb.WriteString(fmt.Sprintf("- %s:%d [%s]: %s\n", c.Path, c.Line, c.Severity, truncate(c.Body, 300)))
+		}
+		sb.WriteString("\n")
+	}
+	findings := sb.String()
+
+	content := fmt.Sprintf(`A large pull request was reviewed in %d parts. Combine the part reviews into the review of the whole PR.
+
+%s
+
+## Pull Request
+
+Title: %s
+Branch: %s → %s
+
+%s
+
+## Changed Files
+
+%s
+
+## Part Reviews
+
+%s`, len(parts), issueSection(req.Issue, req.IssueError), req.PR.Title, req.PR.Branch, req.PR.BaseBranch,
+		truncate(req.PR.Description, 1000), strings.Join(files, "\n"), findings)
+	if req.Parents != "" {
+		content += "\n\n## Stacked On\n\n" + req.Parents
+	}
+	if req.ContractResults != "" {
+		content += "\n\n## Contract Test Results\n\n" + req.ContractResults
+	}
+	if req.Coverage != "" {
+		content += "\n\n## Test Coverage\n\n" + req.Coverage
+	}
+	if req.APIChanges != "" {
+		content += "\n\n## Public API Changes Without Docs\n\n" + req.APIChanges
+	}
+	content += reReviewSections(req)
+	system := systemPrompt(req.Config) + "\n\nYou are combining part reviews, not reading the diff. Decide whether the PR as a whole satisfies every acceptance criterion in the issue, given the files it changes and the findings of the parts. " +
+		"Call submit_review with the overall verdict and a summary covering the whole PR. The parts' inline comments are posted as they are; add comments only for problems no part raised."
+	if req.Since != "" || len(req.Earlier) > 0 {
+		system += "\n\n" + reReviewGuidance
+	}
+
+	resp, err := a.llm.CompleteWithTools(llm.ContextWithModel(ctx, req.Config.Model), system,
+		[]llm.Message{{Role: "user", Content: content}}, []anthropic.ToolParam{toolSubmitReview})
+	if err == nil {
+		for _, block := range resp.Content {
+			if block.Type != "tool_use" || block.Name != toolSubmitReview.Name {
+				continue
+			}
+			overall, perr := parseReviewResult(block.Input)
+			if perr != nil {
+				err = perr
+				break
+			}
+			combined.Verdict = stricter(combined.Verdict, overall.Verdict)
+			combined.Summary = overall.Summary
+			combined.Comments = append(combined.Comments, overall.Comments...)
+			combined.Reasons = append(combined.Reasons, overall.Reasons...)
+			combined.Resolved = overall.Resolved
+			combined.Criteria = overall.Criteria
+			return combined
+		}
+	}
+	a.log.Warn("could not combine part reviews — joining their summaries", "pr", req.PR.Number, "err", err)
+	combined.Summary = fmt.Sprintf("This PR was reviewed in %d parts.\n\n%s", len(parts), findings)
+	return combined
+}
+
+// verdictRank orders verdicts from least to most blocking.
+var verdictRank = map[string]int{"approve": 1, "comment": 2, "request_changes": 3, "escalate": 4}
+
+func stricter(a, b string) string {
+	if verdictRank[b] > verdictRank[a] {
+		return b
+	}
+	return a
+}
+
+// splitDiff cuts diff into chunks of at most max bytes along file
+// boundaries. A file larger than max is cut between hunks, and a hunk larger
+// than max between lines, each piece repeating the file header and starting
+// with a hunk header of its own, so inline comments keep their line numbers.
+func splitDiff(diff string, max int) []string {
+	var chunks []string
+	var cur strings.Builder
+	for _, file := range splitFiles(diff) {
+		pieces := []string{file}
+		if len(file) > max {
+			pieces = splitFile(file, max)
+		}
+		for _, p := range pieces {
+			if cur.Len() > 0 && cur.Len()+len(p) > max {
+				chunks = append(chunks, cur.String())
+				cur.Reset()
+			}
+			cur.WriteString(p)
+		}
+	}
+	if cur.Len() > 0 {
+		chunks = append(chunks, cur.String())
+	}
+	return chunks
+}
+
+// splitFiles cuts a rendered diff before each "--- old" / "+++ new" header.
+func splitFiles(diff string) []string {
+	lines := strings.SplitAfter(diff, "\n")
+	var files []string
+	var cur strings.Builder
+	for i, line := range lines {
+		if strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ") && cur.Len() > 0 {
+			files = append(files, cur.String())
+			cur.Reset()
+		}
+		cur.WriteString(line)
+	}
+	if cur.Len() > 0 {
+		files = append(files, cur.String())
+	}
+	return files
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
+// splitFile cuts one file's diff into pieces of about max bytes, each with
+// the file header.
+func splitFile(file string, max int) []string {
+	lines := strings.SplitAfter(file, "\n")
+	header := ""
+	for len(lines) > 0 && !strings.HasPrefix(lines[0], "@@") {
+		header += lines[0]
+		lines = lines[1:]
+	}
+	budget := max - len(header)
+	if budget < 1000 {
+		budget = 1000
+	}
+
+	var pieces []string
+	var cur strings.Builder
+	for _, frag := range hunkFragments(lines, budget) {
+		if cur.Len() > 0 && cur.Len()+len(frag) > budget {
+			pieces = append(pieces, header+cur.String())
+			cur.Reset()
+		}
+		cur.WriteString(frag)
+	}
+	if cur.Len() > 0 {
+		pieces = append(pieces, header+cur.String())
+	}
+	return pieces
+}
+
+// hunkFragments returns the hunks in lines, with any hunk larger than budget
+// cut into fragments that each get a hunk header with their own line
+// numbers.
+func hunkFragments(lines []string, budget int) []string {
+	var frags []string
+	var body strings.Builder
+	var oldLine, newLine int             // next line numbers in the hunk
+	var fragOld, fragNew, oldN, newN int // the open fragment's start and counts
+	suffix := ""                         // text after the original header, e.g. a function name
+	flush := func() {
+		if body.Len() == 0 {
+			return
+		}
+		frags = append(frags, fmt.Sprintf("@@ -%d,%d +%d,%d @@%s\n%s", fragOld, oldN, fragNew, newN, suffix, body.String()))
+		body.Reset()
+	}
+	for _, line := range lines {
+		if m := hunkHeader.FindStringSubmatch(line); m != nil {
+			flush()
+			oldLine, _ = strconv.Atoi(m[1])
+			newLine, _ = strconv.Atoi(m[2])
+			suffix = strings.TrimRight(line[len(m[0]):], "\n")
+			fragOld, fragNew, oldN, newN = oldLine, newLine, 0, 0
+			continue
+		}
+		if body.Len() > 0 && body.Len()+len(line) > budget {
+			flush()
+			fragOld, fragNew, oldN, newN = oldLine, newLine, 0, 0
+		}
+		body.WriteString(line)
+		switch {
+		case strings.HasPrefix(line, "+"):
+			newLine++
+			newN++
+		case strings.HasPrefix(line, "-"):
+			oldLine++
+			oldN++
+		case strings.HasPrefix(line, "\\"), line == "", line == "\n":
+		default:
+			oldLine++
+			newLine++
+			oldN++
+			newN++
+		}
+	}
+	flush()
+	return frags
+}
@@ -0,0 +1,67 @@
+package reviewer
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jadenj13/droid/internals/git"
+)
+
+// reviewCommand matches "/droid review" or "@droid review" at the start of a
+// comment line.
+var reviewCommand = regexp.MustCompile(`(?im)^\s*[/@]droid\s+review\b`)
+
+// commandMarker identifies the reviewer's reply to a refused command.
+const commandMarker = "<!-- droid:review-command -->"
+
+// WithCommandPolicy sets who may ask for a review with a "/droid review" PR
+// comment: users with at least min access to the repository, and the listed
+// users regardless of their access.
+func WithCommandPolicy(min git.Permission, users ...string) WorkerOption {
+	return func(w *Worker) {
+		w.commandPermission = min
+		w.commandUsers = users
+	}
+}
+
+// HandleReviewCommand reviews a PR someone asked for with a "/droid review"
+// comment, whoever wrote the PR. The comment's author needs the configured
+// permission on the repository, or to be on the allowlist.
+func (w *Worker) HandleReviewCommand(ctx context.Context, repoURL string, prNumber int, author string) error {
+	provider, _, err := w.factory.ProviderFor(ctx, repoURL)
+	if err != nil {
+		return fmt.Errorf("build provider: %w", err)
+	}
+	allowed, err := w.commandAllowed(ctx, provider, author)
+	if err != nil {
+		return fmt.Errorf("check permission of %s: %w", author, err)
+	}
+	if !allowed {
+		w.log.Info("review command refused", "pr", prNumber, "author", author)
+		body := fmt.Sprintf("@%s, asking for a review from a comment needs %s access to this repository, so `/droid review` was ignored. Ask a maintainer to run it or to add the `%s` label.\n\n%s",
+			author, w.commandPermission, w.labels.Review, commandMarker)
+		if err := provider.UpsertMarkedComment(ctx, prNumber, commandMarker, body); err != nil {
+			w.log.Warn("failed to reply to review command", "pr", prNumber, "err", err)
+		}
+		return nil
+	}
+	w.log.Info("review command accepted", "pr", prNumber, "author", author)
+	return w.reviewFailed(ctx, repoURL, prNumber, w.reviewLoop(ctx, provider, repoURL, prNumber, ""))
+}
+
+func (w *Worker) commandAllowed(ctx context.Context, provider git.GitProvider, author string) (bool, error) {
+	if author == "" {
+		return false, nil
+	}
+	if slices.ContainsFunc(w.commandUsers, func(u string) bool { return strings.EqualFold(u, author) }) {
+		return true, nil
+	}
+	p, err := provider.UserPermission(ctx, author)
+	if err != nil {
+		return false, err
+	}
+	return p >= w.commandPermission, nil
+}
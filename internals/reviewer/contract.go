@@ -0,0 +1,309 @@
+package reviewer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/repoconfig"
+	"github.com/jadenj13/droid/internals/sandbox"
+)
+
+// contractDir holds request bodies and captured responses inside the clone.
+const contractDir = ".droid-contract"
+
+// Fixture is one recorded request and the response the service gave to it.
+//
+//	{"name": "get user",
+//	 "request":  {"method": "GET", "path": "/users/1", "headers": {"Authorization": "Bearer test"}},
+//	 "response": {"status": 200, "headers": {"Content-Type": "application/json"},
+//	              "body": {"id": 1, "name": "Ada"}, "ignore": ["updated_at"]}}
+//
+// A JSON response body is compared structurally, skipping the keys in ignore
+// at any depth; a string body is compared as text. Only the listed response
+// headers are checked.
+type Fixture struct {
+	Name    string `json:"name"`
+	Request struct {
+		Method  string            `json:"method"`
+		Path    string            `json:"path"`
+		Headers map[string]string `json:"headers"`
+		Body    string            `json:"body"`
+	} `json:"request"`
+	Response struct {
+		Status  int               `json:"status"`
+		Headers map[string]string `json:"headers"`
+		Body    json.RawMessage   `json:"body"`
+		Ignore  []string          `json:"ignore"`
+	} `json:"response"`
+}
+
+// ContractResult is the outcome of replaying one fixture.
+type ContractResult struct {
+	Name     string
+	Problems []string // empty when the response matched
+}
+
+// ContractReport is the outcome of a contract test run.
+type ContractReport struct {
+	Results []ContractResult
+	Error   string // set when the service could not be built or started
+}
+
+// Failed returns the results with mismatches.
+func (r ContractReport) Failed() []ContractResult {
+	var out []ContractResult
+	for _, res := range r.Results {
+		if len(res.Problems) > 0 {
+			out = append(out, res)
+		}
+	}
+	return out
+}
+
+// ContractRunner replays a repository's recorded fixtures against the PR's
+// build of the service. The PR's code is untrusted, so it only ever runs in
+// the Docker sandbox.
+type ContractRunner struct {
+	sandbox sandbox.Config
+	token   string
+	network *git.Network
+	timeout time.Duration
+	log     *slog.Logger
+}
+
+func NewContractRunner(sb sandbox.Config, token string, network *git.Network, timeout time.Duration, log *slog.Logger) *ContractRunner {
+	return &ContractRunner{sandbox: sb, token: token, network: network, timeout: timeout, log: log}
+}
+
+// Run clones the PR branch, starts the service in the sandbox, sends each
+// fixture's request, and compares the responses with the recordings.
+func (c *ContractRunner) Run(ctx context.Context, provider git.GitProvider, pr git.PR, cfg repoconfig.Contract, fixtures []Fixture) (ContractReport, error) {
+	info, err := git.ParseRepoURL(provider.RepoURL())
+	if err != nil {
+		return ContractReport{}, fmt.Errorf("parse repo url: %w", err)
+	}
+	repo, err := git.Clone(ctx, provider.RepoURL(), c.token,
+		git.WithCloneNetwork(c.network),
+		git.WithRunner(c.sandbox.RunnerFor(info.Owner+"/"+info.Repo)))
+	if err != nil {
+		return ContractReport{}, fmt.Errorf("clone: %w", err)
+	}
+	defer repo.Cleanup()
+	if err := repo.CheckoutRemoteBranch(ctx, pr.Branch); err != nil {
+		return ContractReport{}, fmt.Errorf("checkout %s: %w", pr.Branch, err)
+	}
+
+	for i, f := range fixtures {
+		if err := repo.WriteFile(fmt.Sprintf("%s/req-%d.body", contractDir, i), f.Request.Body); err != nil {
+			return ContractReport{}, fmt.Errorf("write fixture %d: %w", i, err)
+		}
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	out, err := repo.RunInDir(runCtx, contractScript(cfg, fixtures))
+	if err != nil {
+		return ContractReport{}, fmt.Errorf("run contract tests: %w", err)
+	}
+	if ctx.Err() != nil {
+		return ContractReport{}, ctx.Err()
+	}
+	if strings.Contains(out, "service did not start") || runCtx.Err() != nil {
+		return ContractReport{Error: truncate(out, 2000)}, nil
+	}
+
+	report := ContractReport{}
+	for i, f := range fixtures {
+		report.Results = append(report.Results, ContractResult{Name: f.Name, Problems: c.compare(repo, i, f)})
+	}
+	return report, nil
+}
+
+// compare checks the captured response for fixture i against its recording.
+func (c *ContractRunner) compare(repo *git.Repo, i int, f Fixture) []string {
+	statusText, err := repo.ReadFile(fmt.Sprintf("%s/res-%d.status", contractDir, i))
+	if err != nil {
+		return []string{"no response captured"}
+	}
+	var problems []string
+	status, _ := strconv.Atoi(strings.TrimSpace(statusText))
+	if status == 0 {
+		return []string{"request failed (no HTTP response)"}
+	}
+	if status != f.Response.Status {
+		problems = append(problems, fmt.Sprintf("status %d, expected %d", status, f.Response.Status))
+	}
+
+	rawHeaders, _ := repo.ReadFile(fmt.Sprintf("%s/res-%d.headers", contractDir, i))
+	headers := parseHeaders(rawHeaders)
+	for _, name := range sortedKeys(f.Response.Headers) {
+		want := f.Response.Headers[name]
+		if got := headers.Get(name); !strings.HasPrefix(got, want) {
+			problems = append(problems, fmt.Sprintf("header %s is %q, expected %q", name, got, want))
+		}
+	}
+
+	body, _ := repo.ReadFile(fmt.Sprintf("%s/res-%d.body", contractDir, i))
+	if p := compareBody(f.Response.Body, body, f.Response.Ignore); p != "" {
+		problems = append(problems, p)
+	}
+	return problems
+}
+
+// contractScript starts the service, waits up to two minutes for it to
+// answer, and captures the status, headers and body for each fixture with
+// curl, all in one command so it runs in a single sandbox container.
+func contractScript(cfg repoconfig.Contract, fixtures []Fixture) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("( %s ) > %s/server.log 2>&1 &\nserver=$!\n", cfg.Start, contractDir))
+	sb.WriteString(fmt.Sprintf(`i=0
+until curl -s -o /dev/null %s; do
+  i=$((i+1))
+  if [ $i -ge 60 ]; then echo "service did not start"; tail -n 50 %s/server.log; kill $server; exit 1; fi
+  sleep 2
+done
+`, shellQuote(cfg.URL), contractDir))
+	for i, f := range fixtures {
+		method := f.Request.Method
+		if method == "" {
+			method = http.MethodGet
+		}
+		args := []string{"curl", "-s", "-X", shellQuote(method)}
+		for _, name := range sortedKeys(f.Request.Headers) {
+			args = append(args, "-H", shellQuote(name+": "+f.Request.Headers[name]))
+		}
+		if f.Request.Body != "" {
+			args = append(args, "--data-binary", fmt.Sprintf("@%s/req-%d.body", contractDir, i))
+		}
+		args = append(args,
+			"-D", fmt.Sprintf("%s/res-%d.headers", contractDir, i),
+			"-o", fmt.Sprintf("%s/res-%d.body", contractDir, i),
+			"-w", "'%{http_code}'",
+			shellQuote(cfg.URL+f.Request.Path),
+			">", fmt.Sprintf("%s/res-%d.status", contractDir, i))
+		sb.WriteString(strings.Join(args, " ") + "\n")
+	}
+	sb.WriteString("kill $server\n")
+	return sb.String()
+}
+
+// compareBody compares a response body with the recorded one and describes
+// the difference, or returns "" if they match.
+func compareBody(want json.RawMessage, got string, ignore []string) string {
+	if len(want) == 0 {
+		return ""
+	}
+	var text string
+	if json.Unmarshal(want, &text) == nil {
+		if strings.TrimSpace(got) != strings.TrimSpace(text) {
+			return fmt.Sprintf("body differs:\nexpected: %s\nactual:   %s", truncate(text, 500), truncate(got, 500))
+		}
+		return ""
+	}
+
+	var wantV, gotV any
+	_ = json.Unmarshal(want, &wantV)
+	if err := json.Unmarshal([]byte(got), &gotV); err != nil {
+		return fmt.Sprintf("body is not JSON: %s", truncate(got, 500))
+	}
+	skip := make(map[string]bool, len(ignore))
+	for _, k := range ignore {
+		skip[k] = true
+	}
+	wantV, gotV = dropKeys(wantV, skip), dropKeys(gotV, skip)
+	if !reflect.DeepEqual(wantV, gotV) {
+		w, _ := json.Marshal(wantV)
+		g, _ := json.Marshal(gotV)
+		return fmt.Sprintf("body differs:\nexpected: %s\nactual:   %s", truncate(string(w), 500), truncate(string(g), 500))
+	}
+	return ""
+}
+
+// dropKeys removes the keys in skip from every object within v.
+func dropKeys(v any, skip map[string]bool) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			if skip[k] {
+				delete(t, k)
+				continue
+			}
+			t[k] = dropKeys(val, skip)
+		}
+	case []any:
+		for i, val := range t {
+			t[i] = dropKeys(val, skip)
+		}
+	}
+	return v
+}
+
+// parseHeaders reads the headers curl dumped with -D. After redirects the
+// dump holds several responses; the last one wins.
+func parseHeaders(raw string) http.Header {
+	h := make(http.Header)
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.HasPrefix(line, "HTTP/") {
+			h = make(http.Header)
+			continue
+		}
+		if k, v, ok := strings.Cut(line, ":"); ok {
+			h.Add(strings.TrimSpace(k), strings.TrimSpace(v))
+		}
+	}
+	return h
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// parseFixtures reads the fixtures file, a JSON array of Fixture.
+func parseFixtures(content string) ([]Fixture, error) {
+	var fixtures []Fixture
+	if err := json.Unmarshal([]byte(content), &fixtures); err != nil {
+		return nil, fmt.Errorf("parse contract fixtures: %w", err)
+	}
+	for i := range fixtures {
+		if fixtures[i].Name == "" {
+			fixtures[i].Name = fmt.Sprintf("%s %s", fixtures[i].Request.Method, fixtures[i].Request.Path)
+		}
+	}
+	return fixtures, nil
+}
+
+// renderContractReport renders the report for the review prompt.
+func renderContractReport(r ContractReport) string {
+	if r.Error != "" {
+		return "The service could not be started for contract testing:\n```\n" + r.Error + "\n```\n"
+	}
+	failed := r.Failed()
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%d of %d recorded fixtures matched.\n", len(r.Results)-len(failed), len(r.Results)))
+	for _, res := range failed {
+		sb.WriteString(fmt.Sprintf("\n### %s\n", res.Name))
+		for _, p := range res.Problems {
+			sb.WriteString("- " + p + "\n")
+		}
+	}
+	return sb.String()
+}
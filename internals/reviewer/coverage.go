@@ -0,0 +1,298 @@
+package reviewer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/repoconfig"
+	"github.com/jadenj13/droid/internals/sandbox"
+)
+
+// FileCoverage is the coverage of one file the PR changes.
+type FileCoverage struct {
+	Path      string
+	Base      float64 // percent of the file's lines covered on the base branch; -1 if not measured
+	Head      float64 // the same on the PR's branch
+	Added     int     // lines the PR adds that the profile instruments
+	Uncovered []int   // of those, the lines no test runs
+}
+
+// CoverageReport compares a PR's test coverage with its base branch's.
+type CoverageReport struct {
+	Base, Head float64 // percent of all instrumented lines covered; Base is -1 if not measured
+	Files      []FileCoverage
+	Error      string // set when the PR's coverage could not be measured
+}
+
+// CoverageRunner runs a repository's coverage command on a PR's branch and
+// its base. The PR's code is untrusted, so it only ever runs in the Docker
+// sandbox.
+type CoverageRunner struct {
+	sandbox sandbox.Config
+	token   string
+	network *git.Network
+	timeout time.Duration
+	log     *slog.Logger
+}
+
+func NewCoverageRunner(sb sandbox.Config, token string, network *git.Network, timeout time.Duration, log *slog.Logger) *CoverageRunner {
+	return &CoverageRunner{sandbox: sb, token: token, network: network, timeout: timeout, log: log}
+}
+
+// Run measures coverage on the PR branch, then on the base branch, and
+// compares the two for the files and lines diff changes.
+func (c *CoverageRunner) Run(ctx context.Context, provider git.GitProvider, pr git.PR, cfg repoconfig.Coverage, diff string) (CoverageReport, error) {
+	info, err := git.ParseRepoURL(provider.RepoURL())
+	if err != nil {
+		return CoverageReport{}, fmt.Errorf("parse repo url: %w", err)
+	}
+	repo, err := git.Clone(ctx, provider.RepoURL(), c.token,
+		git.WithCloneNetwork(c.network),
+		git.WithRunner(c.sandbox.RunnerFor(info.Owner+"/"+info.Repo)))
+	if err != nil {
+		return CoverageReport{}, fmt.Errorf("clone: %w", err)
+	}
+	defer repo.Cleanup()
+
+	runCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	if err := repo.CheckoutRemoteBranch(runCtx, pr.Branch); err != nil {
+		return CoverageReport{}, fmt.Errorf("checkout %s: %w", pr.Branch, err)
+	}
+	head, out, err := c.measure(runCtx, repo, cfg)
+	if err != nil {
+		return CoverageReport{}, err
+	}
+	if head == nil {
+		return CoverageReport{Error: truncate(out, 2000)}, nil
+	}
+
+	var base lineCoverage
+	if err := repo.CheckoutRemoteBranch(runCtx, pr.BaseBranch); err != nil {
+		c.log.Warn("could not check out base branch for coverage", "pr", pr.Number, "err", err)
+	} else if base, _, err = c.measure(runCtx, repo, cfg); err != nil {
+		return CoverageReport{}, err
+	}
+	return compareCoverage(base, head, addedLines(diff)), nil
+}
+
+// measure runs the coverage command and parses the profile it wrote. It
+// returns a nil coverage, and the command's output, when there is no usable
+// profile.
+func (c *CoverageRunner) measure(ctx context.Context, repo *git.Repo, cfg repoconfig.Coverage) (lineCoverage, string, error) {
+	out, err := repo.RunInDir(ctx, fmt.Sprintf("rm -f %s; %s", shellQuote(cfg.Profile), cfg.Command))
+	if err != nil {
+		return nil, "", fmt.Errorf("run coverage: %w", err)
+	}
+	profile, err := repo.ReadFile(cfg.Profile)
+	if err != nil {
+		return nil, out, nil
+	}
+	cov := parseCoverage(profile)
+	if len(cov) == 0 {
+		return nil, out, nil
+	}
+	return cov, out, nil
+}
+
+// lineCoverage maps a file, as the profile names it, to whether each
+// instrumented line ran.
+type lineCoverage map[string]map[int]bool
+
+func (l lineCoverage) mark(file string, line int, hit bool) {
+	if l[file] == nil {
+		l[file] = make(map[int]bool)
+	}
+	l[file][line] = l[file][line] || hit
+}
+
+// percent returns the share of lines covered in the files, or of all files
+// when none are given, and -1 when no line is instrumented.
+func (l lineCoverage) percent(files ...string) float64 {
+	if len(files) == 0 {
+		for f := range l {
+			files = append(files, f)
+		}
+	}
+	total, covered := 0, 0
+	for _, f := range files {
+		for _, hit := range l[f] {
+			total++
+			if hit {
+				covered++
+			}
+		}
+	}
+	if total == 0 {
+		return -1
+	}
+	return 100 * float64(covered) / float64(total)
+}
+
+// lookup returns the profile's name for the repo-relative path, matching by
+// suffix: Go profiles use import paths and LCOV often absolute ones.
+func (l lineCoverage) lookup(path string) (string, bool) {
+	if _, ok := l[path]; ok {
+		return path, true
+	}
+	for f := range l {
+		if strings.HasSuffix(f, "/"+path) {
+			return f, true
+		}
+	}
+	return "", false
+}
+
+// parseCoverage reads a Go cover profile ("mode: ..." then
+// "file:startLine.col,endLine.col statements count") or LCOV ("SF:" and
+// "DA:line,count" records).
+func parseCoverage(profile string) lineCoverage {
+	cov := make(lineCoverage)
+	if strings.HasPrefix(profile, "mode:") {
+		for _, line := range strings.Split(profile, "\n")[1:] {
+			file, rest, ok := strings.Cut(strings.TrimSpace(line), ":")
+			fields := strings.Fields(rest)
+			if !ok || len(fields) != 3 {
+				continue
+			}
+			start, end, ok := strings.Cut(fields[0], ",")
+			if !ok {
+				continue
+			}
+			from, _ := strconv.Atoi(strings.Split(start, ".")[0])
+			to, _ := strconv.Atoi(strings.Split(end, ".")[0])
+			count, _ := strconv.Atoi(fields[2])
+			for n := from; n > 0 && n <= to; n++ {
+				cov.mark(file, n, count > 0)
+			}
+		}
+		return cov
+	}
+
+	file := ""
+	for _, line := range strings.Split(profile, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "SF:"):
+			file = strings.TrimPrefix(line, "SF:")
+		case strings.HasPrefix(line, "DA:") && file != "":
+			parts := strings.Split(strings.TrimPrefix(line, "DA:"), ",")
+			if len(parts) < 2 {
+				continue
+			}
+			n, _ := strconv.Atoi(parts[0])
+			count, _ := strconv.Atoi(parts[1])
+			if n > 0 {
+				cov.mark(file, n, count > 0)
+			}
+		case line == "end_of_record":
+			file = ""
+		}
+	}
+	return cov
+}
+
+// addedLines returns the new-file line numbers each file in diff adds.
+func addedLines(diff string) map[string][]int {
+	out := make(map[string][]int)
+	for path, f := range git.ParseDiff(diff) {
+		for _, l := range f.Lines {
+			if l.Old == 0 {
+				out[path] = append(out[path], l.New)
+			}
+		}
+	}
+	return out
+}
+
+// compareCoverage builds the report for the files in added from the base and
+// head profiles; base may be nil.
+func compareCoverage(base, head lineCoverage, added map[string][]int) CoverageReport {
+	report := CoverageReport{Base: base.percent(), Head: head.percent()}
+	for _, path := range sortedKeys(added) {
+		name, ok := head.lookup(path)
+		if !ok {
+			continue // not code the profile covers, e.g. docs or tests
+		}
+		fc := FileCoverage{Path: path, Base: -1, Head: head.percent(name)}
+		if baseName, ok := base.lookup(path); ok {
+			fc.Base = base.percent(baseName)
+		}
+		for _, n := range added[path] {
+			hit, instrumented := head[name][n]
+			if !instrumented {
+				continue
+			}
+			fc.Added++
+			if !hit {
+				fc.Uncovered = append(fc.Uncovered, n)
+			}
+		}
+		report.Files = append(report.Files, fc)
+	}
+	return report
+}
+
+// renderCoverageReport formats the report for the review prompt and summary.
+func renderCoverageReport(r CoverageReport) string {
+	if r.Error != "" {
+		return "The coverage command produced no profile on the PR's branch:\n\n```\n" + r.Error + "\n```"
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Line coverage: %s on the base branch, %s with this PR", formatPercent(r.Base), formatPercent(r.Head)))
+	if r.Base >= 0 {
+		sb.WriteString(fmt.Sprintf(" (%+.1f points)", r.Head-r.Base))
+	}
+	sb.WriteString(".\n")
+	if len(r.Files) == 0 {
+		return sb.String() + "\nThe PR changes no file the coverage profile covers."
+	}
+
+	sb.WriteString("\n| File | Base | PR | New lines covered |\n|---|---|---|---|\n")
+	for _, f := range r.Files {
+		sb.WriteString(fmt.Sprintf("| `%s` | %s | %s | %d/%d |\n", f.Path, formatPercent(f.Base), formatPercent(f.Head), f.Added-len(f.Uncovered), f.Added))
+	}
+	var uncovered strings.Builder
+	for _, f := range r.Files {
+		if len(f.Uncovered) > 0 {
+			uncovered.WriteString(fmt.Sprintf("- `%s`: lines %s\n", f.Path, lineRanges(f.Uncovered)))
+		}
+	}
+	if uncovered.Len() > 0 {
+		sb.WriteString("\nNew or changed lines no test runs:\n\n" + uncovered.String())
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func formatPercent(p float64) string {
+	if p < 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.1f%%", p)
+}
+
+// lineRanges compresses sorted line numbers into "3-5, 9".
+func lineRanges(lines []int) string {
+	sort.Ints(lines)
+	var parts []string
+	for i := 0; i < len(lines); {
+		j := i
+		for j+1 < len(lines) && lines[j+1] == lines[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, strconv.Itoa(lines[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", lines[i], lines[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ", ")
+}
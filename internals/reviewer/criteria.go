@@ -0,0 +1,142 @@
+package reviewer
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jadenj13/droid/internals/git"
+)
+
+// Criterion statuses the reviewer reports. Only unmet blocks approval.
+const (
+	criterionMet     = "met"
+	criterionUnmet   = "unmet"
+	criterionUnclear = "unclear"
+)
+
+var (
+	checkboxLine     = regexp.MustCompile(`^(\s*[-*] )\[([ xX])\] (.+)$`)
+	headingLine      = regexp.MustCompile(`^#{1,6}\s`)
+	criteriaHeadline = regexp.MustCompile(`(?i)^#{1,6}\s.*acceptance criteria`)
+)
+
+// Criterion is one acceptance-criteria checkbox in an issue body.
+type Criterion struct {
+	Text    string
+	Checked bool
+}
+
+// criteriaLines returns the indexes of the lines of body holding acceptance
+// criteria: the checkboxes under an "Acceptance Criteria" heading, or every
+// checkbox when the body has no such heading.
+func criteriaLines(lines []string) []int {
+	section := slices.IndexFunc(lines, func(l string) bool { return criteriaHeadline.MatchString(strings.TrimSpace(l)) })
+	var out []int
+	for i, l := range lines {
+		if section >= 0 {
+			if i <= section {
+				continue
+			}
+			if headingLine.MatchString(strings.TrimSpace(l)) {
+				break
+			}
+		}
+		if checkboxLine.MatchString(l) {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// parseCriteria returns the acceptance criteria in an issue body, in order.
+func parseCriteria(body string) []Criterion {
+	lines := strings.Split(body, "\n")
+	var out []Criterion
+	for _, i := range criteriaLines(lines) {
+		m := checkboxLine.FindStringSubmatch(lines[i])
+		out = append(out, Criterion{Text: strings.TrimSpace(m[3]), Checked: m[2] != " "})
+	}
+	return out
+}
+
+// checkCriteria ticks the boxes of the criteria numbered in met (1-based, as
+// shown to the reviewer). It reports whether the body changed.
+func checkCriteria(body string, met []int) (string, bool) {
+	lines := strings.Split(body, "\n")
+	changed := false
+	for n, i := range criteriaLines(lines) {
+		if !slices.Contains(met, n+1) {
+			continue
+		}
+		if out := checkboxLine.ReplaceAllString(lines[i], "${1}[x] ${3}"); out != lines[i] {
+			lines[i], changed = out, true
+		}
+	}
+	return strings.Join(lines, "\n"), changed
+}
+
+// renderCriteriaPrompt numbers the criteria for the review prompt.
+func renderCriteriaPrompt(criteria []Criterion) string {
+	var sb strings.Builder
+	sb.WriteString("### Acceptance Criteria\n\n")
+	for i, c := range criteria {
+		sb.WriteString(fmt.Sprintf("%d. %s\n", i+1, c.Text))
+	}
+	sb.WriteString("\nFor every criterion, report in `criteria` whether the PR meets it and the evidence: the file and function, or the test, that shows it. Use unclear when the diff you have does not show either way.")
+	return sb.String()
+}
+
+// normalizeCriteria keeps the checks of criteria that exist, one per
+// criterion, with a known status.
+func normalizeCriteria(checks []git.CriterionCheck, count int) []git.CriterionCheck {
+	var out []git.CriterionCheck
+	for _, c := range checks {
+		if c.Index < 1 || c.Index > count || slices.ContainsFunc(out, func(o git.CriterionCheck) bool { return o.Index == c.Index }) {
+			continue
+		}
+		if c.Status != criterionMet && c.Status != criterionUnmet {
+			c.Status = criterionUnclear
+		}
+		out = append(out, c)
+	}
+	slices.SortFunc(out, func(a, b git.CriterionCheck) int { return a.Index - b.Index })
+	return out
+}
+
+// metCriteria returns the numbers of the criteria the review found met.
+func metCriteria(checks []git.CriterionCheck) []int {
+	var out []int
+	for _, c := range checks {
+		if c.Status == criterionMet {
+			out = append(out, c.Index)
+		}
+	}
+	return out
+}
+
+// renderCriteria is the per-criterion table of the review summary, or ""
+// when the issue has no criteria.
+func renderCriteria(criteria []Criterion, checks []git.CriterionCheck) string {
+	if len(criteria) == 0 {
+		return ""
+	}
+	icons := map[string]string{criterionMet: "✅ met", criterionUnmet: "❌ unmet", criterionUnclear: "❔ unclear"}
+	var sb strings.Builder
+	sb.WriteString("#### Acceptance criteria\n\n| # | Criterion | Status | Evidence |\n|---|---|---|---|\n")
+	for i, c := range criteria {
+		status, evidence := icons[criterionUnclear], "not assessed"
+		if j := slices.IndexFunc(checks, func(ch git.CriterionCheck) bool { return ch.Index == i+1 }); j >= 0 {
+			status, evidence = icons[checks[j].Status], checks[j].Evidence
+		}
+		sb.WriteString(fmt.Sprintf("| %d | %s | %s | %s |\n", i+1, tableCell(c.Text), status, tableCell(evidence)))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// tableCell flattens s into one Markdown table cell.
+func tableCell(s string) string {
+	s = strings.ReplaceAll(strings.TrimSpace(s), "\n", " ")
+	return truncate(strings.ReplaceAll(s, "|", `\|`), 300)
+}
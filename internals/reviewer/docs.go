@@ -0,0 +1,238 @@
+package reviewer
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/llm"
+	"github.com/jadenj13/droid/internals/repoconfig"
+)
+
+// DocsMode is what the reviewer does with a PR that changes public API
+// without touching the docs.
+type DocsMode string
+
+const (
+	DocsOff    DocsMode = ""
+	DocsReview DocsMode = "review" // flag the missing update in the review
+	DocsIssue  DocsMode = "issue"  // open a follow-up issue with a drafted update
+	DocsPR     DocsMode = "pr"     // open the issue labeled agent:ready, so the executor writes the docs PR
+)
+
+// ParseDocsMode accepts "", "off", "review", "issue" and "pr".
+func ParseDocsMode(s string) (DocsMode, error) {
+	switch m := DocsMode(strings.ToLower(strings.TrimSpace(s))); m {
+	case DocsOff, "off":
+		return DocsOff, nil
+	case DocsReview, DocsIssue, DocsPR:
+		return m, nil
+	default:
+		return DocsOff, fmt.Errorf("invalid docs mode %q — expected off, review, issue or pr", s)
+	}
+}
+
+const (
+	// docsMarker identifies the reviewer's comment linking a PR to its
+	// follow-up docs issue, so the issue is opened once per PR.
+	docsMarker = "<!-- droid:docs-sync -->"
+	// maxAPIChanges bounds the declarations listed for one PR.
+	maxAPIChanges = 30
+	// maxDocBytes bounds each documentation file sent to the LLM.
+	maxDocBytes = 8000
+)
+
+// apiDecl matches the declaration of an exported name, per file extension.
+var apiDecl = map[string]*regexp.Regexp{
+	".go":   regexp.MustCompile(`^(func|type|var|const)\s+(\([^)]*\)\s*)?[A-Z]\w*`),
+	".js":   regexp.MustCompile(`^export\s`),
+	".jsx":  regexp.MustCompile(`^export\s`),
+	".ts":   regexp.MustCompile(`^export\s`),
+	".tsx":  regexp.MustCompile(`^export\s`),
+	".py":   regexp.MustCompile(`^(async\s+)?(def|class)\s+[A-Za-z]\w*`),
+	".rs":   regexp.MustCompile(`^pub\s`),
+	".java": regexp.MustCompile(`^public\s`),
+	".kt":   regexp.MustCompile(`^(public\s+)?(fun|class|interface|object)\s`),
+}
+
+// apiDefinitions are file types that are public API in their entirety.
+var apiDefinitions = []string{".proto", ".graphql", ".gql"}
+
+// apiChanges lists the public declarations a diff adds or removes, as
+// "path: +line" entries. Test files are skipped.
+func apiChanges(diff string, docs repoconfig.Docs) []string {
+	var out []string
+	var file string
+	seen := make(map[string]bool)
+	add := func(entry string) {
+		if !seen[entry] && len(out) < maxAPIChanges {
+			seen[entry] = true
+			out = append(out, entry)
+		}
+	}
+	for _, line := range strings.Split(diff, "\n") {
+		if p, ok := strings.CutPrefix(line, "+++ "); ok {
+			file = strings.TrimPrefix(p, "b/")
+			if !docs.IsAPI(file) || isTestFile(file) {
+				file = ""
+			}
+			continue
+		}
+		if file == "" || strings.HasPrefix(line, "---") || line == "" || (line[0] != '+' && line[0] != '-') {
+			continue
+		}
+		ext := path.Ext(file)
+		base := strings.ToLower(path.Base(file))
+		if isAPIDefinition(ext, base) {
+			add(file + ": definition changed")
+			continue
+		}
+		re := apiDecl[ext]
+		code := strings.TrimSpace(line[1:])
+		if re == nil || !re.MatchString(code) {
+			continue
+		}
+		add(fmt.Sprintf("%s: %c%s", file, line[0], preview(code, 120)))
+	}
+	return out
+}
+
+func isAPIDefinition(ext, base string) bool {
+	for _, e := range apiDefinitions {
+		if ext == e {
+			return true
+		}
+	}
+	return strings.HasPrefix(base, "openapi") || strings.HasPrefix(base, "swagger")
+}
+
+func isTestFile(p string) bool {
+	base := path.Base(p)
+	return strings.HasSuffix(base, "_test.go") || strings.Contains(base, ".test.") || strings.Contains(base, ".spec.") ||
+		strings.HasPrefix(base, "test_") || strings.Contains(p, "/testdata/")
+}
+
+// undocumentedAPIChanges returns the public API changes of a PR that
+// touches no documentation file, or nil.
+func undocumentedAPIChanges(pr git.PR, docs repoconfig.Docs) []string {
+	for _, f := range diffFiles(pr.Diff) {
+		if docs.IsDoc(f) {
+			return nil
+		}
+	}
+	return apiChanges(pr.Diff, docs)
+}
+
+// renderAPIChanges is the review prompt section for undocumented API changes.
+func renderAPIChanges(changes []string, docs repoconfig.Docs) string {
+	paths := docs.Paths
+	if len(paths) == 0 {
+		paths = repoconfig.DefaultDocPaths
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("This PR changes these public declarations but no documentation file (%s):\n\n", strings.Join(paths, ", ")))
+	for _, c := range changes {
+		sb.WriteString("- " + c + "\n")
+	}
+	sb.WriteString("\nIf they change behaviour, signatures or usage that users rely on, say which docs need updating in your summary. " +
+		"Do not request changes for this alone, and ignore declarations that are not user-facing.")
+	return sb.String()
+}
+
+const docsDraftPrompt = `You keep a project's documentation in step with its code.
+You are given an approved pull request's public API changes and the current documentation.
+If users of the project need the documentation updated for these changes, reply with:
+- a first line that is a short issue title starting with "Docs: "
+- then the issue body in Markdown: which files and sections to update and why, and the drafted new or replacement text for each.
+If no documentation change is needed — the declarations are internal in practice, or the docs already cover them — reply with exactly NO_UPDATE.`
+
+// DraftDocsIssue asks the LLM for a follow-up issue updating the docs for a
+// PR's API changes. ok is false when no update is needed.
+func (a *Agent) DraftDocsIssue(ctx context.Context, pr git.PR, changes []string, docs map[string]string, model string) (title, body string, ok bool, err error) {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Pull request: %s\n%s\n\n%s\n\nPublic API changes:\n", pr.Title, pr.URL, truncate(pr.Description, 1500)))
+	for _, c := range changes {
+		sb.WriteString("- " + c + "\n")
+	}
+	sb.WriteString("\nDiff:\n" + truncate(pr.Diff, 15000) + "\n")
+	for name, content := range docs {
+		sb.WriteString(fmt.Sprintf("\nCurrent %s:\n```\n%s\n```\n", name, truncate(content, maxDocBytes)))
+	}
+
+	ctx = llm.ContextWithModel(ctx, model)
+	resp, err := a.llm.CompleteWithTools(ctx, docsDraftPrompt, []llm.Message{{Role: "user", Content: sb.String()}}, nil)
+	if err != nil {
+		return "", "", false, fmt.Errorf("draft docs issue: %w", err)
+	}
+	text := strings.TrimSpace(extractText(resp))
+	if text == "" || strings.HasPrefix(text, "NO_UPDATE") {
+		return "", "", false, nil
+	}
+	title, body, _ = strings.Cut(text, "\n")
+	title = strings.TrimSpace(strings.TrimLeft(title, "# "))
+	if !strings.HasPrefix(title, "Docs:") {
+		title = "Docs: " + title
+	}
+	return title, strings.TrimSpace(body), true, nil
+}
+
+// openDocsIssue opens the follow-up docs issue for an approved PR, once.
+// Failures are logged: the docs check never blocks a review.
+func (w *Worker) openDocsIssue(ctx context.Context, provider git.GitProvider, pr git.PR, cfg repoconfig.Config, changes []string) {
+	if existing, err := provider.GetMarkedComment(ctx, pr.Number, docsMarker); err != nil || existing != "" {
+		return
+	}
+
+	// Read the documentation files named outright; globs would need a clone.
+	paths := cfg.Docs.Paths
+	if len(paths) == 0 {
+		paths = []string{"README.md"}
+	}
+	docs := make(map[string]string)
+	for _, p := range paths {
+		if strings.ContainsAny(p, "*?[") || len(docs) == 3 {
+			continue
+		}
+		if content, err := provider.GetFileAtRef(ctx, p, pr.BaseBranch); err == nil {
+			docs[p] = content
+		}
+	}
+
+	title, body, ok, err := w.agent.DraftDocsIssue(ctx, pr, changes, docs, cfg.Model)
+	if err != nil {
+		w.log.Warn("docs check failed", "pr", pr.Number, "err", err)
+		return
+	}
+	if !ok {
+		w.log.Info("docs check: no update needed", "pr", pr.Number)
+		return
+	}
+
+	input := git.IssueInput{
+		Title: title,
+		Body:  fmt.Sprintf("%s\n\n---\nFollow-up to %s, which changes public API without updating the docs.\n", body, pr.URL),
+	}
+	if w.docsMode == DocsPR {
+		input.Labels = []string{"agent:ready"}
+	}
+	issue, err := provider.CreateIssue(ctx, input)
+	if err != nil {
+		w.log.Warn("failed to open docs issue", "pr", pr.Number, "err", err)
+		return
+	}
+	comment := fmt.Sprintf("This PR changes public API without updating the docs, so I opened %s to follow up.\n\n%s", issue.URL, docsMarker)
+	if err := provider.UpsertMarkedComment(ctx, pr.Number, docsMarker, comment); err != nil {
+		w.log.Warn("failed to link docs issue", "pr", pr.Number, "err", err)
+	}
+	w.log.Info("docs issue opened", "pr", pr.Number, "issue", issue.URL)
+}
+
+func preview(s string, n int) string {
+	if len(s) > n {
+		return s[:n] + "…"
+	}
+	return s
+}
@@ -0,0 +1,183 @@
+package reviewer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/llm"
+)
+
+// maxExploreTurns caps how many rounds of repository tools the reviewer gets
+// before it must submit its review.
+const maxExploreTurns = 15
+
+// maxReadBytes caps a read_file result so one large file cannot crowd out the
+// diff.
+const maxReadBytes = 30000
+
+var toolReadFile = anthropic.ToolParam{
+	Name:        "read_file",
+	Description: anthropic.String("Read a file from the PR's branch, to see the code around a change."),
+	InputSchema: anthropic.ToolInputSchemaParam{
+		Properties: map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Path to the file relative to the repo root.",
+			},
+		},
+		Required: []string{"path"},
+	},
+}
+
+var toolSearchCode = anthropic.ToolParam{
+	Name:        "search_code",
+	Description: anthropic.String("Search the PR's branch with a regular expression (git grep), e.g. for the call sites of a changed function or the tests covering it. Results are 'path:line:text'."),
+	InputSchema: anthropic.ToolInputSchemaParam{
+		Properties: map[string]interface{}{
+			"pattern": map[string]interface{}{
+				"type":        "string",
+				"description": "Extended regular expression.",
+			},
+			"globs": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Optional path globs to restrict the search. E.g. ['**/*_test.go'].",
+			},
+			"context_lines": map[string]interface{}{
+				"type":        "integer",
+				"description": "Lines of context to show around each match (0-10).",
+			},
+		},
+		Required: []string{"pattern"},
+	},
+}
+
+var toolListFiles = anthropic.ToolParam{
+	Name:        "list_files",
+	Description: anthropic.String("Show the repository's files on the PR's branch as a directory tree, optionally scoped to a subdirectory."),
+	InputSchema: anthropic.ToolInputSchemaParam{
+		Properties: map[string]interface{}{
+			"subdir": map[string]interface{}{
+				"type":        "string",
+				"description": "Subdirectory relative to the repo root; omit for the whole repository.",
+			},
+			"depth": map[string]interface{}{
+				"type":        "integer",
+				"description": "How many directory levels to expand.",
+			},
+		},
+	},
+}
+
+// explore lets the model read the PR's branch in repo with the repository
+// tools until it calls submit_review, and returns that call's input, or the
+// model's text if it answered without the tool.
+func (a *Agent) explore(ctx context.Context, system string, msgs []llm.Message, repo *git.Repo) (json.RawMessage, string, error) {
+	tools := []anthropic.ToolParam{toolListFiles, toolSearchCode, toolReadFile, toolSubmitReview}
+	for turn := 0; ; turn++ {
+		if turn == maxExploreTurns {
+			// Out of turns: leave only the verdict to give.
+			tools = []anthropic.ToolParam{toolSubmitReview}
+		}
+		resp, err := a.llm.CompleteWithTools(ctx, system, msgs, tools)
+		if err != nil {
+			return nil, "", fmt.Errorf("llm review turn %d: %w", turn, err)
+		}
+
+		var results []anthropic.ToolResultBlockParam
+		for _, block := range resp.Content {
+			if block.Type != "tool_use" {
+				continue
+			}
+			if block.Name == toolSubmitReview.Name {
+				return block.Input, "", nil
+			}
+			content := a.executeTool(ctx, repo, block.Name, block.Input)
+			if turn+1 == maxExploreTurns {
+				content += "\n\nYou are out of exploration turns. Call submit_review next."
+			}
+			a.log.Info("reviewer tool executed", "tool", block.Name, "turn", turn)
+			results = append(results, anthropic.ToolResultBlockParam{
+				ToolUseID: block.ID,
+				Content: []anthropic.ToolResultBlockParamContentUnion{
+					{OfText: &anthropic.TextBlockParam{Text: content}},
+				},
+			})
+		}
+		if len(results) == 0 {
+			return nil, extractText(resp), nil
+		}
+		blocks, _ := json.Marshal(resp.Content)
+		msgs = append(msgs,
+			llm.Message{Role: "assistant", Content: string(blocks)},
+			llm.Message{Role: "tool_result", RawBlocks: results},
+		)
+	}
+}
+
+// executeTool runs one repository tool. Failures are reported to the model
+// as the result rather than ending the review.
+func (a *Agent) executeTool(ctx context.Context, repo *git.Repo, name string, raw json.RawMessage) string {
+	switch name {
+	case toolReadFile.Name:
+		var in struct {
+			Path string `json:"path"`
+		}
+		if err := json.Unmarshal(raw, &in); err != nil {
+			return "error: " + err.Error()
+		}
+		content, err := repo.ReadFile(in.Path)
+		if err != nil {
+			return "error: " + err.Error()
+		}
+		return truncate(content, maxReadBytes)
+	case toolSearchCode.Name:
+		var in struct {
+			Pattern      string   `json:"pattern"`
+			Globs        []string `json:"globs"`
+			ContextLines int      `json:"context_lines"`
+		}
+		if err := json.Unmarshal(raw, &in); err != nil {
+			return "error: " + err.Error()
+		}
+		out, err := repo.Search(ctx, in.Pattern, git.SearchOptions{Globs: in.Globs, ContextLines: in.ContextLines})
+		if err != nil {
+			return "error: " + err.Error()
+		}
+		if out == "" {
+			return "no matches"
+		}
+		return out
+	case toolListFiles.Name:
+		var in struct {
+			Subdir string `json:"subdir"`
+			Depth  int    `json:"depth"`
+		}
+		if err := json.Unmarshal(raw, &in); err != nil {
+			return "error: " + err.Error()
+		}
+		out, err := repo.ListFiles(ctx, in.Subdir, in.Depth)
+		if err != nil {
+			return "error: " + err.Error()
+		}
+		return out
+	default:
+		return fmt.Sprintf("error: unknown tool %q", name)
+	}
+}
+
+// cloneForReview shallow-clones the PR's branch for the repository tools.
+func (w *Worker) cloneForReview(ctx context.Context, provider git.GitProvider, pr git.PR) (*git.Repo, error) {
+	repo, err := git.Clone(ctx, provider.RepoURL(), w.repoAccess.token, git.WithCloneNetwork(w.repoAccess.network))
+	if err != nil {
+		return nil, fmt.Errorf("clone: %w", err)
+	}
+	if err := repo.CheckoutRemoteBranch(ctx, pr.Branch); err != nil {
+		repo.Cleanup()
+		return nil, fmt.Errorf("checkout %s: %w", pr.Branch, err)
+	}
+	return repo, nil
+}
@@ -0,0 +1,247 @@
+package reviewer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/retention"
+)
+
+// RaisedComment is an inline comment the reviewer posted in an earlier round.
+type RaisedComment struct {
+	ID       int    `json:"id"` // 1-based, per PR
+	Round    int    `json:"round"`
+	Path     string `json:"path"`
+	Line     int    `json:"line"`
+	Body     string `json:"body"`
+	Severity string `json:"severity,omitempty"`
+	Category string `json:"category,omitempty"`
+	Resolved int    `json:"resolved,omitempty"` // round that found it resolved; 0 while open
+}
+
+// RoundVerdict is the verdict of one review round.
+type RoundVerdict struct {
+	Round   int       `json:"round"`
+	SHA     string    `json:"sha"`
+	Verdict string    `json:"verdict"`
+	At      time.Time `json:"at"`
+}
+
+// ReviewState is what the reviewer remembers about a PR between rounds: the
+// head commit it last reviewed, each round's verdict and the comments it
+// raised.
+type ReviewState struct {
+	RepoURL     string          `json:"repo_url"`
+	PRNumber    int             `json:"pr_number"`
+	ReviewedSHA string          `json:"reviewed_sha"`
+	Round       int             `json:"round"`
+	ReviewedAt  time.Time       `json:"reviewed_at"`
+	Verdicts    []RoundVerdict  `json:"verdicts,omitempty"`
+	Comments    []RaisedComment `json:"comments"`
+}
+
+// Revisions counts the rounds that requested changes.
+func (s ReviewState) Revisions() int {
+	n := 0
+	for _, v := range s.Verdicts {
+		if v.Verdict == "request_changes" {
+			n++
+		}
+	}
+	return n
+}
+
+// Open returns the comments no round has found resolved.
+func (s ReviewState) Open() []RaisedComment {
+	var out []RaisedComment
+	for _, c := range s.Comments {
+		if c.Resolved == 0 {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// ReviewHistory keeps each PR's review state in a single JSON file, or only
+// in memory when it has no path.
+type ReviewHistory struct {
+	path string
+
+	mu     sync.Mutex
+	states map[string]ReviewState // key: repo URL + "#" + PR number
+}
+
+func NewReviewHistory(path string) (*ReviewHistory, error) {
+	h := &ReviewHistory{path: path, states: make(map[string]ReviewState)}
+	if path == "" {
+		return h, nil
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read review history: %w", err)
+	}
+	if err := json.Unmarshal(b, &h.states); err != nil {
+		return nil, fmt.Errorf("decode review history: %w", err)
+	}
+	return h, nil
+}
+
+// Get returns the PR's state; the zero state if it was never reviewed.
+func (h *ReviewHistory) Get(repoURL string, prNumber int) ReviewState {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.states[calibrationKey(repoURL, prNumber)]
+}
+
+// Record stores a posted review of the PR at headSHA: its verdict, the
+// comments it raised, and the earlier comments it found resolved.
+func (h *ReviewHistory) Record(repoURL string, prNumber int, headSHA string, review git.Review) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := calibrationKey(repoURL, prNumber)
+	s := h.states[key]
+	s.RepoURL, s.PRNumber = repoURL, prNumber
+	s.ReviewedSHA = headSHA
+	s.Round++
+	s.ReviewedAt = time.Now()
+	s.Verdicts = append(s.Verdicts, RoundVerdict{Round: s.Round, SHA: headSHA, Verdict: review.Verdict, At: s.ReviewedAt})
+	for i, c := range s.Comments {
+		if c.Resolved == 0 && slices.Contains(review.Resolved, c.ID) {
+			s.Comments[i].Resolved = s.Round
+		}
+	}
+	for _, c := range review.Comments {
+		s.Comments = append(s.Comments, RaisedComment{
+			ID:       len(s.Comments) + 1,
+			Round:    s.Round,
+			Path:     c.Path,
+			Line:     c.Line,
+			Body:     c.Body,
+			Severity: c.Severity,
+			Category: c.Category,
+		})
+	}
+	h.states[key] = s
+	return h.save()
+}
+
+// Withdraw marks the open comment at path:line that was posted as posted
+// resolved, so later rounds neither show it nor count it toward the verdict.
+func (h *ReviewHistory) Withdraw(repoURL string, prNumber int, path string, line int, posted string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := calibrationKey(repoURL, prNumber)
+	s, ok := h.states[key]
+	if !ok {
+		return nil
+	}
+	for i, c := range s.Comments {
+		if c.Resolved == 0 && c.Path == path && c.Line == line && strings.Contains(posted, c.Body) {
+			s.Comments[i].Resolved = s.Round
+			h.states[key] = s
+			return h.save()
+		}
+	}
+	return nil
+}
+
+func (h *ReviewHistory) save() error {
+	if h.path == "" {
+		return nil
+	}
+	b, err := json.MarshalIndent(h.states, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal review history: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o755); err != nil {
+		return fmt.Errorf("create review history dir: %w", err)
+	}
+	tmp := h.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return fmt.Errorf("write review history: %w", err)
+	}
+	return os.Rename(tmp, h.path)
+}
+
+// Name implements retention.Source.
+func (h *ReviewHistory) Name() string { return "reviewer_history" }
+
+// ExportData returns the states f selects, matched on the last review.
+// States hold no Slack user data, so a user filter matches none.
+func (h *ReviewHistory) ExportData(f retention.Filter) (any, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := []ReviewState{}
+	for _, s := range h.states {
+		if f.Match(s.RepoURL, nil, s.ReviewedAt) {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+// DeleteData removes the states f selects.
+func (h *ReviewHistory) DeleteData(f retention.Filter) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := 0
+	for key, s := range h.states {
+		if f.Match(s.RepoURL, nil, s.ReviewedAt) {
+			delete(h.states, key)
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	return n, h.save()
+}
+
+// renderEarlier lists the earlier comments a review saw, split by whether it
+// found them resolved, for the review summary.
+func renderEarlier(open []RaisedComment, resolved []int) string {
+	if len(open) == 0 {
+		return ""
+	}
+	var done, pending strings.Builder
+	for _, c := range open {
+		sev := ""
+		if c.Severity != "" {
+			sev = " " + c.Severity + ":"
+		}
+		line := fmt.Sprintf("- `%s:%d` (round %d)%s %s\n", c.Path, c.Line, c.Round, sev, truncate(firstLine(c.Body), 120))
+		if slices.Contains(resolved, c.ID) {
+			done.WriteString(line)
+		} else {
+			pending.WriteString(line)
+		}
+	}
+	var sb strings.Builder
+	sb.WriteString("#### Earlier comments\n\n")
+	if done.Len() > 0 {
+		sb.WriteString("✅ **Resolved**\n\n" + done.String() + "\n")
+	}
+	if pending.Len() > 0 {
+		sb.WriteString("⏳ **Unresolved**\n\n" + pending.String())
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
@@ -0,0 +1,76 @@
+package reviewer
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Labels names the labels the reviewer watches and sets, so an org with its
+// own label taxonomy can keep it. The executor and planner must be given the
+// same Review and Revision names, as those hand work between the services.
+type Labels struct {
+	Review     string // on a PR (or its issue): review it
+	Revision   string // on the issue of a PR that needs changes: the executor revises it
+	Approved   string // on the issue of an approved PR
+	NeedsHuman string // on an escalated PR
+}
+
+// DefaultLabels are the agent:* labels onboarding creates.
+func DefaultLabels() Labels {
+	return Labels{
+		Review:     "agent:review",
+		Revision:   "agent:revision",
+		Approved:   "agent:approved",
+		NeedsHuman: "agent:needs-human",
+	}
+}
+
+// withDefaults fills the names l leaves empty with the defaults.
+func (l Labels) withDefaults() Labels {
+	d := DefaultLabels()
+	l.Review = cmp.Or(l.Review, d.Review)
+	l.Revision = cmp.Or(l.Revision, d.Revision)
+	l.Approved = cmp.Or(l.Approved, d.Approved)
+	l.NeedsHuman = cmp.Or(l.NeedsHuman, d.NeedsHuman)
+	return l
+}
+
+// Trigger is an event that starts a review.
+type Trigger string
+
+const (
+	TriggerLabel   Trigger = "label"   // the review label is added to a PR
+	TriggerPush    Trigger = "push"    // commits are pushed to a PR carrying the review label
+	TriggerComment Trigger = "comment" // someone comments /droid review on a PR
+	TriggerOpened  Trigger = "opened"  // a PR is opened, reopened or marked ready for review
+)
+
+// DefaultTriggers are the events that start a review unless configured.
+func DefaultTriggers() []Trigger {
+	return []Trigger{TriggerLabel, TriggerPush, TriggerComment}
+}
+
+// ParseTriggers accepts a comma-separated list of label, push, comment and
+// opened; an empty list means the defaults.
+func ParseTriggers(s string) ([]Trigger, error) {
+	var out []Trigger
+	for _, part := range strings.Split(s, ",") {
+		t := Trigger(strings.ToLower(strings.TrimSpace(part)))
+		switch t {
+		case "":
+			continue
+		case TriggerLabel, TriggerPush, TriggerComment, TriggerOpened:
+			if !slices.Contains(out, t) {
+				out = append(out, t)
+			}
+		default:
+			return nil, fmt.Errorf("invalid review trigger %q — expected label, push, comment or opened", part)
+		}
+	}
+	if len(out) == 0 {
+		return DefaultTriggers(), nil
+	}
+	return out, nil
+}
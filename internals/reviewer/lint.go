@@ -0,0 +1,137 @@
+package reviewer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/sandbox"
+)
+
+// maxLintComments caps the lint findings posted inline on one review.
+const maxLintComments = 30
+
+// lintPrefix starts every inline comment made from a linter finding, so they
+// read apart from the reviewer's own.
+const lintPrefix = "🤖 **Automated lint**"
+
+// lintLine matches the "file:line[:col]: message" lines golangci-lint, go
+// vet, ruff and eslint's unix formatter print.
+var lintLine = regexp.MustCompile(`^([^\s:][^:]*):(\d+)(?::\d+)?:\s*(.+)$`)
+
+// LintFinding is one linter message on a line the PR adds.
+type LintFinding struct {
+	Path    string
+	Line    int
+	Message string
+}
+
+// LintRunner runs a repository's lint command on a PR's branch. The PR's code
+// is untrusted, so it only ever runs in the Docker sandbox.
+type LintRunner struct {
+	sandbox sandbox.Config
+	token   string
+	network *git.Network
+	timeout time.Duration
+	log     *slog.Logger
+}
+
+func NewLintRunner(sb sandbox.Config, token string, network *git.Network, timeout time.Duration, log *slog.Logger) *LintRunner {
+	return &LintRunner{sandbox: sb, token: token, network: network, timeout: timeout, log: log}
+}
+
+// Run runs command on the PR branch and returns its findings on the lines
+// diff adds; findings on code the PR did not touch are dropped.
+func (l *LintRunner) Run(ctx context.Context, provider git.GitProvider, pr git.PR, command, diff string) ([]LintFinding, error) {
+	info, err := git.ParseRepoURL(provider.RepoURL())
+	if err != nil {
+		return nil, fmt.Errorf("parse repo url: %w", err)
+	}
+	repo, err := git.Clone(ctx, provider.RepoURL(), l.token,
+		git.WithCloneNetwork(l.network),
+		git.WithRunner(l.sandbox.RunnerFor(info.Owner+"/"+info.Repo)))
+	if err != nil {
+		return nil, fmt.Errorf("clone: %w", err)
+	}
+	defer repo.Cleanup()
+
+	runCtx, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+	if err := repo.CheckoutRemoteBranch(runCtx, pr.Branch); err != nil {
+		return nil, fmt.Errorf("checkout %s: %w", pr.Branch, err)
+	}
+	out, err := repo.RunInDir(runCtx, command)
+	if err != nil {
+		return nil, fmt.Errorf("run lint: %w", err)
+	}
+	return lintFindings(out, addedLines(diff)), nil
+}
+
+// lintFindings picks the findings in linter output that fall on added lines.
+// Linters print paths relative to where they ran, sometimes absolute, so a
+// path matches a changed file it ends with.
+func lintFindings(out string, added map[string][]int) []LintFinding {
+	var findings []LintFinding
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(out, "\n") {
+		m := lintLine.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		n, _ := strconv.Atoi(m[2])
+		file := path.Clean(m[1])
+		for changed, lines := range added {
+			if (file == changed || strings.HasSuffix(file, "/"+changed)) && slices.Contains(lines, n) {
+				key := fmt.Sprintf("%s:%d:%s", changed, n, m[3])
+				if !seen[key] {
+					seen[key] = true
+					findings = append(findings, LintFinding{Path: changed, Line: n, Message: strings.TrimSpace(m[3])})
+				}
+				break
+			}
+		}
+	}
+	return findings
+}
+
+// lintComments turns findings into inline comments, one per line, up to
+// maxLintComments.
+func lintComments(findings []LintFinding) []git.PRComment {
+	var comments []git.PRComment
+	byLine := make(map[string]int) // path:line → index in comments
+	for _, f := range findings {
+		key := fmt.Sprintf("%s:%d", f.Path, f.Line)
+		if i, ok := byLine[key]; ok {
+			comments[i].Body += "\n- " + f.Message
+			continue
+		}
+		if len(comments) == maxLintComments {
+			continue
+		}
+		byLine[key] = len(comments)
+		comments = append(comments, git.PRComment{
+			Path:     f.Path,
+			Line:     f.Line,
+			Body:     lintPrefix + "\n\n- " + f.Message,
+			Severity: "minor",
+			Category: "style",
+		})
+	}
+	return comments
+}
+
+// renderLintFindings lists the findings for the review prompt.
+func renderLintFindings(findings []LintFinding) string {
+	var sb strings.Builder
+	for _, f := range findings {
+		sb.WriteString(fmt.Sprintf("- `%s:%d` %s\n", f.Path, f.Line, truncate(f.Message, 200)))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
@@ -0,0 +1,60 @@
+package reviewer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jadenj13/droid/internals/git"
+)
+
+// MissingIssuePolicy is what the reviewer does with a PR whose linked issue
+// could not be fetched, so it was reviewed against no acceptance criteria.
+type MissingIssuePolicy string
+
+const (
+	MissingIssueEscalate MissingIssuePolicy = "escalate" // post the review and hand the PR to a human
+	MissingIssueComment  MissingIssuePolicy = "comment"  // post an approval as a comment; change requests stand
+	MissingIssueReview   MissingIssuePolicy = "review"   // keep the verdict, judged against the PR alone
+)
+
+// ParseMissingIssuePolicy accepts "", "escalate", "comment" and "review"; an
+// empty policy escalates.
+func ParseMissingIssuePolicy(s string) (MissingIssuePolicy, error) {
+	switch p := MissingIssuePolicy(strings.ToLower(strings.TrimSpace(s))); p {
+	case "":
+		return MissingIssueEscalate, nil
+	case MissingIssueEscalate, MissingIssueComment, MissingIssueReview:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid missing-issue policy %q — expected escalate, comment or review", s)
+	}
+}
+
+// missingIssueSection is the prompt's issue section for a PR whose linked
+// issue could not be fetched.
+func missingIssueSection(url, reason string) string {
+	return fmt.Sprintf(`## Original Issue
+
+Unavailable — this pull request links %s, but it could not be fetched (%s).
+You cannot check the PR against the issue's acceptance criteria, so do not assume it
+meets them: judge it against its title and description, review the code for correctness,
+tests, conventions and security as usual, and say in the summary that the issue was not
+checked. Leave criteria empty.`, url, reason)
+}
+
+// applyMissingIssue adds the warning for a PR reviewed without its linked
+// issue to review's summary and applies policy to its verdict.
+func applyMissingIssue(review *git.Review, url, reason string, policy MissingIssuePolicy) {
+	review.Summary += fmt.Sprintf("\n\n> ⚠️ **Issue not checked.** The linked issue %s could not be fetched (%s), so this review did not check the PR against its acceptance criteria.", url, reason)
+	switch policy {
+	case MissingIssueEscalate:
+		if review.Verdict != "escalate" {
+			review.Verdict = "escalate"
+			review.Reasons = append(review.Reasons, "The linked issue could not be fetched, so the PR could not be checked against its acceptance criteria.")
+		}
+	case MissingIssueComment:
+		if review.Verdict == "approve" {
+			review.Verdict = "comment"
+		}
+	}
+}
@@ -3,8 +3,11 @@ package reviewer
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/slack-go/slack"
+
+	slackhandler "github.com/jadenj13/droid/internals/slack"
 )
 
 type SlackNotifier struct {
@@ -38,3 +41,109 @@ func (n *SlackNotifier) NotifyPRReady(ctx context.Context, msg PRReadyMessage) e
 	}
 	return nil
 }
+
+// NotifyChangesRequested posts the top findings and the review summary, with
+// routing buttons when msg.Route is set. The planner handles the buttons,
+// since it owns the Slack Socket Mode connection.
+func (n *SlackNotifier) NotifyChangesRequested(ctx context.Context, msg ChangesRequestedMessage) error {
+	next := "the executor will revise"
+	if msg.Route {
+		next = "choose who fixes it"
+	}
+	findings := ""
+	for _, f := range msg.Findings {
+		findings += "\n• " + f
+	}
+	text := fmt.Sprintf(
+		":memo: *Changes requested* — %s (%d inline comment(s))\n"+
+			"*<%s|%s>*\n"+
+			"Issue: <%s|%s>\n"+
+			"Repo: %s%s\n"+
+			">%s",
+		next, msg.Comments,
+		msg.PRURL, msg.PRTitle,
+		msg.IssueURL, msg.IssueTitle,
+		msg.RepoURL, findings,
+		strings.ReplaceAll(truncate(msg.Summary, 1500), "\n", "\n>"),
+	)
+	if !msg.Route {
+		_, _, err := n.client.PostMessageContext(ctx, n.channelID,
+			slack.MsgOptionText(text, false),
+		)
+		if err != nil {
+			return fmt.Errorf("slack notify: %w", err)
+		}
+		return nil
+	}
+
+	ref := slackhandler.ReviewRef{IssueURL: msg.IssueURL, PRNumber: msg.PRNumber}.String()
+	button := func(route slackhandler.ReviewRoute, label, style string) *slack.ButtonBlockElement {
+		b := slack.NewButtonBlockElement(string(route), ref, slack.NewTextBlockObject(slack.PlainTextType, label, false, false))
+		b.Style = slack.Style(style)
+		return b
+	}
+	_, _, err := n.client.PostMessageContext(ctx, n.channelID,
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionBlocks(
+			slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil),
+			slack.NewActionBlock("",
+				button(slackhandler.RouteRevise, "Send to executor for revision", "primary"),
+				button(slackhandler.RouteSelf, "I'll fix it myself", ""),
+				button(slackhandler.RouteDismiss, "Dismiss review", "danger"),
+			),
+		),
+	)
+	if err != nil {
+		return fmt.Errorf("slack notify: %w", err)
+	}
+	return nil
+}
+
+// NotifyEscalated posts the reasons the reviewer handed a PR to a human.
+func (n *SlackNotifier) NotifyEscalated(ctx context.Context, msg EscalationMessage) error {
+	reasons := ""
+	for _, r := range msg.Reasons {
+		reasons += "\n• " + r
+	}
+	if reasons == "" {
+		reasons = "\n>" + strings.ReplaceAll(truncate(msg.Summary, 1500), "\n", "\n>")
+	}
+	text := fmt.Sprintf(
+		":raising_hand: *PR needs a human decision*\n"+
+			"*<%s|%s>*\n"+
+			"Issue: <%s|%s>\n"+
+			"Repo: %s%s",
+		msg.PRURL, msg.PRTitle,
+		msg.IssueURL, msg.IssueTitle,
+		msg.RepoURL,
+		reasons,
+	)
+
+	_, _, err := n.client.PostMessageContext(ctx, n.channelID,
+		slack.MsgOptionText(text, false),
+	)
+	if err != nil {
+		return fmt.Errorf("slack notify: %w", err)
+	}
+	return nil
+}
+
+// NotifyReviewFailed posts the error a review stopped on.
+func (n *SlackNotifier) NotifyReviewFailed(ctx context.Context, msg ReviewFailedMessage) error {
+	text := fmt.Sprintf(
+		":warning: *Review failed* — PR #%d will not be reviewed until someone retries it\n"+
+			"Repo: %s\n"+
+			"```%s```",
+		msg.PRNumber,
+		msg.RepoURL,
+		truncate(msg.Error, 1500),
+	)
+
+	_, _, err := n.client.PostMessageContext(ctx, n.channelID,
+		slack.MsgOptionText(text, false),
+	)
+	if err != nil {
+		return fmt.Errorf("slack notify: %w", err)
+	}
+	return nil
+}
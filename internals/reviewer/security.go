@@ -0,0 +1,129 @@
+package reviewer
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/llm"
+	"github.com/jadenj13/droid/internals/repoconfig"
+	"github.com/jadenj13/droid/internals/standards"
+)
+
+const securitySystemPrompt = `You are an application security reviewer. You will be given part of a pull
+request diff that touches security-sensitive code. Look only for security problems:
+
+- Injection: SQL, shell commands, templates, paths, LDAP, headers built from untrusted input
+- Authentication and authorization: missing or bypassable checks, privilege escalation, IDOR
+- Secrets: credentials, tokens or keys committed, logged, or returned to callers
+- Unsafe deserialization of untrusted data (pickle, YAML, gob, Java serialization, eval)
+- Cryptography: weak algorithms, hardcoded keys or IVs, non-constant-time comparisons, insecure randomness
+- Data exposure: sensitive fields in responses, logs or error messages; missing input validation; SSRF
+
+Ignore style, performance and general correctness — the main review covers those.
+Report only problems you can point to in the diff, with the exploit path in the comment.
+Grade each finding: blocker for an exploitable hole, major for a real weakness,
+minor for hardening. Use the category security.
+Call submit_review with verdict approve if you found nothing; the verdict of the
+whole review is decided elsewhere. Summarize what you checked and what you found.`
+
+// sensitiveDiff returns the parts of diff that change files scope covers —
+// by path glob or by language — and those files, or "" when there are none.
+func sensitiveDiff(diff string, scope repoconfig.Security) (string, []string) {
+	var sb strings.Builder
+	var files []string
+	for _, f := range splitFiles(diff) {
+		name := diffPath(f)
+		if name == "" {
+			continue
+		}
+		langs := standards.Languages([]string{name})
+		if !scope.Sensitive(name) && !(len(langs) > 0 && slices.Contains(scope.Languages, langs[0])) {
+			continue
+		}
+		sb.WriteString(f)
+		files = append(files, name)
+	}
+	return sb.String(), files
+}
+
+// diffPath returns the new path of one file's diff, from its "+++ " line.
+func diffPath(file string) string {
+	for _, line := range strings.SplitN(file, "\n", 3) {
+		if p, ok := strings.CutPrefix(line, "+++ "); ok {
+			return strings.TrimSpace(p)
+		}
+	}
+	return ""
+}
+
+// SecurityReview reviews the changes to files in scope with the security
+// prompt, part by part when they are large. It returns false when the PR
+// changes none of them.
+func (a *Agent) SecurityReview(ctx context.Context, req ReviewRequest, scope repoconfig.Security) (git.Review, bool, error) {
+	diff, files := sensitiveDiff(req.PR.Diff, scope)
+	if len(files) == 0 {
+		return git.Review{}, false, nil
+	}
+	a.log.Info("running security review", "pr", req.PR.Number, "files", len(files))
+
+	ctx = llm.ContextWithModel(ctx, req.Config.Model)
+	chunks := splitDiff(diff, maxDiffChars)
+	review := git.Review{Verdict: "approve"}
+	var summaries []string
+	for i, chunk := range chunks {
+		content := fmt.Sprintf("Review this diff for security problems.\n\n## Pull Request\n\nTitle: %s\n\n%s\n\n## Diff\n\n%s",
+			req.PR.Title, truncate(req.PR.Description, 1000), chunk)
+		if len(chunks) > 1 {
+			content += fmt.Sprintf("\n\n(Part %d of %d of the security-sensitive changes.)", i+1, len(chunks))
+		}
+		resp, err := a.llm.CompleteWithTools(ctx, securitySystemPrompt,
+			[]llm.Message{{Role: "user", Content: content}}, []anthropic.ToolParam{toolSubmitReview})
+		if err != nil {
+			return git.Review{}, true, fmt.Errorf("llm security review: %w", err)
+		}
+		part := git.Review{Summary: extractText(resp)}
+		for _, block := range resp.Content {
+			if block.Type == "tool_use" && block.Name == toolSubmitReview.Name {
+				if part, err = parseReviewResult(block.Input); err != nil {
+					return git.Review{}, true, err
+				}
+				break
+			}
+		}
+		for _, c := range part.Comments {
+			c.Category = "security"
+			review.Comments = append(review.Comments, c)
+		}
+		if part.Summary != "" {
+			summaries = append(summaries, part.Summary)
+		}
+	}
+	review.Summary = renderSecuritySection(files, summaries, review.Comments)
+	return review, true, nil
+}
+
+// renderSecuritySection is the security pass's section of the review
+// summary.
+func renderSecuritySection(files, summaries []string, comments []git.PRComment) string {
+	var sb strings.Builder
+	sb.WriteString("### Security review\n\n")
+	sb.WriteString(fmt.Sprintf("Checked %d security-sensitive file(s) for injection, authorization, secrets and unsafe deserialization: ", len(files)))
+	quoted := make([]string, len(files))
+	for i, f := range files {
+		quoted[i] = "`" + f + "`"
+	}
+	sb.WriteString(strings.Join(quoted, ", ") + ".\n\n")
+	if len(comments) == 0 {
+		sb.WriteString("No security findings.")
+	} else {
+		sb.WriteString(fmt.Sprintf("%d finding(s), posted inline.", len(comments)))
+	}
+	for _, s := range summaries {
+		sb.WriteString("\n\n" + strings.TrimSpace(s))
+	}
+	return sb.String()
+}
@@ -0,0 +1,139 @@
+package reviewer
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jadenj13/droid/internals/git"
+)
+
+// Severities, most serious first. Only blockers and majors warrant
+// request_changes.
+// maxNotifiedFindings caps the findings a changes-requested notification
+// lists.
+const maxNotifiedFindings = 5
+
+var severities = []string{"blocker", "major", "minor", "nit"}
+
+var categories = []string{"bug", "security", "tests", "style"}
+
+func blocking(severity string) bool {
+	return severity == "blocker" || severity == "major"
+}
+
+// normalizeSeverity maps an unknown severity to major, so a finding the model
+// failed to grade still counts.
+func normalizeSeverity(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if !slices.Contains(severities, s) {
+		return "major"
+	}
+	return s
+}
+
+func normalizeCategory(c string) string {
+	c = strings.ToLower(strings.TrimSpace(c))
+	if !slices.Contains(categories, c) {
+		return ""
+	}
+	return c
+}
+
+// applySeverity makes the verdict follow the findings: any blocker or major —
+// new, or raised earlier and still unresolved — or unmet acceptance criterion
+// requests changes, and a change request whose findings are all minor or
+// nits becomes a comment. A
+// change request without inline findings is left alone; its reasons are in
+// the summary, e.g. an unmet acceptance criterion, and an escalation stands
+// whatever the findings.
+func applySeverity(review *git.Review, earlier []RaisedComment) {
+	if review.Verdict == "escalate" {
+		return
+	}
+	blockers := 0
+	for _, c := range review.Comments {
+		if blocking(c.Severity) {
+			blockers++
+		}
+	}
+	for _, c := range earlier {
+		if blocking(c.Severity) && !slices.Contains(review.Resolved, c.ID) {
+			blockers++
+		}
+	}
+	for _, c := range review.Criteria {
+		if c.Status == criterionUnmet {
+			blockers++
+		}
+	}
+	switch {
+	case blockers > 0:
+		review.Verdict = "request_changes"
+	case review.Verdict == "request_changes" && len(review.Comments) > 0:
+		review.Verdict = "comment"
+	}
+}
+
+// renderFindings groups the review's inline comments by severity for the
+// summary, or returns "" when there are none.
+func renderFindings(comments []git.PRComment) string {
+	if len(comments) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("#### Findings\n")
+	for _, sev := range severities {
+		var group []git.PRComment
+		for _, c := range comments {
+			if c.Severity == sev {
+				group = append(group, c)
+			}
+		}
+		if len(group) == 0 {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("\n**%s%s** (%d)\n\n", strings.ToUpper(sev[:1]), sev[1:], len(group)))
+		for _, c := range group {
+			category := ""
+			if c.Category != "" {
+				category = " " + c.Category + ":"
+			}
+			sb.WriteString(fmt.Sprintf("- `%s:%d`%s %s\n", c.Path, c.Line, category, truncate(firstLine(c.Body), 120)))
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// topFindings lists up to n of the comments, most severe first, one line
+// each, for a notification.
+func topFindings(comments []git.PRComment, n int) []string {
+	var out []string
+	for _, sev := range severities {
+		for _, c := range comments {
+			if c.Severity != sev {
+				continue
+			}
+			if len(out) == n {
+				return out
+			}
+			out = append(out, fmt.Sprintf("[%s] `%s:%d` %s", sev, c.Path, c.Line, truncate(firstLine(c.Body), 120)))
+		}
+	}
+	return out
+}
+
+// renderEscalation is the summary section explaining why an escalated PR
+// needs a human.
+func renderEscalation(reasons []string) string {
+	var sb strings.Builder
+	sb.WriteString("#### Needs a human\n\n")
+	sb.WriteString("This change is too risky to approve automatically; a maintainer should review it.\n")
+	if len(reasons) > 0 {
+		sb.WriteString("\n")
+	}
+	for _, r := range reasons {
+		sb.WriteString("- " + strings.TrimSpace(r) + "\n")
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
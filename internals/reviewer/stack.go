@@ -0,0 +1,107 @@
+package reviewer
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jadenj13/droid/internals/git"
+)
+
+// maxParentDiffChars caps how much of each approved parent's diff the review
+// prompt carries.
+const maxParentDiffChars = 8000
+
+// maxStackParents caps the parents one review looks at.
+const maxStackParents = 5
+
+// StackParent is an open PR the reviewed PR builds on: the PR for an issue
+// its issue depends on, or the PR whose branch it targets.
+type StackParent struct {
+	PR       git.PR
+	Approved bool // the reviewer approved the parent at its current head
+}
+
+type stackChainKey struct{}
+
+// withStackChain records that pr is being reviewed for its stacked children,
+// so a dependency cycle does not review the same PRs forever.
+func withStackChain(ctx context.Context, pr int) context.Context {
+	chain, _ := ctx.Value(stackChainKey{}).([]int)
+	return context.WithValue(ctx, stackChainKey{}, append(slices.Clip(chain), pr))
+}
+
+func inStackChain(ctx context.Context, pr int) bool {
+	chain, _ := ctx.Value(stackChainKey{}).([]int)
+	return slices.Contains(chain, pr)
+}
+
+// stackParents finds the open PRs pr builds on. A parent the reviewer has not
+// reviewed at its current head is reviewed first, so a stack is reviewed in
+// dependency order and each child sees which of its parents were approved.
+// Merged parents are already on the base branch and need no mention.
+func (w *Worker) stackParents(ctx context.Context, provider git.GitProvider, repoURL string, pr git.PR, issue git.Issue) []StackParent {
+	var numbers []int
+	if n, err := provider.FindOpenPR(ctx, pr.BaseBranch); err != nil {
+		w.log.Warn("could not look up a PR for the base branch", "pr", pr.Number, "base", pr.BaseBranch, "err", err)
+	} else if n > 0 && n != pr.Number {
+		numbers = append(numbers, n)
+	}
+	for _, dep := range git.DependsOn(issue.Body) {
+		parentIssue, err := provider.GetIssue(ctx, dep)
+		if err != nil {
+			w.log.Warn("could not fetch dependency issue", "pr", pr.Number, "issue", dep, "err", err)
+			continue
+		}
+		n, err := provider.FindOpenPR(ctx, git.BranchName(parentIssue.Number, parentIssue.Title))
+		if err != nil {
+			w.log.Warn("could not look up the dependency's PR", "pr", pr.Number, "issue", dep, "err", err)
+			continue
+		}
+		if n > 0 && n != pr.Number && !slices.Contains(numbers, n) {
+			numbers = append(numbers, n)
+		}
+	}
+	if len(numbers) > maxStackParents {
+		numbers = numbers[:maxStackParents]
+	}
+
+	var parents []StackParent
+	for _, n := range numbers {
+		parent, err := provider.GetPR(ctx, n)
+		if err != nil {
+			w.log.Warn("could not fetch parent PR", "pr", pr.Number, "parent", n, "err", err)
+			continue
+		}
+		// Only the executor's PRs are reviewed uninvited; a person's PR
+		// this one targets is reviewed when they ask.
+		if parent.IssueURL != "" && w.history.Get(repoURL, n).ReviewedSHA != parent.HeadSHA && !inStackChain(ctx, n) {
+			w.log.Info("reviewing parent PR first", "pr", pr.Number, "parent", n)
+			err := w.reviewLoop(withStackChain(ctx, pr.Number), provider, repoURL, n, "")
+			if err := w.reviewFailed(ctx, repoURL, n, err); err != nil {
+				w.log.Warn("could not review parent PR", "pr", pr.Number, "parent", n, "err", err)
+			}
+		}
+		state := w.history.Get(repoURL, n)
+		approved := state.ReviewedSHA == parent.HeadSHA && len(state.Verdicts) > 0 &&
+			state.Verdicts[len(state.Verdicts)-1].Verdict == "approve"
+		parents = append(parents, StackParent{PR: parent, Approved: approved})
+	}
+	return parents
+}
+
+// renderParents describes the parents for the review prompt: the approved
+// ones with their diffs, the others by name only, as their code may change.
+func renderParents(parents []StackParent) string {
+	var sb strings.Builder
+	for _, p := range parents {
+		if !p.Approved {
+			sb.WriteString(fmt.Sprintf("### PR #%d: %s (not yet approved)\n\nBranch `%s`. Its changes are still under review, so they are not shown; do not flag what this PR uses from it as missing, but say in the summary that this PR depends on unapproved work.\n\n", p.PR.Number, p.PR.Title, p.PR.Branch))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("### PR #%d: %s (approved)\n\nBranch `%s`. Changed files:\n\n%s\n\n```diff\n%s\n```\n\n",
+			p.PR.Number, p.PR.Title, p.PR.Branch, strings.Join(diffFiles(p.PR.Diff), "\n"), truncate(p.PR.Diff, maxParentDiffChars)))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
@@ -0,0 +1,192 @@
+package reviewer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RepoStats summarises the reviewer's verdicts and findings on one
+// repository's PRs over a window.
+type RepoStats struct {
+	RepoURL string `json:"repo_url,omitempty"`
+	PRs     int    `json:"prs"`    // PRs with a review round in the window
+	Rounds  int    `json:"rounds"` // review rounds in the window
+	// Approved counts the PRs whose latest round approved them;
+	// FirstPassApproved those approved in their first round.
+	Approved          int     `json:"approved"`
+	FirstPassApproved int     `json:"first_pass_approved"`
+	Escalated         int     `json:"escalated"`
+	ApprovalRate      float64 `json:"approval_rate"`
+	FirstPassRate     float64 `json:"first_pass_rate"`
+	// AvgRounds is the mean number of rounds per PR, AvgRevisions the mean
+	// number of those that requested changes.
+	AvgRounds    float64        `json:"avg_rounds"`
+	AvgRevisions float64        `json:"avg_revisions"`
+	Verdicts     map[string]int `json:"verdicts"` // rounds by verdict
+	Findings     int            `json:"findings"` // inline comments raised
+	Severities   map[string]int `json:"severities"`
+	Categories   map[string]int `json:"categories"`
+}
+
+// ReviewStats is the stats of every repository the history covers, and
+// their total.
+type ReviewStats struct {
+	Since time.Time   `json:"since"`
+	Until time.Time   `json:"until"`
+	Repos []RepoStats `json:"repos"`
+	Total RepoStats   `json:"total"`
+}
+
+// Stats computes review statistics from the rounds in [since, now], for the
+// repository repoURL or, when it is empty, for all of them.
+func (h *ReviewHistory) Stats(repoURL string, since, now time.Time) ReviewStats {
+	h.mu.Lock()
+	byRepo := make(map[string][]ReviewState)
+	for _, s := range h.states {
+		if repoURL == "" || strings.TrimSuffix(s.RepoURL, "/") == strings.TrimSuffix(repoURL, "/") {
+			byRepo[s.RepoURL] = append(byRepo[s.RepoURL], s)
+		}
+	}
+	h.mu.Unlock()
+
+	out := ReviewStats{Since: since, Until: now, Total: newRepoStats("")}
+	for repo, states := range byRepo {
+		rs := newRepoStats(repo)
+		for _, s := range states {
+			rs.add(s, since, now)
+			out.Total.add(s, since, now)
+		}
+		if rs.PRs > 0 {
+			out.Repos = append(out.Repos, rs.finish())
+		}
+	}
+	sort.Slice(out.Repos, func(i, j int) bool { return out.Repos[i].RepoURL < out.Repos[j].RepoURL })
+	out.Total = out.Total.finish()
+	return out
+}
+
+func newRepoStats(repoURL string) RepoStats {
+	return RepoStats{
+		RepoURL:    repoURL,
+		Verdicts:   make(map[string]int),
+		Severities: make(map[string]int),
+		Categories: make(map[string]int),
+	}
+}
+
+// add counts the rounds of s in the window and the comments they raised.
+func (r *RepoStats) add(s ReviewState, since, now time.Time) {
+	inWindow := make(map[int]bool)
+	latest, revisions := "", 0
+	for _, v := range s.Verdicts {
+		if v.At.Before(since) || v.At.After(now) {
+			continue
+		}
+		inWindow[v.Round] = true
+		r.Rounds++
+		r.Verdicts[v.Verdict]++
+		latest = v.Verdict
+		if v.Verdict == "request_changes" {
+			revisions++
+		}
+	}
+	if len(inWindow) == 0 {
+		return
+	}
+	r.PRs++
+	r.AvgRevisions += float64(revisions)
+	switch latest {
+	case "approve":
+		r.Approved++
+		if len(s.Verdicts) > 0 && s.Verdicts[0].Verdict == "approve" && inWindow[s.Verdicts[0].Round] {
+			r.FirstPassApproved++
+		}
+	case "escalate":
+		r.Escalated++
+	}
+	for _, c := range s.Comments {
+		if !inWindow[c.Round] {
+			continue
+		}
+		r.Findings++
+		if c.Severity != "" {
+			r.Severities[c.Severity]++
+		}
+		if c.Category != "" {
+			r.Categories[c.Category]++
+		}
+	}
+}
+
+// finish turns the counts into rates and averages.
+func (r RepoStats) finish() RepoStats {
+	if r.PRs > 0 {
+		r.ApprovalRate = float64(r.Approved) / float64(r.PRs)
+		r.FirstPassRate = float64(r.FirstPassApproved) / float64(r.PRs)
+		r.AvgRounds = float64(r.Rounds) / float64(r.PRs)
+		r.AvgRevisions /= float64(r.PRs)
+	}
+	return r
+}
+
+// String formats the stats for Slack and plain-text clients.
+func (s ReviewStats) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Review stats, %s to %s\n", s.Since.Format("2006-01-02"), s.Until.Format("2006-01-02")))
+	if s.Total.PRs == 0 {
+		sb.WriteString("\nNo PRs were reviewed in this window.\n")
+		return sb.String()
+	}
+	for _, r := range s.Repos {
+		sb.WriteString("\n" + r.RepoURL + "\n" + r.summary())
+	}
+	if len(s.Repos) > 1 {
+		sb.WriteString("\nAll repositories\n" + s.Total.summary())
+	}
+	return sb.String()
+}
+
+func (r RepoStats) summary() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("- %d PRs, %d rounds: %.0f%% approved (%.0f%% on the first pass), %d escalated\n",
+		r.PRs, r.Rounds, 100*r.ApprovalRate, 100*r.FirstPassRate, r.Escalated))
+	sb.WriteString(fmt.Sprintf("- %.1f rounds and %.1f revision rounds per PR on average\n", r.AvgRounds, r.AvgRevisions))
+	if r.Findings > 0 {
+		sb.WriteString(fmt.Sprintf("- %d findings — by category: %s; by severity: %s\n",
+			r.Findings, countList(r.Categories, categories), countList(r.Severities, severities)))
+	}
+	return sb.String()
+}
+
+// countList renders counts as "bug 4, tests 2", most frequent first, with
+// ties in the order of known.
+func countList(counts map[string]int, known []string) string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	rank := func(k string) int {
+		for i, n := range known {
+			if n == k {
+				return i
+			}
+		}
+		return len(known)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return rank(keys[i]) < rank(keys[j])
+	})
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s %d", k, counts[k])
+	}
+	if len(parts) == 0 {
+		return "none recorded"
+	}
+	return strings.Join(parts, ", ")
+}
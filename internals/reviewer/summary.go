@@ -0,0 +1,71 @@
+package reviewer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jadenj13/droid/internals/git"
+)
+
+// summaryMarker identifies the sticky review summary comment on a PR. It is an
+// HTML comment so it does not render.
+const summaryMarker = "<!-- droid:review-summary -->"
+
+// The round history table sits between these markers, so tables in the
+// model-written summary are never read back as history.
+const (
+	historyStart = "<!-- droid:review-history -->"
+	historyEnd   = "<!-- /droid:review-history -->"
+)
+
+type summaryRound struct {
+	Round    int
+	Verdict  string
+	Comments int
+}
+
+// parseSummaryHistory recovers the per-round history table from an existing
+// sticky summary comment body.
+func parseSummaryHistory(body string) []summaryRound {
+	_, table, ok := strings.Cut(body, historyStart)
+	if !ok {
+		return nil
+	}
+	table, _, _ = strings.Cut(table, historyEnd)
+	var out []summaryRound
+	for _, line := range strings.Split(table, "\n") {
+		cells := strings.Split(strings.Trim(strings.TrimSpace(line), "|"), "|")
+		if len(cells) != 3 {
+			continue
+		}
+		round, err := strconv.Atoi(strings.TrimSpace(cells[0]))
+		if err != nil {
+			continue // header or separator row
+		}
+		comments, _ := strconv.Atoi(strings.TrimSpace(cells[2]))
+		out = append(out, summaryRound{
+			Round:    round,
+			Verdict:  strings.TrimSpace(cells[1]),
+			Comments: comments,
+		})
+	}
+	return out
+}
+
+// renderSummary builds the sticky comment body: the latest verdict and summary
+// followed by a history table covering every round so far.
+func renderSummary(review git.Review, history []summaryRound) string {
+	latest := history[len(history)-1]
+
+	var sb strings.Builder
+	sb.WriteString(summaryMarker + "\n")
+	sb.WriteString(fmt.Sprintf("### Review summary — round %d: **%s**\n\n", latest.Round, latest.Verdict))
+	sb.WriteString(review.Summary)
+	sb.WriteString("\n\n" + historyStart + "\n| Round | Verdict | Inline comments |\n|---|---|---|\n")
+	for _, h := range history {
+		sb.WriteString(fmt.Sprintf("| %d | %s | %d |\n", h.Round, h.Verdict, h.Comments))
+	}
+	sb.WriteString(historyEnd + "\n\n*Maintained by the Reviewer Agent — updated each round*")
+	return sb.String()
+}
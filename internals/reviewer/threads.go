@@ -0,0 +1,240 @@
+package reviewer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/llm"
+	"github.com/jadenj13/droid/internals/repoconfig"
+)
+
+// threadContextLines is how many lines of the file either side of the
+// commented line the reply sees.
+const threadContextLines = 40
+
+const threadSystemPrompt = `You are the code reviewer who wrote the first comment in an inline review
+thread on a pull request. A human has replied to it. Answer them by calling
+respond_to_thread:
+
+- reply: explain the comment concretely — what goes wrong, in which case, and how to fix it —
+  or answer their question. Keep it short and specific to this code.
+- retract: when their reply shows the comment is wrong or does not apply (the case cannot
+  happen, it is already handled elsewhere, the requirement says otherwise). Say briefly why
+  you are withdrawing it. Do not retract just because someone disagrees without a reason.`
+
+var toolRespondToThread = anthropic.ToolParam{
+	Name:        "respond_to_thread",
+	Description: anthropic.String("Reply to the review thread, or retract your comment."),
+	InputSchema: anthropic.ToolInputSchemaParam{
+		Properties: map[string]interface{}{
+			"action": map[string]interface{}{
+				"type": "string",
+				"enum": []string{"reply", "retract"},
+			},
+			"body": map[string]interface{}{
+				"type":        "string",
+				"description": "The reply, or for retract the reason the comment is withdrawn. Markdown.",
+			},
+		},
+		Required: []string{"action", "body"},
+	},
+}
+
+// ThreadRequest is a review thread a human replied to, with the code around it.
+type ThreadRequest struct {
+	PR      git.PR
+	Thread  git.ReviewThread
+	Excerpt string // the commented file around the thread's line, at the PR's head
+	Config  repoconfig.Config
+}
+
+// ThreadResponse is the reviewer's answer to a thread.
+type ThreadResponse struct {
+	Action string `json:"action"` // "reply" or "retract"
+	Body   string `json:"body"`
+}
+
+// RespondToThread decides how to answer a human's reply to one of the
+// reviewer's inline comments.
+func (a *Agent) RespondToThread(ctx context.Context, req ThreadRequest) (ThreadResponse, error) {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("## Pull Request\n\nTitle: %s\n\n%s\n\n", req.PR.Title, truncate(req.PR.Description, 1000)))
+	sb.WriteString(fmt.Sprintf("## Thread on %s:%d\n\n", req.Thread.Path, req.Thread.Line))
+	for i, c := range req.Thread.Comments {
+		who := c.Author
+		if c.Mine {
+			who = "you"
+		}
+		if i == 0 {
+			who += ", the original comment"
+		}
+		sb.WriteString(fmt.Sprintf("**%s**:\n%s\n\n", who, truncate(c.Body, 3000)))
+	}
+	if req.Thread.DiffHunk != "" {
+		sb.WriteString("## Diff Hunk\n\n" + truncate(req.Thread.DiffHunk, 5000) + "\n\n")
+	}
+	if req.Excerpt != "" {
+		sb.WriteString("## " + req.Thread.Path + " at the PR's head\n\n" + req.Excerpt)
+	}
+
+	system := threadSystemPrompt
+	if req.Config.ReviewRubric != "" {
+		system += "\n\nRepository review rubric:\n" + req.Config.ReviewRubric
+	}
+	if req.Config.ReviewGuidelines != "" {
+		system += "\n\nThe team's review guidelines:\n\n" + req.Config.ReviewGuidelines
+	}
+
+	resp, err := a.llm.CompleteWithTools(llm.ContextWithModel(ctx, req.Config.Model), system,
+		[]llm.Message{{Role: "user", Content: sb.String()}}, []anthropic.ToolParam{toolRespondToThread})
+	if err != nil {
+		return ThreadResponse{}, fmt.Errorf("llm thread reply: %w", err)
+	}
+	for _, block := range resp.Content {
+		if block.Type != "tool_use" || block.Name != toolRespondToThread.Name {
+			continue
+		}
+		var out ThreadResponse
+		if err := json.Unmarshal(block.Input, &out); err != nil {
+			return ThreadResponse{}, fmt.Errorf("unmarshal thread reply: %w", err)
+		}
+		out.Body = strings.TrimSpace(out.Body)
+		if out.Action != "retract" {
+			out.Action = "reply"
+		}
+		return out, nil
+	}
+	return ThreadResponse{Action: "reply", Body: extractText(resp)}, nil
+}
+
+// fileExcerpt returns the lines of content within threadContextLines of line,
+// numbered.
+func fileExcerpt(content string, line int) string {
+	lines := strings.Split(content, "\n")
+	start, end := max(line-threadContextLines, 1), min(line+threadContextLines, len(lines))
+	var sb strings.Builder
+	for n := start; n <= end; n++ {
+		sb.WriteString(fmt.Sprintf("%5d  %s\n", n, lines[n-1]))
+	}
+	return sb.String()
+}
+
+// HandleThreadReply answers a reply in an inline review thread, if the thread
+// starts with one of the reviewer's comments and a human spoke last.
+func (w *Worker) HandleThreadReply(ctx context.Context, repoURL string, prNumber int, threadID string) error {
+	provider, _, err := w.factory.ProviderFor(ctx, repoURL)
+	if err != nil {
+		return fmt.Errorf("build provider: %w", err)
+	}
+	thread, err := provider.GetReviewThread(ctx, prNumber, threadID)
+	if err != nil {
+		return fmt.Errorf("get review thread: %w", err)
+	}
+	if len(thread.Comments) < 2 || !thread.Comments[0].Mine || thread.Comments[len(thread.Comments)-1].Mine ||
+		strings.HasPrefix(thread.Comments[0].Body, "**Retracted:**") {
+		return nil
+	}
+
+	pr, err := provider.GetPR(ctx, prNumber)
+	if err != nil {
+		return fmt.Errorf("get PR: %w", err)
+	}
+	req := ThreadRequest{PR: pr, Thread: thread, Config: w.loadRepoConfig(ctx, provider, pr.BaseBranch)}
+	if thread.Path != "" && thread.Line > 0 {
+		if content, err := provider.GetFileAtRef(ctx, thread.Path, pr.HeadSHA); err == nil {
+			req.Excerpt = fileExcerpt(content, thread.Line)
+		}
+	}
+
+	resp, err := w.agent.RespondToThread(ctx, req)
+	if err != nil {
+		return fmt.Errorf("agent thread reply: %w", err)
+	}
+	if resp.Body == "" {
+		return fmt.Errorf("agent thread reply: empty response")
+	}
+	w.log.Info("answering review thread", "pr", prNumber, "thread", thread.ID, "action", resp.Action)
+	if resp.Action == "retract" {
+		if err := provider.RetractThread(ctx, prNumber, thread, resp.Body); err != nil {
+			return fmt.Errorf("retract comment: %w", err)
+		}
+		if err := w.history.Withdraw(repoURL, prNumber, thread.Path, thread.Line, thread.Comments[0].Body); err != nil {
+			w.log.Warn("failed to record retracted comment", "pr", prNumber, "err", err)
+		}
+		return nil
+	}
+	if err := provider.ReplyToThread(ctx, prNumber, thread, resp.Body); err != nil {
+		return fmt.Errorf("reply to thread: %w", err)
+	}
+	return nil
+}
+
+// resolveAddressed resolves the review threads of the earlier comments a
+// re-review found resolved, when changes — the diff of the commits it
+// reviewed — touched the lines they were on. A comment resolved without its
+// lines changing, e.g. by a fix elsewhere, keeps its thread open for a human
+// to close. Failures are logged.
+func (w *Worker) resolveAddressed(ctx context.Context, provider git.GitProvider, prNumber int, earlier []RaisedComment, resolved []int, changes string) {
+	var addressed []RaisedComment
+	for _, c := range earlier {
+		if slices.Contains(resolved, c.ID) && touchesLine(changes, c.Path, c.Line) {
+			addressed = append(addressed, c)
+		}
+	}
+	if len(addressed) == 0 {
+		return
+	}
+	threads, err := provider.ListReviewThreads(ctx, prNumber)
+	if err != nil {
+		w.log.Warn("could not list review threads to resolve", "pr", prNumber, "err", err)
+		return
+	}
+	for _, c := range addressed {
+		for _, t := range threads {
+			if t.Resolved || len(t.Comments) == 0 || !t.Comments[0].Mine || t.Path != c.Path || !strings.Contains(t.Comments[0].Body, c.Body) {
+				continue
+			}
+			if err := provider.ResolveReviewThread(ctx, prNumber, t); err != nil {
+				w.log.Warn("failed to resolve review thread", "pr", prNumber, "thread", t.ID, "err", err)
+			} else {
+				w.log.Info("resolved addressed review thread", "pr", prNumber, "thread", t.ID, "path", c.Path, "line", c.Line)
+			}
+			break
+		}
+	}
+}
+
+// hunkRange reads the old-file start and length from a hunk header.
+var hunkRange = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+`)
+
+// touchesLine reports whether diff changes path at line, counted in the file
+// before the diff, or within the hunk's context around it.
+func touchesLine(diff, path string, line int) bool {
+	current := ""
+	for _, l := range strings.Split(diff, "\n") {
+		if p, ok := strings.CutPrefix(l, "+++ "); ok {
+			current = strings.TrimSpace(p)
+			continue
+		}
+		m := hunkRange.FindStringSubmatch(l)
+		if m == nil || current != path {
+			continue
+		}
+		start, _ := strconv.Atoi(m[1])
+		length := 1
+		if m[2] != "" {
+			length, _ = strconv.Atoi(m[2])
+		}
+		if line >= start && line <= start+max(length-1, 0) {
+			return true
+		}
+	}
+	return false
+}
@@ -4,46 +4,95 @@ import (
 	"context"
 	"crypto/hmac"
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type WebhookServer struct {
 	worker       *Worker
 	githubSecret string
 	gitlabSecret string
+	statsToken   string
 	log          *slog.Logger
 }
 
-func NewWebhookServer(worker *Worker, githubSecret, gitlabSecret string, log *slog.Logger) *WebhookServer {
-	return &WebhookServer{
+type WebhookOption func(*WebhookServer)
+
+// WithStatsToken serves GET /calibration and GET /stats to requests with
+// "Authorization: Bearer <token>". Without it neither is served.
+func WithStatsToken(token string) WebhookOption {
+	return func(s *WebhookServer) { s.statsToken = token }
+}
+
+func NewWebhookServer(worker *Worker, githubSecret, gitlabSecret string, log *slog.Logger, opts ...WebhookOption) *WebhookServer {
+	s := &WebhookServer{
 		worker:       worker,
 		githubSecret: githubSecret,
 		gitlabSecret: gitlabSecret,
 		log:          log,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *WebhookServer) Handler() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/webhook/github", s.handleGitHub)
 	mux.HandleFunc("/webhook/gitlab", s.handleGitLab)
+	if s.statsToken != "" {
+		mux.HandleFunc("GET /calibration", s.requireToken(s.handleCalibration))
+		mux.HandleFunc("GET /stats", s.requireToken(s.handleStats))
+	}
 	return mux
 }
 
+// requireToken lets through only requests bearing the stats token.
+func (s *WebhookServer) requireToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, s.statsToken) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func authorized(r *http.Request, token string) bool {
+	if token == "" {
+		return false
+	}
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
 type githubPRPayload struct {
 	Action string `json:"action"`
 	Label  struct {
 		Name string `json:"name"`
 	} `json:"label"`
+	Before      string `json:"before"` // synchronize: head before the push
+	After       string `json:"after"`
 	PullRequest struct {
 		Number int    `json:"number"`
 		URL    string `json:"html_url"`
+		Merged bool   `json:"merged"`
+		Draft  bool   `json:"draft"`
+		Head   struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+		Labels []struct {
+			Name string `json:"name"`
+		} `json:"labels"`
 	} `json:"pull_request"`
 	Repository struct {
 		HTMLURL string `json:"html_url"`
@@ -58,6 +107,14 @@ func (s *WebhookServer) handleGitHub(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if r.Header.Get("x-github-event") == "pull_request_review_comment" {
+		s.handleGitHubReviewComment(w, body)
+		return
+	}
+	if r.Header.Get("x-github-event") == "issue_comment" {
+		s.handleGitHubComment(w, body)
+		return
+	}
 	if r.Header.Get("x-github-event") != "pull_request" {
 		w.WriteHeader(http.StatusNoContent)
 		return
@@ -69,7 +126,35 @@ func (s *WebhookServer) handleGitHub(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if payload.Action != "labeled" || payload.Label.Name != "agent:review" {
+	if payload.Action == "closed" {
+		pr := payload.PullRequest
+		if err := s.worker.HandlePRClosed(payload.Repository.HTMLURL, pr.Number, pr.Merged, pr.Head.SHA); err != nil {
+			s.log.Error("record PR outcome failed", "pr", pr.Number, "err", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if payload.Action == "synchronize" {
+		pr := payload.PullRequest
+		labeled := false
+		for _, l := range pr.Labels {
+			labeled = labeled || l.Name == s.worker.labels.Review
+		}
+		if !s.worker.triggeredBy(TriggerPush) {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		s.rereview(payload.Repository.HTMLURL, pr.Number, labeled, payload.Before, payload.After)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	opened := payload.Action == "opened" || payload.Action == "reopened" || payload.Action == "ready_for_review"
+	switch {
+	case opened && !payload.PullRequest.Draft && s.worker.triggeredBy(TriggerOpened):
+	case payload.Action == "labeled" && payload.Label.Name == s.worker.labels.Review && s.worker.triggeredBy(TriggerLabel):
+	default:
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
@@ -87,6 +172,114 @@ func (s *WebhookServer) handleGitHub(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusAccepted)
 }
 
+type githubReviewCommentPayload struct {
+	Action  string `json:"action"`
+	Comment struct {
+		ID        int64 `json:"id"`
+		InReplyTo int64 `json:"in_reply_to_id"`
+		User      struct {
+			Type string `json:"type"`
+		} `json:"user"`
+	} `json:"comment"`
+	PullRequest struct {
+		Number int `json:"number"`
+	} `json:"pull_request"`
+	Repository struct {
+		HTMLURL string `json:"html_url"`
+	} `json:"repository"`
+}
+
+// handleGitHubReviewComment answers human replies in inline review threads.
+func (s *WebhookServer) handleGitHubReviewComment(w http.ResponseWriter, body []byte) {
+	var payload githubReviewCommentPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, "bad payload", http.StatusBadRequest)
+		return
+	}
+	c := payload.Comment
+	if payload.Action != "created" || c.InReplyTo == 0 || c.User.Type == "Bot" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	s.answerThread(payload.Repository.HTMLURL, payload.PullRequest.Number, strconv.FormatInt(c.ID, 10))
+	w.WriteHeader(http.StatusAccepted)
+}
+
+type githubCommentPayload struct {
+	Action  string `json:"action"`
+	Comment struct {
+		Body string `json:"body"`
+		User struct {
+			Login string `json:"login"`
+			Type  string `json:"type"`
+		} `json:"user"`
+	} `json:"comment"`
+	Issue struct {
+		Number      int       `json:"number"`
+		PullRequest *struct{} `json:"pull_request"`
+	} `json:"issue"`
+	Repository struct {
+		HTMLURL string `json:"html_url"`
+	} `json:"repository"`
+}
+
+// handleGitHubComment reviews a PR on a "/droid review" comment. PR comments
+// arrive as issue comments.
+func (s *WebhookServer) handleGitHubComment(w http.ResponseWriter, body []byte) {
+	var payload githubCommentPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, "bad payload", http.StatusBadRequest)
+		return
+	}
+	c := payload.Comment
+	if payload.Action != "created" || payload.Issue.PullRequest == nil || c.User.Type == "Bot" || !reviewCommand.MatchString(c.Body) || !s.worker.triggeredBy(TriggerComment) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	s.reviewOnRequest(payload.Repository.HTMLURL, payload.Issue.Number, c.User.Login)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// reviewOnRequest runs a requested review in the background.
+func (s *WebhookServer) reviewOnRequest(repoURL string, prNumber int, author string) {
+	go func() {
+		ctx := context.Background()
+		if err := s.worker.HandleReviewCommand(ctx, repoURL, prNumber, author); err != nil {
+			s.log.Error("requested review failed", "pr", prNumber, "err", err)
+		}
+	}()
+}
+
+// answerThread answers a review thread reply in the background.
+func (s *WebhookServer) answerThread(repoURL string, prNumber int, threadID string) {
+	go func() {
+		ctx := context.Background()
+		if err := s.worker.HandleThreadReply(ctx, repoURL, prNumber, threadID); err != nil {
+			s.log.Error("review thread reply failed", "pr", prNumber, "thread", threadID, "err", err)
+		}
+	}()
+}
+
+type gitlabNotePayload struct {
+	ObjectKind       string `json:"object_kind"`
+	ObjectAttributes struct {
+		NoteableType string `json:"noteable_type"`
+		Type         string `json:"type"`
+		DiscussionID string `json:"discussion_id"`
+		Note         string `json:"note"`
+	} `json:"object_attributes"`
+	User struct {
+		Username string `json:"username"`
+		Bot      bool   `json:"bot"`
+	} `json:"user"`
+	MergeRequest struct {
+		IID int `json:"iid"`
+	} `json:"merge_request"`
+	Project struct {
+		WebURL string `json:"web_url"`
+	} `json:"project"`
+}
+
 type gitlabMRPayload struct {
 	ObjectKind string `json:"object_kind"`
 	Changes    struct {
@@ -99,8 +292,17 @@ type gitlabMRPayload struct {
 			} `json:"previous"`
 		} `json:"labels"`
 	} `json:"changes"`
+	Labels []struct {
+		Title string `json:"title"`
+	} `json:"labels"`
 	ObjectAttributes struct {
-		IID int `json:"iid"`
+		IID        int    `json:"iid"`
+		Action     string `json:"action"`
+		Draft      bool   `json:"draft"`
+		OldRev     string `json:"oldrev"` // set on updates that pushed commits
+		LastCommit struct {
+			ID string `json:"id"`
+		} `json:"last_commit"`
 	} `json:"object_attributes"`
 	Project struct {
 		WebURL string `json:"web_url"`
@@ -119,18 +321,69 @@ func (s *WebhookServer) handleGitLab(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if r.Header.Get("x-gitlab-event") == "Note Hook" {
+		var note gitlabNotePayload
+		if err := json.Unmarshal(body, &note); err != nil {
+			http.Error(w, "bad payload", http.StatusBadRequest)
+			return
+		}
+		attrs := note.ObjectAttributes
+		if attrs.NoteableType == "MergeRequest" && attrs.Type != "DiffNote" && !note.User.Bot && reviewCommand.MatchString(attrs.Note) && s.worker.triggeredBy(TriggerComment) {
+			s.reviewOnRequest(note.Project.WebURL, note.MergeRequest.IID, note.User.Username)
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		if attrs.NoteableType != "MergeRequest" || attrs.Type != "DiffNote" || attrs.DiscussionID == "" || note.User.Bot {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		s.answerThread(note.Project.WebURL, note.MergeRequest.IID, attrs.DiscussionID)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
 	var payload gitlabMRPayload
 	if err := json.Unmarshal(body, &payload); err != nil {
 		http.Error(w, "bad payload", http.StatusBadRequest)
 		return
 	}
 
-	if payload.ObjectKind != "merge_request" || !labelAdded(payload.Changes.Labels.Current, payload.Changes.Labels.Previous, "agent:review") {
+	if attrs := payload.ObjectAttributes; payload.ObjectKind == "merge_request" && (attrs.Action == "merge" || attrs.Action == "close") {
+		if err := s.worker.HandlePRClosed(payload.Project.WebURL, attrs.IID, attrs.Action == "merge", attrs.LastCommit.ID); err != nil {
+			s.log.Error("record MR outcome failed", "mr", attrs.IID, "err", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if attrs := payload.ObjectAttributes; payload.ObjectKind == "merge_request" && attrs.Action == "update" && attrs.OldRev != "" {
+		labeled := false
+		for _, l := range payload.Labels {
+			labeled = labeled || l.Title == s.worker.labels.Review
+		}
+		if !s.worker.triggeredBy(TriggerPush) {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		s.rereview(payload.Project.WebURL, attrs.IID, labeled, attrs.OldRev, attrs.LastCommit.ID)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	attrs := payload.ObjectAttributes
+	opened := attrs.Action == "open" || attrs.Action == "reopen"
+	switch {
+	case payload.ObjectKind != "merge_request":
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case opened && !attrs.Draft && s.worker.triggeredBy(TriggerOpened):
+	case labelAdded(payload.Changes.Labels.Current, payload.Changes.Labels.Previous, s.worker.labels.Review) && s.worker.triggeredBy(TriggerLabel):
+	default:
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
 
-	mrNumber := payload.ObjectAttributes.IID
+	mrNumber := attrs.IID
 	repoURL := payload.Project.WebURL
 
 	go func() {
@@ -143,6 +396,58 @@ func (s *WebhookServer) handleGitLab(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusAccepted)
 }
 
+// rereview reviews the commits pushed to a PR in the background.
+func (s *WebhookServer) rereview(repoURL string, prNumber int, labeled bool, before, after string) {
+	go func() {
+		ctx := context.Background()
+		if err := s.worker.HandlePush(ctx, repoURL, prNumber, labeled, before, after); err != nil {
+			s.log.Error("re-review failed", "pr", prNumber, "err", err)
+		}
+	}()
+}
+
+// handleCalibration serves the calibration report as plain text. Pass ?repo=
+// with a repository URL to restrict it to one repo.
+func (s *WebhookServer) handleCalibration(w http.ResponseWriter, r *http.Request) {
+	report, ok := s.worker.CalibrationReport(r.URL.Query().Get("repo"))
+	if !ok {
+		http.Error(w, "calibration tracking is disabled", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, report.String())
+}
+
+// defaultStatsDays is the /stats window when ?days= is not given.
+const defaultStatsDays = 30
+
+// handleStats serves review statistics as JSON, or as plain text with
+// ?format=text. ?repo= restricts them to one repository and ?days=N sets the
+// window (default 30).
+func (s *WebhookServer) handleStats(w http.ResponseWriter, r *http.Request) {
+	days := defaultStatsDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "days must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		days = n
+	}
+	now := time.Now()
+	stats := s.worker.Stats(r.URL.Query().Get("repo"), now.AddDate(0, 0, -days), now)
+
+	if r.URL.Query().Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(w, stats.String())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(stats)
+}
+
 func (s *WebhookServer) readAndVerify(r *http.Request, secret, sigHeader string) ([]byte, error) {
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -2,17 +2,34 @@ package reviewer
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/jadenj13/droid/internals/git"
+	"github.com/jadenj13/droid/internals/repoconfig"
+	"github.com/jadenj13/droid/internals/standards"
 )
 
+// maxRevisionRounds is how many times a PR goes back to the executor. A
+// change request past it is escalated to a human instead.
 const maxRevisionRounds = 5
 
 type Notifier interface {
 	NotifyPRReady(ctx context.Context, msg PRReadyMessage) error
+	// NotifyChangesRequested asks a human to route a change request: back to
+	// the executor, to themselves, or dismissed.
+	NotifyChangesRequested(ctx context.Context, msg ChangesRequestedMessage) error
+	// NotifyEscalated tells a human the reviewer will not decide a PR, and why.
+	NotifyEscalated(ctx context.Context, msg EscalationMessage) error
+	// NotifyReviewFailed tells a human a review could not be completed, so
+	// the PR does not sit unreviewed unnoticed.
+	NotifyReviewFailed(ctx context.Context, msg ReviewFailedMessage) error
 }
 
 type PRReadyMessage struct {
@@ -23,68 +40,515 @@ type PRReadyMessage struct {
 	RepoURL    string
 }
 
+type ChangesRequestedMessage struct {
+	PRURL      string
+	PRTitle    string
+	PRNumber   int
+	IssueURL   string
+	IssueTitle string
+	RepoURL    string
+	Summary    string
+	Comments   int      // inline comments in the review
+	Findings   []string // the most severe inline comments, one line each
+	Route      bool     // offer buttons to route the changes; otherwise the executor revises
+}
+
+type EscalationMessage struct {
+	PRURL      string
+	PRTitle    string
+	IssueURL   string
+	IssueTitle string
+	RepoURL    string
+	Reasons    []string
+	Summary    string
+}
+
+type ReviewFailedMessage struct {
+	RepoURL  string
+	PRNumber int
+	Error    string
+}
+
 type Worker struct {
-	agent    *Agent
-	factory  ProviderFactory
-	notifier Notifier
-	log      *slog.Logger
+	agent          *Agent
+	factory        ProviderFactory
+	notifier       Notifier
+	log            *slog.Logger
+	stickySummary  bool
+	calibration    *CalibrationStore  // nil disables calibration tracking
+	contracts      *ContractRunner    // nil disables contract tests
+	coverage       *CoverageRunner    // nil disables coverage deltas
+	lint           *LintRunner        // nil disables linting
+	standards      *standards.Library // nil adds no coding standards
+	routeInSlack   bool
+	docsMode       DocsMode
+	repoAccess     *repoAccess // nil reviews the diff alone
+	labels         Labels
+	triggers       []Trigger
+	resolveThreads bool
+	checkCriteria  bool
+	stacked        bool
+	missingIssue   MissingIssuePolicy
+	history        *ReviewHistory
+	security       *repoconfig.Security // nil disables the security pass
+
+	commandPermission git.Permission // needed to ask for a review from a comment
+	commandUsers      []string       // may ask for a review from a comment regardless
+
+	mu       sync.Mutex
+	inFlight map[string]string // PR key → head SHA under review
+}
+
+type repoAccess struct {
+	token   string
+	network *git.Network
+}
+
+type WorkerOption func(*Worker)
+
+// WithStickySummary keeps the review summary in a single PR comment that is
+// edited each round instead of repeating it in every review. Inline comments
+// are still posted per round.
+func WithStickySummary(enabled bool) WorkerOption {
+	return func(w *Worker) { w.stickySummary = enabled }
+}
+
+// WithCalibration records each verdict and the eventual human outcome in s,
+// and feeds a repo's calibration back into its review prompt.
+func WithCalibration(s *CalibrationStore) WorkerOption {
+	return func(w *Worker) { w.calibration = s }
+}
+
+// WithContractTests replays a repo's recorded API fixtures against PRs that
+// change its HTTP handlers, for repos whose .droid.yml configures them.
+func WithContractTests(r *ContractRunner) WorkerOption {
+	return func(w *Worker) { w.contracts = r }
+}
+
+// WithStandards adds the org coding standards relevant to each PR to the
+// review prompt.
+func WithStandards(l *standards.Library) WorkerOption {
+	return func(w *Worker) { w.standards = l }
+}
+
+// WithSlackRouting posts request_changes verdicts to Slack with buttons that
+// send the PR back to the executor, hand it to a human, or dismiss the
+// review, instead of adding the revision label to the issue right away.
+func WithSlackRouting(enabled bool) WorkerOption {
+	return func(w *Worker) { w.routeInSlack = enabled }
+}
+
+// WithDocsSync checks PRs for public API changes that leave the docs
+// untouched, and flags them in the review or opens a follow-up docs issue
+// once the PR is approved, depending on mode.
+func WithDocsSync(mode DocsMode) WorkerOption {
+	return func(w *Worker) { w.docsMode = mode }
+}
+
+// WithCoverage runs a repo's coverage command on each PR and its base branch
+// and gives the reviewer the difference, for repos that configure one.
+func WithCoverage(r *CoverageRunner) WorkerOption {
+	return func(w *Worker) { w.coverage = r }
+}
+
+// WithLint runs a repo's lint command on each PR, posts its findings on the
+// changed lines as inline comments and tells the reviewer about them.
+func WithLint(r *LintRunner) WorkerOption {
+	return func(w *Worker) { w.lint = r }
+}
+
+// WithRepoAccess shallow-clones each PR's branch with token, through network,
+// and lets the agent read, search and list its files before the verdict, to
+// check the code around the changes, their call sites and their tests.
+func WithRepoAccess(token string, network *git.Network) WorkerOption {
+	return func(w *Worker) { w.repoAccess = &repoAccess{token: token, network: network} }
+}
+
+// WithLabels renames the labels the reviewer watches and sets. Names left
+// empty keep their defaults.
+func WithLabels(l Labels) WorkerOption {
+	return func(w *Worker) { w.labels = l.withDefaults() }
+}
+
+// WithTriggers sets the events that start a review; the default is
+// DefaultTriggers. A push review covers the changes since the last review.
+func WithTriggers(triggers ...Trigger) WorkerOption {
+	return func(w *Worker) { w.triggers = triggers }
+}
+
+// WithThreadResolution resolves the threads of earlier comments a re-review
+// finds addressed by commits that changed the commented lines.
+func WithThreadResolution(enabled bool) WorkerOption {
+	return func(w *Worker) { w.resolveThreads = enabled }
+}
+
+// WithCriteriaCheckoff ticks the acceptance-criteria boxes on the issue that
+// an approving review found met.
+func WithCriteriaCheckoff(enabled bool) WorkerOption {
+	return func(w *Worker) { w.checkCriteria = enabled }
+}
+
+// WithStackedReviews reviews a PR's open parents — the PRs of the issues its
+// issue depends on, or the PR whose branch it targets — before the PR itself,
+// and shows the reviewer the approved parents' changes.
+func WithStackedReviews(enabled bool) WorkerOption {
+	return func(w *Worker) { w.stacked = enabled }
+}
+
+// WithMissingIssuePolicy sets what happens to a PR whose linked issue could
+// not be fetched. The default escalates it to a human.
+func WithMissingIssuePolicy(p MissingIssuePolicy) WorkerOption {
+	return func(w *Worker) { w.missingIssue = p }
+}
+
+// WithSecurityPass runs a second, security-focused review of the changes to
+// files a repo's .droid.yml security section covers, or scope when it has
+// none, and merges its findings into the posted review.
+func WithSecurityPass(scope repoconfig.Security) WorkerOption {
+	return func(w *Worker) { w.security = &scope }
+}
+
+// WithHistory keeps each PR's last reviewed commit and raised comments in h,
+// so later rounds review only the new commits and track which earlier
+// comments were resolved. Without it they are kept in memory.
+func WithHistory(h *ReviewHistory) WorkerOption {
+	return func(w *Worker) { w.history = h }
 }
 
 type ProviderFactory interface {
 	ProviderFor(ctx context.Context, repoURL string) (git.GitProvider, git.RepoInfo, error)
 }
 
-func NewWorker(agent *Agent, factory ProviderFactory, notifier Notifier, log *slog.Logger) *Worker {
-	return &Worker{agent: agent, factory: factory, notifier: notifier, log: log}
+func NewWorker(agent *Agent, factory ProviderFactory, notifier Notifier, log *slog.Logger, opts ...WorkerOption) *Worker {
+	w := &Worker{
+		agent:    agent,
+		factory:  factory,
+		notifier: notifier,
+		log:      log,
+		inFlight: make(map[string]string),
+		labels:   DefaultLabels(),
+		triggers: DefaultTriggers(),
+
+		missingIssue:      MissingIssueEscalate,
+		commandPermission: git.PermissionWrite,
+	}
+	for _, o := range opts {
+		o(w)
+	}
+	if w.history == nil {
+		w.history, _ = NewReviewHistory("")
+	}
+	return w
 }
 
 func (w *Worker) HandlePR(ctx context.Context, repoURL string, prNumber int) error {
 	provider, _, err := w.factory.ProviderFor(ctx, repoURL)
 	if err != nil {
-		return fmt.Errorf("build provider: %w", err)
+		return w.reviewFailed(ctx, repoURL, prNumber, fmt.Errorf("build provider: %w", err))
 	}
 
-	return w.reviewLoop(ctx, provider, repoURL, prNumber, 0)
+	return w.reviewFailed(ctx, repoURL, prNumber, w.reviewLoop(ctx, provider, repoURL, prNumber, ""))
 }
 
-func (w *Worker) reviewLoop(ctx context.Context, provider git.GitProvider, repoURL string, prNumber, round int) error {
-	if round >= maxRevisionRounds {
-		return fmt.Errorf("exceeded %d revision rounds for PR #%d", maxRevisionRounds, prNumber)
+// HandlePush re-reviews a PR after commits were pushed to it, moving its head
+// from before to after. The review covers the changes since the last review
+// of the PR, or since before if it has none on record. Only PRs with the
+// review label are re-reviewed; labeled reports whether the PR itself has
+// it, and when it does not, the label on the issue the PR links counts too,
+// since that is where the executor puts it.
+func (w *Worker) HandlePush(ctx context.Context, repoURL string, prNumber int, labeled bool, before, after string) error {
+	if !w.triggeredBy(TriggerPush) {
+		return nil
+	}
+	since := w.history.Get(repoURL, prNumber).ReviewedSHA
+	if since == after {
+		return nil
+	}
+	if since == "" {
+		since = before
 	}
 
+	provider, _, err := w.factory.ProviderFor(ctx, repoURL)
+	if err != nil {
+		return w.reviewFailed(ctx, repoURL, prNumber, fmt.Errorf("build provider: %w", err))
+	}
+	if !labeled {
+		if labeled, err = w.issueLabeled(ctx, provider, prNumber); err != nil || !labeled {
+			return err
+		}
+	}
+	return w.reviewFailed(ctx, repoURL, prNumber, w.reviewLoop(ctx, provider, repoURL, prNumber, since))
+}
+
+// issueLabeled reports whether the issue the PR links has the review label.
+// A PR that links no issue has none.
+func (w *Worker) issueLabeled(ctx context.Context, provider git.GitProvider, prNumber int) (bool, error) {
+	pr, err := provider.GetPR(ctx, prNumber)
+	if err != nil {
+		return false, fmt.Errorf("get PR: %w", err)
+	}
+	issueNumber := parseIssueNumber(pr.IssueURL)
+	if pr.IssueURL == "" || issueNumber == 0 {
+		return false, nil
+	}
+	issue, err := provider.GetIssue(ctx, issueNumber)
+	if err != nil {
+		return false, fmt.Errorf("get issue #%d: %w", issueNumber, err)
+	}
+	return issue.HasLabel(w.labels.Review), nil
+}
+
+// reviewFailed tells Slack the PR's review failed with err, and returns err.
+// A nil err, or one from a cancelled context, sends nothing.
+func (w *Worker) reviewFailed(ctx context.Context, repoURL string, prNumber int, err error) error {
+	if err == nil || ctx.Err() != nil {
+		return err
+	}
+	if nerr := w.notifier.NotifyReviewFailed(ctx, ReviewFailedMessage{
+		RepoURL:  repoURL,
+		PRNumber: prNumber,
+		Error:    err.Error(),
+	}); nerr != nil {
+		w.log.Warn("failed to send Slack notification", "err", nerr)
+	}
+	return err
+}
+
+// triggeredBy reports whether t starts a review.
+func (w *Worker) triggeredBy(t Trigger) bool {
+	return slices.Contains(w.triggers, t)
+}
+
+func prKey(repoURL string, prNumber int) string {
+	return repoURL + "#" + strconv.Itoa(prNumber)
+}
+
+// startReview claims the review of the PR at head. It fails if that head is
+// already being reviewed — the executor's revision push and its review
+// label both ask for the same review.
+func (w *Worker) startReview(key, head string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.inFlight[key] == head {
+		return false
+	}
+	w.inFlight[key] = head
+	return true
+}
+
+func (w *Worker) finishReview(key, head string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.inFlight[key] == head {
+		delete(w.inFlight, key)
+	}
+}
+
+// reviewLoop reviews the PR. A PR reviewed before at another commit gets a
+// review of only the commits pushed since that review — or since since, when
+// set — falling back to a full review when those cannot be diffed. Rounds are
+// counted in the review history, so they survive restarts and every webhook
+// continues the same count.
+func (w *Worker) reviewLoop(ctx context.Context, provider git.GitProvider, repoURL string, prNumber int, since string) error {
 	pr, err := provider.GetPR(ctx, prNumber)
 	if err != nil {
 		return fmt.Errorf("get PR: %w", err)
 	}
 
+	key := prKey(repoURL, prNumber)
+	if !w.startReview(key, pr.HeadSHA) {
+		w.log.Info("review of this commit already running — skipping", "pr", prNumber, "head", pr.HeadSHA)
+		return nil
+	}
+	defer w.finishReview(key, pr.HeadSHA)
+
+	state := w.history.Get(repoURL, prNumber)
+	round := state.Round + 1
+	if state.ReviewedSHA != "" {
+		since = state.ReviewedSHA
+	}
+	var newChanges string
+	if since == pr.HeadSHA {
+		since = "" // asked to review the same commit again
+	}
+	if since != "" {
+		newChanges, err = provider.CompareCommits(ctx, since, pr.HeadSHA)
+		switch {
+		case errors.Is(err, git.ErrNotAncestor):
+			w.log.Info("branch was rewritten since the last review — reviewing it in full", "pr", prNumber)
+			since = ""
+		case err != nil:
+			w.log.Warn("could not diff the new commits — reviewing the PR in full", "pr", prNumber, "err", err)
+			since = ""
+		case strings.TrimSpace(newChanges) == "":
+			w.log.Info("new commits change no reviewed files — skipping re-review", "pr", prNumber)
+			return nil
+		}
+	}
+
 	var originalIssue git.Issue
+	var issueErr string
 	if pr.IssueURL != "" {
 		issueNumber := parseIssueNumber(pr.IssueURL)
 		if issueNumber > 0 {
 			originalIssue, err = provider.GetIssue(ctx, issueNumber)
 			if err != nil {
 				w.log.Warn("could not fetch original issue", "url", pr.IssueURL, "err", err)
+				issueErr = err.Error()
+				// Keep the number, so labels still reach the issue.
+				originalIssue = git.Issue{Number: issueNumber, URL: pr.IssueURL}
 			}
+		} else {
+			w.log.Warn("could not read the issue number from the PR's issue link", "url", pr.IssueURL)
+			issueErr = "no issue number in the link"
+			originalIssue = git.Issue{URL: pr.IssueURL}
+		}
+	}
+
+	cfg := w.loadRepoConfig(ctx, provider, pr.BaseBranch)
+
+	w.log.Info("reviewing PR", "pr", prNumber, "round", round, "since", since)
+
+	req := ReviewRequest{PR: pr, Issue: originalIssue, IssueError: issueErr, Config: cfg}
+	if w.stacked {
+		req.Parents = renderParents(w.stackParents(ctx, provider, repoURL, pr, originalIssue))
+	}
+	req.Earlier = state.Open()
+	if w.calibration != nil {
+		req.Calibration = w.calibration.Report(repoURL).promptGuidance()
+	}
+	req.ContractResults = w.runContractTests(ctx, provider, pr, cfg)
+	req.Coverage = w.runCoverage(ctx, provider, pr, cfg)
+	var apiChanges []string
+	if w.docsMode != DocsOff {
+		apiChanges = undocumentedAPIChanges(pr, cfg.Docs)
+	}
+	if w.docsMode == DocsReview && len(apiChanges) > 0 {
+		req.APIChanges = renderAPIChanges(apiChanges, cfg.Docs)
+	}
+	if w.standards != nil {
+		var name string
+		if info, err := git.ParseRepoURL(repoURL); err == nil {
+			name = info.Owner + "/" + info.Repo
+		}
+		files := diffFiles(pr.Diff)
+		req.Standards = w.standards.PromptSection(ctx, standards.Query{
+			Repo:      name,
+			Languages: standards.Languages(files),
+			Text:      pr.Title + "\n" + originalIssue.Title + "\n" + strings.Join(files, "\n") + "\n" + truncate(pr.Diff, maxDiffChars),
+		})
+	}
+
+	if w.repoAccess != nil {
+		repo, err := w.cloneForReview(ctx, provider, pr)
+		if err != nil {
+			// The diff is still enough to review.
+			w.log.Warn("could not clone PR branch for review", "pr", prNumber, "err", err)
+		} else {
+			defer repo.Cleanup()
+			req.Repo = repo
 		}
 	}
 
-	w.log.Info("reviewing PR", "pr", prNumber, "round", round)
+	if since != "" {
+		// The rest of the PR was reviewed in earlier rounds.
+		req.Since, req.Files = since, diffFiles(pr.Diff)
+		req.PR.Diff = newChanges
+	}
+	lintFound := w.runLint(ctx, provider, pr, cfg, req.PR.Diff)
+	req.Lint = renderLintFindings(lintFound)
 
-	review, err := w.agent.Review(ctx, pr, originalIssue)
+	review, err := w.agent.Review(ctx, req)
 	if err != nil {
 		return fmt.Errorf("agent review: %w", err)
 	}
+	if w.security != nil {
+		scope := cfg.Security
+		if scope.Empty() {
+			scope = *w.security
+		}
+		sec, ran, err := w.agent.SecurityReview(ctx, req, scope)
+		switch {
+		case err != nil:
+			w.log.Warn("security review failed — posting the main review alone", "pr", prNumber, "err", err)
+		case ran:
+			review.Comments = append(review.Comments, sec.Comments...)
+			review.Summary += "\n\n" + sec.Summary
+		}
+	}
+	review.Comments = append(review.Comments, lintComments(lintFound)...)
+	criteria := parseCriteria(originalIssue.Body)
+	review.Criteria = normalizeCriteria(review.Criteria, len(criteria))
+	applySeverity(&review, req.Earlier)
+	if issueErr != "" {
+		applyMissingIssue(&review, pr.IssueURL, issueErr, w.missingIssue)
+	}
+	if review.Verdict == "request_changes" && state.Revisions() >= maxRevisionRounds {
+		review.Verdict = "escalate"
+		review.Reasons = append(review.Reasons, fmt.Sprintf("Changes are still needed after %d revision rounds; the executor will not be asked again.", state.Revisions()))
+	}
+	if review.Verdict == "escalate" {
+		review.Summary += "\n\n" + renderEscalation(review.Reasons)
+	}
+	if table := renderCriteria(criteria, review.Criteria); table != "" {
+		review.Summary += "\n\n" + table
+	}
+	if findings := renderFindings(review.Comments); findings != "" {
+		review.Summary += "\n\n" + findings
+	}
+	if earlier := renderEarlier(req.Earlier, review.Resolved); earlier != "" {
+		review.Summary += "\n\n" + earlier
+	}
+	if req.Coverage != "" {
+		review.Summary += "\n\n#### Coverage\n\n" + req.Coverage
+	}
+
+	summary := review.Summary // before the sticky summary shortens it
+	if w.stickySummary {
+		if err := w.updateStickySummary(ctx, provider, prNumber, round, &review); err != nil {
+			return fmt.Errorf("update summary comment: %w", err)
+		}
+	}
 
 	if err := provider.PostReview(ctx, prNumber, review); err != nil {
 		return fmt.Errorf("post review: %w", err)
 	}
+	if err := w.history.Record(repoURL, prNumber, pr.HeadSHA, review); err != nil {
+		w.log.Warn("failed to record review history", "pr", prNumber, "err", err)
+	}
+	if w.resolveThreads && since != "" {
+		w.resolveAddressed(ctx, provider, prNumber, req.Earlier, review.Resolved, newChanges)
+	}
 
 	w.log.Info("review posted", "pr", prNumber, "verdict", review.Verdict, "comments", len(review.Comments))
 
+	if w.calibration != nil {
+		if err := w.calibration.RecordVerdict(repoURL, prNumber, pr.URL, review.Verdict, pr.HeadSHA); err != nil {
+			w.log.Warn("failed to record verdict for calibration", "pr", prNumber, "err", err)
+		}
+	}
+
+	if originalIssue.Number == 0 && review.Verdict != "escalate" {
+		// A person's PR reviewed on request: there is no issue to label and
+		// no executor to revise it; its author takes it from here.
+		w.log.Info("review posted on a PR without an originating issue", "pr", prNumber)
+		return nil
+	}
+
 	switch review.Verdict {
 	case "approve":
-		if err := provider.AddLabel(ctx, originalIssue.Number, "agent:approved"); err != nil {
-			w.log.Warn("failed to add agent:approved label", "err", err)
+		if err := provider.AddLabel(ctx, originalIssue.Number, w.labels.Approved); err != nil {
+			w.log.Warn("failed to add approved label", "label", w.labels.Approved, "err", err)
+		}
+		if w.checkCriteria {
+			if body, changed := checkCriteria(originalIssue.Body, metCriteria(review.Criteria)); changed {
+				if err := provider.UpdateIssueBody(ctx, originalIssue.Number, body); err != nil {
+					w.log.Warn("failed to tick acceptance criteria", "issue", originalIssue.Number, "err", err)
+				}
+			}
+		}
+		if (w.docsMode == DocsIssue || w.docsMode == DocsPR) && len(apiChanges) > 0 {
+			w.openDocsIssue(ctx, provider, pr, cfg, apiChanges)
 		}
 		if err := w.notifier.NotifyPRReady(ctx, PRReadyMessage{
 			PRURL:      pr.URL,
@@ -97,13 +561,56 @@ func (w *Worker) reviewLoop(ctx context.Context, provider git.GitProvider, repoU
 		}
 
 	case "request_changes":
-		if err := provider.AddLabel(ctx, originalIssue.Number, "agent:revision"); err != nil {
+		msg := ChangesRequestedMessage{
+			PRURL:      pr.URL,
+			PRTitle:    pr.Title,
+			PRNumber:   prNumber,
+			IssueURL:   originalIssue.URL,
+			IssueTitle: originalIssue.Title,
+			RepoURL:    repoURL,
+			Summary:    summary,
+			Comments:   len(review.Comments),
+			Findings:   topFindings(review.Comments, maxNotifiedFindings),
+		}
+		if w.routeInSlack && originalIssue.URL != "" {
+			msg.Route = true
+			err := w.notifier.NotifyChangesRequested(ctx, msg)
+			if err == nil {
+				w.log.Info("requested changes — waiting for a human to route them", "pr", prNumber)
+				return nil
+			}
+			// Fall back to the label so the PR does not stall.
+			w.log.Warn("failed to post review routing to Slack", "err", err)
+		}
+		if err := provider.AddLabel(ctx, originalIssue.Number, w.labels.Revision); err != nil {
 			return fmt.Errorf("add revision label: %w", err)
 		}
+		msg.Route = false
+		if err := w.notifier.NotifyChangesRequested(ctx, msg); err != nil {
+			w.log.Warn("failed to send Slack notification", "err", err)
+		}
 		w.log.Info("requested changes — executor will revise", "pr", prNumber, "round", round)
-		// The executor webhook will fire when it sees "agent:revision" and push
-		// an updated branch, which will re-trigger this reviewer via a new
-		// "agent:review" label — so we don't recurse here directly.
+		// The executor webhook will fire when it sees the revision label and
+		// push an updated branch, which will re-trigger this reviewer via a new
+		// review label — so we don't recurse here directly.
+
+	case "escalate":
+		// No revision label: a human decides what happens to this PR.
+		if err := provider.AddPRLabel(ctx, prNumber, w.labels.NeedsHuman); err != nil {
+			w.log.Warn("failed to add needs-human label", "label", w.labels.NeedsHuman, "err", err)
+		}
+		if err := w.notifier.NotifyEscalated(ctx, EscalationMessage{
+			PRURL:      pr.URL,
+			PRTitle:    pr.Title,
+			IssueURL:   originalIssue.URL,
+			IssueTitle: originalIssue.Title,
+			RepoURL:    repoURL,
+			Reasons:    review.Reasons,
+			Summary:    summary,
+		}); err != nil {
+			w.log.Warn("failed to send Slack notification", "err", err)
+		}
+		w.log.Info("escalated to a human", "pr", prNumber, "reasons", len(review.Reasons))
 
 	case "comment":
 		w.log.Info("review posted as comment — no action required", "pr", prNumber)
@@ -112,6 +619,137 @@ func (w *Worker) reviewLoop(ctx context.Context, provider git.GitProvider, repoU
 	return nil
 }
 
+// HandlePRClosed records how humans resolved a PR for calibration.
+func (w *Worker) HandlePRClosed(repoURL string, prNumber int, merged bool, headSHA string) error {
+	if w.calibration == nil {
+		return nil
+	}
+	return w.calibration.RecordOutcome(repoURL, prNumber, merged, headSHA)
+}
+
+// Stats summarises the review history over [since, now] for repoURL, or for
+// every repository when it is empty.
+func (w *Worker) Stats(repoURL string, since, now time.Time) ReviewStats {
+	return w.history.Stats(repoURL, since, now)
+}
+
+// CalibrationReport reports bot verdicts against human outcomes, for one repo
+// or, with an empty repoURL, for all of them.
+func (w *Worker) CalibrationReport(repoURL string) (CalibrationReport, bool) {
+	if w.calibration == nil {
+		return CalibrationReport{}, false
+	}
+	return w.calibration.Report(repoURL), true
+}
+
+// updateStickySummary writes the review of round into the sticky summary
+// comment and shortens review.Summary to a pointer at it, so the per-round
+// review carries only the verdict and inline comments. The round comes from
+// the review history; the comment's table only supplies earlier rounds' rows.
+func (w *Worker) updateStickySummary(ctx context.Context, provider git.GitProvider, prNumber, round int, review *git.Review) error {
+	existing, err := provider.GetMarkedComment(ctx, prNumber, summaryMarker)
+	if err != nil {
+		return err
+	}
+
+	history := slices.DeleteFunc(parseSummaryHistory(existing), func(h summaryRound) bool { return h.Round >= round })
+	history = append(history, summaryRound{
+		Round:    round,
+		Verdict:  review.Verdict,
+		Comments: len(review.Comments),
+	})
+
+	if err := provider.UpsertMarkedComment(ctx, prNumber, summaryMarker, renderSummary(*review, history)); err != nil {
+		return err
+	}
+
+	review.Summary = fmt.Sprintf("Round %d verdict: **%s**. See the review summary comment for details and history.", round, review.Verdict)
+	return nil
+}
+
+// runContractTests replays the repo's fixtures if the PR touches its
+// handlers, and returns the rendered results or "" if the tests did not run.
+// Failures to run are logged and do not block the review.
+func (w *Worker) runContractTests(ctx context.Context, provider git.GitProvider, pr git.PR, cfg repoconfig.Config) string {
+	if w.contracts == nil || !cfg.Contract.Enabled() || !cfg.Contract.Triggered(diffFiles(pr.Diff)) {
+		return ""
+	}
+	content, err := provider.GetFileAtRef(ctx, cfg.Contract.Fixtures, pr.BaseBranch)
+	if err != nil {
+		w.log.Warn("could not read contract fixtures", "path", cfg.Contract.Fixtures, "err", err)
+		return ""
+	}
+	fixtures, err := parseFixtures(content)
+	if err != nil || len(fixtures) == 0 {
+		w.log.Warn("no usable contract fixtures", "path", cfg.Contract.Fixtures, "err", err)
+		return ""
+	}
+
+	report, err := w.contracts.Run(ctx, provider, pr, cfg.Contract, fixtures)
+	if err != nil {
+		w.log.Warn("contract tests failed to run", "pr", pr.Number, "err", err)
+		return ""
+	}
+	w.log.Info("contract tests ran", "pr", pr.Number, "fixtures", len(report.Results), "mismatches", len(report.Failed()))
+	return renderContractReport(report)
+}
+
+// runCoverage measures the PR's coverage against its base branch if the repo
+// configures a coverage command, and returns the rendered comparison or "" if
+// it did not run. Failures to run are logged and do not block the review.
+func (w *Worker) runCoverage(ctx context.Context, provider git.GitProvider, pr git.PR, cfg repoconfig.Config) string {
+	if w.coverage == nil || !cfg.Coverage.Enabled() {
+		return ""
+	}
+	report, err := w.coverage.Run(ctx, provider, pr, cfg.Coverage, pr.Diff)
+	if err != nil {
+		w.log.Warn("coverage failed to run", "pr", pr.Number, "err", err)
+		return ""
+	}
+	w.log.Info("coverage measured", "pr", pr.Number, "base", report.Base, "head", report.Head, "files", len(report.Files))
+	return renderCoverageReport(report)
+}
+
+// runLint runs the repo's lint command if it has one, and returns the
+// findings on the lines diff adds. Failures to run are logged and do not
+// block the review.
+func (w *Worker) runLint(ctx context.Context, provider git.GitProvider, pr git.PR, cfg repoconfig.Config, diff string) []LintFinding {
+	command := strings.TrimSpace(cfg.Commands["lint"])
+	if w.lint == nil || command == "" {
+		return nil
+	}
+	findings, err := w.lint.Run(ctx, provider, pr, command, diff)
+	if err != nil {
+		w.log.Warn("lint failed to run", "pr", pr.Number, "err", err)
+		return nil
+	}
+	w.log.Info("lint ran", "pr", pr.Number, "findings", len(findings))
+	return findings
+}
+
+// loadRepoConfig reads .droid.yml from the PR's base branch, so a PR cannot
+// relax its own review rubric. A missing or unreadable file yields the zero
+// config.
+func (w *Worker) loadRepoConfig(ctx context.Context, provider git.GitProvider, ref string) repoconfig.Config {
+	var cfg repoconfig.Config
+	if content, err := provider.GetFileAtRef(ctx, repoconfig.FileName, ref); err == nil {
+		if cfg, err = repoconfig.Parse([]byte(content)); err != nil {
+			w.log.Warn("ignoring malformed repo config", "ref", ref, "err", err)
+			cfg = repoconfig.Config{}
+		}
+	}
+	for _, name := range repoconfig.GuidelinesFiles {
+		if content, err := provider.GetFileAtRef(ctx, name, ref); err == nil {
+			cfg.ReviewGuidelines = truncate(strings.TrimSpace(content), maxGuidelinesChars)
+			break
+		}
+	}
+	return cfg
+}
+
+// maxGuidelinesChars caps the review guidelines kept in the system prompt.
+const maxGuidelinesChars = 15000
+
 // parseIssueNumber extracts the issue number from a URL like
 // https://github.com/org/repo/issues/42
 func parseIssueNumber(url string) int {
@@ -0,0 +1,113 @@
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const workdir = "/workspace"
+
+// Config selects the container image for each repo. Commands run with no
+// network access unless Network is set.
+type Config struct {
+	DefaultImage string
+	RepoImages   map[string]string // key: "owner/repo"
+	Network      bool
+}
+
+// RunnerFor returns a Docker runner using the repo's configured image, falling
+// back to DefaultImage.
+func (c Config) RunnerFor(fullName string) *Docker {
+	image := c.DefaultImage
+	if img, ok := c.RepoImages[fullName]; ok {
+		image = img
+	}
+	return &Docker{image: image, network: c.Network}
+}
+
+// ParseRepoImages parses "owner/repo=image" pairs separated by commas.
+func ParseRepoImages(s string) (map[string]string, error) {
+	out := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		repo, image, ok := strings.Cut(pair, "=")
+		if !ok || repo == "" || image == "" {
+			return nil, fmt.Errorf("invalid repo image %q — expected owner/repo=image", pair)
+		}
+		out[strings.TrimSpace(repo)] = strings.TrimSpace(image)
+	}
+	return out, nil
+}
+
+// Docker runs each command in a fresh container with the working tree
+// bind-mounted at /workspace. The container runs as the host user so files it
+// writes stay editable by the executor.
+type Docker struct {
+	image   string
+	network bool
+}
+
+func (d *Docker) Image() string { return d.image }
+
+// Run executes command with sh -c inside the container and returns combined
+// stdout and stderr. A non-zero exit status is reported in the output, not as
+// an error; only failure to start the container is an error. When ctx ends
+// first the container is removed and the output so far is returned.
+func (d *Docker) Run(ctx context.Context, dir, command string) (string, error) {
+	name, err := containerName()
+	if err != nil {
+		return "", err
+	}
+	args := []string{
+		"run", "--rm", "--name", name,
+		"--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()),
+		"-v", dir + ":" + workdir,
+		"-w", workdir,
+	}
+	if !d.network {
+		args = append(args, "--network", "none")
+	}
+	args = append(args, d.image, "sh", "-c", command)
+
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	// Killing the docker client leaves the container running; remove it too.
+	cmd.Cancel = func() error {
+		rmCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = exec.CommandContext(rmCtx, "docker", "rm", "-f", name).Run()
+		return cmd.Process.Kill()
+	}
+	cmd.WaitDelay = 5 * time.Second
+	var buf bytes.Buffer
+	cmd.Stdout = &buf
+	cmd.Stderr = &buf
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return buf.String() + fmt.Sprintf("\n(stopped: %s)", ctx.Err()), nil
+		}
+		if _, ok := err.(*exec.ExitError); ok {
+			return buf.String() + fmt.Sprintf("\n(exit: %s)", err), nil
+		}
+		return "", fmt.Errorf("docker run: %w", err)
+	}
+	return buf.String(), nil
+}
+
+func containerName() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("container name: %w", err)
+	}
+	return "droid-run-" + hex.EncodeToString(b), nil
+}
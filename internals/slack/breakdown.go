@@ -0,0 +1,316 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// Reply is the planner's answer to a message.
+type Reply struct {
+	Text string
+	// Breakdown, when set, is an issue breakdown awaiting approval. It is
+	// posted after Text with buttons to approve, edit or remove each issue.
+	Breakdown *Breakdown
+}
+
+// Breakdown is a proposed list of issues for a planning thread. Issues are
+// created only once someone approves them and presses Create.
+type Breakdown struct {
+	Round int // counts the breakdowns proposed in the thread; a new one retires the old buttons
+	Items []BreakdownItem
+	Done  bool // the approved issues were created; the buttons are gone
+}
+
+// BreakdownItem is one proposed issue.
+type BreakdownItem struct {
+	Key                int // 1-based position in the breakdown, stable across edits
+	Title              string
+	Description        string
+	AcceptanceCriteria []string
+	DependsOn          []int // keys of the items this one builds on
+	Status             ItemStatus
+	IssueURL           string // set once created
+}
+
+// ItemStatus is where a proposed issue stands.
+type ItemStatus string
+
+const (
+	ItemPending  ItemStatus = "pending"
+	ItemApproved ItemStatus = "approved"
+	ItemRemoved  ItemStatus = "removed"
+	ItemCreated  ItemStatus = "created"
+	ItemFailed   ItemStatus = "failed" // creating the issue failed
+)
+
+// BreakdownChoice is a button on a breakdown.
+type BreakdownChoice string
+
+const (
+	BreakdownApprove BreakdownChoice = "breakdown_approve"
+	BreakdownEdit    BreakdownChoice = "breakdown_edit"
+	BreakdownRemove  BreakdownChoice = "breakdown_remove"
+	BreakdownCreate  BreakdownChoice = "breakdown_create" // create every approved issue
+)
+
+// ItemEdit is the new content of a proposed issue, from the edit dialog.
+type ItemEdit struct {
+	Title              string
+	Description        string
+	AcceptanceCriteria []string
+}
+
+// editCallbackID identifies the edit dialog's submissions.
+const editCallbackID = "breakdown_edit_submit"
+
+// Block and action IDs of the edit dialog's inputs.
+const (
+	editTitleBlock    = "title"
+	editDescBlock     = "description"
+	editCriteriaBlock = "criteria"
+	editInputAction   = "value"
+)
+
+// BreakdownRef identifies the breakdown item behind a button. It is the
+// buttons' value, encoded by String.
+type BreakdownRef struct {
+	ThreadTS string
+	Round    int
+	Key      int // 0 for Create
+}
+
+func (r BreakdownRef) String() string {
+	return fmt.Sprintf("%s:%d:%d", r.ThreadTS, r.Round, r.Key)
+}
+
+// ParseBreakdownRef decodes a breakdown button value.
+func ParseBreakdownRef(s string) (BreakdownRef, bool) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 || parts[0] == "" {
+		return BreakdownRef{}, false
+	}
+	round, err1 := strconv.Atoi(parts[1])
+	key, err2 := strconv.Atoi(parts[2])
+	if err1 != nil || err2 != nil {
+		return BreakdownRef{}, false
+	}
+	return BreakdownRef{ThreadTS: parts[0], Round: round, Key: key}, true
+}
+
+// breakdownText is the plain-text fallback of a breakdown message, for
+// notifications and clients without blocks.
+func breakdownText(b Breakdown) string {
+	var sb strings.Builder
+	sb.WriteString("Proposed issues:")
+	for _, it := range b.Items {
+		sb.WriteString(fmt.Sprintf("\n%d. %s (%s)", it.Key, it.Title, it.Status))
+	}
+	return sb.String()
+}
+
+// breakdownBlocks renders a breakdown: a section per issue with its status
+// and, until the issues are created, its buttons, then a Create button.
+func breakdownBlocks(threadTS string, b Breakdown) []slack.Block {
+	header := ":clipboard: *Proposed issues.* Approve, edit or remove each one, then press *Create approved issues*. Nothing is created until then."
+	if b.Done {
+		header = ":clipboard: *Issue breakdown* — the approved issues were created."
+	}
+	blocks := []slack.Block{slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, header, false, false), nil, nil)}
+
+	approved := 0
+	for _, it := range b.Items {
+		blocks = append(blocks, slack.NewDividerBlock(),
+			slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, itemText(it), false, false), nil, nil))
+		if it.Status == ItemApproved {
+			approved++
+		}
+		if b.Done || it.Status == ItemCreated {
+			continue
+		}
+		ref := BreakdownRef{ThreadTS: threadTS, Round: b.Round, Key: it.Key}.String()
+		var buttons []slack.BlockElement
+		if it.Status != ItemApproved {
+			buttons = append(buttons, slack.NewButtonBlockElement(string(BreakdownApprove), ref,
+				slack.NewTextBlockObject(slack.PlainTextType, "Approve", false, false)).WithStyle(slack.StylePrimary))
+		}
+		buttons = append(buttons, slack.NewButtonBlockElement(string(BreakdownEdit), ref,
+			slack.NewTextBlockObject(slack.PlainTextType, "Edit", false, false)))
+		if it.Status != ItemRemoved {
+			buttons = append(buttons, slack.NewButtonBlockElement(string(BreakdownRemove), ref,
+				slack.NewTextBlockObject(slack.PlainTextType, "Remove", false, false)).WithStyle(slack.StyleDanger))
+		}
+		blocks = append(blocks, slack.NewActionBlock("", buttons...))
+	}
+
+	if !b.Done {
+		create := slack.NewButtonBlockElement(string(BreakdownCreate), BreakdownRef{ThreadTS: threadTS, Round: b.Round}.String(),
+			slack.NewTextBlockObject(slack.PlainTextType, fmt.Sprintf("Create approved issues (%d)", approved), false, false))
+		if approved > 0 {
+			create = create.WithStyle(slack.StylePrimary)
+		}
+		blocks = append(blocks, slack.NewDividerBlock(), slack.NewActionBlock("", create))
+	}
+	return blocks
+}
+
+var itemStatusIcons = map[ItemStatus]string{
+	ItemPending:  ":white_circle:",
+	ItemApproved: ":large_green_circle:",
+	ItemRemoved:  ":no_entry_sign:",
+	ItemCreated:  ":white_check_mark:",
+	ItemFailed:   ":warning:",
+}
+
+func itemText(it BreakdownItem) string {
+	title := fmt.Sprintf("*%d. %s*", it.Key, it.Title)
+	switch {
+	case it.Status == ItemRemoved:
+		title = fmt.Sprintf("~%d. %s~", it.Key, it.Title)
+	case it.IssueURL != "":
+		title = fmt.Sprintf("*%d. <%s|%s>*", it.Key, it.IssueURL, it.Title)
+	}
+	text := itemStatusIcons[it.Status] + " " + title + "\n" + preview(it.Description, 300)
+	if len(it.AcceptanceCriteria) > 0 {
+		text += fmt.Sprintf("\n_%d acceptance criteria_", len(it.AcceptanceCriteria))
+	}
+	if len(it.DependsOn) > 0 {
+		deps := make([]string, len(it.DependsOn))
+		for i, k := range it.DependsOn {
+			deps[i] = strconv.Itoa(k)
+		}
+		text += "\n_Depends on " + strings.Join(deps, ", ") + "_"
+	}
+	return text
+}
+
+// preview shortens s to at most n runes.
+func preview(s string, n int) string {
+	s = strings.TrimSpace(s)
+	if r := []rune(s); len(r) > n {
+		return string(r[:n]) + "…"
+	}
+	return s
+}
+
+// postBreakdown posts b in the thread with its buttons.
+func (h *Handler) postBreakdown(ctx context.Context, channelID, threadTS string, b Breakdown) {
+	_, _, err := h.client.PostMessageContext(ctx, channelID,
+		slack.MsgOptionText(breakdownText(b), false),
+		slack.MsgOptionBlocks(breakdownBlocks(threadTS, b)...),
+		slack.MsgOptionTS(threadTS),
+	)
+	if err != nil {
+		h.log.Error("failed to post issue breakdown", "err", err)
+	}
+}
+
+// updateBreakdown redraws the breakdown message at ts.
+func (h *Handler) updateBreakdown(ctx context.Context, channelID, ts, threadTS string, b Breakdown) {
+	_, _, _, err := h.client.UpdateMessageContext(ctx, channelID, ts,
+		slack.MsgOptionText(breakdownText(b), false),
+		slack.MsgOptionBlocks(breakdownBlocks(threadTS, b)...),
+	)
+	if err != nil {
+		h.log.Warn("failed to update issue breakdown", "err", err)
+	}
+}
+
+// reviewBreakdown carries out a breakdown button. Edit opens a dialog, whose
+// submission is handled by editBreakdown.
+func (h *Handler) reviewBreakdown(ctx context.Context, callback slack.InteractionCallback, choice BreakdownChoice, value string) {
+	channelID, ts, userID := callback.Channel.ID, callback.Container.MessageTs, callback.User.ID
+	ref, ok := ParseBreakdownRef(value)
+	if !ok {
+		h.log.Warn("malformed breakdown action", "value", value)
+		return
+	}
+	if choice == BreakdownEdit {
+		h.openEditDialog(ctx, callback.TriggerID, channelID, ts, ref)
+		return
+	}
+
+	b, reply, err := h.planner.ReviewBreakdown(ctx, ref, choice, userID)
+	if err != nil {
+		h.log.Error("breakdown action failed", "choice", choice, "thread", ref.ThreadTS, "err", err)
+		h.postNotice(channelID, ref.ThreadTS, userID, fmt.Sprintf("Sorry, that didn't work: %s", err))
+		return
+	}
+	h.updateBreakdown(ctx, channelID, ts, ref.ThreadTS, b)
+	if reply != "" {
+		h.postReply(channelID, ref.ThreadTS, reply)
+	}
+}
+
+// openEditDialog opens a dialog prefilled with the item's title, description
+// and acceptance criteria. The breakdown message's location rides in the
+// dialog's metadata so the submission can redraw it.
+func (h *Handler) openEditDialog(ctx context.Context, triggerID, channelID, ts string, ref BreakdownRef) {
+	item, err := h.planner.BreakdownItem(ctx, ref)
+	if err != nil {
+		h.log.Error("could not load breakdown item", "thread", ref.ThreadTS, "key", ref.Key, "err", err)
+		h.postNotice(channelID, ref.ThreadTS, "", fmt.Sprintf("Sorry, I couldn't open that issue for editing: %s", err))
+		return
+	}
+	input := func(blockID, label, initial string, multiline bool) *slack.InputBlock {
+		el := slack.NewPlainTextInputBlockElement(nil, editInputAction).WithInitialValue(initial).WithMultiline(multiline)
+		return slack.NewInputBlock(blockID, slack.NewTextBlockObject(slack.PlainTextType, label, false, false), nil, el)
+	}
+	criteria := input(editCriteriaBlock, "Acceptance criteria (one per line)", strings.Join(item.AcceptanceCriteria, "\n"), true)
+	criteria.Optional = true
+	view := slack.ModalViewRequest{
+		Type:            slack.VTModal,
+		CallbackID:      editCallbackID,
+		Title:           slack.NewTextBlockObject(slack.PlainTextType, fmt.Sprintf("Edit issue %d", ref.Key), false, false),
+		Submit:          slack.NewTextBlockObject(slack.PlainTextType, "Save", false, false),
+		Close:           slack.NewTextBlockObject(slack.PlainTextType, "Cancel", false, false),
+		PrivateMetadata: strings.Join([]string{channelID, ts, ref.String()}, " "),
+		Blocks: slack.Blocks{BlockSet: []slack.Block{
+			input(editTitleBlock, "Title", item.Title, false),
+			input(editDescBlock, "Description", item.Description, true),
+			criteria,
+		}},
+	}
+	if _, err := h.client.OpenViewContext(ctx, triggerID, view); err != nil {
+		h.log.Error("failed to open breakdown edit dialog", "err", err)
+	}
+}
+
+// editBreakdown saves a submitted edit dialog and redraws the breakdown.
+func (h *Handler) editBreakdown(ctx context.Context, callback slack.InteractionCallback) {
+	meta := strings.Fields(callback.View.PrivateMetadata)
+	var ref BreakdownRef
+	ok := len(meta) == 3
+	if ok {
+		ref, ok = ParseBreakdownRef(meta[2])
+	}
+	if !ok {
+		h.log.Warn("malformed breakdown edit metadata", "metadata", callback.View.PrivateMetadata)
+		return
+	}
+	channelID, ts := meta[0], meta[1]
+
+	value := func(blockID string) string {
+		if callback.View.State == nil {
+			return ""
+		}
+		return strings.TrimSpace(callback.View.State.Values[blockID][editInputAction].Value)
+	}
+	edit := ItemEdit{Title: value(editTitleBlock), Description: value(editDescBlock)}
+	for _, l := range strings.Split(value(editCriteriaBlock), "\n") {
+		if l = strings.TrimSpace(strings.TrimLeft(l, "-*")); l != "" {
+			edit.AcceptanceCriteria = append(edit.AcceptanceCriteria, l)
+		}
+	}
+
+	b, err := h.planner.EditBreakdown(ctx, ref, edit, callback.User.ID)
+	if err != nil {
+		h.log.Error("breakdown edit failed", "thread", ref.ThreadTS, "key", ref.Key, "err", err)
+		h.postNotice(channelID, ref.ThreadTS, callback.User.ID, fmt.Sprintf("Sorry, I couldn't save that edit: %s", err))
+		return
+	}
+	h.updateBreakdown(ctx, channelID, ts, ref.ThreadTS, b)
+}
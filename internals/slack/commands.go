@@ -0,0 +1,135 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// commandPrefix introduces a control command rather than a message for the
+// planner, e.g. "/droid fork".
+const commandPrefix = "/droid"
+
+// parseCommand splits "/droid <name> [args]" into name and args.
+func parseCommand(text string) (name, args string, ok bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(text), commandPrefix)
+	if !ok || (rest != "" && rest[0] != ' ') {
+		return "", "", false
+	}
+	name, args, _ = strings.Cut(strings.TrimSpace(rest), " ")
+	return strings.ToLower(name), strings.TrimSpace(args), true
+}
+
+// handleCommand runs a control command. It reports false if text is not a
+// command, in which case the message goes to the planner as usual.
+func (h *Handler) handleCommand(ctx context.Context, msg IncomingMessage) bool {
+	name, args, ok := parseCommand(msg.Text)
+	if !ok {
+		return false
+	}
+
+	switch name {
+	case "fork":
+		h.fork(ctx, msg)
+	case "merge":
+		h.merge(ctx, msg)
+	case "retry":
+		if args == "" {
+			h.postNotice(msg.ChannelID, msg.ThreadTS, msg.UserID, fmt.Sprintf("Usage: `%s retry <issue number or URL>`", commandPrefix))
+			break
+		}
+		h.retry(ctx, msg.ChannelID, msg.ThreadTS, msg.UserID, args)
+	case "stats":
+		h.stats(ctx, msg, args)
+	case "sessions":
+		h.sessions(ctx, msg, args)
+	default:
+		h.postNotice(msg.ChannelID, msg.ThreadTS, msg.UserID,
+			fmt.Sprintf("Unknown command `%s %s`. Available: `fork`, `merge`, `retry`, `stats`, `sessions`.", commandPrefix, name))
+	}
+	return true
+}
+
+// fork starts a new root message in the channel and clones the current
+// planning session into its thread.
+func (h *Handler) fork(ctx context.Context, msg IncomingMessage) {
+	origin := h.permalink(msg.ChannelID, msg.ThreadTS, "the original thread")
+	_, forkTS, err := h.client.PostMessageContext(ctx, msg.ChannelID,
+		slack.MsgOptionText(fmt.Sprintf(":twisted_rightwards_arrows: Forked planning session from %s", origin), false),
+	)
+	if err != nil {
+		h.log.Error("post fork root failed", "err", err)
+		h.postNotice(msg.ChannelID, msg.ThreadTS, msg.UserID, "Sorry, I couldn't start a new thread for the fork.")
+		return
+	}
+
+	reply, err := h.planner.Fork(ctx, msg.ThreadTS, forkTS, msg.ChannelID)
+	if err != nil {
+		h.log.Error("fork failed", "thread", msg.ThreadTS, "err", err)
+		h.postNotice(msg.ChannelID, msg.ThreadTS, msg.UserID, "Sorry, there's no planning session in this thread to fork yet.")
+		return
+	}
+
+	h.postReply(msg.ChannelID, forkTS, reply)
+	h.postReply(msg.ChannelID, msg.ThreadTS, fmt.Sprintf("Forked this plan into %s.", h.permalink(msg.ChannelID, forkTS, "a new thread")))
+}
+
+// merge folds a fork's conclusions back into the thread it was forked from.
+func (h *Handler) merge(ctx context.Context, msg IncomingMessage) {
+	parentTS, reply, err := h.planner.Merge(ctx, msg.ThreadTS)
+	if err != nil {
+		h.log.Error("merge failed", "thread", msg.ThreadTS, "err", err)
+		h.postNotice(msg.ChannelID, msg.ThreadTS, msg.UserID, "Sorry, I couldn't merge this thread. Only forked threads can be merged.")
+		return
+	}
+
+	h.postReply(msg.ChannelID, parentTS, reply)
+	h.postReply(msg.ChannelID, msg.ThreadTS, fmt.Sprintf("Merged into %s.", h.permalink(msg.ChannelID, parentTS, "the original thread")))
+}
+
+// retry re-queues a failed issue, from the Retry button or `/droid retry`.
+func (h *Handler) retry(ctx context.Context, channelID, threadTS, userID, ref string) {
+	reply, err := h.planner.Retry(ctx, threadTS, strings.TrimPrefix(ref, "#"))
+	if err != nil {
+		h.log.Error("retry failed", "ref", ref, "err", err)
+		h.postNotice(channelID, threadTS, userID, fmt.Sprintf("Sorry, I couldn't retry %s: %s", ref, err))
+		return
+	}
+	h.postReply(channelID, threadTS, reply)
+}
+
+// stats posts the reviewer's statistics, for `/droid stats [repo] [days]`.
+func (h *Handler) stats(ctx context.Context, msg IncomingMessage, args string) {
+	reply, err := h.planner.ReviewStats(ctx, msg.ThreadTS, args)
+	if err != nil {
+		h.log.Error("review stats failed", "err", err)
+		h.postNotice(msg.ChannelID, msg.ThreadTS, msg.UserID, fmt.Sprintf("Sorry, I couldn't get the review stats: %s", err))
+		return
+	}
+	h.postReply(msg.ChannelID, msg.ThreadTS, reply)
+}
+
+// sessions lists or archives planning sessions, for `/droid sessions
+// [archive [thread] | expire <duration>]`.
+func (h *Handler) sessions(ctx context.Context, msg IncomingMessage, args string) {
+	reply, err := h.planner.Sessions(ctx, msg.ThreadTS, args)
+	if err != nil {
+		h.log.Error("sessions command failed", "args", args, "err", err)
+		h.postNotice(msg.ChannelID, msg.ThreadTS, msg.UserID, fmt.Sprintf("Sorry, I couldn't do that: %s", err))
+		return
+	}
+	h.postReply(msg.ChannelID, msg.ThreadTS, reply)
+}
+
+// permalink links label to a message, falling back to the bare label if Slack
+// can't resolve one.
+func (h *Handler) permalink(channelID, ts, label string) string {
+	link, err := h.client.GetPermalink(&slack.PermalinkParameters{Channel: channelID, Ts: ts})
+	if err != nil {
+		h.log.Warn("get permalink failed", "ts", ts, "err", err)
+		return label
+	}
+	return "<" + link + "|" + label + ">"
+}
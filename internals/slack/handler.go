@@ -2,8 +2,11 @@ package slack
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
@@ -16,10 +19,161 @@ type Handler struct {
 	botID   string
 	planner Planner
 	log     *slog.Logger
+
+	userLimit       *rateLimiter
+	channelLimit    *rateLimiter
+	allowedChannels map[string]bool // empty allows every channel
+
+	replyMode        ReplyMode
+	ephemeralNotices bool
+	suppressPresence bool
+}
+
+// ReplyMode controls where the planner posts its replies.
+type ReplyMode int
+
+const (
+	// ReplyInThread always replies in a thread, starting one under a root
+	// channel message if needed. This is the default.
+	ReplyInThread ReplyMode = iota
+	// ReplyMatchUser replies in a thread only when the user wrote in one, and
+	// in the channel otherwise.
+	ReplyMatchUser
+)
+
+// ParseReplyMode maps "thread" or "match" to a ReplyMode. An empty string
+// selects ReplyInThread.
+func ParseReplyMode(s string) (ReplyMode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "thread":
+		return ReplyInThread, nil
+	case "match":
+		return ReplyMatchUser, nil
+	default:
+		return 0, fmt.Errorf("unknown reply mode %q — expected thread or match", s)
+	}
+}
+
+// presenceSubtypes are message subtypes Slack emits for membership changes.
+// They never carry a request for the planner.
+var presenceSubtypes = map[string]bool{
+	"channel_join":  true,
+	"channel_leave": true,
+	"group_join":    true,
+	"group_leave":   true,
+}
+
+type HandlerOption func(*Handler)
+
+// WithReplyMode sets where replies are posted. Defaults to ReplyInThread.
+func WithReplyMode(m ReplyMode) HandlerOption {
+	return func(h *Handler) { h.replyMode = m }
+}
+
+// WithEphemeralNotices posts errors and refusals (rate limits, failed
+// actions) as ephemeral messages visible only to the requesting user.
+func WithEphemeralNotices(enabled bool) HandlerOption {
+	return func(h *Handler) { h.ephemeralNotices = enabled }
+}
+
+// WithSuppressPresence ignores join/leave messages and anything the bot
+// itself posted. Enabled by default.
+func WithSuppressPresence(enabled bool) HandlerOption {
+	return func(h *Handler) { h.suppressPresence = enabled }
+}
+
+// WithUserRateLimit caps how many messages a single user can send to the
+// planner per window.
+func WithUserRateLimit(limit int, window time.Duration) HandlerOption {
+	return func(h *Handler) { h.userLimit = newRateLimiter(limit, window) }
+}
+
+// WithChannelRateLimit caps how many messages the planner handles per channel
+// per window, across all users.
+func WithChannelRateLimit(limit int, window time.Duration) HandlerOption {
+	return func(h *Handler) { h.channelLimit = newRateLimiter(limit, window) }
+}
+
+// WithAllowedChannels restricts the planner to the given channel IDs. Direct
+// messages are always allowed.
+func WithAllowedChannels(ids ...string) HandlerOption {
+	return func(h *Handler) {
+		for _, id := range ids {
+			h.allowedChannels[id] = true
+		}
+	}
 }
 
 type Planner interface {
-	Handle(ctx context.Context, msg IncomingMessage) (string, error)
+	Handle(ctx context.Context, msg IncomingMessage) (Reply, error)
+	Requeue(ctx context.Context, threadTS string, issueNumber int) (string, error)
+	Fork(ctx context.Context, parentTS, forkTS, channelID string) (string, error)
+	Merge(ctx context.Context, forkTS string) (parentTS, reply string, err error)
+	// Retry re-queues a failed issue for the executor. ref is an issue URL, or
+	// an issue number in the repository of the planning session in threadTS.
+	Retry(ctx context.Context, threadTS, ref string) (string, error)
+	// RouteReview carries out a human's choice for a change request the
+	// reviewer posted with review buttons. userID is the Slack user who chose.
+	RouteReview(ctx context.Context, route ReviewRoute, ref ReviewRef, userID string) (string, error)
+	// ReviewStats reports the reviewer's verdict and finding statistics. args
+	// may name a repository URL and a number of days.
+	ReviewStats(ctx context.Context, threadTS, args string) (string, error)
+	// Sessions lists the active planning sessions or archives some; args is
+	// empty, "archive [thread]" or "expire <duration>".
+	Sessions(ctx context.Context, threadTS, args string) (string, error)
+	// ReviewBreakdown applies a breakdown button to the item ref names —
+	// approve or remove it — or, for BreakdownCreate, creates the approved
+	// issues. It returns the updated breakdown and a reply to post, if any.
+	ReviewBreakdown(ctx context.Context, ref BreakdownRef, choice BreakdownChoice, userID string) (Breakdown, string, error)
+	// BreakdownItem returns a proposed issue, to prefill the edit dialog.
+	BreakdownItem(ctx context.Context, ref BreakdownRef) (BreakdownItem, error)
+	// EditBreakdown replaces a proposed issue's content with edit.
+	EditBreakdown(ctx context.Context, ref BreakdownRef, edit ItemEdit, userID string) (Breakdown, error)
+}
+
+// Reminder is a nudge about a stalled issue, posted in the planning thread with
+// a button that requeues the issue.
+type Reminder struct {
+	ChannelID   string
+	ThreadTS    string
+	IssueNumber int
+	Text        string
+}
+
+const actionRequeue = "requeue_issue"
+
+// ActionRetryIssue is the action ID of the Retry button the executor attaches
+// to failure notifications. The button's value is the issue URL.
+const ActionRetryIssue = "retry_issue"
+
+// ReviewRoute is a human's choice for a request_changes review.
+type ReviewRoute string
+
+const (
+	RouteRevise  ReviewRoute = "review_revise"  // send the PR back to the executor
+	RouteSelf    ReviewRoute = "review_self"    // a human fixes it; bot labels come off
+	RouteDismiss ReviewRoute = "review_dismiss" // drop the change request
+)
+
+// ReviewRef identifies the PR and issue behind review buttons. It is the
+// buttons' value, encoded by String.
+type ReviewRef struct {
+	IssueURL string
+	PRNumber int
+}
+
+func (r ReviewRef) String() string {
+	return fmt.Sprintf("%d %s", r.PRNumber, r.IssueURL)
+}
+
+// ParseReviewRef decodes a review button value.
+func ParseReviewRef(s string) (ReviewRef, bool) {
+	num, url, ok := strings.Cut(s, " ")
+	n, err := strconv.Atoi(num)
+	if !ok || err != nil || url == "" {
+		return ReviewRef{}, false
+	}
+	return ReviewRef{IssueURL: url, PRNumber: n}, true
 }
 
 type IncomingMessage struct {
@@ -28,9 +182,10 @@ type IncomingMessage struct {
 	UserID    string
 	Text      string
 	IsDM      bool
+	InThread  bool // true if the user wrote inside an existing thread
 }
 
-func NewHandler(botToken, appToken string, planner Planner, log *slog.Logger) (*Handler, error) {
+func NewHandler(botToken, appToken string, planner Planner, log *slog.Logger, opts ...HandlerOption) (*Handler, error) {
 	api := slack.New(
 		botToken,
 		slack.OptionAppLevelToken(appToken),
@@ -47,13 +202,19 @@ func NewHandler(botToken, appToken string, planner Planner, log *slog.Logger) (*
 		return nil, err
 	}
 
-	return &Handler{
-		client:  api,
-		socket:  socket,
-		botID:   authResp.UserID,
-		planner: planner,
-		log:     log,
-	}, nil
+	h := &Handler{
+		client:           api,
+		socket:           socket,
+		botID:            authResp.UserID,
+		planner:          planner,
+		log:              log,
+		allowedChannels:  make(map[string]bool),
+		suppressPresence: true,
+	}
+	for _, o := range opts {
+		o(h)
+	}
+	return h, nil
 }
 
 func (h *Handler) Run(ctx context.Context) error {
@@ -66,6 +227,9 @@ func (h *Handler) Run(ctx context.Context) error {
 		case socketmode.EventTypeEventsAPI:
 			h.socket.Ack(*evt.Request)
 			h.handleEventsAPI(ctx, evt)
+		case socketmode.EventTypeInteractive:
+			h.socket.Ack(*evt.Request)
+			h.handleInteractive(ctx, evt)
 		case socketmode.EventTypeConnecting:
 			h.log.Info("Connecting to slack")
 		case socketmode.EventTypeConnected:
@@ -98,6 +262,7 @@ func (h *Handler) handleCallback(ctx context.Context, inner slackevents.EventsAP
 			UserID:    ev.User,
 			Text:      h.stripMention(ev.Text),
 			IsDM:      false,
+			InThread:  ev.ThreadTimeStamp != "",
 		})
 
 	case *slackevents.MessageEvent:
@@ -105,12 +270,16 @@ func (h *Handler) handleCallback(ctx context.Context, inner slackevents.EventsAP
 		if ev.BotID != "" || ev.SubType == "bot_message" {
 			return
 		}
+		if h.suppressPresence && (presenceSubtypes[ev.SubType] || ev.User == h.botID) {
+			return
+		}
 		h.dispatch(ctx, IncomingMessage{
 			ThreadTS:  threadTS(ev.ThreadTimeStamp, ev.TimeStamp),
 			ChannelID: ev.Channel,
 			UserID:    ev.User,
 			Text:      ev.Text,
 			IsDM:      true,
+			InThread:  ev.ThreadTimeStamp != "",
 		})
 	}
 }
@@ -123,26 +292,169 @@ func (h *Handler) dispatch(ctx context.Context, msg IncomingMessage) {
 		"dm", msg.IsDM,
 	)
 
+	if !msg.IsDM && len(h.allowedChannels) > 0 && !h.allowedChannels[msg.ChannelID] {
+		h.log.Info("ignoring message from channel not in allowlist", "channel", msg.ChannelID)
+		return
+	}
+	if !h.userLimit.Allow(msg.UserID) {
+		h.log.Warn("user rate limited", "user", msg.UserID)
+		h.postNotice(msg.ChannelID, msg.ThreadTS, msg.UserID, "You're sending requests faster than I can keep up with. Please wait a little while and try again.")
+		return
+	}
+	if !h.channelLimit.Allow(msg.ChannelID) {
+		h.log.Warn("channel rate limited", "channel", msg.ChannelID)
+		h.postNotice(msg.ChannelID, msg.ThreadTS, msg.UserID, "This channel has reached its planning request limit for now. Please try again later.")
+		return
+	}
+
+	if h.handleCommand(ctx, msg) {
+		return
+	}
+
 	reply, err := h.planner.Handle(ctx, msg)
 	if err != nil {
 		h.log.Error("planner error", "err", err)
-		reply = "Sorry, something went wrong. Please try again."
+		h.postNotice(msg.ChannelID, msg.ThreadTS, msg.UserID, "Sorry, something went wrong. Please try again.")
+		return
 	}
 
-	h.postReply(msg.ChannelID, msg.ThreadTS, reply)
+	replyTS := msg.ThreadTS
+	if h.replyMode == ReplyMatchUser && !msg.InThread {
+		replyTS = ""
+	}
+	h.postReply(msg.ChannelID, replyTS, reply.Text)
+	if reply.Breakdown != nil {
+		// The buttons act on the thread's session, so they always go in it.
+		h.postBreakdown(ctx, msg.ChannelID, msg.ThreadTS, *reply.Breakdown)
+	}
 }
 
-func (h *Handler) postReply(channelID, threadTS, text string) {
-	_, _, err := h.client.PostMessage(
-		channelID,
+func (h *Handler) handleInteractive(ctx context.Context, evt socketmode.Event) {
+	callback, ok := evt.Data.(slack.InteractionCallback)
+	if !ok {
+		return
+	}
+	if callback.Type == slack.InteractionTypeViewSubmission && callback.View.CallbackID == editCallbackID {
+		h.editBreakdown(ctx, callback)
+		return
+	}
+	if callback.Type != slack.InteractionTypeBlockActions {
+		return
+	}
+
+	for _, action := range callback.ActionCallback.BlockActions {
+		if action.ActionID == ActionRetryIssue {
+			h.retry(ctx, callback.Channel.ID, callback.Container.MessageTs, callback.User.ID, action.Value)
+			continue
+		}
+		switch route := ReviewRoute(action.ActionID); route {
+		case RouteRevise, RouteSelf, RouteDismiss:
+			h.routeReview(ctx, callback, route, action.Value)
+			continue
+		}
+		switch choice := BreakdownChoice(action.ActionID); choice {
+		case BreakdownApprove, BreakdownEdit, BreakdownRemove, BreakdownCreate:
+			h.reviewBreakdown(ctx, callback, choice, action.Value)
+			continue
+		}
+		if action.ActionID != actionRequeue {
+			continue
+		}
+		threadTS, num, ok := strings.Cut(action.Value, ":")
+		issueNumber, err := strconv.Atoi(num)
+		if !ok || err != nil {
+			h.log.Warn("malformed requeue action", "value", action.Value)
+			continue
+		}
+
+		reply, err := h.planner.Requeue(ctx, threadTS, issueNumber)
+		if err != nil {
+			h.log.Error("requeue failed", "issue", issueNumber, "err", err)
+			h.postNotice(callback.Channel.ID, threadTS, callback.User.ID, fmt.Sprintf("Sorry, I couldn't requeue #%d.", issueNumber))
+			continue
+		}
+		h.postReply(callback.Channel.ID, threadTS, reply)
+	}
+}
+
+// routeReview carries out a review button and replaces the buttons with the
+// outcome, so the choice is made once.
+func (h *Handler) routeReview(ctx context.Context, callback slack.InteractionCallback, route ReviewRoute, value string) {
+	channelID, ts, userID := callback.Channel.ID, callback.Container.MessageTs, callback.User.ID
+	ref, ok := ParseReviewRef(value)
+	if !ok {
+		h.log.Warn("malformed review action", "value", value)
+		return
+	}
+
+	reply, err := h.planner.RouteReview(ctx, route, ref, userID)
+	if err != nil {
+		h.log.Error("review routing failed", "route", route, "issue", ref.IssueURL, "err", err)
+		h.postNotice(channelID, ts, userID, fmt.Sprintf("Sorry, that didn't work: %s", err))
+		return
+	}
+
+	text := callback.Message.Text + "\n" + reply
+	_, _, _, err = h.client.UpdateMessageContext(ctx, channelID, ts,
 		slack.MsgOptionText(text, false),
-		slack.MsgOptionTS(threadTS), // reply in thread
+		slack.MsgOptionBlocks(slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil)),
 	)
+	if err != nil {
+		h.log.Warn("failed to update review message", "err", err)
+		h.postReply(channelID, ts, reply)
+	}
+}
+
+// PostReminder posts r in its thread with a one-click requeue button.
+func (h *Handler) PostReminder(ctx context.Context, r Reminder) error {
+	text := slack.NewTextBlockObject(slack.MarkdownType, r.Text, false, false)
+	button := slack.NewButtonBlockElement(actionRequeue,
+		fmt.Sprintf("%s:%d", r.ThreadTS, r.IssueNumber),
+		slack.NewTextBlockObject(slack.PlainTextType, "Requeue", false, false),
+	)
+
+	_, _, err := h.client.PostMessageContext(ctx, r.ChannelID,
+		slack.MsgOptionText(r.Text, false),
+		slack.MsgOptionBlocks(
+			slack.NewSectionBlock(text, nil, nil),
+			slack.NewActionBlock("", button),
+		),
+		slack.MsgOptionTS(r.ThreadTS),
+	)
+	if err != nil {
+		return fmt.Errorf("post reminder: %w", err)
+	}
+	return nil
+}
+
+// postReply posts text in the thread, or in the channel when threadTS is empty.
+func (h *Handler) postReply(channelID, threadTS, text string) {
+	opts := []slack.MsgOption{slack.MsgOptionText(text, false)}
+	if threadTS != "" {
+		opts = append(opts, slack.MsgOptionTS(threadTS)) // reply in thread
+	}
+	_, _, err := h.client.PostMessage(channelID, opts...)
 	if err != nil {
 		h.log.Error("failed to post message", "err", err)
 	}
 }
 
+// postNotice posts an error or refusal, ephemerally to userID when ephemeral
+// notices are enabled.
+func (h *Handler) postNotice(channelID, threadTS, userID, text string) {
+	if !h.ephemeralNotices || userID == "" {
+		h.postReply(channelID, threadTS, text)
+		return
+	}
+	_, err := h.client.PostEphemeral(channelID, userID,
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionTS(threadTS),
+	)
+	if err != nil {
+		h.log.Error("failed to post ephemeral message", "err", err)
+	}
+}
+
 func (h *Handler) stripMention(text string) string {
 	mention := "<@" + h.botID + ">"
 	return strings.TrimSpace(strings.TrimPrefix(text, mention))
@@ -0,0 +1,53 @@
+package slack
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter is a sliding-window limiter keyed by user or channel ID. It
+// allows at most limit events per window for each key.
+type rateLimiter struct {
+	limit  int
+	window time.Duration
+
+	mu     sync.Mutex
+	events map[string][]time.Time
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{
+		limit:  limit,
+		window: window,
+		events: make(map[string][]time.Time),
+	}
+}
+
+// Allow records an event for key and reports whether it is within the limit.
+// Rejected events are not recorded, so a spamming user is not locked out
+// longer than the window.
+func (r *rateLimiter) Allow(key string) bool {
+	if r == nil || r.limit <= 0 {
+		return true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	cutoff := now.Add(-r.window)
+
+	recent := r.events[key][:0]
+	for _, t := range r.events[key] {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+
+	if len(recent) >= r.limit {
+		r.events[key] = recent
+		return false
+	}
+	r.events[key] = append(recent, now)
+	return true
+}
@@ -0,0 +1,151 @@
+package standards
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxDocBytes caps an uploaded document.
+const maxDocBytes = 1 << 20
+
+// Handler serves the store under /standards/:
+//
+//	GET    /standards/        list documents
+//	GET    /standards/{name}  read a document
+//	PUT    /standards/{name}  upload a document (markdown body)
+//	DELETE /standards/{name}  remove a document
+//
+// Writes require "Authorization: Bearer <adminToken>"; with an empty
+// adminToken the store is read-only over HTTP.
+func Handler(s *Store, adminToken string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/standards/")
+		switch r.Method {
+		case http.MethodGet:
+			docs, err := s.Docs()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if name == "" {
+				type entry struct {
+					Name      string   `json:"name"`
+					Languages []string `json:"languages,omitempty"`
+					Repos     []string `json:"repos,omitempty"`
+				}
+				list := make([]entry, 0, len(docs))
+				for _, d := range docs {
+					list = append(list, entry{Name: d.Name, Languages: d.Languages, Repos: d.Repos})
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_ = json.NewEncoder(w).Encode(list)
+				return
+			}
+			for _, d := range docs {
+				if d.Name == strings.TrimSuffix(name, ".md") {
+					w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+					_, _ = io.WriteString(w, d.Body)
+					return
+				}
+			}
+			http.NotFound(w, r)
+
+		case http.MethodPut, http.MethodDelete:
+			if !authorized(r, adminToken) {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			var err error
+			if r.Method == http.MethodPut {
+				var body []byte
+				body, err = io.ReadAll(io.LimitReader(r.Body, maxDocBytes+1))
+				if err == nil && len(body) > maxDocBytes {
+					http.Error(w, "document too large", http.StatusRequestEntityTooLarge)
+					return
+				}
+				if err == nil {
+					err = s.Put(name, string(body))
+				}
+			} else {
+				err = s.Delete(name)
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func authorized(r *http.Request, token string) bool {
+	if token == "" {
+		return false
+	}
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// HTTPEmbedder calls an OpenAI-compatible embeddings endpoint: it POSTs
+// {"model", "input": [...]} and reads data[].embedding.
+type HTTPEmbedder struct {
+	URL    string
+	Model  string
+	APIKey string
+	Client *http.Client
+}
+
+func (e HTTPEmbedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	payload, err := json.Marshal(map[string]any{"model": e.Model, "input": texts})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.APIKey)
+	}
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 500))
+		return nil, fmt.Errorf("embeddings endpoint returned %s: %s", resp.Status, b)
+	}
+	var out struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decode embeddings: %w", err)
+	}
+	vecs := make([][]float64, len(texts))
+	for i, d := range out.Data {
+		idx := d.Index
+		if idx < 0 || idx >= len(vecs) {
+			idx = i
+		}
+		vecs[idx] = d.Embedding
+	}
+	return vecs, nil
+}
@@ -0,0 +1,197 @@
+package standards
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Query describes the task standards are retrieved for.
+type Query struct {
+	Repo      string   // "owner/repo"
+	Languages []string // e.g. "go", "typescript"
+	Text      string   // issue or PR text, file names, etc.
+}
+
+// Retriever picks the sections most relevant to a query, best first.
+type Retriever interface {
+	Retrieve(ctx context.Context, sections []Section, q Query, limit int) ([]Section, error)
+}
+
+var wordRe = regexp.MustCompile(`[a-z][a-z0-9_]{2,}`)
+
+// stopWords are dropped before keyword scoring.
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "that": true, "this": true,
+	"are": true, "from": true, "use": true, "should": true, "must": true, "not": true,
+	"when": true, "all": true, "any": true, "can": true, "into": true, "its": true,
+}
+
+func terms(s string) []string {
+	var out []string
+	for _, w := range wordRe.FindAllString(strings.ToLower(s), -1) {
+		if !stopWords[w] {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// KeywordRetriever scores sections by TF-IDF overlap with the query text. It
+// needs no external service.
+type KeywordRetriever struct{}
+
+func (KeywordRetriever) Retrieve(_ context.Context, sections []Section, q Query, limit int) ([]Section, error) {
+	if len(sections) == 0 {
+		return nil, nil
+	}
+	queryTerms := make(map[string]bool)
+	for _, t := range terms(q.Text + " " + strings.Join(q.Languages, " ")) {
+		queryTerms[t] = true
+	}
+
+	df := make(map[string]int)
+	counts := make([]map[string]int, len(sections))
+	for i, s := range sections {
+		counts[i] = make(map[string]int)
+		for _, t := range terms(s.Heading + " " + s.Heading + " " + s.Text) {
+			counts[i][t]++
+		}
+		for t := range counts[i] {
+			df[t]++
+		}
+	}
+
+	scores := make([]float64, len(sections))
+	for i := range sections {
+		for t := range queryTerms {
+			if c := counts[i][t]; c > 0 {
+				idf := math.Log(1 + float64(len(sections))/float64(df[t]))
+				scores[i] += (1 + math.Log(float64(c))) * idf
+			}
+		}
+	}
+	return topSections(sections, scores, limit), nil
+}
+
+// Embedder turns texts into vectors, e.g. through a hosted embedding model.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float64, error)
+}
+
+// EmbeddingRetriever ranks sections by cosine similarity between embeddings.
+// Section embeddings are cached by content.
+type EmbeddingRetriever struct {
+	embedder Embedder
+
+	mu    sync.Mutex
+	cache map[string][]float64
+}
+
+func NewEmbeddingRetriever(e Embedder) *EmbeddingRetriever {
+	return &EmbeddingRetriever{embedder: e, cache: make(map[string][]float64)}
+}
+
+func (r *EmbeddingRetriever) Retrieve(ctx context.Context, sections []Section, q Query, limit int) ([]Section, error) {
+	if len(sections) == 0 {
+		return nil, nil
+	}
+	keys := make([]string, len(sections))
+	var missing []string
+	r.mu.Lock()
+	for i, s := range sections {
+		keys[i] = s.Heading + "\n" + s.Text
+		if _, ok := r.cache[keys[i]]; !ok {
+			missing = append(missing, keys[i])
+		}
+	}
+	r.mu.Unlock()
+
+	vecs, err := r.embedder.Embed(ctx, append([]string{q.Text}, missing...))
+	if err != nil {
+		return nil, fmt.Errorf("embed: %w", err)
+	}
+	if len(vecs) != len(missing)+1 {
+		return nil, fmt.Errorf("embed: got %d vectors for %d texts", len(vecs), len(missing)+1)
+	}
+	r.mu.Lock()
+	for i, k := range missing {
+		r.cache[k] = vecs[i+1]
+	}
+	scores := make([]float64, len(sections))
+	for i, k := range keys {
+		scores[i] = cosine(vecs[0], r.cache[k])
+	}
+	r.mu.Unlock()
+	return topSections(sections, scores, limit), nil
+}
+
+func cosine(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// topSections returns up to limit sections with a positive score, best first.
+func topSections(sections []Section, scores []float64, limit int) []Section {
+	idx := make([]int, 0, len(sections))
+	for i, s := range scores {
+		if s > 0 {
+			idx = append(idx, i)
+		}
+	}
+	sort.SliceStable(idx, func(a, b int) bool { return scores[idx[a]] > scores[idx[b]] })
+	if len(idx) > limit {
+		idx = idx[:limit]
+	}
+	out := make([]Section, len(idx))
+	for i, j := range idx {
+		out[i] = sections[j]
+	}
+	return out
+}
+
+// languageByExt maps file extensions to the language names used in document
+// front matter.
+var languageByExt = map[string]string{
+	".go": "go", ".py": "python", ".ts": "typescript", ".tsx": "typescript",
+	".js": "javascript", ".jsx": "javascript", ".rb": "ruby", ".java": "java",
+	".kt": "kotlin", ".rs": "rust", ".cs": "csharp", ".php": "php",
+	".swift": "swift", ".scala": "scala", ".sql": "sql", ".tf": "terraform",
+}
+
+// Languages returns the languages of files, most common first.
+func Languages(files []string) []string {
+	counts := make(map[string]int)
+	for _, f := range files {
+		if lang, ok := languageByExt[strings.ToLower(path.Ext(f))]; ok {
+			counts[lang]++
+		}
+	}
+	langs := make([]string, 0, len(counts))
+	for l := range counts {
+		langs = append(langs, l)
+	}
+	sort.Slice(langs, func(i, j int) bool {
+		if counts[langs[i]] != counts[langs[j]] {
+			return counts[langs[i]] > counts[langs[j]]
+		}
+		return langs[i] < langs[j]
+	})
+	return langs
+}
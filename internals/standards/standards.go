@@ -0,0 +1,62 @@
+package standards
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+const (
+	// maxExcerpts and maxPromptBytes bound what is added to a prompt.
+	maxExcerpts    = 6
+	maxPromptBytes = 6000
+)
+
+// Library combines a Store with a Retriever to produce prompt sections.
+type Library struct {
+	store     *Store
+	retriever Retriever
+	log       *slog.Logger
+}
+
+// NewLibrary uses retriever to rank excerpts; nil means KeywordRetriever.
+func NewLibrary(store *Store, retriever Retriever, log *slog.Logger) *Library {
+	if retriever == nil {
+		retriever = KeywordRetriever{}
+	}
+	return &Library{store: store, retriever: retriever, log: log}
+}
+
+// PromptSection returns the standards relevant to q, formatted for a system
+// prompt, or "" if none apply. Lookup errors are logged and yield "", so a
+// broken store never blocks a job.
+func (l *Library) PromptSection(ctx context.Context, q Query) string {
+	if l == nil {
+		return ""
+	}
+	sections, err := l.store.Sections(q.Repo, q.Languages)
+	if err != nil {
+		l.log.Warn("could not load coding standards", "err", err)
+		return ""
+	}
+	picked, err := l.retriever.Retrieve(ctx, sections, q, maxExcerpts)
+	if err != nil {
+		l.log.Warn("standards retrieval failed, falling back to keywords", "err", err)
+		picked, _ = KeywordRetriever{}.Retrieve(ctx, sections, q, maxExcerpts)
+	}
+	if len(picked) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Organization coding standards — follow these; they apply to every repository in scope:\n")
+	for _, s := range picked {
+		excerpt := fmt.Sprintf("\n[%s — %s]\n%s\n", s.Doc, s.Heading, s.Text)
+		if sb.Len()+len(excerpt) > maxPromptBytes {
+			break
+		}
+		sb.WriteString(excerpt)
+	}
+	return sb.String()
+}
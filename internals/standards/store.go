@@ -0,0 +1,212 @@
+// Package standards holds an organization's coding standards documents and
+// retrieves the excerpts relevant to a task, so the executor and reviewer
+// prompts carry the same rules.
+package standards
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Doc is one standards document. Languages and Repos scope it; empty means
+// it applies everywhere. They are read from an optional front matter block:
+//
+//	---
+//	languages: go, python
+//	repos: myorg/*, otherorg/api
+//	---
+//	# Error handling
+//	...
+type Doc struct {
+	Name      string
+	Languages []string
+	Repos     []string // "owner/repo" globs
+	Body      string
+}
+
+// Section is a heading-delimited part of a document, the unit of retrieval.
+type Section struct {
+	Doc     string
+	Heading string
+	Text    string
+}
+
+// AppliesTo reports whether d is scoped to repo and to one of languages.
+func (d Doc) AppliesTo(repo string, languages []string) bool {
+	if len(d.Repos) > 0 {
+		ok := false
+		for _, pattern := range d.Repos {
+			if m, _ := path.Match(strings.ToLower(pattern), strings.ToLower(repo)); m {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if len(d.Languages) == 0 {
+		return true
+	}
+	for _, want := range d.Languages {
+		for _, have := range languages {
+			if strings.EqualFold(want, have) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Store keeps standards documents as markdown files in a directory. The
+// executor and reviewer can share the directory; documents are re-read on
+// each lookup, so uploads take effect on the next job.
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// validName keeps document names to safe file names.
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create standards dir: %w", err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Docs returns every document, sorted by name.
+func (s *Store) Docs() ([]Doc, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("read standards dir: %w", err)
+	}
+	var docs []Doc
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".md") {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(s.dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", e.Name(), err)
+		}
+		docs = append(docs, parseDoc(strings.TrimSuffix(e.Name(), ".md"), string(b)))
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i].Name < docs[j].Name })
+	return docs, nil
+}
+
+// Put creates or replaces the document name.
+func (s *Store) Put(name, content string) error {
+	name = strings.TrimSuffix(name, ".md")
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid document name %q", name)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tmp := filepath.Join(s.dir, "."+name+".tmp")
+	if err := os.WriteFile(tmp, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	return os.Rename(tmp, filepath.Join(s.dir, name+".md"))
+}
+
+// Delete removes the document name. Deleting a missing document is not an
+// error.
+func (s *Store) Delete(name string) error {
+	name = strings.TrimSuffix(name, ".md")
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid document name %q", name)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(filepath.Join(s.dir, name+".md")); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("delete %s: %w", name, err)
+	}
+	return nil
+}
+
+// Sections returns the sections of the documents that apply to repo and
+// languages.
+func (s *Store) Sections(repo string, languages []string) ([]Section, error) {
+	docs, err := s.Docs()
+	if err != nil {
+		return nil, err
+	}
+	var out []Section
+	for _, d := range docs {
+		if d.AppliesTo(repo, languages) {
+			out = append(out, splitSections(d)...)
+		}
+	}
+	return out, nil
+}
+
+func parseDoc(name, content string) Doc {
+	d := Doc{Name: name, Body: content}
+	rest, ok := strings.CutPrefix(strings.ReplaceAll(content, "\r\n", "\n"), "---\n")
+	if !ok {
+		return d
+	}
+	front, body, ok := strings.Cut(rest, "\n---\n")
+	if !ok {
+		return d
+	}
+	for _, line := range strings.Split(front, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "languages":
+			d.Languages = splitList(value)
+		case "repos":
+			d.Repos = splitList(value)
+		}
+	}
+	d.Body = body
+	return d
+}
+
+// splitSections splits a document at its "#" and "##" headings. Text before
+// the first heading is a section headed by the document name.
+func splitSections(d Doc) []Section {
+	var out []Section
+	cur := Section{Doc: d.Name, Heading: d.Name}
+	var sb strings.Builder
+	flush := func() {
+		if text := strings.TrimSpace(sb.String()); text != "" {
+			cur.Text = text
+			out = append(out, cur)
+		}
+		sb.Reset()
+	}
+	for _, line := range strings.Split(d.Body, "\n") {
+		if strings.HasPrefix(line, "# ") || strings.HasPrefix(line, "## ") {
+			flush()
+			cur = Section{Doc: d.Name, Heading: strings.TrimSpace(strings.TrimLeft(line, "#"))}
+		}
+		sb.WriteString(line + "\n")
+	}
+	flush()
+	return out
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -0,0 +1,156 @@
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// JiraConfig locates the Jira site tickets are filed in. With an Email the
+// token is a Jira Cloud API token; without one it is a Data Center personal
+// access token.
+type JiraConfig struct {
+	BaseURL   string // e.g. https://acme.atlassian.net
+	Email     string
+	Token     string
+	Project   string // project key used when a session names none
+	IssueType string // issue type used when a session names none; defaults to Task
+}
+
+// Jira files tickets through the Jira REST API, version 2.
+type Jira struct {
+	cfg    JiraConfig
+	client *http.Client
+}
+
+func NewJira(cfg JiraConfig) (*Jira, error) {
+	if cfg.BaseURL == "" || cfg.Token == "" {
+		return nil, errors.New("Jira needs JIRA_URL and JIRA_API_TOKEN")
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	if cfg.IssueType == "" {
+		cfg.IssueType = "Task"
+	}
+	return &Jira{cfg: cfg, client: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+// Name implements Tracker.
+func (j *Jira) Name() string { return "Jira" }
+
+// CheckProject implements Tracker.
+func (j *Jira) CheckProject(ctx context.Context, project string) error {
+	if project == "" {
+		project = j.cfg.Project
+	}
+	if project == "" {
+		return errors.New("no Jira project key given and JIRA_PROJECT is not set")
+	}
+	if err := j.do(ctx, http.MethodGet, "/rest/api/2/project/"+url.PathEscape(project), nil, nil); err != nil {
+		return fmt.Errorf("jira project %s: %w", project, err)
+	}
+	return nil
+}
+
+// CreateTicket implements Tracker.
+func (j *Jira) CreateTicket(ctx context.Context, input TicketInput) (Ticket, error) {
+	project, issueType := input.Project, input.IssueType
+	if project == "" {
+		project = j.cfg.Project
+	}
+	if issueType == "" {
+		issueType = j.cfg.IssueType
+	}
+	if project == "" {
+		return Ticket{}, errors.New("jira create ticket: no project key")
+	}
+
+	fields := map[string]any{
+		"project":     map[string]string{"key": project},
+		"issuetype":   map[string]string{"name": issueType},
+		"summary":     input.Summary,
+		"description": input.Description,
+	}
+	if labels := jiraLabels(input.Labels); len(labels) > 0 {
+		fields["labels"] = labels
+	}
+	var created struct {
+		Key string `json:"key"`
+	}
+	if err := j.do(ctx, http.MethodPost, "/rest/api/2/issue", map[string]any{"fields": fields}, &created); err != nil {
+		return Ticket{}, fmt.Errorf("jira create ticket: %w", err)
+	}
+	return Ticket{Tracker: j.Name(), Key: created.Key, URL: j.cfg.BaseURL + "/browse/" + created.Key}, nil
+}
+
+// AddLink implements Tracker with a remote link whose global ID is the URL,
+// so linking it again updates the existing link.
+func (j *Jira) AddLink(ctx context.Context, key, link, title string) error {
+	payload := map[string]any{
+		"globalId": link,
+		"object":   map[string]string{"url": link, "title": title},
+	}
+	if err := j.do(ctx, http.MethodPost, "/rest/api/2/issue/"+url.PathEscape(key)+"/remotelink", payload, nil); err != nil {
+		return fmt.Errorf("jira link %s: %w", key, err)
+	}
+	return nil
+}
+
+// jiraLabels makes labels valid in Jira, which rejects labels with spaces.
+func jiraLabels(labels []string) []string {
+	out := make([]string, 0, len(labels))
+	for _, l := range labels {
+		if l = strings.Join(strings.Fields(l), "-"); l != "" {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// do sends a JSON request and decodes the response into out, if given.
+func (j *Jira) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, j.cfg.BaseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if j.cfg.Email != "" {
+		req.SetBasicAuth(j.cfg.Email, j.cfg.Token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+j.cfg.Token)
+	}
+
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
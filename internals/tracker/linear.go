@@ -0,0 +1,206 @@
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const linearAPI = "https://api.linear.app/graphql"
+
+// LinearConfig authenticates with Linear and picks the team tickets are filed
+// in when a session names none.
+type LinearConfig struct {
+	APIKey string // personal API key
+	Team   string // team key, e.g. ENG
+}
+
+// Linear files tickets through Linear's GraphQL API. Linear has no issue
+// types, so TicketInput.IssueType is ignored.
+type Linear struct {
+	cfg    LinearConfig
+	client *http.Client
+}
+
+func NewLinear(cfg LinearConfig) (*Linear, error) {
+	if cfg.APIKey == "" {
+		return nil, errors.New("Linear needs LINEAR_API_KEY")
+	}
+	return &Linear{cfg: cfg, client: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+// Name implements Tracker.
+func (l *Linear) Name() string { return "Linear" }
+
+// CheckProject implements Tracker; project is a team key.
+func (l *Linear) CheckProject(ctx context.Context, project string) error {
+	_, err := l.teamID(ctx, project)
+	return err
+}
+
+// CreateTicket implements Tracker. Labels Linear does not know are left out.
+func (l *Linear) CreateTicket(ctx context.Context, input TicketInput) (Ticket, error) {
+	teamID, err := l.teamID(ctx, input.Project)
+	if err != nil {
+		return Ticket{}, err
+	}
+	labelIDs, err := l.labelIDs(ctx, teamID, input.Labels)
+	if err != nil {
+		return Ticket{}, err
+	}
+
+	var out struct {
+		IssueCreate struct {
+			Success bool `json:"success"`
+			Issue   struct {
+				Identifier string `json:"identifier"`
+				URL        string `json:"url"`
+			} `json:"issue"`
+		} `json:"issueCreate"`
+	}
+	err = l.query(ctx, `mutation($input: IssueCreateInput!) {
+		issueCreate(input: $input) { success issue { identifier url } }
+	}`, map[string]any{"input": map[string]any{
+		"teamId":      teamID,
+		"title":       input.Summary,
+		"description": input.Description,
+		"labelIds":    labelIDs,
+	}}, &out)
+	if err != nil {
+		return Ticket{}, fmt.Errorf("linear create ticket: %w", err)
+	}
+	if !out.IssueCreate.Success {
+		return Ticket{}, errors.New("linear create ticket: not created")
+	}
+	issue := out.IssueCreate.Issue
+	return Ticket{Tracker: l.Name(), Key: issue.Identifier, URL: issue.URL}, nil
+}
+
+// AddLink implements Tracker with an attachment, which Linear keeps unique
+// per issue and URL.
+func (l *Linear) AddLink(ctx context.Context, key, link, title string) error {
+	var out struct {
+		AttachmentCreate struct {
+			Success bool `json:"success"`
+		} `json:"attachmentCreate"`
+	}
+	err := l.query(ctx, `mutation($input: AttachmentCreateInput!) {
+		attachmentCreate(input: $input) { success }
+	}`, map[string]any{"input": map[string]any{"issueId": key, "url": link, "title": title}}, &out)
+	if err != nil {
+		return fmt.Errorf("linear link %s: %w", key, err)
+	}
+	if !out.AttachmentCreate.Success {
+		return fmt.Errorf("linear link %s: not created", key)
+	}
+	return nil
+}
+
+// teamID resolves a team key, or the configured team when key is empty.
+func (l *Linear) teamID(ctx context.Context, key string) (string, error) {
+	if key == "" {
+		key = l.cfg.Team
+	}
+	if key == "" {
+		return "", errors.New("no Linear team key given and LINEAR_TEAM is not set")
+	}
+	var out struct {
+		Teams struct {
+			Nodes []struct {
+				ID string `json:"id"`
+			} `json:"nodes"`
+		} `json:"teams"`
+	}
+	err := l.query(ctx, `query($key: String!) {
+		teams(filter: { key: { eq: $key } }) { nodes { id } }
+	}`, map[string]any{"key": key}, &out)
+	if err != nil {
+		return "", fmt.Errorf("linear team %s: %w", key, err)
+	}
+	if len(out.Teams.Nodes) == 0 {
+		return "", fmt.Errorf("linear team %s: not found", key)
+	}
+	return out.Teams.Nodes[0].ID, nil
+}
+
+// labelIDs resolves label names to the IDs of the team's or the workspace's
+// existing labels.
+func (l *Linear) labelIDs(ctx context.Context, teamID string, names []string) ([]string, error) {
+	if len(names) == 0 {
+		return []string{}, nil
+	}
+	var out struct {
+		IssueLabels struct {
+			Nodes []struct {
+				ID string `json:"id"`
+			} `json:"nodes"`
+		} `json:"issueLabels"`
+	}
+	err := l.query(ctx, `query($names: [String!], $team: ID) {
+		issueLabels(filter: {
+			name: { in: $names }
+			or: [{ team: { id: { eq: $team } } }, { team: { null: true } }]
+		}) { nodes { id } }
+	}`, map[string]any{"names": names, "team": teamID}, &out)
+	if err != nil {
+		return nil, fmt.Errorf("linear labels: %w", err)
+	}
+	ids := make([]string, 0, len(out.IssueLabels.Nodes))
+	for _, n := range out.IssueLabels.Nodes {
+		ids = append(ids, n.ID)
+	}
+	return ids, nil
+}
+
+// query runs a GraphQL request and decodes its data into out.
+func (l *Linear) query(ctx context.Context, query string, vars map[string]any, out any) error {
+	b, err := json.Marshal(map[string]any{"query": query, "variables": vars})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, linearAPI, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", l.cfg.APIKey)
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body[:min(len(body), 1024)])))
+	}
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	if len(result.Errors) > 0 {
+		msgs := make([]string, len(result.Errors))
+		for i, e := range result.Errors {
+			msgs[i] = e.Message
+		}
+		return errors.New(strings.Join(msgs, "; "))
+	}
+	if err := json.Unmarshal(result.Data, out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+// Package tracker files planned work in an issue tracker other than the
+// repository's own, such as Jira or Linear, for teams that track work there
+// while the code lives on GitHub or GitLab.
+package tracker
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// Tracker files tickets and links them to the issues and PRs made for them.
+type Tracker interface {
+	// Name is the tracker's name as users know it, e.g. "Jira". It also
+	// introduces the ticket's line in an issue body.
+	Name() string
+	// CheckProject reports an error unless project (a Jira project or Linear
+	// team key) exists. An empty project checks the tracker's default.
+	CheckProject(ctx context.Context, project string) error
+	CreateTicket(ctx context.Context, input TicketInput) (Ticket, error)
+	// AddLink links url from the ticket. Linking the same URL again updates
+	// the link instead of adding another.
+	AddLink(ctx context.Context, key, url, title string) error
+}
+
+// TicketInput is a ticket to file. Empty Project and IssueType use the
+// tracker's defaults; trackers without issue types ignore IssueType.
+type TicketInput struct {
+	Project     string
+	IssueType   string
+	Summary     string
+	Description string
+	Labels      []string
+}
+
+// Ticket is a filed ticket.
+type Ticket struct {
+	Tracker string // the tracker's Name
+	Key     string // e.g. PROJ-12
+	URL     string
+}
+
+// Line is the line of an issue body naming the ticket the issue was filed
+// for, e.g. "Jira: https://acme.atlassian.net/browse/PROJ-12".
+func (t Ticket) Line() string {
+	return t.Tracker + ": " + t.URL
+}
+
+var ticketLine = regexp.MustCompile(`(?m)^(Jira|Linear):\s*(\S+/(?:browse|issue)/([A-Z][A-Z0-9_]*-[0-9]+)(?:/\S*)?)\s*$`)
+
+// TicketFor returns the ticket an issue body names on its ticket line, or
+// false when it names none.
+func TicketFor(body string) (Ticket, bool) {
+	m := ticketLine.FindStringSubmatch(strings.ReplaceAll(body, "\r\n", "\n"))
+	if m == nil {
+		return Ticket{}, false
+	}
+	return Ticket{Tracker: m[1], Key: m[3], URL: m[2]}, true
+}
+
+// IsTicketLine reports whether line is a ticket line.
+func IsTicketLine(line string) bool {
+	return ticketLine.MatchString(strings.TrimSpace(line))
+}
+
+// Set is the trackers a service is configured with.
+type Set []Tracker
+
+// Get returns the tracker named name, ignoring case, or nil.
+func (s Set) Get(name string) Tracker {
+	for _, t := range s {
+		if strings.EqualFold(t.Name(), name) {
+			return t
+		}
+	}
+	return nil
+}
+
+// Names lists the trackers' names.
+func (s Set) Names() []string {
+	names := make([]string, len(s))
+	for i, t := range s {
+		names[i] = t.Name()
+	}
+	return names
+}